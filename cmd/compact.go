@@ -57,7 +57,11 @@ func NewCompactCommand(ctx context.Context) *cobra.Command {
 				CompactorConfig: opts.compactorConfig,
 			}
 
-			snapshot, err := cp.Compact(ctx, compactOptions)
+			compact := cp.Compact
+			if opts.compactorConfig.DeleteCompactedDeltas {
+				compact = cp.CompactChain
+			}
+			snapshot, err := compact(ctx, compactOptions)
 			if err != nil {
 				if strings.Contains(err.Error(), mvcc.ErrCompacted.Error()) {
 					logger.Warnf("Stopping backup compaction: %v", err)
@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/gardener/etcd-backup-restore/pkg/etcdutil"
+	"github.com/gardener/etcd-backup-restore/pkg/snapshot/restorer"
+	"github.com/gardener/etcd-backup-restore/pkg/snapstore"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// NewFollowCommand returns the command to keep a standby etcd up to date with the snapstore
+func NewFollowCommand(ctx context.Context) *cobra.Command {
+	opts := newFollowerOptions()
+	followCmd := &cobra.Command{
+		Use:   "follow",
+		Short: "keeps a standby etcd up to date with the snapstore",
+		Long: `Polls the snapstore for new delta snapshots and applies their events to a standby etcd, which has to be
+restored from the same snapstore beforehand, so that it stays close to the revision of the primary etcd.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			printVersionInfo()
+			logger := logrus.NewEntry(logrus.New())
+			if err := opts.validate(); err != nil {
+				logger.Fatalf("failed to validate the options: %v", err)
+				return
+			}
+
+			opts.complete()
+
+			ss, err := snapstore.GetSnapstore(opts.snapstoreConfig)
+			if err != nil {
+				logger.Fatalf("Failed to create snapstore from configured storage provider: %v", err)
+			}
+
+			follower, err := restorer.NewFollower(ss, etcdutil.NewFactory(*opts.etcdConnectionConfig), opts.followerConfig, logger)
+			if err != nil {
+				logger.Fatalf("Failed to create follower: %v", err)
+			}
+			if err := follower.Run(ctx); err != nil {
+				logger.Fatalf("Failed to keep the standby etcd up to date: %v", err)
+			}
+		},
+	}
+
+	opts.addFlags(followCmd.Flags())
+	return followCmd
+}
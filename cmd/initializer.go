@@ -31,6 +31,13 @@ func NewInitializeCommand(ctx context.Context) *cobra.Command {
 				return
 			}
 
+			// the scale-up after the restoration only finishes once etcd has been started with the restored data directory,
+			// which the one-shot initialization doesn't do, hence only the server scales up the cluster
+			if opts.restorerOptions.restorationConfig.ScaleUpClusterSize != 0 {
+				logger.Fatalf("failed to validate the options: scale-up-cluster-size is not supported by the initialize command, as the cluster is only scaled up by the server")
+				return
+			}
+
 			opts.complete()
 
 			clusterUrlsMap, err := types.NewURLsMap(opts.restorerOptions.restorationConfig.InitialCluster)
@@ -43,6 +50,11 @@ func NewInitializeCommand(ctx context.Context) *cobra.Command {
 				logger.Fatalf("failed parsing peers urls for restore cluster: %v", err)
 			}
 
+			restoreToTime, err := opts.restorerOptions.restorationConfig.GetRestoreToTime()
+			if err != nil {
+				logger.Fatalf("failed parsing the time to restore to: %v", err)
+			}
+
 			var mode validator.Mode
 			switch validator.Mode(opts.validatorOptions.ValidationMode) {
 			case validator.Full:
@@ -54,9 +66,14 @@ func NewInitializeCommand(ctx context.Context) *cobra.Command {
 			}
 
 			restoreOptions := &brtypes.RestoreOptions{
-				Config:      opts.restorerOptions.restorationConfig,
-				ClusterURLs: clusterUrlsMap,
-				PeerURLs:    peerUrls,
+				Config:              opts.restorerOptions.restorationConfig,
+				ClusterURLs:         clusterUrlsMap,
+				PeerURLs:            peerUrls,
+				PeerTLS:             opts.etcdConnectionConfig.PeerTLSConfig,
+				MaxRestoreDuration:  opts.restorerOptions.restorationConfig.MaxRestoreDuration.Duration,
+				RestoreLockTTL:      opts.restorerOptions.restorationConfig.RestoreLockTTL.Duration,
+				RestoreToTime:       restoreToTime,
+				InitialClusterState: opts.restorerOptions.initialClusterState,
 			}
 
 			etcdInitializer, err := initializer.NewInitializer(restoreOptions, opts.restorerOptions.snapstoreConfig, opts.etcdConnectionConfig, logger)
@@ -12,6 +12,7 @@ import (
 	"github.com/gardener/etcd-backup-restore/pkg/snapstore"
 	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
 	ver "github.com/gardener/etcd-backup-restore/pkg/version"
+	"github.com/sirupsen/logrus"
 	"go.etcd.io/etcd/pkg/types"
 )
 
@@ -31,6 +32,11 @@ func BuildRestoreOptionsAndStore(opts *restorerOptions) (*brtypes.RestoreOptions
 
 	opts.complete()
 
+	restoreToTime, err := opts.restorationConfig.GetRestoreToTime()
+	if err != nil {
+		return nil, nil, err
+	}
+
 	clusterUrlsMap, err := types.NewURLsMap(opts.restorationConfig.InitialCluster)
 	if err != nil {
 		logger.Fatalf("failed creating url map for restore cluster: %v", err)
@@ -47,7 +53,16 @@ func BuildRestoreOptionsAndStore(opts *restorerOptions) (*brtypes.RestoreOptions
 	}
 
 	logger.Info("Finding latest set of snapshot to recover from...")
-	baseSnap, deltaSnapList, err := miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(store)
+	var (
+		baseSnap      *brtypes.Snapshot
+		deltaSnapList brtypes.SnapList
+		fallback      string
+	)
+	if opts.restorationConfig.UseChainManifest {
+		baseSnap, deltaSnapList, fallback, err = miscellaneous.GetLatestFullSnapshotAndDeltaSnapListFromChainManifest(store, logrus.NewEntry(logger))
+	} else {
+		baseSnap, deltaSnapList, err = miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(store)
+	}
 	if err != nil {
 		logger.Fatalf("failed to get latest snapshot: %v", err)
 	}
@@ -58,10 +73,17 @@ func BuildRestoreOptionsAndStore(opts *restorerOptions) (*brtypes.RestoreOptions
 	}
 
 	return &brtypes.RestoreOptions{
-		Config:        opts.restorationConfig,
-		BaseSnapshot:  baseSnap,
-		DeltaSnapList: deltaSnapList,
-		ClusterURLs:   clusterUrlsMap,
-		PeerURLs:      peerUrls,
+		Config:                opts.restorationConfig,
+		BaseSnapshot:          baseSnap,
+		DeltaSnapList:         deltaSnapList,
+		ClusterURLs:           clusterUrlsMap,
+		PeerURLs:              peerUrls,
+		MaxRestoreDuration:    opts.restorationConfig.MaxRestoreDuration.Duration,
+		RestoreLockTTL:        opts.restorationConfig.RestoreLockTTL.Duration,
+		RestoreToTime:         restoreToTime,
+		SkipDeltaRevisions:    opts.skipDeltaRevisions,
+		BaseOnly:              opts.baseSnapshotOnly,
+		InitialClusterState:   opts.initialClusterState,
+		ChainManifestFallback: fallback,
 	}, store, nil
 }
@@ -7,11 +7,13 @@ package cmd
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 
 	"github.com/gardener/etcd-backup-restore/pkg/compressor"
 
 	"github.com/gardener/etcd-backup-restore/pkg/initializer/validator"
+	"github.com/gardener/etcd-backup-restore/pkg/miscellaneous"
 	"github.com/gardener/etcd-backup-restore/pkg/server"
 	"github.com/gardener/etcd-backup-restore/pkg/snapshot/snapshotter"
 	"github.com/gardener/etcd-backup-restore/pkg/snapstore"
@@ -152,15 +154,19 @@ func (c *compactOptions) validate() error {
 }
 
 type restorerOptions struct {
-	restorationConfig *brtypes.RestorationConfig
-	snapstoreConfig   *brtypes.SnapstoreConfig
+	restorationConfig   *brtypes.RestorationConfig
+	snapstoreConfig     *brtypes.SnapstoreConfig
+	skipDeltaRevisions  []int64
+	baseSnapshotOnly    bool
+	initialClusterState string
 }
 
 // newRestorerOptions returns the validation config.
 func newRestorerOptions() *restorerOptions {
 	return &restorerOptions{
-		restorationConfig: brtypes.NewRestorationConfig(),
-		snapstoreConfig:   snapstore.NewSnapstoreConfig(),
+		restorationConfig:   brtypes.NewRestorationConfig(),
+		snapstoreConfig:     snapstore.NewSnapstoreConfig(),
+		initialClusterState: miscellaneous.ClusterStateNew,
 	}
 }
 
@@ -168,6 +174,9 @@ func newRestorerOptions() *restorerOptions {
 func (c *restorerOptions) addFlags(fs *flag.FlagSet) {
 	c.restorationConfig.AddFlags(fs)
 	c.snapstoreConfig.AddFlags(fs)
+	fs.Int64SliceVar(&c.skipDeltaRevisions, "skip-delta-revisions", c.skipDeltaRevisions, "revisions whose delta snapshots are skipped by the restoration, along with all the other events they hold, to work around events which can't be applied. The skipped events are missing from the restored data")
+	fs.BoolVar(&c.baseSnapshotOnly, "base-snapshot-only", c.baseSnapshotOnly, "restore only the latest full snapshot up to its revision and discard all the delta snapshots taken after it, e.g. if they are suspected to be corrupted. The events of the discarded delta snapshots are missing from the restored data")
+	fs.StringVar(&c.initialClusterState, "initial-cluster-state", c.initialClusterState, "initial cluster state of the restored member, either 'new' to bootstrap a new cluster or 'existing' to join an existing cluster")
 }
 
 // Validate validates the config.
@@ -176,6 +185,22 @@ func (c *restorerOptions) validate() error {
 		return err
 	}
 
+	for _, revision := range c.skipDeltaRevisions {
+		if revision <= 0 {
+			return errors.New("parameter skip-delta-revisions must only hold revisions greater than 0")
+		}
+	}
+	if len(c.skipDeltaRevisions) > 0 && c.restorationConfig.RestoreCheckpointInterval > 0 {
+		return errors.New("parameter skip-delta-revisions cannot be combined with restore-checkpoint-interval")
+	}
+	if c.baseSnapshotOnly && (c.restorationConfig.RestoreToTime != "" || len(c.skipDeltaRevisions) > 0) {
+		return errors.New("parameter base-snapshot-only cannot be combined with restore-to-time or skip-delta-revisions")
+	}
+
+	if c.initialClusterState != miscellaneous.ClusterStateNew && c.initialClusterState != miscellaneous.ClusterStateExisting {
+		return fmt.Errorf("parameter initial-cluster-state must be either %s or %s", miscellaneous.ClusterStateNew, miscellaneous.ClusterStateExisting)
+	}
+
 	return c.restorationConfig.Validate()
 }
 
@@ -184,6 +209,89 @@ func (c *restorerOptions) complete() {
 	c.snapstoreConfig.Complete()
 }
 
+type verifyRestoreOptions struct {
+	*restorerOptions
+	startEmbeddedEtcd bool
+}
+
+// newVerifyRestoreOptions returns the restore verification config.
+func newVerifyRestoreOptions() *verifyRestoreOptions {
+	return &verifyRestoreOptions{
+		restorerOptions:   newRestorerOptions(),
+		startEmbeddedEtcd: true,
+	}
+}
+
+// AddFlags adds the flags to flagset.
+func (c *verifyRestoreOptions) addFlags(fs *flag.FlagSet) {
+	c.restorerOptions.addFlags(fs)
+	fs.BoolVar(&c.startEmbeddedEtcd, "start-embedded-etcd", c.startEmbeddedEtcd, "start an embedded etcd over the restored data directory to verify that it boots and reaches the revision of the latest snapshot")
+}
+
+type credentialsVerifierOptions struct {
+	snapstoreConfig *brtypes.SnapstoreConfig
+}
+
+// newCredentialsVerifierOptions returns the credentials verification options.
+func newCredentialsVerifierOptions() *credentialsVerifierOptions {
+	return &credentialsVerifierOptions{
+		snapstoreConfig: snapstore.NewSnapstoreConfig(),
+	}
+}
+
+// AddFlags adds the flags to flagset.
+func (c *credentialsVerifierOptions) addFlags(fs *flag.FlagSet) {
+	c.snapstoreConfig.AddFlags(fs)
+}
+
+// Validate validates the config.
+func (c *credentialsVerifierOptions) validate() error {
+	return c.snapstoreConfig.Validate()
+}
+
+// complete completes the config.
+func (c *credentialsVerifierOptions) complete() {
+	c.snapstoreConfig.Complete()
+}
+
+type followerOptions struct {
+	etcdConnectionConfig *brtypes.EtcdConnectionConfig
+	snapstoreConfig      *brtypes.SnapstoreConfig
+	followerConfig       *brtypes.FollowerConfig
+}
+
+// newFollowerOptions returns the follower options.
+func newFollowerOptions() *followerOptions {
+	return &followerOptions{
+		etcdConnectionConfig: brtypes.NewEtcdConnectionConfig(),
+		snapstoreConfig:      snapstore.NewSnapstoreConfig(),
+		followerConfig:       brtypes.NewFollowerConfig(),
+	}
+}
+
+// AddFlags adds the flags to flagset.
+func (c *followerOptions) addFlags(fs *flag.FlagSet) {
+	c.etcdConnectionConfig.AddFlags(fs)
+	c.snapstoreConfig.AddFlags(fs)
+	c.followerConfig.AddFlags(fs)
+}
+
+// Validate validates the config.
+func (c *followerOptions) validate() error {
+	if err := c.snapstoreConfig.Validate(); err != nil {
+		return err
+	}
+	if err := c.followerConfig.Validate(); err != nil {
+		return err
+	}
+	return c.etcdConnectionConfig.Validate()
+}
+
+// complete completes the config.
+func (c *followerOptions) complete() {
+	c.snapstoreConfig.Complete()
+}
+
 type validatorOptions struct {
 	ValidationMode    string `json:"validationMode,omitempty"`
 	FailBelowRevision int64  `json:"experimentalFailBelowRevision,omitempty"`
@@ -250,10 +358,22 @@ func (c *snapshotterOptions) validate() error {
 		return err
 	}
 
+	if err := snapstore.ValidateRecordClusterMetadata(c.snapstoreConfig.Provider, c.snapshotterConfig); err != nil {
+		return err
+	}
+
+	if err := snapstore.ValidateStorageBudget(c.snapstoreConfig.Provider, c.snapshotterConfig); err != nil {
+		return err
+	}
+
 	if err := c.compressionConfig.Validate(); err != nil {
 		return err
 	}
 
+	if err := snapstore.ValidateCompressionPolicy(c.snapstoreConfig.Provider, c.compressionConfig); err != nil {
+		return err
+	}
+
 	if err := c.exponentialBackoffConfig.Validate(); err != nil {
 		return err
 	}
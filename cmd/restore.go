@@ -36,7 +36,7 @@ func NewRestoreCommand(ctx context.Context) *cobra.Command {
 			if err != nil {
 				logger.Fatalf("failed to create restorer object: %v", err)
 			}
-			if err := rs.RestoreAndStopEtcd(*options, nil); err != nil {
+			if err := rs.RestoreAndStopEtcd(ctx, *options, nil); err != nil {
 				logger.Fatalf("Failed to restore snapshot: %v", err)
 				return
 			}
@@ -28,6 +28,9 @@ from previously taken snapshot.`,
 	RootCmd.Flags().BoolVarP(&version, "version", "v", false, "print version info")
 	RootCmd.AddCommand(NewSnapshotCommand(ctx),
 		NewRestoreCommand(ctx),
+		NewVerifyRestoreCommand(ctx),
+		NewVerifyCredentialsCommand(ctx),
+		NewFollowCommand(ctx),
 		NewCompactCommand(ctx),
 		NewInitializeCommand(ctx),
 		NewServerCommand(ctx),
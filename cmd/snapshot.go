@@ -39,6 +39,9 @@ storing snapshots on various cloud storage providers as well as local disk locat
 			if err != nil {
 				logger.Fatalf("Failed to create snapstore from configured storage provider: %v", err)
 			}
+			if err := snapstore.VerifySnapstore(ss, opts.snapstoreConfig); err != nil {
+				logger.Fatalf("Failed to verify snapstore: %v", err)
+			}
 
 			ssr, err := snapshotter.NewSnapshotter(logger, opts.snapshotterConfig, ss, opts.etcdConnectionConfig, opts.compressionConfig, brtypes.NewHealthConfig(), opts.snapstoreConfig)
 			if err != nil {
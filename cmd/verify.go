@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gardener/etcd-backup-restore/pkg/snapshot/restorer"
+	"github.com/gardener/etcd-backup-restore/pkg/snapstore"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// NewVerifyRestoreCommand returns the command to verify that the latest snapshots are restorable
+func NewVerifyRestoreCommand(ctx context.Context) *cobra.Command {
+	opts := newVerifyRestoreOptions()
+	verifyRestoreCmd := &cobra.Command{
+		Use:   "verify-restore",
+		Short: "verifies that the latest snapshots are restorable",
+		Long:  "Restores the latest snapshots into a throwaway directory next to the etcd data directory to verify that they are restorable, without touching the data directory itself.",
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := logrus.New()
+
+			options, store, err := BuildRestoreOptionsAndStore(opts.restorerOptions)
+			if err != nil {
+				return
+			}
+
+			rs, err := restorer.NewRestorer(store, logrus.NewEntry(logger))
+			if err != nil {
+				logger.Fatalf("failed to create restorer object: %v", err)
+			}
+			report := rs.VerifyRestore(ctx, *options, opts.startEmbeddedEtcd)
+			data, err := json.Marshal(report)
+			if err != nil {
+				logger.Fatalf("failed to marshal the verification report: %v", err)
+			}
+			if !report.Passed {
+				logger.Fatalf("Verification of the restoration failed: %s", data)
+			}
+			logger.Infof("Successfully verified the restoration: %s", data)
+		},
+	}
+
+	opts.addFlags(verifyRestoreCmd.Flags())
+	return verifyRestoreCmd
+}
+
+// NewVerifyCredentialsCommand returns the command to verify the credentials of the snapstore without taking a snapshot
+func NewVerifyCredentialsCommand(ctx context.Context) *cobra.Command {
+	opts := newCredentialsVerifierOptions()
+	verifyCredentialsCmd := &cobra.Command{
+		Use:   "verify-credentials",
+		Short: "verifies the credentials of the snapstore",
+		Long:  "Reads the bucket or container of the snapstore to verify that its credentials are accepted by the storage provider, without writing to the snapstore or taking a snapshot.",
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := logrus.New()
+			if err := opts.validate(); err != nil {
+				logger.Fatalf("failed to validate the options: %v", err)
+			}
+
+			opts.complete()
+
+			result := snapstore.VerifyCredentials(opts.snapstoreConfig)
+			if !result.Passed {
+				logger.Fatalf("Verification of the credentials of storage provider %s failed with %s error: %v", opts.snapstoreConfig.Provider, result.Failure, result.Err)
+			}
+			logger.Infof("Successfully verified the credentials of storage provider %s", opts.snapstoreConfig.Provider)
+		},
+	}
+
+	opts.addFlags(verifyCredentialsCmd.Flags())
+	return verifyCredentialsCmd
+}
@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path"
 	"time"
 
 	"github.com/gardener/etcd-backup-restore/pkg/compressor"
@@ -15,6 +16,8 @@ import (
 	"github.com/gardener/etcd-backup-restore/pkg/health/heartbeat"
 	"github.com/gardener/etcd-backup-restore/pkg/miscellaneous"
 	"github.com/gardener/etcd-backup-restore/pkg/snapshot/restorer"
+	"github.com/gardener/etcd-backup-restore/pkg/snapstore"
+	"github.com/gardener/etcd-backup-restore/pkg/tracing"
 	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
 	"go.etcd.io/etcd/clientv3"
 
@@ -60,6 +63,8 @@ func (cp *Compactor) Compact(ctx context.Context, opts *brtypes.CompactOptions)
 
 	// Deepcopy restoration options ro to avoid any mutation of the passing object
 	compactorRestoreOptions := opts.RestoreOptions.DeepCopy()
+	// The restoration for the compaction must not overwrite the report of the restorations of the data directory.
+	compactorRestoreOptions.Config.RestoreReportPath = ""
 
 	// If no base snapshot is found, abort compaction as there would be nothing to compact
 	if compactorRestoreOptions.BaseSnapshot == nil {
@@ -67,6 +72,12 @@ func (cp *Compactor) Compact(ctx context.Context, opts *brtypes.CompactOptions)
 		return nil, fmt.Errorf("no base snapshot found. Nothing is available for compaction")
 	}
 
+	// The compacted snapshot is taken with the snapshot API of the embedded etcd, whose revisions do not match the
+	// revisions of the snapshots scoped to a key prefix.
+	if compactorRestoreOptions.Config.RestoreKeyPrefix != "" {
+		return nil, fmt.Errorf("snapshots scoped to the key prefix %s cannot be compacted", compactorRestoreOptions.Config.RestoreKeyPrefix)
+	}
+
 	cp.logger.Infof("Creating temporary etcd directory %s for restoration.", compactorRestoreOptions.Config.DataDir)
 	err := os.MkdirAll(compactorRestoreOptions.Config.DataDir, 0700)
 	if err != nil {
@@ -85,7 +96,7 @@ func (cp *Compactor) Compact(ctx context.Context, opts *brtypes.CompactOptions)
 	if err != nil {
 		return nil, err
 	}
-	embeddedEtcd, err := r.Restore(*compactorRestoreOptions, nil)
+	embeddedEtcd, err := r.Restore(ctx, *compactorRestoreOptions, nil)
 	if err != nil {
 		return nil, fmt.Errorf("unable to restore snapshots during compaction: %v", err)
 	}
@@ -137,7 +148,10 @@ func (cp *Compactor) Compact(ctx context.Context, opts *brtypes.CompactOptions)
 	// Compact
 	// Please refer below issue for why physical compaction was necessary
 	// https://github.com/gardener/etcd-backup-restore/issues/451
-	if _, err := clientKV.Compact(ctx, etcdRevision, clientv3.WithCompactPhysical()); err != nil {
+	compactCtx, span := tracing.Start(ctx, tracing.SpanCompact, tracing.Int64(tracing.AttributeLastRevision, etcdRevision))
+	_, err = clientKV.Compact(compactCtx, etcdRevision, clientv3.WithCompactPhysical())
+	tracing.End(span, err)
+	if err != nil {
 		return nil, fmt.Errorf("failed to compact: %v", err)
 	}
 
@@ -149,7 +163,9 @@ func (cp *Compactor) Compact(ctx context.Context, opts *brtypes.CompactOptions)
 		}
 		defer client.Close()
 
-		err = etcdutil.DefragmentData(ctx, clientMaintenance, client, ep, opts.DefragTimeout.Duration, cp.logger)
+		defragCtx, span := tracing.Start(ctx, tracing.SpanDefragment)
+		err = etcdutil.DefragmentData(defragCtx, clientMaintenance, client, ep, opts.DefragTimeout.Duration, cp.logger)
+		tracing.End(span, err)
 		if err != nil {
 			cp.logger.Errorf("failed to defragment: %v", err)
 		}
@@ -182,7 +198,7 @@ func (cp *Compactor) Compact(ctx context.Context, opts *brtypes.CompactOptions)
 	if opts.EnabledLeaseRenewal {
 		// Update revisions in holder identity of full snapshot lease.
 		ctx, cancel := context.WithTimeout(ctx, brtypes.LeaseUpdateTimeoutDuration)
-		if err := heartbeat.FullSnapshotCaseLeaseUpdate(ctx, cp.logger, snapshot, cp.k8sClientset, opts.FullSnapshotLeaseName); err != nil {
+		if err := heartbeat.FullSnapshotCaseLeaseUpdate(ctx, cp.logger, snapshot, cp.k8sClientset, opts.SnapshotLeaseNamespace, opts.FullSnapshotLeaseName); err != nil {
 			cp.logger.Warnf("Snapshot lease update failed : %v", err)
 		}
 		cancel()
@@ -197,6 +213,49 @@ func (cp *Compactor) Compact(ctx context.Context, opts *brtypes.CompactOptions)
 	return snapshot, nil
 }
 
+// CompactChain shortens the restoration chain of the given base snapshot and its delta snapshots, without loading the
+// live etcd: the chain is replayed offline into a temporary etcd, which is then compacted and exported as a new base
+// snapshot, after which the delta snapshots consumed by the new base snapshot are deleted from the snapstore.
+// Delta snapshots which are retained or belong to the chain of a running restoration are kept.
+func (cp *Compactor) CompactChain(ctx context.Context, opts *brtypes.CompactOptions) (*brtypes.Snapshot, error) {
+	if opts.RestoreOptions.BaseSnapshot != nil && len(opts.RestoreOptions.DeltaSnapList) == 0 {
+		return nil, fmt.Errorf("no delta snapshots found after base snapshot %s. Nothing is available for compaction", opts.RestoreOptions.BaseSnapshot.SnapName)
+	}
+	// Capture the chain before compacting, so that only the delta snapshots replayed into the new base are deleted.
+	deltaSnapList := append(brtypes.SnapList{}, opts.RestoreOptions.DeltaSnapList...)
+
+	snapshot, err := cp.Compact(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	cp.logger.Infof("Deleting the delta snapshots compacted into base snapshot %s", snapshot.SnapName)
+	for _, snap := range deltaSnapList {
+		snapPath := path.Join(snap.SnapDir, snap.SnapName)
+		if snap.LastRevision > snapshot.LastRevision {
+			cp.logger.Warnf("Retaining delta snapshot %s which is newer than the compacted base snapshot", snapPath)
+			continue
+		}
+		if restorer.IsSnapshotInRestore(snap) {
+			cp.logger.Infof("Retaining delta snapshot %s which is being restored", snapPath)
+			continue
+		}
+		retained, err := snapstore.IsSnapshotRetained(cp.store, *snap)
+		if err != nil {
+			cp.logger.Warnf("Failed to check whether delta snapshot %s is retained, hence retaining it: %v", snapPath, err)
+			continue
+		}
+		if retained {
+			cp.logger.Infof("Retaining delta snapshot %s tagged with %s", snapPath, brtypes.SnapshotExcludeTag)
+			continue
+		}
+		if err := cp.store.Delete(*snap); err != nil {
+			return snapshot, fmt.Errorf("failed to delete compacted delta snapshot %s: %v", snapPath, err)
+		}
+	}
+	return snapshot, nil
+}
+
 func sleepWithContext(ctx context.Context, sleepFor time.Duration) error {
 	for {
 		select {
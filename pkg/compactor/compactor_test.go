@@ -19,6 +19,7 @@ import (
 	"github.com/gardener/etcd-backup-restore/test/utils"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"go.etcd.io/etcd/clientv3"
 	"go.etcd.io/etcd/pkg/types"
 )
 
@@ -179,7 +180,7 @@ var _ = Describe("Running Compactor", func() {
 				restorer, err := restorer.NewRestorer(store, logger)
 				Expect(err).ShouldNot(HaveOccurred())
 
-				err = restorer.RestoreAndStopEtcd(*restoreOpts, nil)
+				err = restorer.RestoreAndStopEtcd(testCtx, *restoreOpts, nil)
 
 				Expect(err).ShouldNot(HaveOccurred())
 				err = utils.CheckDataConsistency(testCtx, restoreOpts.Config.DataDir, keyTo, logger)
@@ -256,13 +257,105 @@ var _ = Describe("Running Compactor", func() {
 				restorer, err := restorer.NewRestorer(store, logger)
 				Expect(err).ShouldNot(HaveOccurred())
 
-				err = restorer.RestoreAndStopEtcd(*restoreOpts, nil)
+				err = restorer.RestoreAndStopEtcd(testCtx, *restoreOpts, nil)
 
 				Expect(err).ShouldNot(HaveOccurred())
 				err = utils.CheckDataConsistency(testCtx, restoreOpts.Config.DataDir, keyTo, logger)
 				Expect(err).ShouldNot(HaveOccurred())
 			})
 		})
+		Context("compacting the chain of delta snapshots", func() {
+			var chainStore brtypes.SnapStore
+
+			BeforeEach(func() {
+				// The delta snapshots are deleted by the compaction of the chain, hence it runs on a copy of the backup.
+				chainDir, err := os.MkdirTemp(testSuiteDir, "chain-snapshotter.bkp-")
+				Expect(err).ShouldNot(HaveOccurred())
+				chainStore, err = snapstore.GetSnapstore(&brtypes.SnapstoreConfig{Container: chainDir, Provider: "Local"})
+				Expect(err).ShouldNot(HaveOccurred())
+
+				baseSnapshot, deltaSnapList, err := miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(store)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(deltaSnapList).ShouldNot(BeEmpty())
+				for _, snap := range append(brtypes.SnapList{baseSnapshot}, deltaSnapList...) {
+					rc, err := store.Fetch(*snap)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(chainStore.Save(*snap, rc)).To(Succeed())
+				}
+				cptr = compactor.NewCompactor(chainStore, logger, nil)
+			})
+
+			It("should write a synthetic base snapshot which restores to the same state as the base and delta snapshots", func() {
+				baseSnapshot, deltaSnapList, err := miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(chainStore)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				// Restore the base and delta snapshots
+				chainRestoreDir, err := os.MkdirTemp(testSuiteDir, "restore-chain-test-")
+				Expect(err).ShouldNot(HaveOccurred())
+				defer os.RemoveAll(chainRestoreDir)
+				chainRestoreOpts := *restoreOpts
+				chainRestoreConfig := *restoreOpts.Config
+				chainRestoreConfig.DataDir = chainRestoreDir
+				chainRestoreOpts.Config = &chainRestoreConfig
+				chainRestoreOpts.BaseSnapshot = baseSnapshot
+				chainRestoreOpts.DeltaSnapList = deltaSnapList
+				rstr, err := restorer.NewRestorer(chainStore, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(rstr.RestoreAndStopEtcd(testCtx, chainRestoreOpts, nil)).To(Succeed())
+				chainKVs, chainRevision, err := readKVs(chainRestoreDir)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				restoreOpts.BaseSnapshot = baseSnapshot
+				restoreOpts.DeltaSnapList = deltaSnapList
+				syntheticSnapshot, err := cptr.CompactChain(testCtx, compactOptions)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(syntheticSnapshot.Kind).To(Equal(brtypes.SnapshotKindFull))
+				Expect(syntheticSnapshot.LastRevision).To(Equal(deltaSnapList[len(deltaSnapList)-1].LastRevision))
+
+				// The consumed delta snapshots are deleted, leaving the synthetic base snapshot as the restoration chain
+				latestSnapshot, latestDeltaSnapList, err := miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(chainStore)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(latestSnapshot.SnapName).To(Equal(syntheticSnapshot.SnapName))
+				Expect(latestDeltaSnapList).To(BeEmpty())
+				snapList, err := chainStore.List()
+				Expect(err).ShouldNot(HaveOccurred())
+				for _, snap := range snapList {
+					Expect(snap.Kind).To(Equal(brtypes.SnapshotKindFull))
+				}
+
+				// Restore the synthetic base snapshot
+				tempRestoreDir, err = os.MkdirTemp(testSuiteDir, "restore-synthetic-test-")
+				Expect(err).ShouldNot(HaveOccurred())
+				defer os.RemoveAll(tempRestoreDir)
+				restoreOpts.Config.DataDir = tempRestoreDir
+				restoreOpts.BaseSnapshot = latestSnapshot
+				restoreOpts.DeltaSnapList = latestDeltaSnapList
+				rstr, err = restorer.NewRestorer(chainStore, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(rstr.RestoreAndStopEtcd(testCtx, *restoreOpts, nil)).To(Succeed())
+				syntheticKVs, syntheticRevision, err := readKVs(tempRestoreDir)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				Expect(syntheticRevision).To(Equal(chainRevision))
+				Expect(syntheticKVs).To(Equal(chainKVs))
+				Expect(utils.CheckDataConsistency(testCtx, tempRestoreDir, keyTo, logger)).To(Succeed())
+			})
+
+			It("should not compact a base snapshot without delta snapshots", func() {
+				baseSnapshot, _, err := miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(chainStore)
+				Expect(err).ShouldNot(HaveOccurred())
+				snapList, err := chainStore.List()
+				Expect(err).ShouldNot(HaveOccurred())
+
+				restoreOpts.BaseSnapshot = baseSnapshot
+				restoreOpts.DeltaSnapList = brtypes.SnapList{}
+				_, err = cptr.CompactChain(testCtx, compactOptions)
+				Expect(err).Should(HaveOccurred())
+
+				// No snapshot is written or deleted
+				Expect(chainStore.List()).To(HaveLen(len(snapList)))
+			})
+		})
 		Context("with no base snapshot in backup store", func() {
 			It("should not run compaction", func() {
 				restoreOpts.Config.MaxFetchers = 4
@@ -282,3 +375,33 @@ var _ = Describe("Running Compactor", func() {
 		})
 	})
 })
+
+// readKVs returns the values of all the keys, along with the revision, of the etcd restored into the given directory.
+func readKVs(dir string) (map[string]string, int64, error) {
+	etcd, err := utils.StartEmbeddedEtcd(testCtx, dir, logger, utils.DefaultEtcdName, utils.EmbeddedEtcdPortNo)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		etcd.Server.Stop()
+		etcd.Close()
+	}()
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{etcd.Clients[0].Addr().String()},
+		DialTimeout: 10 * time.Second,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cli.Close()
+
+	resp, err := cli.Get(testCtx, "", clientv3.WithFromKey())
+	if err != nil {
+		return nil, 0, err
+	}
+	kvs := make(map[string]string, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		kvs[string(kv.Key)] = string(kv.Value)
+	}
+	return kvs, resp.Header.Revision, nil
+}
@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package compressor
+
+import (
+	"compress/flate"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// MinAdaptiveCompressionLevel is the lowest compression level the adaptive compression level backs off to.
+	MinAdaptiveCompressionLevel = flate.BestSpeed
+	// MaxAdaptiveCompressionLevel is the highest compression level the adaptive compression level raises to.
+	MaxAdaptiveCompressionLevel = flate.BestCompression
+	// InitialAdaptiveCompressionLevel is the compression level the adaptive compression level starts at, which is the
+	// level of the default compression of gzip and zlib.
+	InitialAdaptiveCompressionLevel = 6
+	// AdaptiveCompressionLevelWindow is the number of recent compressions whose average duration is compared with the
+	// time budget to adjust the adaptive compression level.
+	AdaptiveCompressionLevelWindow = 3
+	// AdaptiveCompressionLevelHeadroom is the fraction of the time budget below which the recent compressions have
+	// to stay for the adaptive compression level to be raised.
+	AdaptiveCompressionLevelHeadroom = 0.5
+	// AdaptiveCompressionMinSampleSize is the minimum size of the data of a compression to be recorded by the adaptive
+	// compression level, as the duration of compressing less data is dominated by setting up the compressor.
+	AdaptiveCompressionMinSampleSize = 64 * 1024
+
+	mib = 1024 * 1024
+)
+
+// AdaptiveLevel tunes the compression level of the gzip, zlib and zlib-dict compression policies to a time budget
+// for compressing a MiB of data. The level is lowered while the recent compressions exceed the time budget, e.g. as
+// the CPU is constrained, and raised again while they leave enough headroom.
+type AdaptiveLevel struct {
+	budget   time.Duration
+	level    int
+	window   []time.Duration
+	onChange func(level int)
+	mutex    sync.Mutex
+}
+
+// NewAdaptiveLevel returns an adaptive compression level starting at InitialAdaptiveCompressionLevel, which is tuned
+// to the given time budget per MiB of data. The given function, if any, is called with the new level whenever the
+// level changes.
+func NewAdaptiveLevel(budget time.Duration, onChange func(level int)) *AdaptiveLevel {
+	return &AdaptiveLevel{
+		budget:   budget,
+		level:    InitialAdaptiveCompressionLevel,
+		onChange: onChange,
+	}
+}
+
+// Level returns the current compression level.
+func (a *AdaptiveLevel) Level() int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.level
+}
+
+// Record records the time taken to compress the given number of bytes at the current level, and adjusts the level
+// once the average time per MiB of the recent compressions is above the time budget, or well below it.
+func (a *AdaptiveLevel) Record(uncompressedBytes int64, duration time.Duration) {
+	if uncompressedBytes < AdaptiveCompressionMinSampleSize {
+		return
+	}
+
+	a.mutex.Lock()
+	a.window = append(a.window, time.Duration(float64(duration)*mib/float64(uncompressedBytes)))
+	if len(a.window) < AdaptiveCompressionLevelWindow {
+		a.mutex.Unlock()
+		return
+	}
+	var total time.Duration
+	for _, d := range a.window {
+		total += d
+	}
+	average := total / time.Duration(len(a.window))
+	a.window = a.window[1:]
+
+	level := a.level
+	if average > a.budget && level > MinAdaptiveCompressionLevel {
+		level--
+	} else if float64(average) < float64(a.budget)*AdaptiveCompressionLevelHeadroom && level < MaxAdaptiveCompressionLevel {
+		level++
+	}
+	changed := level != a.level
+	if changed {
+		// the compressions at the previous level say little about the new level
+		a.level = level
+		a.window = nil
+	}
+	a.mutex.Unlock()
+
+	if changed && a.onChange != nil {
+		a.onChange(level)
+	}
+}
+
+// supportsCompressionLevel returns whether the data is compressed at a compression level with the given compression
+// policy. The lzw compression policy has no compression level.
+func supportsCompressionLevel(compressionPolicy string) bool {
+	return compressionPolicy == GzipCompressionPolicy || compressionPolicy == ZlibCompressionPolicy || compressionPolicy == ZlibDictCompressionPolicy
+}
+
+// timedWriter measures the time spent writing to and closing the underlying writer.
+type timedWriter struct {
+	w       io.Writer
+	elapsed time.Duration
+}
+
+func (t *timedWriter) Write(p []byte) (int, error) {
+	start := time.Now()
+	n, err := t.w.Write(p)
+	t.elapsed += time.Since(start)
+	return n, err
+}
+
+func (t *timedWriter) Close() error {
+	c, ok := t.w.(io.Closer)
+	if !ok {
+		return nil
+	}
+	start := time.Now()
+	err := c.Close()
+	t.elapsed += time.Since(start)
+	return err
+}
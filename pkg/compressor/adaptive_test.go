@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package compressor_test
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	. "github.com/gardener/etcd-backup-restore/pkg/compressor"
+	"github.com/gardener/etcd-backup-restore/pkg/wrappers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Adaptive compression level", func() {
+	const (
+		budget = 100 * time.Millisecond
+		mib    = 1024 * 1024
+	)
+
+	var (
+		level   *AdaptiveLevel
+		changes []int
+	)
+
+	BeforeEach(func() {
+		changes = nil
+		level = NewAdaptiveLevel(budget, func(l int) {
+			changes = append(changes, l)
+		})
+	})
+
+	// recordCompressions records the given number of compressions of a MiB which each took the given duration.
+	recordCompressions := func(count int, duration time.Duration) {
+		for i := 0; i < count; i++ {
+			level.Record(mib, duration)
+		}
+	}
+
+	It("should start at the initial compression level", func() {
+		Expect(level.Level()).To(Equal(InitialAdaptiveCompressionLevel))
+	})
+
+	It("should lower the compression level while the compressions are slower than the time budget", func() {
+		recordCompressions(AdaptiveCompressionLevelWindow-1, 3*budget)
+		Expect(level.Level()).To(Equal(InitialAdaptiveCompressionLevel))
+
+		recordCompressions(1, 3*budget)
+		Expect(level.Level()).To(Equal(InitialAdaptiveCompressionLevel - 1))
+		Expect(changes).To(Equal([]int{InitialAdaptiveCompressionLevel - 1}))
+
+		recordCompressions(10*AdaptiveCompressionLevelWindow, 3*budget)
+		Expect(level.Level()).To(Equal(MinAdaptiveCompressionLevel))
+	})
+
+	It("should raise the compression level while the compressions leave enough headroom in the time budget", func() {
+		recordCompressions(AdaptiveCompressionLevelWindow, budget/10)
+		Expect(level.Level()).To(Equal(InitialAdaptiveCompressionLevel + 1))
+
+		recordCompressions(10*AdaptiveCompressionLevelWindow, budget/10)
+		Expect(level.Level()).To(Equal(MaxAdaptiveCompressionLevel))
+	})
+
+	It("should keep the compression level while the compressions are within the time budget", func() {
+		recordCompressions(10*AdaptiveCompressionLevelWindow, 3*budget/4)
+		Expect(level.Level()).To(Equal(InitialAdaptiveCompressionLevel))
+		Expect(changes).To(BeEmpty())
+	})
+
+	It("should normalize the duration of the compressions to the size of the compressed data", func() {
+		// compressing 8MiB within 4 times the time budget per MiB is within the time budget
+		for i := 0; i < AdaptiveCompressionLevelWindow; i++ {
+			level.Record(8*mib, 4*budget)
+		}
+		Expect(level.Level()).To(Equal(InitialAdaptiveCompressionLevel))
+	})
+
+	It("should ignore the compressions of too little data", func() {
+		for i := 0; i < 10*AdaptiveCompressionLevelWindow; i++ {
+			level.Record(AdaptiveCompressionMinSampleSize-1, time.Hour)
+		}
+		Expect(level.Level()).To(Equal(InitialAdaptiveCompressionLevel))
+	})
+
+	Context("compressing the snapshots", func() {
+		DescribeTable("should compress at the current level and record the compression",
+			func(policy string, recorded bool) {
+				// a time budget which can't be met lowers the level as soon as the compressions are recorded
+				level = NewAdaptiveLevel(time.Nanosecond, nil)
+				data := compressibleData(0)
+				for i := 0; i < AdaptiveCompressionLevelWindow; i++ {
+					rc, err := CompressSnapshotAdaptively(io.NopCloser(bytes.NewReader(data)), policy, nil, level)
+					Expect(err).ShouldNot(HaveOccurred())
+					compressed, err := io.ReadAll(rc)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(rc.Close()).To(Succeed())
+
+					rc, err = DecompressSnapshot(io.NopCloser(bytes.NewReader(compressed)), policy)
+					Expect(err).ShouldNot(HaveOccurred())
+					decompressed, err := io.ReadAll(rc)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(decompressed).To(Equal(data))
+				}
+				// the compression is recorded once the compressed data has been read completely
+				if recorded {
+					Eventually(level.Level).Should(Equal(InitialAdaptiveCompressionLevel - 1))
+				} else {
+					Consistently(level.Level, 100*time.Millisecond).Should(Equal(InitialAdaptiveCompressionLevel))
+				}
+			},
+			Entry("with gzip", GzipCompressionPolicy, true),
+			Entry("with zlib", ZlibCompressionPolicy, true),
+			Entry("with zlib-dict", ZlibDictCompressionPolicy, true),
+			Entry("with lzw, which has no compression level", LzwCompressionPolicy, false),
+		)
+	})
+
+	It("should reject a negative compression time budget", func() {
+		config := &CompressionConfig{
+			Enabled:               true,
+			CompressionPolicy:     GzipCompressionPolicy,
+			CompressionTimeBudget: wrappers.Duration{Duration: -time.Second},
+		}
+		Expect(config.Validate()).NotTo(Succeed())
+	})
+})
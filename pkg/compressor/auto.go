@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package compressor
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+)
+
+// autoCompressionCandidates are the compression policies sampled by the auto compression policy,
+// in order of preference when they perform equally well.
+var autoCompressionCandidates = []string{GzipCompressionPolicy, ZlibCompressionPolicy, LzwCompressionPolicy}
+
+// AutoPolicySelector selects the compression policy best suited for the data of a cluster. It samples the
+// compression ratio and CPU time of all available compression policies on the first few delta snapshots and
+// then locks in the best performing policy for the rest of the session.
+type AutoPolicySelector struct {
+	sampleCount       int
+	samplesTaken      int
+	uncompressedBytes int64
+	stats             map[string]*policyStats
+	policy            string
+	decided           bool
+	mutex             sync.Mutex
+}
+
+// policyStats holds the accumulated results of sampling a compression policy.
+type policyStats struct {
+	compressedBytes int64
+	duration        time.Duration
+}
+
+// NewAutoPolicySelector returns a selector which locks in a compression policy after sampling sampleCount snapshots.
+func NewAutoPolicySelector(sampleCount int) *AutoPolicySelector {
+	if sampleCount <= 0 {
+		sampleCount = DefaultAutoCompressionSampleCount
+	}
+	stats := make(map[string]*policyStats, len(autoCompressionCandidates))
+	for _, policy := range autoCompressionCandidates {
+		stats[policy] = &policyStats{}
+	}
+	return &AutoPolicySelector{
+		sampleCount: sampleCount,
+		stats:       stats,
+		policy:      DefaultCompressionPolicy,
+	}
+}
+
+// Select samples the given snapshot data if the compression policy is not yet locked in, and returns the
+// compression policy to compress the data with, along with whether the policy has been locked in.
+// An empty compression policy means that the data is not worth compressing.
+func (s *AutoPolicySelector) Select(data []byte) (string, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.decided || len(data) == 0 {
+		return s.policy, s.decided, nil
+	}
+
+	for _, policy := range autoCompressionCandidates {
+		compressedBytes, duration, err := measureCompression(data, policy)
+		if err != nil {
+			return "", false, err
+		}
+		s.stats[policy].compressedBytes += compressedBytes
+		s.stats[policy].duration += duration
+	}
+	s.uncompressedBytes += int64(len(data))
+	s.samplesTaken++
+
+	s.policy = s.bestPolicy()
+	s.decided = s.samplesTaken >= s.sampleCount
+	return s.policy, s.decided, nil
+}
+
+// Policy returns the currently selected compression policy, along with whether the policy has been locked in.
+func (s *AutoPolicySelector) Policy() (string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.policy, s.decided
+}
+
+// bestPolicy returns the fastest compression policy among those whose compressed size is close to the
+// smallest compressed size, or an empty policy if none of the policies saves enough bytes.
+func (s *AutoPolicySelector) bestPolicy() string {
+	var minCompressedBytes int64 = -1
+	for _, policy := range autoCompressionCandidates {
+		if minCompressedBytes < 0 || s.stats[policy].compressedBytes < minCompressedBytes {
+			minCompressedBytes = s.stats[policy].compressedBytes
+		}
+	}
+
+	if float64(minCompressedBytes) > float64(s.uncompressedBytes)*(1-AutoCompressionMinSavingsRatio) {
+		return ""
+	}
+
+	best := ""
+	for _, policy := range autoCompressionCandidates {
+		stats := s.stats[policy]
+		if float64(stats.compressedBytes) > float64(minCompressedBytes)*(1+AutoCompressionSizeTolerance) {
+			continue
+		}
+		if best == "" || stats.duration < s.stats[best].duration {
+			best = policy
+		}
+	}
+	return best
+}
+
+// measureCompression compresses the data with the given compression policy and
+// returns the size of the compressed data along with the time taken to compress it.
+func measureCompression(data []byte, compressionPolicy string) (int64, time.Duration, error) {
+	counter := &countingWriter{}
+	startTime := time.Now()
+	w, err := NewCompressionWriter(counter, compressionPolicy)
+	if err != nil {
+		return 0, 0, err
+	}
+	if _, err := io.Copy(w, bytes.NewReader(data)); err != nil {
+		return 0, 0, err
+	}
+	if err := w.Close(); err != nil {
+		return 0, 0, err
+	}
+	return counter.n, time.Since(startTime), nil
+}
+
+// countingWriter discards the data written to it and counts the number of bytes written.
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
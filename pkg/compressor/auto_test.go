@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package compressor_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"testing"
+
+	. "github.com/gardener/etcd-backup-restore/pkg/compressor"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const sampleSize = 256 * 1024
+
+var _ = Describe("Auto compression policy", func() {
+	var selector *AutoPolicySelector
+
+	BeforeEach(func() {
+		selector = NewAutoPolicySelector(DefaultAutoCompressionSampleCount)
+	})
+
+	It("should be accepted as a valid compression policy", func() {
+		config := &CompressionConfig{Enabled: true, CompressionPolicy: AutoCompressionPolicy}
+		Expect(config.Validate()).To(Succeed())
+	})
+
+	It("should use the default compression policy before any snapshot is sampled", func() {
+		policy, decided := selector.Policy()
+		Expect(policy).To(Equal(DefaultCompressionPolicy))
+		Expect(decided).To(BeFalse())
+	})
+
+	Context("with compressible data", func() {
+		It("should lock in a deflate based compression policy after sampling the configured number of snapshots", func() {
+			for i := 0; i < DefaultAutoCompressionSampleCount-1; i++ {
+				_, decided, err := selector.Select(compressibleData(i))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(decided).To(BeFalse())
+			}
+
+			policy, decided, err := selector.Select(compressibleData(DefaultAutoCompressionSampleCount))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(decided).To(BeTrue())
+			Expect(policy).To(BeElementOf(GzipCompressionPolicy, ZlibCompressionPolicy))
+
+			// the policy stays locked in even if the data characteristics change afterwards
+			lockedPolicy, decided, err := selector.Select(incompressibleData())
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(decided).To(BeTrue())
+			Expect(lockedPolicy).To(Equal(policy))
+		})
+	})
+
+	Context("with incompressible data", func() {
+		It("should lock in storing the snapshots uncompressed", func() {
+			var (
+				policy  string
+				decided bool
+				err     error
+			)
+			for i := 0; i < DefaultAutoCompressionSampleCount; i++ {
+				policy, decided, err = selector.Select(incompressibleData())
+				Expect(err).ShouldNot(HaveOccurred())
+			}
+			Expect(decided).To(BeTrue())
+			Expect(policy).To(BeEmpty())
+
+			policy, decided = selector.Policy()
+			Expect(decided).To(BeTrue())
+			Expect(policy).To(BeEmpty())
+		})
+	})
+
+	It("should not count empty snapshots as samples", func() {
+		for i := 0; i < DefaultAutoCompressionSampleCount; i++ {
+			_, decided, err := selector.Select(nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(decided).To(BeFalse())
+		}
+	})
+})
+
+// BenchmarkCompressionPolicies measures the compression of synthetic delta snapshot data with each compression policy.
+func BenchmarkCompressionPolicies(b *testing.B) {
+	for _, policy := range []string{GzipCompressionPolicy, ZlibCompressionPolicy, LzwCompressionPolicy} {
+		for name, data := range map[string][]byte{"compressible": compressibleData(0), "incompressible": incompressibleData()} {
+			b.Run(fmt.Sprintf("%s/%s", policy, name), func(b *testing.B) {
+				b.SetBytes(int64(len(data)))
+				for i := 0; i < b.N; i++ {
+					rc, err := CompressSnapshot(io.NopCloser(bytes.NewReader(data)), policy)
+					if err != nil {
+						b.Fatal(err)
+					}
+					compressed, err := io.ReadAll(rc)
+					if err != nil {
+						b.Fatal(err)
+					}
+					b.ReportMetric(float64(len(compressed))/float64(len(data)), "ratio")
+				}
+			})
+		}
+	}
+}
+
+// compressibleData returns delta snapshot like data consisting of etcd events with similar keys and values.
+func compressibleData(seed int) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i := 0; buf.Len() < sampleSize; i++ {
+		fmt.Fprintf(&buf, `{"etcdEvent":{"type":0,"kv":{"key":"/registry/pods/default/pod-%d-%d","create_revision":%d,"mod_revision":%d,"version":1,"value":"running"}},"time":"2024-01-01T00:00:00Z"},`, seed, i, i+1, i+1)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
+
+// incompressibleData returns random data which cannot be compressed.
+func incompressibleData() []byte {
+	data := make([]byte, sampleSize)
+	if _, err := rand.Read(data); err != nil {
+		panic(err)
+	}
+	return data
+}
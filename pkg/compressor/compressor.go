@@ -17,36 +17,41 @@ import (
 // CompressSnapshot takes uncompressed data as input and compress the data according to Compression Policy
 // and write the compressed data into one end of pipe.
 func CompressSnapshot(data io.ReadCloser, compressionPolicy string) (io.ReadCloser, error) {
+	return compressSnapshot(data, compressionPolicy, func(w io.Writer) (io.WriteCloser, error) {
+		return NewCompressionWriter(w, compressionPolicy)
+	}, nil)
+}
+
+// compressSnapshot compresses the data with the compression writer returned by newWriter and writes the compressed
+// data into one end of pipe. If an adaptive compression level is given, the time spent compressing the data, without
+// the time spent waiting for the compressed data to be read, is recorded with it once the data is compressed.
+func compressSnapshot(data io.ReadCloser, compressionPolicy string, newWriter func(io.Writer) (io.WriteCloser, error), level *AdaptiveLevel) (io.ReadCloser, error) {
 	pReader, pWriter := io.Pipe()
 
-	var gWriter io.WriteCloser
 	logger := logrus.New().WithField("actor", "compressor")
 	logger.Infof("start compressing the snapshot using %v Compression Policy", compressionPolicy)
 
-	switch compressionPolicy {
-	case GzipCompressionPolicy:
-		gWriter = gzip.NewWriter(pWriter)
-
-	case LzwCompressionPolicy:
-		gWriter = lzw.NewWriter(pWriter, lzw.LSB, LzwLiteralWidth)
-
-	case ZlibCompressionPolicy:
-		gWriter = zlib.NewWriter(pWriter)
-
-	// It is actually unreachable but just to be on safe side:
-	// for unsupported CompressionPolicy return the error
-	default:
-		return nil, fmt.Errorf("unsupported Compression Policy")
-
+	sink := &timedWriter{w: pWriter}
+	gWriter, err := newWriter(sink)
+	if err != nil {
+		return nil, err
 	}
+	compression := &timedWriter{w: gWriter}
 
 	go func() {
 		var err error
 		var n int64
 		defer pWriter.CloseWithError(err)
-		defer gWriter.Close()
+		if level != nil {
+			defer func() {
+				if err == nil {
+					level.Record(n, compression.elapsed-sink.elapsed)
+				}
+			}()
+		}
+		defer compression.Close()
 		defer data.Close()
-		n, err = io.Copy(gWriter, data)
+		n, err = io.Copy(compression, data)
 		if err != nil {
 			logger.Errorf("compression failed: %v", err)
 			return
@@ -57,8 +62,53 @@ func CompressSnapshot(data io.ReadCloser, compressionPolicy string) (io.ReadClos
 	return pReader, nil
 }
 
+// NewCompressionWriter returns a writer which compresses the data written to it according to
+// the compression policy and writes the compressed data to the given writer.
+func NewCompressionWriter(w io.Writer, compressionPolicy string) (io.WriteCloser, error) {
+	switch compressionPolicy {
+	case GzipCompressionPolicy:
+		return gzip.NewWriter(w), nil
+
+	case LzwCompressionPolicy:
+		return lzw.NewWriter(w, lzw.LSB, LzwLiteralWidth), nil
+
+	case ZlibCompressionPolicy:
+		return zlib.NewWriter(w), nil
+
+	case ZlibDictCompressionPolicy:
+		return NewDictionaryCompressionWriter(w, nil)
+
+	// It is actually unreachable but just to be on safe side:
+	// for unsupported CompressionPolicy return the error
+	default:
+		return nil, fmt.Errorf("unsupported Compression Policy")
+	}
+}
+
+// NewCompressionWriterWithLevel returns a writer like NewCompressionWriterWithDictionary, which compresses the data at
+// the given compression level if the compression policy is gzip, zlib or zlib-dict. The lzw compression policy has
+// no compression level.
+func NewCompressionWriterWithLevel(w io.Writer, compressionPolicy string, dictionary []byte, level int) (io.WriteCloser, error) {
+	switch compressionPolicy {
+	case GzipCompressionPolicy:
+		return gzip.NewWriterLevel(w, level)
+
+	case ZlibCompressionPolicy:
+		return zlib.NewWriterLevel(w, level)
+
+	case ZlibDictCompressionPolicy:
+		// the data is compressed without a dictionary if the dictionary is empty
+		return zlib.NewWriterLevelDict(w, level, dictionary)
+
+	default:
+		return NewCompressionWriterWithDictionary(w, compressionPolicy, dictionary)
+	}
+}
+
 // DecompressSnapshot take compressed data and compressionPolicy as input and
 // it decompresses the data according to compression Policy and return uncompressed data.
+// The data is decompressed as it is read from the returned reader, so that the memory used doesn't grow with the size
+// of the snapshot.
 func DecompressSnapshot(data io.ReadCloser, compressionPolicy string) (io.ReadCloser, error) {
 	var deCompressedData io.ReadCloser
 	var err error
@@ -67,7 +117,8 @@ func DecompressSnapshot(data io.ReadCloser, compressionPolicy string) (io.ReadCl
 	logger.Infof("start decompressing the snapshot with %v compressionPolicy", compressionPolicy)
 
 	switch compressionPolicy {
-	case ZlibCompressionPolicy:
+	case ZlibCompressionPolicy, ZlibDictCompressionPolicy:
+		// a snapshot compressed with a dictionary fails with zlib.ErrDictionary, see DecompressSnapshotWithDictionary
 		deCompressedData, err = zlib.NewReader(data)
 		if err != nil {
 			logger.Errorf("unable to decompress: %v", err)
@@ -105,6 +156,9 @@ func GetCompressionSuffix(compressionEnabled bool, compressionPolicy string) (st
 	case ZlibCompressionPolicy:
 		return ZlibCompressionExtension, nil
 
+	case ZlibDictCompressionPolicy:
+		return ZlibDictCompressionExtension, nil
+
 	case LzwCompressionPolicy:
 		return LzwCompressionExtension, nil
 
@@ -127,6 +181,9 @@ func IsSnapshotCompressed(compressionSuffix string) (bool, string, error) {
 	case ZlibCompressionExtension:
 		return true, ZlibCompressionPolicy, nil
 
+	case ZlibDictCompressionExtension:
+		return true, ZlibDictCompressionPolicy, nil
+
 	case GzipCompressionExtension:
 		return true, GzipCompressionPolicy, nil
 
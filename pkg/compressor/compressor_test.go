@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package compressor_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"testing"
+
+	. "github.com/gardener/etcd-backup-restore/pkg/compressor"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Decompressing snapshots", func() {
+	It("should decompress the snapshots to their original data", func() {
+		data := snapshotData(1 << 20)
+		for _, policy := range []string{GzipCompressionPolicy, ZlibCompressionPolicy, LzwCompressionPolicy} {
+			compressed, err := compressData(data, policy)
+			Expect(err).ShouldNot(HaveOccurred())
+			rc, err := DecompressSnapshot(io.NopCloser(bytes.NewReader(compressed)), policy)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(io.ReadAll(rc)).To(Equal(data))
+		}
+	})
+
+	It("should decompress a large snapshot as a stream within a bounded amount of memory", func() {
+		const (
+			size = 64 << 20
+			// maxAllocatedBytes bounds the memory allocated while decompressing, far below the size of the snapshot.
+			maxAllocatedBytes = 4 << 20
+		)
+		data := snapshotData(size)
+		for _, policy := range []string{GzipCompressionPolicy, ZlibCompressionPolicy, LzwCompressionPolicy} {
+			compressed, err := compressData(data, policy)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			rc, err := DecompressSnapshot(io.NopCloser(bytes.NewReader(compressed)), policy)
+			Expect(err).ShouldNot(HaveOccurred())
+			n, err := io.Copy(io.Discard, rc)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rc.Close()).To(Succeed())
+			runtime.ReadMemStats(&after)
+
+			Expect(n).To(Equal(int64(size)))
+			Expect(after.TotalAlloc-before.TotalAlloc).To(BeNumerically("<", maxAllocatedBytes), "policy %s", policy)
+		}
+	})
+})
+
+// BenchmarkDecompressSnapshot measures the decompression of full snapshots of increasing sizes with each compression
+// policy. The allocated bytes per operation stay the same across the sizes, as the snapshots are decompressed as a stream.
+func BenchmarkDecompressSnapshot(b *testing.B) {
+	for _, policy := range []string{GzipCompressionPolicy, ZlibCompressionPolicy, LzwCompressionPolicy} {
+		for _, size := range []int{1 << 20, 16 << 20, 64 << 20} {
+			data := snapshotData(size)
+			compressed, err := compressData(data, policy)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.Run(fmt.Sprintf("%s/%dMiB", policy, size>>20), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					rc, err := DecompressSnapshot(io.NopCloser(bytes.NewReader(compressed)), policy)
+					if err != nil {
+						b.Fatal(err)
+					}
+					if _, err := io.Copy(io.Discard, rc); err != nil {
+						b.Fatal(err)
+					}
+					rc.Close()
+				}
+			})
+		}
+	}
+}
+
+// snapshotData returns full snapshot like data of the given size, made of repeated etcd keys and values.
+func snapshotData(size int) []byte {
+	var buf bytes.Buffer
+	buf.Grow(size)
+	for i := 0; buf.Len() < size; i++ {
+		fmt.Fprintf(&buf, "/registry/configmaps/namespace-%d/configmap-%d{\"data\":{\"revision\":\"%d\"}}", i%100, i, i)
+	}
+	return buf.Bytes()[:size]
+}
+
+// compressData returns the given data compressed with the given compression policy.
+func compressData(data []byte, policy string) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := NewCompressionWriter(&buf, policy)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
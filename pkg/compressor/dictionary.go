@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package compressor
+
+import (
+	"bufio"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/adler32"
+	"io"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// zlibHeaderSize is the size of the zlib header, followed by the identifier of the preset dictionary if any.
+	zlibHeaderSize = 2
+	// zlibDictFlag is the flag of the zlib header which is set if the data was compressed with a preset dictionary.
+	zlibDictFlag = 0x20
+)
+
+// ErrFetchDictionary is returned by DecompressSnapshotWithDictionary if the dictionary of the snapshot can't be fetched.
+var ErrFetchDictionary = errors.New("failed to fetch compression dictionary")
+
+// DictionaryFetcher returns the compression dictionary with the given identifier.
+type DictionaryFetcher func(id uint32) ([]byte, error)
+
+// DictionaryID returns the identifier of the given compression dictionary, which is its Adler-32 checksum as recorded
+// by zlib in the header of the data compressed with it.
+func DictionaryID(dictionary []byte) uint32 {
+	return adler32.Checksum(dictionary)
+}
+
+// TrainDictionary returns a compression dictionary for the zlib-dict compression policy, made of the given samples of
+// snapshot data, such as the events of the previous delta snapshots. As the delta snapshots of a cluster share most of
+// their structure, the dictionary lets even small delta snapshots refer to the data of earlier ones. The samples are
+// expected from the oldest to the latest, and the latest ones are placed at the end of the dictionary, where they are
+// the cheapest to refer to, while the oldest data is dropped if the samples exceed MaxDictionarySize.
+// Returns nil if there are no samples.
+func TrainDictionary(samples [][]byte) []byte {
+	var size int
+	first := len(samples)
+	for first > 0 && size < MaxDictionarySize {
+		first--
+		size += len(samples[first])
+	}
+	if size == 0 {
+		return nil
+	}
+	dictionary := make([]byte, 0, size)
+	for _, sample := range samples[first:] {
+		dictionary = append(dictionary, sample...)
+	}
+	if len(dictionary) > MaxDictionarySize {
+		dictionary = append([]byte(nil), dictionary[len(dictionary)-MaxDictionarySize:]...)
+	}
+	return dictionary
+}
+
+// NewDictionaryCompressionWriter returns a writer which compresses the data written to it with zlib, using the given
+// preset dictionary, and writes the compressed data to the given writer. The data is compressed without a dictionary
+// if the given dictionary is empty.
+func NewDictionaryCompressionWriter(w io.Writer, dictionary []byte) (io.WriteCloser, error) {
+	if len(dictionary) == 0 {
+		return zlib.NewWriter(w), nil
+	}
+	return zlib.NewWriterLevelDict(w, zlib.DefaultCompression, dictionary)
+}
+
+// NewCompressionWriterWithDictionary returns a writer like NewCompressionWriter, which compresses the data with the
+// given dictionary if the compression policy is zlib-dict. The dictionary is ignored by the other compression policies.
+func NewCompressionWriterWithDictionary(w io.Writer, compressionPolicy string, dictionary []byte) (io.WriteCloser, error) {
+	if compressionPolicy == ZlibDictCompressionPolicy {
+		return NewDictionaryCompressionWriter(w, dictionary)
+	}
+	return NewCompressionWriter(w, compressionPolicy)
+}
+
+// CompressSnapshotWithDictionary compresses the data like CompressSnapshot, with the given dictionary if the
+// compression policy is zlib-dict.
+func CompressSnapshotWithDictionary(data io.ReadCloser, compressionPolicy string, dictionary []byte) (io.ReadCloser, error) {
+	return compressSnapshot(data, compressionPolicy, func(w io.Writer) (io.WriteCloser, error) {
+		return NewCompressionWriterWithDictionary(w, compressionPolicy, dictionary)
+	}, nil)
+}
+
+// CompressSnapshotAdaptively compresses the data like CompressSnapshotWithDictionary, at the current level of the given
+// adaptive compression level, and records the time taken to compress the data with it. The data is compressed like
+// CompressSnapshotWithDictionary if no adaptive compression level is given, or the compression policy has no level.
+func CompressSnapshotAdaptively(data io.ReadCloser, compressionPolicy string, dictionary []byte, level *AdaptiveLevel) (io.ReadCloser, error) {
+	if level == nil || !supportsCompressionLevel(compressionPolicy) {
+		return CompressSnapshotWithDictionary(data, compressionPolicy, dictionary)
+	}
+	compressionLevel := level.Level()
+	return compressSnapshot(data, compressionPolicy, func(w io.Writer) (io.WriteCloser, error) {
+		return NewCompressionWriterWithLevel(w, compressionPolicy, dictionary, compressionLevel)
+	}, level)
+}
+
+// DecompressSnapshotWithDictionary decompresses the data like DecompressSnapshot. If the compression policy is
+// zlib-dict and the data was compressed with a dictionary, the dictionary is fetched with the given fetcher by the
+// identifier recorded in the zlib header, so that the same dictionary is used to decompress the data.
+func DecompressSnapshotWithDictionary(data io.ReadCloser, compressionPolicy string, fetchDictionary DictionaryFetcher) (io.ReadCloser, error) {
+	if compressionPolicy != ZlibDictCompressionPolicy {
+		return DecompressSnapshot(data, compressionPolicy)
+	}
+
+	logger := logrus.New().WithField("actor", "de-compressor")
+	logger.Infof("start decompressing the snapshot with %v compressionPolicy", compressionPolicy)
+
+	r := bufio.NewReader(data)
+	var dictionary []byte
+	// a malformed header is left to be reported by zlib
+	if header, err := r.Peek(zlibHeaderSize + 4); err == nil {
+		if id, ok := dictionaryIDFromHeader(header); ok {
+			if dictionary, err = fetchDictionary(id); err != nil {
+				return data, fmt.Errorf("%w %08x: %v", ErrFetchDictionary, id, err)
+			}
+		}
+	}
+	deCompressedData, err := zlib.NewReaderDict(r, dictionary)
+	if err != nil {
+		logger.Errorf("unable to decompress: %v", err)
+		return data, err
+	}
+	return deCompressedData, nil
+}
+
+// ReadDictionaryID returns the identifier of the dictionary which the zlib-dict compressed data read from the given
+// reader was compressed with, as recorded in its zlib header, and false if the data was compressed without one.
+// Only the zlib header is read.
+func ReadDictionaryID(r io.Reader) (uint32, bool, error) {
+	// any zlib stream is longer than its header along with the identifier of a preset dictionary
+	header := make([]byte, zlibHeaderSize+4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, false, fmt.Errorf("failed to read the zlib header: %w", err)
+	}
+	id, ok := dictionaryIDFromHeader(header)
+	return id, ok, nil
+}
+
+// dictionaryIDFromHeader returns the identifier of the preset dictionary recorded in the given zlib header, and false
+// if the data was compressed without a preset dictionary.
+func dictionaryIDFromHeader(header []byte) (uint32, bool) {
+	if header[1]&zlibDictFlag == 0 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(header[zlibHeaderSize:]), true
+}
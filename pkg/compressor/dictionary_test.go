@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package compressor_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	. "github.com/gardener/etcd-backup-restore/pkg/compressor"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Compressing with a dictionary", func() {
+	var samples [][]byte
+
+	BeforeEach(func() {
+		samples = nil
+		for revision := 1; revision <= 100; revision++ {
+			samples = append(samples, deltaEvents(revision))
+		}
+	})
+
+	// compress returns the given data compressed with the zlib-dict compression policy and the given dictionary.
+	compress := func(data, dictionary []byte) []byte {
+		rc, err := CompressSnapshotWithDictionary(io.NopCloser(bytes.NewReader(data)), ZlibDictCompressionPolicy, dictionary)
+		Expect(err).ShouldNot(HaveOccurred())
+		defer rc.Close()
+		compressed, err := io.ReadAll(rc)
+		Expect(err).ShouldNot(HaveOccurred())
+		return compressed
+	}
+
+	// decompress returns the given data decompressed with the zlib-dict compression policy, along with the
+	// identifiers of the dictionaries fetched for it from the given dictionaries.
+	decompress := func(data []byte, dictionaries map[uint32][]byte) ([]byte, []uint32, error) {
+		var fetched []uint32
+		rc, err := DecompressSnapshotWithDictionary(io.NopCloser(bytes.NewReader(data)), ZlibDictCompressionPolicy, func(id uint32) ([]byte, error) {
+			fetched = append(fetched, id)
+			dictionary, ok := dictionaries[id]
+			if !ok {
+				return nil, fmt.Errorf("dictionary not found")
+			}
+			return dictionary, nil
+		})
+		if err != nil {
+			return nil, fetched, err
+		}
+		defer rc.Close()
+		decompressed, err := io.ReadAll(rc)
+		return decompressed, fetched, err
+	}
+
+	It("should be accepted as a valid compression policy", func() {
+		config := &CompressionConfig{Enabled: true, CompressionPolicy: ZlibDictCompressionPolicy}
+		Expect(config.Validate()).To(Succeed())
+		suffix, err := GetCompressionSuffix(true, ZlibDictCompressionPolicy)
+		Expect(err).ShouldNot(HaveOccurred())
+		isCompressed, policy, err := IsSnapshotCompressed(suffix)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(isCompressed).To(BeTrue())
+		Expect(policy).To(Equal(ZlibDictCompressionPolicy))
+	})
+
+	It("should train the dictionary from the latest samples which fit into it", func() {
+		Expect(TrainDictionary(nil)).To(BeNil())
+
+		dictionary := TrainDictionary(samples)
+		Expect(dictionary).To(HaveLen(MaxDictionarySize))
+		Expect(dictionary).To(HaveSuffix(string(samples[len(samples)-1])))
+
+		large := bytes.Repeat([]byte("x"), 2*MaxDictionarySize)
+		Expect(TrainDictionary([][]byte{large})).To(Equal(large[:MaxDictionarySize]))
+	})
+
+	It("should compress small delta snapshots better with a dictionary trained from the previous ones", func() {
+		dictionary := TrainDictionary(samples)
+		delta := deltaEvents(101)
+
+		withoutDictionary := compress(delta, nil)
+		withDictionary := compress(delta, dictionary)
+		Expect(len(withDictionary)).To(BeNumerically("<", len(withoutDictionary)/2))
+	})
+
+	It("should decompress the delta snapshots with the dictionary recorded in the zlib header", func() {
+		dictionary := TrainDictionary(samples)
+		delta := deltaEvents(101)
+
+		decompressed, fetched, err := decompress(compress(delta, dictionary), map[uint32][]byte{DictionaryID(dictionary): dictionary})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(decompressed).To(Equal(delta))
+		Expect(fetched).To(Equal([]uint32{DictionaryID(dictionary)}))
+	})
+
+	It("should read the identifier of the dictionary from the zlib header", func() {
+		dictionary := TrainDictionary(samples)
+		delta := deltaEvents(101)
+
+		id, ok, err := ReadDictionaryID(bytes.NewReader(compress(delta, dictionary)))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(id).To(Equal(DictionaryID(dictionary)))
+
+		_, ok, err = ReadDictionaryID(bytes.NewReader(compress(delta, nil)))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should decompress the snapshots compressed without a dictionary without fetching one", func() {
+		delta := deltaEvents(101)
+
+		decompressed, fetched, err := decompress(compress(delta, nil), nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(decompressed).To(Equal(delta))
+		Expect(fetched).To(BeEmpty())
+
+		// the full snapshots are compressed without a dictionary, hence they don't need one to be decompressed
+		rc, err := CompressSnapshot(io.NopCloser(bytes.NewReader(delta)), ZlibDictCompressionPolicy)
+		Expect(err).ShouldNot(HaveOccurred())
+		compressed, err := io.ReadAll(rc)
+		Expect(err).ShouldNot(HaveOccurred())
+		rc, err = DecompressSnapshot(io.NopCloser(bytes.NewReader(compressed)), ZlibDictCompressionPolicy)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(io.ReadAll(rc)).To(Equal(delta))
+	})
+
+	It("should fail to decompress the delta snapshots without their dictionary", func() {
+		dictionary := TrainDictionary(samples)
+		compressed := compress(deltaEvents(101), dictionary)
+
+		_, fetched, err := decompress(compressed, nil)
+		Expect(err).Should(MatchError(ErrFetchDictionary))
+		Expect(fetched).To(Equal([]uint32{DictionaryID(dictionary)}))
+
+		_, err = DecompressSnapshot(io.NopCloser(bytes.NewReader(compressed)), ZlibDictCompressionPolicy)
+		Expect(err).Should(MatchError(zlib.ErrDictionary))
+	})
+})
+
+// deltaEvents returns the events of a small delta snapshot as marshaled by the snapshotter, which update a pod
+// at the given revision.
+func deltaEvents(revision int) []byte {
+	key := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("/registry/pods/default/web-%d", revision%5)))
+	value := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(`{"kind":"Pod","apiVersion":"v1","metadata":{"name":"web-%d","namespace":"default","resourceVersion":"%d","labels":{"app":"web"}},"status":{"phase":"Running"}}`, revision%5, revision)))
+	return []byte(fmt.Sprintf(`[{"etcdEvent":{"kv":{"key":"%s","create_revision":%d,"mod_revision":%d,"version":%d,"value":"%s"}},"time":"2024-06-01T12:%02d:00Z"}]`,
+		key, revision%5+1, revision, revision/5+1, value, revision%60))
+}
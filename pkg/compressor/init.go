@@ -22,7 +22,9 @@ func NewCompressorConfig() *CompressionConfig {
 func (c *CompressionConfig) AddFlags(fs *flag.FlagSet) {
 
 	fs.BoolVar(&c.Enabled, "compress-snapshots", c.Enabled, "whether to compress the snapshots or not")
-	fs.StringVar(&c.CompressionPolicy, "compression-policy", c.CompressionPolicy, "Policy for compressing the snapshots")
+	fs.StringVar(&c.CompressionPolicy, "compression-policy", c.CompressionPolicy, "Policy for compressing the snapshots, one of gzip, lzw, zlib, zlib-dict or auto. With zlib-dict, the delta snapshots are compressed with zlib using a dictionary trained from the previous delta snapshots, which is stored in the snapstore and only supported by the Local and S3 compatible storage providers")
+	fs.IntVar(&c.MinCompressionSize, "min-compression-size", c.MinCompressionSize, "minimum size in bytes of the events of a delta snapshot for it to be compressed. Smaller delta snapshots are stored uncompressed, as compressing them saves little or even makes them larger. If this value is set to be lesser than 1, all the delta snapshots are compressed.")
+	fs.DurationVar(&c.CompressionTimeBudget.Duration, "compression-time-budget", c.CompressionTimeBudget.Duration, "time budget for compressing a MiB of snapshot data with the gzip, zlib or zlib-dict compression policy. The compression level is lowered while the recent snapshots take longer to compress, e.g. on CPU constrained nodes, and raised again while they take less than half of it. If this value is set to 0, the snapshots are compressed at the default compression level.")
 }
 
 // Validate validates the compression Config.
@@ -33,7 +35,11 @@ func (c *CompressionConfig) Validate() error {
 		return nil
 	}
 
-	for _, policy := range []string{GzipCompressionPolicy, ZlibCompressionPolicy, LzwCompressionPolicy} {
+	if c.CompressionTimeBudget.Duration < 0 {
+		return fmt.Errorf("compression time budget must not be negative")
+	}
+
+	for _, policy := range []string{GzipCompressionPolicy, ZlibCompressionPolicy, ZlibDictCompressionPolicy, LzwCompressionPolicy, AutoCompressionPolicy} {
 		if c.CompressionPolicy == policy {
 			return nil
 		}
@@ -4,6 +4,10 @@
 
 package compressor
 
+import (
+	"github.com/gardener/etcd-backup-restore/pkg/wrappers"
+)
+
 const (
 
 	// GzipCompressionPolicy is constant for gzip compression algorithm.
@@ -12,6 +16,11 @@ const (
 	LzwCompressionPolicy = "lzw"
 	// ZlibCompressionPolicy is constant for zlib compression algorithm.
 	ZlibCompressionPolicy = "zlib"
+	// ZlibDictCompressionPolicy is constant for zlib compression algorithm, compressing the delta snapshots with a
+	// preset dictionary trained from the previous delta snapshots.
+	ZlibDictCompressionPolicy = "zlib-dict"
+	// AutoCompressionPolicy is constant for selecting the compression algorithm by sampling the delta snapshots.
+	AutoCompressionPolicy = "auto"
 
 	// DefaultCompression is constant used for whether to compress the snapshots or not.
 	DefaultCompression = false
@@ -26,14 +35,36 @@ const (
 	LzwCompressionExtension = ".Z"
 	// ZlibCompressionExtension is used for snapshot suffix when compressionPolicy is zlib.
 	ZlibCompressionExtension = ".zlib"
+	// ZlibDictCompressionExtension is used for snapshot suffix when compressionPolicy is zlib-dict.
+	ZlibDictCompressionExtension = ".zlibd"
 	// Reference: https://en.wikipedia.org/wiki/List_of_archive_formats
 
 	// LzwLiteralWidth is constant used as literal Width in lzw compressionPolicy.
 	LzwLiteralWidth = 8 //[2,8]
+
+	// MaxDictionarySize is the maximum size of a compression dictionary, which is the size of the deflate window
+	// as only the last 32KiB of a preset dictionary can be referred to.
+	MaxDictionarySize = 32 * 1024
+
+	// DefaultAutoCompressionSampleCount is the number of delta snapshots sampled by the auto compression policy
+	// before locking in a compression algorithm.
+	DefaultAutoCompressionSampleCount = 3
+	// AutoCompressionMinSavingsRatio is the minimum fraction of bytes a compression algorithm has to save
+	// for the auto compression policy to compress the snapshots at all.
+	AutoCompressionMinSavingsRatio = 0.1
+	// AutoCompressionSizeTolerance is the fraction by which the compressed size of an algorithm may exceed the
+	// smallest compressed size and still be selected by the auto compression policy, if it is faster.
+	AutoCompressionSizeTolerance = 0.05
 )
 
 // CompressionConfig holds the compression configuration.
 type CompressionConfig struct {
-	Enabled           bool   `json:"enabled"`
-	CompressionPolicy string `json:"policy,omitempty"`
+	Enabled            bool   `json:"enabled"`
+	CompressionPolicy  string `json:"policy,omitempty"`
+	MinCompressionSize int    `json:"minCompressionSize,omitempty"`
+	// CompressionTimeBudget is the time budget for compressing a MiB of snapshot data, to which the compression level
+	// of the gzip, zlib and zlib-dict compression policies is tuned. The compression level is not tuned if 0.
+	CompressionTimeBudget wrappers.Duration `json:"compressionTimeBudget,omitempty"`
+	// AdaptiveLevel is the adaptive compression level the snapshots are compressed at, if any.
+	AdaptiveLevel *AdaptiveLevel `json:"-"`
 }
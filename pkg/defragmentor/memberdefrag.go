@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package defragmentor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gardener/etcd-backup-restore/pkg/etcdutil"
+	"github.com/gardener/etcd-backup-restore/pkg/etcdutil/client"
+	"github.com/gardener/etcd-backup-restore/pkg/metrics"
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+
+	"github.com/prometheus/client_golang/prometheus"
+	cron "github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+	"go.etcd.io/etcd/clientv3"
+)
+
+var (
+	// ErrMemberIsLearner is returned if the defragmentation of the local member is skipped because it is a learner.
+	ErrMemberIsLearner = errors.New("etcd member is a learner")
+	// ErrMemberUnhealthy is returned if the defragmentation of the local member is skipped because it is unhealthy.
+	ErrMemberUnhealthy = errors.New("etcd member is unhealthy")
+)
+
+// memberDefragmentorJob implement the cron.Job for the defragmentation of the local etcd member.
+type memberDefragmentorJob struct {
+	ctx                  context.Context
+	etcdConnectionConfig *brtypes.EtcdConnectionConfig
+	logger               *logrus.Entry
+}
+
+// NewMemberDefragmentorJob returns the new job defragmenting the local etcd member.
+func NewMemberDefragmentorJob(ctx context.Context, etcdConnectionConfig *brtypes.EtcdConnectionConfig, logger *logrus.Entry) cron.Job {
+	return &memberDefragmentorJob{
+		ctx:                  ctx,
+		etcdConnectionConfig: etcdConnectionConfig,
+		logger:               logger.WithField("job", "member-defragmentor"),
+	}
+}
+
+func (d *memberDefragmentorJob) Run() {
+	if len(d.etcdConnectionConfig.Endpoints) == 0 {
+		d.logger.Warnf("No endpoint of the local etcd member to defragment")
+		return
+	}
+	clientMaintenance, err := etcdutil.NewFactory(*d.etcdConnectionConfig).NewMaintenance()
+	if err != nil {
+		d.logger.Warnf("failed to create etcd maintenance client: %v", err)
+		return
+	}
+	defer clientMaintenance.Close()
+
+	err = DefragmentMember(d.ctx, clientMaintenance, d.etcdConnectionConfig.Endpoints[0], d.etcdConnectionConfig.ConnectionTimeout.Duration, d.etcdConnectionConfig.DefragTimeout.Duration, d.logger)
+	if errors.Is(err, ErrMemberIsLearner) || errors.Is(err, ErrMemberUnhealthy) {
+		d.logger.Infof("Skipping the scheduled defragmentation: %v", err)
+	} else if err != nil {
+		d.logger.Warnf("failed to defragment the local etcd member: %v", err)
+	}
+}
+
+// DefragmentMember defragments the etcd member at the given endpoint, and records the duration of the defragmentation
+// and the bytes it reclaimed. The defragmentation is skipped with ErrMemberIsLearner if the member is a learner, as a
+// learner catching up with the leader should not be blocked, and with ErrMemberUnhealthy if the member does not respond
+// to the status request, has an active alarm or has no leader.
+func DefragmentMember(ctx context.Context, clientMaintenance client.MaintenanceCloser, endpoint string, connectionTimeout, defragTimeout time.Duration, logger *logrus.Entry) error {
+	statusCtx, cancel := context.WithTimeout(ctx, connectionTimeout)
+	status, err := clientMaintenance.Status(statusCtx, endpoint)
+	cancel()
+	if err := checkMemberStatus(endpoint, status, err); err != nil {
+		reason := metrics.ValueSkipReasonUnhealthy
+		if errors.Is(err, ErrMemberIsLearner) {
+			reason = metrics.ValueSkipReasonLearner
+		}
+		metrics.MemberDefragmentationSkippedTotal.With(prometheus.Labels{metrics.LabelSkipReason: reason}).Inc()
+		return err
+	}
+
+	logger.Infof("Defragmenting etcd member[%s] with database size %dB, of which %dB are in use", endpoint, status.DbSize, status.DbSizeInUse)
+	defragCtx, cancel := context.WithTimeout(ctx, defragTimeout)
+	defer cancel()
+	start := time.Now()
+	if _, err := clientMaintenance.Defragment(defragCtx, endpoint); err != nil {
+		metrics.MemberDefragmentationDurationSeconds.With(prometheus.Labels{metrics.LabelSucceeded: metrics.ValueSucceededFalse}).Observe(time.Since(start).Seconds())
+		return fmt.Errorf("failed to defragment etcd member[%s]: %v", endpoint, err)
+	}
+	metrics.MemberDefragmentationDurationSeconds.With(prometheus.Labels{metrics.LabelSucceeded: metrics.ValueSucceededTrue}).Observe(time.Since(start).Seconds())
+
+	// the status races with the writes to etcd, so the reclaimed bytes are approximate
+	statusAfterDefrag, err := clientMaintenance.Status(defragCtx, endpoint)
+	if err != nil {
+		logger.Warnf("Finished defragmenting etcd member[%s], but failed to get its status: %v", endpoint, err)
+		return nil
+	}
+	reclaimedBytes := status.DbSize - statusAfterDefrag.DbSize
+	if reclaimedBytes < 0 {
+		reclaimedBytes = 0
+	}
+	metrics.MemberDefragmentationReclaimedBytes.With(prometheus.Labels{}).Set(float64(reclaimedBytes))
+	logger.Infof("Finished defragmenting etcd member[%s] in %s, reclaiming %dB: %dB -> %dB", endpoint, time.Since(start), reclaimedBytes, status.DbSize, statusAfterDefrag.DbSize)
+	return nil
+}
+
+// checkMemberStatus checks whether the etcd member with the given status, or the given error of the status request,
+// can be defragmented.
+func checkMemberStatus(endpoint string, status *clientv3.StatusResponse, err error) error {
+	switch {
+	case err != nil:
+		return fmt.Errorf("%w: failed to get status of etcd member[%s]: %v", ErrMemberUnhealthy, endpoint, err)
+	case status.IsLearner:
+		return fmt.Errorf("%w: etcd member[%s] is not in sync with the leader yet", ErrMemberIsLearner, endpoint)
+	case len(status.Errors) > 0:
+		return fmt.Errorf("%w: etcd member[%s] reports errors: %s", ErrMemberUnhealthy, endpoint, strings.Join(status.Errors, "; "))
+	case status.Leader == 0:
+		return fmt.Errorf("%w: etcd member[%s] has no leader", ErrMemberUnhealthy, endpoint)
+	}
+	return nil
+}
+
+// ParseMemberDefragmentationSchedule parses the cron schedule of the defragmentation of the local etcd member. It
+// returns no schedule for an empty schedule, which disables the defragmentation of the local etcd member.
+func ParseMemberDefragmentationSchedule(schedule string) (cron.Schedule, error) {
+	if schedule == "" {
+		return nil, nil
+	}
+	s, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid member defragmentation schedule %q: %v", schedule, err)
+	}
+	return s, nil
+}
+
+// DefragMemberPeriodically defragments the data directory of the local etcd member on the given schedule, which is
+// independent of the schedule of the defragmentation of all the members of the etcd cluster.
+func DefragMemberPeriodically(ctx context.Context, etcdConnectionConfig *brtypes.EtcdConnectionConfig, memberDefragmentationSchedule cron.Schedule, logger *logrus.Entry) {
+	memberDefragmentorJob := NewMemberDefragmentorJob(ctx, etcdConnectionConfig, logger)
+	jobRunner := cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)))
+	jobRunner.Schedule(memberDefragmentationSchedule, memberDefragmentorJob)
+
+	jobRunner.Start()
+
+	<-ctx.Done()
+	logger.Info("Closing member defragmentor.")
+	jobRunnerCtx := jobRunner.Stop()
+	<-jobRunnerCtx.Done()
+}
@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package defragmentor_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gardener/etcd-backup-restore/pkg/metrics"
+	mockfactory "github.com/gardener/etcd-backup-restore/pkg/mock/etcdutil/client"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/etcdserver/etcdserverpb"
+
+	. "github.com/gardener/etcd-backup-restore/pkg/defragmentor"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Defragmenting the local member", func() {
+	const endpoint = "http://127.0.0.1:2379"
+
+	DescribeTable("parsing the member defragmentation schedule",
+		func(schedule string, expectSchedule, expectErr bool) {
+			s, err := ParseMemberDefragmentationSchedule(schedule)
+			if expectErr {
+				Expect(err).Should(MatchError(ContainSubstring("invalid member defragmentation schedule")))
+				return
+			}
+			Expect(err).ShouldNot(HaveOccurred())
+			if expectSchedule {
+				Expect(s).ShouldNot(BeNil())
+				next := s.Next(time.Date(2024, 5, 6, 12, 0, 0, 0, time.Local))
+				Expect(next).To(Equal(time.Date(2024, 5, 7, 3, 0, 0, 0, time.Local)))
+			} else {
+				Expect(s).Should(BeNil())
+			}
+		},
+		Entry("empty schedule disables the defragmentation", "", false, false),
+		Entry("valid schedule", "0 3 * * *", true, false),
+		Entry("schedule with too few fields", "0 3 * *", false, true),
+		Entry("schedule with an invalid field", "0 25 * * *", false, true),
+	)
+
+	Describe("with a mocked etcd member", func() {
+		var (
+			ctrl *gomock.Controller
+			cm   *mockfactory.MockMaintenanceCloser
+		)
+
+		BeforeEach(func() {
+			ctrl = gomock.NewController(GinkgoT())
+			cm = mockfactory.NewMockMaintenanceCloser(ctrl)
+		})
+
+		skippedTotal := func(reason string) float64 {
+			m := &dto.Metric{}
+			Expect(metrics.MemberDefragmentationSkippedTotal.With(prometheus.Labels{metrics.LabelSkipReason: reason}).Write(m)).To(Succeed())
+			return m.GetCounter().GetValue()
+		}
+
+		DescribeTable("should skip the defragmentation of an unhealthy member",
+			func(status *clientv3.StatusResponse, statusErr error, message string) {
+				skippedBefore := skippedTotal(metrics.ValueSkipReasonUnhealthy)
+				cm.EXPECT().Status(gomock.Any(), endpoint).Return(status, statusErr)
+				// the member must not be defragmented
+				cm.EXPECT().Defragment(gomock.Any(), gomock.Any()).Times(0)
+
+				err := DefragmentMember(testCtx, cm, endpoint, mockTimeout, mockTimeout, logger)
+				Expect(err).Should(MatchError(ErrMemberUnhealthy))
+				Expect(err).Should(MatchError(ContainSubstring(message)))
+				Expect(skippedTotal(metrics.ValueSkipReasonUnhealthy)).To(Equal(skippedBefore + 1))
+			},
+			Entry("status request fails", nil, fmt.Errorf("context deadline exceeded"), "failed to get status"),
+			Entry("member reports an alarm", &clientv3.StatusResponse{Leader: 1, Errors: []string{"memberID:1 alarm:NOSPACE "}}, nil, "alarm:NOSPACE"),
+			Entry("member has no leader", &clientv3.StatusResponse{Leader: 0}, nil, "has no leader"),
+		)
+
+		It("should skip the defragmentation of a learner", func() {
+			skippedBefore := skippedTotal(metrics.ValueSkipReasonLearner)
+			cm.EXPECT().Status(gomock.Any(), endpoint).Return(&clientv3.StatusResponse{Leader: 1, IsLearner: true}, nil)
+			cm.EXPECT().Defragment(gomock.Any(), gomock.Any()).Times(0)
+
+			err := DefragmentMember(testCtx, cm, endpoint, mockTimeout, mockTimeout, logger)
+			Expect(err).Should(MatchError(ErrMemberIsLearner))
+			Expect(skippedTotal(metrics.ValueSkipReasonLearner)).To(Equal(skippedBefore + 1))
+		})
+
+		It("should defragment a healthy member and record the reclaimed bytes", func() {
+			gomock.InOrder(
+				cm.EXPECT().Status(gomock.Any(), endpoint).Return(&clientv3.StatusResponse{Header: &etcdserverpb.ResponseHeader{MemberId: 1}, Leader: 1, DbSize: 1000, DbSizeInUse: 400}, nil),
+				cm.EXPECT().Defragment(gomock.Any(), endpoint).Return(&clientv3.DefragmentResponse{}, nil),
+				cm.EXPECT().Status(gomock.Any(), endpoint).Return(&clientv3.StatusResponse{Leader: 1, DbSize: 400, DbSizeInUse: 400}, nil),
+			)
+
+			Expect(DefragmentMember(testCtx, cm, endpoint, mockTimeout, mockTimeout, logger)).To(Succeed())
+			m := &dto.Metric{}
+			Expect(metrics.MemberDefragmentationReclaimedBytes.With(prometheus.Labels{}).Write(m)).To(Succeed())
+			Expect(m.GetGauge().GetValue()).To(Equal(float64(600)))
+		})
+
+		It("should return the error of a failed defragmentation", func() {
+			cm.EXPECT().Status(gomock.Any(), endpoint).Return(&clientv3.StatusResponse{Leader: 1, DbSize: 1000}, nil)
+			cm.EXPECT().Defragment(gomock.Any(), endpoint).Return(nil, fmt.Errorf("etcdserver: request timed out"))
+
+			err := DefragmentMember(testCtx, cm, endpoint, mockTimeout, mockTimeout, logger)
+			Expect(err).Should(MatchError(ContainSubstring("failed to defragment etcd member")))
+			Expect(err).ShouldNot(MatchError(ErrMemberUnhealthy))
+		})
+	})
+})
@@ -24,6 +24,16 @@ func (e *SnapstoreError) Error() string {
 	return e.Message
 }
 
+// SnapstoreCredentialError is struct to categorize errors occurred while reloading the snapstore access credentials
+type SnapstoreCredentialError struct {
+	Message   string
+	operation string
+}
+
+func (e *SnapstoreCredentialError) Error() string {
+	return e.Message
+}
+
 // IsErrNotNil checks whether err is nil or not and return boolean.
 func IsErrNotNil(err error) bool {
 	return err != nil
@@ -6,8 +6,15 @@ package etcdutil
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/json"
+	stderrors "errors"
 	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gardener/etcd-backup-restore/pkg/compressor"
@@ -20,6 +27,8 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/etcdserver/api/v3rpc/rpctypes"
+	"go.etcd.io/etcd/mvcc/mvccpb"
 	"go.etcd.io/etcd/pkg/transport"
 )
 
@@ -123,14 +132,60 @@ func GetTLSClientForEtcd(tlsConfig *brtypes.EtcdConnectionConfig, options *clien
 		cfg.TLS.InsecureSkipVerify = true
 	}
 
-	if tlsConfig.Username != "" && tlsConfig.Password != "" {
-		cfg.Username = tlsConfig.Username
-		cfg.Password = tlsConfig.Password
+	username, password := tlsConfig.Username, tlsConfig.Password
+	if tlsConfig.UsernameFile != "" {
+		data, err := os.ReadFile(tlsConfig.UsernameFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read etcd username file: %v", err)
+		}
+		username = strings.TrimSpace(string(data))
+	}
+	if tlsConfig.PasswordFile != "" {
+		data, err := os.ReadFile(tlsConfig.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read etcd password file: %v", err)
+		}
+		password = strings.TrimSpace(string(data))
+	}
+	if username != "" && password != "" {
+		cfg.Username = username
+		cfg.Password = password
 	}
 
 	return clientv3.New(*cfg)
 }
 
+// FileModTimeFunc returns the modification time of the given file.
+type FileModTimeFunc func(file string) (time.Time, error)
+
+// GetFileModTime returns the modification time of the given file.
+func GetFileModTime(file string) (time.Time, error) {
+	fileInfo, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return fileInfo.ModTime(), nil
+}
+
+// GetCredentialsModifiedTime returns the latest modification time of the credential files of the given etcd
+// connection config, as returned by the given FileModTimeFunc.
+func GetCredentialsModifiedTime(cfg *brtypes.EtcdConnectionConfig, modTimeFn FileModTimeFunc) (time.Time, error) {
+	if modTimeFn == nil {
+		modTimeFn = GetFileModTime
+	}
+	var latestModifiedTime time.Time
+	for _, file := range cfg.CredentialFiles() {
+		modifiedTime, err := modTimeFn(file)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to get the modification time of %s: %v", file, err)
+		}
+		if modifiedTime.After(latestModifiedTime) {
+			latestModifiedTime = modifiedTime
+		}
+	}
+	return latestModifiedTime, nil
+}
+
 // PerformDefragmentation defragment the data directory of each etcd member.
 func PerformDefragmentation(defragCtx context.Context, client client.MaintenanceCloser, endpoint string, logger *logrus.Entry) error {
 	var dbSizeBeforeDefrag, dbSizeAfterDefrag int64
@@ -265,9 +320,13 @@ func TakeAndSaveFullSnapshot(ctx context.Context, client client.MaintenanceClose
 	timeTaken := time.Since(startTime)
 	logger.Infof("Total time taken by Snapshot API: %f seconds.", timeTaken.Seconds())
 
+	// count the bytes of the snapshot before it is compressed, to record the size of the snapshot
+	counter := &countingReadCloser{ReadCloser: rc}
+	rc = counter
+
 	if cc.Enabled {
 		startTimeCompression := time.Now()
-		rc, err = compressor.CompressSnapshot(rc, cc.CompressionPolicy)
+		rc, err = compressor.CompressSnapshotAdaptively(rc, cc.CompressionPolicy, nil, cc.AdaptiveLevel)
 		if err != nil {
 			return nil, fmt.Errorf("unable to obtain reader for compressed file: %v", err)
 		}
@@ -278,8 +337,180 @@ func TakeAndSaveFullSnapshot(ctx context.Context, client client.MaintenanceClose
 
 	logger.Infof("Successfully opened snapshot reader on etcd")
 
-	// Then save the snapshot to the store.
-	snapshot := snapstore.NewSnapshot(brtypes.SnapshotKindFull, 0, lastRevision, suffix, isFinal)
+	snapshot, err := saveFullSnapshot(store, rc, lastRevision, suffix, isFinal, startTime, logger)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.SizeBytes = counter.count
+	return snapshot, nil
+}
+
+// TakeAndSaveKeyPrefixSnapshot takes a full snapshot of the keys under the given prefix by exporting them with ranged
+// gets, and saves it to the store. The exported keys are serialized as a list of put events followed by their sha256
+// hash, the same way as the events of a delta snapshot. The keys are exported at the given revision, or at the latest
+// revision if it is 0, and the snapshot is named after the revision at which they were exported. The keys are fetched
+// in pages of the given page size, or of the default page size if it is 0, and each page is streamed to the store as
+// soon as it is fetched, so that the keys are never held in memory all at once.
+func TakeAndSaveKeyPrefixSnapshot(ctx context.Context, clientKV client.KVCloser, store brtypes.SnapStore, keyPrefix string, revision, pageSize int64, cc *compressor.CompressionConfig, suffix string, isFinal bool, logger *logrus.Entry) (*brtypes.Snapshot, error) {
+	startTime := time.Now()
+	// the first page is fetched before the snapshot is saved, as the snapshot is named after its revision
+	pager := newKeyPrefixPager(clientKV, keyPrefix, revision, pageSize)
+	firstPage, err := pager.next(ctx)
+	if err != nil {
+		return nil, &errors.EtcdError{
+			Message: fmt.Sprintf("failed to export the keys under %s: %v", keyPrefix, err),
+		}
+	}
+
+	pr, pw := io.Pipe()
+	type exportResult struct {
+		keys int
+		err  error
+	}
+	exportCh := make(chan exportResult, 1)
+	go func() {
+		keys, err := writeKeyPrefixExport(ctx, pw, pager, firstPage, startTime)
+		pw.CloseWithError(err)
+		exportCh <- exportResult{keys: keys, err: err}
+	}()
+
+	// count the bytes of the export before it is compressed, to record the size of the snapshot
+	counter := &countingReadCloser{ReadCloser: pr}
+	rc := io.ReadCloser(counter)
+	if cc.Enabled {
+		rc, err = compressor.CompressSnapshotAdaptively(rc, cc.CompressionPolicy, nil, cc.AdaptiveLevel)
+		if err != nil {
+			pr.Close()
+			<-exportCh
+			return nil, fmt.Errorf("unable to obtain reader for compressed file: %v", err)
+		}
+	}
+	snapshot, err := saveFullSnapshot(store, rc, pager.revision, suffix, isFinal, startTime, logger)
+	rc.Close()
+	// the export is stopped if the snapshot could not be saved
+	pr.Close()
+	export := <-exportCh
+	if export.err != nil && !stderrors.Is(export.err, io.ErrClosedPipe) {
+		return nil, &errors.EtcdError{
+			Message: fmt.Sprintf("failed to export the keys under %s: %v", keyPrefix, export.err),
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("Exported %d keys under %s at revision %d in %f seconds.", export.keys, keyPrefix, pager.revision, time.Since(startTime).Seconds())
+	snapshot.SizeBytes = counter.count
+	return snapshot, nil
+}
+
+// writeKeyPrefixExport writes the keys of the given first page and of the remaining pages of the given pager to the
+// given writer as a JSON list of put events, followed by the sha256 hash of the list, and returns the number of keys.
+// The list is written exactly as if the events were marshalled all at once.
+func writeKeyPrefixExport(ctx context.Context, w io.Writer, pager *keyPrefixPager, page []*mvccpb.KeyValue, eventTime time.Time) (int, error) {
+	hash := sha256.New()
+	hw := io.MultiWriter(w, hash)
+	if _, err := io.WriteString(hw, "["); err != nil {
+		return 0, err
+	}
+	var keys int
+	for {
+		for _, kv := range page {
+			data, err := json.Marshal(brtypes.Event{
+				EtcdEvent: &clientv3.Event{Type: mvccpb.PUT, Kv: kv},
+				Time:      eventTime,
+			})
+			if err != nil {
+				return keys, fmt.Errorf("failed to marshal the exported key %s to json: %v", kv.Key, err)
+			}
+			if keys > 0 {
+				if _, err := io.WriteString(hw, ","); err != nil {
+					return keys, err
+				}
+			}
+			if _, err := hw.Write(data); err != nil {
+				return keys, err
+			}
+			keys++
+		}
+		if pager.done {
+			break
+		}
+		var err error
+		if page, err = pager.next(ctx); err != nil {
+			return keys, err
+		}
+	}
+	if _, err := io.WriteString(hw, "]"); err != nil {
+		return keys, err
+	}
+	_, err := w.Write(hash.Sum(nil))
+	return keys, err
+}
+
+// keyPrefixPager gets the keys under a prefix page by page, all at the same revision. The keys of the whole keyspace
+// are got if the prefix is empty.
+type keyPrefixPager struct {
+	clientKV client.KVCloser
+	key      string
+	rangeEnd string
+	// revision is the revision at which the keys are got, which is the revision of the first page if it is 0.
+	revision int64
+	pageSize int64
+	// done is set once the last page has been got.
+	done bool
+}
+
+// newKeyPrefixPager returns a pager which gets the keys under the given prefix in pages of the given page size, or of
+// the default page size if it is 0, at the given revision, or at the revision of the first page if it is 0.
+func newKeyPrefixPager(clientKV client.KVCloser, keyPrefix string, revision, pageSize int64) *keyPrefixPager {
+	if pageSize <= 0 {
+		pageSize = brtypes.DefaultKVExportPageSize
+	}
+	p := &keyPrefixPager{
+		clientKV: clientKV,
+		key:      keyPrefix,
+		rangeEnd: clientv3.GetPrefixRangeEnd(keyPrefix),
+		revision: revision,
+		pageSize: pageSize,
+	}
+	if keyPrefix == "" {
+		// the range from the key "\x00" to the range end "\x00" covers the whole keyspace
+		p.key = "\x00"
+	}
+	return p
+}
+
+// next gets the next page of the keys.
+func (p *keyPrefixPager) next(ctx context.Context) ([]*mvccpb.KeyValue, error) {
+	opts := []clientv3.OpOption{clientv3.WithRange(p.rangeEnd), clientv3.WithLimit(p.pageSize)}
+	if p.revision != 0 {
+		opts = append(opts, clientv3.WithRev(p.revision))
+	}
+	resp, err := p.clientKV.Get(ctx, p.key, opts...)
+	switch {
+	case stderrors.Is(err, rpctypes.ErrCompacted):
+		return nil, fmt.Errorf("revision %d has been compacted", p.revision)
+	case stderrors.Is(err, rpctypes.ErrFutureRev):
+		return nil, fmt.Errorf("revision %d is a future revision", p.revision)
+	case err != nil:
+		return nil, err
+	}
+	if p.revision == 0 {
+		p.revision = resp.Header.Revision
+	}
+	if !resp.More || len(resp.Kvs) == 0 {
+		p.done = true
+	} else {
+		// continue right after the last key of the page
+		p.key = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	}
+	return resp.Kvs, nil
+}
+
+// saveFullSnapshot saves the full snapshot with the given contents to the store, and records the duration of the
+// snapshot since the given start time.
+func saveFullSnapshot(store brtypes.SnapStore, rc io.ReadCloser, lastRevision int64, suffix string, isFinal bool, startTime time.Time, logger *logrus.Entry) (*brtypes.Snapshot, error) {
+	snapshot := snapstore.NewSnapshotForStore(store, brtypes.SnapshotKindFull, 0, lastRevision, suffix, isFinal)
 	if err := store.Save(*snapshot, rc); err != nil {
 		timeTaken := time.Since(startTime)
 		metrics.SnapshotDurationSeconds.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindFull, metrics.LabelSucceeded: metrics.ValueSucceededFalse}).Observe(timeTaken.Seconds())
@@ -288,9 +519,62 @@ func TakeAndSaveFullSnapshot(ctx context.Context, client client.MaintenanceClose
 		}
 	}
 
-	timeTaken = time.Since(startTime)
+	timeTaken := time.Since(startTime)
 	metrics.SnapshotDurationSeconds.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindFull, metrics.LabelSucceeded: metrics.ValueSucceededTrue}).Observe(timeTaken.Seconds())
 	logger.Infof("Total time to save full snapshot: %f seconds.", timeTaken.Seconds())
-
 	return snapshot, nil
 }
+
+// PutCanary deletes the canary keys under the given prefix and writes a new canary key, whose value is the unique
+// time at which it is written, and returns the revision of the new canary key.
+func PutCanary(ctx context.Context, clientKV client.KVCloser, prefix string) (int64, error) {
+	if _, err := clientKV.Delete(ctx, prefix, clientv3.WithPrefix()); err != nil {
+		return 0, fmt.Errorf("failed to delete the previous canary keys under %s: %v", prefix, err)
+	}
+	value := strconv.FormatInt(time.Now().UnixNano(), 10)
+	resp, err := clientKV.Put(ctx, prefix+value, value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to put the canary key %s: %v", prefix+value, err)
+	}
+	return resp.Header.GetRevision(), nil
+}
+
+// VerifyCanary verifies that the canary key written right before the given full snapshot is the only canary key
+// under the given prefix, and that it still holds the value with which it was written.
+func VerifyCanary(ctx context.Context, clientKV client.KVCloser, prefix string, fullSnapshot *brtypes.Snapshot) error {
+	resp, err := clientKV.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return fmt.Errorf("failed to get the canary keys under %s: %v", prefix, err)
+	}
+	if len(resp.Kvs) != 1 {
+		return fmt.Errorf("found %d canary keys under %s instead of one", len(resp.Kvs), prefix)
+	}
+	kv := resp.Kvs[0]
+	if string(kv.Key) != prefix+string(kv.Value) {
+		return fmt.Errorf("canary key %s holds the unexpected value %s", kv.Key, kv.Value)
+	}
+	if kv.ModRevision != fullSnapshot.LastRevision {
+		return fmt.Errorf("canary key %s was written at revision %d instead of the revision %d of the full snapshot %s", kv.Key, kv.ModRevision, fullSnapshot.LastRevision, fullSnapshot.SnapName)
+	}
+	writtenAt, err := strconv.ParseInt(string(kv.Value), 10, 64)
+	if err != nil {
+		return fmt.Errorf("canary key %s holds the invalid value %s: %v", kv.Key, kv.Value, err)
+	}
+	// the creation time of a snapshot is only known to the second from its name
+	if time.Unix(0, writtenAt).Unix() > fullSnapshot.CreatedOn.Unix() {
+		return fmt.Errorf("canary key %s was written after the full snapshot %s", kv.Key, fullSnapshot.SnapName)
+	}
+	return nil
+}
+
+// countingReadCloser is an io.ReadCloser which counts the bytes read from the underlying reader.
+type countingReadCloser struct {
+	io.ReadCloser
+	count int64
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.count += int64(n)
+	return n, err
+}
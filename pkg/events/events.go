@@ -0,0 +1,220 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ReasonFullSnapshotSucceeded is the reason of the event emitted when a full snapshot has been saved.
+	ReasonFullSnapshotSucceeded = "FullSnapshotSucceeded"
+	// ReasonFullSnapshotFailed is the reason of the event emitted when a full snapshot has failed.
+	ReasonFullSnapshotFailed = "FullSnapshotFailed"
+	// ReasonDeltaSnapshotFailed is the reason of the event emitted when a delta snapshot has failed.
+	ReasonDeltaSnapshotFailed = "DeltaSnapshotFailed"
+	// ReasonRestorationStarted is the reason of the event emitted when the restoration of the data directory starts.
+	ReasonRestorationStarted = "RestorationStarted"
+	// ReasonRestorationSucceeded is the reason of the event emitted when the data directory has been restored.
+	ReasonRestorationSucceeded = "RestorationSucceeded"
+	// ReasonRestorationFailed is the reason of the event emitted when the restoration of the data directory has failed.
+	ReasonRestorationFailed = "RestorationFailed"
+	// ReasonLearnerPromoted is the reason of the event emitted when the learner has been promoted to a voting member.
+	ReasonLearnerPromoted = "LearnerPromoted"
+	// ReasonLearnerPromotionFailed is the reason of the event emitted when the promotion of the learner has failed.
+	ReasonLearnerPromotionFailed = "LearnerPromotionFailed"
+	// ReasonScaleUpFailed is the reason of the event emitted when the scale-up of the cluster after the restoration of
+	// the member has failed.
+	ReasonScaleUpFailed = "ScaleUpFailed"
+
+	// component is the component reported as the source of the events.
+	component = "etcd-backup-restore"
+	// eventTimeout is the timeout for creating an event.
+	eventTimeout = 10 * time.Second
+	// eventAggregationWindow is the period within which the events of the same type and reason are aggregated into a
+	// single event, which counts their occurrences and shows the message of the latest one.
+	eventAggregationWindow = 10 * time.Minute
+	// eventQueueSize is the number of events which are queued to be created, beyond which the events are dropped.
+	eventQueueSize = 100
+)
+
+// Recorder records the events of the backup-restore milestones.
+type Recorder interface {
+	// Eventf records an event of the given type, i.e. `Normal` or `Warning`, with the given reason and message.
+	Eventf(eventType, reason, messageFmt string, args ...interface{})
+}
+
+type nopRecorder struct{}
+
+// NewNopRecorder returns a recorder which drops the events, to be used if the events are disabled.
+func NewNopRecorder() Recorder {
+	return nopRecorder{}
+}
+
+func (nopRecorder) Eventf(string, string, string, ...interface{}) {}
+
+// podRecorder records the events as Kubernetes events of the pod of backup-restore.
+type podRecorder struct {
+	logger    *logrus.Entry
+	k8sClient client.Client
+	pod       corev1.ObjectReference
+	disabled  atomic.Bool
+	queue     chan *corev1.Event
+	// aggregated holds the latest event created for each type and reason. It is only accessed by the goroutine
+	// creating the events.
+	aggregated map[string]*corev1.Event
+}
+
+// NewRecorder returns a recorder which creates the events as Kubernetes events of the pod with the given name and
+// namespace, so that they are shown by `kubectl describe` for the pod. The events are created in the background until
+// the given context is done, and the events of the same type and reason are aggregated into a single event counting
+// them, so that a repeatedly failing operation doesn't flood the API server with events. The recorder stops creating
+// events once it is forbidden to create them, as backup-restore doesn't depend on them.
+func NewRecorder(ctx context.Context, logger *logrus.Entry, k8sClient client.Client, podName, podNamespace string) Recorder {
+	logger = logger.WithField("actor", "event-recorder")
+	r := &podRecorder{
+		logger:    logger,
+		k8sClient: k8sClient,
+		pod: corev1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Pod",
+			Name:       podName,
+			Namespace:  podNamespace,
+		},
+		queue:      make(chan *corev1.Event, eventQueueSize),
+		aggregated: map[string]*corev1.Event{},
+	}
+
+	// the events are only shown for the pod if they refer to its UID
+	getCtx, cancel := context.WithTimeout(ctx, eventTimeout)
+	defer cancel()
+	pod := &corev1.Pod{}
+	if err := k8sClient.Get(getCtx, client.ObjectKey{Namespace: podNamespace, Name: podName}, pod); err != nil {
+		logger.Warnf("Failed to get pod %s/%s, the events are not shown for the pod: %v", podNamespace, podName, err)
+	} else {
+		r.pod.UID = pod.UID
+	}
+
+	go r.run(ctx)
+	return r
+}
+
+// Eventf records an event of the given type, with the given reason and message.
+func (r *podRecorder) Eventf(eventType, reason, messageFmt string, args ...interface{}) {
+	if r.disabled.Load() {
+		return
+	}
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", r.pod.Name, now.UnixNano()),
+			Namespace: r.pod.Namespace,
+		},
+		InvolvedObject:      r.pod,
+		Reason:              reason,
+		Message:             fmt.Sprintf(messageFmt, args...),
+		Type:                eventType,
+		FirstTimestamp:      now,
+		LastTimestamp:       now,
+		Count:               1,
+		Source:              corev1.EventSource{Component: component},
+		ReportingController: component,
+		ReportingInstance:   r.pod.Name,
+	}
+
+	select {
+	case r.queue <- event:
+	default:
+		r.logger.Warnf("Dropping %s event %s, as %d events are pending to be created", eventType, reason, eventQueueSize)
+	}
+}
+
+// run creates the queued events until the given context is done.
+func (r *podRecorder) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-r.queue:
+			if r.disabled.Load() {
+				continue
+			}
+			if err := r.record(ctx, event); err != nil {
+				if apierrors.IsForbidden(err) {
+					if !r.disabled.Swap(true) {
+						r.logger.Warnf("Not permitted to create events, no further events are recorded: %v", err)
+					}
+					continue
+				}
+				r.logger.Warnf("Failed to create %s event %s: %v", event.Type, event.Reason, err)
+			}
+		}
+	}
+}
+
+// record creates the given event, or counts it in the event of the same type and reason created within the
+// aggregation window instead.
+func (r *podRecorder) record(ctx context.Context, event *corev1.Event) error {
+	ctx, cancel := context.WithTimeout(ctx, eventTimeout)
+	defer cancel()
+
+	key := event.Type + "/" + event.Reason
+	if previous, ok := r.aggregated[key]; ok && event.LastTimestamp.Sub(previous.FirstTimestamp.Time) < eventAggregationWindow {
+		updated := previous.DeepCopy()
+		updated.Count++
+		updated.Message = event.Message
+		updated.LastTimestamp = event.LastTimestamp
+		err := r.k8sClient.Update(ctx, updated)
+		if err == nil {
+			r.aggregated[key] = updated
+			return nil
+		}
+		if apierrors.IsForbidden(err) {
+			return err
+		}
+		// the aggregated event may have expired in the meantime, hence a new event is created instead
+		r.logger.Debugf("Failed to update %s event %s, creating a new event: %v", event.Type, event.Reason, err)
+	}
+	if err := r.k8sClient.Create(ctx, event); err != nil {
+		return err
+	}
+	r.aggregated[key] = event
+	return nil
+}
+
+// FakeRecorder records the events in memory. To be used for unit tests.
+type FakeRecorder struct {
+	mutex  sync.Mutex
+	events []string
+}
+
+// NewFakeRecorder returns a fake recorder.
+func NewFakeRecorder() *FakeRecorder {
+	return &FakeRecorder{}
+}
+
+// Eventf records an event as the string `<type> <reason> <message>`.
+func (f *FakeRecorder) Eventf(eventType, reason, messageFmt string, args ...interface{}) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.events = append(f.events, fmt.Sprintf("%s %s %s", eventType, reason, fmt.Sprintf(messageFmt, args...)))
+}
+
+// Events returns the recorded events.
+func (f *FakeRecorder) Events() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string(nil), f.events...)
+}
@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package events_test
+
+import (
+	"context"
+	"sync/atomic"
+
+	. "github.com/gardener/etcd-backup-restore/pkg/events"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	podName      = "etcd-main-0"
+	podNamespace = "shoot--dev--test"
+)
+
+// forbiddenClient is a Kubernetes client which is forbidden to create any object.
+type forbiddenClient struct {
+	client.Client
+	creates atomic.Int32
+}
+
+func (c *forbiddenClient) Create(context.Context, client.Object, ...client.CreateOption) error {
+	c.creates.Add(1)
+	return apierrors.NewForbidden(schema.GroupResource{Resource: "events"}, "", nil)
+}
+
+var _ = Describe("Recording the Kubernetes events", func() {
+	var (
+		ctx       context.Context
+		logger    *logrus.Entry
+		k8sClient client.Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		logger = logrus.New().WithField("suite", "events")
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: podNamespace, UID: "pod-uid"}}
+		k8sClient = fake.NewClientBuilder().WithObjects(pod).Build()
+	})
+
+	listEvents := func() []corev1.Event {
+		eventList := &corev1.EventList{}
+		Expect(k8sClient.List(ctx, eventList, client.InNamespace(podNamespace))).To(Succeed())
+		return eventList.Items
+	}
+
+	It("should create the events for the pod", func() {
+		recorder := NewRecorder(ctx, logger, k8sClient, podName, podNamespace)
+		recorder.Eventf(corev1.EventTypeNormal, ReasonRestorationStarted, "Restoring up to revision %d", 10)
+		recorder.Eventf(corev1.EventTypeWarning, ReasonRestorationFailed, "Restoring failed")
+
+		Eventually(listEvents).Should(HaveLen(2))
+		for _, event := range listEvents() {
+			Expect(event.InvolvedObject.Kind).Should(Equal("Pod"))
+			Expect(event.InvolvedObject.Name).Should(Equal(podName))
+			Expect(event.InvolvedObject.UID).Should(BeEquivalentTo("pod-uid"))
+			Expect(event.Source.Component).Should(Equal("etcd-backup-restore"))
+			switch event.Reason {
+			case ReasonRestorationStarted:
+				Expect(event.Type).Should(Equal(corev1.EventTypeNormal))
+				Expect(event.Message).Should(Equal("Restoring up to revision 10"))
+			case ReasonRestorationFailed:
+				Expect(event.Type).Should(Equal(corev1.EventTypeWarning))
+				Expect(event.Message).Should(Equal("Restoring failed"))
+			default:
+				Fail("unexpected event reason " + event.Reason)
+			}
+		}
+	})
+
+	It("should aggregate the repeated events of the same type and reason into a single event", func() {
+		recorder := NewRecorder(ctx, logger, k8sClient, podName, podNamespace)
+		for i := 1; i <= 3; i++ {
+			recorder.Eventf(corev1.EventTypeWarning, ReasonFullSnapshotFailed, "Failed attempt %d", i)
+		}
+		recorder.Eventf(corev1.EventTypeNormal, ReasonFullSnapshotSucceeded, "Saved")
+
+		Eventually(listEvents).Should(HaveLen(2))
+		Eventually(func() int32 {
+			for _, event := range listEvents() {
+				if event.Reason == ReasonFullSnapshotFailed {
+					return event.Count
+				}
+			}
+			return 0
+		}).Should(BeEquivalentTo(3))
+		for _, event := range listEvents() {
+			if event.Reason == ReasonFullSnapshotFailed {
+				Expect(event.Message).Should(Equal("Failed attempt 3"))
+			}
+		}
+	})
+
+	It("should still create the events if the pod can't be found", func() {
+		recorder := NewRecorder(ctx, logger, k8sClient, "etcd-main-1", podNamespace)
+		recorder.Eventf(corev1.EventTypeNormal, ReasonLearnerPromoted, "Promoted")
+
+		Eventually(listEvents).Should(HaveLen(1))
+		Expect(listEvents()[0].InvolvedObject.Name).Should(Equal("etcd-main-1"))
+		Expect(listEvents()[0].InvolvedObject.UID).Should(BeEmpty())
+	})
+
+	It("should stop creating the events once it is forbidden to create them", func() {
+		forbidden := &forbiddenClient{Client: k8sClient}
+		recorder := NewRecorder(ctx, logger, forbidden, podName, podNamespace)
+
+		recorder.Eventf(corev1.EventTypeNormal, ReasonFullSnapshotSucceeded, "Saved")
+		Eventually(forbidden.creates.Load).Should(BeEquivalentTo(1))
+		Consistently(forbidden.creates.Load).Should(BeEquivalentTo(1))
+
+		recorder.Eventf(corev1.EventTypeWarning, ReasonFullSnapshotFailed, "Failed")
+		Consistently(forbidden.creates.Load).Should(BeEquivalentTo(1))
+	})
+
+	It("should drop the events with the no-op recorder", func() {
+		NewNopRecorder().Eventf(corev1.EventTypeNormal, ReasonFullSnapshotSucceeded, "Saved")
+		Consistently(listEvents).Should(BeEmpty())
+	})
+})
@@ -144,7 +144,8 @@ func (hb *Heartbeat) RenewMemberLease(ctx context.Context) error {
 }
 
 // UpdateFullSnapshotLease renews the full snapshot lease and updates the holderIdentity field with the last revision in the latest full snapshot.
-func UpdateFullSnapshotLease(ctx context.Context, logger *logrus.Entry, fullSnapshot *brtypes.Snapshot, k8sClientset client.Client, fullSnapshotLeaseName string) error {
+// The lease is looked up in the given namespace, or in the namespace of the pod if it is empty.
+func UpdateFullSnapshotLease(ctx context.Context, logger *logrus.Entry, fullSnapshot *brtypes.Snapshot, k8sClientset client.Client, leaseNamespace, fullSnapshotLeaseName string) error {
 	if k8sClientset == nil {
 		return &errors.EtcdError{
 			Message: "nil clientset passed",
@@ -157,11 +158,9 @@ func UpdateFullSnapshotLease(ctx context.Context, logger *logrus.Entry, fullSnap
 		}
 	}
 
-	namespace, err := utils.GetEnvVarOrError(podNamespace)
+	namespace, err := getSnapshotLeaseNamespace(leaseNamespace)
 	if err != nil {
-		return &errors.EtcdError{
-			Message: fmt.Sprintf("Pod namespace env var not present: %v", err),
-		}
+		return err
 	}
 
 	// Retry on conflict is necessary because multiple actors update the full snapshot lease.
@@ -215,18 +214,17 @@ func UpdateFullSnapshotLease(ctx context.Context, logger *logrus.Entry, fullSnap
 }
 
 // UpdateDeltaSnapshotLease renews delta snapshot lease and updates the holderIdentity field with the total number or revisions stored in delta snapshots since the last full snapshot was taken
-func UpdateDeltaSnapshotLease(ctx context.Context, logger *logrus.Entry, prevDeltaSnapshots brtypes.SnapList, k8sClientset client.Client, deltaSnapshotLeaseName string) error {
+// The lease is looked up in the given namespace, or in the namespace of the pod if it is empty.
+func UpdateDeltaSnapshotLease(ctx context.Context, logger *logrus.Entry, prevDeltaSnapshots brtypes.SnapList, k8sClientset client.Client, leaseNamespace, deltaSnapshotLeaseName string) error {
 	if k8sClientset == nil {
 		return &errors.EtcdError{
 			Message: "nil clientset passed",
 		}
 	}
 
-	namespace, err := utils.GetEnvVarOrError(podNamespace)
+	namespace, err := getSnapshotLeaseNamespace(leaseNamespace)
 	if err != nil {
-		return &errors.EtcdError{
-			Message: fmt.Sprintf("Pod namespace env var not present: %v", err),
-		}
+		return err
 	}
 
 	logString := "Renewed delta snapshot lease"
@@ -277,9 +275,23 @@ func UpdateDeltaSnapshotLease(ctx context.Context, logger *logrus.Entry, prevDel
 	return nil
 }
 
+// getSnapshotLeaseNamespace returns the given namespace of the snapshot leases, or the namespace of the pod if it is empty.
+func getSnapshotLeaseNamespace(leaseNamespace string) (string, error) {
+	if leaseNamespace != "" {
+		return leaseNamespace, nil
+	}
+	namespace, err := utils.GetEnvVarOrError(podNamespace)
+	if err != nil {
+		return "", &errors.EtcdError{
+			Message: fmt.Sprintf("Pod namespace env var not present: %v", err),
+		}
+	}
+	return namespace, nil
+}
+
 // FullSnapshotCaseLeaseUpdate Updates the fullsnapshot lease as needed when a full snapshot is taken
-func FullSnapshotCaseLeaseUpdate(ctx context.Context, logger *logrus.Entry, fullSnapshot *brtypes.Snapshot, k8sClientset client.Client, fullSnapshotLeaseName string) error {
-	if err := UpdateFullSnapshotLease(ctx, logger, fullSnapshot, k8sClientset, fullSnapshotLeaseName); err != nil {
+func FullSnapshotCaseLeaseUpdate(ctx context.Context, logger *logrus.Entry, fullSnapshot *brtypes.Snapshot, k8sClientset client.Client, leaseNamespace, fullSnapshotLeaseName string) error {
+	if err := UpdateFullSnapshotLease(ctx, logger, fullSnapshot, k8sClientset, leaseNamespace, fullSnapshotLeaseName); err != nil {
 		return &errors.EtcdError{
 			Message: fmt.Sprintf("Failed to update full snapshot lease: %v", err),
 		}
@@ -288,10 +300,10 @@ func FullSnapshotCaseLeaseUpdate(ctx context.Context, logger *logrus.Entry, full
 }
 
 // DeltaSnapshotCaseLeaseUpdate Updates the deltasnapshot lease as needed when a delta snapshot is taken
-func DeltaSnapshotCaseLeaseUpdate(ctx context.Context, logger *logrus.Entry, k8sClientset client.Client, deltaSnapshotLeaseName string, store brtypes.SnapStore) error {
+func DeltaSnapshotCaseLeaseUpdate(ctx context.Context, logger *logrus.Entry, k8sClientset client.Client, leaseNamespace, deltaSnapshotLeaseName string, store brtypes.SnapStore) error {
 	_, latestDeltaSnapshotList, err := miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(store)
 	if err == nil {
-		if err = UpdateDeltaSnapshotLease(ctx, logger, latestDeltaSnapshotList, k8sClientset, deltaSnapshotLeaseName); err != nil {
+		if err = UpdateDeltaSnapshotLease(ctx, logger, latestDeltaSnapshotList, k8sClientset, leaseNamespace, deltaSnapshotLeaseName); err != nil {
 			return &errors.EtcdError{
 				Message: fmt.Sprintf("Failed to update delta snapshot lease with error: %v", err),
 			}
@@ -120,7 +120,7 @@ var _ = Describe("Heartbeat", func() {
 				Expect(err).ShouldNot(HaveOccurred())
 
 				// Update full snapshot lease with the first full snapshot
-				err = heartbeat.UpdateFullSnapshotLease(context.TODO(), logger, prevFullSnap, k8sClientset, brtypes.DefaultFullSnapshotLeaseName)
+				err = heartbeat.UpdateFullSnapshotLease(context.TODO(), logger, prevFullSnap, k8sClientset, "", brtypes.DefaultFullSnapshotLeaseName)
 				Expect(err).ShouldNot(HaveOccurred())
 
 				l := &v1.Lease{}
@@ -131,7 +131,7 @@ var _ = Describe("Heartbeat", func() {
 				Expect(l.Spec.HolderIdentity).To(PointTo(Equal("980")))
 
 				// Trigger full snapshot lease update with latest full snapshot which is not the first full snapshot
-				err = heartbeat.UpdateFullSnapshotLease(context.TODO(), logger, latestFullSnap, k8sClientset, brtypes.DefaultFullSnapshotLeaseName)
+				err = heartbeat.UpdateFullSnapshotLease(context.TODO(), logger, latestFullSnap, k8sClientset, "", brtypes.DefaultFullSnapshotLeaseName)
 				Expect(err).ShouldNot(HaveOccurred())
 
 				l = &v1.Lease{}
@@ -150,7 +150,7 @@ var _ = Describe("Heartbeat", func() {
 
 				Expect(k8sClientset.Create(context.TODO(), lease)).To(Succeed())
 
-				err := heartbeat.UpdateFullSnapshotLease(context.TODO(), logger, nil, k8sClientset, brtypes.DefaultFullSnapshotLeaseName)
+				err := heartbeat.UpdateFullSnapshotLease(context.TODO(), logger, nil, k8sClientset, "", brtypes.DefaultFullSnapshotLeaseName)
 				Expect(err).Should(HaveOccurred())
 
 				err = k8sClientset.Delete(context.TODO(), lease)
@@ -206,7 +206,7 @@ var _ = Describe("Heartbeat", func() {
 				err = k8sClientset.Create(context.TODO(), lease)
 				Expect(err).ShouldNot(HaveOccurred())
 
-				err = heartbeat.UpdateDeltaSnapshotLease(context.TODO(), logger, snapList, k8sClientset, brtypes.DefaultDeltaSnapshotLeaseName)
+				err = heartbeat.UpdateDeltaSnapshotLease(context.TODO(), logger, snapList, k8sClientset, "", brtypes.DefaultDeltaSnapshotLeaseName)
 				Expect(err).ShouldNot(HaveOccurred())
 
 				l := &v1.Lease{}
@@ -228,7 +228,7 @@ var _ = Describe("Heartbeat", func() {
 
 				err = k8sClientset.Create(context.TODO(), lease)
 
-				err = heartbeat.UpdateDeltaSnapshotLease(context.TODO(), logger, nil, k8sClientset, brtypes.DefaultDeltaSnapshotLeaseName)
+				err = heartbeat.UpdateDeltaSnapshotLease(context.TODO(), logger, nil, k8sClientset, "", brtypes.DefaultDeltaSnapshotLeaseName)
 				Expect(err).ShouldNot(HaveOccurred())
 
 				l := &v1.Lease{}
@@ -255,7 +255,7 @@ var _ = Describe("Heartbeat", func() {
 
 				err = k8sClientset.Create(context.TODO(), newLease)
 
-				err = heartbeat.UpdateDeltaSnapshotLease(context.TODO(), logger, nil, k8sClientset, brtypes.DefaultDeltaSnapshotLeaseName)
+				err = heartbeat.UpdateDeltaSnapshotLease(context.TODO(), logger, nil, k8sClientset, "", brtypes.DefaultDeltaSnapshotLeaseName)
 				Expect(err).ShouldNot(HaveOccurred())
 
 				l := &v1.Lease{}
@@ -272,6 +272,79 @@ var _ = Describe("Heartbeat", func() {
 				Expect(err).ShouldNot(HaveOccurred())
 			})
 		})
+		Context("With the snapshot leases in a configured namespace", func() {
+			const (
+				leaseNamespace         = "etcd-tenant"
+				fullSnapshotLeaseName  = "etcd-main-full-snapshot-revisions"
+				deltaSnapshotLeaseName = "etcd-main-delta-snapshot-revisions"
+			)
+
+			newLease := func(namespace, name string) *v1.Lease {
+				return &v1.Lease{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      name,
+						Namespace: namespace,
+					},
+				}
+			}
+
+			getHolderIdentity := func(namespace, name string) *string {
+				l := &v1.Lease{}
+				Expect(k8sClientset.Get(context.TODO(), client.ObjectKey{Namespace: namespace, Name: name}, l)).To(Succeed())
+				return l.Spec.HolderIdentity
+			}
+
+			BeforeEach(func() {
+				Expect(os.Setenv("POD_NAMESPACE", "test_namespace")).To(Succeed())
+				k8sClientset = fake.NewClientBuilder().Build()
+				for _, namespace := range []string{leaseNamespace, os.Getenv("POD_NAMESPACE")} {
+					Expect(k8sClientset.Create(context.TODO(), newLease(namespace, fullSnapshotLeaseName))).To(Succeed())
+					Expect(k8sClientset.Create(context.TODO(), newLease(namespace, deltaSnapshotLeaseName))).To(Succeed())
+				}
+			})
+			AfterEach(func() {
+				Expect(os.Unsetenv("POD_NAMESPACE")).To(Succeed())
+			})
+
+			It("Should update the full snapshot lease with the configured name in the configured namespace", func() {
+				fullSnap := &brtypes.Snapshot{
+					Kind:          brtypes.SnapshotKindFull,
+					CreatedOn:     time.Now(),
+					StartRevision: 0,
+					LastRevision:  980,
+				}
+				fullSnap.GenerateSnapshotName()
+
+				Expect(heartbeat.UpdateFullSnapshotLease(context.TODO(), logger, fullSnap, k8sClientset, leaseNamespace, fullSnapshotLeaseName)).To(Succeed())
+				Expect(getHolderIdentity(leaseNamespace, fullSnapshotLeaseName)).To(PointTo(Equal("980")))
+				Expect(getHolderIdentity(os.Getenv("POD_NAMESPACE"), fullSnapshotLeaseName)).To(BeNil())
+			})
+
+			It("Should update the delta snapshot lease with the configured name in the configured namespace", func() {
+				deltaSnap := &brtypes.Snapshot{
+					Kind:          brtypes.SnapshotKindDelta,
+					CreatedOn:     time.Now(),
+					StartRevision: 981,
+					LastRevision:  1900,
+				}
+
+				Expect(heartbeat.UpdateDeltaSnapshotLease(context.TODO(), logger, brtypes.SnapList{deltaSnap}, k8sClientset, leaseNamespace, deltaSnapshotLeaseName)).To(Succeed())
+				Expect(getHolderIdentity(leaseNamespace, deltaSnapshotLeaseName)).To(PointTo(Equal("1900")))
+				Expect(getHolderIdentity(os.Getenv("POD_NAMESPACE"), deltaSnapshotLeaseName)).To(BeNil())
+			})
+
+			It("Should return error if the lease is not present in the configured namespace", func() {
+				fullSnap := &brtypes.Snapshot{
+					Kind:          brtypes.SnapshotKindFull,
+					CreatedOn:     time.Now(),
+					StartRevision: 0,
+					LastRevision:  980,
+				}
+				fullSnap.GenerateSnapshotName()
+
+				Expect(heartbeat.UpdateFullSnapshotLease(context.TODO(), logger, fullSnap, k8sClientset, "other-tenant", fullSnapshotLeaseName)).ShouldNot(Succeed())
+			})
+		})
 	})
 	Describe("Renewing member lease", func() {
 		var (
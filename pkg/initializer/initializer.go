@@ -8,11 +8,13 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/gardener/etcd-backup-restore/pkg/errors"
+	"github.com/gardener/etcd-backup-restore/pkg/events"
 	"github.com/gardener/etcd-backup-restore/pkg/initializer/validator"
 	"github.com/gardener/etcd-backup-restore/pkg/member"
 	"github.com/gardener/etcd-backup-restore/pkg/metrics"
@@ -23,12 +25,17 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/util/retry"
 )
 
 const (
 	// addLearnerAttempts are the total number of attempts that will be made to add a learner
 	addLearnerAttempts = 6
+
+	// scaleUpAttempts are the total number of attempts that will be made to add or promote each learner while scaling up
+	// the cluster after a single member restoration.
+	scaleUpAttempts = 10
 )
 
 // Initialize has the following steps:
@@ -47,6 +54,10 @@ func (e *EtcdInitializer) Initialize(mode validator.Mode, failBelowRevision int6
 	memberHeartbeatPresent := false
 	ctx := context.Background()
 	var err error
+	e.initialClusterState = ""
+	e.scaleUpMutex.Lock()
+	e.scaleUpDone = nil
+	e.scaleUpMutex.Unlock()
 
 	podName, err := miscellaneous.GetEnvVarOrError("POD_NAME")
 	if err != nil {
@@ -82,6 +93,7 @@ func (e *EtcdInitializer) Initialize(mode validator.Mode, failBelowRevision int6
 				if err := member.AddLearnerWithRetry(ctx, m, addLearnerAttempts, e.Config.RestoreOptions.Config.DataDir); err != nil {
 					logger.Fatalf("unable to add a learner in a cluster: %v", err)
 				}
+				e.initialClusterState = miscellaneous.ClusterStateExisting
 				// return here after adding learner(non-voting member) as no restoration or validation required.
 				return nil
 			}
@@ -122,19 +134,31 @@ func (e *EtcdInitializer) Initialize(mode validator.Mode, failBelowRevision int6
 		} else {
 			// For case: ClusterSize=1 or when multi-node cluster(ClusterSize>1) is bootstrapped
 			start := time.Now()
-			restored, err := e.restoreCorruptData()
+			restored, err := e.restoreCorruptData(ctx)
 			if err != nil {
 				metrics.RestorationDurationSeconds.With(prometheus.Labels{metrics.LabelRestorationKind: metrics.ValueRestoreSingleNode, metrics.LabelSucceeded: metrics.ValueSucceededFalse}).Observe(time.Since(start).Seconds())
 				return fmt.Errorf("error while restoring corrupt data: %v", err)
 			}
 			if restored {
 				metrics.RestorationDurationSeconds.With(prometheus.Labels{metrics.LabelRestorationKind: metrics.ValueRestoreSingleNode, metrics.LabelSucceeded: metrics.ValueSucceededTrue}).Observe(time.Since(start).Seconds())
+				if e.Config.RestoreOptions.Config.ScaleUpClusterSize > 1 {
+					if err := e.scaleUpAfterRestore(ctx, podName); err != nil {
+						return err
+					}
+				}
 			}
 		}
 	}
 	return nil
 }
 
+// InitialClusterState returns the initial cluster state the member has been initialized for, either `new` if it has
+// been restored from the snapshots to bootstrap the cluster, or `existing` if it has been added to the cluster as a
+// learner. It is empty if the member has been neither restored nor added to the cluster by the last initialization.
+func (e *EtcdInitializer) InitialClusterState() string {
+	return e.initialClusterState
+}
+
 // NewInitializer creates an etcd initializer object.
 func NewInitializer(restoreOptions *brtypes.RestoreOptions, snapstoreConfig *brtypes.SnapstoreConfig, etcdConnectionConfig *brtypes.EtcdConnectionConfig, logger *logrus.Logger) (*EtcdInitializer, error) {
 	zapLogger, err := zap.NewProduction()
@@ -151,6 +175,8 @@ func NewInitializer(restoreOptions *brtypes.RestoreOptions, snapstoreConfig *brt
 		Validator: &validator.DataValidator{
 			Config: &validator.Config{
 				DataDir:                restoreOptions.Config.DataDir,
+				MemberSubPath:          restoreOptions.Config.MemberSubPath,
+				WALSubPath:             restoreOptions.Config.WALSubPath,
 				EmbeddedEtcdQuotaBytes: restoreOptions.Config.EmbeddedEtcdQuotaBytes,
 				SnapstoreConfig:        snapstoreConfig,
 			},
@@ -158,14 +184,15 @@ func NewInitializer(restoreOptions *brtypes.RestoreOptions, snapstoreConfig *brt
 			Logger:              logger,
 			ZapLogger:           zapLogger,
 		},
-		Logger: logger,
+		Logger:        logger,
+		EventRecorder: events.NewNopRecorder(),
 	}, nil
 }
 
 // restoreCorruptData attempts to restore a corrupted data directory.
 // It returns true only if restoration was successful, and false when
 // bootstrapping a new data directory or if restoration failed
-func (e *EtcdInitializer) restoreCorruptData() (bool, error) {
+func (e *EtcdInitializer) restoreCorruptData(ctx context.Context) (bool, error) {
 	logger := e.Logger
 	tempRestoreOptions := *(e.Config.RestoreOptions.DeepCopy())
 	dataDir := tempRestoreOptions.Config.DataDir
@@ -180,7 +207,16 @@ func (e *EtcdInitializer) restoreCorruptData() (bool, error) {
 		return false, err
 	}
 	logger.Info("Finding latest set of snapshot to recover from...")
-	baseSnap, deltaSnapList, err := miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(store)
+	var (
+		baseSnap      *brtypes.Snapshot
+		deltaSnapList brtypes.SnapList
+		fallback      string
+	)
+	if tempRestoreOptions.Config.UseChainManifest {
+		baseSnap, deltaSnapList, fallback, err = miscellaneous.GetLatestFullSnapshotAndDeltaSnapListFromChainManifest(store, logrus.NewEntry(logger))
+	} else {
+		baseSnap, deltaSnapList, err = miscellaneous.GetLatestFullSnapshotAndDeltaSnapListWithContext(ctx, store)
+	}
 	if err != nil {
 		logger.Errorf("failed to get latest set of snapshot: %v", err)
 		return false, err
@@ -192,31 +228,82 @@ func (e *EtcdInitializer) restoreCorruptData() (bool, error) {
 		return e.restoreWithEmptySnapstore()
 	}
 
+	var latestSnapshotRevision int64
+	if len(deltaSnapList) > 0 {
+		latestSnapshotRevision = deltaSnapList[len(deltaSnapList)-1].LastRevision
+	} else {
+		latestSnapshotRevision = baseSnap.LastRevision
+	}
+	if err := e.Validator.CheckRestoreRevisionGap(latestSnapshotRevision, tempRestoreOptions.Config.MaxRestoreRevisionGap, tempRestoreOptions.Config.ForceRestore); err != nil {
+		return false, fmt.Errorf("refusing to restore over the data directory: %v", err)
+	}
+
 	tempRestoreOptions.BaseSnapshot = baseSnap
 	tempRestoreOptions.DeltaSnapList = deltaSnapList
+	tempRestoreOptions.ChainManifestFallback = fallback
+	tempRestoreOptions.PreservedKeysConnectionConfig = e.Config.EtcdConnectionConfig
 	tempRestoreOptions.Config.DataDir = fmt.Sprintf("%s.%s", tempRestoreOptions.Config.DataDir, "part")
 
-	if err := e.removeDir(tempRestoreOptions.Config.DataDir); err != nil {
-		return false, fmt.Errorf("failed to delete previous temporary data directory: %v", err)
-	}
-
 	rs, err := restorer.NewRestorer(store, logrus.NewEntry(logger))
 	if err != nil {
 		return false, err
 	}
+	// the restore lock is held until the restored data directory replaces the data directory
+	release, err := rs.AcquireRestoreLock(tempRestoreOptions.RestoreLockTTL)
+	if err != nil {
+		e.EventRecorder.Eventf(corev1.EventTypeWarning, events.ReasonRestorationFailed, "Restoring the data directory failed: %v", err)
+		return false, err
+	}
+	defer release()
+	tempRestoreOptions.RestoreLockTTL = 0
+
+	// the temporary data directory of a previous restoration is kept if the restoration can be resumed from its checkpoint
+	if tempRestoreOptions.Config.RestoreCheckpointInterval == 0 || !restorer.HasCheckpoint(tempRestoreOptions.Config.DataDir) {
+		if err := e.removeDir(tempRestoreOptions.Config.DataDir); err != nil {
+			return false, fmt.Errorf("failed to delete previous temporary data directory: %v", err)
+		}
+	}
+
 	m := member.NewMemberControl(e.Config.EtcdConnectionConfig)
-	if err := rs.RestoreAndStopEtcd(tempRestoreOptions, m); err != nil {
+	e.EventRecorder.Eventf(corev1.EventTypeNormal, events.ReasonRestorationStarted, "Restoring the data directory from full snapshot %s and %d delta snapshots up to revision %d", snapshotName(baseSnap), len(deltaSnapList), latestSnapshotRevision)
+	if err := rs.RestoreAndStopEtcd(ctx, tempRestoreOptions, m); err != nil {
+		if tempRestoreOptions.Config.RestoreCheckpointInterval > 0 && restorer.HasCheckpoint(tempRestoreOptions.Config.DataDir) {
+			logger.Infof("Keeping the temporary data directory to resume the restoration from its checkpoint")
+		} else if removeErr := e.removeDir(tempRestoreOptions.Config.DataDir); removeErr != nil {
+			logger.Errorf("failed to delete temporary data directory: %v", removeErr)
+		}
 		err = fmt.Errorf("failed to restore snapshot: %v", err)
+		e.EventRecorder.Eventf(corev1.EventTypeWarning, events.ReasonRestorationFailed, "Restoring the data directory failed: %v", err)
 		return false, err
 	}
 
 	if err := e.removeContents(dataDir); err != nil {
-		return false, fmt.Errorf("failed to remove corrupt contents with restored snapshot: %v", err)
+		err = fmt.Errorf("failed to remove corrupt contents with restored snapshot: %v", err)
+		e.EventRecorder.Eventf(corev1.EventTypeWarning, events.ReasonRestorationFailed, "Restoring the data directory failed: %v", err)
+		return false, err
 	}
 	logger.Infoln("Successfully restored the etcd data directory.")
+	e.EventRecorder.Eventf(corev1.EventTypeNormal, events.ReasonRestorationSucceeded, "Restored the data directory up to revision %d", latestSnapshotRevision)
+	e.initialClusterState = tempRestoreOptions.InitialClusterState
+	if e.initialClusterState == "" {
+		e.initialClusterState = miscellaneous.ClusterStateNew
+	}
+	if tempRestoreOptions.Config.UseClusterMetadata {
+		if e.clusterMetadata, err = snapstore.FetchClusterMetadata(store, baseSnap); err != nil {
+			logger.Warnf("Failed to fetch the cluster metadata of the restored full snapshot: %v", err)
+		}
+	}
 	return true, nil
 }
 
+// snapshotName returns the path of the given snapshot in the snapstore, or `none` if there is no snapshot.
+func snapshotName(snap *brtypes.Snapshot) string {
+	if snap == nil {
+		return "none"
+	}
+	return path.Join(snap.SnapDir, snap.SnapName)
+}
+
 // restoreWithEmptySnapstore removes the data directory as
 // part of restoration process for empty snapstore case.
 // It returns true if data directory removal is successful,
@@ -263,6 +350,58 @@ func (e *EtcdInitializer) removeDir(dirname string) error {
 	return nil
 }
 
+// scaleUpAfterRestore scales up the cluster of the restored member to the configured size in the background with the
+// given context, as the learners can only be added once etcd has been started with the restored data directory. If the
+// member was restored with the cluster metadata, the cluster is scaled up with the other members recorded in it
+// instead. The result of the scale-up is returned by WaitForScaleUp.
+func (e *EtcdInitializer) scaleUpAfterRestore(ctx context.Context, podName string) error {
+	var memberNames []string
+	if e.clusterMetadata != nil {
+		// the cluster is rebuilt with the members of the cluster the restored snapshot was taken from
+		memberNames = member.GetClusterMetadataMemberNames(e.clusterMetadata, e.Config.RestoreOptions.Config.Name)
+	} else {
+		var err error
+		if memberNames, err = member.GetScaleUpMemberNames(podName, e.Config.RestoreOptions.Config.ScaleUpClusterSize); err != nil {
+			return fmt.Errorf("unable to scale up the cluster after restoration: %v", err)
+		}
+	}
+	m := member.NewScaleUpControl(e.Config.EtcdConnectionConfig)
+	logger := e.Logger.WithField("actor", "scale-up")
+	done := make(chan struct{})
+	e.scaleUpMutex.Lock()
+	e.scaleUpDone, e.scaleUpErr = done, nil
+	e.scaleUpMutex.Unlock()
+	go func() {
+		err := member.ScaleUpCluster(ctx, m, memberNames, scaleUpAttempts, logger)
+		e.scaleUpMutex.Lock()
+		defer e.scaleUpMutex.Unlock()
+		if err != nil {
+			e.scaleUpErr = fmt.Errorf("failed to scale up the cluster after restoration: %v", err)
+		}
+		close(done)
+	}()
+	return nil
+}
+
+// WaitForScaleUp waits for the scale-up of the cluster started by the last initialization after restoring the member,
+// and returns its error. It returns immediately if the last initialization hasn't started a scale-up.
+func (e *EtcdInitializer) WaitForScaleUp(ctx context.Context) error {
+	e.scaleUpMutex.Lock()
+	done := e.scaleUpDone
+	e.scaleUpMutex.Unlock()
+	if done == nil {
+		return nil
+	}
+	select {
+	case <-done:
+		e.scaleUpMutex.Lock()
+		defer e.scaleUpMutex.Unlock()
+		return e.scaleUpErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // restoreInMultiNode
 // * Remove the member from the cluster
 // * Clean the data-dir of member that needs to be restored.
@@ -292,5 +431,6 @@ func (e *EtcdInitializer) restoreInMultiNode(ctx context.Context) error {
 	}); err != nil {
 		return fmt.Errorf("unable to add the member as learner %v", err)
 	}
+	e.initialClusterState = miscellaneous.ClusterStateExisting
 	return nil
 }
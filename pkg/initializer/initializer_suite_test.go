@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package initializer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gardener/etcd-backup-restore/pkg/compressor"
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+
+	"github.com/gardener/etcd-backup-restore/test/utils"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	outputDir          = "../../test/output"
+	embeddedEtcdPortNo = "9089"
+)
+
+var (
+	etcdDir = filepath.Join(outputDir, "default.etcd")
+	// snapstoreDir is the container of the local snapstore, which is relative to the home directory
+	snapstoreDir = "snapshotter.bkp"
+	testCtx      = context.Background()
+	logger       = logrus.New().WithField("suite", "initializer")
+)
+
+func TestInitializer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Initializer Suite")
+}
+
+var _ = SynchronizedBeforeSuite(func() []byte {
+	var data []byte
+
+	Expect(os.RemoveAll(outputDir)).To(Succeed())
+	Expect(os.MkdirAll(outputDir, 0700)).To(Succeed())
+	// the local snapstore is kept in the output directory
+	homeDir, err := filepath.Abs(outputDir)
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(os.Setenv("HOME", homeDir)).To(Succeed())
+
+	etcd, err := utils.StartEmbeddedEtcd(testCtx, etcdDir, logger, utils.DefaultEtcdName, embeddedEtcdPortNo)
+	Expect(err).ShouldNot(HaveOccurred())
+	defer func() {
+		etcd.Server.Stop()
+		etcd.Close()
+	}()
+	endpoints := []string{etcd.Clients[0].Addr().String()}
+
+	resp := &utils.EtcdDataPopulationResponse{}
+	utils.PopulateEtcd(testCtx, logger, endpoints, 0, 5, resp)
+	Expect(resp.Err).ShouldNot(HaveOccurred())
+
+	// only the full snapshot is taken, as delta snapshots are disabled
+	ctx, cancel := context.WithTimeout(testCtx, 5*time.Second)
+	defer cancel()
+	snapstoreConfig := brtypes.SnapstoreConfig{Container: snapstoreDir, Provider: "Local"}
+	Expect(utils.RunSnapshotter(logger, snapstoreConfig, 0, endpoints, ctx.Done(), true, compressor.NewCompressorConfig())).To(Succeed())
+	return data
+}, func(data []byte) {})
+
+var _ = SynchronizedAfterSuite(func() {}, func() {
+	Expect(os.RemoveAll(outputDir)).To(Succeed())
+})
@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package initializer
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gardener/etcd-backup-restore/pkg/events"
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+	"go.etcd.io/etcd/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Restoring the corrupt data directory", func() {
+	var (
+		snapstoreConfig *brtypes.SnapstoreConfig
+		recorder        *events.FakeRecorder
+	)
+
+	BeforeEach(func() {
+		for name, value := range map[string]string{"POD_NAME": "etcd-main-0", "POD_NAMESPACE": "default"} {
+			Expect(os.Setenv(name, value)).To(Succeed())
+			DeferCleanup(os.Unsetenv, name)
+		}
+		snapstoreConfig = &brtypes.SnapstoreConfig{Container: snapstoreDir, Provider: "Local"}
+		recorder = events.NewFakeRecorder()
+	})
+
+	newInitializer := func() *EtcdInitializer {
+		restorationConfig := brtypes.NewRestorationConfig()
+		restorationConfig.DataDir = filepath.Join(outputDir, "restored.etcd")
+		restorationConfig.TempSnapshotsDir = filepath.Join(outputDir, "restoration.tmp")
+		DeferCleanup(os.RemoveAll, restorationConfig.DataDir)
+		DeferCleanup(os.RemoveAll, restorationConfig.TempSnapshotsDir)
+		clusterURLs, err := types.NewURLsMap(restorationConfig.InitialCluster)
+		Expect(err).ShouldNot(HaveOccurred())
+		peerURLs, err := types.NewURLs(restorationConfig.InitialAdvertisePeerURLs)
+		Expect(err).ShouldNot(HaveOccurred())
+		restoreOptions := &brtypes.RestoreOptions{
+			Config:      restorationConfig,
+			ClusterURLs: clusterURLs,
+			PeerURLs:    peerURLs,
+		}
+
+		etcdConnectionConfig := brtypes.NewEtcdConnectionConfig()
+		e, err := NewInitializer(restoreOptions, snapstoreConfig, etcdConnectionConfig, logger.Logger)
+		Expect(err).ShouldNot(HaveOccurred())
+		e.EventRecorder = recorder
+		return e
+	}
+
+	Context("with the Kubernetes events enabled", func() {
+		It("should emit the events of a successful restoration", func() {
+			restored, err := newInitializer().restoreCorruptData(testCtx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(restored).Should(BeTrue())
+
+			recorded := recorder.Events()
+			Expect(recorded).Should(HaveLen(2))
+			Expect(recorded[0]).Should(HavePrefix(fmt.Sprintf("%s %s Restoring the data directory from full snapshot ", corev1.EventTypeNormal, events.ReasonRestorationStarted)))
+			Expect(recorded[1]).Should(HavePrefix(fmt.Sprintf("%s %s Restored the data directory up to revision ", corev1.EventTypeNormal, events.ReasonRestorationSucceeded)))
+		})
+
+		It("should emit the events of a failed restoration", func() {
+			// the full snapshot is corrupted in a copy of the snapstore
+			snapstoreConfig.Container = "corrupted.bkp"
+			corruptedDir := filepath.Join(outputDir, snapstoreConfig.Container)
+			DeferCleanup(os.RemoveAll, corruptedDir)
+			Expect(filepath.WalkDir(filepath.Join(outputDir, snapstoreDir), func(path string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return err
+				}
+				rel, err := filepath.Rel(filepath.Join(outputDir, snapstoreDir), path)
+				if err != nil {
+					return err
+				}
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				if strings.HasPrefix(d.Name(), brtypes.SnapshotKindFull) {
+					data = []byte("corrupted-full-snapshot")
+				}
+				target := filepath.Join(corruptedDir, rel)
+				if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+					return err
+				}
+				return os.WriteFile(target, data, 0600)
+			})).To(Succeed())
+
+			restored, err := newInitializer().restoreCorruptData(testCtx)
+			Expect(err).Should(HaveOccurred())
+			Expect(restored).Should(BeFalse())
+
+			recorded := recorder.Events()
+			Expect(recorded).Should(HaveLen(2))
+			Expect(recorded[0]).Should(HavePrefix(fmt.Sprintf("%s %s ", corev1.EventTypeNormal, events.ReasonRestorationStarted)))
+			Expect(recorded[1]).Should(HavePrefix(fmt.Sprintf("%s %s Restoring the data directory failed: ", corev1.EventTypeWarning, events.ReasonRestorationFailed)))
+		})
+	})
+
+	It("should validate the member and WAL sub-paths of the restoration config", func() {
+		restoreOptions := &brtypes.RestoreOptions{Config: brtypes.NewRestorationConfig()}
+		restoreOptions.Config.MemberSubPath = "data/member"
+		restoreOptions.Config.WALSubPath = "/var/etcd/wal"
+		e, err := NewInitializer(restoreOptions, snapstoreConfig, brtypes.NewEtcdConnectionConfig(), logger.Logger)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(e.Validator.Config.MemberSubPath).To(Equal("data/member"))
+		Expect(e.Validator.Config.WALSubPath).To(Equal("/var/etcd/wal"))
+	})
+})
+
+var _ = Describe("Waiting for the scale-up after the restoration", func() {
+	var e *EtcdInitializer
+
+	BeforeEach(func() {
+		e = &EtcdInitializer{}
+	})
+
+	It("should return immediately if no scale-up has been started", func() {
+		Expect(e.WaitForScaleUp(testCtx)).To(Succeed())
+	})
+
+	It("should return the error of the finished scale-up", func() {
+		e.scaleUpDone = make(chan struct{})
+		e.scaleUpErr = fmt.Errorf("failed to scale up the cluster after restoration")
+		close(e.scaleUpDone)
+		Expect(e.WaitForScaleUp(testCtx)).To(MatchError(e.scaleUpErr))
+	})
+
+	It("should stop waiting for the scale-up once the context is cancelled", func() {
+		e.scaleUpDone = make(chan struct{})
+		ctx, cancel := context.WithCancel(testCtx)
+		cancel()
+		Expect(e.WaitForScaleUp(ctx)).To(MatchError(context.Canceled))
+	})
+})
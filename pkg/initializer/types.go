@@ -5,6 +5,9 @@
 package initializer
 
 import (
+	"sync"
+
+	"github.com/gardener/etcd-backup-restore/pkg/events"
 	"github.com/gardener/etcd-backup-restore/pkg/initializer/validator"
 	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
 	"github.com/sirupsen/logrus"
@@ -24,6 +27,21 @@ type EtcdInitializer struct {
 	Validator *validator.DataValidator
 	Config    *Config
 	Logger    *logrus.Logger
+	// EventRecorder records the events of the restoration of the data directory.
+	EventRecorder events.Recorder
+	// initialClusterState is the initial cluster state the member has been initialized for by the last initialization,
+	// empty if the member has not been restored or added to the cluster by it.
+	initialClusterState string
+	// clusterMetadata is the cluster metadata recorded along with the full snapshot the member has been restored from
+	// by the last initialization, if it was restored with the cluster metadata.
+	clusterMetadata *brtypes.ClusterMetadata
+	// scaleUpMutex guards the scale-up of the cluster started by the last initialization.
+	scaleUpMutex sync.Mutex
+	// scaleUpDone is closed once the scale-up of the cluster started by the last initialization has finished, nil if
+	// it hasn't started a scale-up.
+	scaleUpDone chan struct{}
+	// scaleUpErr is the error of the scale-up of the cluster, set before scaleUpDone is closed.
+	scaleUpErr error
 }
 
 // Initializer is the interface for etcd initialization actions.
@@ -49,9 +49,22 @@ var (
 	isBoltDBPanic = false
 )
 
-func (d *DataValidator) memberDir() string { return filepath.Join(d.Config.DataDir, "member") }
+func (d *DataValidator) memberDir() string {
+	if d.Config.MemberSubPath != "" {
+		return filepath.Join(d.Config.DataDir, d.Config.MemberSubPath)
+	}
+	return filepath.Join(d.Config.DataDir, defaultMemberSubPath)
+}
 
-func (d *DataValidator) walDir() string { return filepath.Join(d.memberDir(), "wal") }
+func (d *DataValidator) walDir() string {
+	if filepath.IsAbs(d.Config.WALSubPath) {
+		return d.Config.WALSubPath
+	}
+	if d.Config.WALSubPath != "" {
+		return filepath.Join(d.Config.DataDir, d.Config.WALSubPath)
+	}
+	return filepath.Join(d.memberDir(), "wal")
+}
 
 func (d *DataValidator) snapDir() string { return filepath.Join(d.memberDir(), "snap") }
 
@@ -163,7 +176,7 @@ func (d *DataValidator) sanityCheck(failBelowRevision int64) (DataDirStatus, err
 	//   check the etcd revision consistency by starting an embedded etcd since the WALs file can have uncommited data which it was unable to flush to Bolt DB
 	if etcdRevisionStatus == RevisionConsistencyError {
 		d.Logger.Info("Checking for Full revision consistency...")
-		fullRevisionConsistencyStatus, err := d.checkFullRevisionConsistency(dataDir, latestSnapshotRevision)
+		fullRevisionConsistencyStatus, err := d.checkFullRevisionConsistency(latestSnapshotRevision)
 		return fullRevisionConsistencyStatus, err
 	}
 
@@ -349,17 +362,24 @@ func (d *DataValidator) checkEtcdDataRevisionConsistency(etcdRevision, failBelow
 
 // checkFullRevisionConsistency starts an embedded etcd and then compares the latest revision of etcd db file and the latest snapshot revision to verify that the etcd revision is not lesser than snapshot revision.
 // Return DataDirStatus indicating whether WALs file have uncommited data which it was unable to flush to DB or latest DB revision is still less than snapshot revision.
-func (d *DataValidator) checkFullRevisionConsistency(dataDir string, latestSnapshotRevision int64) (DataDirStatus, error) {
+func (d *DataValidator) checkFullRevisionConsistency(latestSnapshotRevision int64) (DataDirStatus, error) {
 	var latestSyncedEtcdRevision int64
 
+	// etcd always keeps its member directory at the `member` sub-path of its data directory
+	memberDir := d.memberDir()
+	if filepath.Base(memberDir) != defaultMemberSubPath {
+		return DataDirectoryStatusUnknown, fmt.Errorf("unable to start an embedded etcd with the member directory %s, which is not named %s", memberDir, defaultMemberSubPath)
+	}
+
 	d.Logger.Info("Starting embedded etcd server...")
 	ro := &brtypes.RestoreOptions{
 		Config: &brtypes.RestorationConfig{
-			DataDir:                dataDir,
+			DataDir:                filepath.Dir(memberDir),
 			EmbeddedEtcdQuotaBytes: d.Config.EmbeddedEtcdQuotaBytes,
 			MaxRequestBytes:        defaultMaxRequestBytes,
 			MaxTxnOps:              defaultMaxTxnOps,
 		},
+		WALDir: d.walDir(),
 	}
 	e, err := miscellaneous.StartEmbeddedEtcd(logrus.NewEntry(d.Logger), ro)
 	if err != nil {
@@ -408,6 +428,29 @@ waitLoop:
 	return DataDirectoryValid, nil
 }
 
+// CheckRestoreRevisionGap checks that the latest revision of the etcd db file is not ahead of the given latest snapshot
+// revision by more than maxRevisionGap revisions, so that the data directory is not replaced by the restoration of a
+// snapshot far behind it by mistake. The check is disabled if maxRevisionGap is 0, and only logged if force is set.
+// It passes if the revision of the db file can't be read, as there is no data left to protect then.
+func (d *DataValidator) CheckRestoreRevisionGap(latestSnapshotRevision, maxRevisionGap int64, force bool) error {
+	if maxRevisionGap == 0 {
+		return nil
+	}
+	etcdRevision, err := getLatestEtcdRevision(d.backendPath())
+	if err != nil {
+		d.Logger.Infof("Skipping check for revision gap to the latest snapshot, since the etcd revision can't be read: %v", err)
+		return nil
+	}
+	if etcdRevision-latestSnapshotRevision <= maxRevisionGap {
+		return nil
+	}
+	if force {
+		d.Logger.Warnf("current etcd revision (%d) is ahead of latest snapshot revision (%d) by more than %d revisions: forcing the restoration", etcdRevision, latestSnapshotRevision, maxRevisionGap)
+		return nil
+	}
+	return fmt.Errorf("current etcd revision (%d) is ahead of latest snapshot revision (%d) by more than %d revisions: restoration has to be forced", etcdRevision, latestSnapshotRevision, maxRevisionGap)
+}
+
 // getLatestEtcdRevision finds out the latest revision on the etcd db file without starting etcd server or an embedded etcd server.
 func getLatestEtcdRevision(path string) (int64, error) {
 	if _, err := os.Stat(path); err != nil {
@@ -297,6 +297,174 @@ var _ = Describe("Running Datavalidator", func() {
 		})
 	})
 
+	Context("with the WAL directory moved out of the member directory and WALs file have some uncommitted data", func() {
+		It("should replay the WALs from the moved WAL directory, and return DataDirStatus as DataDirectoryValid and nil error", func() {
+			const walSubPath = "etcd-wal"
+			snapPath := path.Join(restoreDataDir, "member", "snap")
+			tempPath := path.Join(outputDir, "temp")
+
+			err = copyDir(snapPath, tempPath)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer func() {
+				tempDirInfo, err := os.Stat(tempPath)
+				Expect(err).ShouldNot(HaveOccurred())
+				err = os.RemoveAll(tempPath)
+				Expect(err).ShouldNot(HaveOccurred())
+				err = os.Mkdir(tempPath, tempDirInfo.Mode())
+				Expect(err).ShouldNot(HaveOccurred())
+			}()
+
+			etcd, err := utils.StartEmbeddedEtcd(testCtx, restoreDataDir, logger, utils.DefaultEtcdName, embeddedEtcdPortNo)
+			Expect(err).ShouldNot(HaveOccurred())
+			endpoints := []string{etcd.Clients[0].Addr().String()}
+
+			resp := &utils.EtcdDataPopulationResponse{}
+			utils.PopulateEtcd(testCtx, logger, endpoints, 0, int(keyTo/2), resp)
+			Expect(resp.Err).ShouldNot(HaveOccurred())
+
+			deltaSnapshotPeriod := 5 * time.Second
+			ctx, cancel := context.WithTimeout(testCtx, time.Duration(15*time.Second))
+			err = runSnapshotter(logger, deltaSnapshotPeriod, endpoints, ctx.Done())
+			Expect(err).ShouldNot(HaveOccurred())
+
+			etcd.Close()
+			cancel()
+
+			// restore the old db file, so that the WALs file have data which is ahead of the db file
+			err = os.RemoveAll(snapPath)
+			Expect(err).ShouldNot(HaveOccurred())
+			err = os.Mkdir(snapPath, 0777)
+			Expect(err).ShouldNot(HaveOccurred())
+			err = copyDir(tempPath, snapPath)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			// move the WAL directory out of the member directory
+			err = os.Rename(path.Join(restoreDataDir, "member", "wal"), path.Join(restoreDataDir, walSubPath))
+			Expect(err).ShouldNot(HaveOccurred())
+			defer func() {
+				err = os.Rename(path.Join(restoreDataDir, walSubPath), path.Join(restoreDataDir, "member", "wal"))
+				Expect(err).ShouldNot(HaveOccurred())
+			}()
+
+			validator.Config.WALSubPath = walSubPath
+			dataDirStatus, err := validator.Validate(Full, 0)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(int(dataDirStatus)).Should(Equal(DataDirectoryValid))
+		})
+	})
+
+	Context("with the etcd revision far ahead of the latest snapshot revision before a restoration", func() {
+		var (
+			latestSnapshotRevision int64
+			maxRevisionGap         int64
+		)
+
+		BeforeEach(func() {
+			// etcdRevision: current revision number on etcd db, which is treated as far ahead of a snapshot of revision 1
+			Expect(etcdRevision).To(BeNumerically(">", 2))
+			latestSnapshotRevision = 1
+			maxRevisionGap = etcdRevision / 2
+		})
+
+		It("should block the restoration if it is not forced", func() {
+			err := validator.CheckRestoreRevisionGap(latestSnapshotRevision, maxRevisionGap, false)
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("restoration has to be forced"))
+		})
+
+		It("should allow the restoration if it is forced", func() {
+			Expect(validator.CheckRestoreRevisionGap(latestSnapshotRevision, maxRevisionGap, true)).To(Succeed())
+		})
+
+		It("should allow the restoration if the revision gap is within the maximum revision gap", func() {
+			Expect(validator.CheckRestoreRevisionGap(latestSnapshotRevision, math.MaxInt64, false)).To(Succeed())
+		})
+
+		It("should allow the restoration if the check is disabled", func() {
+			Expect(validator.CheckRestoreRevisionGap(latestSnapshotRevision, 0, false)).To(Succeed())
+		})
+
+		It("should allow the restoration if the data directory does not exist", func() {
+			tempDir := fmt.Sprintf("%s.%s", restoreDataDir, "temp")
+			err = os.Rename(restoreDataDir, tempDir)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer func() {
+				err = os.Rename(tempDir, restoreDataDir)
+				Expect(err).ShouldNot(HaveOccurred())
+			}()
+
+			Expect(validator.CheckRestoreRevisionGap(latestSnapshotRevision, maxRevisionGap, false)).To(Succeed())
+		})
+	})
+
+	Context("with a non-default data directory layout", func() {
+		const (
+			memberSubPath = "etcd-member"
+			walSubPath    = "etcd-wal"
+		)
+
+		BeforeEach(func() {
+			// move the member directory, and the WAL directory out of the member directory
+			err = os.Rename(path.Join(restoreDataDir, "member"), path.Join(restoreDataDir, memberSubPath))
+			Expect(err).ShouldNot(HaveOccurred())
+			err = os.Rename(path.Join(restoreDataDir, memberSubPath, "wal"), path.Join(restoreDataDir, walSubPath))
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			err = os.Rename(path.Join(restoreDataDir, walSubPath), path.Join(restoreDataDir, memberSubPath, "wal"))
+			Expect(err).ShouldNot(HaveOccurred())
+			err = os.Rename(path.Join(restoreDataDir, memberSubPath), path.Join(restoreDataDir, "member"))
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("should return DataDirStatus as DataDirectoryInvStruct, and nil error without the sub-paths configured", func() {
+			dataDirStatus, err := validator.Validate(Full, 0)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(int(dataDirStatus)).Should(Equal(DataDirectoryInvStruct))
+		})
+
+		It("should return DataDirStatus as DataDirectoryValid, and nil error with the sub-paths configured", func() {
+			validator.Config.MemberSubPath = memberSubPath
+			validator.Config.WALSubPath = walSubPath
+			dataDirStatus, err := validator.Validate(Full, 0)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(int(dataDirStatus)).Should(Equal(DataDirectoryValid))
+		})
+
+		It("should return DataDirStatus as DataDirectoryValid, and nil error with an absolute WAL path configured", func() {
+			validator.Config.MemberSubPath = memberSubPath
+			validator.Config.WALSubPath = path.Join(restoreDataDir, walSubPath)
+			dataDirStatus, err := validator.Validate(Full, 0)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(int(dataDirStatus)).Should(Equal(DataDirectoryValid))
+		})
+
+		It("should return DataDirStatus as DataDirectoryCorrupt, and nil error with a corrupt db file", func() {
+			validator.Config.MemberSubPath = memberSubPath
+			validator.Config.WALSubPath = walSubPath
+
+			dbFile := path.Join(restoreDataDir, memberSubPath, "snap", "db")
+			dbFileInfo, err := os.Stat(dbFile)
+			Expect(err).ShouldNot(HaveOccurred())
+			tempFile := path.Join(outputDir, "temp", "db")
+			err = copyFile(dbFile, tempFile, dbFileInfo.Mode())
+			Expect(err).ShouldNot(HaveOccurred())
+			defer func() {
+				err = os.Rename(tempFile, dbFile)
+				Expect(err).ShouldNot(HaveOccurred())
+			}()
+
+			// corrupt the db file by writing random data to it
+			err = os.WriteFile(dbFile, []byte("Random data!\n"), 0666)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			dataDirStatus, err := validator.Validate(Full, 0)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(int(dataDirStatus)).Should(Equal(DataDirectoryCorrupt))
+		})
+	})
+
 	Context("with fail below revision configured to low value and no snapshots taken", func() {
 		It("should return DataDirStatus as DataDirectoryValid, and nil error", func() {
 			validator.Config.SnapstoreConfig.Container = path.Join(snapstoreBackupDir, "tmp")
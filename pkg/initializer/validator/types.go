@@ -42,6 +42,7 @@ const (
 
 const (
 	snapSuffix                    = ".snap"
+	defaultMemberSubPath          = "member"
 	connectionTimeout             = time.Duration(10 * time.Second)
 	embeddedEtcdPingLimitDuration = 60 * time.Second
 	timeoutToOpenBoltDB           = 120 * time.Second
@@ -64,6 +65,13 @@ type Config struct {
 	DataDir                string
 	EmbeddedEtcdQuotaBytes int64
 	SnapstoreConfig        *brtypes.SnapstoreConfig
+	// MemberSubPath is the path of the member directory relative to the data directory.
+	// Defaults to `member` if not set.
+	MemberSubPath string
+	// WALSubPath is the path of the WAL directory relative to the data directory, for etcd
+	// deployments which have moved the WAL directory out of the member directory. An absolute
+	// path is used as is. Defaults to `wal` within the member directory if not set.
+	WALSubPath string
 }
 
 // DataValidator contains implements Validator interface to perform data validation.
@@ -7,11 +7,13 @@ package leaderelection
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"time"
 
 	"github.com/gardener/etcd-backup-restore/pkg/errors"
 	"github.com/gardener/etcd-backup-restore/pkg/etcdutil"
 	"github.com/gardener/etcd-backup-restore/pkg/metrics"
+	"github.com/gardener/etcd-backup-restore/pkg/miscellaneous"
 	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
@@ -45,6 +47,8 @@ type LeaderElector struct {
 	LeaseCallbacks       *brtypes.MemberLeaseCallbacks
 	PromoteCallback      *brtypes.PromoteLearnerCallback
 	CheckMemberStatus    brtypes.EtcdMemberStatusCallbackFunc
+	// learnerInSyncSince is the time since which the raft index lag of the learner is within the configured threshold.
+	learnerInSyncSince time.Time
 }
 
 // NewLeaderElector returns LeaderElector configurations.
@@ -68,6 +72,18 @@ func (le *LeaderElector) Run(ctx context.Context) error {
 	var leCtx context.Context
 	var leCancel context.CancelFunc
 
+	// delay the first leadership status check, so that the backup-restore
+	// sidecars of a restarting cluster don't all start electing at once.
+	if startupDelay := le.startupDelay(); startupDelay > 0 {
+		le.logger.Infof("Delaying leaderElection by %v...", startupDelay)
+		select {
+		case <-ctx.Done():
+			le.logger.Info("Shutting down LeaderElection...")
+			return nil
+		case <-time.After(startupDelay):
+		}
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -93,6 +109,7 @@ func (le *LeaderElector) Run(ctx context.Context) error {
 					le.Callbacks.OnStoppedLeading()
 				}
 				le.CurrentState = StateUnknown
+				le.learnerInSyncSince = time.Time{}
 				le.logger.Infof("backup-restore is in: %v", le.CurrentState)
 				le.logger.Info("waiting for Re-election...")
 				continue
@@ -143,13 +160,57 @@ func (le *LeaderElector) Run(ctx context.Context) error {
 				if isLearner && le.PromoteCallback != nil {
 					metrics.IsLearner.With(prometheus.Labels{}).Set(1)
 					le.logger.Info("member is a learner(non-voting) member in the cluster...")
-					le.PromoteCallback.Promote(ctx, le.logger)
+					if le.isLearnerReadyForPromotion(ctx) {
+						le.PromoteCallback.Promote(ctx, le.logger)
+					}
+				} else {
+					le.learnerInSyncSince = time.Time{}
 				}
 			}
 		}
 	}
 }
 
+// startupDelay returns a random delay within the configured startup delay range.
+func (le *LeaderElector) startupDelay() time.Duration {
+	minDelay, maxDelay := le.Config.MinStartupDelay.Duration, le.Config.MaxStartupDelay.Duration
+	if maxDelay <= minDelay {
+		return minDelay
+	}
+	return minDelay + time.Duration(rand.Int63n(int64(maxDelay-minDelay)+1))
+}
+
+// isLearnerReadyForPromotion checks whether the raft index lag of the learner has stayed within the configured threshold
+// for the configured stability period, so that a learner which only briefly catches up with the leader under heavy write
+// load is not promoted.
+func (le *LeaderElector) isLearnerReadyForPromotion(ctx context.Context) bool {
+	if le.Config.LearnerPromotionMaxRaftIndexLag == 0 || le.PromoteCallback.RaftIndexLag == nil {
+		return true
+	}
+
+	lag, err := le.PromoteCallback.RaftIndexLag(ctx, le.logger)
+	if err != nil {
+		le.logger.Errorf("failed to get the raft index lag of the learner: %v", err)
+		le.learnerInSyncSince = time.Time{}
+		return false
+	}
+	if lag > le.Config.LearnerPromotionMaxRaftIndexLag {
+		le.logger.Infof("learner lags %d raft indices behind the leader, more than the allowed %d, delaying its promotion...", lag, le.Config.LearnerPromotionMaxRaftIndexLag)
+		le.learnerInSyncSince = time.Time{}
+		return false
+	}
+
+	now := time.Now()
+	if le.learnerInSyncSince.IsZero() {
+		le.learnerInSyncSince = now
+	}
+	if inSyncFor := now.Sub(le.learnerInSyncSince); inSyncFor < le.Config.LearnerPromotionStabilityPeriod.Duration {
+		le.logger.Infof("learner lags %d raft indices behind the leader, within the allowed %d for %v, delaying its promotion until %v...", lag, le.Config.LearnerPromotionMaxRaftIndexLag, inSyncFor, le.Config.LearnerPromotionStabilityPeriod.Duration)
+		return false
+	}
+	return true
+}
+
 // EtcdMemberStatus checks whether the current instance of backup-restore is leader or not.
 // It also returns the boolean indicating the presence of learner(non-voting) member.
 func EtcdMemberStatus(ctx context.Context, etcdConnectionConfig *brtypes.EtcdConnectionConfig, etcdConnectionTimeout time.Duration, logger *logrus.Entry) (bool, bool, error) {
@@ -192,3 +253,55 @@ func EtcdMemberStatus(ctx context.Context, etcdConnectionConfig *brtypes.EtcdCon
 
 	return false, false, nil
 }
+
+// EtcdLearnerRaftIndexLag returns the number of raft indices by which the etcd member lags behind the etcd leader.
+func EtcdLearnerRaftIndexLag(ctx context.Context, etcdConnectionConfig *brtypes.EtcdConnectionConfig, etcdConnectionTimeout time.Duration, logger *logrus.Entry) (uint64, error) {
+	if len(etcdConnectionConfig.Endpoints) == 0 {
+		return 0, fmt.Errorf("etcd endpoints are not passed correctly")
+	}
+	endPoint := etcdConnectionConfig.Endpoints[0]
+
+	factory := etcdutil.NewFactory(*etcdConnectionConfig)
+	clientMaintenance, err := factory.NewMaintenance()
+	if err != nil {
+		return 0, &errors.EtcdError{
+			Message: fmt.Sprintf("failed to create etcd maintenance client: %v", err),
+		}
+	}
+	defer clientMaintenance.Close()
+
+	clientCluster, err := factory.NewCluster()
+	if err != nil {
+		return 0, &errors.EtcdError{
+			Message: fmt.Sprintf("failed to create etcd cluster client: %v", err),
+		}
+	}
+	defer clientCluster.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, etcdConnectionTimeout)
+	defer cancel()
+
+	response, err := clientMaintenance.Status(ctx, endPoint)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get status of etcd endPoint: %v with error: %v", endPoint, err)
+	}
+
+	_, leaderEndPoints, err := miscellaneous.GetLeader(ctx, clientMaintenance, clientCluster, endPoint)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the etcd leader: %v", err)
+	}
+	if len(leaderEndPoints) == 0 {
+		return 0, fmt.Errorf("client URLs of the etcd leader are not known")
+	}
+
+	leaderResponse, err := clientMaintenance.Status(ctx, leaderEndPoints[0])
+	if err != nil {
+		return 0, fmt.Errorf("failed to get status of etcd leader endPoint: %v with error: %v", leaderEndPoints[0], err)
+	}
+
+	if leaderResponse.RaftIndex <= response.RaftIndex {
+		return 0, nil
+	}
+	logger.Debugf("raft index of the member is %d, of the leader %d", response.RaftIndex, leaderResponse.RaftIndex)
+	return leaderResponse.RaftIndex - response.RaftIndex, nil
+}
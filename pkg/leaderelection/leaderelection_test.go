@@ -13,6 +13,7 @@ import (
 
 	. "github.com/gardener/etcd-backup-restore/pkg/leaderelection"
 	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+	"github.com/gardener/etcd-backup-restore/pkg/wrappers"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -193,5 +194,123 @@ var _ = Describe("Etcd Cluster", func() {
 				Expect(promoteLearnerCount).Should(Equal(minCount))
 			})
 		})
+
+		Context("Etcd member is learner with a promotion threshold configured", func() {
+			var (
+				lags            []uint64
+				lagChecks       int
+				promotedAtCheck int
+			)
+
+			BeforeEach(func() {
+				promoteLearnerCount = 0
+				learnerToVotingMember = 0
+				lagChecks = 0
+				promotedAtCheck = 0
+				config.LearnerPromotionMaxRaftIndexLag = 100
+				config.LearnerPromotionStabilityPeriod = wrappers.Duration{Duration: 1500 * time.Millisecond}
+				le.PromoteCallback.RaftIndexLag = func(_ context.Context, _ *logrus.Entry) (uint64, error) {
+					lag := lags[len(lags)-1]
+					if lagChecks < len(lags) {
+						lag = lags[lagChecks]
+					}
+					lagChecks++
+					return lag, nil
+				}
+				promote := le.PromoteCallback.Promote
+				le.PromoteCallback.Promote = func(ctx context.Context, logger *logrus.Entry) {
+					promotedAtCheck = lagChecks
+					promote(ctx, logger)
+				}
+				le.CheckMemberStatus = func(_ context.Context, _ *brtypes.EtcdConnectionConfig, _ time.Duration, _ *logrus.Entry) (bool, bool, error) {
+					return false, learnerToVotingMember == 0, nil
+				}
+			})
+
+			It("should promote the learner only once its lag stays within the threshold for the stability period", func() {
+				ctx, cancel := context.WithTimeout(testCtx, 8*time.Second)
+				defer cancel()
+
+				// the lag oscillates around the threshold before it stays within it from the fourth check onwards
+				lags = []uint64{500, 10, 600, 10, 20, 30}
+
+				err := le.Run(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(promoteLearnerCount).Should(Equal(1))
+				// the lag is within the threshold since the fourth check, which is the stability period before the sixth check
+				Expect(promotedAtCheck).Should(Equal(6))
+			})
+
+			It("should not promote the learner while its lag keeps exceeding the threshold", func() {
+				ctx, cancel := context.WithTimeout(testCtx, 8*time.Second)
+				defer cancel()
+
+				lags = []uint64{10, 500, 10, 500, 10, 500, 10, 500}
+
+				err := le.Run(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(lagChecks).Should(BeNumerically(">=", 6))
+				Expect(promoteLearnerCount).Should(BeZero())
+			})
+
+			It("should not promote the learner if its lag can't be determined", func() {
+				ctx, cancel := context.WithTimeout(testCtx, mockTimeout)
+				defer cancel()
+
+				le.PromoteCallback.RaftIndexLag = func(_ context.Context, _ *logrus.Entry) (uint64, error) {
+					lagChecks++
+					return 0, fmt.Errorf("unable to connect to the etcd leader")
+				}
+
+				err := le.Run(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(lagChecks).Should(BeNumerically(">", 0))
+				Expect(promoteLearnerCount).Should(BeZero())
+			})
+		})
+
+		Context("With startup delay configured", func() {
+			BeforeEach(func() {
+				config.MinStartupDelay = wrappers.Duration{Duration: 2 * time.Second}
+				config.MaxStartupDelay = wrappers.Duration{Duration: 3 * time.Second}
+			})
+
+			It("should wait within the configured range before the first member status check", func() {
+				ctx, cancel := context.WithTimeout(testCtx, 2*mockTimeout)
+				defer cancel()
+
+				var firstCheckDelay time.Duration
+				start := time.Now()
+				le.CheckMemberStatus = func(_ context.Context, _ *brtypes.EtcdConnectionConfig, _ time.Duration, _ *logrus.Entry) (bool, bool, error) {
+					if firstCheckDelay == 0 {
+						firstCheckDelay = time.Since(start)
+						cancel()
+					}
+					return false, false, nil
+				}
+
+				err := le.Run(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				// the first member status check happens one reelection period after the startup delay
+				Expect(firstCheckDelay).Should(BeNumerically(">=", config.MinStartupDelay.Duration+config.ReelectionPeriod.Duration))
+				Expect(firstCheckDelay).Should(BeNumerically("<", config.MaxStartupDelay.Duration+config.ReelectionPeriod.Duration+time.Second))
+			})
+
+			It("should not check the member status if stopped during the startup delay", func() {
+				ctx, cancel := context.WithTimeout(testCtx, time.Second)
+				defer cancel()
+
+				checks := 0
+				le.CheckMemberStatus = func(_ context.Context, _ *brtypes.EtcdConnectionConfig, _ time.Duration, _ *logrus.Entry) (bool, bool, error) {
+					checks++
+					return false, false, nil
+				}
+
+				err := le.Run(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(checks).Should(BeZero())
+				Expect(le.CurrentState).Should(Equal(DefaultCurrentState))
+			})
+		})
 	})
 })
@@ -8,7 +8,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	utilError "github.com/gardener/etcd-backup-restore/pkg/errors"
@@ -23,6 +25,7 @@ import (
 	"go.etcd.io/etcd/etcdserver/api/v3rpc/rpctypes"
 	"go.etcd.io/etcd/etcdserver/etcdserverpb"
 	v1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -71,6 +74,16 @@ type Control interface {
 	IsLearnerPresent(context.Context) (bool, error)
 }
 
+// ScaleUpControl interface defines the functionalities needed to add other members to the etcd cluster.
+type ScaleUpControl interface {
+	// AddLearnerMember adds the member with the given name as a learner to the etcd cluster.
+	AddLearnerMember(context.Context, string) error
+
+	// PromoteLearnerMember promotes the learner with the given name to a voting member of the cluster.
+	// This will succeed if and only if learner is in a healthy state and the learner is in sync with leader.
+	PromoteLearnerMember(context.Context, string) error
+}
+
 // memberControl holds the configuration for the mechanism of adding a new member to the cluster.
 type memberControl struct {
 	clientFactory etcdClient.Factory
@@ -78,10 +91,21 @@ type memberControl struct {
 	podName       string
 	configFile    string
 	podNamespace  string
+	// peerTLS is set if the members communicate with their peers using mTLS.
+	peerTLS bool
 }
 
 // NewMemberControl returns new ExponentialBackoff.
 func NewMemberControl(etcdConnConfig *brtypes.EtcdConnectionConfig) Control {
+	return newMemberControl(etcdConnConfig)
+}
+
+// NewScaleUpControl returns a ScaleUpControl which adds other members to the etcd cluster.
+func NewScaleUpControl(etcdConnConfig *brtypes.EtcdConnectionConfig) ScaleUpControl {
+	return newMemberControl(etcdConnConfig)
+}
+
+func newMemberControl(etcdConnConfig *brtypes.EtcdConnectionConfig) *memberControl {
 	var configFile string
 	logger := logrus.New().WithField("actor", "member-add")
 	etcdConn := *etcdConnConfig
@@ -107,13 +131,14 @@ func NewMemberControl(etcdConnConfig *brtypes.EtcdConnectionConfig) Control {
 		podName:       podName,
 		configFile:    configFile,
 		podNamespace:  podNamespace,
+		peerTLS:       etcdConnConfig.PeerTLSEnabled(),
 	}
 }
 
 // AddMemberAsLearner add a member as a learner to the etcd cluster
 func (m *memberControl) AddMemberAsLearner(ctx context.Context) error {
 	//Add member as learner to cluster
-	memberURL, err := getMemberPeerURL(m.configFile, m.podName)
+	memberURL, err := m.getMemberPeerURL(m.podName)
 	if err != nil {
 		m.logger.Fatalf("Error fetching etcd member URL : %v", err)
 	}
@@ -148,6 +173,64 @@ func (m *memberControl) AddMemberAsLearner(ctx context.Context) error {
 	return nil
 }
 
+// AddLearnerMember adds the member with the given name as a learner to the etcd cluster
+func (m *memberControl) AddLearnerMember(ctx context.Context, memberName string) error {
+	memberURL, err := m.getMemberPeerURL(memberName)
+	if err != nil {
+		return fmt.Errorf("error fetching etcd member URL : %v", err)
+	}
+
+	cli, err := m.clientFactory.NewCluster()
+	if err != nil {
+		return fmt.Errorf("failed to build etcd cluster client : %v", err)
+	}
+	defer cli.Close()
+
+	memAddCtx, cancel := context.WithTimeout(ctx, EtcdTimeout)
+	defer cancel()
+	response, err := cli.MemberAddAsLearner(memAddCtx, []string{memberURL})
+	if err != nil {
+		if errors.Is(err, rpctypes.Error(rpctypes.ErrGRPCPeerURLExist)) || errors.Is(err, rpctypes.Error(rpctypes.ErrGRPCMemberExist)) {
+			m.logger.Infof("Member %s already part of etcd cluster", memberURL)
+			return nil
+		}
+		return fmt.Errorf("error while adding member %s as a learner: %w", memberName, err)
+	}
+
+	m.logger.Infof("Added member %s [ID: %v] to cluster as a learner", memberName, strconv.FormatUint(response.Member.GetID(), 16))
+	return nil
+}
+
+// PromoteLearnerMember promotes the learner with the given name to a voting member of the cluster. This will succeed only if its logs are caught up with the leader
+func (m *memberControl) PromoteLearnerMember(ctx context.Context, memberName string) error {
+	m.logger.Infof("Attempting to promote member %s", memberName)
+	memberURL, err := m.getMemberPeerURL(memberName)
+	if err != nil {
+		return fmt.Errorf("error fetching etcd member URL : %v", err)
+	}
+
+	cli, err := m.clientFactory.NewCluster()
+	if err != nil {
+		return fmt.Errorf("failed to build etcd cluster client : %v", err)
+	}
+	defer cli.Close()
+
+	memListCtx, memListCtxCancel := context.WithTimeout(ctx, brtypes.DefaultEtcdConnectionTimeout)
+	defer memListCtxCancel()
+	etcdList, err := cli.MemberList(memListCtx)
+	if err != nil {
+		return fmt.Errorf("error listing members: %v", err)
+	}
+
+	// The name of a learner appears in the member list only once it has started, hence look it up by its peer URL.
+	foundMember := findMemberByPeerURL(etcdList.Members, memberURL)
+	if foundMember == nil {
+		return ErrMissingMember
+	}
+
+	return miscellaneous.DoPromoteMember(ctx, foundMember, cli, &m.logger)
+}
+
 // IsMemberInCluster checks is the current members peer URL is already part of the etcd cluster
 func (m *memberControl) IsMemberInCluster(ctx context.Context) (bool, error) {
 	m.logger.Infof("Checking if member %s is part of a running cluster", m.podName)
@@ -198,6 +281,20 @@ func (m *memberControl) IsMemberInCluster(ctx context.Context) (bool, error) {
 	return false, nil
 }
 
+// getMemberPeerURL returns the peer URL of the member with the given name, using the https scheme if peer TLS is enabled.
+func (m *memberControl) getMemberPeerURL(podName string) (string, error) {
+	peerURL, err := getMemberPeerURL(m.configFile, podName)
+	if err != nil || !m.peerTLS {
+		return peerURL, err
+	}
+	u, err := url.Parse(peerURL)
+	if err != nil {
+		return "", fmt.Errorf("could not parse peer URL %s : %v", peerURL, err)
+	}
+	u.Scheme = "https"
+	return u.String(), nil
+}
+
 func getMemberPeerURL(configFile string, podName string) (string, error) {
 	config, err := miscellaneous.ReadConfigFileAsMap(configFile)
 	if err != nil {
@@ -219,7 +316,7 @@ func (m *memberControl) doUpdateMemberPeerAddress(ctx context.Context, cli etcdC
 	// Already existing clusters or cluster after restoration have `http://localhost:2380` as the peer address. This needs to explicitly updated to the correct peer address.
 	m.logger.Infof("Updating member peer URL for %s", m.podName)
 
-	memberPeerURL, err := getMemberPeerURL(m.configFile, m.podName)
+	memberPeerURL, err := m.getMemberPeerURL(m.podName)
 	if err != nil {
 		return fmt.Errorf("could not fetch member URL : %v", err)
 	}
@@ -271,6 +368,17 @@ func findMember(existingMembers []*etcdserverpb.Member, memberName string) *etcd
 	return nil
 }
 
+func findMemberByPeerURL(existingMembers []*etcdserverpb.Member, peerURL string) *etcdserverpb.Member {
+	for _, member := range existingMembers {
+		for _, url := range member.GetPeerURLs() {
+			if url == peerURL {
+				return member
+			}
+		}
+	}
+	return nil
+}
+
 // UpdateMemberPeerURL updates the peer address of a specified etcd cluster member.
 func (m *memberControl) UpdateMemberPeerURL(ctx context.Context, cli etcdClient.ClusterCloser) error {
 	m.logger.Infof("Attempting to update the member Info: %v", m.podName)
@@ -287,7 +395,13 @@ func (m *memberControl) UpdateMemberPeerURL(ctx context.Context, cli etcdClient.
 
 // RemoveMember removes the member from the etcd cluster.
 func (m *memberControl) RemoveMember(ctx context.Context) error {
-	m.logger.Infof("Removing the %s member from cluster", m.podName)
+	return m.RemoveMemberByName(ctx, m.podName)
+}
+
+// RemoveMemberByName removes the member with the given name from the etcd cluster. A learner which has not started
+// yet is looked up by its peer URL, as its name appears in the member list only once it has started.
+func (m *memberControl) RemoveMemberByName(ctx context.Context, memberName string) error {
+	m.logger.Infof("Removing the %s member from cluster", memberName)
 
 	cli, err := m.clientFactory.NewCluster()
 	if err != nil {
@@ -303,9 +417,15 @@ func (m *memberControl) RemoveMember(ctx context.Context) error {
 		return fmt.Errorf("error listing members: %v", err)
 	}
 
-	foundMember := findMember(memberInfo.Members, m.podName)
+	foundMember := findMember(memberInfo.Members, memberName)
 	if foundMember == nil {
-		return nil
+		// the member is considered removed if its peer URL can't be determined either
+		if memberURL, err := m.getMemberPeerURL(memberName); err == nil {
+			foundMember = findMemberByPeerURL(memberInfo.Members, memberURL)
+		}
+		if foundMember == nil {
+			return nil
+		}
 	}
 
 	return miscellaneous.RemoveMemberFromCluster(memRemoveCtx, cli, foundMember.GetID(), &m.logger)
@@ -395,3 +515,72 @@ func AddLearnerWithRetry(ctx context.Context, m Control, retrySteps int, dataDir
 		return nil
 	})
 }
+
+// GetScaleUpMemberNames returns the names of the members which are to be added to the cluster of the given pod,
+// in order to scale it up to the given size. The pods are expected to be named <statefulset-name>-<ordinal>.
+func GetScaleUpMemberNames(podName string, clusterSize int) ([]string, error) {
+	idx := strings.LastIndex(podName, "-")
+	if idx < 0 {
+		return nil, fmt.Errorf("pod name %s does not end with an ordinal", podName)
+	}
+	ordinal, err := strconv.Atoi(podName[idx+1:])
+	if err != nil {
+		return nil, fmt.Errorf("pod name %s does not end with an ordinal: %v", podName, err)
+	}
+
+	var memberNames []string
+	for i := 0; i < clusterSize; i++ {
+		if i != ordinal {
+			memberNames = append(memberNames, fmt.Sprintf("%s-%d", podName[:idx], i))
+		}
+	}
+	return memberNames, nil
+}
+
+// GetClusterMetadataMemberNames returns the names of the voting members of the given cluster metadata other than the
+// given member, which are to be added to the cluster of the given member after it was restored from a full snapshot,
+// in order to rebuild the cluster the snapshot was taken from.
+func GetClusterMetadataMemberNames(metadata *brtypes.ClusterMetadata, memberName string) []string {
+	var memberNames []string
+	for _, member := range metadata.Members {
+		if member.Name != memberName && !member.IsLearner {
+			memberNames = append(memberNames, member.Name)
+		}
+	}
+	return memberNames
+}
+
+// ScaleUpCluster adds the given members one after the other as learners to the etcd cluster,
+// and promotes each of them to a voting member once it is in sync with the leader.
+// Only one learner is added at a time, as etcd allows only a single learner in the cluster.
+func ScaleUpCluster(ctx context.Context, m ScaleUpControl, memberNames []string, retrySteps int, logger *logrus.Entry) error {
+	backoff := miscellaneous.CreateBackoff(RetryPeriod, retrySteps)
+
+	for _, memberName := range memberNames {
+		if err := addAndPromoteLearner(ctx, m, memberName, backoff, logger); err != nil {
+			return err
+		}
+	}
+	logger.Infof("Successfully scaled up the etcd cluster by %d members", len(memberNames))
+	return nil
+}
+
+// addAndPromoteLearner adds the given member as a learner to the etcd cluster, and promotes it to a voting member
+// once it is in sync with the leader, retrying both with the given backoff.
+func addAndPromoteLearner(ctx context.Context, m ScaleUpControl, memberName string, backoff wait.Backoff, logger *logrus.Entry) error {
+	logger.Infof("Adding member %s as a learner", memberName)
+	if err := retry.OnError(backoff, utilError.IsErrNotNil, func() error {
+		return m.AddLearnerMember(ctx, memberName)
+	}); err != nil {
+		return fmt.Errorf("unable to add member %s as a learner: %v", memberName, err)
+	}
+
+	// The promotion fails until the learner has started and caught up with the leader.
+	logger.Infof("Waiting for learner %s to be in sync with the leader to promote it", memberName)
+	if err := retry.OnError(backoff, utilError.IsErrNotNil, func() error {
+		return m.PromoteLearnerMember(ctx, memberName)
+	}); err != nil {
+		return fmt.Errorf("unable to promote learner %s: %v", memberName, err)
+	}
+	return nil
+}
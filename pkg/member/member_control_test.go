@@ -18,6 +18,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/etcdserver/api/v3rpc/rpctypes"
 	"go.etcd.io/etcd/etcdserver/etcdserverpb"
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -94,6 +95,42 @@ var _ = Describe("Membercontrol", func() {
 				Expect(present).To(BeTrue())
 			})
 		})
+
+		Context("Peer TLS is enabled", func() {
+			var cli *clientv3.Client
+
+			BeforeEach(func() {
+				var err error
+				cli, err = clientv3.New(clientv3.Config{Endpoints: etcdConnectionConfig.Endpoints, DialTimeout: member.EtcdTimeout})
+				Expect(err).ShouldNot(HaveOccurred())
+				// The cluster allows only a single learner at a time.
+				Expect(removeLearners(cli)).To(Succeed())
+
+				etcdConnectionConfig.PeerCertFile = "/var/etcd/ssl/peer/tls.crt"
+				etcdConnectionConfig.PeerKeyFile = "/var/etcd/ssl/peer/tls.key"
+				etcdConnectionConfig.PeerCaFile = "/var/etcd/ssl/peer/ca.crt"
+			})
+
+			AfterEach(func() {
+				Expect(removeLearners(cli)).To(Succeed())
+				Expect(cli.Close()).To(Succeed())
+			})
+
+			It("Should add member to the cluster as a learner with an https peer URL", func() {
+				mem := member.NewMemberControl(etcdConnectionConfig)
+				Expect(mem.AddMemberAsLearner(context.TODO())).To(Succeed())
+
+				response, err := cli.MemberList(context.TODO())
+				Expect(err).ShouldNot(HaveOccurred())
+				var learnerPeerURLs []string
+				for _, m := range response.Members {
+					if m.IsLearner {
+						learnerPeerURLs = append(learnerPeerURLs, m.PeerURLs...)
+					}
+				}
+				Expect(learnerPeerURLs).To(Equal([]string{"https://" + podName + ".etcd-main-peer.default.svc:2380"}))
+			})
+		})
 	})
 
 	Describe("While attempting to check if etcd is part of a cluster", func() {
@@ -238,3 +275,121 @@ var _ = Describe("Membercontrol", func() {
 		})
 	})
 })
+
+var _ = Describe("Scaling up the cluster after a single member restoration", func() {
+	Describe("Getting the names of the members to add", func() {
+		It("should return the names of all other members of the statefulset", func() {
+			memberNames, err := member.GetScaleUpMemberNames("etcd-main-0", 3)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(memberNames).To(Equal([]string{"etcd-main-1", "etcd-main-2"}))
+		})
+		It("should return no names if the cluster size is one", func() {
+			memberNames, err := member.GetScaleUpMemberNames("etcd-main-0", 1)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(memberNames).To(BeEmpty())
+		})
+		It("should return an error if the pod name does not end with an ordinal", func() {
+			_, err := member.GetScaleUpMemberNames("etcd-main", 3)
+			Expect(err).Should(HaveOccurred())
+		})
+		It("should return the names of the other voting members recorded in the cluster metadata", func() {
+			metadata := &brtypes.ClusterMetadata{Members: []brtypes.ClusterMember{
+				{Name: "etcd-main-0"},
+				{Name: "etcd-main-1"},
+				{Name: "etcd-main-3", IsLearner: true},
+				{Name: "etcd-main-2"},
+			}}
+			Expect(member.GetClusterMetadataMemberNames(metadata, "etcd-main-1")).To(Equal([]string{"etcd-main-0", "etcd-main-2"}))
+		})
+	})
+
+	Describe("Adding and promoting the learners", func() {
+		var m *fakeScaleUpControl
+
+		BeforeEach(func() {
+			m = &fakeScaleUpControl{
+				promoteErrors: map[string][]error{},
+			}
+		})
+
+		It("should add and promote each learner before adding the next one", func() {
+			err := member.ScaleUpCluster(testCtx, m, []string{"etcd-main-1", "etcd-main-2"}, 1, logger)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(m.calls).To(Equal([]string{
+				"add etcd-main-1",
+				"promote etcd-main-1",
+				"add etcd-main-2",
+				"promote etcd-main-2",
+			}))
+		})
+
+		It("should retry the promotion until the learner is in sync with the leader", func() {
+			m.promoteErrors["etcd-main-1"] = []error{rpctypes.ErrGRPCLearnerNotReady}
+			err := member.ScaleUpCluster(testCtx, m, []string{"etcd-main-1", "etcd-main-2"}, 2, logger)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(m.calls).To(Equal([]string{
+				"add etcd-main-1",
+				"promote etcd-main-1",
+				"promote etcd-main-1",
+				"add etcd-main-2",
+				"promote etcd-main-2",
+			}))
+		})
+
+		It("should not add further learners if a learner could not be promoted", func() {
+			m.promoteErrors["etcd-main-1"] = []error{rpctypes.ErrGRPCLearnerNotReady}
+			err := member.ScaleUpCluster(testCtx, m, []string{"etcd-main-1", "etcd-main-2"}, 1, logger)
+			Expect(err).Should(HaveOccurred())
+			Expect(m.calls).To(Equal([]string{
+				"add etcd-main-1",
+				"promote etcd-main-1",
+			}))
+		})
+
+		It("should not promote a learner which could not be added", func() {
+			m.addErr = rpctypes.ErrGRPCTooManyLearners
+			err := member.ScaleUpCluster(testCtx, m, []string{"etcd-main-1", "etcd-main-2"}, 1, logger)
+			Expect(err).Should(HaveOccurred())
+			Expect(m.calls).To(Equal([]string{
+				"add etcd-main-1",
+			}))
+		})
+	})
+})
+
+// removeLearners removes the learners from the etcd cluster.
+func removeLearners(cli *clientv3.Client) error {
+	response, err := cli.MemberList(context.TODO())
+	if err != nil {
+		return err
+	}
+	for _, m := range response.Members {
+		if m.IsLearner {
+			if _, err := cli.MemberRemove(context.TODO(), m.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fakeScaleUpControl records the calls made to it, and fails them with the configured errors.
+type fakeScaleUpControl struct {
+	calls         []string
+	addErr        error
+	promoteErrors map[string][]error
+}
+
+func (f *fakeScaleUpControl) AddLearnerMember(_ context.Context, memberName string) error {
+	f.calls = append(f.calls, "add "+memberName)
+	return f.addErr
+}
+
+func (f *fakeScaleUpControl) PromoteLearnerMember(_ context.Context, memberName string) error {
+	f.calls = append(f.calls, "promote "+memberName)
+	if errs := f.promoteErrors[memberName]; len(errs) > 0 {
+		f.promoteErrors[memberName] = errs[1:]
+		return errs[0]
+	}
+	return nil
+}
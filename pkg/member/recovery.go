@@ -0,0 +1,161 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package member
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	utilError "github.com/gardener/etcd-backup-restore/pkg/errors"
+	"github.com/gardener/etcd-backup-restore/pkg/miscellaneous"
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+// RecoveryControl interface defines the functionalities needed to recover other members of the etcd cluster, by
+// replacing each of them with a new member which catches up with the leader.
+type RecoveryControl interface {
+	ScaleUpControl
+
+	// RemoveMemberByName removes the member with the given name from the etcd cluster.
+	RemoveMemberByName(context.Context, string) error
+
+	// CleanMember removes the data of the member with the given name, so that it rejoins the cluster as a new member.
+	CleanMember(context.Context, string) error
+}
+
+// CleanMemberFunc removes the data of the member with the given name, such as by deleting its data directory or its
+// volume. It is provided by the operator driving the recovery, as the data of the other members is out of reach.
+type CleanMemberFunc func(ctx context.Context, memberName string) error
+
+// recoveryControl recovers the members of the etcd cluster using the member control, and the given func to remove
+// the data of the members.
+type recoveryControl struct {
+	*memberControl
+	cleanMember CleanMemberFunc
+}
+
+// NewRecoveryControl returns a RecoveryControl which removes the data of the members with the given func.
+func NewRecoveryControl(etcdConnConfig *brtypes.EtcdConnectionConfig, cleanMember CleanMemberFunc) RecoveryControl {
+	return &recoveryControl{
+		memberControl: newMemberControl(etcdConnConfig),
+		cleanMember:   cleanMember,
+	}
+}
+
+// CleanMember removes the data of the member with the given name.
+func (r *recoveryControl) CleanMember(ctx context.Context, memberName string) error {
+	return r.cleanMember(ctx, memberName)
+}
+
+// MaxUnavailableMembers returns the number of voting members which an etcd cluster of the given size can be without,
+// while it still has a quorum.
+func MaxUnavailableMembers(clusterSize int) int {
+	if clusterSize < 1 {
+		return 0
+	}
+	return (clusterSize - 1) / 2
+}
+
+// RecoverMembers recovers the given members of the etcd cluster of the given size, by removing each of them from the
+// cluster, cleaning its data, adding it back as a learner and promoting it once it is in sync with the leader.
+// The members are recovered in the given order, in batches of at most maxParallel members. The batches are capped to
+// the number of members the cluster can be without while it still has a quorum, so that the cluster never loses its
+// quorum by the recovery. The members of a batch are removed and cleaned concurrently, but they are added and promoted
+// one after the other, as etcd allows only a single learner in the cluster. The next batch is only started once all
+// the members of the batch have been promoted. If a member of a batch could not be removed or cleaned, the other
+// members of the batch are still added back, but the recovery stops after the batch.
+func RecoverMembers(ctx context.Context, m RecoveryControl, memberNames []string, clusterSize, maxParallel, retrySteps int, logger *logrus.Entry) error {
+	batchSize, err := getRecoveryBatchSize(memberNames, clusterSize, maxParallel)
+	if err != nil {
+		return err
+	}
+	backoff := miscellaneous.CreateBackoff(RetryPeriod, retrySteps)
+	logger.Infof("Recovering %d members of the etcd cluster of size %d, at most %d at a time", len(memberNames), clusterSize, batchSize)
+
+	for start := 0; start < len(memberNames); start += batchSize {
+		end := start + batchSize
+		if end > len(memberNames) {
+			end = len(memberNames)
+		}
+		batch := memberNames[start:end]
+
+		errs := make([]error, len(batch))
+		var wg sync.WaitGroup
+		for i, memberName := range batch {
+			wg.Add(1)
+			go func(i int, memberName string) {
+				defer wg.Done()
+				errs[i] = removeAndCleanMember(ctx, m, memberName, backoff, logger)
+			}(i, memberName)
+		}
+		wg.Wait()
+
+		// the removed members are added back, even if other members of the batch could not be removed or cleaned
+		for i, memberName := range batch {
+			if errs[i] != nil {
+				continue
+			}
+			if err := addAndPromoteLearner(ctx, m, memberName, backoff, logger); err != nil {
+				return err
+			}
+		}
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+	}
+	logger.Infof("Successfully recovered %d members of the etcd cluster", len(memberNames))
+	return nil
+}
+
+// getRecoveryBatchSize returns the number of members which can be recovered at a time, validating the members to
+// recover against the cluster size.
+func getRecoveryBatchSize(memberNames []string, clusterSize, maxParallel int) (int, error) {
+	maxUnavailable := MaxUnavailableMembers(clusterSize)
+	if maxUnavailable < 1 {
+		return 0, fmt.Errorf("etcd cluster of size %d cannot be without any of its members while it still has a quorum", clusterSize)
+	}
+	if len(memberNames) > clusterSize {
+		return 0, fmt.Errorf("cannot recover %d members of the etcd cluster of size %d", len(memberNames), clusterSize)
+	}
+	seen := make(map[string]struct{}, len(memberNames))
+	for _, memberName := range memberNames {
+		if _, ok := seen[memberName]; ok {
+			return 0, fmt.Errorf("member %s is to be recovered more than once", memberName)
+		}
+		seen[memberName] = struct{}{}
+	}
+
+	if maxParallel < 1 {
+		maxParallel = 1
+	}
+	if maxParallel > maxUnavailable {
+		maxParallel = maxUnavailable
+	}
+	return maxParallel, nil
+}
+
+// removeAndCleanMember removes the given member from the etcd cluster and removes its data, retrying both with the
+// given backoff.
+func removeAndCleanMember(ctx context.Context, m RecoveryControl, memberName string, backoff wait.Backoff, logger *logrus.Entry) error {
+	logger.Infof("Removing member %s from the cluster", memberName)
+	if err := retry.OnError(backoff, utilError.IsErrNotNil, func() error {
+		return m.RemoveMemberByName(ctx, memberName)
+	}); err != nil {
+		return fmt.Errorf("unable to remove member %s: %v", memberName, err)
+	}
+
+	logger.Infof("Cleaning the data of member %s", memberName)
+	if err := retry.OnError(backoff, utilError.IsErrNotNil, func() error {
+		return m.CleanMember(ctx, memberName)
+	}); err != nil {
+		return fmt.Errorf("unable to clean the data of member %s: %v", memberName, err)
+	}
+	return nil
+}
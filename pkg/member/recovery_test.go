@@ -0,0 +1,182 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package member_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gardener/etcd-backup-restore/pkg/member"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Recovering the members of the cluster", func() {
+	DescribeTable("should tolerate the loss of the members as long as the cluster has a quorum",
+		func(clusterSize, maxUnavailable int) {
+			Expect(member.MaxUnavailableMembers(clusterSize)).To(Equal(maxUnavailable))
+		},
+		Entry("no members", 0, 0),
+		Entry("single member", 1, 0),
+		Entry("two members", 2, 0),
+		Entry("three members", 3, 1),
+		Entry("four members", 4, 1),
+		Entry("five members", 5, 2),
+		Entry("seven members", 7, 3),
+	)
+
+	Describe("Orchestrating the recovery", func() {
+		var m *fakeRecoveryControl
+
+		BeforeEach(func() {
+			m = newFakeRecoveryControl()
+		})
+
+		indexOf := func(call string) int {
+			for i, c := range m.calls {
+				if c == call {
+					return i
+				}
+			}
+			return -1
+		}
+
+		It("should recover each member in batches without losing the quorum", func() {
+			memberNames := []string{"etcd-main-0", "etcd-main-1", "etcd-main-2", "etcd-main-3", "etcd-main-4"}
+			err := member.RecoverMembers(testCtx, m, memberNames, 5, 2, 1, logger)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(m.maxUnavailable).To(Equal(2))
+			Expect(m.maxLearners).To(Equal(1))
+			Expect(m.unavailable).To(BeEmpty())
+			for _, memberName := range memberNames {
+				Expect(indexOf("remove " + memberName)).To(BeNumerically("<", indexOf("clean "+memberName)))
+				Expect(indexOf("clean " + memberName)).To(BeNumerically("<", indexOf("add "+memberName)))
+				Expect(indexOf("add " + memberName)).To(BeNumerically("<", indexOf("promote "+memberName)))
+			}
+			// the members are added back in the given order, and a batch is only started once the previous one is promoted
+			Expect(indexOf("promote etcd-main-0")).To(BeNumerically("<", indexOf("add etcd-main-1")))
+			Expect(indexOf("promote etcd-main-1")).To(BeNumerically("<", indexOf("remove etcd-main-2")))
+			Expect(indexOf("promote etcd-main-1")).To(BeNumerically("<", indexOf("remove etcd-main-3")))
+			Expect(indexOf("promote etcd-main-3")).To(BeNumerically("<", indexOf("remove etcd-main-4")))
+		})
+
+		It("should cap the parallelism to the number of members the cluster can be without", func() {
+			memberNames := []string{"etcd-main-0", "etcd-main-1", "etcd-main-2"}
+			err := member.RecoverMembers(testCtx, m, memberNames, 3, 3, 1, logger)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(m.maxUnavailable).To(Equal(1))
+			Expect(m.calls).To(Equal([]string{
+				"remove etcd-main-0", "clean etcd-main-0", "add etcd-main-0", "promote etcd-main-0",
+				"remove etcd-main-1", "clean etcd-main-1", "add etcd-main-1", "promote etcd-main-1",
+				"remove etcd-main-2", "clean etcd-main-2", "add etcd-main-2", "promote etcd-main-2",
+			}))
+		})
+
+		It("should recover one member at a time if no parallelism is configured", func() {
+			memberNames := []string{"etcd-main-0", "etcd-main-1", "etcd-main-2", "etcd-main-3", "etcd-main-4"}
+			err := member.RecoverMembers(testCtx, m, memberNames, 5, 0, 1, logger)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(m.maxUnavailable).To(Equal(1))
+		})
+
+		DescribeTable("should reject a recovery which would lose the quorum or is inconsistent with the cluster",
+			func(memberNames []string, clusterSize int, message string) {
+				err := member.RecoverMembers(testCtx, m, memberNames, clusterSize, 1, 1, logger)
+				Expect(err).Should(MatchError(ContainSubstring(message)))
+				Expect(m.calls).To(BeEmpty())
+			},
+			Entry("single member cluster", []string{"etcd-main-0"}, 1, "etcd cluster of size 1 cannot be without any of its members"),
+			Entry("two member cluster", []string{"etcd-main-0"}, 2, "etcd cluster of size 2 cannot be without any of its members"),
+			Entry("more members than the cluster size", []string{"etcd-main-0", "etcd-main-1", "etcd-main-2", "etcd-main-3"}, 3, "cannot recover 4 members of the etcd cluster of size 3"),
+			Entry("duplicate members", []string{"etcd-main-0", "etcd-main-0"}, 3, "member etcd-main-0 is to be recovered more than once"),
+		)
+
+		It("should add back the removed members of a batch and stop if a member could not be removed", func() {
+			m.removeErrors["etcd-main-1"] = fmt.Errorf("etcdserver: request timed out")
+			memberNames := []string{"etcd-main-0", "etcd-main-1", "etcd-main-2"}
+			err := member.RecoverMembers(testCtx, m, memberNames, 5, 2, 1, logger)
+			Expect(err).Should(MatchError(ContainSubstring("unable to remove member etcd-main-1")))
+
+			Expect(m.unavailable).To(BeEmpty())
+			Expect(indexOf("promote etcd-main-0")).To(BeNumerically(">", 0))
+			Expect(indexOf("clean etcd-main-1")).To(Equal(-1))
+			Expect(indexOf("remove etcd-main-2")).To(Equal(-1))
+		})
+
+		It("should not add back a member whose data could not be cleaned", func() {
+			m.cleanErrors["etcd-main-0"] = fmt.Errorf("volume is still attached")
+			err := member.RecoverMembers(testCtx, m, []string{"etcd-main-0", "etcd-main-1"}, 3, 1, 1, logger)
+			Expect(err).Should(MatchError(ContainSubstring("unable to clean the data of member etcd-main-0")))
+
+			Expect(m.calls).To(Equal([]string{"remove etcd-main-0", "clean etcd-main-0"}))
+			Expect(m.unavailable).To(HaveKey("etcd-main-0"))
+		})
+	})
+})
+
+// fakeRecoveryControl records the calls made to it, fails them with the configured errors, and tracks the members
+// which are not voting members of the cluster, as they have been removed and not yet promoted.
+type fakeRecoveryControl struct {
+	mutex          sync.Mutex
+	calls          []string
+	unavailable    map[string]struct{}
+	maxUnavailable int
+	learners       int
+	maxLearners    int
+	removeErrors   map[string]error
+	cleanErrors    map[string]error
+}
+
+func newFakeRecoveryControl() *fakeRecoveryControl {
+	return &fakeRecoveryControl{
+		unavailable:  map[string]struct{}{},
+		removeErrors: map[string]error{},
+		cleanErrors:  map[string]error{},
+	}
+}
+
+func (f *fakeRecoveryControl) RemoveMemberByName(_ context.Context, memberName string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.calls = append(f.calls, "remove "+memberName)
+	if err := f.removeErrors[memberName]; err != nil {
+		return err
+	}
+	f.unavailable[memberName] = struct{}{}
+	if len(f.unavailable) > f.maxUnavailable {
+		f.maxUnavailable = len(f.unavailable)
+	}
+	return nil
+}
+
+func (f *fakeRecoveryControl) CleanMember(_ context.Context, memberName string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.calls = append(f.calls, "clean "+memberName)
+	return f.cleanErrors[memberName]
+}
+
+func (f *fakeRecoveryControl) AddLearnerMember(_ context.Context, memberName string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.calls = append(f.calls, "add "+memberName)
+	f.learners++
+	if f.learners > f.maxLearners {
+		f.maxLearners = f.learners
+	}
+	return nil
+}
+
+func (f *fakeRecoveryControl) PromoteLearnerMember(_ context.Context, memberName string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.calls = append(f.calls, "promote "+memberName)
+	f.learners--
+	delete(f.unavailable, memberName)
+	return nil
+}
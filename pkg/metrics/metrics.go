@@ -8,7 +8,9 @@ import (
 	"sort"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"go.etcd.io/etcd/etcdserver/etcdserverpb"
 
+	"github.com/gardener/etcd-backup-restore/pkg/compressor"
 	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
 )
 
@@ -32,6 +34,24 @@ const (
 	LabelRestorationKind = "restore"
 	// LabelEndPoint is metric label for metric of etcd cluster endpoint.
 	LabelEndPoint = "endpoint"
+	// LabelCompressionPolicy is metric label indicating the compression policy associated with metric.
+	LabelCompressionPolicy = "policy"
+	// ValueCompressionPolicyNone is value for metric label policy when snapshots are not compressed.
+	ValueCompressionPolicyNone = "none"
+	// LabelSnapshotterState is metric label indicating the state of the snapshotter associated with metric.
+	LabelSnapshotterState = "state"
+	// ValueSnapshotterStateActive is value for metric label state when the snapshotter is active.
+	ValueSnapshotterStateActive = "active"
+	// ValueSnapshotterStateInactive is value for metric label state when the snapshotter is inactive.
+	ValueSnapshotterStateInactive = "inactive"
+	// LabelEtcdAlarm is metric label indicating the type of the etcd alarm associated with metric.
+	LabelEtcdAlarm = "alarm"
+	// LabelSkipReason is metric label indicating why the operation associated with metric was skipped.
+	LabelSkipReason = "reason"
+	// ValueSkipReasonLearner is value for metric label reason when the etcd member is a learner.
+	ValueSkipReasonLearner = "learner"
+	// ValueSkipReasonUnhealthy is value for metric label reason when the etcd member is unhealthy.
+	ValueSkipReasonUnhealthy = "unhealthy"
 
 	namespaceEtcdBR      = "etcdbr"
 	subsystemSnapshot    = "snapshot"
@@ -56,6 +76,24 @@ var (
 			ValueRestoreSingleNode,
 		},
 		LabelEndPoint: {""},
+		LabelCompressionPolicy: {
+			compressor.GzipCompressionPolicy,
+			compressor.LzwCompressionPolicy,
+			compressor.ZlibCompressionPolicy,
+			ValueCompressionPolicyNone,
+		},
+		LabelSnapshotterState: {
+			ValueSnapshotterStateActive,
+			ValueSnapshotterStateInactive,
+		},
+		LabelEtcdAlarm: {
+			etcdserverpb.AlarmType_NOSPACE.String(),
+			etcdserverpb.AlarmType_CORRUPT.String(),
+		},
+		LabelSkipReason: {
+			ValueSkipReasonLearner,
+			ValueSkipReasonUnhealthy,
+		},
 	}
 
 	// GCSnapshotCounter is metric to count the garbage collected snapshots.
@@ -144,6 +182,36 @@ var (
 		[]string{LabelSucceeded, LabelEndPoint},
 	)
 
+	// MemberDefragmentationDurationSeconds is metric to expose duration required to defragment the local etcd member on its own schedule.
+	MemberDefragmentationDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespaceEtcdBR,
+			Name:      "member_defragmentation_duration_seconds",
+			Help:      "Total latency distribution of the scheduled defragmentation of the local etcd member.",
+		},
+		[]string{LabelSucceeded},
+	)
+
+	// MemberDefragmentationReclaimedBytes is metric to expose the bytes reclaimed by the latest defragmentation of the local etcd member.
+	MemberDefragmentationReclaimedBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespaceEtcdBR,
+			Name:      "member_defragmentation_reclaimed_bytes",
+			Help:      "Bytes of the database of the local etcd member reclaimed by its latest scheduled defragmentation.",
+		},
+		[]string{},
+	)
+
+	// MemberDefragmentationSkippedTotal is metric to count the scheduled defragmentations of the local etcd member which were skipped.
+	MemberDefragmentationSkippedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespaceEtcdBR,
+			Name:      "member_defragmentation_skipped_total",
+			Help:      "Total number of scheduled defragmentations of the local etcd member skipped because the member was a learner or unhealthy.",
+		},
+		[]string{LabelSkipReason},
+	)
+
 	// SnapstoreLatestDeltasTotal is metric to expose total number of delta snapshots taken since the latest full snapshot.
 	SnapstoreLatestDeltasTotal = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -165,6 +233,17 @@ var (
 		[]string{},
 	)
 
+	// SnapstoreCredentialReloadTotal is metric to count the reloads of the snapstore access credentials.
+	SnapstoreCredentialReloadTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespaceEtcdBR,
+			Subsystem: subsystemSnapstore,
+			Name:      "credential_reload_total",
+			Help:      "Total number of reloads of the snapstore access credentials.",
+		},
+		[]string{LabelSucceeded},
+	)
+
 	//SnapshotterOperationFailure is metric to count the number of snapshotter operations that have errored out
 	SnapshotterOperationFailure = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -176,6 +255,226 @@ var (
 		[]string{LabelError},
 	)
 
+	// OrphanDeltaSnapshotChainsTotal is metric to count the number of times the previous full snapshot was found missing from the snapstore.
+	OrphanDeltaSnapshotChainsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespaceEtcdBR,
+			Subsystem: subsystemSnapshotter,
+			Name:      "orphan_delta_snapshot_chains_total",
+			Help:      "Total number of times the previous full snapshot was found missing from the snapstore.",
+		},
+		[]string{},
+	)
+
+	// FullSnapshotConsecutiveFailures is metric to expose the number of consecutive failed full snapshots.
+	FullSnapshotConsecutiveFailures = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespaceEtcdBR,
+			Subsystem: subsystemSnapshotter,
+			Name:      "full_snapshot_consecutive_failures",
+			Help:      "Number of consecutive failed full snapshots, reset to 0 by a successful full snapshot.",
+		},
+		[]string{},
+	)
+
+	// DeltaEventsCollectionTimeoutsTotal is metric to count the number of times the events since the previous snapshot could not be collected in time at startup.
+	DeltaEventsCollectionTimeoutsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespaceEtcdBR,
+			Subsystem: subsystemSnapshotter,
+			Name:      "delta_events_collection_timeouts_total",
+			Help:      "Total number of times the events since the previous snapshot could not be collected within the timeout at startup.",
+		},
+		[]string{},
+	)
+
+	// SnapshotterStateDurationSeconds is metric to expose the total duration spent by the snapshotter in each state.
+	SnapshotterStateDurationSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespaceEtcdBR,
+			Subsystem: subsystemSnapshotter,
+			Name:      "state_duration_seconds",
+			Help:      "Total duration in seconds spent by the snapshotter in each state, updated on each state transition.",
+		},
+		[]string{LabelSnapshotterState},
+	)
+
+	// DeltaSnapshottingEnabled is metric to expose whether the snapshotter takes delta snapshots.
+	DeltaSnapshottingEnabled = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespaceEtcdBR,
+			Subsystem: subsystemSnapshotter,
+			Name:      "delta_snapshotting_enabled",
+			Help:      "Whether delta snapshots are taken. 1 if they are, 0 if the delta snapshot period disables them.",
+		},
+		[]string{},
+	)
+
+	// SnapshotterDegraded is metric to expose whether the snapshotter is degraded because the snapstore is out of quota.
+	SnapshotterDegraded = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespaceEtcdBR,
+			Subsystem: subsystemSnapshotter,
+			Name:      "degraded",
+			Help:      "Whether the snapshotter is degraded because the snapstore is out of quota or capacity. 1 if it is, in which case the events are buffered instead of uploaded, 0 otherwise.",
+		},
+		[]string{},
+	)
+
+	// DeltaSnapshotPendingEvents is metric to expose the number of events collected for the next delta snapshot.
+	DeltaSnapshotPendingEvents = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespaceEtcdBR,
+			Subsystem: subsystemSnapshotter,
+			Name:      "delta_snapshot_pending_events",
+			Help:      "Number of events collected from the watch on etcd which are pending for the next delta snapshot, reset to 0 when the events are flushed.",
+		},
+		[]string{},
+	)
+
+	// DeltaSnapshotPendingBytes is metric to expose the size of the events collected for the next delta snapshot.
+	DeltaSnapshotPendingBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespaceEtcdBR,
+			Subsystem: subsystemSnapshotter,
+			Name:      "delta_snapshot_pending_bytes",
+			Help:      "Uncompressed size in bytes of the events which are pending for the next delta snapshot, reset to 0 when the events are flushed.",
+		},
+		[]string{},
+	)
+
+	// EtcdAlarmActive is metric to expose the etcd alarms found active during the full snapshots.
+	EtcdAlarmActive = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespaceEtcdBR,
+			Subsystem: subsystemSnapshotter,
+			Name:      "etcd_alarm_active",
+			Help:      "Whether an etcd alarm of the given type was active on any etcd member when last queried during a full snapshot. 1 if it was, 0 otherwise.",
+		},
+		[]string{LabelEtcdAlarm},
+	)
+
+	// EtcdDBSizeBytes is metric to expose the size of the backend database of etcd.
+	EtcdDBSizeBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespaceEtcdBR,
+			Subsystem: subsystemSnapshotter,
+			Name:      "etcd_db_size_bytes",
+			Help:      "Physically allocated size in bytes of the backend database of etcd, as last read from the status of the etcd member.",
+		},
+		[]string{},
+	)
+
+	// EtcdDBSizeInUseBytes is metric to expose the size of the backend database of etcd which is in use.
+	EtcdDBSizeInUseBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespaceEtcdBR,
+			Subsystem: subsystemSnapshotter,
+			Name:      "etcd_db_size_in_use_bytes",
+			Help:      "Logically used size in bytes of the backend database of etcd, as last read from the status of the etcd member. The difference to the physically allocated size can be reclaimed by a defragmentation.",
+		},
+		[]string{},
+	)
+
+	// RestorableRPOSeconds is metric to expose the age of the latest snapshot up to which the data can be restored.
+	RestorableRPOSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespaceEtcdBR,
+			Subsystem: subsystemSnapshotter,
+			Name:      "restorable_rpo_seconds",
+			Help:      "Age in seconds of the latest full or delta snapshot saved in the snapstore, i.e. the recovery point objective currently achievable by a restoration.",
+		},
+		[]string{},
+	)
+
+	// RestoreDrillsTotal is metric to count the restore drills run by the snapshotter.
+	RestoreDrillsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespaceEtcdBR,
+			Subsystem: subsystemRestore,
+			Name:      "drills_total",
+			Help:      "Total number of restore drills, which restore the latest snapshots into a throwaway directory and boot an embedded etcd on it.",
+		},
+		[]string{LabelSucceeded},
+	)
+
+	// RestoreDrillPassed is metric to expose the outcome of the latest restore drill.
+	RestoreDrillPassed = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespaceEtcdBR,
+			Subsystem: subsystemRestore,
+			Name:      "drill_passed",
+			Help:      "Whether the latest restore drill passed, i.e. the latest snapshots were restored and the restored etcd reached their revision. 1 if it did, 0 otherwise.",
+		},
+		[]string{},
+	)
+
+	// AutoCompressionPolicySelected is metric to expose the compression policy locked in by the auto compression policy.
+	AutoCompressionPolicySelected = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespaceEtcdBR,
+			Subsystem: subsystemSnapshotter,
+			Name:      "auto_compression_policy_selected",
+			Help:      "Compression policy selected by the auto compression policy. 1 for the selected policy, 0 otherwise.",
+		},
+		[]string{LabelCompressionPolicy},
+	)
+
+	// SnapshotCompressionLevel is metric to expose the current adaptive compression level of the snapshots.
+	SnapshotCompressionLevel = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespaceEtcdBR,
+			Subsystem: subsystemSnapshotter,
+			Name:      "compression_level",
+			Help:      "Current compression level of the snapshots, as tuned to the compression time budget.",
+		},
+		[]string{},
+	)
+
+	// GarbageCollectionRunsTotal is metric to count the garbage collection cycles.
+	GarbageCollectionRunsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespaceEtcdBR,
+			Subsystem: subsystemSnapshotter,
+			Name:      "gc_runs_total",
+			Help:      "Total number of garbage collection cycles run by the snapshotter.",
+		},
+		[]string{LabelSucceeded},
+	)
+
+	// GarbageCollectionDeletedSnapshotsTotal is metric to count the snapshots deleted by the garbage collection cycles.
+	GarbageCollectionDeletedSnapshotsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespaceEtcdBR,
+			Subsystem: subsystemSnapshotter,
+			Name:      "gc_deleted_snapshots_total",
+			Help:      "Total number of snapshots deleted by the garbage collection cycles.",
+		},
+		[]string{LabelKind},
+	)
+
+	// GarbageCollectionInvalidSnapshotsTotal is metric to count the structurally invalid snapshots deleted by the garbage collection cycles.
+	GarbageCollectionInvalidSnapshotsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespaceEtcdBR,
+			Subsystem: subsystemSnapshotter,
+			Name:      "gc_invalid_snapshots_total",
+			Help:      "Total number of structurally invalid snapshots deleted by the garbage collection cycles.",
+		},
+		[]string{},
+	)
+
+	// GarbageCollectionDurationSeconds is metric to expose the duration of the garbage collection cycles in seconds.
+	GarbageCollectionDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespaceEtcdBR,
+			Subsystem: subsystemSnapshotter,
+			Name:      "gc_duration_seconds",
+			Help:      "Total latency distribution of the garbage collection cycles.",
+		},
+		[]string{LabelSucceeded},
+	)
+
 	// CurrentClusterSize is metric to expose the current Etcd cluster size.
 	CurrentClusterSize = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -397,6 +696,27 @@ func init() {
 		DefragmentationDurationSeconds.With(prometheus.Labels(combination))
 	}
 
+	// MemberDefragmentationDurationSeconds
+	memberDefragmentationDurationSecondsLabelValues := map[string][]string{
+		LabelSucceeded: labels[LabelSucceeded],
+	}
+	memberDefragmentationDurationSecondsCombinations := generateLabelCombinations(memberDefragmentationDurationSecondsLabelValues)
+	for _, combination := range memberDefragmentationDurationSecondsCombinations {
+		MemberDefragmentationDurationSeconds.With(prometheus.Labels(combination))
+	}
+
+	// MemberDefragmentationReclaimedBytes
+	MemberDefragmentationReclaimedBytes.With(prometheus.Labels(map[string]string{}))
+
+	// MemberDefragmentationSkippedTotal
+	memberDefragmentationSkippedTotalLabelValues := map[string][]string{
+		LabelSkipReason: labels[LabelSkipReason],
+	}
+	memberDefragmentationSkippedTotalCombinations := generateLabelCombinations(memberDefragmentationSkippedTotalLabelValues)
+	for _, combination := range memberDefragmentationSkippedTotalCombinations {
+		MemberDefragmentationSkippedTotal.With(prometheus.Labels(combination))
+	}
+
 	// MemberRemoveDurationSeconds
 	MemberRemoveDurationSecondsLabelValues := map[string][]string{
 		LabelSucceeded: labels[LabelSucceeded],
@@ -439,9 +759,116 @@ func init() {
 	// SnapstoreLatestDeltasSize
 	SnapstoreLatestDeltasRevisionsTotal.With(prometheus.Labels(map[string]string{}))
 
+	// SnapstoreCredentialReloadTotal
+	snapstoreCredentialReloadTotalLabelValues := map[string][]string{
+		LabelSucceeded: labels[LabelSucceeded],
+	}
+	snapstoreCredentialReloadTotalCombinations := generateLabelCombinations(snapstoreCredentialReloadTotalLabelValues)
+	for _, combination := range snapstoreCredentialReloadTotalCombinations {
+		SnapstoreCredentialReloadTotal.With(prometheus.Labels(combination))
+	}
+
 	//SnapshotterOperationFailure
 	SnapshotterOperationFailure.With(prometheus.Labels(map[string]string{LabelError: ""}))
 
+	// OrphanDeltaSnapshotChainsTotal
+	OrphanDeltaSnapshotChainsTotal.With(prometheus.Labels(map[string]string{}))
+
+	// FullSnapshotConsecutiveFailures
+	FullSnapshotConsecutiveFailures.With(prometheus.Labels(map[string]string{}))
+
+	// DeltaEventsCollectionTimeoutsTotal
+	DeltaEventsCollectionTimeoutsTotal.With(prometheus.Labels(map[string]string{}))
+
+	// SnapshotterStateDurationSeconds
+	snapshotterStateDurationSecondsLabelValues := map[string][]string{
+		LabelSnapshotterState: labels[LabelSnapshotterState],
+	}
+	snapshotterStateDurationSecondsCombinations := generateLabelCombinations(snapshotterStateDurationSecondsLabelValues)
+	for _, combination := range snapshotterStateDurationSecondsCombinations {
+		SnapshotterStateDurationSeconds.With(prometheus.Labels(combination))
+	}
+
+	// DeltaSnapshottingEnabled
+	DeltaSnapshottingEnabled.With(prometheus.Labels(map[string]string{}))
+
+	// RestorableRPOSeconds
+	RestorableRPOSeconds.With(prometheus.Labels(map[string]string{}))
+
+	// RestoreDrillsTotal
+	restoreDrillsTotalLabelValues := map[string][]string{
+		LabelSucceeded: labels[LabelSucceeded],
+	}
+	restoreDrillsTotalCombinations := generateLabelCombinations(restoreDrillsTotalLabelValues)
+	for _, combination := range restoreDrillsTotalCombinations {
+		RestoreDrillsTotal.With(prometheus.Labels(combination))
+	}
+
+	// RestoreDrillPassed is not initialized, as its 0 value would report a failed restore drill before any has run.
+
+	// EtcdDBSizeBytes and EtcdDBSizeInUseBytes are not initialized, as their 0 values would report an empty database
+	// before it is read.
+
+	// SnapshotterDegraded
+	SnapshotterDegraded.With(prometheus.Labels(map[string]string{}))
+
+	// DeltaSnapshotPendingEvents
+	DeltaSnapshotPendingEvents.With(prometheus.Labels(map[string]string{}))
+
+	// DeltaSnapshotPendingBytes
+	DeltaSnapshotPendingBytes.With(prometheus.Labels(map[string]string{}))
+
+	// EtcdAlarmActive
+	etcdAlarmActiveLabelValues := map[string][]string{
+		LabelEtcdAlarm: labels[LabelEtcdAlarm],
+	}
+	etcdAlarmActiveCombinations := generateLabelCombinations(etcdAlarmActiveLabelValues)
+	for _, combination := range etcdAlarmActiveCombinations {
+		EtcdAlarmActive.With(prometheus.Labels(combination))
+	}
+
+	// AutoCompressionPolicySelected
+	autoCompressionPolicySelectedLabelValues := map[string][]string{
+		LabelCompressionPolicy: labels[LabelCompressionPolicy],
+	}
+	autoCompressionPolicySelectedCombinations := generateLabelCombinations(autoCompressionPolicySelectedLabelValues)
+	for _, combination := range autoCompressionPolicySelectedCombinations {
+		AutoCompressionPolicySelected.With(prometheus.Labels(combination))
+	}
+
+	// SnapshotCompressionLevel
+	SnapshotCompressionLevel.With(prometheus.Labels(map[string]string{}))
+
+	// GarbageCollectionRunsTotal
+	garbageCollectionRunsTotalLabelValues := map[string][]string{
+		LabelSucceeded: labels[LabelSucceeded],
+	}
+	garbageCollectionRunsTotalCombinations := generateLabelCombinations(garbageCollectionRunsTotalLabelValues)
+	for _, combination := range garbageCollectionRunsTotalCombinations {
+		GarbageCollectionRunsTotal.With(prometheus.Labels(combination))
+	}
+
+	// GarbageCollectionInvalidSnapshotsTotal
+	GarbageCollectionInvalidSnapshotsTotal.With(prometheus.Labels(map[string]string{}))
+
+	// GarbageCollectionDeletedSnapshotsTotal
+	garbageCollectionDeletedSnapshotsTotalLabelValues := map[string][]string{
+		LabelKind: labels[LabelKind],
+	}
+	garbageCollectionDeletedSnapshotsTotalCombinations := generateLabelCombinations(garbageCollectionDeletedSnapshotsTotalLabelValues)
+	for _, combination := range garbageCollectionDeletedSnapshotsTotalCombinations {
+		GarbageCollectionDeletedSnapshotsTotal.With(prometheus.Labels(combination))
+	}
+
+	// GarbageCollectionDurationSeconds
+	garbageCollectionDurationSecondsLabelValues := map[string][]string{
+		LabelSucceeded: labels[LabelSucceeded],
+	}
+	garbageCollectionDurationSecondsCombinations := generateLabelCombinations(garbageCollectionDurationSecondsLabelValues)
+	for _, combination := range garbageCollectionDurationSecondsCombinations {
+		GarbageCollectionDurationSeconds.With(prometheus.Labels(combination))
+	}
+
 	//CurrentClusterSize
 	CurrentClusterSize.With(prometheus.Labels(map[string]string{}))
 
@@ -459,11 +886,35 @@ func init() {
 	prometheus.MustRegister(RestorationDurationSeconds)
 	prometheus.MustRegister(ValidationDurationSeconds)
 	prometheus.MustRegister(DefragmentationDurationSeconds)
+	prometheus.MustRegister(MemberDefragmentationDurationSeconds)
+	prometheus.MustRegister(MemberDefragmentationReclaimedBytes)
+	prometheus.MustRegister(MemberDefragmentationSkippedTotal)
 
 	prometheus.MustRegister(SnapstoreLatestDeltasTotal)
 	prometheus.MustRegister(SnapstoreLatestDeltasRevisionsTotal)
+	prometheus.MustRegister(SnapstoreCredentialReloadTotal)
 
 	prometheus.MustRegister(SnapshotterOperationFailure)
+	prometheus.MustRegister(OrphanDeltaSnapshotChainsTotal)
+	prometheus.MustRegister(FullSnapshotConsecutiveFailures)
+	prometheus.MustRegister(DeltaEventsCollectionTimeoutsTotal)
+	prometheus.MustRegister(SnapshotterStateDurationSeconds)
+	prometheus.MustRegister(DeltaSnapshottingEnabled)
+	prometheus.MustRegister(RestorableRPOSeconds)
+	prometheus.MustRegister(RestoreDrillsTotal)
+	prometheus.MustRegister(RestoreDrillPassed)
+	prometheus.MustRegister(SnapshotterDegraded)
+	prometheus.MustRegister(DeltaSnapshotPendingEvents)
+	prometheus.MustRegister(DeltaSnapshotPendingBytes)
+	prometheus.MustRegister(EtcdAlarmActive)
+	prometheus.MustRegister(EtcdDBSizeBytes)
+	prometheus.MustRegister(EtcdDBSizeInUseBytes)
+	prometheus.MustRegister(AutoCompressionPolicySelected)
+	prometheus.MustRegister(SnapshotCompressionLevel)
+	prometheus.MustRegister(GarbageCollectionRunsTotal)
+	prometheus.MustRegister(GarbageCollectionDeletedSnapshotsTotal)
+	prometheus.MustRegister(GarbageCollectionInvalidSnapshotsTotal)
+	prometheus.MustRegister(GarbageCollectionDurationSeconds)
 
 	prometheus.MustRegister(CurrentClusterSize)
 	prometheus.MustRegister(IsLearner)
@@ -24,9 +24,12 @@ import (
 	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
 	"go.etcd.io/etcd/embed"
 	"go.etcd.io/etcd/etcdserver/api/v3rpc/rpctypes"
 	"go.etcd.io/etcd/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/mvcc/backend"
+	"go.etcd.io/etcd/pkg/transport"
 	"go.etcd.io/etcd/pkg/types"
 	"gopkg.in/yaml.v2"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -58,11 +61,17 @@ const (
 
 // GetLatestFullSnapshotAndDeltaSnapList returns the latest snapshot
 func GetLatestFullSnapshotAndDeltaSnapList(store brtypes.SnapStore) (*brtypes.Snapshot, brtypes.SnapList, error) {
+	return GetLatestFullSnapshotAndDeltaSnapListWithContext(context.Background(), store)
+}
+
+// GetLatestFullSnapshotAndDeltaSnapListWithContext returns the latest snapshot, and gives up retrying the throttled
+// listings of the store once the given context is done.
+func GetLatestFullSnapshotAndDeltaSnapListWithContext(ctx context.Context, store brtypes.SnapStore) (*brtypes.Snapshot, brtypes.SnapList, error) {
 	var (
 		fullSnapshot  *brtypes.Snapshot
 		deltaSnapList brtypes.SnapList
 	)
-	snapList, err := store.List()
+	snapList, err := listLatestSnapshots(ctx, store)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -79,6 +88,48 @@ func GetLatestFullSnapshotAndDeltaSnapList(store brtypes.SnapStore) (*brtypes.Sn
 	}
 
 	sort.Sort(deltaSnapList) // ensures that the delta snapshot list is well formed
+	setLatestDeltasMetrics(deltaSnapList)
+	return fullSnapshot, deltaSnapList, nil
+}
+
+// GetLatestFullSnapshotAndDeltaSnapListFromChainManifest returns the full snapshot and the delta snapshots recorded in
+// the chain manifest of the store, which saves listing the store. The store is listed instead, as by
+// GetLatestFullSnapshotAndDeltaSnapList, if it has no chain manifest or if the chain manifest drifted from the
+// snapshots in the store, in which case the reason of the fallback is returned as well.
+func GetLatestFullSnapshotAndDeltaSnapListFromChainManifest(store brtypes.SnapStore, logger *logrus.Entry) (*brtypes.Snapshot, brtypes.SnapList, string, error) {
+	var fallback string
+	manifest, err := snapstore.FetchChainManifest(store)
+	switch {
+	case err != nil:
+		fallback = fmt.Sprintf("failed to fetch the chain manifest: %v", err)
+		logger.Warnf("Failed to fetch the chain manifest, listing the snapstore instead: %v", err)
+	case manifest == nil:
+		fallback = "no chain manifest found"
+		logger.Info("No chain manifest found, listing the snapstore instead.")
+	default:
+		if err := snapstore.VerifyChainManifest(store, manifest); err != nil {
+			fallback = fmt.Sprintf("chain manifest drifted from the snapshots in the snapstore: %v", err)
+			logger.Warnf("Chain manifest drifted from the snapshots in the snapstore, listing the snapstore instead: %v", err)
+			break
+		}
+		fullSnapshot := manifest.FullSnapshot.Snapshot
+		deltaSnapList := brtypes.SnapList{}
+		for _, snap := range manifest.DeltaSnapshots {
+			deltaSnap := snap.Snapshot
+			deltaSnapList = append(deltaSnapList, &deltaSnap)
+		}
+		sort.Sort(deltaSnapList)
+		setLatestDeltasMetrics(deltaSnapList)
+		logger.Infof("Found the latest full snapshot and %d delta snapshots in the chain manifest updated on %s.", len(deltaSnapList), manifest.UpdatedOn)
+		return &fullSnapshot, deltaSnapList, "", nil
+	}
+	fullSnapshot, deltaSnapList, err := GetLatestFullSnapshotAndDeltaSnapList(store)
+	return fullSnapshot, deltaSnapList, fallback, err
+}
+
+// setLatestDeltasMetrics sets the metrics of the delta snapshots of the latest full snapshot to the given sorted list
+// of delta snapshots.
+func setLatestDeltasMetrics(deltaSnapList brtypes.SnapList) {
 	metrics.SnapstoreLatestDeltasTotal.With(prometheus.Labels{}).Set(float64(len(deltaSnapList)))
 	if len(deltaSnapList) == 0 {
 		metrics.SnapstoreLatestDeltasRevisionsTotal.With(prometheus.Labels{}).Set(0)
@@ -86,7 +137,100 @@ func GetLatestFullSnapshotAndDeltaSnapList(store brtypes.SnapStore) (*brtypes.Sn
 		revisionDiff := deltaSnapList[len(deltaSnapList)-1].LastRevision - deltaSnapList[0].StartRevision
 		metrics.SnapstoreLatestDeltasRevisionsTotal.With(prometheus.Labels{}).Set(float64(revisionDiff))
 	}
-	return fullSnapshot, deltaSnapList, nil
+}
+
+// GetLatestRevisionCoverage returns the revisions which can be restored from the latest snapshot chain in the store.
+// It returns nil if the store does not contain any full snapshot.
+func GetLatestRevisionCoverage(store brtypes.SnapStore) (*brtypes.RevisionCoverage, error) {
+	fullSnapshot, deltaSnapList, err := GetLatestFullSnapshotAndDeltaSnapList(store)
+	if err != nil {
+		return nil, err
+	}
+	return GetRevisionCoverage(fullSnapshot, deltaSnapList), nil
+}
+
+// GetRevisionCoverage returns the revisions which can be restored from the given full snapshot and the sorted list of
+// the delta snapshots taken after it. It returns nil if the full snapshot is nil.
+func GetRevisionCoverage(fullSnapshot *brtypes.Snapshot, deltaSnapList brtypes.SnapList) *brtypes.RevisionCoverage {
+	if fullSnapshot == nil {
+		return nil
+	}
+	coverage := &brtypes.RevisionCoverage{
+		MinRevision: fullSnapshot.LastRevision,
+		MaxRevision: fullSnapshot.LastRevision,
+		Contiguous:  true,
+	}
+	for _, deltaSnap := range deltaSnapList {
+		if deltaSnap.StartRevision != coverage.MaxRevision+1 {
+			// the revisions after the gap can't be reached by a restoration
+			coverage.Contiguous = false
+			break
+		}
+		coverage.MaxRevision = deltaSnap.LastRevision
+	}
+	return coverage
+}
+
+// listLatestSnapshots returns the sorted list of snapshots required to assemble the latest snapshot chain.
+// The partitions of a date partitioned store are listed from newest to oldest until a full snapshot is found,
+// instead of listing the whole store, if the underlying store is able to list a single partition. Otherwise the
+// whole store is listed once, rather than once for the partitions and once more for each partition.
+// The listings are retried as long as they are throttled by the storage provider.
+func listLatestSnapshots(ctx context.Context, store brtypes.SnapStore) (brtypes.SnapList, error) {
+	partitionedStore, ok := store.(brtypes.PartitionedSnapStore)
+	if !ok || !snapstore.IsPartitionListingSupported(store) {
+		return snapstore.ListWithThrottlingRetries(ctx, store)
+	}
+
+	var partitions []string
+	if err := snapstore.RetryListOnThrottling(ctx, store, func() error {
+		var err error
+		partitions, err = partitionedStore.ListPartitions()
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	var snapList brtypes.SnapList
+	for index := len(partitions) - 1; index >= 0; index-- {
+		var partitionSnapList brtypes.SnapList
+		if err := snapstore.RetryListOnThrottling(ctx, store, func() error {
+			var err error
+			partitionSnapList, err = partitionedStore.ListPartition(partitions[index])
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		snapList = append(snapList, partitionSnapList...)
+		for _, snap := range partitionSnapList {
+			if !snap.IsChunk && snap.Kind == brtypes.SnapshotKindFull {
+				sort.Sort(snapList)
+				return snapList, nil
+			}
+		}
+	}
+
+	// None of the partitions contain a full snapshot, which is the case if the
+	// snapshots were taken before the store was partitioned.
+	return snapstore.ListWithThrottlingRetries(ctx, store)
+}
+
+// GetFinalSnapshot returns the most recent final full snapshot present in the store.
+// It returns nil if the store does not contain any final snapshot.
+func GetFinalSnapshot(store brtypes.SnapStore) (*brtypes.Snapshot, error) {
+	snapList, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	for index := len(snapList) - 1; index >= 0; index-- {
+		if snapList[index].IsChunk {
+			continue
+		}
+		if snapList[index].IsFinal {
+			return snapList[index], nil
+		}
+	}
+	return nil, nil
 }
 
 type backup struct {
@@ -146,11 +290,43 @@ func getStructuredBackupList(snapList brtypes.SnapList) []backup {
 
 // StartEmbeddedEtcd starts the embedded etcd server.
 func StartEmbeddedEtcd(logger *logrus.Entry, ro *brtypes.RestoreOptions) (*embed.Etcd, error) {
+	cfg, err := newEmbeddedEtcdConfig(ro)
+	if err != nil {
+		return nil, err
+	}
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-e.Server.ReadyNotify():
+		logger.Infof("Embedded server is ready to listen client at: %s", e.Clients[0].Addr())
+	case <-time.After(60 * time.Second):
+		e.Server.Stop() // trigger a shutdown
+		e.Close()
+		return nil, fmt.Errorf("server took too long to start")
+	}
+	return e, nil
+}
+
+// newEmbeddedEtcdConfig returns the config of the embedded etcd server for the given restore options.
+func newEmbeddedEtcdConfig(ro *brtypes.RestoreOptions) (*embed.Config, error) {
 	cfg := embed.NewConfig()
 	cfg.Dir = filepath.Join(ro.Config.DataDir)
-	DefaultListenPeerURLs := "http://localhost:0"
+	cfg.WalDir = ro.WALDir
+	peerScheme := "http"
+	if ro.PeerTLS.PeerTLSEnabled() {
+		peerScheme = https
+		cfg.PeerTLSInfo = transport.TLSInfo{
+			CertFile:       ro.PeerTLS.PeerCertFile,
+			KeyFile:        ro.PeerTLS.PeerKeyFile,
+			TrustedCAFile:  ro.PeerTLS.PeerCaFile,
+			ClientCertAuth: ro.PeerTLS.PeerCaFile != "",
+		}
+	}
+	DefaultListenPeerURLs := peerScheme + "://localhost:0"
 	DefaultListenClientURLs := "http://localhost:0"
-	DefaultInitialAdvertisePeerURLs := "http://localhost:0"
+	DefaultInitialAdvertisePeerURLs := peerScheme + "://localhost:0"
 	DefaultAdvertiseClientURLs := "http://localhost:0"
 	lpurl, err := url.Parse(DefaultListenPeerURLs)
 	if err != nil {
@@ -173,25 +349,38 @@ func StartEmbeddedEtcd(logger *logrus.Entry, ro *brtypes.RestoreOptions) (*embed
 	cfg.APUrls = []url.URL{*apurl}
 	cfg.ACUrls = []url.URL{*acurl}
 	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+	switch ro.InitialClusterState {
+	case "", ClusterStateNew:
+		cfg.ClusterState = embed.ClusterStateFlagNew
+	case ClusterStateExisting:
+		cfg.ClusterState = embed.ClusterStateFlagExisting
+	default:
+		return nil, fmt.Errorf("invalid initial cluster state %q, must be either %q or %q", ro.InitialClusterState, ClusterStateNew, ClusterStateExisting)
+	}
 	cfg.QuotaBackendBytes = ro.Config.EmbeddedEtcdQuotaBytes
 	cfg.MaxRequestBytes = ro.Config.MaxRequestBytes
 	cfg.MaxTxnOps = ro.Config.MaxTxnOps
 	cfg.AutoCompactionMode = ro.Config.AutoCompactionMode
 	cfg.AutoCompactionRetention = ro.Config.AutoCompactionRetention
+	cfg.ExperimentalBackendFreelistType = ro.Config.BackendFreelistType
 	cfg.Logger = "zap"
-	e, err := embed.StartEtcd(cfg)
-	if err != nil {
-		return nil, err
+	return cfg, nil
+}
+
+// NewBackendConfig returns the config of the backend of the db at the given path, which is restored as per the given
+// restoration config.
+func NewBackendConfig(dbPath string, config *brtypes.RestorationConfig) backend.BackendConfig {
+	bcfg := backend.DefaultBackendConfig()
+	bcfg.Path = dbPath
+	if config.BackendFreelistType == brtypes.BackendFreelistTypeMap {
+		bcfg.BackendFreelistType = bolt.FreelistMapType
+	} else {
+		bcfg.BackendFreelistType = bolt.FreelistArrayType
 	}
-	select {
-	case <-e.Server.ReadyNotify():
-		logger.Infof("Embedded server is ready to listen client at: %s", e.Clients[0].Addr())
-	case <-time.After(60 * time.Second):
-		e.Server.Stop() // trigger a shutdown
-		e.Close()
-		return nil, fmt.Errorf("server took too long to start")
+	if config.BackendInitialMmapSize > 0 {
+		bcfg.MmapSize = config.BackendInitialMmapSize
 	}
-	return e, nil
+	return bcfg
 }
 
 // GetKubernetesClientSetOrError creates and returns a kubernetes clientset or an error if creation fails
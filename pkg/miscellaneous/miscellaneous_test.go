@@ -11,19 +11,24 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"time"
 
 	mockfactory "github.com/gardener/etcd-backup-restore/pkg/mock/etcdutil/client"
 	"github.com/gardener/etcd-backup-restore/pkg/snapstore"
 	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+	"github.com/gardener/etcd-backup-restore/pkg/wrappers"
 	"github.com/golang/mock/gomock"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gstruct"
 
+	bolt "go.etcd.io/bbolt"
 	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/embed"
 	"go.etcd.io/etcd/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/mvcc/backend"
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/pointer"
@@ -100,6 +105,306 @@ var _ = Describe("Miscellaneous Tests", func() {
 		})
 	})
 
+	Describe("Getting the final snapshot", func() {
+		var (
+			store    brtypes.SnapStore
+			storeDir string
+		)
+
+		BeforeEach(func() {
+			var err error
+			storeDir, err = os.MkdirTemp("", "finalsnapshot")
+			Expect(err).ShouldNot(HaveOccurred())
+			store, err = snapstore.GetSnapstore(&brtypes.SnapstoreConfig{Container: storeDir, Prefix: "v2"})
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(storeDir)).To(Succeed())
+		})
+
+		Context("with no final snapshot in the store", func() {
+			It("should return nil", func() {
+				Expect(saveSnapshot(store, brtypes.SnapshotKindFull, 0, 100, false, time.Now().Add(-time.Hour))).To(Succeed())
+				Expect(saveSnapshot(store, brtypes.SnapshotKindDelta, 101, 200, false, time.Now())).To(Succeed())
+
+				snap, err := GetFinalSnapshot(store)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(snap).Should(BeNil())
+			})
+		})
+
+		Context("with a mix of final and non-final snapshots in the store", func() {
+			It("should return the most recent final snapshot", func() {
+				Expect(saveSnapshot(store, brtypes.SnapshotKindFull, 0, 100, true, time.Now().Add(-3*time.Hour))).To(Succeed())
+				Expect(saveSnapshot(store, brtypes.SnapshotKindFull, 0, 200, false, time.Now().Add(-2*time.Hour))).To(Succeed())
+				Expect(saveSnapshot(store, brtypes.SnapshotKindFull, 0, 300, true, time.Now().Add(-time.Hour))).To(Succeed())
+				Expect(saveSnapshot(store, brtypes.SnapshotKindDelta, 301, 400, false, time.Now())).To(Succeed())
+
+				snap, err := GetFinalSnapshot(store)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(snap).ShouldNot(BeNil())
+				Expect(snap.IsFinal).Should(BeTrue())
+				Expect(snap.Kind).Should(Equal(brtypes.SnapshotKindFull))
+				Expect(snap.LastRevision).Should(Equal(int64(300)))
+				Expect(snap.SnapName).Should(HaveSuffix(brtypes.FinalSuffix))
+			})
+		})
+	})
+
+	Describe("Getting the latest snapshot chain from a date partitioned store", func() {
+		var (
+			snapstoreConfig *brtypes.SnapstoreConfig
+			store           brtypes.SnapStore
+			storeDir        string
+		)
+
+		BeforeEach(func() {
+			var err error
+			storeDir, err = os.MkdirTemp("", "partitionedstore")
+			Expect(err).ShouldNot(HaveOccurred())
+			snapstoreConfig = &brtypes.SnapstoreConfig{Container: storeDir, Prefix: "v2", DatePartitionedPrefix: true}
+			store, err = snapstore.GetSnapstore(snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(store).Should(BeAssignableToTypeOf(&snapstore.DatePartitionedSnapStore{}))
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(storeDir)).To(Succeed())
+		})
+
+		Context("with the snapshot chain spread across multiple date partitions", func() {
+			It("should assemble the chain from the latest full snapshot", func() {
+				Expect(saveSnapshot(store, brtypes.SnapshotKindFull, 0, 100, false, time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC))).To(Succeed())
+				Expect(saveSnapshot(store, brtypes.SnapshotKindDelta, 101, 150, false, time.Date(2024, 3, 25, 0, 0, 0, 0, time.UTC))).To(Succeed())
+				Expect(saveSnapshot(store, brtypes.SnapshotKindFull, 0, 200, false, time.Date(2024, 4, 30, 23, 0, 0, 0, time.UTC))).To(Succeed())
+				Expect(saveSnapshot(store, brtypes.SnapshotKindDelta, 201, 250, false, time.Date(2024, 4, 30, 23, 30, 0, 0, time.UTC))).To(Succeed())
+				Expect(saveSnapshot(store, brtypes.SnapshotKindDelta, 251, 300, false, time.Date(2024, 5, 1, 0, 30, 0, 0, time.UTC))).To(Succeed())
+				Expect(saveSnapshot(store, brtypes.SnapshotKindDelta, 301, 350, false, time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC))).To(Succeed())
+
+				partitions, err := store.(brtypes.PartitionedSnapStore).ListPartitions()
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(partitions).Should(Equal([]string{"2024/03", "2024/04", "2024/05", "2024/06"}))
+
+				fullSnap, deltaSnapList, err := GetLatestFullSnapshotAndDeltaSnapList(store)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(fullSnap).ShouldNot(BeNil())
+				Expect(fullSnap.LastRevision).Should(Equal(int64(200)))
+				Expect(fullSnap.SnapDir).Should(Equal("2024/04"))
+				Expect(deltaSnapList).Should(HaveLen(3))
+				for i, expectedPartition := range []string{"2024/04", "2024/05", "2024/06"} {
+					Expect(deltaSnapList[i].SnapDir).Should(Equal(expectedPartition))
+					Expect(deltaSnapList[i].StartRevision).Should(Equal(int64(201 + 50*i)))
+				}
+
+				rc, err := store.Fetch(*fullSnap)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer rc.Close()
+				Expect(io.ReadAll(rc)).Should(ContainSubstring("dummy-snapshot-content"))
+			})
+		})
+
+		Context("with an underlying store which can't list a single partition", func() {
+			It("should list the whole store once", func() {
+				Expect(saveSnapshot(store, brtypes.SnapshotKindFull, 0, 100, false, time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC))).To(Succeed())
+				Expect(saveSnapshot(store, brtypes.SnapshotKindDelta, 101, 150, false, time.Date(2024, 4, 25, 0, 0, 0, 0, time.UTC))).To(Succeed())
+				Expect(saveSnapshot(store, brtypes.SnapshotKindDelta, 151, 200, false, time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC))).To(Succeed())
+
+				countingStore := &listCountingSnapStore{SnapStore: store.(*snapstore.DatePartitionedSnapStore).SnapStore}
+				fullSnap, deltaSnapList, err := GetLatestFullSnapshotAndDeltaSnapList(snapstore.NewDatePartitionedSnapStore(countingStore))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(fullSnap.LastRevision).Should(Equal(int64(100)))
+				Expect(deltaSnapList).Should(HaveLen(2))
+				Expect(countingStore.listings).Should(Equal(1))
+			})
+		})
+
+		Context("with the full snapshot taken before the store was partitioned", func() {
+			It("should assemble the chain from the unpartitioned full snapshot", func() {
+				unpartitionedStore, err := snapstore.GetSnapstore(&brtypes.SnapstoreConfig{Container: storeDir, Prefix: "v2"})
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(saveSnapshot(unpartitionedStore, brtypes.SnapshotKindFull, 0, 100, false, time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC))).To(Succeed())
+				Expect(saveSnapshot(unpartitionedStore, brtypes.SnapshotKindDelta, 101, 150, false, time.Date(2024, 5, 25, 0, 0, 0, 0, time.UTC))).To(Succeed())
+				Expect(saveSnapshot(store, brtypes.SnapshotKindDelta, 151, 200, false, time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC))).To(Succeed())
+
+				fullSnap, deltaSnapList, err := GetLatestFullSnapshotAndDeltaSnapList(store)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(fullSnap).ShouldNot(BeNil())
+				Expect(fullSnap.LastRevision).Should(Equal(int64(100)))
+				Expect(fullSnap.SnapDir).Should(BeEmpty())
+				Expect(deltaSnapList).Should(HaveLen(2))
+				Expect(deltaSnapList[0].SnapDir).Should(BeEmpty())
+				Expect(deltaSnapList[1].SnapDir).Should(Equal("2024/06"))
+			})
+		})
+	})
+
+	Describe("Getting the latest snapshot chain from a store throttling the listings", func() {
+		var (
+			store    *throttledListStore
+			storeDir string
+		)
+
+		BeforeEach(func() {
+			var err error
+			storeDir, err = os.MkdirTemp("", "throttledstore")
+			Expect(err).ShouldNot(HaveOccurred())
+			DeferCleanup(os.RemoveAll, storeDir)
+			localStore, err := snapstore.GetSnapstore(&brtypes.SnapstoreConfig{
+				Container:             storeDir,
+				Prefix:                "v2",
+				ListThrottlingRetries: 3,
+				ListThrottlingBackoff: wrappers.Duration{Duration: time.Millisecond},
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+			store = &throttledListStore{SnapStore: localStore}
+			Expect(saveSnapshot(store, brtypes.SnapshotKindFull, 0, 100, false, time.Now().Add(-time.Hour))).To(Succeed())
+			Expect(saveSnapshot(store, brtypes.SnapshotKindDelta, 101, 200, false, time.Now())).To(Succeed())
+		})
+
+		It("should retry the throttled listings until they succeed", func() {
+			store.throttledLists = 2
+
+			fullSnap, deltaSnapList, err := GetLatestFullSnapshotAndDeltaSnapList(store)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(fullSnap).ShouldNot(BeNil())
+			Expect(fullSnap.LastRevision).Should(Equal(int64(100)))
+			Expect(deltaSnapList).Should(HaveLen(1))
+			Expect(store.throttledLists).Should(BeZero())
+		})
+
+		It("should fail once the listings are throttled more often than they are retried", func() {
+			store.throttledLists = 5
+
+			_, _, err := GetLatestFullSnapshotAndDeltaSnapList(store)
+			Expect(snapstore.IsThrottlingError(err)).Should(BeTrue())
+			Expect(store.throttledLists).Should(Equal(1))
+		})
+	})
+
+	Describe("Getting the revision coverage of the latest snapshot chain", func() {
+		newSnap := func(kind string, startRevision, lastRevision int64) *brtypes.Snapshot {
+			return &brtypes.Snapshot{Kind: kind, StartRevision: startRevision, LastRevision: lastRevision}
+		}
+
+		DescribeTable("should report the restorable revisions of the chain",
+			func(fullSnap *brtypes.Snapshot, deltaSnapList brtypes.SnapList, expectedCoverage *brtypes.RevisionCoverage) {
+				Expect(GetRevisionCoverage(fullSnap, deltaSnapList)).Should(Equal(expectedCoverage))
+			},
+			Entry("without a full snapshot", nil, brtypes.SnapList{newSnap(brtypes.SnapshotKindDelta, 101, 150)}, nil),
+			Entry("with only a full snapshot", newSnap(brtypes.SnapshotKindFull, 0, 100), nil,
+				&brtypes.RevisionCoverage{MinRevision: 100, MaxRevision: 100, Contiguous: true}),
+			Entry("with contiguous delta snapshots", newSnap(brtypes.SnapshotKindFull, 0, 100),
+				brtypes.SnapList{newSnap(brtypes.SnapshotKindDelta, 101, 150), newSnap(brtypes.SnapshotKindDelta, 151, 200)},
+				&brtypes.RevisionCoverage{MinRevision: 100, MaxRevision: 200, Contiguous: true}),
+			Entry("with a gap between the full and the first delta snapshot", newSnap(brtypes.SnapshotKindFull, 0, 100),
+				brtypes.SnapList{newSnap(brtypes.SnapshotKindDelta, 120, 150), newSnap(brtypes.SnapshotKindDelta, 151, 200)},
+				&brtypes.RevisionCoverage{MinRevision: 100, MaxRevision: 100, Contiguous: false}),
+			Entry("with a gap between the delta snapshots", newSnap(brtypes.SnapshotKindFull, 0, 100),
+				brtypes.SnapList{newSnap(brtypes.SnapshotKindDelta, 101, 150), newSnap(brtypes.SnapshotKindDelta, 160, 200), newSnap(brtypes.SnapshotKindDelta, 201, 250)},
+				&brtypes.RevisionCoverage{MinRevision: 100, MaxRevision: 150, Contiguous: false}),
+			Entry("with overlapping delta snapshots", newSnap(brtypes.SnapshotKindFull, 0, 100),
+				brtypes.SnapList{newSnap(brtypes.SnapshotKindDelta, 101, 150), newSnap(brtypes.SnapshotKindDelta, 140, 200)},
+				&brtypes.RevisionCoverage{MinRevision: 100, MaxRevision: 150, Contiguous: false}),
+		)
+
+		It("should report the restorable revisions of the latest chain in the store", func() {
+			storeDir, err := os.MkdirTemp("", "coveragestore")
+			Expect(err).ShouldNot(HaveOccurred())
+			defer os.RemoveAll(storeDir)
+			store, err := snapstore.GetSnapstore(&brtypes.SnapstoreConfig{Container: storeDir, Prefix: "v2"})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			coverage, err := GetLatestRevisionCoverage(store)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(coverage).Should(BeNil())
+
+			now := time.Now().UTC()
+			Expect(saveSnapshot(store, brtypes.SnapshotKindFull, 0, 100, false, now.Add(-4*time.Hour))).To(Succeed())
+			Expect(saveSnapshot(store, brtypes.SnapshotKindDelta, 101, 150, false, now.Add(-3*time.Hour))).To(Succeed())
+			Expect(saveSnapshot(store, brtypes.SnapshotKindFull, 0, 200, false, now.Add(-2*time.Hour))).To(Succeed())
+			Expect(saveSnapshot(store, brtypes.SnapshotKindDelta, 201, 250, false, now.Add(-time.Hour))).To(Succeed())
+			Expect(saveSnapshot(store, brtypes.SnapshotKindDelta, 251, 300, false, now)).To(Succeed())
+
+			coverage, err = GetLatestRevisionCoverage(store)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(coverage).Should(Equal(&brtypes.RevisionCoverage{MinRevision: 200, MaxRevision: 300, Contiguous: true}))
+		})
+	})
+
+	Describe("Getting the latest snapshot chain with a custom snapshot namer", func() {
+		var (
+			store    brtypes.SnapStore
+			storeDir string
+		)
+
+		BeforeEach(func() {
+			var err error
+			storeDir, err = os.MkdirTemp("", "namedstore")
+			Expect(err).ShouldNot(HaveOccurred())
+			store, err = snapstore.GetSnapstore(&brtypes.SnapstoreConfig{Container: storeDir, Prefix: "v2", SnapshotNamer: testSnapshotNamer{}})
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(storeDir)).To(Succeed())
+		})
+
+		It("should generate and parse the snapshot names with the custom namer", func() {
+			var snaps brtypes.SnapList
+			for _, snap := range []*brtypes.Snapshot{
+				snapstore.NewSnapshotForStore(store, brtypes.SnapshotKindFull, 0, 100, ".gz", false),
+				snapstore.NewSnapshotForStore(store, brtypes.SnapshotKindDelta, 101, 150, ".gz", false),
+				snapstore.NewSnapshotForStore(store, brtypes.SnapshotKindDelta, 151, 200, "", true),
+			} {
+				Expect(snap.SnapName).Should(HavePrefix("etcd-"))
+				Expect(store.Save(*snap, io.NopCloser(strings.NewReader("dummy-snapshot-content")))).To(Succeed())
+				snaps = append(snaps, snap)
+			}
+			// snapshots named by the default namer are not recognised by the custom namer
+			Expect(saveSnapshot(store, brtypes.SnapshotKindFull, 0, 300, false, time.Now())).To(Succeed())
+
+			fullSnap, deltaSnapList, err := GetLatestFullSnapshotAndDeltaSnapList(store)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(fullSnap).ShouldNot(BeNil())
+			Expect(fullSnap.SnapName).Should(Equal(snaps[0].SnapName))
+			Expect(fullSnap.LastRevision).Should(Equal(int64(100)))
+			Expect(fullSnap.CompressionSuffix).Should(Equal(".gz"))
+			Expect(fullSnap.CreatedOn).Should(Equal(snaps[0].CreatedOn.Truncate(time.Second)))
+			Expect(deltaSnapList).Should(HaveLen(2))
+			for i, deltaSnap := range deltaSnapList {
+				Expect(deltaSnap.SnapName).Should(Equal(snaps[i+1].SnapName))
+				Expect(deltaSnap.Kind).Should(Equal(brtypes.SnapshotKindDelta))
+				Expect(deltaSnap.StartRevision).Should(Equal(snaps[i+1].StartRevision))
+				Expect(deltaSnap.LastRevision).Should(Equal(snaps[i+1].LastRevision))
+				Expect(deltaSnap.CompressionSuffix).Should(Equal(snaps[i+1].CompressionSuffix))
+				Expect(deltaSnap.IsFinal).Should(Equal(snaps[i+1].IsFinal))
+			}
+		})
+
+		It("should name and parse the snapshots of another snapstore with the default namer", func() {
+			defaultStoreDir, err := os.MkdirTemp("", "defaultstore")
+			Expect(err).ShouldNot(HaveOccurred())
+			defer os.RemoveAll(defaultStoreDir)
+			defaultStore, err := snapstore.GetSnapstore(&brtypes.SnapstoreConfig{Container: defaultStoreDir, Prefix: "v2"})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			namedSnap := snapstore.NewSnapshotForStore(store, brtypes.SnapshotKindFull, 0, 100, "", false)
+			Expect(namedSnap.SnapName).Should(HavePrefix("etcd-"))
+			Expect(store.Save(*namedSnap, io.NopCloser(strings.NewReader("dummy-snapshot-content")))).To(Succeed())
+			defaultSnap := snapstore.NewSnapshotForStore(defaultStore, brtypes.SnapshotKindFull, 0, 200, "", false)
+			Expect(defaultSnap.SnapName).Should(HavePrefix(brtypes.SnapshotKindFull + "-"))
+			Expect(defaultStore.Save(*defaultSnap, io.NopCloser(strings.NewReader("dummy-snapshot-content")))).To(Succeed())
+
+			fullSnap, _, err := GetLatestFullSnapshotAndDeltaSnapList(store)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(fullSnap.SnapName).Should(Equal(namedSnap.SnapName))
+			fullSnap, _, err = GetLatestFullSnapshotAndDeltaSnapList(defaultStore)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(fullSnap.SnapName).Should(Equal(defaultSnap.SnapName))
+		})
+	})
+
 	Describe("Etcd Cluster", func() {
 		var (
 			dummyID              = uint64(1111)
@@ -780,6 +1085,90 @@ initial-cluster: etcd1=http://0.0.0.0:2380`
 			})
 		})
 	})
+
+	Describe("Configuring the embedded etcd", func() {
+		var ro *brtypes.RestoreOptions
+
+		BeforeEach(func() {
+			ro = &brtypes.RestoreOptions{
+				Config: brtypes.NewRestorationConfig(),
+			}
+		})
+
+		Context("without peer TLS", func() {
+			It("should use the http scheme for the peer URLs", func() {
+				cfg, err := newEmbeddedEtcdConfig(ro)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(cfg.LPUrls[0].Scheme).To(Equal("http"))
+				Expect(cfg.APUrls[0].Scheme).To(Equal("http"))
+				Expect(cfg.PeerTLSInfo.Empty()).To(BeTrue())
+			})
+		})
+
+		Context("with peer TLS", func() {
+			It("should use the https scheme and the TLS files for the peer communication", func() {
+				ro.PeerTLS = brtypes.PeerTLSConfig{
+					PeerCertFile: "/var/etcd/ssl/peer/tls.crt",
+					PeerKeyFile:  "/var/etcd/ssl/peer/tls.key",
+					PeerCaFile:   "/var/etcd/ssl/peer/ca.crt",
+				}
+				cfg, err := newEmbeddedEtcdConfig(ro)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(cfg.LPUrls[0].Scheme).To(Equal(https))
+				Expect(cfg.APUrls[0].Scheme).To(Equal(https))
+				Expect(cfg.LCUrls[0].Scheme).To(Equal("http"))
+				Expect(cfg.PeerTLSInfo.CertFile).To(Equal("/var/etcd/ssl/peer/tls.crt"))
+				Expect(cfg.PeerTLSInfo.KeyFile).To(Equal("/var/etcd/ssl/peer/tls.key"))
+				Expect(cfg.PeerTLSInfo.TrustedCAFile).To(Equal("/var/etcd/ssl/peer/ca.crt"))
+				Expect(cfg.PeerTLSInfo.ClientCertAuth).To(BeTrue())
+				Expect(cfg.Validate()).To(Succeed())
+			})
+		})
+
+		DescribeTable("should start the embedded etcd with the initial cluster state of the restored member",
+			func(initialClusterState, expectedClusterState string) {
+				ro.InitialClusterState = initialClusterState
+				cfg, err := newEmbeddedEtcdConfig(ro)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(cfg.ClusterState).To(Equal(expectedClusterState))
+				Expect(cfg.IsNewCluster()).To(Equal(expectedClusterState == embed.ClusterStateFlagNew))
+			},
+			Entry("by default, bootstrapping a new cluster", "", embed.ClusterStateFlagNew),
+			Entry("bootstrapping a new cluster", ClusterStateNew, embed.ClusterStateFlagNew),
+			Entry("joining an existing cluster", ClusterStateExisting, embed.ClusterStateFlagExisting),
+		)
+
+		It("should fail for an invalid initial cluster state", func() {
+			ro.InitialClusterState = "unknown"
+			_, err := newEmbeddedEtcdConfig(ro)
+			Expect(err).Should(MatchError(ContainSubstring("invalid initial cluster state")))
+		})
+
+		It("should use the configured backend freelist type", func() {
+			ro.Config.BackendFreelistType = brtypes.BackendFreelistTypeMap
+			cfg, err := newEmbeddedEtcdConfig(ro)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(cfg.ExperimentalBackendFreelistType).To(Equal("map"))
+		})
+	})
+
+	Describe("Configuring the backend of the restored db", func() {
+		It("should use the defaults of etcd without backend options", func() {
+			bcfg := NewBackendConfig("/var/etcd/data/member/snap/db", brtypes.NewRestorationConfig())
+			Expect(bcfg.Path).To(Equal("/var/etcd/data/member/snap/db"))
+			Expect(bcfg.BackendFreelistType).To(Equal(bolt.FreelistArrayType))
+			Expect(bcfg.MmapSize).To(Equal(backend.DefaultBackendConfig().MmapSize))
+		})
+
+		It("should pass the configured backend options through", func() {
+			config := brtypes.NewRestorationConfig()
+			config.BackendFreelistType = brtypes.BackendFreelistTypeMap
+			config.BackendInitialMmapSize = 64 * 1024 * 1024
+			bcfg := NewBackendConfig("/var/etcd/data/member/snap/db", config)
+			Expect(bcfg.BackendFreelistType).To(Equal(bolt.FreelistMapType))
+			Expect(bcfg.MmapSize).To(Equal(uint64(64 * 1024 * 1024)))
+		})
+	})
 })
 
 func emptyStatefulSet(name, namespace string) *appsv1.StatefulSet {
@@ -791,6 +1180,52 @@ func emptyStatefulSet(name, namespace string) *appsv1.StatefulSet {
 	}
 }
 
+// saveSnapshot saves a dummy snapshot with the given metadata to the store.
+func saveSnapshot(store brtypes.SnapStore, kind string, startRevision, lastRevision int64, isFinal bool, createdOn time.Time) error {
+	snap := &brtypes.Snapshot{
+		Kind:          kind,
+		StartRevision: startRevision,
+		LastRevision:  lastRevision,
+		CreatedOn:     createdOn,
+		IsFinal:       isFinal,
+	}
+	snap.GenerateSnapshotName()
+	return store.Save(*snap, io.NopCloser(strings.NewReader(fmt.Sprintf("dummy-snapshot-content for snap created on %s", snap.CreatedOn))))
+}
+
+// testSnapshotNamer names the snapshots etcd-<kind>_<start revision>_<last revision>_<creation time><compression suffix>[_final].
+type testSnapshotNamer struct{}
+
+func (testSnapshotNamer) GenerateName(snap *brtypes.Snapshot) string {
+	name := fmt.Sprintf("etcd-%s_%d_%d_%d%s", snap.Kind, snap.StartRevision, snap.LastRevision, snap.CreatedOn.Unix(), snap.CompressionSuffix)
+	if snap.IsFinal {
+		name += "_final"
+	}
+	return name
+}
+
+func (testSnapshotNamer) Parse(snapName string) (*brtypes.Snapshot, error) {
+	if !strings.HasPrefix(snapName, "etcd-") {
+		return nil, fmt.Errorf("invalid snapshot name: %s", snapName)
+	}
+	snap := &brtypes.Snapshot{SnapName: snapName}
+	name := strings.TrimPrefix(snapName, "etcd-")
+	if strings.HasSuffix(name, "_final") {
+		snap.IsFinal = true
+		name = strings.TrimSuffix(name, "_final")
+	}
+	if idx := strings.Index(name, "."); idx >= 0 {
+		snap.CompressionSuffix = name[idx:]
+		name = name[:idx]
+	}
+	var createdOn int64
+	if _, err := fmt.Sscanf(strings.ReplaceAll(name, "_", " "), "%s %d %d %d", &snap.Kind, &snap.StartRevision, &snap.LastRevision, &createdOn); err != nil {
+		return nil, fmt.Errorf("invalid snapshot name %s: %v", snapName, err)
+	}
+	snap.CreatedOn = time.Unix(createdOn, 0).UTC()
+	return snap, nil
+}
+
 func generateSnapshotList(n int) brtypes.SnapList {
 	snapList := brtypes.SnapList{}
 	for i := 0; i < n; i++ {
@@ -809,6 +1244,24 @@ func generateSnapshotList(n int) brtypes.SnapList {
 	return snapList
 }
 
+// throttledListStore fails the next listings of the snapstore it wraps as if they were throttled.
+type throttledListStore struct {
+	brtypes.SnapStore
+	throttledLists int
+}
+
+func (s *throttledListStore) List() (brtypes.SnapList, error) {
+	if s.throttledLists > 0 {
+		s.throttledLists--
+		return nil, fmt.Errorf("SlowDown: Please reduce your request rate")
+	}
+	return s.SnapStore.List()
+}
+
+func (s *throttledListStore) ListThrottlingRetries() (int, time.Duration) {
+	return s.SnapStore.(brtypes.ListThrottlingSnapStore).ListThrottlingRetries()
+}
+
 type DummyStore struct {
 	SnapList brtypes.SnapList
 }
@@ -832,3 +1285,14 @@ func (ds *DummyStore) Save(snap brtypes.Snapshot, rc io.ReadCloser) error {
 func (ds *DummyStore) Fetch(snap brtypes.Snapshot) (io.ReadCloser, error) {
 	return nil, nil
 }
+
+// listCountingSnapStore counts the listings of the snapstore it wraps, which it hides the other capabilities of.
+type listCountingSnapStore struct {
+	brtypes.SnapStore
+	listings int
+}
+
+func (s *listCountingSnapStore) List() (brtypes.SnapList, error) {
+	s.listings++
+	return s.SnapStore.List()
+}
@@ -6,6 +6,7 @@ package server
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"net/http"
 	"sync"
@@ -16,6 +17,7 @@ import (
 	"github.com/gardener/etcd-backup-restore/pkg/defragmentor"
 	"github.com/gardener/etcd-backup-restore/pkg/errors"
 	"github.com/gardener/etcd-backup-restore/pkg/etcdutil"
+	"github.com/gardener/etcd-backup-restore/pkg/events"
 	"github.com/gardener/etcd-backup-restore/pkg/health/heartbeat"
 	"github.com/gardener/etcd-backup-restore/pkg/health/membergarbagecollector"
 	"github.com/gardener/etcd-backup-restore/pkg/initializer"
@@ -31,15 +33,17 @@ import (
 	cron "github.com/robfig/cron/v3"
 	"github.com/sirupsen/logrus"
 	"go.etcd.io/etcd/pkg/types"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/util/retry"
 )
 
 // BackupRestoreServer holds the details for backup-restore server.
 type BackupRestoreServer struct {
-	logger                  *logrus.Entry
-	config                  *BackupRestoreComponentConfig
-	defragmentationSchedule cron.Schedule
-	backoffConfig           *backoff.ExponentialBackoff
+	logger                        *logrus.Entry
+	config                        *BackupRestoreComponentConfig
+	defragmentationSchedule       cron.Schedule
+	memberDefragmentationSchedule cron.Schedule
+	backoffConfig                 *backoff.ExponentialBackoff
 }
 
 var (
@@ -56,13 +60,19 @@ func NewBackupRestoreServer(logger *logrus.Logger, config *BackupRestoreComponen
 		// Ideally this case should not occur, since this check is done at the config validaitions.
 		return nil, err
 	}
+	memberDefragmentationSchedule, err := defragmentor.ParseMemberDefragmentationSchedule(config.MemberDefragmentationSchedule)
+	if err != nil {
+		// Ideally this case should not occur, since this check is done at the config validaitions.
+		return nil, err
+	}
 	exponentialBackoffConfig := backoff.NewExponentialBackOffConfig(config.ExponentialBackoffConfig.AttemptLimit, config.ExponentialBackoffConfig.Multiplier, config.ExponentialBackoffConfig.ThresholdTime.Duration)
 
 	return &BackupRestoreServer{
-		logger:                  serverLogger,
-		config:                  config,
-		defragmentationSchedule: defragmentationSchedule,
-		backoffConfig:           exponentialBackoffConfig,
+		logger:                        serverLogger,
+		config:                        config,
+		defragmentationSchedule:       defragmentationSchedule,
+		memberDefragmentationSchedule: memberDefragmentationSchedule,
+		backoffConfig:                 exponentialBackoffConfig,
 	}, nil
 }
 
@@ -98,41 +108,68 @@ func (b *BackupRestoreServer) Run(ctx context.Context) error {
 		b.logger.Fatalf("failed creating url map for restore cluster: %v", err)
 	}
 
+	restoreToTime, err := b.config.RestorationConfig.GetRestoreToTime()
+	if err != nil {
+		// Ideally this case should not occur, since this check is done at the config validations.
+		b.logger.Fatalf("failed parsing the time to restore to: %v", err)
+	}
+
 	options := &brtypes.RestoreOptions{
 		Config:              b.config.RestorationConfig,
 		ClusterURLs:         clusterURLsMap,
 		OriginalClusterSize: initialClusterSize,
 		PeerURLs:            peerURLs,
+		PeerTLS:             b.config.EtcdConnectionConfig.PeerTLSConfig,
+		MaxRestoreDuration:  b.config.RestorationConfig.MaxRestoreDuration.Duration,
+		RestoreLockTTL:      b.config.RestorationConfig.RestoreLockTTL.Duration,
+		RestoreToTime:       restoreToTime,
 	}
 
 	if b.config.SnapstoreConfig == nil || len(b.config.SnapstoreConfig.Provider) == 0 {
 		b.logger.Warnf("No snapstore storage provider configured. Will not start backup schedule.")
 		runServerWithSnapshotter = false
+	} else if err := verifySnapstore(b.config.SnapstoreConfig); err != nil {
+		return err
 	}
 	return b.runServer(ctx, options)
 }
 
+// verifySnapstore verifies at startup that the bucket or container of the configured snapstore exists and is
+// writable, instead of failing the first snapshot saved to it.
+func verifySnapstore(config *brtypes.SnapstoreConfig) error {
+	if config.SkipVerification {
+		return nil
+	}
+	store, err := snapstore.GetSnapstore(config)
+	if err != nil {
+		return fmt.Errorf("failed to create snapstore from configured storage provider: %v", err)
+	}
+	return snapstore.VerifySnapstore(store, config)
+}
+
 // startHTTPServer creates and starts the HTTP handler
 // with status 503 (Service Unavailable)
 func (b *BackupRestoreServer) startHTTPServer(initializer initializer.Initializer, storageProvider string, etcdConfig *brtypes.EtcdConnectionConfig, snapstoreConfig *brtypes.SnapstoreConfig, ssr *snapshotter.Snapshotter) *HTTPHandler {
 	// Start http handler with Error state and wait till snapshotter is up
 	// and running before setting the status to OK.
 	handler := &HTTPHandler{
-		Port:                 b.config.ServerConfig.Port,
-		Initializer:          initializer,
-		Snapshotter:          ssr,
-		Logger:               b.logger,
-		StopCh:               make(chan struct{}),
-		EnableProfiling:      b.config.ServerConfig.EnableProfiling,
-		ReqCh:                make(chan struct{}),
-		AckCh:                make(chan struct{}),
-		EnableTLS:            b.config.ServerConfig.TLSCertFile != "" && b.config.ServerConfig.TLSKeyFile != "",
-		ServerTLSCertFile:    b.config.ServerConfig.TLSCertFile,
-		ServerTLSKeyFile:     b.config.ServerConfig.TLSKeyFile,
-		HTTPHandlerMutex:     &sync.Mutex{},
-		EtcdConnectionConfig: etcdConfig,
-		StorageProvider:      storageProvider,
-		SnapstoreConfig:      snapstoreConfig,
+		Port:                  b.config.ServerConfig.Port,
+		BindAddress:           b.config.ServerConfig.BindAddress,
+		Initializer:           initializer,
+		Snapshotter:           ssr,
+		Logger:                b.logger,
+		StopCh:                make(chan struct{}),
+		EnableProfiling:       b.config.ServerConfig.EnableProfiling,
+		ReqCh:                 make(chan struct{}),
+		AckCh:                 make(chan struct{}),
+		EnableTLS:             b.config.ServerConfig.TLSCertFile != "" && b.config.ServerConfig.TLSKeyFile != "",
+		ServerTLSCertFile:     b.config.ServerConfig.TLSCertFile,
+		ServerTLSKeyFile:      b.config.ServerConfig.TLSKeyFile,
+		ServerTLSClientCAFile: b.config.ServerConfig.TLSClientCAFile,
+		HTTPHandlerMutex:      &sync.Mutex{},
+		EtcdConnectionConfig:  etcdConfig,
+		StorageProvider:       storageProvider,
+		SnapstoreConfig:       snapstoreConfig,
 	}
 	handler.SetStatus(http.StatusServiceUnavailable)
 	b.logger.Info("Registering the http request handlers...")
@@ -185,24 +222,58 @@ func isEtcdRunning(ctx context.Context, timeout time.Duration, etcdConnectionCon
 	return true
 }
 
+// newEventRecorder returns the recorder of the Kubernetes events of the pod if the events are enabled. The events are
+// dropped if they are disabled, or if the pod or the Kubernetes client can't be determined.
+func (b *BackupRestoreServer) newEventRecorder(ctx context.Context) events.Recorder {
+	if !b.config.HealthConfig.KubernetesEventsEnabled {
+		return events.NewNopRecorder()
+	}
+	podName, err := miscellaneous.GetEnvVarOrError("POD_NAME")
+	if err != nil {
+		b.logger.Warnf("Kubernetes events are disabled: %v", err)
+		return events.NewNopRecorder()
+	}
+	podNamespace, err := miscellaneous.GetEnvVarOrError("POD_NAMESPACE")
+	if err != nil {
+		b.logger.Warnf("Kubernetes events are disabled: %v", err)
+		return events.NewNopRecorder()
+	}
+	clientSet, err := miscellaneous.GetKubernetesClientSetOrError()
+	if err != nil {
+		b.logger.Warnf("Kubernetes events are disabled, failed to create clientset: %v", err)
+		return events.NewNopRecorder()
+	}
+	return events.NewRecorder(ctx, b.logger, clientSet, podName, podNamespace)
+}
+
+// promoteLearner promotes the member to a voting member if it is a learner.
+func promoteLearner(ctx context.Context, m member.Control, recorder events.Recorder, logger *logrus.Entry) {
+	if err := m.PromoteMember(ctx); err != nil {
+		logger.Errorf("unable to promote the learner to a voting member: %v", err)
+		recorder.Eventf(corev1.EventTypeWarning, events.ReasonLearnerPromotionFailed, "Promoting the learner to a voting member failed: %v", err)
+		return
+	}
+	logger.Info("Successfully promoted the learner to a voting member...")
+	recorder.Eventf(corev1.EventTypeNormal, events.ReasonLearnerPromoted, "Promoted the learner to a voting member")
+}
+
 // runServer runs the etcd-backup-restore server according to snapstore provider configuration.
 func (b *BackupRestoreServer) runServer(ctx context.Context, restoreOpts *brtypes.RestoreOptions) error {
 	var (
-		snapstoreConfig *brtypes.SnapstoreConfig
-		ssr             *snapshotter.Snapshotter
-		ss              brtypes.SnapStore
+		ssr *snapshotter.Snapshotter
+		ss  brtypes.SnapStore
 	)
 	ackCh := make(chan struct{})
 	ssrStopCh := make(chan struct{})
 	mmStopCh := make(chan struct{})
 
-	if runServerWithSnapshotter {
-		snapstoreConfig = b.config.SnapstoreConfig
-	}
-	etcdInitializer, err := initializer.NewInitializer(restoreOpts, snapstoreConfig, b.config.EtcdConnectionConfig, b.logger.Logger)
+	eventRecorder := b.newEventRecorder(ctx)
+
+	etcdInitializer, err := initializer.NewInitializer(restoreOpts, b.config.GetRestoreSnapstoreConfig(), b.config.EtcdConnectionConfig, b.logger.Logger)
 	if err != nil {
 		return err
 	}
+	etcdInitializer.EventRecorder = eventRecorder
 
 	handler := b.startHTTPServer(etcdInitializer, b.config.SnapstoreConfig.Provider, b.config.EtcdConnectionConfig, b.config.SnapstoreConfig, nil)
 	defer func() {
@@ -217,6 +288,14 @@ func (b *BackupRestoreServer) runServer(ctx context.Context, restoreOpts *brtype
 		return err
 	}
 
+	// the cluster is scaled up once etcd has been started with the data directory restored by the initializer
+	go func() {
+		if err := etcdInitializer.WaitForScaleUp(ctx); err != nil && ctx.Err() == nil {
+			b.logger.Errorf("%v", err)
+			eventRecorder.Eventf(corev1.EventTypeWarning, events.ReasonScaleUpFailed, "Scaling up the cluster after the restoration failed: %v", err)
+		}
+	}()
+
 	m := member.NewMemberControl(b.config.EtcdConnectionConfig)
 	if err := retry.OnError(retry.DefaultBackoff, errors.IsErrNotNil, func() error {
 		cli, err := etcdutil.NewFactory(*b.config.EtcdConnectionConfig).NewCluster()
@@ -233,6 +312,11 @@ func (b *BackupRestoreServer) runServer(ctx context.Context, restoreOpts *brtype
 		b.logger.Errorf("failed to update member peer url: %v", err)
 	}
 
+	// every member is defragmented on its own schedule, irrespective of the leadership of its backup-restore
+	if b.memberDefragmentationSchedule != nil {
+		go defragmentor.DefragMemberPeriodically(ctx, b.config.EtcdConnectionConfig, b.memberDefragmentationSchedule, b.logger)
+	}
+
 	leaderCallbacks := &brtypes.LeaderCallbacks{
 		OnStartedLeading: func(leCtx context.Context) {
 			ssrStopCh = make(chan struct{})
@@ -251,6 +335,8 @@ func (b *BackupRestoreServer) runServer(ctx context.Context, restoreOpts *brtype
 				if err != nil {
 					b.logger.Fatalf("failed to create new Snapshotter object: %v", err)
 				}
+				ssr.EventRecorder = eventRecorder
+				ssr.RestoreOptions = restoreOpts
 
 				// set "http handler" with the latest snapshotter object
 				handler.SetSnapshotter(ssr)
@@ -295,14 +381,12 @@ func (b *BackupRestoreServer) runServer(ctx context.Context, restoreOpts *brtype
 	promoteCallback := &brtypes.PromoteLearnerCallback{
 		Promote: func(ctx context.Context, logger *logrus.Entry) {
 			if restoreOpts.OriginalClusterSize > 1 {
-				m := member.NewMemberControl(b.config.EtcdConnectionConfig)
-				if err := m.PromoteMember(ctx); err == nil {
-					logger.Info("Successfully promoted the learner to a voting member...")
-				} else {
-					logger.Errorf("unable to promote the learner to a voting member: %v", err)
-				}
+				promoteLearner(ctx, member.NewMemberControl(b.config.EtcdConnectionConfig), eventRecorder, logger)
 			}
 		},
+		RaftIndexLag: func(ctx context.Context, logger *logrus.Entry) (uint64, error) {
+			return leaderelection.EtcdLearnerRaftIndexLag(ctx, b.config.EtcdConnectionConfig, b.config.LeaderElectionConfig.EtcdConnectionTimeout.Duration, logger)
+		},
 	}
 
 	checkLeadershipFunc := leaderelection.EtcdMemberStatus
@@ -378,10 +462,10 @@ func (b *BackupRestoreServer) runEtcdProbeLoopWithSnapshotter(ctx context.Contex
 			// the delta snapshot memory limit), after which a full snapshot
 			// is taken and the regular snapshot schedule comes into effect.
 
-			fullSnapshotMaxTimeWindowInHours := ssr.GetFullSnapshotMaxTimeWindow(b.config.SnapshotterConfig.FullSnapshotSchedule)
+			fullSnapshotMaxTimeWindowInHours := ssr.GetFullSnapshotsMaxTimeWindow()
 			initialDeltaSnapshotTaken = false
 			if !ssr.IsFullSnapshotRequiredAtStartup(fullSnapshotMaxTimeWindowInHours) {
-				ssrStopped, err := ssr.CollectEventsSincePrevSnapshot(ssrStopCh)
+				ssrStopped, err := ssr.CollectEventsSincePrevSnapshot(ctx, ssrStopCh)
 				if ssrStopped {
 					b.logger.Info("Snapshotter stopped.")
 					ackCh <- emptyStruct
@@ -389,7 +473,7 @@ func (b *BackupRestoreServer) runEtcdProbeLoopWithSnapshotter(ctx context.Contex
 					return
 				}
 				if err == nil {
-					if _, err := ssr.TakeDeltaSnapshot(); err != nil {
+					if _, err := ssr.TakeDeltaSnapshot(ctx); err != nil {
 						b.logger.Warnf("Failed to take first delta snapshot: snapshotter failed with error: %v", err)
 						continue
 					}
@@ -397,7 +481,7 @@ func (b *BackupRestoreServer) runEtcdProbeLoopWithSnapshotter(ctx context.Contex
 					if b.config.HealthConfig.SnapshotLeaseRenewalEnabled {
 						leaseUpdatectx, cancel := context.WithTimeout(ctx, brtypes.LeaseUpdateTimeoutDuration)
 						defer cancel()
-						if err = heartbeat.DeltaSnapshotCaseLeaseUpdate(leaseUpdatectx, b.logger, ssr.K8sClientset, b.config.HealthConfig.DeltaSnapshotLeaseName, ss); err != nil {
+						if err = heartbeat.DeltaSnapshotCaseLeaseUpdate(leaseUpdatectx, b.logger, ssr.K8sClientset, b.config.HealthConfig.SnapshotLeaseNamespace, b.config.HealthConfig.DeltaSnapshotLeaseName, ss); err != nil {
 							b.logger.Warnf("Snapshot lease update failed : %v", err)
 						}
 					}
@@ -415,7 +499,7 @@ func (b *BackupRestoreServer) runEtcdProbeLoopWithSnapshotter(ctx context.Contex
 				var snapshot *brtypes.Snapshot
 				metrics.SnapshotRequired.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindDelta}).Set(0)
 				metrics.SnapshotRequired.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindFull}).Set(1)
-				if snapshot, err = ssr.TakeFullSnapshotAndResetTimer(false); err != nil {
+				if snapshot, err = ssr.TakeFullSnapshotAndResetTimer(ctx, false); err != nil {
 					metrics.SnapshotterOperationFailure.With(prometheus.Labels{metrics.LabelError: err.Error()}).Inc()
 					b.logger.Errorf("Failed to take substitute first full snapshot: %v", err)
 					continue
@@ -423,7 +507,7 @@ func (b *BackupRestoreServer) runEtcdProbeLoopWithSnapshotter(ctx context.Contex
 				if b.config.HealthConfig.SnapshotLeaseRenewalEnabled {
 					leaseUpdatectx, cancel := context.WithTimeout(ctx, brtypes.LeaseUpdateTimeoutDuration)
 					defer cancel()
-					if err = heartbeat.FullSnapshotCaseLeaseUpdate(leaseUpdatectx, b.logger, snapshot, ssr.K8sClientset, b.config.HealthConfig.FullSnapshotLeaseName); err != nil {
+					if err = heartbeat.FullSnapshotCaseLeaseUpdate(leaseUpdatectx, b.logger, snapshot, ssr.K8sClientset, b.config.HealthConfig.SnapshotLeaseNamespace, b.config.HealthConfig.FullSnapshotLeaseName); err != nil {
 						b.logger.Warnf("Snapshot lease update failed : %v", err)
 					}
 				}
@@ -440,13 +524,21 @@ func (b *BackupRestoreServer) runEtcdProbeLoopWithSnapshotter(ctx context.Contex
 			b.logger.Info("Starting the garbage collector...")
 			go ssr.RunGarbageCollector(gcStopCh)
 
+			// Start restore drills
+			restoreDrillStopCh := make(chan struct{})
+			go ssr.RunRestoreDrills(restoreDrillStopCh)
+
 			// Start snapshotter
 			b.logger.Infof("Starting snapshotter...")
 			startWithFullSnapshot := ssr.IsFullSnapshotRequiredAtStartup(fullSnapshotMaxTimeWindowInHours)
 			if err := ssr.Run(ssrStopCh, startWithFullSnapshot); err != nil {
+				var credentialErr *errors.SnapstoreCredentialError
 				if etcdErr, ok := err.(*errors.EtcdError); ok {
 					metrics.SnapshotterOperationFailure.With(prometheus.Labels{metrics.LabelError: etcdErr.Error()}).Inc()
 					b.logger.Errorf("Snapshotter failed with etcd error: %v", etcdErr)
+				} else if stderrors.As(err, &credentialErr) {
+					metrics.SnapshotterOperationFailure.With(prometheus.Labels{metrics.LabelError: credentialErr.Error()}).Inc()
+					b.logger.Errorf("Snapshotter failed with snapstore credential error: %v", credentialErr)
 				} else {
 					metrics.SnapshotterOperationFailure.With(prometheus.Labels{metrics.LabelError: err.Error()}).Inc()
 					b.logger.Errorf("Snapshotter failed with error: %v", err)
@@ -460,6 +552,9 @@ func (b *BackupRestoreServer) runEtcdProbeLoopWithSnapshotter(ctx context.Contex
 
 			// Stop garbage collector
 			close(gcStopCh)
+
+			// Stop restore drills
+			close(restoreDrillStopCh)
 		} else {
 			// for the case when snapshotter is not configured
 
@@ -0,0 +1,49 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gardener/etcd-backup-restore/pkg/events"
+	"github.com/gardener/etcd-backup-restore/pkg/member"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// fakeMemberControl is a member control whose promotion of the member returns the given error.
+type fakeMemberControl struct {
+	member.Control
+	promoteErr error
+}
+
+func (m *fakeMemberControl) PromoteMember(context.Context) error {
+	return m.promoteErr
+}
+
+func TestPromoteLearnerEmitsEvent(t *testing.T) {
+	recorder := events.NewFakeRecorder()
+
+	promoteLearner(context.Background(), &fakeMemberControl{}, recorder, logrus.NewEntry(logrus.New()))
+
+	expected := fmt.Sprintf("%s %s Promoted the learner to a voting member", corev1.EventTypeNormal, events.ReasonLearnerPromoted)
+	if recorded := recorder.Events(); len(recorded) != 1 || recorded[0] != expected {
+		t.Fatalf("recorded events are %v instead of [%s]", recorded, expected)
+	}
+}
+
+func TestFailedLearnerPromotionEmitsWarningEvent(t *testing.T) {
+	recorder := events.NewFakeRecorder()
+
+	promoteLearner(context.Background(), &fakeMemberControl{promoteErr: errors.New("learner not in sync")}, recorder, logrus.NewEntry(logrus.New()))
+
+	expected := fmt.Sprintf("%s %s Promoting the learner to a voting member failed: learner not in sync", corev1.EventTypeWarning, events.ReasonLearnerPromotionFailed)
+	if recorded := recorder.Events(); len(recorded) != 1 || recorded[0] != expected {
+		t.Fatalf("recorded events are %v instead of [%s]", recorded, expected)
+	}
+}
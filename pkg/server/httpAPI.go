@@ -11,6 +11,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/http/pprof"
@@ -72,6 +73,7 @@ type HTTPHandler struct {
 	EtcdConnectionConfig      *brtypes.EtcdConnectionConfig
 	StorageProvider           string
 	Port                      uint
+	BindAddress               string
 	server                    *http.Server
 	Logger                    *logrus.Entry
 	initializationStatusMutex sync.Mutex
@@ -85,6 +87,7 @@ type HTTPHandler struct {
 	EnableTLS                 bool
 	ServerTLSCertFile         string
 	ServerTLSKeyFile          string
+	ServerTLSClientCAFile     string
 	HTTPHandlerMutex          *sync.Mutex
 	SnapstoreConfig           *brtypes.SnapstoreConfig
 }
@@ -139,11 +142,27 @@ func (h *HTTPHandler) RegisterHandler() {
 	mux.Handle("/metrics", promhttp.Handler())
 
 	h.server = &http.Server{
-		Addr:    fmt.Sprintf(":%d", h.Port),
-		Handler: mux,
+		Addr:    net.JoinHostPort(h.BindAddress, strconv.FormatUint(uint64(h.Port), 10)),
+		Handler: h.requireClientCertificate(mux),
 	}
 }
 
+// requireClientCertificate rejects the requests without a verified client certificate if the server verifies the
+// certificates of its clients, except for the health checks, which have to remain probe-able without one.
+func (h *HTTPHandler) requireClientCertificate(handler http.Handler) http.Handler {
+	if !h.EnableTLS || h.ServerTLSClientCAFile == "" {
+		return handler
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/healthz" && (req.TLS == nil || len(req.TLS.VerifiedChains) == 0) {
+			h.checkAndSetSecurityHeaders(rw)
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(rw, req)
+	})
+}
+
 // registerPProfHandler registers the PProf handler for profiling.
 func registerPProfHandler(mux *http.ServeMux) {
 	mux.HandleFunc("/debug/pprof/", pprof.Index)
@@ -181,13 +200,63 @@ func (h *HTTPHandler) Start() {
 
 	h.Logger.Infof("TLS enabled. Starting HTTPS server.")
 
-	err := h.server.ListenAndServeTLS(h.ServerTLSCertFile, h.ServerTLSKeyFile)
+	tlsConfig, err := h.serverTLSConfig()
+	if err != nil {
+		h.Logger.Fatalf("Failed to start HTTPS server: %v", err)
+	}
+	h.server.TLSConfig = tlsConfig
+	err = h.server.ListenAndServeTLS(h.ServerTLSCertFile, h.ServerTLSKeyFile)
 	if err != nil && err != http.ErrServerClosed {
 		h.Logger.Fatalf("Failed to start HTTPS server: %v", err)
 	}
 	h.Logger.Infof("HTTPS server closed gracefully.")
 }
 
+// serverTLSConfig returns the TLS config of the HTTPS server, which verifies the certificates of the clients against
+// the client CA if one is configured. The certificates are only required by the handlers, see requireClientCertificate.
+func (h *HTTPHandler) serverTLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+	if h.ServerTLSClientCAFile == "" {
+		return tlsConfig, nil
+	}
+	clientCACert, err := os.ReadFile(h.ServerTLSClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read client CA file: %v", err)
+	}
+	clientCAPool := x509.NewCertPool()
+	if !clientCAPool.AppendCertsFromPEM(clientCACert) {
+		return nil, fmt.Errorf("no certificates found in client CA file %s", h.ServerTLSClientCAFile)
+	}
+	tlsConfig.ClientCAs = clientCAPool
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	return tlsConfig, nil
+}
+
+// leaderTLSConfig returns the TLS config of the requests to the backup-restore leader, or nil if TLS is not enabled.
+// The server certificate is presented to the leader if the server verifies the certificates of its clients.
+func (h *HTTPHandler) leaderTLSConfig() (*tls.Config, error) {
+	if !h.EnableTLS {
+		return nil, nil
+	}
+	caCert, err := os.ReadFile(h.EtcdConnectionConfig.CaFile)
+	if err != nil {
+		return nil, err
+	}
+	caCertPool := x509.NewCertPool()
+	caCertPool.AppendCertsFromPEM(caCert)
+	tlsConfig := &tls.Config{
+		RootCAs: caCertPool,
+	}
+	if h.ServerTLSClientCAFile != "" {
+		cert, err := tls.LoadX509KeyPair(h.ServerTLSCertFile, h.ServerTLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
 // Stop stops the http server
 func (h *HTTPHandler) Stop() error {
 	return h.server.Close()
@@ -303,7 +372,25 @@ func (h *HTTPHandler) serveFullSnapshotTrigger(rw http.ResponseWriter, req *http
 			return
 		}
 	}
-	s, err := h.Snapshotter.TriggerFullSnapshot(req.Context(), isFinal)
+	var revision int64
+	if revisionValue := req.URL.Query().Get("revision"); revisionValue != "" {
+		var err error
+		revision, err = strconv.ParseInt(revisionValue, 10, 64)
+		if err != nil || revision < 1 || isFinal {
+			h.Logger.Warnf("Could not parse request parameter 'revision' to a positive revision of a non-final full snapshot: %s", revisionValue)
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	var (
+		s   *brtypes.Snapshot
+		err error
+	)
+	if revision != 0 {
+		s, err = h.Snapshotter.TriggerFullSnapshotAtRevision(req.Context(), revision)
+	} else {
+		s, err = h.Snapshotter.TriggerFullSnapshot(req.Context(), isFinal)
+	}
 	if err != nil {
 		h.Logger.Warnf("Skipped triggering out-of-schedule full snapshot: %v", err)
 		rw.WriteHeader(http.StatusInternalServerError)
@@ -333,7 +420,7 @@ func (h *HTTPHandler) serveDeltaSnapshotTrigger(rw http.ResponseWriter, req *htt
 		rw.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	s, err := h.Snapshotter.TriggerDeltaSnapshot()
+	s, err := h.Snapshotter.TriggerDeltaSnapshot(req.Context())
 	if err != nil {
 		h.Logger.Warnf("Skipped triggering out-of-schedule delta snapshot: %v", err)
 		rw.WriteHeader(http.StatusInternalServerError)
@@ -378,8 +465,9 @@ func (h *HTTPHandler) serveLatestSnapshotMetadata(rw http.ResponseWriter, req *h
 	}
 
 	resp := latestSnapshotMetadataResponse{
-		FullSnapshot:   fullSnap,
-		DeltaSnapshots: deltaSnaps,
+		FullSnapshot:     fullSnap,
+		DeltaSnapshots:   deltaSnaps,
+		RevisionCoverage: miscellaneous.GetRevisionCoverage(fullSnap, deltaSnaps),
 	}
 
 	json, err := json.Marshal(resp)
@@ -488,6 +576,13 @@ func (h *HTTPHandler) GetClusterState(ctx context.Context, clusterSize int, clie
 		return miscellaneous.ClusterStateNew, nil
 	}
 
+	// the initializer knows the cluster state if it has restored the member, or added it to the cluster as a learner
+	if etcdInitializer, ok := h.Initializer.(*initializer.EtcdInitializer); ok {
+		if state := etcdInitializer.InitialClusterState(); state != "" {
+			return state, nil
+		}
+	}
+
 	// clusterSize > 1
 	state, err := miscellaneous.GetInitialClusterStateIfScaleup(ctx, *h.Logger, client, podName, podNS)
 	if err != nil {
@@ -637,7 +732,14 @@ func (h *HTTPHandler) delegateReqToLeader(rw http.ResponseWriter, req *http.Requ
 		return
 	}
 
-	isHealthy, err := IsBackupRestoreHealthy(backupLeaderEndPoint+"/healthz", h.EnableTLS, h.EtcdConnectionConfig.CaFile)
+	tlsConfig, err := h.leaderTLSConfig()
+	if err != nil {
+		h.Logger.Warnf("Unable to build the TLS config for the backup leader: %v", err)
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	isHealthy, err := IsBackupRestoreHealthy(backupLeaderEndPoint+"/healthz", tlsConfig)
 	if err != nil {
 		h.Logger.Warnf("Unable to check backup leader health: %v", err)
 		rw.WriteHeader(http.StatusMethodNotAllowed)
@@ -656,18 +758,9 @@ func (h *HTTPHandler) delegateReqToLeader(rw http.ResponseWriter, req *http.Requ
 	// create the reverse Proxy
 	revProxyHandler := httputil.NewSingleHostReverseProxy(backupLeaderURL)
 
-	if h.EnableTLS {
-		caCertPool := x509.NewCertPool()
-
-		caCert, err := os.ReadFile(h.EtcdConnectionConfig.CaFile)
-		if err != nil {
-			return
-		}
-		caCertPool.AppendCertsFromPEM(caCert)
+	if tlsConfig != nil {
 		revProxyHandler.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				RootCAs: caCertPool,
-			},
+			TLSClientConfig: tlsConfig,
 		}
 	}
 
@@ -675,23 +768,14 @@ func (h *HTTPHandler) delegateReqToLeader(rw http.ResponseWriter, req *http.Requ
 }
 
 // IsBackupRestoreHealthy checks the whether the backup-restore of given backup-restore URL healthy or not.
-func IsBackupRestoreHealthy(backupRestoreURL string, TLSEnabled bool, rootCA string) (bool, error) {
+// The request is sent with the given TLS config, if any.
+func IsBackupRestoreHealthy(backupRestoreURL string, tlsConfig *tls.Config) (bool, error) {
 	var health healthCheck
 	client := &http.Client{}
 
-	if TLSEnabled {
-		caCertPool := x509.NewCertPool()
-
-		caCert, err := os.ReadFile(rootCA)
-		if err != nil {
-			return false, err
-		}
-		caCertPool.AppendCertsFromPEM(caCert)
-
+	if tlsConfig != nil {
 		client.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				RootCAs: caCertPool,
-			},
+			TLSClientConfig: tlsConfig,
 		}
 	}
 
@@ -5,10 +5,26 @@
 package server
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
+	"io"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 func TestHealthCheckHandler(t *testing.T) {
@@ -54,3 +70,193 @@ func healthCheckTest(handlerFunc http.HandlerFunc, expectedStatus int, expectedH
 	}
 	return nil
 }
+
+// testCertificate is a certificate along with its key, written to files in PEM format.
+type testCertificate struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+// newTestCertificate creates a certificate for localhost, signed by the given CA, or a self-signed CA if ca is nil.
+func newTestCertificate(t *testing.T, dir, name string, ca *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	parent, signer := template, key
+	if ca == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		parent, signer = ca.cert, ca.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &testCertificate{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(dir, name+".crt"),
+		keyFile:  filepath.Join(dir, name+".key"),
+	}
+	if err := os.WriteFile(c.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(c.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// startTestHTTPHandler starts the given handler on a free port of the loopback interface, and returns its address.
+func startTestHTTPHandler(t *testing.T, h *HTTPHandler) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Port = uint(l.Addr().(*net.TCPAddr).Port)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	h.BindAddress = "127.0.0.1"
+	h.Logger = logrus.NewEntry(logrus.New())
+	h.HTTPHandlerMutex = &sync.Mutex{}
+	h.SetStatus(http.StatusOK)
+	h.RegisterHandler()
+	go h.Start()
+	t.Cleanup(func() {
+		_ = h.Stop()
+	})
+
+	addr := fmt.Sprintf("127.0.0.1:%d", h.Port)
+	if h.server.Addr != addr {
+		t.Fatalf("server listens at %s, want %s", h.server.Addr, addr)
+	}
+	for i := 0; i < 50; i++ {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			return addr
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("server did not come up at %s", addr)
+	return ""
+}
+
+func newTestHTTPClient(ca *testCertificate, clientCert *testCertificate) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	tlsConfig := &tls.Config{RootCAs: pool}
+	if clientCert != nil {
+		tlsConfig.Certificates = []tls.Certificate{{
+			Certificate: [][]byte{clientCert.cert.Raw},
+			PrivateKey:  clientCert.key,
+		}}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+}
+
+func TestHTTPServerWithTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCertificate(t, dir, "ca", nil)
+	serverCert := newTestCertificate(t, dir, "server", ca)
+	h := &HTTPHandler{
+		EnableTLS:         true,
+		ServerTLSCertFile: serverCert.certFile,
+		ServerTLSKeyFile:  serverCert.keyFile,
+	}
+	addr := startTestHTTPHandler(t, h)
+
+	client := newTestHTTPClient(ca, nil)
+	for _, endpoint := range []string{"/healthz", "/metrics"} {
+		resp, err := client.Get("https://" + addr + endpoint)
+		if err != nil {
+			t.Fatalf("failed to get %s over TLS: %v", endpoint, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("got status %d for %s over TLS, want %d", resp.StatusCode, endpoint, http.StatusOK)
+		}
+	}
+
+	resp, err := http.Get("http://" + addr + "/healthz")
+	if err == nil {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK || string(body) == `{"health":true}` {
+			t.Fatalf("plaintext request was served by the TLS server")
+		}
+	}
+}
+
+func TestHTTPServerWithMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCertificate(t, dir, "ca", nil)
+	serverCert := newTestCertificate(t, dir, "server", ca)
+	clientCert := newTestCertificate(t, dir, "client", ca)
+	h := &HTTPHandler{
+		EnableTLS:             true,
+		ServerTLSCertFile:     serverCert.certFile,
+		ServerTLSKeyFile:      serverCert.keyFile,
+		ServerTLSClientCAFile: ca.certFile,
+	}
+	addr := startTestHTTPHandler(t, h)
+
+	resp, err := newTestHTTPClient(ca, nil).Get("https://" + addr + "/healthz")
+	if err != nil {
+		t.Fatalf("failed to probe the health without a client certificate: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d for the health probe without a client certificate, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	for _, endpoint := range []string{"/metrics", "/config", "/snapshot/latest"} {
+		if resp, err := newTestHTTPClient(ca, nil).Get("https://" + addr + endpoint); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Fatalf("request to %s without a client certificate was served with status %d", endpoint, resp.StatusCode)
+			}
+		}
+	}
+
+	untrustedCA := newTestCertificate(t, dir, "untrusted-ca", nil)
+	untrustedClientCert := newTestCertificate(t, dir, "untrusted-client", untrustedCA)
+	if resp, err := newTestHTTPClient(ca, untrustedClientCert).Get("https://" + addr + "/metrics"); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("request with an untrusted client certificate was served with status %d", resp.StatusCode)
+		}
+	}
+
+	resp, err = newTestHTTPClient(ca, clientCert).Get("https://" + addr + "/metrics")
+	if err != nil {
+		t.Fatalf("failed to get metrics with a client certificate: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d for metrics with a client certificate, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
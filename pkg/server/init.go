@@ -6,9 +6,11 @@ package server
 
 import (
 	"fmt"
+	"net"
 	"os"
 
 	"github.com/gardener/etcd-backup-restore/pkg/compressor"
+	"github.com/gardener/etcd-backup-restore/pkg/defragmentor"
 	"github.com/gardener/etcd-backup-restore/pkg/snapshot/snapshotter"
 	"github.com/gardener/etcd-backup-restore/pkg/snapstore"
 	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
@@ -19,11 +21,14 @@ import (
 
 // NewBackupRestoreComponentConfig returns the backup-restore componenet config.
 func NewBackupRestoreComponentConfig() *BackupRestoreComponentConfig {
+	restoreSnapstoreConfig := snapstore.NewSnapstoreConfig()
+	restoreSnapstoreConfig.IsSource = true
 	return &BackupRestoreComponentConfig{
 		EtcdConnectionConfig:     brtypes.NewEtcdConnectionConfig(),
 		ServerConfig:             NewHTTPServerConfig(),
 		SnapshotterConfig:        snapshotter.NewSnapshotterConfig(),
 		SnapstoreConfig:          snapstore.NewSnapstoreConfig(),
+		RestoreSnapstoreConfig:   restoreSnapstoreConfig,
 		CompressionConfig:        compressor.NewCompressorConfig(),
 		RestorationConfig:        brtypes.NewRestorationConfig(),
 		DefragmentationSchedule:  defaultDefragmentationSchedule,
@@ -39,6 +44,7 @@ func (c *BackupRestoreComponentConfig) AddFlags(fs *flag.FlagSet) {
 	c.ServerConfig.AddFlags(fs)
 	c.SnapshotterConfig.AddFlags(fs)
 	c.SnapstoreConfig.AddFlags(fs)
+	c.RestoreSnapstoreConfig.AddRestoreFlags(fs)
 	c.RestorationConfig.AddFlags(fs)
 	c.CompressionConfig.AddFlags(fs)
 	c.HealthConfig.AddFlags(fs)
@@ -47,6 +53,7 @@ func (c *BackupRestoreComponentConfig) AddFlags(fs *flag.FlagSet) {
 
 	// Miscellaneous
 	fs.StringVar(&c.DefragmentationSchedule, "defragmentation-schedule", c.DefragmentationSchedule, "schedule to defragment etcd data directory")
+	fs.StringVar(&c.MemberDefragmentationSchedule, "member-defragmentation-schedule", c.MemberDefragmentationSchedule, "schedule to defragment the data directory of the local etcd member only, skipped while the member is a learner or unhealthy (empty disables it)")
 }
 
 // Validate validates the config.
@@ -63,18 +70,33 @@ func (c *BackupRestoreComponentConfig) Validate() error {
 	if err := c.SnapstoreConfig.Validate(); err != nil {
 		return err
 	}
+	if err := snapstore.ValidateRecordClusterMetadata(c.SnapstoreConfig.Provider, c.SnapshotterConfig); err != nil {
+		return err
+	}
+	if err := snapstore.ValidateStorageBudget(c.SnapstoreConfig.Provider, c.SnapshotterConfig); err != nil {
+		return err
+	}
+	if err := c.RestoreSnapstoreConfig.Validate(); err != nil {
+		return err
+	}
 	if err := c.RestorationConfig.Validate(); err != nil {
 		return err
 	}
 	if err := c.CompressionConfig.Validate(); err != nil {
 		return err
 	}
+	if err := snapstore.ValidateCompressionPolicy(c.SnapstoreConfig.Provider, c.CompressionConfig); err != nil {
+		return err
+	}
 	if err := c.HealthConfig.Validate(); err != nil {
 		return err
 	}
 	if _, err := cron.ParseStandard(c.DefragmentationSchedule); err != nil {
 		return err
 	}
+	if _, err := defragmentor.ParseMemberDefragmentationSchedule(c.MemberDefragmentationSchedule); err != nil {
+		return err
+	}
 	if err := c.LeaderElectionConfig.Validate(); err != nil {
 		return err
 	}
@@ -87,14 +109,29 @@ func (c *BackupRestoreComponentConfig) Validate() error {
 // Complete completes the config.
 func (c *BackupRestoreComponentConfig) Complete() {
 	c.SnapstoreConfig.Complete()
+	if len(c.RestoreSnapstoreConfig.Provider) > 0 {
+		c.RestoreSnapstoreConfig.MergeWith(c.SnapstoreConfig)
+	}
+}
+
+// GetRestoreSnapstoreConfig returns the config of the snapstore to restore from. It is the restore-specific snapstore
+// config if a storage provider is configured for it, so that the restoration can use a different provider than the
+// one the snapshots are taken to, and the config of the snapstore the snapshots are taken to otherwise.
+func (c *BackupRestoreComponentConfig) GetRestoreSnapstoreConfig() *brtypes.SnapstoreConfig {
+	if c.RestoreSnapstoreConfig != nil && len(c.RestoreSnapstoreConfig.Provider) > 0 {
+		return c.RestoreSnapstoreConfig
+	}
+	return c.SnapstoreConfig
 }
 
 // HTTPServerConfig holds the server config.
 type HTTPServerConfig struct {
 	Port            uint   `json:"port,omitempty"`
+	BindAddress     string `json:"bindAddress,omitempty"`
 	EnableProfiling bool   `json:"enableProfiling,omitempty"`
 	TLSCertFile     string `json:"server-cert,omitempty"`
 	TLSKeyFile      string `json:"server-key,omitempty"`
+	TLSClientCAFile string `json:"server-client-ca,omitempty"`
 }
 
 // NewHTTPServerConfig returns the config for http server
@@ -108,9 +145,11 @@ func NewHTTPServerConfig() *HTTPServerConfig {
 // AddFlags adds the flags to flagset.
 func (c *HTTPServerConfig) AddFlags(fs *flag.FlagSet) {
 	fs.UintVarP(&c.Port, "server-port", "p", c.Port, "port on which server should listen")
+	fs.StringVar(&c.BindAddress, "server-bind-address", c.BindAddress, "address of the interface on which server should listen (defaults to all interfaces)")
 	fs.BoolVar(&c.EnableProfiling, "enable-profiling", c.EnableProfiling, "enable profiling")
 	fs.StringVar(&c.TLSCertFile, "server-cert", "", "TLS certificate file for backup-restore server")
 	fs.StringVar(&c.TLSKeyFile, "server-key", "", "TLS key file for backup-restore server")
+	fs.StringVar(&c.TLSClientCAFile, "server-client-ca", "", "CA file to verify the client certificates of backup-restore server with, which requires the clients to authenticate with mutual TLS")
 }
 
 // Validate validates the config.E
@@ -125,5 +164,16 @@ func (c *HTTPServerConfig) Validate() error {
 			return fmt.Errorf("TLS enabled but server TLS key file is invalid. Will not start HTTPS server: %v", err)
 		}
 	}
+	if c.TLSClientCAFile != "" {
+		if !enableTLS {
+			return fmt.Errorf("server client CA file requires the server TLS cert and key files")
+		}
+		if _, err := os.Stat(c.TLSClientCAFile); err != nil {
+			return fmt.Errorf("TLS enabled but server client CA file is invalid. Will not start HTTPS server: %v", err)
+		}
+	}
+	if c.BindAddress != "" && net.ParseIP(c.BindAddress) == nil {
+		return fmt.Errorf("server bind address %s is not an IP address", c.BindAddress)
+	}
 	return nil
 }
@@ -0,0 +1,207 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/gardener/etcd-backup-restore/pkg/initializer"
+	"github.com/gardener/etcd-backup-restore/pkg/snapstore"
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+
+	flag "github.com/spf13/pflag"
+)
+
+func parseComponentConfig(t *testing.T, args ...string) *BackupRestoreComponentConfig {
+	config := NewBackupRestoreComponentConfig()
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	config.AddFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("failed to validate config: %v", err)
+	}
+	config.Complete()
+	return config
+}
+
+func TestRestoreSnapstoreConfigDefaultsToSnapshotSnapstore(t *testing.T) {
+	config := parseComponentConfig(t, "--storage-provider=GCS", "--store-container=snapshots", "--store-prefix=etcd")
+
+	if restoreConfig := config.GetRestoreSnapstoreConfig(); restoreConfig != config.SnapstoreConfig {
+		t.Fatalf("restore snapstore config is %+v instead of the snapshot snapstore config", restoreConfig)
+	}
+}
+
+func TestRestoreSnapstoreConfigWithDifferentProvider(t *testing.T) {
+	container := t.TempDir()
+	config := parseComponentConfig(t, "--storage-provider=GCS", "--store-container=snapshots", "--store-prefix=etcd",
+		"--restore-storage-provider="+brtypes.SnapstoreProviderLocal, "--restore-store-container="+container)
+
+	restoreConfig := config.GetRestoreSnapstoreConfig()
+	if restoreConfig == config.SnapstoreConfig {
+		t.Fatal("restore snapstore config is the snapshot snapstore config")
+	}
+	if restoreConfig.Provider != brtypes.SnapstoreProviderLocal || restoreConfig.Container != container {
+		t.Fatalf("restore snapstore config has provider %q and container %q", restoreConfig.Provider, restoreConfig.Container)
+	}
+	if !restoreConfig.IsSource {
+		t.Fatal("restore snapstore config doesn't read the credentials of the source snapstore")
+	}
+	if restoreConfig.Prefix != config.SnapstoreConfig.Prefix {
+		t.Fatalf("restore snapstore config has prefix %q instead of %q", restoreConfig.Prefix, config.SnapstoreConfig.Prefix)
+	}
+	if config.SnapstoreConfig.Provider != brtypes.SnapstoreProviderGCS || config.SnapstoreConfig.IsSource {
+		t.Fatalf("snapshot snapstore config changed to %+v", config.SnapstoreConfig)
+	}
+
+	restoreOpts := &brtypes.RestoreOptions{Config: config.RestorationConfig}
+	etcdInitializer, err := initializer.NewInitializer(restoreOpts, restoreConfig, config.EtcdConnectionConfig, nil)
+	if err != nil {
+		t.Fatalf("failed to create initializer: %v", err)
+	}
+	if etcdInitializer.Config.SnapstoreConfig != restoreConfig || etcdInitializer.Validator.Config.SnapstoreConfig != restoreConfig {
+		t.Fatal("initializer doesn't restore from the restore snapstore")
+	}
+
+	store, err := snapstore.GetSnapstore(restoreConfig)
+	if err != nil {
+		t.Fatalf("failed to create restore snapstore: %v", err)
+	}
+	snapList, err := store.List()
+	if err != nil {
+		t.Fatalf("failed to list restore snapstore: %v", err)
+	}
+	if len(snapList) != 0 {
+		t.Fatalf("restore snapstore has %d snapshots instead of none", len(snapList))
+	}
+}
+
+func TestRestoreSnapstoreConfigWithOwnPrefix(t *testing.T) {
+	config := parseComponentConfig(t, "--storage-provider=GCS", "--store-container=snapshots", "--store-prefix=etcd",
+		"--restore-storage-provider="+brtypes.SnapstoreProviderS3, "--restore-store-container=restore", "--restore-store-prefix=dr/etcd")
+
+	restoreConfig := config.GetRestoreSnapstoreConfig()
+	if expected := path.Join("dr/etcd", path.Base(config.SnapstoreConfig.Prefix)); restoreConfig.Prefix != expected {
+		t.Fatalf("restore snapstore config has prefix %q instead of %q", restoreConfig.Prefix, expected)
+	}
+}
+
+func TestHTTPServerConfigValidation(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, caFile := path.Join(dir, "tls.crt"), path.Join(dir, "tls.key"), path.Join(dir, "ca.crt")
+	for _, f := range []string{certFile, keyFile, caFile} {
+		if err := os.WriteFile(f, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		name    string
+		config  HTTPServerConfig
+		wantErr bool
+	}{
+		{"bind address", HTTPServerConfig{BindAddress: "127.0.0.1"}, false},
+		{"bind address which is not an IP address", HTTPServerConfig{BindAddress: "localhost:8080"}, true},
+		{"client CA", HTTPServerConfig{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSClientCAFile: caFile}, false},
+		{"client CA without TLS", HTTPServerConfig{TLSClientCAFile: caFile}, true},
+		{"missing client CA", HTTPServerConfig{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSClientCAFile: path.Join(dir, "missing.crt")}, true},
+	} {
+		if err := tc.config.Validate(); (err != nil) != tc.wantErr {
+			t.Errorf("%s: got error %v, want error %t", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestValidateRejectsCompressionDictionariesOnUnsupportedProvider(t *testing.T) {
+	config := NewBackupRestoreComponentConfig()
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	config.AddFlags(fs)
+	if err := fs.Parse([]string{"--storage-provider=GCS", "--store-container=snapshots", "--compress-snapshots", "--compression-policy=zlib-dict"}); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+	if err := config.Validate(); err == nil {
+		t.Fatal("config with the zlib-dict compression policy on GCS is valid")
+	}
+
+	parseComponentConfig(t, "--storage-provider=S3", "--store-container=snapshots", "--compress-snapshots", "--compression-policy=zlib-dict")
+}
+
+func TestValidateRejectsClusterMetadataOnUnsupportedProvider(t *testing.T) {
+	config := NewBackupRestoreComponentConfig()
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	config.AddFlags(fs)
+	if err := fs.Parse([]string{"--storage-provider=GCS", "--store-container=snapshots", "--record-cluster-metadata"}); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+	if err := config.Validate(); err == nil {
+		t.Fatal("config recording the cluster metadata on GCS is valid")
+	}
+
+	parseComponentConfig(t, "--storage-provider=S3", "--store-container=snapshots", "--record-cluster-metadata")
+}
+
+func TestRestoreLockTTLIsConfiguredForTheServerRestoration(t *testing.T) {
+	config := parseComponentConfig(t, "--storage-provider=S3", "--store-container=snapshots", "--restore-lock-ttl=1h")
+	if config.RestorationConfig.RestoreLockTTL.Duration != time.Hour {
+		t.Errorf("got restore lock TTL %s, want %s", config.RestorationConfig.RestoreLockTTL.Duration, time.Hour)
+	}
+}
+
+func TestMaxRestoreDurationIsConfiguredForTheServerRestoration(t *testing.T) {
+	config := NewBackupRestoreComponentConfig()
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	config.AddFlags(fs)
+	if err := fs.Parse([]string{"--storage-provider=S3", "--store-container=snapshots", "--restore-lock-ttl=10m", "--max-restore-duration=30m"}); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+	if err := config.Validate(); err == nil {
+		t.Fatal("config with a restore lock TTL less than the max restore duration is valid")
+	}
+
+	config = parseComponentConfig(t, "--storage-provider=S3", "--store-container=snapshots", "--max-restore-duration=30m")
+	if config.RestorationConfig.MaxRestoreDuration.Duration != 30*time.Minute {
+		t.Errorf("got max restore duration %s, want %s", config.RestorationConfig.MaxRestoreDuration.Duration, 30*time.Minute)
+	}
+}
+
+func TestRestoreToTimeIsConfiguredForTheServerRestoration(t *testing.T) {
+	config := NewBackupRestoreComponentConfig()
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	config.AddFlags(fs)
+	if err := fs.Parse([]string{"--storage-provider=S3", "--store-container=snapshots", "--restore-to-time=yesterday"}); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+	if err := config.Validate(); err == nil {
+		t.Fatal("config with a restore to time which is not an RFC3339 time is valid")
+	}
+
+	config = parseComponentConfig(t, "--storage-provider=S3", "--store-container=snapshots", "--restore-to-time=2024-05-06T14:32:00Z")
+	restoreToTime, err := config.RestorationConfig.GetRestoreToTime()
+	if err != nil {
+		t.Fatalf("failed to get the restore to time: %v", err)
+	}
+	if want := time.Date(2024, 5, 6, 14, 32, 0, 0, time.UTC); !restoreToTime.Equal(want) {
+		t.Errorf("got restore to time %s, want %s", restoreToTime, want)
+	}
+}
+
+func TestValidateRejectsStorageBudgetOnUnsupportedProvider(t *testing.T) {
+	config := NewBackupRestoreComponentConfig()
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	config.AddFlags(fs)
+	if err := fs.Parse([]string{"--storage-provider=GCS", "--store-container=snapshots", "--garbage-collection-policy=StorageBudget", "--max-total-storage-bytes=1024"}); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+	if err := config.Validate(); err == nil {
+		t.Fatal("config with the storage budget garbage collection policy on GCS is valid")
+	}
+
+	parseComponentConfig(t, "--storage-provider=S3", "--store-container=snapshots", "--garbage-collection-policy=StorageBudget", "--max-total-storage-bytes=1024")
+}
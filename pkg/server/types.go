@@ -16,20 +16,23 @@ const (
 
 // BackupRestoreComponentConfig holds the component configuration.
 type BackupRestoreComponentConfig struct {
-	EtcdConnectionConfig     *brtypes.EtcdConnectionConfig     `json:"etcdConnectionConfig,omitempty"`
-	ServerConfig             *HTTPServerConfig                 `json:"serverConfig,omitempty"`
-	SnapshotterConfig        *brtypes.SnapshotterConfig        `json:"snapshotterConfig,omitempty"`
-	SnapstoreConfig          *brtypes.SnapstoreConfig          `json:"snapstoreConfig,omitempty"`
-	CompressionConfig        *compressor.CompressionConfig     `json:"compressionConfig,omitempty"`
-	RestorationConfig        *brtypes.RestorationConfig        `json:"restorationConfig,omitempty"`
-	DefragmentationSchedule  string                            `json:"defragmentationSchedule"`
-	HealthConfig             *brtypes.HealthConfig             `json:"healthConfig,omitempty"`
-	LeaderElectionConfig     *brtypes.Config                   `json:"leaderElectionConfig,omitempty"`
-	ExponentialBackoffConfig *brtypes.ExponentialBackoffConfig `json:"exponentialBackoffConfig,omitempty"`
+	EtcdConnectionConfig          *brtypes.EtcdConnectionConfig     `json:"etcdConnectionConfig,omitempty"`
+	ServerConfig                  *HTTPServerConfig                 `json:"serverConfig,omitempty"`
+	SnapshotterConfig             *brtypes.SnapshotterConfig        `json:"snapshotterConfig,omitempty"`
+	SnapstoreConfig               *brtypes.SnapstoreConfig          `json:"snapstoreConfig,omitempty"`
+	RestoreSnapstoreConfig        *brtypes.SnapstoreConfig          `json:"restoreSnapstoreConfig,omitempty"`
+	CompressionConfig             *compressor.CompressionConfig     `json:"compressionConfig,omitempty"`
+	RestorationConfig             *brtypes.RestorationConfig        `json:"restorationConfig,omitempty"`
+	DefragmentationSchedule       string                            `json:"defragmentationSchedule"`
+	MemberDefragmentationSchedule string                            `json:"memberDefragmentationSchedule,omitempty"`
+	HealthConfig                  *brtypes.HealthConfig             `json:"healthConfig,omitempty"`
+	LeaderElectionConfig          *brtypes.Config                   `json:"leaderElectionConfig,omitempty"`
+	ExponentialBackoffConfig      *brtypes.ExponentialBackoffConfig `json:"exponentialBackoffConfig,omitempty"`
 }
 
 // latestSnapshotMetadata holds snapshot details of latest full and delta snapshots
 type latestSnapshotMetadataResponse struct {
-	FullSnapshot   *brtypes.Snapshot `json:"fullSnapshot"`
-	DeltaSnapshots brtypes.SnapList  `json:"deltaSnapshots"`
+	FullSnapshot     *brtypes.Snapshot         `json:"fullSnapshot"`
+	DeltaSnapshots   brtypes.SnapList          `json:"deltaSnapshots"`
+	RevisionCoverage *brtypes.RevisionCoverage `json:"revisionCoverage,omitempty"`
 }
@@ -146,6 +146,11 @@ func (c *Copier) copyBackups() error {
 		return nil
 	}
 
+	// the delta snapshots compressed with a dictionary can't be decompressed without it
+	if err := snapstore.CopyCompressionDictionaries(c.sourceSnapStore, c.destSnapStore); err != nil {
+		return fmt.Errorf("could not copy compression dictionaries: %v", err)
+	}
+
 	// copy all missing snapshots with configured concurrency
 	var (
 		wg     sync.WaitGroup
@@ -221,6 +226,12 @@ func (c *Copier) copySnapshot(snapshot *brtypes.Snapshot) error {
 		return fmt.Errorf("could not save snapshot %s to destination store: %v", snapshot.SnapName, err)
 	}
 
+	if snapshot.Kind == brtypes.SnapshotKindFull {
+		if err := snapstore.CopyClusterMetadata(c.sourceSnapStore, c.destSnapStore, snapshot); err != nil {
+			return fmt.Errorf("could not copy cluster metadata of snapshot %s: %v", snapshot.SnapName, err)
+		}
+	}
+
 	return nil
 }
 
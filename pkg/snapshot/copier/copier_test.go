@@ -7,6 +7,7 @@ package copier_test
 import (
 	"context"
 	"os"
+	"path"
 
 	"github.com/gardener/etcd-backup-restore/pkg/miscellaneous"
 	. "github.com/gardener/etcd-backup-restore/pkg/snapshot/copier"
@@ -60,6 +61,43 @@ var _ = Describe("Running Copier", func() {
 			chekIfSnapsAreTheSame(ssnap, deltaSrourceStoreSnapList[i])
 		}
 	})
+
+	It("should copy the snapshots under the prefixes of their kinds in the destination store", func() {
+		destSnapstoreConfig.FullSnapshotPrefix = path.Join("fulls", "v2")
+		destSnapstoreConfig.DeltaSnapshotPrefix = path.Join("deltas", "v2")
+		ss, ds, err = GetSourceAndDestinationStores(sourceSnapstoreConfig, destSnapstoreConfig)
+		Expect(err).ToNot(HaveOccurred())
+		copier = NewCopier(logger, ss, ds, -1, -1, 10, false, 0)
+		Expect(copier.Run(context.TODO())).ToNot(HaveOccurred())
+
+		sourceSnapList, err := ss.List()
+		Expect(err).NotTo(HaveOccurred())
+		targetSnapList, err := ds.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(targetSnapList).To(HaveLen(len(sourceSnapList)))
+		for _, snap := range targetSnapList {
+			kindPrefix := destSnapstoreConfig.DeltaSnapshotPrefix
+			if snap.Kind == brtypes.SnapshotKindFull {
+				kindPrefix = destSnapstoreConfig.FullSnapshotPrefix
+			}
+			Expect(path.Clean(snap.Prefix)).To(Equal(path.Join(targetSnapstoreDir, kindPrefix)))
+		}
+
+		// the copied snapshots are copied again from the prefixes of their kinds
+		copiedSnapstoreConfig := &brtypes.SnapstoreConfig{
+			MaxParallelChunkUploads: 5,
+			TempDir:                 "/tmp",
+			Provider:                "Local",
+			Container:               targetSnapstoreDir + ".copy",
+		}
+		defer os.RemoveAll(copiedSnapstoreConfig.Container)
+		_, cs, err := GetSourceAndDestinationStores(destSnapstoreConfig, copiedSnapstoreConfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(NewCopier(logger, ds, cs, -1, -1, 10, false, 0).Run(context.TODO())).ToNot(HaveOccurred())
+		copiedSnapList, err := cs.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(copiedSnapList).To(HaveLen(len(sourceSnapList)))
+	})
 })
 
 func chekIfSnapsAreTheSame(s1 *brtypes.Snapshot, s2 *brtypes.Snapshot) {
@@ -0,0 +1,72 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restorer
+
+import (
+	"path"
+	"sync"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// chainReferences counts the references of all the restorations running in the process to the snapshots of the chains
+// they restore, so that the garbage collector running in the same process doesn't delete a snapshot of a chain while
+// it is being restored.
+var chainReferences = newSnapshotReferences()
+
+// snapshotReferences counts the references to snapshots, keyed by their path in the snapstore.
+type snapshotReferences struct {
+	mutex sync.Mutex
+	refs  map[string]int
+}
+
+func newSnapshotReferences() *snapshotReferences {
+	return &snapshotReferences{refs: map[string]int{}}
+}
+
+// snapshotPath returns the path of the given snapshot in the snapstore, which identifies it across snapstore listings.
+func snapshotPath(snap *brtypes.Snapshot) string {
+	return path.Join(snap.Prefix, snap.SnapDir, snap.SnapName)
+}
+
+// reference accounts for one more reference to each of the given base snapshot, if set, and delta snapshots, and
+// returns the function which releases the references again.
+func (s *snapshotReferences) reference(baseSnapshot *brtypes.Snapshot, deltaSnapList brtypes.SnapList) func() {
+	var paths []string
+	if baseSnapshot != nil && baseSnapshot.SnapName != "" {
+		paths = append(paths, snapshotPath(baseSnapshot))
+	}
+	for _, snap := range deltaSnapList {
+		paths = append(paths, snapshotPath(snap))
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, p := range paths {
+		s.refs[p]++
+	}
+	return func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		for _, p := range paths {
+			if s.refs[p]--; s.refs[p] <= 0 {
+				delete(s.refs, p)
+			}
+		}
+	}
+}
+
+// isReferenced returns whether the given snapshot is referenced.
+func (s *snapshotReferences) isReferenced(snap *brtypes.Snapshot) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.refs[snapshotPath(snap)] > 0
+}
+
+// IsSnapshotInRestore returns whether the given snapshot belongs to the chain of a restoration running in the process,
+// in which case it must not be deleted until the restoration completes.
+func IsSnapshotInRestore(snap *brtypes.Snapshot) bool {
+	return chainReferences.isReferenced(snap)
+}
@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restorer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// CheckpointFileName is the name of the file in the data directory which records the progress of a restoration.
+const CheckpointFileName = "restore.checkpoint"
+
+// checkpoint records the last delta snapshot which was successfully applied over a base snapshot.
+type checkpoint struct {
+	BaseSnapshot        string `json:"baseSnapshot"`
+	LastAppliedSnapshot string `json:"lastAppliedSnapshot"`
+	LastAppliedRevision int64  `json:"lastAppliedRevision"`
+	// BaseSnapshotIsKeyExport records that the base snapshot is an export of the keys of the whole keyspace.
+	BaseSnapshotIsKeyExport bool `json:"baseSnapshotIsKeyExport,omitempty"`
+}
+
+// checkpointer records a checkpoint of the restoration into the data directory once every interval applied delta
+// snapshots. A nil checkpointer records nothing.
+type checkpointer struct {
+	path                    string
+	baseSnapshot            string
+	baseSnapshotIsKeyExport bool
+	interval                uint
+	applied                 uint
+}
+
+// newCheckpointer returns a checkpointer for the given restore options, or nil if checkpointing is disabled.
+func newCheckpointer(ro brtypes.RestoreOptions) *checkpointer {
+	if ro.Config.RestoreCheckpointInterval == 0 || ro.BaseSnapshot == nil {
+		return nil
+	}
+	return &checkpointer{
+		path:                    CheckpointFilePath(ro.Config.DataDir),
+		baseSnapshot:            ro.BaseSnapshot.SnapName,
+		baseSnapshotIsKeyExport: ro.BaseSnapshotIsKeyExport,
+		interval:                ro.Config.RestoreCheckpointInterval,
+	}
+}
+
+// recordApplied records the given delta snapshot as applied, and writes the checkpoint if the interval is reached.
+func (c *checkpointer) recordApplied(snap *brtypes.Snapshot) error {
+	if c == nil {
+		return nil
+	}
+	c.applied++
+	if c.applied%c.interval != 0 {
+		return nil
+	}
+	return writeCheckpoint(c.path, &checkpoint{
+		BaseSnapshot:            c.baseSnapshot,
+		LastAppliedSnapshot:     snap.SnapName,
+		LastAppliedRevision:     snap.LastRevision,
+		BaseSnapshotIsKeyExport: c.baseSnapshotIsKeyExport,
+	})
+}
+
+// CheckpointFilePath returns the path of the checkpoint file of a restoration into the given data directory.
+func CheckpointFilePath(dataDir string) string {
+	return filepath.Join(dataDir, CheckpointFileName)
+}
+
+// HasCheckpoint checks whether a restoration into the given data directory has recorded a checkpoint, from which it
+// can be resumed.
+func HasCheckpoint(dataDir string) bool {
+	_, err := os.Stat(CheckpointFilePath(dataDir))
+	return err == nil
+}
+
+// writeCheckpoint replaces the checkpoint file atomically, so that a crash never leaves a partially written checkpoint.
+func writeCheckpoint(path string, cp *checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the restoration checkpoint: %v", err)
+	}
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write the restoration checkpoint %s: %v", tempPath, err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("failed to replace the restoration checkpoint %s: %v", path, err)
+	}
+	return nil
+}
+
+// readCheckpoint reads the checkpoint file. It returns nil if the file does not exist.
+func readCheckpoint(path string) (*checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read the restoration checkpoint %s: %v", path, err)
+	}
+	cp := &checkpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the restoration checkpoint %s: %v", path, err)
+	}
+	return cp, nil
+}
+
+// remainingDeltaSnapshots returns the delta snapshots which still have to be applied over the data which was restored
+// up to the given revision before the restoration was interrupted. It returns an error if the checkpoint is stale,
+// i.e. if it was recorded for another base snapshot, or if the restored revision is not consistent with the checkpoint
+// and the delta snapshots.
+func (cp *checkpoint) remainingDeltaSnapshots(baseSnapshot *brtypes.Snapshot, deltaSnapList brtypes.SnapList, restoredRevision int64) (brtypes.SnapList, error) {
+	if cp.BaseSnapshot != baseSnapshot.SnapName {
+		return nil, fmt.Errorf("checkpoint was recorded for the base snapshot %s instead of %s", cp.BaseSnapshot, baseSnapshot.SnapName)
+	}
+	if restoredRevision < cp.LastAppliedRevision {
+		return nil, fmt.Errorf("restored revision %d is behind the revision %d of the checkpoint", restoredRevision, cp.LastAppliedRevision)
+	}
+	foundLastApplied := false
+	for i, snap := range deltaSnapList {
+		if snap.SnapName == cp.LastAppliedSnapshot {
+			foundLastApplied = true
+		}
+		if snap.LastRevision <= restoredRevision {
+			continue
+		}
+		if !foundLastApplied {
+			break
+		}
+		if snap.StartRevision > restoredRevision+1 {
+			return nil, fmt.Errorf("restored revision %d does not precede the start revision %d of the delta snapshot %s", restoredRevision, snap.StartRevision, snap.SnapName)
+		}
+		return deltaSnapList[i:], nil
+	}
+	if !foundLastApplied {
+		return nil, fmt.Errorf("last applied delta snapshot %s of the checkpoint is not part of the delta snapshots", cp.LastAppliedSnapshot)
+	}
+	if lastRevision := deltaSnapList[len(deltaSnapList)-1].LastRevision; restoredRevision != lastRevision {
+		return nil, fmt.Errorf("restored revision %d is ahead of the last revision %d of the delta snapshots", restoredRevision, lastRevision)
+	}
+	return brtypes.SnapList{}, nil
+}
@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restorer
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/gardener/etcd-backup-restore/pkg/snapstore"
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+	"go.etcd.io/etcd/etcdserver/api/membership"
+	"go.etcd.io/etcd/pkg/types"
+)
+
+// ErrClusterIDMismatch is returned if the restored member would be bootstrapped into another cluster than the one the
+// base snapshot was taken from, as per the cluster metadata recorded along with it, without the cluster ID mismatch
+// being allowed.
+var ErrClusterIDMismatch = errors.New("cluster ID mismatch")
+
+// fetchClusterMetadata returns the cluster metadata recorded along with the base snapshot of the given restore options,
+// or nil if none was recorded. The cluster metadata only fails to be fetched if it is used to restore the member,
+// otherwise the restoration continues without it.
+func (r *Restorer) fetchClusterMetadata(ro *brtypes.RestoreOptions) (*brtypes.ClusterMetadata, error) {
+	if ro.BaseSnapshot == nil || ro.BaseSnapshot.SnapName == "" {
+		return nil, nil
+	}
+	snapPath := path.Join(ro.BaseSnapshot.SnapDir, ro.BaseSnapshot.SnapName)
+	metadata, err := snapstore.FetchClusterMetadata(r.store, ro.BaseSnapshot)
+	if err != nil {
+		if ro.Config.UseClusterMetadata {
+			return nil, fmt.Errorf("failed to fetch the cluster metadata of base snapshot %s: %v", snapPath, err)
+		}
+		r.logger.Warnf("Failed to fetch the cluster metadata of base snapshot %s, hence not checking the cluster ID of the restored member: %v", snapPath, err)
+		return nil, nil
+	}
+	if metadata == nil && ro.Config.UseClusterMetadata {
+		r.warnf("No cluster metadata was recorded along with base snapshot %s, restoring with the configured initial cluster.", snapPath)
+	}
+	return metadata, nil
+}
+
+// checkClusterID fails with ErrClusterIDMismatch if the restored member would be bootstrapped into another cluster
+// than the one the base snapshot was taken from, as per the given cluster metadata recorded along with it, unless the
+// cluster ID mismatch is allowed by the restoration config. As etcd derives the ID of a member bootstrapped from the
+// initial cluster from its peer URLs and the initial cluster token, the member restored with its recorded peer URLs
+// keeps its recorded ID only with the original initial cluster token. Nothing is checked unless the cluster metadata
+// is used to restore the member, if no cluster metadata was recorded or the member isn't part of it, and if the
+// members weren't all bootstrapped from the initial cluster, as the IDs of the members added at runtime are salted
+// with the time they were added at, and can't be derived again.
+func (r *Restorer) checkClusterID(ro *brtypes.RestoreOptions, metadata *brtypes.ClusterMetadata) error {
+	if !ro.Config.UseClusterMetadata || metadata == nil {
+		return nil
+	}
+	member := metadata.Member(ro.Config.Name)
+	if member == nil {
+		return nil
+	}
+	snapPath := path.Join(ro.BaseSnapshot.SnapDir, ro.BaseSnapshot.SnapName)
+	if member.IsLearner || initialClusterID(metadata.Members) != metadata.ClusterID {
+		r.logger.Infof("Not checking the cluster ID of member %s, as the members of the cluster metadata of base snapshot %s were not all bootstrapped from the initial cluster.", ro.Config.Name, snapPath)
+		return nil
+	}
+	peerURLs, err := types.NewURLs(member.PeerURLs)
+	if err != nil {
+		return fmt.Errorf("invalid peer URLs of member %s of the cluster metadata of base snapshot %s: %v", ro.Config.Name, snapPath, err)
+	}
+	clusterURLs := types.URLsMap{ro.Config.Name: peerURLs}
+	cl, err := membership.NewClusterFromURLsMap(r.zapLogger, ro.Config.InitialClusterToken, clusterURLs)
+	if err != nil {
+		return err
+	}
+	m := cl.MemberByName(ro.Config.Name)
+	if m == nil {
+		return fmt.Errorf("member %s is not part of the initial cluster %s", ro.Config.Name, clusterURLs)
+	}
+	if id := uint64(m.ID); id != member.ID {
+		if !ro.Config.AllowClusterIDMismatch {
+			return fmt.Errorf("%w: member %s would get the ID %x in cluster %x instead of its recorded ID %x in cluster %x of base snapshot %s, as its peer URLs %s or the initial cluster token %s differ from the ones of the cluster the base snapshot was taken from. Allow the cluster ID mismatch to clone the data into a differently identified cluster", ErrClusterIDMismatch, ro.Config.Name, id, uint64(cl.ID()), member.ID, metadata.ClusterID, snapPath, m.PeerURLs, ro.Config.InitialClusterToken)
+		}
+		r.warnf("CLUSTER ID MISMATCH ALLOWED: Cloning the data of cluster %x from base snapshot %s into cluster %x. Member %s gets the regenerated ID %x instead of its recorded ID %x, as its peer URLs %s or the initial cluster token %s differ from the ones of the cluster the base snapshot was taken from. The restored member can't rejoin the members of the original cluster.", metadata.ClusterID, snapPath, uint64(cl.ID()), ro.Config.Name, id, member.ID, m.PeerURLs, ro.Config.InitialClusterToken)
+	}
+	return nil
+}
+
+// applyClusterMetadata replaces the initial cluster and the peer URLs of the given restore options with the peer URLs
+// the restored member had in the cluster the base snapshot was taken from, as per the given cluster metadata recorded
+// along with the base snapshot, if enabled. The restored member thereby keeps its original ID if the original initial
+// cluster token is used, as checked by checkClusterID beforehand. The restored member still bootstraps a cluster of its
+// own, as the delta snapshots are applied with the quorum of the restored member alone, and the other members are
+// added to it afterwards. The configured initial cluster is kept if no cluster metadata was recorded along with the
+// base snapshot.
+func (r *Restorer) applyClusterMetadata(ro *brtypes.RestoreOptions, metadata *brtypes.ClusterMetadata) error {
+	if !ro.Config.UseClusterMetadata || metadata == nil {
+		return nil
+	}
+	snapPath := path.Join(ro.BaseSnapshot.SnapDir, ro.BaseSnapshot.SnapName)
+	member := metadata.Member(ro.Config.Name)
+	if member == nil || member.IsLearner {
+		return fmt.Errorf("member %s is not a voting member of the cluster metadata of base snapshot %s", ro.Config.Name, snapPath)
+	}
+	peerURLs, err := types.NewURLs(member.PeerURLs)
+	if err != nil {
+		return fmt.Errorf("invalid peer URLs of member %s of the cluster metadata of base snapshot %s: %v", ro.Config.Name, snapPath, err)
+	}
+
+	r.logger.Infof("Restoring member %s with its peer URLs %s recorded along with base snapshot %s.", ro.Config.Name, peerURLs, snapPath)
+	ro.ClusterURLs = types.URLsMap{ro.Config.Name: peerURLs}
+	ro.PeerURLs = peerURLs
+	return nil
+}
+
+// initialClusterID returns the ID etcd derives for a cluster bootstrapped from an initial cluster with the given members,
+// which matches the recorded cluster ID only if all the members were bootstrapped from the initial cluster.
+func initialClusterID(members []brtypes.ClusterMember) uint64 {
+	ids := make([]uint64, 0, len(members))
+	for _, member := range members {
+		ids = append(ids, member.ID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	b := make([]byte, 8*len(ids))
+	for i, id := range ids {
+		binary.BigEndian.PutUint64(b[8*i:], id)
+	}
+	hash := sha1.Sum(b)
+	return binary.BigEndian.Uint64(hash[:8])
+}
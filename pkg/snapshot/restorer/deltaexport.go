@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restorer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gardener/etcd-backup-restore/pkg/snapstore"
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+	"go.etcd.io/etcd/mvcc/mvccpb"
+)
+
+const (
+	// DeltaExportFormatText renders the events of a delta snapshot as a table with one event per line.
+	DeltaExportFormatText = "text"
+	// DeltaExportFormatJSONLines renders the events of a delta snapshot as one JSON object per line.
+	DeltaExportFormatJSONLines = "jsonl"
+
+	// deltaExportValuePreviewLength is the number of bytes of the value of an event rendered in its preview.
+	deltaExportValuePreviewLength = 64
+)
+
+// DeltaSnapshotChange is a single event of a delta snapshot, rendered for humans.
+type DeltaSnapshotChange struct {
+	Revision     int64     `json:"revision"`
+	Time         time.Time `json:"time"`
+	Operation    string    `json:"operation"`
+	Key          string    `json:"key"`
+	ValuePreview string    `json:"valuePreview,omitempty"`
+}
+
+// ExportDeltaSnapshot fetches the given delta snapshot from the snapstore, decompresses it if required, verifies its
+// hash, and writes its events to the given writer in the given format, so that the changes of a delta snapshot can be
+// inspected when debugging data issues. The values of the events are truncated to a short preview.
+func ExportDeltaSnapshot(store brtypes.SnapStore, snap *brtypes.Snapshot, format string, w io.Writer) error {
+	if snap == nil {
+		return fmt.Errorf("delta snapshot not provided")
+	}
+	if snap.Kind != brtypes.SnapshotKindDelta {
+		return fmt.Errorf("snapshot %s is not a delta snapshot", path.Join(snap.SnapDir, snap.SnapName))
+	}
+	if format != DeltaExportFormatText && format != DeltaExportFormatJSONLines {
+		return fmt.Errorf("unsupported delta snapshot export format %q", format)
+	}
+
+	rc, err := store.Fetch(*snap)
+	if err != nil {
+		return fmt.Errorf("failed to fetch delta snapshot %s from store: %v", snap.SnapName, err)
+	}
+	defer rc.Close()
+
+	decompressed, _, _, err := getNormalizedSnapshotReadCloser(rc, snap, snapstore.NewCompressionDictionaryFetcher(store))
+	if err != nil {
+		return fmt.Errorf("failed to decompress delta snapshot %s: %v", snap.SnapName, err)
+	}
+	defer decompressed.Close()
+	buf := new(bytes.Buffer)
+	if _, err := buf.ReadFrom(decompressed); err != nil {
+		return fmt.Errorf("failed to read delta snapshot %s: %v", snap.SnapName, err)
+	}
+	data, _, err := brtypes.VerifyDeltaSnapshotHash(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to verify delta snapshot %s: %v", snap.SnapName, err)
+	}
+	events := []brtypes.Event{}
+	if err := json.Unmarshal(data, &events); err != nil {
+		return fmt.Errorf("failed to decode the events of delta snapshot %s: %v", snap.SnapName, err)
+	}
+
+	changes := make([]DeltaSnapshotChange, 0, len(events))
+	for _, event := range events {
+		changes = append(changes, newDeltaSnapshotChange(event))
+	}
+	if format == DeltaExportFormatJSONLines {
+		enc := json.NewEncoder(w)
+		for _, change := range changes {
+			if err := enc.Encode(change); err != nil {
+				return fmt.Errorf("failed to export delta snapshot %s: %v", snap.SnapName, err)
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "REVISION\tTIME\tOPERATION\tKEY\tVALUE")
+	for _, change := range changes {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%q\t%q\n", change.Revision, change.Time.UTC().Format(time.RFC3339), change.Operation, change.Key, change.ValuePreview)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to export delta snapshot %s: %v", snap.SnapName, err)
+	}
+	return nil
+}
+
+// newDeltaSnapshotChange renders the given event of a delta snapshot, with its value truncated to a preview.
+func newDeltaSnapshotChange(event brtypes.Event) DeltaSnapshotChange {
+	change := DeltaSnapshotChange{
+		Time:      event.Time,
+		Operation: event.EtcdEvent.Type.String(),
+	}
+	if kv := event.EtcdEvent.Kv; kv != nil {
+		change.Revision = kv.ModRevision
+		change.Key = string(kv.Key)
+		if event.EtcdEvent.Type == mvccpb.PUT {
+			change.ValuePreview = string(kv.Value)
+			if len(kv.Value) > deltaExportValuePreviewLength {
+				change.ValuePreview = string(kv.Value[:deltaExportValuePreviewLength]) + "..."
+			}
+		}
+	}
+	return change
+}
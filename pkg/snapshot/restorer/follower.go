@@ -0,0 +1,160 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restorer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/gardener/etcd-backup-restore/pkg/etcdutil/client"
+	"github.com/gardener/etcd-backup-restore/pkg/miscellaneous"
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+	"github.com/sirupsen/logrus"
+	"go.etcd.io/etcd/clientv3"
+)
+
+// ErrFollowerBehindFullSnapshot is returned when the standby etcd fell behind the latest full snapshot, and the events
+// between its revision and the latest full snapshot aren't held by the delta snapshots of the snapstore anymore, e.g.
+// as the delta snapshots of the previous chains were garbage collected.
+var ErrFollowerBehindFullSnapshot = errors.New("standby etcd fell behind the latest full snapshot")
+
+// Follower keeps a standby etcd up to date with the snapstore, by applying the events of the delta snapshots to it as
+// they appear in the snapstore. The standby etcd has to be restored from the snapshots of the snapstore beforehand, so
+// that its revision matches the revisions of the snapshots.
+type Follower struct {
+	restorer      *Restorer
+	store         brtypes.SnapStore
+	clientFactory client.Factory
+	config        *brtypes.FollowerConfig
+	logger        *logrus.Entry
+}
+
+// NewFollower returns a follower which applies the delta snapshots of the given snapstore to the standby etcd of the
+// given client factory.
+func NewFollower(store brtypes.SnapStore, clientFactory client.Factory, config *brtypes.FollowerConfig, logger *logrus.Entry) (*Follower, error) {
+	r, err := NewRestorer(store, logger)
+	if err != nil {
+		return nil, err
+	}
+	return &Follower{
+		restorer:      r,
+		store:         store,
+		clientFactory: clientFactory,
+		config:        config,
+		logger:        logger.WithField("actor", "follower"),
+	}, nil
+}
+
+// Run polls the snapstore for new delta snapshots and applies them to the standby etcd until the given context is
+// cancelled. A failed poll is retried at the next poll, unless the delta snapshots until the latest full snapshot are
+// missing, in which case the standby etcd has to be restored again and ErrFollowerBehindFullSnapshot is returned.
+func (f *Follower) Run(ctx context.Context) error {
+	ticker := time.NewTicker(f.config.PollInterval.Duration)
+	defer ticker.Stop()
+	for {
+		if _, err := f.Sync(ctx); err != nil {
+			if errors.Is(err, ErrFollowerBehindFullSnapshot) {
+				return err
+			}
+			if ctx.Err() == nil {
+				f.logger.Errorf("Failed to apply the new delta snapshots to the standby etcd: %v", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			f.logger.Info("Stopping the follower")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync applies the events of the delta snapshots after the revision of the standby etcd to it, and returns the revision
+// the standby etcd reached. If a full snapshot was taken since the standby etcd was synced, the standby etcd follows
+// across it with the delta snapshots of the previous chain, which end at the revision of the full snapshot.
+func (f *Follower) Sync(ctx context.Context) (int64, error) {
+	clientKV, err := f.clientFactory.NewKV()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create etcd KV client: %v", err)
+	}
+	defer clientKV.Close()
+
+	getCtx, cancel := context.WithTimeout(ctx, etcdConnectionTimeout)
+	resp, err := clientKV.Get(getCtx, "", clientv3.WithLastRev()...)
+	cancel()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the revision of the standby etcd: %v", err)
+	}
+	revision := resp.Header.Revision
+
+	baseSnapshot, deltaSnapList, err := miscellaneous.GetLatestFullSnapshotAndDeltaSnapListWithContext(ctx, f.store)
+	if err != nil {
+		return revision, fmt.Errorf("failed to list the snapshots: %v", err)
+	}
+	if baseSnapshot != nil && revision < baseSnapshot.LastRevision {
+		previousDeltaSnapList, err := f.listPreviousDeltaSnapshots(revision, baseSnapshot.LastRevision)
+		if err != nil {
+			return revision, fmt.Errorf("failed to list the delta snapshots before the full snapshot: %v", err)
+		}
+		deltaSnapList = append(previousDeltaSnapList, deltaSnapList...)
+	}
+
+	var applied int
+	for _, snap := range deltaSnapList {
+		if snap.LastRevision <= revision {
+			continue
+		}
+		if baseSnapshot != nil && revision < baseSnapshot.LastRevision && snap.StartRevision > revision+1 {
+			break
+		}
+		events, err := f.restorer.getEventsFromDeltaSnapshot(*snap)
+		if err != nil {
+			return revision, fmt.Errorf("failed to read the events of delta snapshot %s: %v", snap.SnapName, err)
+		}
+		// the standby etcd may already hold the first events of the delta snapshot, e.g. of the last full snapshot
+		for len(events) > 0 && events[0].EtcdEvent.Kv.ModRevision <= revision {
+			events = events[1:]
+		}
+		if len(events) > 0 {
+			if err := applyEventsToEtcd(ctx, clientKV, events); err != nil {
+				return revision, fmt.Errorf("failed to apply the events of delta snapshot %s: %v", snap.SnapName, err)
+			}
+		}
+		if err := verifySnapshotRevision(ctx, clientKV, snap); err != nil {
+			return revision, fmt.Errorf("standby etcd diverged from delta snapshot %s: %v", snap.SnapName, err)
+		}
+		revision = snap.LastRevision
+		applied++
+	}
+
+	if applied > 0 {
+		f.logger.Infof("Applied %d delta snapshots to the standby etcd up to revision %d", applied, revision)
+	}
+	if baseSnapshot != nil && revision < baseSnapshot.LastRevision {
+		return revision, fmt.Errorf("%w %s: the standby etcd is at revision %d, but the full snapshot is at revision %d", ErrFollowerBehindFullSnapshot, path.Join(baseSnapshot.SnapDir, baseSnapshot.SnapName), revision, baseSnapshot.LastRevision)
+	}
+	return revision, nil
+}
+
+// listPreviousDeltaSnapshots returns the sorted delta snapshots of the previous chains in the snapstore, which hold
+// the events after the given revision until the revision of the latest full snapshot.
+func (f *Follower) listPreviousDeltaSnapshots(revision, fullSnapshotRevision int64) (brtypes.SnapList, error) {
+	snapList, err := f.store.List()
+	if err != nil {
+		return nil, err
+	}
+	var deltaSnapList brtypes.SnapList
+	for _, snap := range snapList {
+		if snap.Kind == brtypes.SnapshotKindDelta && !snap.IsChunk && snap.LastRevision > revision && snap.LastRevision <= fullSnapshotRevision {
+			deltaSnapList = append(deltaSnapList, snap)
+		}
+	}
+	sort.Sort(deltaSnapList)
+	return deltaSnapList, nil
+}
@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restorer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// detectKeyExportBaseSnapshot checks whether the base snapshot of the given restore options is an export of the keys of
+// the whole keyspace instead of a database snapshot, as taken by a full snapshot at a historical revision, so that it
+// is restored the same way as the base snapshot of a restoration scoped to a key prefix. The exported keys are
+// serialized as a JSON list of events, whereas a database snapshot starts with the zero ID of its first page.
+func (r *Restorer) detectKeyExportBaseSnapshot(ro *brtypes.RestoreOptions) error {
+	if ro.BaseSnapshotIsKeyExport || ro.Config.RestoreKeyPrefix != "" || ro.BaseSnapshot == nil || ro.BaseSnapshot.SnapName == "" {
+		return nil
+	}
+	rc, err := r.store.Fetch(*ro.BaseSnapshot)
+	if err != nil {
+		return fmt.Errorf("failed to fetch base snapshot %s: %v", ro.BaseSnapshot.SnapName, err)
+	}
+	defer rc.Close()
+	data, _, _, err := getNormalizedSnapshotReadCloser(rc, ro.BaseSnapshot, r.fetchDictionary)
+	if err != nil {
+		return fmt.Errorf("failed to decompress base snapshot %s: %v", ro.BaseSnapshot.SnapName, err)
+	}
+	defer data.Close()
+	first, err := bufio.NewReader(data).Peek(1)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read base snapshot %s: %v", ro.BaseSnapshot.SnapName, err)
+	}
+	if first[0] == '[' {
+		r.logger.Infof("Base snapshot %s is an export of the keys of the whole keyspace.", ro.BaseSnapshot.SnapName)
+		ro.BaseSnapshotIsKeyExport = true
+	}
+	return nil
+}
+
+// isKeyExportRestoration checks whether the base snapshot of the given restore options is an export of the keys, i.e.
+// of the keys under the key prefix of a scoped restoration, or of the whole keyspace. The keys of such a base snapshot
+// are written afresh, so the revisions of the restored etcd do not match the revisions of the snapshots.
+func isKeyExportRestoration(ro brtypes.RestoreOptions) bool {
+	return ro.Config.RestoreKeyPrefix != "" || ro.BaseSnapshotIsKeyExport
+}
@@ -0,0 +1,45 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restorer
+
+import "sync"
+
+// nodeFetchers counts the delta snapshots being fetched by all the restorations running in the process, so that the
+// restorations of the members restored at once on a shared node don't overwhelm the snapstore and the node with
+// their combined fetches.
+var nodeFetchers = newFetcherLimiter()
+
+// fetcherLimiter limits the number of delta snapshots fetched in parallel.
+type fetcherLimiter struct {
+	mutex    sync.Mutex
+	cond     *sync.Cond
+	inFlight uint
+}
+
+func newFetcherLimiter() *fetcherLimiter {
+	l := &fetcherLimiter{}
+	l.cond = sync.NewCond(&l.mutex)
+	return l
+}
+
+// acquire waits until fewer than the given limit of delta snapshots are being fetched, and accounts for one more
+// fetch. It doesn't wait if the limit is 0.
+func (l *fetcherLimiter) acquire(limit uint) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for limit > 0 && l.inFlight >= limit {
+		l.cond.Wait()
+	}
+	l.inFlight++
+}
+
+// release accounts for a completed fetch, and wakes up the fetchers waiting for it.
+func (l *fetcherLimiter) release() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.inFlight--
+	// the waiting fetchers may have been configured with different limits
+	l.cond.Broadcast()
+}
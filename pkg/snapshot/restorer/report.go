@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restorer
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+	bolt "go.etcd.io/bbolt"
+	"go.etcd.io/etcd/embed"
+)
+
+// LastRestoreReport returns the report of the last restoration by the restorer, or nil if it didn't restore yet.
+func (r *Restorer) LastRestoreReport() *brtypes.RestoreReport {
+	return r.lastReport
+}
+
+// warnf logs the given warning and records it in the report of the ongoing restoration, if any.
+func (r *Restorer) warnf(format string, args ...interface{}) {
+	r.logger.Warnf(format, args...)
+	if r.report != nil {
+		r.report.Warnings = append(r.report.Warnings, fmt.Sprintf(format, args...))
+	}
+}
+
+// recordRestoredSnapshots records the snapshots of the given restore options in the report of the ongoing restoration,
+// once they are limited to the ones to restore.
+func (r *Restorer) recordRestoredSnapshots(ro brtypes.RestoreOptions) {
+	if r.report == nil {
+		return
+	}
+	if ro.BaseSnapshot != nil {
+		r.report.BaseSnapshot = path.Join(ro.BaseSnapshot.SnapDir, ro.BaseSnapshot.SnapName)
+		r.report.FinalRevision = ro.BaseSnapshot.LastRevision
+	}
+	for _, snap := range ro.DeltaSnapList {
+		r.report.DeltaSnapshots = append(r.report.DeltaSnapshots, path.Join(snap.SnapDir, snap.SnapName))
+		r.report.FinalRevision = snap.LastRevision
+	}
+}
+
+// recordFinalRevision records the revision actually restored in the report of the ongoing restoration, which is read
+// from the given embedded etcd if the restoration started one, or from the restored database otherwise. The last
+// revision of the restored snapshots is kept in the report if the revision can't be read.
+func (r *Restorer) recordFinalRevision(ctx context.Context, ro brtypes.RestoreOptions, e *embed.Etcd) {
+	if r.report == nil {
+		return
+	}
+	var (
+		revision int64
+		err      error
+	)
+	if e != nil {
+		revision, err = getEtcdRevision(ctx, ro, e)
+	} else {
+		revision, err = getDatabaseRevision(filepath.Join(ro.Config.DataDir, "member", "snap", "db"))
+	}
+	if err != nil {
+		r.warnf("Failed to read the restored revision, reporting the last revision of the restored snapshots instead: %v", err)
+		return
+	}
+	r.report.FinalRevision = revision
+}
+
+// getDatabaseRevision reads the latest revision of the given etcd database without starting an embedded etcd.
+func getDatabaseRevision(dbPath string) (int64, error) {
+	db, err := bolt.Open(dbPath, 0400, &bolt.Options{Timeout: etcdConnectionTimeout, ReadOnly: true})
+	if err != nil {
+		return 0, fmt.Errorf("failed to open the restored database %s: %v", dbPath, err)
+	}
+	defer db.Close()
+
+	var revision int64
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("key"))
+		if b == nil {
+			return fmt.Errorf("no key bucket found in the restored database %s", dbPath)
+		}
+		// The keys of the key bucket start with the big-endian main revision.
+		if k, _ := b.Cursor().Last(); len(k) >= 8 {
+			revision = int64(binary.BigEndian.Uint64(k[0:8]))
+		} else {
+			revision = 1
+		}
+		return nil
+	})
+	return revision, err
+}
+
+// completeRestoreReport records the outcome of the restoration in the given report, and writes it to the given path
+// if any. A report which can't be written fails only the write, not the restoration.
+func (r *Restorer) completeRestoreReport(report *brtypes.RestoreReport, restoreErr error, reportPath string) {
+	report.Duration = time.Since(report.StartedOn)
+	if restoreErr != nil {
+		report.Error = restoreErr.Error()
+	} else {
+		report.Succeeded = true
+	}
+	if reportPath == "" {
+		return
+	}
+	if err := writeRestoreReport(reportPath, report); err != nil {
+		r.logger.Errorf("Failed to write the restore report: %v", err)
+		return
+	}
+	r.logger.Infof("Wrote the restore report to %s", reportPath)
+}
+
+// writeRestoreReport replaces the restore report file atomically, so that a crash never leaves a partially written report.
+func writeRestoreReport(reportPath string, report *brtypes.RestoreReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the restore report: %v", err)
+	}
+	tempPath := reportPath + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write the restore report %s: %v", tempPath, err)
+	}
+	if err := os.Rename(tempPath, reportPath); err != nil {
+		return fmt.Errorf("failed to replace the restore report %s: %v", reportPath, err)
+	}
+	return nil
+}
@@ -9,7 +9,9 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"math"
 	"os"
@@ -25,6 +27,8 @@ import (
 	"github.com/gardener/etcd-backup-restore/pkg/etcdutil/client"
 	"github.com/gardener/etcd-backup-restore/pkg/member"
 	"github.com/gardener/etcd-backup-restore/pkg/miscellaneous"
+	"github.com/gardener/etcd-backup-restore/pkg/snapstore"
+	"github.com/gardener/etcd-backup-restore/pkg/tracing"
 	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
 	"github.com/sirupsen/logrus"
 	"go.etcd.io/etcd/clientv3"
@@ -33,6 +37,7 @@ import (
 	"go.etcd.io/etcd/etcdserver/api/membership"
 	"go.etcd.io/etcd/etcdserver/api/snap"
 	store "go.etcd.io/etcd/etcdserver/api/v2store"
+	"go.etcd.io/etcd/etcdserver/api/v3rpc/rpctypes"
 	"go.etcd.io/etcd/etcdserver/etcdserverpb"
 	"go.etcd.io/etcd/lease"
 	"go.etcd.io/etcd/mvcc"
@@ -54,11 +59,20 @@ const (
 	thresholdPercentageForDBSizeAlarm             float64 = 80.0 / 100.0
 )
 
+// ErrMaxRestoreDurationExceeded is returned if a restoration is aborted because it did not complete within the
+// maximum restore duration of the restore options.
+var ErrMaxRestoreDurationExceeded = errors.New("restoration exceeded the maximum restore duration")
+
 // Restorer is a struct for etcd data directory restorer
 type Restorer struct {
 	logger    *logrus.Entry
 	zapLogger *zap.Logger
 	store     brtypes.SnapStore
+	// fetchDictionary fetches the dictionaries of the delta snapshots compressed with the zlib-dict compression policy.
+	fetchDictionary compressor.DictionaryFetcher
+	// report is the report of the ongoing restoration, and lastReport the one of the last restoration.
+	report     *brtypes.RestoreReport
+	lastReport *brtypes.RestoreReport
 }
 
 // NewRestorer returns the restorer object.
@@ -68,15 +82,24 @@ func NewRestorer(store brtypes.SnapStore, logger *logrus.Entry) (*Restorer, erro
 		return nil, fmt.Errorf("unable to create the object of zapLogger: %s", err)
 	}
 	return &Restorer{
-		logger:    logger.WithField("actor", "restorer"),
-		zapLogger: zapLogger,
-		store:     store,
+		logger:          logger.WithField("actor", "restorer"),
+		zapLogger:       zapLogger,
+		store:           store,
+		fetchDictionary: snapstore.NewCompressionDictionaryFetcher(store),
 	}, nil
 }
 
 // RestoreAndStopEtcd restore the etcd data directory as per specified restore options but doesn't return the ETCD server that it statrted.
-func (r *Restorer) RestoreAndStopEtcd(ro brtypes.RestoreOptions, m member.Control) error {
-	embeddedEtcd, err := r.Restore(ro, m)
+// The restoration can be aborted by cancelling the given context.
+// It fails fast without touching the data directory if another restoration holds the restore lock of the snapstore.
+func (r *Restorer) RestoreAndStopEtcd(ctx context.Context, ro brtypes.RestoreOptions, m member.Control) error {
+	release, err := r.AcquireRestoreLock(ro.RestoreLockTTL)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	embeddedEtcd, err := r.Restore(ctx, ro, m)
 	defer func() {
 		if embeddedEtcd != nil {
 			embeddedEtcd.Server.Stop()
@@ -86,22 +109,147 @@ func (r *Restorer) RestoreAndStopEtcd(ro brtypes.RestoreOptions, m member.Contro
 	return err
 }
 
+// AcquireRestoreLock acquires the restore lock of the snapstore for the given TTL, to reject the other restorations of
+// the same cluster while it is held, and returns the function which releases it. It fails fast with an error wrapping
+// snapstore.ErrRestoreLockExists if another restoration holds the restore lock. Nothing is locked if the TTL is 0.
+func (r *Restorer) AcquireRestoreLock(ttl time.Duration) (func(), error) {
+	if ttl <= 0 {
+		return func() {}, nil
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	holder := fmt.Sprintf("%s-%d-%d", host, os.Getpid(), time.Now().UnixNano())
+	lock, err := snapstore.AcquireRestoreLock(r.store, holder, host, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire the restore lock: %w", err)
+	}
+	r.logger.Infof("Acquired the restore lock as %s until %s", holder, lock.ExpiresOn)
+	return func() {
+		if err := snapstore.ReleaseRestoreLock(r.store, holder); err != nil {
+			r.logger.Errorf("Failed to release the restore lock: %v", err)
+			return
+		}
+		r.logger.Infof("Released the restore lock as %s", holder)
+	}, nil
+}
+
 // Restore restores the etcd data directory as per specified restore options but returns the ETCD server that it statrted.
-func (r *Restorer) Restore(ro brtypes.RestoreOptions, m member.Control) (*embed.Etcd, error) {
-	if err := r.restoreFromBaseSnapshot(ro); err != nil {
-		return nil, fmt.Errorf("failed to restore from the base snapshot: %v", err)
+// If the given context is cancelled, the restoration is aborted, the embedded etcd server is stopped, the partially
+// restored member directory is removed and the context error is returned. The restoration is aborted the same way
+// with ErrMaxRestoreDurationExceeded if it does not complete within the maximum restore duration of the restore options.
+// The report of the restoration is available from LastRestoreReport afterwards, and is written to the restore report
+// path of the restore options if any. The snapshots of the chain are kept from being garbage collected within the
+// process until the restoration completes.
+func (r *Restorer) Restore(ctx context.Context, ro brtypes.RestoreOptions, m member.Control) (*embed.Etcd, error) {
+	release := chainReferences.reference(ro.BaseSnapshot, ro.DeltaSnapList)
+	defer release()
+
+	report := &brtypes.RestoreReport{StartedOn: time.Now().UTC()}
+	r.report = report
+	e, err := r.restoreWithinMaxDuration(ctx, ro, m)
+	if err == nil {
+		r.recordFinalRevision(ctx, ro, e)
+	}
+	r.report, r.lastReport = nil, report
+	r.completeRestoreReport(report, err, ro.Config.RestoreReportPath)
+	return e, err
+}
+
+func (r *Restorer) restoreWithinMaxDuration(ctx context.Context, ro brtypes.RestoreOptions, m member.Control) (*embed.Etcd, error) {
+	restoreCtx := ctx
+	if ro.MaxRestoreDuration > 0 {
+		var cancel context.CancelFunc
+		restoreCtx, cancel = context.WithTimeout(ctx, ro.MaxRestoreDuration)
+		defer cancel()
+	}
+	e, err := r.restore(restoreCtx, ro, m)
+	if err == nil {
+		return e, nil
+	}
+	if e != nil {
+		e.Server.Stop()
+		e.Close()
+	}
+	if abortErr := restoreCtx.Err(); abortErr != nil {
+		if ctx.Err() == nil {
+			abortErr = fmt.Errorf("%w of %s", ErrMaxRestoreDurationExceeded, ro.MaxRestoreDuration)
+		}
+		r.logger.Warnf("Restoration aborted: %v", abortErr)
+		if ro.Config.RestoreCheckpointInterval > 0 && HasCheckpoint(ro.Config.DataDir) {
+			r.logger.Infof("Keeping the partially restored data directory %s to resume the restoration from its checkpoint", ro.Config.DataDir)
+			return nil, abortErr
+		}
+		memberDir := filepath.Join(ro.Config.DataDir, "member")
+		if err := os.RemoveAll(memberDir); err != nil {
+			r.logger.Errorf("failed to remove partially restored member directory %s: %v", memberDir, err)
+		}
+		return nil, abortErr
+	}
+	return nil, err
+}
+
+func (r *Restorer) restore(ctx context.Context, ro brtypes.RestoreOptions, m member.Control) (*embed.Etcd, error) {
+	metadata, err := r.fetchClusterMetadata(&ro)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkClusterID(&ro, metadata); err != nil {
+		return nil, err
+	}
+	if err := r.discardDeltaSnapshots(&ro); err != nil {
+		return nil, err
+	}
+	if err := r.limitToRestoreTime(&ro); err != nil {
+		return nil, err
 	}
+	if err := r.skipDeltaSnapshots(&ro); err != nil {
+		return nil, err
+	}
+	if err := r.applyClusterMetadata(&ro, metadata); err != nil {
+		return nil, err
+	}
+	if ro.ChainManifestFallback != "" {
+		r.warnf("Restoring from the snapshots found by listing the snapstore instead of from the chain manifest: %s", ro.ChainManifestFallback)
+	}
+	r.recordRestoredSnapshots(ro)
 
-	if len(ro.DeltaSnapList) == 0 {
+	var preserved *preservedKeys
+	if len(ro.Config.PreservedKeyPrefixes) > 0 {
+		var err error
+		if preserved, err = r.capturePreservedKeys(ctx, ro); err != nil {
+			return nil, fmt.Errorf("failed to capture the preserved keys from the live etcd cluster: %v", err)
+		}
+	}
+
+	remainingSnaps, resumed, err := r.resumeFromCheckpoint(ctx, &ro)
+	if err != nil {
+		return nil, err
+	}
+	if resumed {
+		ro.DeltaSnapList = remainingSnaps
+		if r.report != nil {
+			r.report.ResumedFromCheckpoint = true
+		}
+	} else {
+		if err := r.detectKeyExportBaseSnapshot(&ro); err != nil {
+			return nil, err
+		}
+		if err := r.restoreFromBaseSnapshot(ctx, ro); err != nil {
+			return nil, fmt.Errorf("failed to restore from the base snapshot: %v", err)
+		}
+	}
+
+	if len(ro.DeltaSnapList) == 0 && len(ro.Config.PreservedKeyPrefixes) == 0 {
 		r.logger.Infof("No delta snapshots present over base snapshot.")
-		return nil, nil
+		return nil, r.removeCheckpoint(ro.Config.DataDir)
 	}
 
 	r.logger.Infof("Attempting to apply %d delta snapshots for restoration.", len(ro.DeltaSnapList))
 	r.logger.Infof("Creating temporary directory %s for persisting delta snapshots locally.", ro.Config.TempSnapshotsDir)
 
-	err := os.MkdirAll(ro.Config.TempSnapshotsDir, 0700)
-	if err != nil {
+	if err := os.MkdirAll(ro.Config.TempSnapshotsDir, 0700); err != nil {
 		return nil, err
 	}
 
@@ -111,6 +259,10 @@ func (r *Restorer) Restore(ro brtypes.RestoreOptions, m member.Control) (*embed.
 		}
 	}()
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	r.logger.Infof("Starting an embedded etcd server...")
 	e, err := miscellaneous.StartEmbeddedEtcd(r.logger, &ro)
 	if err != nil {
@@ -125,9 +277,18 @@ func (r *Restorer) Restore(ro brtypes.RestoreOptions, m member.Control) (*embed.
 		InsecureTransport:  true,
 	})
 
-	r.logger.Infof("Applying delta snapshots...")
-	if err := r.applyDeltaSnapshots(clientFactory, embeddedEtcdEndpoints, ro); err != nil {
-		return e, err
+	if len(ro.DeltaSnapList) > 0 {
+		r.logger.Infof("Applying delta snapshots...")
+		if err := r.applyDeltaSnapshots(ctx, clientFactory, embeddedEtcdEndpoints, ro); err != nil {
+			return e, err
+		}
+	}
+
+	if len(ro.Config.PreservedKeyPrefixes) > 0 {
+		r.logger.Infof("Applying %d preserved keys over the restored data...", len(preserved.kvs))
+		if err := r.applyPreservedKeys(ctx, clientFactory, ro.Config.PreservedKeyPrefixes, preserved, int(ro.Config.MaxTxnOps)); err != nil {
+			return e, fmt.Errorf("failed to apply the preserved keys: %v", err)
+		}
 	}
 
 	if m != nil {
@@ -140,14 +301,384 @@ func (r *Restorer) Restore(ro brtypes.RestoreOptions, m member.Control) (*embed.
 				r.logger.Errorf("failed to close etcd cluster client: %v", err)
 			}
 		}()
-		m.UpdateMemberPeerURL(context.TODO(), clientCluster)
+		m.UpdateMemberPeerURL(ctx, clientCluster)
+	}
+	return e, r.removeCheckpoint(ro.Config.DataDir)
+}
+
+// resumeFromCheckpoint checks whether a previous restoration into the data directory recorded a checkpoint, and if so
+// returns the delta snapshots which remain to be applied over the partially restored data directory. The restored
+// revision is read by booting the data directory with an embedded etcd. A stale checkpoint, which is not consistent
+// with the base snapshot, the delta snapshots or the restored revision, is discarded along with the partially
+// restored member directory, so that the restoration starts over from the base snapshot. Whether the base snapshot
+// is an export of the keys is taken from the checkpoint, so that the base snapshot isn't fetched again.
+func (r *Restorer) resumeFromCheckpoint(ctx context.Context, ro *brtypes.RestoreOptions) (brtypes.SnapList, bool, error) {
+	if ro.Config.RestoreCheckpointInterval == 0 || ro.BaseSnapshot == nil {
+		return nil, false, nil
+	}
+	cp, err := readCheckpoint(CheckpointFilePath(ro.Config.DataDir))
+	if err != nil {
+		r.logger.Warnf("Discarding the unreadable restoration checkpoint: %v", err)
+		return nil, false, r.discardCheckpoint(ro.Config.DataDir)
+	}
+	if cp == nil {
+		return nil, false, nil
+	}
+
+	restoredRevision, err := r.getRestoredRevision(ctx, *ro)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, false, ctx.Err()
+		}
+		r.logger.Warnf("Discarding the restoration checkpoint, as the partially restored data directory is not usable: %v", err)
+		return nil, false, r.discardCheckpoint(ro.Config.DataDir)
+	}
+	remainingSnaps, err := cp.remainingDeltaSnapshots(ro.BaseSnapshot, ro.DeltaSnapList, restoredRevision)
+	if err != nil {
+		r.logger.Warnf("Discarding the stale restoration checkpoint: %v", err)
+		return nil, false, r.discardCheckpoint(ro.Config.DataDir)
+	}
+	r.logger.Infof("Resuming the restoration from the checkpoint at revision %d, with %d of %d delta snapshots remaining", restoredRevision, len(remainingSnaps), len(ro.DeltaSnapList))
+	ro.BaseSnapshotIsKeyExport = ro.BaseSnapshotIsKeyExport || cp.BaseSnapshotIsKeyExport
+	return remainingSnaps, true, nil
+}
+
+// getRestoredRevision boots the partially restored data directory with an embedded etcd to read its revision.
+func (r *Restorer) getRestoredRevision(ctx context.Context, ro brtypes.RestoreOptions) (int64, error) {
+	e, err := miscellaneous.StartEmbeddedEtcd(r.logger, &ro)
+	if e != nil {
+		defer func() {
+			e.Server.Stop()
+			e.Close()
+		}()
+	}
+	if err != nil {
+		return 0, err
+	}
+	return getEtcdRevision(ctx, ro, e)
+}
+
+// getEtcdRevision reads the revision of the given embedded etcd.
+func getEtcdRevision(ctx context.Context, ro brtypes.RestoreOptions, e *embed.Etcd) (int64, error) {
+	clientKV, err := etcdutil.NewClientFactory(ro.NewClientFactory, brtypes.EtcdConnectionConfig{
+		MaxCallSendMsgSize: ro.Config.MaxCallSendMsgSize,
+		Endpoints:          []string{e.Clients[0].Addr().String()},
+		InsecureTransport:  true,
+	}).NewKV()
+	if err != nil {
+		return 0, err
+	}
+	defer clientKV.Close()
+
+	getCtx, cancel := context.WithTimeout(ctx, etcdConnectionTimeout)
+	defer cancel()
+	resp, err := clientKV.Get(getCtx, "", clientv3.WithLastRev()...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the restored revision: %v", err)
+	}
+	return resp.Header.Revision, nil
+}
+
+// discardCheckpoint removes the checkpoint and the partially restored member directory of the data directory.
+func (r *Restorer) discardCheckpoint(dataDir string) error {
+	memberDir := filepath.Join(dataDir, "member")
+	if err := os.RemoveAll(memberDir); err != nil {
+		return fmt.Errorf("failed to remove partially restored member directory %s: %v", memberDir, err)
+	}
+	return r.removeCheckpoint(dataDir)
+}
+
+// removeCheckpoint removes the checkpoint of the restoration into the data directory, if any.
+func (r *Restorer) removeCheckpoint(dataDir string) error {
+	if err := os.Remove(CheckpointFilePath(dataDir)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove the restoration checkpoint: %v", err)
+	}
+	return nil
+}
+
+// VerifyRestore restores the given snapshots into a throwaway directory next to the configured data directory, to
+// confirm that they are restorable without touching the data directory itself. If startEtcd is set, the restored data
+// directory is booted with an embedded etcd, which has to reach the last revision of the snapshots, and which has to hold
+// the canary key written before the base snapshot if a canary key prefix is configured. The throwaway directory is
+// removed before returning the report of the verification.
+func (r *Restorer) VerifyRestore(ctx context.Context, ro brtypes.RestoreOptions, startEtcd bool) *brtypes.RestoreVerificationReport {
+	// the snapshots are read before the restoration itself references them
+	release := chainReferences.reference(ro.BaseSnapshot, ro.DeltaSnapList)
+	defer release()
+
+	start := time.Now()
+	report := &brtypes.RestoreVerificationReport{}
+	if ro.BaseSnapshot != nil {
+		report.BaseSnapshot = ro.BaseSnapshot.SnapName
+	}
+
+	if err := r.verifyRestore(ctx, ro, startEtcd, report); err != nil {
+		r.logger.Errorf("Verification of the restoration failed: %v", err)
+		report.Error = err.Error()
+	} else {
+		report.Passed = true
+	}
+	report.Duration = time.Since(start)
+	return report
+}
+
+func (r *Restorer) verifyRestore(ctx context.Context, ro brtypes.RestoreOptions, startEtcd bool, report *brtypes.RestoreVerificationReport) error {
+	if err := r.discardDeltaSnapshots(&ro); err != nil {
+		return err
+	}
+	// the expected revision is only known once the delta snapshots are limited to the restore time
+	if err := r.limitToRestoreTime(&ro); err != nil {
+		return err
 	}
-	return e, nil
+	if err := r.skipDeltaSnapshots(&ro); err != nil {
+		return err
+	}
+	if err := r.detectKeyExportBaseSnapshot(&ro); err != nil {
+		return err
+	}
+	report.DeltaSnapshots = len(ro.DeltaSnapList)
+	if ro.BaseSnapshot != nil {
+		report.ExpectedRevision = ro.BaseSnapshot.LastRevision
+	}
+	if len(ro.DeltaSnapList) > 0 {
+		report.ExpectedRevision = ro.DeltaSnapList[len(ro.DeltaSnapList)-1].LastRevision
+	}
+
+	verificationDir, err := os.MkdirTemp(filepath.Dir(ro.Config.DataDir), "restore-verification-")
+	if err != nil {
+		return fmt.Errorf("failed to create the verification directory: %v", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(verificationDir); err != nil {
+			r.logger.Errorf("failed to remove the verification directory %s: %v", verificationDir, err)
+		}
+	}()
+
+	verificationOptions := ro.DeepCopy()
+	verificationOptions.Config.DataDir = filepath.Join(verificationDir, "data")
+	verificationOptions.Config.TempSnapshotsDir = filepath.Join(verificationDir, "tmp")
+	// The live etcd cluster must not be touched by a verification.
+	verificationOptions.Config.PreservedKeyPrefixes = nil
+	// The report of the restoration which is verified must not be overwritten by the report of the verification.
+	verificationOptions.Config.RestoreReportPath = ""
+
+	r.logger.Infof("Verifying the restoration in %s...", verificationDir)
+	e, err := r.Restore(ctx, *verificationOptions, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e != nil {
+			e.Server.Stop()
+			e.Close()
+		}
+	}()
+	if !startEtcd {
+		return nil
+	}
+
+	if e == nil {
+		// The embedded etcd is only started by the restoration to apply the delta snapshots.
+		if e, err = miscellaneous.StartEmbeddedEtcd(r.logger, verificationOptions); err != nil {
+			return fmt.Errorf("failed to start the restored etcd: %v", err)
+		}
+	}
+	clientKV, err := etcdutil.NewClientFactory(ro.NewClientFactory, brtypes.EtcdConnectionConfig{
+		MaxCallSendMsgSize: ro.Config.MaxCallSendMsgSize,
+		Endpoints:          []string{e.Clients[0].Addr().String()},
+		InsecureTransport:  true,
+	}).NewKV()
+	if err != nil {
+		return err
+	}
+	defer clientKV.Close()
+
+	getCtx, cancel := context.WithTimeout(ctx, etcdConnectionTimeout)
+	defer cancel()
+	resp, err := clientKV.Get(getCtx, "foo")
+	if err != nil {
+		return fmt.Errorf("failed to get the revision of the restored etcd: %v", err)
+	}
+	report.RestoredRevision = resp.Header.GetRevision()
+	// the revisions of a restoration from an export of the keys, or skipping delta snapshots, do not match the revisions of the snapshots
+	if !isKeyExportRestoration(ro) && len(ro.SkipDeltaRevisions) == 0 && report.RestoredRevision != report.ExpectedRevision {
+		return fmt.Errorf("restored etcd reached revision %d instead of the expected revision %d", report.RestoredRevision, report.ExpectedRevision)
+	}
+
+	if ro.Config.CanaryKeyPrefix != "" && ro.BaseSnapshot != nil {
+		if err := etcdutil.VerifyCanary(getCtx, clientKV, ro.Config.CanaryKeyPrefix, ro.BaseSnapshot); err != nil {
+			return fmt.Errorf("failed to verify the canary of the base snapshot: %v", err)
+		}
+		report.CanaryVerified = true
+	}
+	return nil
+}
+
+// preservedKeys holds the key-values under the preserved key prefixes captured from the live etcd cluster at a single
+// revision, along with the remaining TTLs of the leases attached to them.
+type preservedKeys struct {
+	kvs       []*mvccpb.KeyValue
+	leaseTTLs map[int64]int64
+}
+
+// capturePreservedKeys fetches the key-values under the preserved key prefixes from the live etcd cluster in a single
+// transaction, so that all the prefixes are captured at the same revision, along with the leases attached to them.
+func (r *Restorer) capturePreservedKeys(ctx context.Context, ro brtypes.RestoreOptions) (*preservedKeys, error) {
+	etcdConnectionConfig := brtypes.NewEtcdConnectionConfig()
+	if ro.PreservedKeysConnectionConfig != nil {
+		*etcdConnectionConfig = *ro.PreservedKeysConnectionConfig
+	}
+	etcdConnectionConfig.Endpoints = ro.Config.PreservedKeysEndpoints
+	etcdConnectionConfig.MaxCallSendMsgSize = ro.Config.MaxCallSendMsgSize
+	clientKV, err := etcdutil.NewClientFactory(ro.NewClientFactory, *etcdConnectionConfig).NewKV()
+	if err != nil {
+		return nil, err
+	}
+	defer clientKV.Close()
+
+	ops := make([]clientv3.Op, 0, len(ro.Config.PreservedKeyPrefixes))
+	for _, prefix := range ro.Config.PreservedKeyPrefixes {
+		ops = append(ops, clientv3.OpGet(prefix, clientv3.WithPrefix()))
+	}
+	getCtx, cancel := context.WithTimeout(ctx, etcdConnectionTimeout)
+	defer cancel()
+	resp, err := clientKV.Txn(getCtx).Then(ops...).Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	preserved := &preservedKeys{leaseTTLs: map[int64]int64{}}
+	for i, opResp := range resp.Responses {
+		kvs := opResp.GetResponseRange().Kvs
+		r.logger.Infof("Captured %d keys with prefix %s from the live etcd cluster at revision %d.", len(kvs), ro.Config.PreservedKeyPrefixes[i], resp.Header.Revision)
+		preserved.kvs = append(preserved.kvs, kvs...)
+	}
+
+	for _, kv := range preserved.kvs {
+		if kv.Lease == 0 {
+			continue
+		}
+		if _, ok := preserved.leaseTTLs[kv.Lease]; ok {
+			continue
+		}
+		lessor, ok := clientKV.(clientv3.Lease)
+		if !ok {
+			return nil, fmt.Errorf("unable to capture the lease %x of key %s with the etcd client", kv.Lease, kv.Key)
+		}
+		ttlResp, err := lessor.TimeToLive(getCtx, clientv3.LeaseID(kv.Lease))
+		if err != nil {
+			return nil, fmt.Errorf("failed to capture the lease %x of key %s: %v", kv.Lease, kv.Key, err)
+		}
+		// a lease which has expired since the keys were captured is left out, along with its keys
+		if ttlResp.TTL > 0 {
+			preserved.leaseTTLs[kv.Lease] = ttlResp.TTL
+		}
+	}
+	return preserved, nil
+}
+
+// applyPreservedKeys replaces the restored keys under the preserved key prefixes with the given key-values captured
+// from the live etcd cluster, so that the preserved prefixes hold exactly the live values. The leases of the preserved
+// keys are granted with the same IDs and the remaining TTLs they have in the live etcd cluster.
+func (r *Restorer) applyPreservedKeys(ctx context.Context, clientFactory client.Factory, prefixes []string, preserved *preservedKeys, maxTxnOps int) error {
+	clientKV, err := clientFactory.NewKV()
+	if err != nil {
+		return err
+	}
+	defer clientKV.Close()
+
+	for _, prefix := range prefixes {
+		if _, err := clientKV.Delete(ctx, prefix, clientv3.WithPrefix()); err != nil {
+			return err
+		}
+	}
+	if err := grantPreservedLeases(ctx, clientKV, preserved.leaseTTLs); err != nil {
+		return err
+	}
+
+	ops := []clientv3.Op{}
+	for _, kv := range preserved.kvs {
+		var opts []clientv3.OpOption
+		if kv.Lease != 0 {
+			if _, ok := preserved.leaseTTLs[kv.Lease]; !ok {
+				r.logger.Infof("Skipping preserved key %s, whose lease %x has expired in the live etcd cluster.", kv.Key, kv.Lease)
+				continue
+			}
+			opts = append(opts, clientv3.WithLease(clientv3.LeaseID(kv.Lease)))
+		}
+		ops = append(ops, clientv3.OpPut(string(kv.Key), string(kv.Value), opts...))
+		if len(ops) == maxTxnOps {
+			if _, err := clientKV.Txn(ctx).Then(ops...).Commit(); err != nil {
+				return err
+			}
+			ops = []clientv3.Op{}
+		}
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+	_, err = clientKV.Txn(ctx).Then(ops...).Commit()
+	return err
+}
+
+// grantPreservedLeases grants the leases with the given IDs and TTLs, unless they have been restored along with the
+// snapshots. The leases are granted with the lease API of etcd, as the etcd client doesn't allow choosing their IDs.
+func grantPreservedLeases(ctx context.Context, clientKV client.KVCloser, leaseTTLs map[int64]int64) error {
+	if len(leaseTTLs) == 0 {
+		return nil
+	}
+	cli, ok := clientKV.(*clientv3.Client)
+	if !ok {
+		return fmt.Errorf("unable to grant the leases of the preserved keys with the etcd client")
+	}
+	leaseClient := clientv3.RetryLeaseClient(cli)
+	for id, ttl := range leaseTTLs {
+		if _, err := leaseClient.LeaseGrant(ctx, &etcdserverpb.LeaseGrantRequest{ID: id, TTL: ttl}); err != nil && rpctypes.Error(err) != rpctypes.ErrLeaseExist {
+			return fmt.Errorf("failed to grant the lease %x of the preserved keys: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// ExportBaseSnapshot fetches the given base snapshot from the snapstore, decompresses it if required, and streams
+// the etcd db bytes to the given writer without starting an embedded etcd. The output can be piped into external
+// tools such as `etcdutl snapshot restore`.
+func ExportBaseSnapshot(store brtypes.SnapStore, snap *brtypes.Snapshot, w io.Writer) error {
+	if snap == nil {
+		return fmt.Errorf("base snapshot not provided")
+	}
+	if snap.Kind != brtypes.SnapshotKindFull {
+		return fmt.Errorf("snapshot %s is not a full snapshot", path.Join(snap.SnapDir, snap.SnapName))
+	}
+
+	rc, err := store.Fetch(*snap)
+	if err != nil {
+		return fmt.Errorf("failed to fetch base snapshot %s from store: %v", snap.SnapName, err)
+	}
+	defer rc.Close()
+
+	// the decompressing reader is closed along with the fetched one
+	data, _, _, err := getNormalizedSnapshotReadCloser(rc, snap, snapstore.NewCompressionDictionaryFetcher(store))
+	if err != nil {
+		return fmt.Errorf("failed to decompress base snapshot %s: %v", snap.SnapName, err)
+	}
+	defer data.Close()
+
+	if _, err := io.Copy(w, data); err != nil {
+		return fmt.Errorf("failed to export base snapshot %s: %v", snap.SnapName, err)
+	}
+	return nil
 }
 
 // restoreFromBaseSnapshot restore the etcd data directory from base snapshot.
-func (r *Restorer) restoreFromBaseSnapshot(ro brtypes.RestoreOptions) error {
-	var err error
+func (r *Restorer) restoreFromBaseSnapshot(ctx context.Context, ro brtypes.RestoreOptions) (err error) {
+	ctx, span := tracing.Start(ctx, tracing.SpanRestoreBaseSnapshot, tracing.SnapshotAttributes(ro.BaseSnapshot)...)
+	defer func() {
+		tracing.End(span, err)
+	}()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if path.Join(ro.BaseSnapshot.SnapDir, ro.BaseSnapshot.SnapName) == "" {
 		r.logger.Warnf("Base snapshot path not provided. Will do nothing.")
 		return nil
@@ -175,14 +706,19 @@ func (r *Restorer) restoreFromBaseSnapshot(ro brtypes.RestoreOptions) error {
 
 	walDir := filepath.Join(memberDir, "wal")
 	snapDir := filepath.Join(memberDir, "snap")
-	if err = r.makeDB(snapDir, ro.BaseSnapshot, len(cl.Members()), ro.Config.SkipHashCheck); err != nil {
+	if isKeyExportRestoration(ro) {
+		err = r.makeKeyPrefixDB(snapDir, ro.BaseSnapshot, len(cl.Members()), ro.Config)
+	} else {
+		err = r.makeDB(ctx, snapDir, ro.BaseSnapshot, len(cl.Members()), ro.Config)
+	}
+	if err != nil {
 		return err
 	}
 	return makeWALAndSnap(r.zapLogger, walDir, snapDir, cl, ro.Config.Name)
 }
 
 // makeDB copies the database snapshot to the snapshot directory.
-func (r *Restorer) makeDB(snapDir string, snap *brtypes.Snapshot, commit int, skipHashCheck bool) error {
+func (r *Restorer) makeDB(ctx context.Context, snapDir string, snap *brtypes.Snapshot, commit int, config *brtypes.RestorationConfig) error {
 	rc, err := r.store.Fetch(*snap)
 	if err != nil {
 		return err
@@ -190,16 +726,9 @@ func (r *Restorer) makeDB(snapDir string, snap *brtypes.Snapshot, commit int, sk
 	defer rc.Close()
 
 	startTime := time.Now()
-	isCompressed, compressionPolicy, err := compressor.IsSnapshotCompressed(snap.CompressionSuffix)
+	rc, isCompressed, compressionPolicy, err := getNormalizedSnapshotReadCloser(rc, snap, r.fetchDictionary)
 	if err != nil {
-		return err
-	}
-	if isCompressed {
-		// decompress the snapshot
-		rc, err = compressor.DecompressSnapshot(rc, compressionPolicy)
-		if err != nil {
-			return fmt.Errorf("unable to decompress the snapshot: %v", err)
-		}
+		return fmt.Errorf("failed to decompress base snapshot %s : %v", snap.SnapName, err)
 	}
 
 	if err := os.MkdirAll(snapDir, 0700); err != nil {
@@ -211,11 +740,23 @@ func (r *Restorer) makeDB(snapDir string, snap *brtypes.Snapshot, commit int, sk
 	if err != nil {
 		return err
 	}
-	if _, err := io.Copy(db, rc); err != nil {
-		return err
-	}
 
-	if err := db.Sync(); err != nil {
+	// stop copying the base snapshot as soon as the restoration is aborted
+	cr := &contextReader{ctx: ctx, r: rc}
+	if config.StreamBaseSnapshot {
+		r.logger.Info("Streaming base snapshot into the data directory")
+		err = streamDBAndVerifyHash(db, cr, config.SkipHashCheck)
+		var readErr *streamReadError
+		if errors.As(err, &readErr) && ctx.Err() == nil {
+			// the stream can't be resumed, hence the base snapshot is fetched anew into the db file before verifying it
+			r.logger.Warnf("Failed to stream base snapshot %s, falling back to fetching it into the data directory before verifying it: %v", snap.SnapName, err)
+			err = r.refetchDBAndVerifyHash(ctx, db, snap, config.SkipHashCheck)
+		}
+	} else {
+		err = copyDBAndVerifyHash(db, cr, config.SkipHashCheck)
+	}
+	if err != nil {
+		db.Close()
 		return err
 	}
 
@@ -227,6 +768,112 @@ func (r *Restorer) makeDB(snapDir string, snap *brtypes.Snapshot, commit int, sk
 		r.logger.Infof("successfully fetched data of base snapshot in %v seconds", totalTime)
 	}
 
+	// db hash is OK
+	if err := db.Close(); err != nil {
+		return err
+	}
+
+	// update consistentIndex so applies go through on etcdserver despite
+	// having a new raft instance
+	be := backend.New(miscellaneous.NewBackendConfig(dbPath, config))
+	// a lessor that never times out leases
+	lessor := lease.NewLessor(r.zapLogger, be, lease.LessorConfig{MinLeaseTTL: math.MaxInt64})
+	s := mvcc.NewStore(r.zapLogger, be, lessor, (*brtypes.InitIndex)(&commit), mvcc.StoreConfig{})
+	trace := traceutil.New("write", r.zapLogger)
+
+	txn := s.Write(trace)
+	btx := be.BatchTx()
+	del := func(k, v []byte) error {
+		txn.DeleteRange(k, nil)
+		return nil
+	}
+
+	// delete stored members from old cluster since using new members
+	if err := btx.UnsafeForEach([]byte("members"), del); err != nil {
+		return err
+	}
+
+	// todo: add back new members when we start to deprecate old snap file.
+	if err := btx.UnsafeForEach([]byte("members_removed"), del); err != nil {
+		return err
+	}
+
+	// trigger write-out of new consistent index
+	txn.End()
+	s.Commit()
+
+	if err := s.Close(); err != nil {
+		return err
+	}
+
+	if err := be.Close(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// makeKeyPrefixDB creates the database in the snapshot directory from a base snapshot which was taken as an export of
+// the keys under the key prefix of the given restoration config, or of the whole keyspace if it is empty. The keys are written afresh, so their revisions do not
+// match the revisions at which they were exported.
+func (r *Restorer) makeKeyPrefixDB(snapDir string, snap *brtypes.Snapshot, commit int, config *brtypes.RestorationConfig) error {
+	keyPrefix := config.RestoreKeyPrefix
+	rc, err := r.store.Fetch(*snap)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	data, err := r.readSnapshotContentsFromReadCloser(rc, snap)
+	if err != nil {
+		return fmt.Errorf("failed to read the exported keys from base snapshot %s : %v", snap.SnapName, err)
+	}
+	var events []brtypes.Event
+	if err := json.Unmarshal(data, &events); err != nil {
+		return fmt.Errorf("base snapshot %s is not an export of the keys under %s: %v", snap.SnapName, keyPrefix, err)
+	}
+
+	if err := os.MkdirAll(snapDir, 0700); err != nil {
+		return err
+	}
+	be := backend.New(miscellaneous.NewBackendConfig(filepath.Join(snapDir, "db"), config))
+	// a lessor that never times out leases
+	lessor := lease.NewLessor(r.zapLogger, be, lease.LessorConfig{MinLeaseTTL: math.MaxInt64})
+	s := mvcc.NewStore(r.zapLogger, be, lessor, (*brtypes.InitIndex)(&commit), mvcc.StoreConfig{})
+
+	txn := s.Write(traceutil.New("write", r.zapLogger))
+	for _, event := range events {
+		kv := event.EtcdEvent.Kv
+		if !strings.HasPrefix(string(kv.Key), keyPrefix) {
+			txn.End()
+			s.Close()
+			be.Close()
+			return fmt.Errorf("key %s of base snapshot %s is not under the key prefix %s", kv.Key, snap.SnapName, keyPrefix)
+		}
+		txn.Put(kv.Key, kv.Value, lease.NoLease)
+	}
+	// trigger write-out of the keys and the consistent index
+	txn.End()
+	s.Commit()
+	r.logger.Infof("Restored %d keys under %s from base snapshot %s", len(events), keyPrefix, snap.SnapName)
+
+	if err := s.Close(); err != nil {
+		return err
+	}
+	return be.Close()
+}
+
+// copyDBAndVerifyHash copies the database snapshot to the db file, and then verifies and
+// truncates away the integrity hash by re-reading the db file from disk.
+func copyDBAndVerifyHash(db *os.File, rc io.Reader, skipHashCheck bool) error {
+	if _, err := io.Copy(db, rc); err != nil {
+		return err
+	}
+
+	if err := db.Sync(); err != nil {
+		return err
+	}
+
 	off, err := db.Seek(0, io.SeekEnd)
 	if err != nil {
 		return err
@@ -268,50 +915,130 @@ func (r *Restorer) makeDB(snapDir string, snap *brtypes.Snapshot, commit int, sk
 			}
 		}
 	}
+	return nil
+}
 
-	// db hash is OK
-	if err := db.Close(); err != nil {
+// refetchDBAndVerifyHash fetches the base snapshot anew into the db file, which is truncated first, and then verifies
+// it like copyDBAndVerifyHash.
+func (r *Restorer) refetchDBAndVerifyHash(ctx context.Context, db *os.File, snap *brtypes.Snapshot, skipHashCheck bool) error {
+	if err := db.Truncate(0); err != nil {
 		return err
 	}
-
-	// update consistentIndex so applies go through on etcdserver despite
-	// having a new raft instance
-	be := backend.NewDefaultBackend(dbPath)
-	// a lessor that never times out leases
-	lessor := lease.NewLessor(r.zapLogger, be, lease.LessorConfig{MinLeaseTTL: math.MaxInt64})
-	s := mvcc.NewStore(r.zapLogger, be, lessor, (*brtypes.InitIndex)(&commit), mvcc.StoreConfig{})
-	trace := traceutil.New("write", r.zapLogger)
-
-	txn := s.Write(trace)
-	btx := be.BatchTx()
-	del := func(k, v []byte) error {
-		txn.DeleteRange(k, nil)
-		return nil
+	if _, err := db.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	rc, err := r.store.Fetch(*snap)
+	if err != nil {
+		return fmt.Errorf("failed to fetch base snapshot %s from store: %v", snap.SnapName, err)
 	}
+	defer rc.Close()
+	data, _, _, err := getNormalizedSnapshotReadCloser(rc, snap, r.fetchDictionary)
+	if err != nil {
+		return fmt.Errorf("failed to decompress base snapshot %s : %v", snap.SnapName, err)
+	}
+	defer data.Close()
+	return copyDBAndVerifyHash(db, &contextReader{ctx: ctx, r: data}, skipHashCheck)
+}
 
-	// delete stored members from old cluster since using new members
-	if err := btx.UnsafeForEach([]byte("members"), del); err != nil {
+// streamDBAndVerifyHash streams the database snapshot to the db file in a single pass. The
+// integrity hash is computed while writing and the trailing hash is never written to disk,
+// so the db file neither has to be re-read nor truncated. An error reading the database
+// snapshot is returned as a streamReadError.
+func streamDBAndVerifyHash(db *os.File, rc io.Reader, skipHashCheck bool) error {
+	w := &trailingHashWriter{
+		w:    db,
+		hash: sha256.New(),
+	}
+	if _, err := io.Copy(w, &streamReader{r: rc}); err != nil {
 		return err
 	}
 
-	// todo: add back new members when we start to deprecate old snap file.
-	if err := btx.UnsafeForEach([]byte("members_removed"), del); err != nil {
-		return err
+	hasHash := (w.written+int64(len(w.tail)))%512 == sha256.Size
+	if !hasHash {
+		if !skipHashCheck {
+			return fmt.Errorf("snapshot missing hash but --skip-hash-check=false")
+		}
+		// the held back bytes are part of the db in absence of an integrity hash
+		if _, err := db.Write(w.tail); err != nil {
+			return err
+		}
+	} else if !skipHashCheck {
+		// check for match
+		dbSha := w.hash.Sum(nil)
+		if !reflect.DeepEqual(w.tail, dbSha) {
+			return fmt.Errorf("expected sha256 %v, got %v", w.tail, dbSha)
+		}
 	}
 
-	// trigger write-out of new consistent index
-	txn.End()
-	s.Commit()
+	return db.Sync()
+}
 
-	if err := s.Close(); err != nil {
-		return err
+// streamReadError is the error of reading the database snapshot while streaming it.
+type streamReadError struct {
+	err error
+}
+
+func (e *streamReadError) Error() string {
+	return fmt.Sprintf("failed to read the streamed base snapshot: %v", e.err)
+}
+
+func (e *streamReadError) Unwrap() error {
+	return e.err
+}
+
+// streamReader is an io.Reader which returns the errors of the underlying reader as a streamReadError.
+type streamReader struct {
+	r io.Reader
+}
+
+// Read implements io.Reader.
+func (s *streamReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = &streamReadError{err: err}
 	}
+	return n, err
+}
 
-	if err := be.Close(); err != nil {
-		return err
+// trailingHashWriter writes everything but the last sha256.Size bytes of the stream to the
+// underlying writer, and computes the hash of the written data along the way.
+type trailingHashWriter struct {
+	w       io.Writer
+	hash    hash.Hash
+	tail    []byte
+	written int64
+}
+
+// Write implements io.Writer.
+func (t *trailingHashWriter) Write(p []byte) (int, error) {
+	t.tail = append(t.tail, p...)
+	if len(t.tail) <= sha256.Size {
+		return len(p), nil
 	}
 
-	return nil
+	n := len(t.tail) - sha256.Size
+	if _, err := t.w.Write(t.tail[:n]); err != nil {
+		return 0, err
+	}
+	if _, err := t.hash.Write(t.tail[:n]); err != nil {
+		return 0, err
+	}
+	t.written += int64(n)
+	t.tail = t.tail[:copy(t.tail, t.tail[n:])]
+	return len(p), nil
+}
+
+// contextReader is an io.Reader which fails with the context error once the context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
 }
 
 func makeWALAndSnap(logger *zap.Logger, walDir, snapDir string, cl *membership.RaftCluster, restoreName string) error {
@@ -402,7 +1129,15 @@ func makeWALAndSnap(logger *zap.Logger, walDir, snapDir string, cl *membership.R
 }
 
 // applyDeltaSnapshots fetches the events from delta snapshots in parallel and applies them to the embedded etcd sequentially.
-func (r *Restorer) applyDeltaSnapshots(clientFactory client.Factory, endPoints []string, ro brtypes.RestoreOptions) error {
+func (r *Restorer) applyDeltaSnapshots(ctx context.Context, clientFactory client.Factory, endPoints []string, ro brtypes.RestoreOptions) (err error) {
+	ctx, span := tracing.Start(ctx, tracing.SpanApplyDeltaSnapshots,
+		tracing.Int64(tracing.AttributeSnapshotCount, int64(len(ro.DeltaSnapList))),
+		tracing.Int64(tracing.AttributeStartRevision, ro.DeltaSnapList[0].StartRevision),
+		tracing.Int64(tracing.AttributeLastRevision, ro.DeltaSnapList[len(ro.DeltaSnapList)-1].LastRevision),
+	)
+	defer func() {
+		tracing.End(span, err)
+	}()
 
 	clientKV, err := clientFactory.NewKV()
 	if err != nil {
@@ -429,14 +1164,24 @@ func (r *Restorer) applyDeltaSnapshots(clientFactory client.Factory, endPoints [
 
 	firstDeltaSnap := snapList[0]
 
-	if err := r.applyFirstDeltaSnapshot(clientKV, firstDeltaSnap); err != nil {
+	// the revisions of a restoration from an export of the keys do not match the revisions of the delta snapshots,
+	// as the exported keys are written afresh, and neither do the revisions following a skipped delta snapshot.
+	verifyRevisions := !isKeyExportRestoration(ro) && len(ro.SkipDeltaRevisions) == 0
+	if err := r.applyFirstDeltaSnapshot(ctx, clientKV, firstDeltaSnap, ro); err != nil {
 		return err
 	}
 
 	embeddedEtcdQuotaBytes := float64(ro.Config.EmbeddedEtcdQuotaBytes)
 
-	if err := verifySnapshotRevision(clientKV, snapList[0]); err != nil {
-		return err
+	if verifyRevisions {
+		if err := verifySnapshotRevision(ctx, clientKV, snapList[0]); err != nil {
+			return err
+		}
+	}
+
+	cp := newCheckpointer(ro)
+	if err := cp.recordApplied(firstDeltaSnap); err != nil {
+		r.logger.Warnf("Failed to record the restoration checkpoint: %v", err)
 	}
 
 	// no more delta snapshots available
@@ -459,10 +1204,10 @@ func (r *Restorer) applyDeltaSnapshots(clientFactory client.Factory, endPoints [
 		dbSizeAlarmDisarmCh = make(chan bool)
 	)
 
-	go r.applySnaps(clientKV, clientMaintenance, remainingSnaps, dbSizeAlarmCh, dbSizeAlarmDisarmCh, applierInfoCh, errCh, stopCh, &wg, endPoints, embeddedEtcdQuotaBytes)
+	go r.applySnaps(ctx, clientKV, clientMaintenance, remainingSnaps, dbSizeAlarmCh, dbSizeAlarmDisarmCh, applierInfoCh, errCh, stopCh, &wg, endPoints, embeddedEtcdQuotaBytes, verifyRevisions, cp)
 
 	for f := 0; f < numFetchers; f++ {
-		go r.fetchSnaps(f, fetcherInfoCh, applierInfoCh, snapLocationsCh, errCh, stopCh, &wg, ro.Config.TempSnapshotsDir)
+		go r.fetchSnaps(f, fetcherInfoCh, applierInfoCh, snapLocationsCh, errCh, stopCh, &wg, ro.Config.TempSnapshotsDir, ro.Config.MaxNodeFetchers)
 	}
 
 	go r.HandleAlarm(stopHandleAlarmCh, dbSizeAlarmCh, dbSizeAlarmDisarmCh, clientMaintenance)
@@ -477,7 +1222,11 @@ func (r *Restorer) applyDeltaSnapshots(clientFactory client.Factory, endPoints [
 	}
 	close(fetcherInfoCh)
 
-	err = <-errCh
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
 
 	if cleanupErr := r.cleanup(snapLocationsCh, stopCh, &wg); cleanupErr != nil {
 		r.logger.Errorf("Cleanup of temporary snapshots failed: %v", cleanupErr)
@@ -523,8 +1272,9 @@ func (r *Restorer) cleanup(snapLocationsCh chan string, stopCh chan bool, wg *sy
 	return nil
 }
 
-// fetchSnaps fetches delta snapshots as events and persists them onto disk.
-func (r *Restorer) fetchSnaps(fetcherIndex int, fetcherInfoCh <-chan brtypes.FetcherInfo, applierInfoCh chan<- brtypes.ApplierInfo, snapLocationsCh chan<- string, errCh chan<- error, stopCh chan bool, wg *sync.WaitGroup, tempDir string) {
+// fetchSnaps fetches delta snapshots as events and persists them onto disk. Each delta snapshot is only fetched once
+// fewer than maxNodeFetchers delta snapshots are being fetched by all the restorations of the process.
+func (r *Restorer) fetchSnaps(fetcherIndex int, fetcherInfoCh <-chan brtypes.FetcherInfo, applierInfoCh chan<- brtypes.ApplierInfo, snapLocationsCh chan<- string, errCh chan<- error, stopCh chan bool, wg *sync.WaitGroup, tempDir string, maxNodeFetchers uint) {
 	defer wg.Done()
 	wg.Add(1)
 
@@ -535,18 +1285,24 @@ func (r *Restorer) fetchSnaps(fetcherIndex int, fetcherInfoCh <-chan brtypes.Fet
 				return
 			}
 		default:
+			nodeFetchers.acquire(maxNodeFetchers)
 			r.logger.Infof("Fetcher #%d fetching delta snapshot %s", fetcherIndex+1, path.Join(fetcherInfo.Snapshot.SnapDir, fetcherInfo.Snapshot.SnapName))
 
 			rc, err := r.store.Fetch(fetcherInfo.Snapshot)
 			if err != nil {
+				nodeFetchers.release()
 				errCh <- fmt.Errorf("failed to fetch delta snapshot %s from store : %v", fetcherInfo.Snapshot.SnapName, err)
 				applierInfoCh <- brtypes.ApplierInfo{SnapIndex: -1} // cannot use close(ch) as concurrent fetchSnaps routines might try to send on channel, causing a panic
+				continue
 			}
 
 			snapTempFilePath := filepath.Join(tempDir, fetcherInfo.Snapshot.SnapName)
-			if err = persistRawDeltaSnapshot(rc, snapTempFilePath); err != nil {
+			err = persistRawDeltaSnapshot(rc, snapTempFilePath)
+			nodeFetchers.release()
+			if err != nil {
 				errCh <- fmt.Errorf("failed to persist delta snapshot %s to temp file path %s : %v", fetcherInfo.Snapshot.SnapName, snapTempFilePath, err)
 				applierInfoCh <- brtypes.ApplierInfo{SnapIndex: -1}
+				continue
 			}
 
 			snapLocationsCh <- snapTempFilePath // used for cleanup later
@@ -561,7 +1317,8 @@ func (r *Restorer) fetchSnaps(fetcherIndex int, fetcherInfoCh <-chan brtypes.Fet
 }
 
 // applySnaps applies delta snapshot events to the embedded etcd sequentially, in the right order of snapshots, regardless of the order in which they were fetched.
-func (r *Restorer) applySnaps(clientKV client.KVCloser, clientMaintenance client.MaintenanceCloser, remainingSnaps brtypes.SnapList, dbSizeAlarmCh chan string, dbSizeAlarmDisarmCh <-chan bool, applierInfoCh <-chan brtypes.ApplierInfo, errCh chan<- error, stopCh <-chan bool, wg *sync.WaitGroup, endPoints []string, embeddedEtcdQuotaBytes float64) {
+// Each applied delta snapshot is recorded with the given checkpointer.
+func (r *Restorer) applySnaps(ctx context.Context, clientKV client.KVCloser, clientMaintenance client.MaintenanceCloser, remainingSnaps brtypes.SnapList, dbSizeAlarmCh chan string, dbSizeAlarmDisarmCh <-chan bool, applierInfoCh <-chan brtypes.ApplierInfo, errCh chan<- error, stopCh <-chan bool, wg *sync.WaitGroup, endPoints []string, embeddedEtcdQuotaBytes float64, verifyRevisions bool, cp *checkpointer) {
 	defer wg.Done()
 	wg.Add(1)
 
@@ -618,10 +1375,13 @@ func (r *Restorer) applySnaps(clientKV client.KVCloser, clientMaintenance client
 					}
 
 					r.logger.Infof("Applying delta snapshot %s [%d/%d]", path.Join(remainingSnaps[currSnapIndex].SnapDir, remainingSnaps[currSnapIndex].SnapName), currSnapIndex+2, len(remainingSnaps)+1)
-					if err := applyEventsAndVerify(clientKV, events, remainingSnaps[currSnapIndex]); err != nil {
+					if err := applyEventsAndVerify(ctx, clientKV, events, remainingSnaps[currSnapIndex], verifyRevisions); err != nil {
 						errCh <- err
 						return
 					}
+					if err := cp.recordApplied(remainingSnaps[currSnapIndex]); err != nil {
+						r.logger.Warnf("Failed to record the restoration checkpoint: %v", err)
+					}
 
 					r.logger.Infof("Removing temporary delta snapshot events file %s for snapshot %s", filePath, snapName)
 					if err = os.Remove(filePath); err != nil {
@@ -638,7 +1398,7 @@ func (r *Restorer) applySnaps(clientKV client.KVCloser, clientMaintenance client
 
 					if numberOfDeltaSnapApplied%periodicallyMakeEtcdLeanDeltaSnapshotInterval == 0 || prevAttemptToMakeEtcdLeanFailed {
 						r.logger.Info("making an embedded etcd lean and check for db size alarm")
-						if err := r.MakeEtcdLeanAndCheckAlarm(int64(remainingSnaps[currSnapIndex].LastRevision), endPoints, embeddedEtcdQuotaBytes, dbSizeAlarmCh, dbSizeAlarmDisarmCh, clientKV, clientMaintenance); err != nil {
+						if err := r.MakeEtcdLeanAndCheckAlarm(ctx, int64(remainingSnaps[currSnapIndex].LastRevision), endPoints, embeddedEtcdQuotaBytes, dbSizeAlarmCh, dbSizeAlarmDisarmCh, clientKV, clientMaintenance); err != nil {
 							r.logger.Errorf("unable to make embedded etcd lean: %v", err)
 							r.logger.Warn("etcd mvcc: database space might exceeds its quota limit")
 							r.logger.Info("backup-restore will try again in next attempt...")
@@ -656,28 +1416,34 @@ func (r *Restorer) applySnaps(clientKV client.KVCloser, clientMaintenance client
 	}
 }
 
-// applyEventsAndVerify applies events from one snapshot to the embedded etcd and verifies the correctness of the sequence of snapshot applied.
-func applyEventsAndVerify(clientKV client.KVCloser, events []brtypes.Event, snap *brtypes.Snapshot) error {
-	if err := applyEventsToEtcd(clientKV, events); err != nil {
+// applyEventsAndVerify applies events from one snapshot to the embedded etcd and, if requested, verifies the correctness of the sequence of snapshot applied.
+func applyEventsAndVerify(ctx context.Context, clientKV client.KVCloser, events []brtypes.Event, snap *brtypes.Snapshot, verifyRevision bool) error {
+	if err := applyEventsToEtcd(ctx, clientKV, eventsUpToRevision(events, snap.LastRevision)); err != nil {
 		return fmt.Errorf("failed to apply events to etcd for delta snapshot %s : %v", snap.SnapName, err)
 	}
 
-	if err := verifySnapshotRevision(clientKV, snap); err != nil {
+	if !verifyRevision {
+		return nil
+	}
+	if err := verifySnapshotRevision(ctx, clientKV, snap); err != nil {
 		return fmt.Errorf("snapshot revision verification failed for delta snapshot %s : %v", snap.SnapName, err)
 	}
 	return nil
 }
 
 // applyFirstDeltaSnapshot applies the events from first delta snapshot to etcd.
-func (r *Restorer) applyFirstDeltaSnapshot(clientKV client.KVCloser, snap *brtypes.Snapshot) error {
+func (r *Restorer) applyFirstDeltaSnapshot(ctx context.Context, clientKV client.KVCloser, snap *brtypes.Snapshot, ro brtypes.RestoreOptions) error {
 	r.logger.Infof("Applying first delta snapshot %s", path.Join(snap.SnapDir, snap.SnapName))
 
+	nodeFetchers.acquire(ro.Config.MaxNodeFetchers)
 	rc, err := r.store.Fetch(*snap)
 	if err != nil {
+		nodeFetchers.release()
 		return fmt.Errorf("failed to fetch delta snapshot %s from store : %v", snap.SnapName, err)
 	}
 
 	eventsData, err := r.readSnapshotContentsFromReadCloser(rc, snap)
+	nodeFetchers.release()
 	if err != nil {
 		return fmt.Errorf("failed to read events data from delta snapshot %s : %v", snap.SnapName, err)
 	}
@@ -692,13 +1458,20 @@ func (r *Restorer) applyFirstDeltaSnapshot(clientKV client.KVCloser, snap *brtyp
 	// the latest revision from full snapshot may overlap with first few revision on first delta snapshot
 	// Hence, we have to additionally take care of that.
 	// Refer: https://github.com/coreos/etcd/issues/9037
-	ctx, cancel := context.WithTimeout(context.TODO(), etcdConnectionTimeout)
-	defer cancel()
-	resp, err := clientKV.Get(ctx, "", clientv3.WithLastRev()...)
-	if err != nil {
-		return fmt.Errorf("failed to get etcd latest revision: %v", err)
+	var lastRevision int64
+	if isKeyExportRestoration(ro) {
+		// the revisions of a restoration from an export of the keys do not match the revisions of the events, but the
+		// keys of its base snapshot were exported atomically at the revision of the base snapshot.
+		lastRevision = ro.BaseSnapshot.LastRevision
+	} else {
+		getCtx, cancel := context.WithTimeout(ctx, etcdConnectionTimeout)
+		defer cancel()
+		resp, err := clientKV.Get(getCtx, "", clientv3.WithLastRev()...)
+		if err != nil {
+			return fmt.Errorf("failed to get etcd latest revision: %v", err)
+		}
+		lastRevision = resp.Header.Revision
 	}
-	lastRevision := resp.Header.Revision
 
 	var newRevisionIndex int
 	for index, event := range events {
@@ -710,7 +1483,7 @@ func (r *Restorer) applyFirstDeltaSnapshot(clientKV client.KVCloser, snap *brtyp
 
 	r.logger.Infof("Applying first delta snapshot %s", path.Join(snap.SnapDir, snap.SnapName))
 
-	return applyEventsToEtcd(clientKV, events[newRevisionIndex:])
+	return applyEventsToEtcd(ctx, clientKV, eventsUpToRevision(events[newRevisionIndex:], snap.LastRevision))
 }
 
 // getEventsFromDeltaSnapshot returns the events from delta snapshot from snap store.
@@ -734,24 +1507,16 @@ func (r *Restorer) getEventsDataFromDeltaSnapshot(snap brtypes.Snapshot) ([]byte
 	if err != nil {
 		return nil, err
 	}
+	defer rc.Close()
 
 	startTime := time.Now()
-	isCompressed, compressionPolicy, err := compressor.IsSnapshotCompressed(snap.CompressionSuffix)
+	rc, isCompressed, compressionPolicy, err := getNormalizedSnapshotReadCloser(rc, &snap, r.fetchDictionary)
 	if err != nil {
-		return nil, err
-	}
-	if isCompressed {
-		// decompress the snapshot
-		rc, err = compressor.DecompressSnapshot(rc, compressionPolicy)
-		if err != nil {
-			return nil, fmt.Errorf("unable to decompress the snapshot: %v", err)
-		}
+		return nil, fmt.Errorf("failed to decompress delta snapshot %s : %v", snap.SnapName, err)
 	}
-	defer rc.Close()
 
 	buf := new(bytes.Buffer)
-	bufSize, err := buf.ReadFrom(rc)
-	if err != nil {
+	if _, err := buf.ReadFrom(rc); err != nil {
 		return nil, err
 	}
 	totalTime := time.Now().Sub(startTime).Seconds()
@@ -761,25 +1526,12 @@ func (r *Restorer) getEventsDataFromDeltaSnapshot(snap brtypes.Snapshot) ([]byte
 	} else {
 		r.logger.Infof("successfully fetched data of delta snapshot in %v seconds", totalTime)
 	}
-	sha := buf.Bytes()
-
-	if bufSize <= sha256.Size {
-		return nil, fmt.Errorf("delta snapshot is missing hash")
-	}
-	data := sha[:bufSize-sha256.Size]
-	snapHash := sha[bufSize-sha256.Size:]
-
-	// check for match
-	h := sha256.New()
-	if _, err := h.Write(data); err != nil {
+	// check for match, with the hash algorithm recorded in the delta snapshot
+	data, _, err := brtypes.VerifyDeltaSnapshotHash(buf.Bytes())
+	if err != nil {
 		return nil, err
 	}
 
-	computedSha := h.Sum(nil)
-	if !reflect.DeepEqual(snapHash, computedSha) {
-		return nil, fmt.Errorf("expected sha256 %v, got %v", snapHash, computedSha)
-	}
-
 	return data, nil
 }
 
@@ -802,11 +1554,10 @@ func persistRawDeltaSnapshot(rc io.ReadCloser, tempFilePath string) error {
 }
 
 // applyEventsToEtcd performs operations in events sequentially.
-func applyEventsToEtcd(clientKV client.KVCloser, events []brtypes.Event) error {
+func applyEventsToEtcd(ctx context.Context, clientKV client.KVCloser, events []brtypes.Event) error {
 	var (
 		lastRev int64
 		ops     = []clientv3.Op{}
-		ctx     = context.TODO()
 	)
 
 	for _, e := range events {
@@ -833,8 +1584,7 @@ func applyEventsToEtcd(clientKV client.KVCloser, events []brtypes.Event) error {
 	return err
 }
 
-func verifySnapshotRevision(clientKV client.KVCloser, snap *brtypes.Snapshot) error {
-	ctx := context.TODO()
+func verifySnapshotRevision(ctx context.Context, clientKV client.KVCloser, snap *brtypes.Snapshot) error {
 	getResponse, err := clientKV.Get(ctx, "foo")
 	if err != nil {
 		return fmt.Errorf("failed to connect to etcd KV client: %v", err)
@@ -851,15 +1601,18 @@ func verifySnapshotRevision(clientKV client.KVCloser, snap *brtypes.Snapshot) er
 // If snapshot is not compressed, it returns the given ReadCloser as is.
 // It also returns whether the snapshot was initially compressed or not, as well as
 // the compression policy used for compressing the snapshot.
-func getNormalizedSnapshotReadCloser(rc io.ReadCloser, snap *brtypes.Snapshot) (io.ReadCloser, bool, string, error) {
+// The compression policy is determined by the compression suffix of the given snapshot alone,
+// since the snapshots of a single chain may have been taken with different compression policies.
+// The dictionary of a snapshot compressed with a dictionary is fetched with the given fetcher.
+func getNormalizedSnapshotReadCloser(rc io.ReadCloser, snap *brtypes.Snapshot, fetchDictionary compressor.DictionaryFetcher) (io.ReadCloser, bool, string, error) {
 	isCompressed, compressionPolicy, err := compressor.IsSnapshotCompressed(snap.CompressionSuffix)
 	if err != nil {
-		return rc, false, "", err
+		return rc, false, "", fmt.Errorf("unable to determine the compression policy from the compression suffix %q: %v", snap.CompressionSuffix, err)
 	}
 
 	if isCompressed {
 		// decompress the snapshot
-		rc, err = compressor.DecompressSnapshot(rc, compressionPolicy)
+		rc, err = compressor.DecompressSnapshotWithDictionary(rc, compressionPolicy, fetchDictionary)
 		if err != nil {
 			return rc, true, compressionPolicy, fmt.Errorf("unable to decompress the snapshot: %v", err)
 		}
@@ -871,14 +1624,13 @@ func getNormalizedSnapshotReadCloser(rc io.ReadCloser, snap *brtypes.Snapshot) (
 func (r *Restorer) readSnapshotContentsFromReadCloser(rc io.ReadCloser, snap *brtypes.Snapshot) ([]byte, error) {
 	startTime := time.Now()
 
-	rc, wasCompressed, compressionPolicy, err := getNormalizedSnapshotReadCloser(rc, snap)
+	rc, wasCompressed, compressionPolicy, err := getNormalizedSnapshotReadCloser(rc, snap, r.fetchDictionary)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decompress delta snapshot %s : %v", snap.SnapName, err)
 	}
 
 	buf := new(bytes.Buffer)
-	bufSize, err := buf.ReadFrom(rc)
-	if err != nil {
+	if _, err := buf.ReadFrom(rc); err != nil {
 		return nil, fmt.Errorf("failed to parse contents from delta snapshot %s : %v", snap.SnapName, err)
 	}
 
@@ -889,23 +1641,10 @@ func (r *Restorer) readSnapshotContentsFromReadCloser(rc io.ReadCloser, snap *br
 		r.logger.Infof("successfully read the data of delta snapshot in %v seconds", totalTime)
 	}
 
-	if bufSize <= sha256.Size {
-		return nil, fmt.Errorf("delta snapshot is missing hash")
-	}
-
-	sha := buf.Bytes()
-	data := sha[:bufSize-sha256.Size]
-	snapHash := sha[bufSize-sha256.Size:]
-
-	// check for match
-	h := sha256.New()
-	if _, err := h.Write(data); err != nil {
-		return nil, fmt.Errorf("unable to check integrity of snapshot %s: %v", snap.SnapName, err)
-	}
-
-	computedSha := h.Sum(nil)
-	if !reflect.DeepEqual(snapHash, computedSha) {
-		return nil, fmt.Errorf("expected sha256 %v, got %v", snapHash, computedSha)
+	// check for match, with the hash algorithm recorded in the delta snapshot
+	data, _, err := brtypes.VerifyDeltaSnapshotHash(buf.Bytes())
+	if err != nil {
+		return nil, err
 	}
 
 	return data, nil
@@ -966,20 +1705,21 @@ func (r *Restorer) HandleAlarm(stopHandleAlarmCh chan bool, dbSizeAlarmCh <-chan
 }
 
 // MakeEtcdLeanAndCheckAlarm calls etcd compaction on given revision number and raise db size alarm if embedded etcd db size crosses threshold.
-func (r *Restorer) MakeEtcdLeanAndCheckAlarm(revision int64, endPoints []string, embeddedEtcdQuotaBytes float64, dbSizeAlarmCh chan string, dbSizeAlarmDisarmCh <-chan bool, clientKV client.KVCloser, clientMaintenance client.MaintenanceCloser) error {
+// The compaction is aborted if the given context is cancelled.
+func (r *Restorer) MakeEtcdLeanAndCheckAlarm(ctx context.Context, revision int64, endPoints []string, embeddedEtcdQuotaBytes float64, dbSizeAlarmCh chan string, dbSizeAlarmDisarmCh <-chan bool, clientKV client.KVCloser, clientMaintenance client.MaintenanceCloser) error {
 
-	ctx, cancel := context.WithTimeout(context.Background(), etcdCompactTimeout)
+	compactCtx, cancel := context.WithTimeout(ctx, etcdCompactTimeout)
 	defer cancel()
-	if _, err := clientKV.Compact(ctx, revision, clientv3.WithCompactPhysical()); err != nil {
+	if _, err := clientKV.Compact(compactCtx, revision, clientv3.WithCompactPhysical()); err != nil {
 		return fmt.Errorf("Compact API call failed: %v", err)
 	}
 	r.logger.Infof("Successfully compacted embedded etcd till revision: %v", revision)
 
-	ctx, cancel = context.WithTimeout(context.Background(), etcdConnectionTimeout)
+	statusCtx, cancel := context.WithTimeout(ctx, etcdConnectionTimeout)
 	defer cancel()
 
 	// check database size of embedded etcdServer.
-	status, err := clientMaintenance.Status(ctx, endPoints[0])
+	status, err := clientMaintenance.Status(statusCtx, endPoints[0])
 	if err != nil {
 		return fmt.Errorf("unable to check embedded etcd status: %v", err)
 	}
@@ -995,9 +1735,9 @@ func (r *Restorer) MakeEtcdLeanAndCheckAlarm(revision int64, endPoints []string,
 
 			if <-dbSizeAlarmDisarmCh {
 				r.logger.Info("Successfully disalarm the embedded etcd dbSize alarm")
-				ctx, cancel := context.WithTimeout(context.Background(), etcdConnectionTimeout)
+				statusCtx, cancel := context.WithTimeout(ctx, etcdConnectionTimeout)
 				defer cancel()
-				if afterDefragStatus, err := clientMaintenance.Status(ctx, endPoint); err != nil {
+				if afterDefragStatus, err := clientMaintenance.Status(statusCtx, endPoint); err != nil {
 					r.logger.Warnf("failed to get status of embedded etcd with error: %v", err)
 				} else {
 					dbSizeBeforeDefrag := status.DbSize
@@ -5,7 +5,11 @@
 package restorer_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -16,14 +20,20 @@ import (
 	"time"
 
 	"github.com/gardener/etcd-backup-restore/pkg/compressor"
+	"github.com/gardener/etcd-backup-restore/pkg/etcdutil"
 	"github.com/gardener/etcd-backup-restore/pkg/miscellaneous"
 	mockfactory "github.com/gardener/etcd-backup-restore/pkg/mock/etcdutil/client"
+	"github.com/gardener/etcd-backup-restore/pkg/snapshot/snapshotter"
 	"github.com/gardener/etcd-backup-restore/pkg/snapstore"
+	"github.com/gardener/etcd-backup-restore/pkg/tracing"
 	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
 	"github.com/gardener/etcd-backup-restore/test/utils"
 	"github.com/golang/mock/gomock"
 	"github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
 	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/embed"
+	"go.etcd.io/etcd/mvcc/mvccpb"
 	"go.etcd.io/etcd/pkg/types"
 
 	. "github.com/gardener/etcd-backup-restore/pkg/snapshot/restorer"
@@ -133,7 +143,7 @@ var _ = Describe("Running Restorer", func() {
 				restoreOpts.Config.InitialAdvertisePeerURLs = []string{"http://localhost:2390"}
 				restoreOpts.ClusterURLs, err = types.NewURLsMap(restoreOpts.Config.InitialCluster)
 
-				err = restorer.RestoreAndStopEtcd(restoreOpts, nil)
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
 				Expect(err).Should(HaveOccurred())
 			})
 		})
@@ -142,7 +152,7 @@ var _ = Describe("Running Restorer", func() {
 			It("should fail to restore", func() {
 				restoreOpts.Config.DataDir = ""
 
-				err = restorer.RestoreAndStopEtcd(restoreOpts, nil)
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
 				Expect(err).Should(HaveOccurred())
 			})
 		})
@@ -152,7 +162,7 @@ var _ = Describe("Running Restorer", func() {
 				restoreOpts.BaseSnapshot.SnapDir = "test"
 				restoreOpts.BaseSnapshot.SnapName = "test"
 
-				err := restorer.RestoreAndStopEtcd(restoreOpts, nil)
+				err := restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
 				Expect(err).Should(HaveOccurred())
 			})
 		})
@@ -178,7 +188,7 @@ var _ = Describe("Running Restorer", func() {
 		Context("with maximum of one fetcher allowed", func() {
 			It("should restore etcd data directory", func() {
 				restoreOpts.Config.MaxFetchers = 1
-				err = restorer.RestoreAndStopEtcd(restoreOpts, nil)
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
 				Expect(err).ShouldNot(HaveOccurred())
 
 				err = utils.CheckDataConsistency(testCtx, restoreOpts.Config.DataDir, keyTo, logger)
@@ -186,11 +196,108 @@ var _ = Describe("Running Restorer", func() {
 			})
 		})
 
+		Context("with the restored member joining an existing cluster", func() {
+			It("should restore etcd data directory", func() {
+				restoreOpts.InitialClusterState = miscellaneous.ClusterStateExisting
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				err = utils.CheckDataConsistency(testCtx, restoreOpts.Config.DataDir, keyTo, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+		})
+
+		Context("with the cluster metadata recorded along with the base snapshot", func() {
+			var metadata *brtypes.ClusterMetadata
+
+			BeforeEach(func() {
+				// the cluster the snapshots were taken from is the one to rebuild
+				metadata = &brtypes.ClusterMetadata{ClusterID: uint64(etcd.Server.Cluster().ID())}
+				for _, m := range etcd.Server.Cluster().Members() {
+					metadata.Members = append(metadata.Members, brtypes.ClusterMember{ID: uint64(m.ID), Name: m.Name, PeerURLs: m.PeerURLs, ClientURLs: m.ClientURLs})
+				}
+				Expect(snapstore.SaveClusterMetadata(store, baseSnapshot, metadata)).To(Succeed())
+				DeferCleanup(os.Remove, filepath.Join(baseSnapshot.Prefix, baseSnapshot.SnapDir, baseSnapshot.SnapName+brtypes.ClusterMetadataSuffix))
+				Expect(snapstore.FetchClusterMetadata(store, baseSnapshot)).To(Equal(metadata))
+
+				// the member is rebuilt with other peer URLs than the ones it had in the recorded cluster
+				restoreOpts.Config.UseClusterMetadata = true
+				restoreOpts.Config.InitialCluster = "default=http://localhost:2390"
+				restoreOpts.ClusterURLs, err = types.NewURLsMap(restoreOpts.Config.InitialCluster)
+				Expect(err).ShouldNot(HaveOccurred())
+				restoreOpts.PeerURLs, err = types.NewURLs([]string{"http://localhost:2390"})
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+
+			It("should restore the member with its recorded ID and peer URLs", func() {
+				Expect(restoreOpts.DeltaSnapList).NotTo(BeEmpty())
+				e, err := restorer.Restore(testCtx, restoreOpts, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer func() {
+					e.Server.Stop()
+					e.Close()
+				}()
+
+				recorded := metadata.Member(restoreName)
+				Expect(recorded).NotTo(BeNil())
+				Expect(uint64(e.Server.ID())).To(Equal(recorded.ID))
+				Expect(e.Server.Cluster().Members()).To(HaveLen(1))
+				Expect(e.Server.Cluster().Members()[0].PeerURLs).To(Equal(recorded.PeerURLs))
+			})
+
+			It("should refuse to restore the member into another cluster than the one the base snapshot was taken from", func() {
+				restoreOpts.Config.InitialClusterToken = "cloned-etcd-cluster"
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).Should(MatchError(ErrClusterIDMismatch))
+				Expect(err).Should(MatchError(ContainSubstring(fmt.Sprintf("in cluster %x of base snapshot", metadata.ClusterID))))
+				Expect(filepath.Join(restoreOpts.Config.DataDir, "member")).ShouldNot(BeADirectory())
+			})
+
+			It("should clone the data into another cluster with a regenerated member ID if the cluster ID mismatch is allowed", func() {
+				restoreOpts.Config.InitialClusterToken = "cloned-etcd-cluster"
+				restoreOpts.Config.AllowClusterIDMismatch = true
+				e, err := restorer.Restore(testCtx, restoreOpts, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer func() {
+					e.Server.Stop()
+					e.Close()
+				}()
+
+				recorded := metadata.Member(restoreName)
+				Expect(recorded).NotTo(BeNil())
+				Expect(uint64(e.Server.ID())).NotTo(Equal(recorded.ID))
+				Expect(uint64(e.Server.Cluster().ID())).NotTo(Equal(metadata.ClusterID))
+				Expect(e.Server.Cluster().Members()[0].PeerURLs).To(Equal(recorded.PeerURLs))
+			})
+
+			It("should not check the cluster ID of a member which was added to the cluster at runtime", func() {
+				// the ID of a member added at runtime is salted with the time it was added at
+				metadata.Members[0].ID++
+				Expect(snapstore.SaveClusterMetadata(store, baseSnapshot, metadata)).To(Succeed())
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+
+			It("should fail to restore a member which is not recorded in the cluster metadata", func() {
+				restoreOpts.Config.Name = "unknown"
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).Should(MatchError(ContainSubstring("member unknown is not a voting member of the cluster metadata")))
+			})
+		})
+
+		Context("with an invalid initial cluster state", func() {
+			It("should fail to restore", func() {
+				restoreOpts.InitialClusterState = "unknown"
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).Should(MatchError(ContainSubstring("invalid initial cluster state")))
+			})
+		})
+
 		Context("with maximum of four fetchers allowed", func() {
 			It("should restore etcd data directory", func() {
 				restoreOpts.Config.MaxFetchers = 4
 
-				err = restorer.RestoreAndStopEtcd(restoreOpts, nil)
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
 				Expect(err).ShouldNot(HaveOccurred())
 
 				err = utils.CheckDataConsistency(testCtx, restoreOpts.Config.DataDir, keyTo, logger)
@@ -202,13 +309,420 @@ var _ = Describe("Running Restorer", func() {
 			It("should restore etcd data directory", func() {
 				restoreOpts.Config.MaxFetchers = 100
 
-				err = restorer.RestoreAndStopEtcd(restoreOpts, nil)
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				err = utils.CheckDataConsistency(testCtx, restoreOpts.Config.DataDir, keyTo, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+		})
+
+		Context("with multiple restorations in the process limited by the maximum of node fetchers", func() {
+			It("should not fetch more delta snapshots in parallel than the maximum of node fetchers across all the restorations", func() {
+				const (
+					restorations    = 3
+					maxNodeFetchers = 2
+				)
+				fetches := &concurrentFetches{}
+				errs := make([]error, restorations)
+				dataDirs := make([]string, restorations)
+				var restorationsWg sync.WaitGroup
+				for i := 0; i < restorations; i++ {
+					opts := restoreOpts.DeepCopy()
+					opts.Config.DataDir = filepath.Join(GinkgoT().TempDir(), "default.etcd")
+					opts.Config.TempSnapshotsDir = filepath.Join(GinkgoT().TempDir(), "default.restoration.tmp")
+					opts.Config.MaxFetchers = 4
+					opts.Config.MaxNodeFetchers = maxNodeFetchers
+					dataDirs[i] = opts.Config.DataDir
+
+					r, err := NewRestorer(&concurrencyTrackingSnapStore{SnapStore: store, fetches: fetches, fetchDelay: 100 * time.Millisecond}, logger)
+					Expect(err).ShouldNot(HaveOccurred())
+					restorationsWg.Add(1)
+					go func(i int) {
+						defer GinkgoRecover()
+						defer restorationsWg.Done()
+						errs[i] = r.RestoreAndStopEtcd(testCtx, *opts, nil)
+					}(i)
+				}
+				restorationsWg.Wait()
+
+				for i := 0; i < restorations; i++ {
+					Expect(errs[i]).ShouldNot(HaveOccurred())
+					Expect(utils.CheckDataConsistency(testCtx, dataDirs[i], keyTo, logger)).To(Succeed())
+				}
+				Expect(fetches.max).To(Equal(maxNodeFetchers))
+			})
+		})
+
+		Context("with streaming of base snapshot enabled", func() {
+			var streamedEtcdDir = filepath.Join(outputDir, "streamed.etcd")
+
+			AfterEach(func() {
+				Expect(os.RemoveAll(streamedEtcdDir)).To(Succeed())
+			})
+
+			It("should restore the same base snapshot state as the non-streamed restoration", func() {
+				restoreOpts.DeltaSnapList = nil
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				streamedRestoreOpts := restoreOpts.DeepCopy()
+				streamedRestoreOpts.Config.DataDir = streamedEtcdDir
+				streamedRestoreOpts.Config.StreamBaseSnapshot = true
+				err = restorer.RestoreAndStopEtcd(testCtx, *streamedRestoreOpts, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				kvs, err := readBucketsFromDB(filepath.Join(restoreOpts.Config.DataDir, "member", "snap", "db"))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(kvs).ShouldNot(BeEmpty())
+				streamedKVs, err := readBucketsFromDB(filepath.Join(streamedRestoreOpts.Config.DataDir, "member", "snap", "db"))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(streamedKVs).Should(Equal(kvs))
+			})
+
+			It("should fall back to fetching the base snapshot anew if the stream fails", func() {
+				restoreOpts.DeltaSnapList = nil
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				streamedRestoreOpts := restoreOpts.DeepCopy()
+				streamedRestoreOpts.Config.DataDir = streamedEtcdDir
+				streamedRestoreOpts.Config.StreamBaseSnapshot = true
+				brokenStore := &brokenStreamSnapStore{SnapStore: store}
+				streamingRestorer, err := NewRestorer(brokenStore, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+				err = streamingRestorer.RestoreAndStopEtcd(testCtx, *streamedRestoreOpts, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(brokenStore.broken).Should(BeTrue())
+				Expect(brokenStore.baseFetches).Should(BeNumerically(">=", 2))
+
+				kvs, err := readBucketsFromDB(filepath.Join(restoreOpts.Config.DataDir, "member", "snap", "db"))
+				Expect(err).ShouldNot(HaveOccurred())
+				streamedKVs, err := readBucketsFromDB(filepath.Join(streamedRestoreOpts.Config.DataDir, "member", "snap", "db"))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(streamedKVs).Should(Equal(kvs))
+			})
+
+			It("should restore etcd data directory", func() {
+				restoreOpts.Config.StreamBaseSnapshot = true
+
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				err = utils.CheckDataConsistency(testCtx, restoreOpts.Config.DataDir, keyTo, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+		})
+
+		Context("with non-default backend options", func() {
+			It("should restore etcd data directory", func() {
+				restoreOpts.Config.BackendFreelistType = brtypes.BackendFreelistTypeMap
+				restoreOpts.Config.BackendInitialMmapSize = 64 * 1024 * 1024
+
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				err = utils.CheckDataConsistency(testCtx, restoreOpts.Config.DataDir, keyTo, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+		})
+
+		Context("with the restoration being verified", func() {
+			expectVerificationDirRemoved := func() {
+				verificationDirs, err := filepath.Glob(filepath.Join(outputDir, "restore-verification-*"))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(verificationDirs).Should(BeEmpty())
+				_, err = os.Stat(etcdDir)
+				Expect(os.IsNotExist(err)).Should(BeTrue())
+			}
+
+			It("should pass the verification of a restorable snapshot chain", func() {
+				reportPath := filepath.Join(outputDir, "restore-report.json")
+				restoreOpts.Config.RestoreReportPath = reportPath
+
+				report := restorer.VerifyRestore(testCtx, restoreOpts, true)
+				Expect(report.Error).Should(BeEmpty())
+				Expect(report.Passed).Should(BeTrue())
+				Expect(report.BaseSnapshot).Should(Equal(baseSnapshot.SnapName))
+				Expect(report.DeltaSnapshots).Should(Equal(len(deltaSnapList)))
+				Expect(report.ExpectedRevision).Should(Equal(deltaSnapList[len(deltaSnapList)-1].LastRevision))
+				Expect(report.RestoredRevision).Should(Equal(report.ExpectedRevision))
+				Expect(restorer.LastRestoreReport().FinalRevision).Should(Equal(report.RestoredRevision))
+				expectVerificationDirRemoved()
+				// the report of the restoration of the data directory is not overwritten by the verification
+				_, err = os.Stat(reportPath)
+				Expect(os.IsNotExist(err)).Should(BeTrue())
+			})
+
+			It("should pass the verification of a restoration up to a wall-clock time", func() {
+				Expect(len(deltaSnapList)).Should(BeNumerically(">", 2))
+				restoreToSnapshot := deltaSnapList[len(deltaSnapList)/2]
+				restoreOpts.RestoreToTime = restoreToSnapshot.CreatedOn
+
+				report := restorer.VerifyRestore(testCtx, restoreOpts, true)
+				Expect(report.Error).Should(BeEmpty())
+				Expect(report.Passed).Should(BeTrue())
+				Expect(report.DeltaSnapshots).Should(BeNumerically("<", len(deltaSnapList)))
+				Expect(report.ExpectedRevision).Should(BeNumerically(">=", baseSnapshot.LastRevision))
+				Expect(report.ExpectedRevision).Should(BeNumerically("<=", restoreToSnapshot.LastRevision))
+				Expect(report.RestoredRevision).Should(Equal(report.ExpectedRevision))
+				expectVerificationDirRemoved()
+			})
+
+			It("should fail the verification of a broken snapshot chain", func() {
+				missingSnapshot := *deltaSnapList[len(deltaSnapList)-1]
+				missingSnapshot.StartRevision = missingSnapshot.LastRevision + 1
+				missingSnapshot.LastRevision = missingSnapshot.StartRevision + 10
+				missingSnapshot.SnapName = "Incr-missing"
+				restoreOpts.DeltaSnapList = append(append(brtypes.SnapList{}, deltaSnapList...), &missingSnapshot)
+
+				report := restorer.VerifyRestore(testCtx, restoreOpts, true)
+				Expect(report.Passed).Should(BeFalse())
+				Expect(report.Error).ShouldNot(BeEmpty())
+				Expect(report.ExpectedRevision).Should(Equal(missingSnapshot.LastRevision))
+				expectVerificationDirRemoved()
+			})
+
+			It("should fail the verification if the canary of the base snapshot is missing", func() {
+				// the snapshots of the suite are taken without writing canary keys
+				restoreOpts.Config.CanaryKeyPrefix = "/etcd-backup-restore/canary/"
+
+				report := restorer.VerifyRestore(testCtx, restoreOpts, true)
+				Expect(report.Passed).Should(BeFalse())
+				Expect(report.Error).Should(ContainSubstring("failed to verify the canary of the base snapshot"))
+				Expect(report.CanaryVerified).Should(BeFalse())
+				Expect(report.RestoredRevision).Should(Equal(report.ExpectedRevision))
+				expectVerificationDirRemoved()
+			})
+		})
+
+		Context("with the snapshots of the chain stored with mixed compression suffixes", func() {
+			var mixedSnapstoreDir = filepath.Join(outputDir, "mixed-suffixes.bkp")
+
+			AfterEach(func() {
+				Expect(os.RemoveAll(mixedSnapstoreDir)).To(Succeed())
+			})
+
+			It("should decompress each snapshot according to its own compression suffix and restore", func() {
+				Expect(len(restoreOpts.DeltaSnapList)).Should(BeNumerically(">", 3))
+				mixedStore, err := snapstore.GetSnapstore(&brtypes.SnapstoreConfig{Container: mixedSnapstoreDir, Provider: "Local"})
+				Expect(err).ShouldNot(HaveOccurred())
+
+				// the base snapshot is compressed, whereas the delta snapshots alternate between being uncompressed and compressed with every compression policy
+				restoreOpts.BaseSnapshot = copySnapshotWithCompressionSuffix(store, mixedStore, baseSnapshot, compressor.GzipCompressionExtension)
+				deltaSuffixes := []string{compressor.UnCompressSnapshotExtension, compressor.ZlibCompressionExtension, compressor.LzwCompressionExtension, compressor.GzipCompressionExtension}
+				restoreOpts.DeltaSnapList = brtypes.SnapList{}
+				for i, snap := range deltaSnapList {
+					restoreOpts.DeltaSnapList = append(restoreOpts.DeltaSnapList, copySnapshotWithCompressionSuffix(store, mixedStore, snap, deltaSuffixes[i%len(deltaSuffixes)]))
+				}
+
+				restorer, err = NewRestorer(mixedStore, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				err = utils.CheckDataConsistency(testCtx, restoreOpts.Config.DataDir, keyTo, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+		})
+
+		Context("with tracing enabled", func() {
+			var tracer *tracing.InMemoryTracer
+
+			BeforeEach(func() {
+				tracer = tracing.NewInMemoryTracer()
+				tracing.SetTracer(tracer)
+			})
+
+			AfterEach(func() {
+				tracing.SetTracer(nil)
+			})
+
+			It("should record the spans of the restoration phases", func() {
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				baseSpans := tracer.SpansByName(tracing.SpanRestoreBaseSnapshot)
+				Expect(baseSpans).Should(HaveLen(1))
+				Expect(baseSpans[0].Ended).Should(BeTrue())
+				Expect(baseSpans[0].Err).ShouldNot(HaveOccurred())
+				Expect(baseSpans[0].Attributes).Should(HaveKeyWithValue(tracing.AttributeSnapshotName, baseSnapshot.SnapName))
+				Expect(baseSpans[0].Attributes).Should(HaveKeyWithValue(tracing.AttributeLastRevision, baseSnapshot.LastRevision))
+
+				deltaSpans := tracer.SpansByName(tracing.SpanApplyDeltaSnapshots)
+				Expect(deltaSpans).Should(HaveLen(1))
+				Expect(deltaSpans[0].Ended).Should(BeTrue())
+				Expect(deltaSpans[0].Err).ShouldNot(HaveOccurred())
+				Expect(deltaSpans[0].Attributes).Should(HaveKeyWithValue(tracing.AttributeSnapshotCount, int64(len(deltaSnapList))))
+				Expect(deltaSpans[0].Attributes).Should(HaveKeyWithValue(tracing.AttributeStartRevision, deltaSnapList[0].StartRevision))
+				Expect(deltaSpans[0].Attributes).Should(HaveKeyWithValue(tracing.AttributeLastRevision, deltaSnapList[len(deltaSnapList)-1].LastRevision))
+			})
+		})
+
+		Context("with the restoration checkpointed", func() {
+			BeforeEach(func() {
+				restoreOpts.Config.RestoreCheckpointInterval = 1
+				// the delta snapshots are fetched one after the other to fail the restoration at a known delta snapshot
+				restoreOpts.Config.MaxFetchers = 1
+			})
+
+			It("should resume a failed restoration from its checkpoint without reapplying the applied delta snapshots", func() {
+				Expect(len(restoreOpts.DeltaSnapList)).Should(BeNumerically(">", 3))
+
+				failingRestorer, err := NewRestorer(&fetchRecordingSnapStore{SnapStore: store, failOnDeltaFetch: 3}, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+				err = failingRestorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).Should(HaveOccurred())
+				Expect(filepath.Join(restoreOpts.Config.DataDir, "member")).Should(BeADirectory())
+				Expect(HasCheckpoint(restoreOpts.Config.DataDir)).Should(BeTrue())
+
+				resumingStore := &fetchRecordingSnapStore{SnapStore: store}
+				resumingRestorer, err := NewRestorer(resumingStore, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+				err = resumingRestorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				// neither the base snapshot nor the first delta snapshot, which was applied before the failure, are fetched again
+				Expect(resumingStore.fetched).ShouldNot(BeEmpty())
+				Expect(resumingStore.fetched).ShouldNot(ContainElement(restoreOpts.BaseSnapshot.SnapName))
+				Expect(resumingStore.fetched).ShouldNot(ContainElement(restoreOpts.DeltaSnapList[0].SnapName))
+				Expect(resumingStore.fetched).Should(ContainElement(restoreOpts.DeltaSnapList[len(restoreOpts.DeltaSnapList)-1].SnapName))
+				Expect(HasCheckpoint(restoreOpts.Config.DataDir)).Should(BeFalse())
+
+				err = utils.CheckDataConsistency(testCtx, restoreOpts.Config.DataDir, keyTo, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+
+			It("should discard a stale checkpoint and restore from the base snapshot", func() {
+				failingRestorer, err := NewRestorer(&fetchRecordingSnapStore{SnapStore: store, failOnDeltaFetch: 3}, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(failingRestorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)).ShouldNot(Succeed())
+				Expect(HasCheckpoint(restoreOpts.Config.DataDir)).Should(BeTrue())
+
+				// the checkpoint was recorded for another base snapshot
+				staleCheckpoint := fmt.Sprintf(`{"baseSnapshot":"Full-00000000-00000001-1700000000.gz","lastAppliedSnapshot":%q,"lastAppliedRevision":%d}`,
+					restoreOpts.DeltaSnapList[0].SnapName, restoreOpts.DeltaSnapList[0].LastRevision)
+				Expect(os.WriteFile(CheckpointFilePath(restoreOpts.Config.DataDir), []byte(staleCheckpoint), 0600)).To(Succeed())
+
+				restartingStore := &fetchRecordingSnapStore{SnapStore: store}
+				restartingRestorer, err := NewRestorer(restartingStore, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+				err = restartingRestorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(restartingStore.fetched).Should(ContainElement(restoreOpts.BaseSnapshot.SnapName))
+				Expect(restartingStore.fetched).Should(ContainElement(restoreOpts.DeltaSnapList[0].SnapName))
+				Expect(HasCheckpoint(restoreOpts.Config.DataDir)).Should(BeFalse())
+
+				err = utils.CheckDataConsistency(testCtx, restoreOpts.Config.DataDir, keyTo, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+		})
+
+		Context("with the restoration being cancelled", func() {
+			It("should abort before restoring the base snapshot if the context is already cancelled", func() {
+				ctx, cancel := context.WithCancel(testCtx)
+				cancel()
+
+				err = restorer.RestoreAndStopEtcd(ctx, restoreOpts, nil)
+				Expect(err).Should(MatchError(context.Canceled))
+				Expect(filepath.Join(restoreOpts.Config.DataDir, "member")).ShouldNot(BeADirectory())
+			})
+
+			It("should abort while applying delta snapshots and clean up the partially restored data directory", func() {
+				Expect(len(restoreOpts.DeltaSnapList)).Should(BeNumerically(">", 2))
+				ctx, cancel := context.WithCancel(testCtx)
+				defer cancel()
+
+				// cancel the restoration once the fetchers start fetching the remaining delta snapshots
+				cancellingRestorer, err := NewRestorer(&cancellingSnapStore{SnapStore: store, cancel: cancel, cancelOnDeltaFetch: 2}, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				err = cancellingRestorer.RestoreAndStopEtcd(ctx, restoreOpts, nil)
+				Expect(err).Should(MatchError(context.Canceled))
+				Expect(filepath.Join(restoreOpts.Config.DataDir, "member")).ShouldNot(BeADirectory())
+				Expect(restoreOpts.Config.TempSnapshotsDir).ShouldNot(BeADirectory())
+
+				// a subsequent restoration into the same data directory succeeds only if no embedded etcd is left running
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
 				Expect(err).ShouldNot(HaveOccurred())
 
 				err = utils.CheckDataConsistency(testCtx, restoreOpts.Config.DataDir, keyTo, logger)
 				Expect(err).ShouldNot(HaveOccurred())
 			})
 		})
+
+		Context("with a maximum restore duration", func() {
+			It("should abort the restoration at the deadline and clean up the partially restored data directory", func() {
+				const fetchDelay = 2 * time.Second
+				restoreOpts.MaxRestoreDuration = 3 * time.Second
+				slowRestorer, err := NewRestorer(&slowSnapStore{SnapStore: store, fetchDelay: fetchDelay}, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				start := time.Now()
+				err = slowRestorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).Should(MatchError(ErrMaxRestoreDurationExceeded))
+				Expect(err.Error()).Should(ContainSubstring("3s"))
+				// the restoration is aborted at the latest once the delta snapshot being fetched at the deadline is fetched
+				Expect(time.Since(start)).Should(BeNumerically("<", restoreOpts.MaxRestoreDuration+fetchDelay+5*time.Second))
+				Expect(filepath.Join(restoreOpts.Config.DataDir, "member")).ShouldNot(BeADirectory())
+				Expect(restoreOpts.Config.TempSnapshotsDir).ShouldNot(BeADirectory())
+			})
+
+			It("should complete a restoration within the maximum restore duration", func() {
+				restoreOpts.MaxRestoreDuration = 5 * time.Minute
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				err = utils.CheckDataConsistency(testCtx, restoreOpts.Config.DataDir, keyTo, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+		})
+
+		Context("with a restore lock TTL", func() {
+			BeforeEach(func() {
+				restoreOpts.RestoreLockTTL = time.Hour
+			})
+
+			AfterEach(func() {
+				// the snapstore is shared with the other specs
+				lock, err := snapstore.FetchRestoreLock(store)
+				Expect(err).ShouldNot(HaveOccurred())
+				if lock != nil {
+					Expect(snapstore.ReleaseRestoreLock(store, lock.Holder)).To(Succeed())
+				}
+			})
+
+			It("should reject a second restoration while another restoration holds the restore lock, without touching the data directory", func() {
+				release, err := restorer.AcquireRestoreLock(time.Hour)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(os.MkdirAll(restoreOpts.Config.DataDir, 0700)).To(Succeed())
+				markerPath := filepath.Join(restoreOpts.Config.DataDir, "restore-lock-marker")
+				Expect(os.WriteFile(markerPath, []byte("untouched"), 0600)).To(Succeed())
+
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).Should(MatchError(snapstore.ErrRestoreLockExists))
+				Expect(markerPath).Should(BeAnExistingFile())
+				Expect(os.Remove(markerPath)).To(Succeed())
+
+				release()
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				lock, err := snapstore.FetchRestoreLock(store)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(lock).To(BeNil())
+			})
+
+			It("should take over a stale restore lock left behind by a crashed restoration", func() {
+				_, err := restorer.AcquireRestoreLock(time.Nanosecond)
+				Expect(err).ShouldNot(HaveOccurred())
+				time.Sleep(time.Millisecond)
+
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				err = utils.CheckDataConsistency(testCtx, restoreOpts.Config.DataDir, keyTo, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+		})
 	})
 
 	Describe("NEGATIVE: Negative Compression Scenarios", func() {
@@ -298,63 +812,798 @@ var _ = Describe("Running Restorer", func() {
 				etcd.Server.Stop()
 				etcd.Close()
 
-				err = corruptEtcdDir()
+				err = corruptEtcdDir()
+				Expect(err).ShouldNot(HaveOccurred())
+
+				baseSnapshot, deltaSnapList, err = miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(store)
+				Expect(err).ShouldNot(HaveOccurred())
+				logger.Infof("No of delta snapshots: %d", deltaSnapList.Len())
+				logger.Infof("Base snapshot is %v", baseSnapshot)
+
+				restorer, err = NewRestorer(store, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+				restoreOpts := brtypes.RestoreOptions{
+					Config:        restorationConfig,
+					BaseSnapshot:  baseSnapshot,
+					DeltaSnapList: deltaSnapList,
+					ClusterURLs:   clusterUrlsMap,
+					PeerURLs:      peerUrls,
+				}
+
+				if baseSnapshot != nil {
+					restoreOpts.BaseSnapshot.SnapDir = ""
+					restoreOpts.BaseSnapshot.SnapName = ""
+				}
+
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				err = utils.CheckDataConsistency(testCtx, restoreOpts.Config.DataDir, keyTo, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+		})
+
+		Context("with no delta snapshots", func() {
+			It("Should restore only full snapshot", func() {
+				deltaSnapshotPeriod = time.Duration(0)
+				logger.Infoln("Starting snapshotter for no delta snapshots")
+				wg.Add(1)
+				populatorCtx, cancelPopulator := context.WithTimeout(testCtx, 2*time.Second)
+				go utils.PopulateEtcdWithWaitGroup(populatorCtx, wg, logger, endpoints, nil)
+				defer cancelPopulator()
+				ssrCtx := utils.ContextWithWaitGroupFollwedByGracePeriod(testCtx, wg, time.Second)
+				compressionConfig := compressor.NewCompressorConfig()
+				snapstoreConfig := brtypes.SnapstoreConfig{Container: snapstoreDir, Provider: "Local"}
+				err = utils.RunSnapshotter(logger, snapstoreConfig, deltaSnapshotPeriod, endpoints, ssrCtx.Done(), true, compressionConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				etcd.Server.Stop()
+				etcd.Close()
+
+				err = corruptEtcdDir()
+				Expect(err).ShouldNot(HaveOccurred())
+
+				baseSnapshot, deltaSnapList, err = miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(store)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(deltaSnapList.Len()).Should(BeZero())
+
+				restorer, err = NewRestorer(store, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				restoreOpts := brtypes.RestoreOptions{
+					Config:        restorationConfig,
+					BaseSnapshot:  baseSnapshot,
+					DeltaSnapList: deltaSnapList,
+					ClusterURLs:   clusterUrlsMap,
+					PeerURLs:      peerUrls,
+				}
+
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+
+				Expect(err).ShouldNot(HaveOccurred())
+
+			})
+		})
+
+		Context("with preserved key prefixes", func() {
+			var mergeRestoreDir = filepath.Join(outputDir, "merge.etcd")
+
+			AfterEach(func() {
+				Expect(os.RemoveAll(mergeRestoreDir)).To(Succeed())
+			})
+
+			It("should retain the live values of the preserved prefixes and the backed up values of the remaining keys", func() {
+				liveClient, err := clientv3.New(clientv3.Config{Endpoints: endpoints, DialTimeout: 10 * time.Second})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer liveClient.Close()
+				for i := 0; i < 10; i++ {
+					_, err = liveClient.Put(testCtx, fmt.Sprintf("/preserved/key-%d", i), "backup")
+					Expect(err).ShouldNot(HaveOccurred())
+					_, err = liveClient.Put(testCtx, fmt.Sprintf("/restored/key-%d", i), "backup")
+					Expect(err).ShouldNot(HaveOccurred())
+				}
+
+				ctx, cancel := context.WithTimeout(testCtx, 2*time.Second)
+				defer cancel()
+				snapstoreConfig := brtypes.SnapstoreConfig{Container: snapstoreDir, Provider: "Local"}
+				err = utils.RunSnapshotter(logger, snapstoreConfig, deltaSnapshotPeriod, endpoints, ctx.Done(), true, compressor.NewCompressorConfig())
+				Expect(err).ShouldNot(HaveOccurred())
+
+				// the live cluster diverges from the backup after the snapshots are taken
+				for i := 0; i < 5; i++ {
+					_, err = liveClient.Put(testCtx, fmt.Sprintf("/preserved/key-%d", i), "live")
+					Expect(err).ShouldNot(HaveOccurred())
+					_, err = liveClient.Put(testCtx, fmt.Sprintf("/restored/key-%d", i), "live")
+					Expect(err).ShouldNot(HaveOccurred())
+				}
+				_, err = liveClient.Delete(testCtx, "/preserved/key-9")
+				Expect(err).ShouldNot(HaveOccurred())
+				_, err = liveClient.Put(testCtx, "/preserved/new-key", "live")
+				Expect(err).ShouldNot(HaveOccurred())
+				lease, err := liveClient.Grant(testCtx, 600)
+				Expect(err).ShouldNot(HaveOccurred())
+				_, err = liveClient.Put(testCtx, "/preserved/leased-key", "live", clientv3.WithLease(lease.ID))
+				Expect(err).ShouldNot(HaveOccurred())
+				liveResp, err := liveClient.Get(testCtx, "/preserved/", clientv3.WithPrefix())
+				Expect(err).ShouldNot(HaveOccurred())
+
+				baseSnapshot, deltaSnapList, err = miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(store)
+				Expect(err).ShouldNot(HaveOccurred())
+				restorer, err = NewRestorer(store, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				restorationConfig.DataDir = mergeRestoreDir
+				restorationConfig.PreservedKeyPrefixes = []string{"/preserved/"}
+				restorationConfig.PreservedKeysEndpoints = endpoints
+				restoreOpts := brtypes.RestoreOptions{
+					Config:        restorationConfig,
+					BaseSnapshot:  baseSnapshot,
+					DeltaSnapList: deltaSnapList,
+					ClusterURLs:   clusterUrlsMap,
+					PeerURLs:      peerUrls,
+				}
+				restoredEtcd, err := restorer.Restore(testCtx, restoreOpts, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(restoredEtcd).ShouldNot(BeNil())
+				defer func() {
+					restoredEtcd.Server.Stop()
+					restoredEtcd.Close()
+				}()
+
+				restoredClient, err := clientv3.New(clientv3.Config{Endpoints: []string{restoredEtcd.Clients[0].Addr().String()}, DialTimeout: 10 * time.Second})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer restoredClient.Close()
+
+				preservedResp, err := restoredClient.Get(testCtx, "/preserved/", clientv3.WithPrefix())
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(preservedResp.Kvs).Should(HaveLen(len(liveResp.Kvs)))
+				for i, kv := range preservedResp.Kvs {
+					Expect(string(kv.Key)).Should(Equal(string(liveResp.Kvs[i].Key)))
+					Expect(string(kv.Value)).Should(Equal(string(liveResp.Kvs[i].Value)))
+					Expect(kv.Lease).Should(Equal(liveResp.Kvs[i].Lease))
+				}
+				// the lease of the preserved keys is granted with its live ID
+				ttlResp, err := restoredClient.TimeToLive(testCtx, lease.ID)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(ttlResp.TTL).Should(BeNumerically(">", 0))
+
+				restoredResp, err := restoredClient.Get(testCtx, "/restored/", clientv3.WithPrefix())
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(restoredResp.Kvs).Should(HaveLen(10))
+				for _, kv := range restoredResp.Kvs {
+					Expect(string(kv.Value)).Should(Equal("backup"))
+				}
+			})
+		})
+
+		Context("with the snapshots scoped to a key prefix", func() {
+			var prefixRestoreDir = filepath.Join(outputDir, "prefix.etcd")
+
+			AfterEach(func() {
+				Expect(os.RemoveAll(prefixRestoreDir)).To(Succeed())
+			})
+
+			It("should restore only the keys under the prefix from the full and delta snapshots", func() {
+				liveClient, err := clientv3.New(clientv3.Config{Endpoints: endpoints, DialTimeout: 10 * time.Second})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer liveClient.Close()
+				for i := 0; i < 10; i++ {
+					_, err = liveClient.Put(testCtx, fmt.Sprintf("/scoped/key-%d", i), "full")
+					Expect(err).ShouldNot(HaveOccurred())
+					_, err = liveClient.Put(testCtx, fmt.Sprintf("/unscoped/key-%d", i), "full")
+					Expect(err).ShouldNot(HaveOccurred())
+				}
+
+				etcdConnectionConfig := brtypes.NewEtcdConnectionConfig()
+				etcdConnectionConfig.Endpoints = endpoints
+				etcdConnectionConfig.ConnectionTimeout.Duration = 10 * time.Second
+				snapshotterConfig := snapshotter.NewSnapshotterConfig()
+				snapshotterConfig.DeltaSnapshotPeriod.Duration = 0
+				snapshotterConfig.SnapshotKeyPrefix = "/scoped/"
+				snapstoreConfig := &brtypes.SnapstoreConfig{Container: snapstoreDir, Provider: "Local"}
+				ssr, err := snapshotter.NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressor.NewCompressorConfig(), brtypes.NewHealthConfig(), snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				_, err = ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				for i := 0; i < 5; i++ {
+					_, err = liveClient.Put(testCtx, fmt.Sprintf("/scoped/key-%d", i), "delta")
+					Expect(err).ShouldNot(HaveOccurred())
+					_, err = liveClient.Put(testCtx, fmt.Sprintf("/unscoped/key-%d", i), "delta")
+					Expect(err).ShouldNot(HaveOccurred())
+				}
+				_, err = liveClient.Delete(testCtx, "/scoped/key-9")
+				Expect(err).ShouldNot(HaveOccurred())
+				// the latest etcd revision is not under the prefix
+				_, err = liveClient.Put(testCtx, "/unscoped/new-key", "delta")
+				Expect(err).ShouldNot(HaveOccurred())
+				stopped, err := ssr.CollectEventsSincePrevSnapshot(testCtx, make(chan struct{}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(stopped).Should(BeFalse())
+				deltaSnap, err := ssr.TakeDeltaSnapshot(testCtx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(deltaSnap).ShouldNot(BeNil())
+				liveResp, err := liveClient.Get(testCtx, "/scoped/", clientv3.WithPrefix())
+				Expect(err).ShouldNot(HaveOccurred())
+
+				baseSnapshot, deltaSnapList, err = miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(store)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(deltaSnapList).Should(HaveLen(1))
+				restorer, err = NewRestorer(store, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				restorationConfig.DataDir = prefixRestoreDir
+				restorationConfig.RestoreKeyPrefix = "/scoped/"
+				restoreOpts := brtypes.RestoreOptions{
+					Config:        restorationConfig,
+					BaseSnapshot:  baseSnapshot,
+					DeltaSnapList: deltaSnapList,
+					ClusterURLs:   clusterUrlsMap,
+					PeerURLs:      peerUrls,
+				}
+				restoredEtcd, err := restorer.Restore(testCtx, restoreOpts, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(restoredEtcd).ShouldNot(BeNil())
+				defer func() {
+					restoredEtcd.Server.Stop()
+					restoredEtcd.Close()
+				}()
+
+				restoredClient, err := clientv3.New(clientv3.Config{Endpoints: []string{restoredEtcd.Clients[0].Addr().String()}, DialTimeout: 10 * time.Second})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer restoredClient.Close()
+
+				restoredResp, err := restoredClient.Get(testCtx, "", clientv3.WithPrefix())
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(restoredResp.Kvs).Should(HaveLen(len(liveResp.Kvs)))
+				for i, kv := range restoredResp.Kvs {
+					Expect(string(kv.Key)).Should(Equal(string(liveResp.Kvs[i].Key)))
+					Expect(string(kv.Value)).Should(Equal(string(liveResp.Kvs[i].Value)))
+				}
+			})
+
+			It("should restore the keys under the prefix from a full snapshot taken at a historical revision", func() {
+				liveClient, err := clientv3.New(clientv3.Config{Endpoints: endpoints, DialTimeout: 10 * time.Second})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer liveClient.Close()
+				var revision int64
+				for i := 0; i < 5; i++ {
+					resp, err := liveClient.Put(testCtx, fmt.Sprintf("/historical/key-%d", i), "old")
+					Expect(err).ShouldNot(HaveOccurred())
+					revision = resp.Header.Revision
+				}
+				// newer writes exist when the full snapshot is taken
+				for i := 0; i < 5; i++ {
+					_, err = liveClient.Put(testCtx, fmt.Sprintf("/historical/key-%d", i), "new")
+					Expect(err).ShouldNot(HaveOccurred())
+				}
+				_, err = liveClient.Put(testCtx, "/historical/new-key", "new")
+				Expect(err).ShouldNot(HaveOccurred())
+
+				// the snapshots of the other specs in the snapstore are ahead of the historical revision
+				snapstoreConfig := &brtypes.SnapstoreConfig{Container: filepath.Join(outputDir, "historical.bkp"), Provider: "Local"}
+				historicalStore, err := snapstore.GetSnapstore(snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer os.RemoveAll(snapstoreConfig.Container)
+				etcdConnectionConfig := brtypes.NewEtcdConnectionConfig()
+				etcdConnectionConfig.Endpoints = endpoints
+				etcdConnectionConfig.ConnectionTimeout.Duration = 10 * time.Second
+				snapshotterConfig := snapshotter.NewSnapshotterConfig()
+				snapshotterConfig.DeltaSnapshotPeriod.Duration = 0
+				snapshotterConfig.SnapshotKeyPrefix = "/historical/"
+				ssr, err := snapshotter.NewSnapshotter(logger, snapshotterConfig, historicalStore, etcdConnectionConfig, compressor.NewCompressorConfig(), brtypes.NewHealthConfig(), snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				snap, err := ssr.TakeFullSnapshotAtRevisionAndResetTimer(testCtx, revision)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(snap.LastRevision).Should(Equal(revision))
+
+				baseSnapshot, _, err := miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(historicalStore)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(baseSnapshot.SnapName).Should(Equal(snap.SnapName))
+				restorer, err = NewRestorer(historicalStore, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+				restorationConfig.DataDir = prefixRestoreDir
+				restorationConfig.RestoreKeyPrefix = "/historical/"
+				restoreOpts := brtypes.RestoreOptions{
+					Config:       restorationConfig,
+					BaseSnapshot: baseSnapshot,
+					ClusterURLs:  clusterUrlsMap,
+					PeerURLs:     peerUrls,
+				}
+				Expect(restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)).To(Succeed())
+				restoredEtcd, err := miscellaneous.StartEmbeddedEtcd(logger, &restoreOpts)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer func() {
+					restoredEtcd.Server.Stop()
+					restoredEtcd.Close()
+				}()
+
+				restoredClient, err := clientv3.New(clientv3.Config{Endpoints: []string{restoredEtcd.Clients[0].Addr().String()}, DialTimeout: 10 * time.Second})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer restoredClient.Close()
+				restoredResp, err := restoredClient.Get(testCtx, "", clientv3.WithPrefix())
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(restoredResp.Kvs).Should(HaveLen(5))
+				for i, kv := range restoredResp.Kvs {
+					Expect(string(kv.Key)).Should(Equal(fmt.Sprintf("/historical/key-%d", i)))
+					Expect(string(kv.Value)).Should(Equal("old"))
+				}
+			})
+
+			It("should restore the whole keyspace from an unscoped full snapshot taken at a historical revision", func() {
+				liveClient, err := clientv3.New(clientv3.Config{Endpoints: endpoints, DialTimeout: 10 * time.Second})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer liveClient.Close()
+				var revision int64
+				for i := 0; i < 5; i++ {
+					resp, err := liveClient.Put(testCtx, fmt.Sprintf("/unscoped-historical/key-%d", i), "old")
+					Expect(err).ShouldNot(HaveOccurred())
+					revision = resp.Header.Revision
+				}
+				for i := 0; i < 5; i++ {
+					_, err = liveClient.Put(testCtx, fmt.Sprintf("/unscoped-historical/key-%d", i), "new")
+					Expect(err).ShouldNot(HaveOccurred())
+				}
+				_, err = liveClient.Put(testCtx, "/unscoped-historical/new-key", "new")
+				Expect(err).ShouldNot(HaveOccurred())
+				liveResp, err := liveClient.Get(testCtx, "", clientv3.WithPrefix(), clientv3.WithRev(revision))
+				Expect(err).ShouldNot(HaveOccurred())
+
+				snapstoreConfig := &brtypes.SnapstoreConfig{Container: filepath.Join(outputDir, "unscoped-historical.bkp"), Provider: "Local"}
+				historicalStore, err := snapstore.GetSnapstore(snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer os.RemoveAll(snapstoreConfig.Container)
+				etcdConnectionConfig := brtypes.NewEtcdConnectionConfig()
+				etcdConnectionConfig.Endpoints = endpoints
+				etcdConnectionConfig.ConnectionTimeout.Duration = 10 * time.Second
+				snapshotterConfig := snapshotter.NewSnapshotterConfig()
+				snapshotterConfig.DeltaSnapshotPeriod.Duration = 0
+				ssr, err := snapshotter.NewSnapshotter(logger, snapshotterConfig, historicalStore, etcdConnectionConfig, compressor.NewCompressorConfig(), brtypes.NewHealthConfig(), snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				snap, err := ssr.TakeFullSnapshotAtRevisionAndResetTimer(testCtx, revision)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(snap.LastRevision).Should(Equal(revision))
+
+				baseSnapshot, _, err := miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(historicalStore)
+				Expect(err).ShouldNot(HaveOccurred())
+				restorer, err = NewRestorer(historicalStore, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+				restorationConfig.DataDir = prefixRestoreDir
+				restoreOpts := brtypes.RestoreOptions{
+					Config:       restorationConfig,
+					BaseSnapshot: baseSnapshot,
+					ClusterURLs:  clusterUrlsMap,
+					PeerURLs:     peerUrls,
+				}
+				Expect(restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)).To(Succeed())
+				restoredEtcd, err := miscellaneous.StartEmbeddedEtcd(logger, &restoreOpts)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer func() {
+					restoredEtcd.Server.Stop()
+					restoredEtcd.Close()
+				}()
+
+				restoredClient, err := clientv3.New(clientv3.Config{Endpoints: []string{restoredEtcd.Clients[0].Addr().String()}, DialTimeout: 10 * time.Second})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer restoredClient.Close()
+				restoredResp, err := restoredClient.Get(testCtx, "", clientv3.WithPrefix())
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(restoredResp.Kvs).Should(HaveLen(len(liveResp.Kvs)))
+				for i, kv := range restoredResp.Kvs {
+					Expect(string(kv.Key)).Should(Equal(string(liveResp.Kvs[i].Key)))
+					Expect(string(kv.Value)).Should(Equal(string(liveResp.Kvs[i].Value)))
+				}
+				historicalResp, err := restoredClient.Get(testCtx, "/unscoped-historical/", clientv3.WithPrefix())
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(historicalResp.Kvs).Should(HaveLen(5))
+				for _, kv := range historicalResp.Kvs {
+					Expect(string(kv.Value)).Should(Equal("old"))
+				}
+			})
+		})
+
+		Context("with the full and delta snapshots saved under prefixes of their own", func() {
+			var kindsRestoreDir = filepath.Join(outputDir, "kinds.etcd")
+
+			AfterEach(func() {
+				Expect(os.RemoveAll(kindsRestoreDir)).To(Succeed())
+			})
+
+			It("should restore the chain of snapshots spanning both prefixes", func() {
+				liveClient, err := clientv3.New(clientv3.Config{Endpoints: endpoints, DialTimeout: 10 * time.Second})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer liveClient.Close()
+				for i := 0; i < 5; i++ {
+					_, err = liveClient.Put(testCtx, fmt.Sprintf("/kinds/key-%d", i), "full")
+					Expect(err).ShouldNot(HaveOccurred())
+				}
+
+				snapstoreConfig := &brtypes.SnapstoreConfig{
+					Container:           filepath.Join(outputDir, "kinds.bkp"),
+					Provider:            "Local",
+					FullSnapshotPrefix:  "fulls/v2",
+					DeltaSnapshotPrefix: "deltas/v2",
+				}
+				kindsStore, err := snapstore.GetSnapstore(snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer os.RemoveAll(snapstoreConfig.Container)
+				etcdConnectionConfig := brtypes.NewEtcdConnectionConfig()
+				etcdConnectionConfig.Endpoints = endpoints
+				etcdConnectionConfig.ConnectionTimeout.Duration = 10 * time.Second
+				snapshotterConfig := snapshotter.NewSnapshotterConfig()
+				snapshotterConfig.DeltaSnapshotPeriod.Duration = 0
+				ssr, err := snapshotter.NewSnapshotter(logger, snapshotterConfig, kindsStore, etcdConnectionConfig, compressor.NewCompressorConfig(), brtypes.NewHealthConfig(), snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				fullSnap, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				var deltaSnap *brtypes.Snapshot
+				for i := 0; i < 2; i++ {
+					_, err = liveClient.Put(testCtx, fmt.Sprintf("/kinds/key-%d", i), fmt.Sprintf("delta-%d", i))
+					Expect(err).ShouldNot(HaveOccurred())
+					stopped, err := ssr.CollectEventsSincePrevSnapshot(testCtx, make(chan struct{}))
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(stopped).Should(BeFalse())
+					deltaSnap, err = ssr.TakeDeltaSnapshot(testCtx)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(deltaSnap).ShouldNot(BeNil())
+				}
+				Expect(filepath.Join(snapstoreConfig.Container, "fulls", "v2", fullSnap.SnapDir, fullSnap.SnapName)).Should(BeARegularFile())
+				Expect(filepath.Join(snapstoreConfig.Container, "deltas", "v2", deltaSnap.SnapDir, deltaSnap.SnapName)).Should(BeARegularFile())
+
+				baseSnapshot, deltaSnapList, err := miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(kindsStore)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(baseSnapshot.SnapName).Should(Equal(fullSnap.SnapName))
+				Expect(deltaSnapList).Should(HaveLen(2))
+				Expect(deltaSnapList[1].SnapName).Should(Equal(deltaSnap.SnapName))
+				restorer, err = NewRestorer(kindsStore, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+				restorationConfig.DataDir = kindsRestoreDir
+				restoreOpts := brtypes.RestoreOptions{
+					Config:        restorationConfig,
+					BaseSnapshot:  baseSnapshot,
+					DeltaSnapList: deltaSnapList,
+					ClusterURLs:   clusterUrlsMap,
+					PeerURLs:      peerUrls,
+				}
+				Expect(restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)).To(Succeed())
+				restoredEtcd, err := miscellaneous.StartEmbeddedEtcd(logger, &restoreOpts)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer func() {
+					restoredEtcd.Server.Stop()
+					restoredEtcd.Close()
+				}()
+
+				restoredClient, err := clientv3.New(clientv3.Config{Endpoints: []string{restoredEtcd.Clients[0].Addr().String()}, DialTimeout: 10 * time.Second})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer restoredClient.Close()
+				restoredResp, err := restoredClient.Get(testCtx, "/kinds/", clientv3.WithPrefix())
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(restoredResp.Header.Revision).Should(Equal(deltaSnap.LastRevision))
+				Expect(restoredResp.Kvs).Should(HaveLen(5))
+				for i, kv := range restoredResp.Kvs {
+					value := "full"
+					if i < 2 {
+						value = fmt.Sprintf("delta-%d", i)
+					}
+					Expect(string(kv.Value)).Should(Equal(value))
+				}
+			})
+		})
+
+		Context("with delta snapshots being skipped", func() {
+			var (
+				skipRestoreDir = filepath.Join(outputDir, "skip.etcd")
+				skippedSnap    *brtypes.Snapshot
+			)
+
+			BeforeEach(func() {
+				liveClient, err := clientv3.New(clientv3.Config{Endpoints: endpoints, DialTimeout: 10 * time.Second})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer liveClient.Close()
+				_, err = liveClient.Put(testCtx, "full-key", "full")
+				Expect(err).ShouldNot(HaveOccurred())
+
+				etcdConnectionConfig := brtypes.NewEtcdConnectionConfig()
+				etcdConnectionConfig.Endpoints = endpoints
+				etcdConnectionConfig.ConnectionTimeout.Duration = 10 * time.Second
+				snapshotterConfig := snapshotter.NewSnapshotterConfig()
+				snapshotterConfig.DeltaSnapshotPeriod.Duration = 0
+				snapstoreConfig := &brtypes.SnapstoreConfig{Container: snapstoreDir, Provider: "Local"}
+				ssr, err := snapshotter.NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressor.NewCompressorConfig(), brtypes.NewHealthConfig(), snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				_, err = ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				// each delta snapshot holds the keys of its own group
+				for _, group := range []string{"first", "poisoned", "last"} {
+					for i := 0; i < 3; i++ {
+						_, err = liveClient.Put(testCtx, fmt.Sprintf("%s-key-%d", group, i), group)
+						Expect(err).ShouldNot(HaveOccurred())
+					}
+					stopped, err := ssr.CollectEventsSincePrevSnapshot(testCtx, make(chan struct{}))
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(stopped).Should(BeFalse())
+					deltaSnap, err := ssr.TakeDeltaSnapshot(testCtx)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(deltaSnap).ShouldNot(BeNil())
+				}
+
+				baseSnapshot, deltaSnapList, err = miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(store)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(deltaSnapList).Should(HaveLen(3))
+				skippedSnap = deltaSnapList[1]
+				restorer, err = NewRestorer(store, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+				restorationConfig.DataDir = skipRestoreDir
+			})
+
+			AfterEach(func() {
+				Expect(os.RemoveAll(skipRestoreDir)).To(Succeed())
+			})
+
+			It("should restore the events of all but the skipped delta snapshot", func() {
+				restoreOpts := brtypes.RestoreOptions{
+					Config:             restorationConfig,
+					BaseSnapshot:       baseSnapshot,
+					DeltaSnapList:      deltaSnapList,
+					ClusterURLs:        clusterUrlsMap,
+					PeerURLs:           peerUrls,
+					SkipDeltaRevisions: []int64{skippedSnap.StartRevision + 1},
+				}
+				restoredEtcd, err := restorer.Restore(testCtx, restoreOpts, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(restoredEtcd).ShouldNot(BeNil())
+				defer func() {
+					restoredEtcd.Server.Stop()
+					restoredEtcd.Close()
+				}()
+
+				restoredClient, err := clientv3.New(clientv3.Config{Endpoints: []string{restoredEtcd.Clients[0].Addr().String()}, DialTimeout: 10 * time.Second})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer restoredClient.Close()
+
+				restoredResp, err := restoredClient.Get(testCtx, "", clientv3.WithPrefix(), clientv3.WithKeysOnly())
+				Expect(err).ShouldNot(HaveOccurred())
+				var keys []string
+				for _, kv := range restoredResp.Kvs {
+					keys = append(keys, string(kv.Key))
+				}
+				Expect(keys).Should(ContainElements("full-key", "first-key-0", "first-key-1", "first-key-2", "last-key-0", "last-key-1", "last-key-2"))
+				Expect(keys).ShouldNot(ContainElement(HavePrefix("poisoned-key-")))
+			})
+
+			It("should report the skipped delta snapshot and the fallback from the chain manifest", func() {
+				reportPath := filepath.Join(outputDir, "restore-report.json")
+				defer os.Remove(reportPath)
+				restorationConfig.RestoreReportPath = reportPath
+				restoreOpts := brtypes.RestoreOptions{
+					Config:                restorationConfig,
+					BaseSnapshot:          baseSnapshot,
+					DeltaSnapList:         deltaSnapList,
+					ClusterURLs:           clusterUrlsMap,
+					PeerURLs:              peerUrls,
+					SkipDeltaRevisions:    []int64{skippedSnap.StartRevision + 1},
+					ChainManifestFallback: "no chain manifest found",
+				}
+				Expect(restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)).To(Succeed())
+
+				data, err := os.ReadFile(reportPath)
+				Expect(err).ShouldNot(HaveOccurred())
+				report := &brtypes.RestoreReport{}
+				Expect(json.Unmarshal(data, report)).To(Succeed())
+				Expect(report).Should(Equal(restorer.LastRestoreReport()))
+				Expect(report.Succeeded).Should(BeTrue())
+				Expect(report.Error).Should(BeEmpty())
+				Expect(report.BaseSnapshot).Should(Equal(path.Join(baseSnapshot.SnapDir, baseSnapshot.SnapName)))
+				Expect(report.DeltaSnapshots).Should(Equal([]string{
+					path.Join(deltaSnapList[0].SnapDir, deltaSnapList[0].SnapName),
+					path.Join(deltaSnapList[2].SnapDir, deltaSnapList[2].SnapName),
+				}))
+				Expect(report.SkippedDeltaSnapshots).Should(Equal([]string{path.Join(skippedSnap.SnapDir, skippedSnap.SnapName)}))
+				// the events of the skipped delta snapshot don't advance the revision of the restored etcd
+				Expect(report.FinalRevision).Should(Equal(deltaSnapList[2].LastRevision - (skippedSnap.LastRevision - skippedSnap.StartRevision + 1)))
+				Expect(report.Duration).Should(BeNumerically(">", 0))
+				Expect(report.Warnings).Should(ContainElements(
+					ContainSubstring("Skipping delta snapshot %s", path.Join(skippedSnap.SnapDir, skippedSnap.SnapName)),
+					ContainSubstring("no chain manifest found"),
+				))
+			})
+
+			It("should fail to restore if the restoration is checkpointed", func() {
+				restorationConfig.RestoreCheckpointInterval = 1
+				restoreOpts := brtypes.RestoreOptions{
+					Config:             restorationConfig,
+					BaseSnapshot:       baseSnapshot,
+					DeltaSnapList:      deltaSnapList,
+					ClusterURLs:        clusterUrlsMap,
+					PeerURLs:           peerUrls,
+					SkipDeltaRevisions: []int64{skippedSnap.LastRevision},
+				}
+				err := restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).Should(MatchError(ContainSubstring("cannot be checkpointed")))
+			})
+		})
+
+		Context("with only the base snapshot being restored", func() {
+			var baseOnlyRestoreDir = filepath.Join(outputDir, "base-only.etcd")
+
+			BeforeEach(func() {
+				liveClient, err := clientv3.New(clientv3.Config{Endpoints: endpoints, DialTimeout: 10 * time.Second})
+				Expect(err).ShouldNot(HaveOccurred())
+				defer liveClient.Close()
+				_, err = liveClient.Put(testCtx, "full-key", "full")
+				Expect(err).ShouldNot(HaveOccurred())
+
+				etcdConnectionConfig := brtypes.NewEtcdConnectionConfig()
+				etcdConnectionConfig.Endpoints = endpoints
+				etcdConnectionConfig.ConnectionTimeout.Duration = 10 * time.Second
+				snapshotterConfig := snapshotter.NewSnapshotterConfig()
+				snapshotterConfig.DeltaSnapshotPeriod.Duration = 0
+				snapstoreConfig := &brtypes.SnapstoreConfig{Container: snapstoreDir, Provider: "Local"}
+				ssr, err := snapshotter.NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressor.NewCompressorConfig(), brtypes.NewHealthConfig(), snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				_, err = ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				for i := 0; i < 2; i++ {
+					_, err = liveClient.Put(testCtx, fmt.Sprintf("delta-key-%d", i), "delta")
+					Expect(err).ShouldNot(HaveOccurred())
+					stopped, err := ssr.CollectEventsSincePrevSnapshot(testCtx, make(chan struct{}))
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(stopped).Should(BeFalse())
+					deltaSnap, err := ssr.TakeDeltaSnapshot(testCtx)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(deltaSnap).ShouldNot(BeNil())
+				}
+
+				baseSnapshot, deltaSnapList, err = miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(store)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(deltaSnapList).Should(HaveLen(2))
+				restorer, err = NewRestorer(store, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+				restorationConfig.DataDir = baseOnlyRestoreDir
+			})
+
+			AfterEach(func() {
+				Expect(os.RemoveAll(baseOnlyRestoreDir)).To(Succeed())
+			})
+
+			It("should restore up to the last revision of the base snapshot without the events of the delta snapshots", func() {
+				restoreOpts := brtypes.RestoreOptions{
+					Config:        restorationConfig,
+					BaseSnapshot:  baseSnapshot,
+					DeltaSnapList: deltaSnapList,
+					ClusterURLs:   clusterUrlsMap,
+					PeerURLs:      peerUrls,
+					BaseOnly:      true,
+				}
+				Expect(restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)).To(Succeed())
+				restoredEtcd, err := miscellaneous.StartEmbeddedEtcd(logger, &restoreOpts)
 				Expect(err).ShouldNot(HaveOccurred())
+				defer func() {
+					restoredEtcd.Server.Stop()
+					restoredEtcd.Close()
+				}()
 
-				baseSnapshot, deltaSnapList, err = miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(store)
+				restoredClient, err := clientv3.New(clientv3.Config{Endpoints: []string{restoredEtcd.Clients[0].Addr().String()}, DialTimeout: 10 * time.Second})
 				Expect(err).ShouldNot(HaveOccurred())
-				logger.Infof("No of delta snapshots: %d", deltaSnapList.Len())
-				logger.Infof("Base snapshot is %v", baseSnapshot)
+				defer restoredClient.Close()
 
-				restorer, err = NewRestorer(store, logger)
+				restoredResp, err := restoredClient.Get(testCtx, "", clientv3.WithPrefix(), clientv3.WithKeysOnly())
 				Expect(err).ShouldNot(HaveOccurred())
+				Expect(restoredResp.Header.Revision).Should(Equal(baseSnapshot.LastRevision))
+				var keys []string
+				for _, kv := range restoredResp.Kvs {
+					keys = append(keys, string(kv.Key))
+				}
+				Expect(keys).Should(ContainElement("full-key"))
+				Expect(keys).ShouldNot(ContainElement(HavePrefix("delta-key-")))
+			})
+
+			It("should report the discarded delta snapshots", func() {
 				restoreOpts := brtypes.RestoreOptions{
 					Config:        restorationConfig,
 					BaseSnapshot:  baseSnapshot,
 					DeltaSnapList: deltaSnapList,
 					ClusterURLs:   clusterUrlsMap,
 					PeerURLs:      peerUrls,
+					BaseOnly:      true,
 				}
+				Expect(restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)).To(Succeed())
+
+				report := restorer.LastRestoreReport()
+				Expect(report).ShouldNot(BeNil())
+				Expect(report.Succeeded).Should(BeTrue())
+				Expect(report.DeltaSnapshots).Should(BeEmpty())
+				Expect(report.DiscardedDeltaSnapshots).Should(Equal(2))
+				Expect(report.FinalRevision).Should(Equal(baseSnapshot.LastRevision))
+				Expect(report.Warnings).Should(ContainElement(ContainSubstring("Restoring only the base snapshot")))
+			})
 
-				if baseSnapshot != nil {
-					restoreOpts.BaseSnapshot.SnapDir = ""
-					restoreOpts.BaseSnapshot.SnapName = ""
+			It("should fail to restore if delta snapshots are skipped as well", func() {
+				restoreOpts := brtypes.RestoreOptions{
+					Config:             restorationConfig,
+					BaseSnapshot:       baseSnapshot,
+					DeltaSnapList:      deltaSnapList,
+					ClusterURLs:        clusterUrlsMap,
+					PeerURLs:           peerUrls,
+					BaseOnly:           true,
+					SkipDeltaRevisions: []int64{deltaSnapList[0].LastRevision},
 				}
+				err := restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+				Expect(err).Should(MatchError(ContainSubstring("cannot be combined")))
 
-				err = restorer.RestoreAndStopEtcd(restoreOpts, nil)
+				report := restorer.LastRestoreReport()
+				Expect(report).ShouldNot(BeNil())
+				Expect(report.Succeeded).Should(BeFalse())
+				Expect(report.Error).Should(ContainSubstring("cannot be combined"))
+			})
+		})
+
+		Context("following the snapstore with a standby etcd", func() {
+			var (
+				standbyRestoreDir = filepath.Join(outputDir, "standby.etcd")
+				liveClient        *clientv3.Client
+				ssr               *snapshotter.Snapshotter
+				standbyEtcd       *embed.Etcd
+				follower          *Follower
+			)
+
+			// putAndTakeDeltaSnapshot puts the given keys into the live etcd and takes a delta snapshot of them.
+			putAndTakeDeltaSnapshot := func(keys ...string) *brtypes.Snapshot {
+				for _, key := range keys {
+					_, err := liveClient.Put(testCtx, key, "value")
+					Expect(err).ShouldNot(HaveOccurred())
+				}
+				stopped, err := ssr.CollectEventsSincePrevSnapshot(testCtx, make(chan struct{}))
 				Expect(err).ShouldNot(HaveOccurred())
+				Expect(stopped).Should(BeFalse())
+				deltaSnap, err := ssr.TakeDeltaSnapshot(testCtx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(deltaSnap).ShouldNot(BeNil())
+				return deltaSnap
+			}
 
-				err = utils.CheckDataConsistency(testCtx, restoreOpts.Config.DataDir, keyTo, logger)
+			// standbyKeys returns the keys of the standby etcd along with its revision.
+			standbyKeys := func() ([]string, int64) {
+				standbyClient, err := clientv3.New(clientv3.Config{Endpoints: []string{standbyEtcd.Clients[0].Addr().String()}, DialTimeout: 10 * time.Second})
 				Expect(err).ShouldNot(HaveOccurred())
-			})
-		})
+				defer standbyClient.Close()
+				resp, err := standbyClient.Get(testCtx, "", clientv3.WithPrefix(), clientv3.WithKeysOnly())
+				Expect(err).ShouldNot(HaveOccurred())
+				var keys []string
+				for _, kv := range resp.Kvs {
+					keys = append(keys, string(kv.Key))
+				}
+				return keys, resp.Header.Revision
+			}
 
-		Context("with no delta snapshots", func() {
-			It("Should restore only full snapshot", func() {
-				deltaSnapshotPeriod = time.Duration(0)
-				logger.Infoln("Starting snapshotter for no delta snapshots")
-				wg.Add(1)
-				populatorCtx, cancelPopulator := context.WithTimeout(testCtx, 2*time.Second)
-				go utils.PopulateEtcdWithWaitGroup(populatorCtx, wg, logger, endpoints, nil)
-				defer cancelPopulator()
-				ssrCtx := utils.ContextWithWaitGroupFollwedByGracePeriod(testCtx, wg, time.Second)
-				compressionConfig := compressor.NewCompressorConfig()
-				snapstoreConfig := brtypes.SnapstoreConfig{Container: snapstoreDir, Provider: "Local"}
-				err = utils.RunSnapshotter(logger, snapstoreConfig, deltaSnapshotPeriod, endpoints, ssrCtx.Done(), true, compressionConfig)
+			BeforeEach(func() {
+				var err error
+				liveClient, err = clientv3.New(clientv3.Config{Endpoints: endpoints, DialTimeout: 10 * time.Second})
+				Expect(err).ShouldNot(HaveOccurred())
+				DeferCleanup(liveClient.Close)
+				_, err = liveClient.Put(testCtx, "full-key", "full")
 				Expect(err).ShouldNot(HaveOccurred())
-				etcd.Server.Stop()
-				etcd.Close()
 
-				err = corruptEtcdDir()
+				etcdConnectionConfig := brtypes.NewEtcdConnectionConfig()
+				etcdConnectionConfig.Endpoints = endpoints
+				etcdConnectionConfig.ConnectionTimeout.Duration = 10 * time.Second
+				snapshotterConfig := snapshotter.NewSnapshotterConfig()
+				snapshotterConfig.DeltaSnapshotPeriod.Duration = 0
+				snapstoreConfig := &brtypes.SnapstoreConfig{Container: snapstoreDir, Provider: "Local"}
+				ssr, err = snapshotter.NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressor.NewCompressorConfig(), brtypes.NewHealthConfig(), snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				_, err = ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
 				Expect(err).ShouldNot(HaveOccurred())
+				putAndTakeDeltaSnapshot("restored-key")
 
+				// the standby etcd is restored from the snapshots before following them
 				baseSnapshot, deltaSnapList, err = miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(store)
 				Expect(err).ShouldNot(HaveOccurred())
-				Expect(deltaSnapList.Len()).Should(BeZero())
-
 				restorer, err = NewRestorer(store, logger)
 				Expect(err).ShouldNot(HaveOccurred())
-
+				restorationConfig.DataDir = standbyRestoreDir
 				restoreOpts := brtypes.RestoreOptions{
 					Config:        restorationConfig,
 					BaseSnapshot:  baseSnapshot,
@@ -362,11 +1611,107 @@ var _ = Describe("Running Restorer", func() {
 					ClusterURLs:   clusterUrlsMap,
 					PeerURLs:      peerUrls,
 				}
+				Expect(restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)).To(Succeed())
+				standbyEtcd, err = miscellaneous.StartEmbeddedEtcd(logger, &restoreOpts)
+				Expect(err).ShouldNot(HaveOccurred())
+				DeferCleanup(func() {
+					standbyEtcd.Server.Stop()
+					standbyEtcd.Close()
+					Expect(os.RemoveAll(standbyRestoreDir)).To(Succeed())
+				})
+
+				followerConfig := brtypes.NewFollowerConfig()
+				followerConfig.PollInterval.Duration = 100 * time.Millisecond
+				clientFactory := etcdutil.NewFactory(brtypes.EtcdConnectionConfig{
+					Endpoints:         []string{standbyEtcd.Clients[0].Addr().String()},
+					InsecureTransport: true,
+				})
+				follower, err = NewFollower(store, clientFactory, followerConfig, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+
+			It("should apply the new delta snapshots to the standby etcd", func() {
+				putAndTakeDeltaSnapshot("first-key-0", "first-key-1")
+				deltaSnap := putAndTakeDeltaSnapshot("second-key-0")
+
+				revision, err := follower.Sync(testCtx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(revision).Should(Equal(deltaSnap.LastRevision))
+				keys, standbyRevision := standbyKeys()
+				Expect(standbyRevision).Should(Equal(deltaSnap.LastRevision))
+				Expect(keys).Should(ContainElements("full-key", "restored-key", "first-key-0", "first-key-1", "second-key-0"))
+
+				// nothing is applied without new delta snapshots
+				revision, err = follower.Sync(testCtx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(revision).Should(Equal(deltaSnap.LastRevision))
+			})
+
+			It("should converge to the latest revision while new delta snapshots arrive", func() {
+				ctx, cancel := context.WithCancel(testCtx)
+				done := make(chan error)
+				go func() {
+					done <- follower.Run(ctx)
+				}()
+				defer func() {
+					cancel()
+					Eventually(done, 10*time.Second).Should(Receive(BeNil()))
+				}()
+
+				for i := 0; i < 3; i++ {
+					deltaSnap := putAndTakeDeltaSnapshot(fmt.Sprintf("arriving-key-%d", i))
+					Eventually(func() int64 {
+						_, revision := standbyKeys()
+						return revision
+					}, 10*time.Second, 50*time.Millisecond).Should(Equal(deltaSnap.LastRevision))
+				}
+				keys, _ := standbyKeys()
+				Expect(keys).Should(ContainElements("arriving-key-0", "arriving-key-1", "arriving-key-2"))
+			})
+
+			It("should follow the snapstore across a full snapshot with the delta snapshots before it", func() {
+				putAndTakeDeltaSnapshot("first-key-0")
+				// the events collected since the previous delta snapshot are flushed into a delta snapshot before the full snapshot
+				stopped, err := ssr.CollectEventsSincePrevSnapshot(testCtx, make(chan struct{}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(stopped).Should(BeFalse())
+				_, err = liveClient.Put(testCtx, "flushed-key", "value")
+				Expect(err).ShouldNot(HaveOccurred())
+				fullSnap, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+				Expect(err).ShouldNot(HaveOccurred())
+				deltaSnap := putAndTakeDeltaSnapshot("second-key-0")
 
-				err = restorer.RestoreAndStopEtcd(restoreOpts, nil)
+				revision, err := follower.Sync(testCtx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(revision).Should(Equal(deltaSnap.LastRevision))
+				keys, standbyRevision := standbyKeys()
+				Expect(standbyRevision).Should(Equal(deltaSnap.LastRevision))
+				Expect(standbyRevision).Should(BeNumerically(">", fullSnap.LastRevision))
+				Expect(keys).Should(ContainElements("full-key", "restored-key", "first-key-0", "flushed-key", "second-key-0"))
+			})
+
+			It("should fail once the delta snapshots before the latest full snapshot are missing", func() {
+				_, standbyRevision := standbyKeys()
+				stopped, err := ssr.CollectEventsSincePrevSnapshot(testCtx, make(chan struct{}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(stopped).Should(BeFalse())
+				_, err = liveClient.Put(testCtx, "missed-key", "value")
+				Expect(err).ShouldNot(HaveOccurred())
+				_, err = ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+				Expect(err).ShouldNot(HaveOccurred())
 
+				// the delta snapshots of the previous chain are gone, e.g. garbage collected
+				snapList, err := store.List()
 				Expect(err).ShouldNot(HaveOccurred())
+				for _, snap := range snapList {
+					if snap.Kind == brtypes.SnapshotKindDelta && snap.LastRevision > standbyRevision {
+						Expect(store.Delete(*snap)).To(Succeed())
+					}
+				}
 
+				_, err = follower.Sync(testCtx)
+				Expect(err).Should(MatchError(ErrFollowerBehindFullSnapshot))
+				Expect(follower.Run(testCtx)).Should(MatchError(ErrFollowerBehindFullSnapshot))
 			})
 		})
 
@@ -408,7 +1753,7 @@ var _ = Describe("Running Restorer", func() {
 					PeerURLs:      peerUrls,
 				}
 
-				err = restorer.RestoreAndStopEtcd(restoreOpts, nil)
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
 				Expect(err).Should(HaveOccurred())
 				// the below consistency fails with index out of range error hence commented,
 				// but the etcd directory is filled partially as part of the restore which should be relooked.
@@ -447,7 +1792,7 @@ var _ = Describe("Running Restorer", func() {
 				}
 
 				logger.Infoln("starting restore, restore directory exists already")
-				err = restorer.RestoreAndStopEtcd(restoreOpts, nil)
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
 				logger.Infof("Failed to restore because :: %s", err)
 
 				Expect(err).Should(HaveOccurred())
@@ -530,7 +1875,7 @@ var _ = Describe("Running Restorer", func() {
 					PeerURLs:      peerUrls,
 				}
 
-				err = restorer.RestoreAndStopEtcd(restoreOpts, nil)
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
 				Expect(err).ShouldNot(HaveOccurred())
 				err = utils.CheckDataConsistency(testCtx, restoreOpts.Config.DataDir, keyTo, logger)
 				Expect(err).ShouldNot(HaveOccurred())
@@ -616,7 +1961,7 @@ var _ = Describe("Running Restorer", func() {
 					PeerURLs:      peerUrls,
 				}
 
-				err = restorer.RestoreAndStopEtcd(restoreOpts, nil)
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
 				Expect(err).ShouldNot(HaveOccurred())
 				err = utils.CheckDataConsistency(testCtx, restoreOpts.Config.DataDir, keyTo, logger)
 				Expect(err).ShouldNot(HaveOccurred())
@@ -671,7 +2016,7 @@ var _ = Describe("Running Restorer", func() {
 					clientKV, err := factory.NewKV()
 					Expect(err).ShouldNot(HaveOccurred())
 
-					err = restorer.MakeEtcdLeanAndCheckAlarm(dummyRevisionNo, dummyEtcdEndpoints, dummyEmbeddedEtcdQuotaBytes, dbSizeAlarmCh, dbSizeDisAlarmCh, clientKV, clientMaintenance)
+					err = restorer.MakeEtcdLeanAndCheckAlarm(testCtx, dummyRevisionNo, dummyEtcdEndpoints, dummyEmbeddedEtcdQuotaBytes, dbSizeAlarmCh, dbSizeDisAlarmCh, clientKV, clientMaintenance)
 					Expect(err).Should(HaveOccurred())
 				})
 			})
@@ -695,7 +2040,7 @@ var _ = Describe("Running Restorer", func() {
 					clientKV, err := factory.NewKV()
 					Expect(err).ShouldNot(HaveOccurred())
 
-					err = restorer.MakeEtcdLeanAndCheckAlarm(dummyRevisionNo, dummyEtcdEndpoints, dummyEmbeddedEtcdQuotaBytes, dbSizeAlarmCh, dbSizeDisAlarmCh, clientKV, clientMaintenance)
+					err = restorer.MakeEtcdLeanAndCheckAlarm(testCtx, dummyRevisionNo, dummyEtcdEndpoints, dummyEmbeddedEtcdQuotaBytes, dbSizeAlarmCh, dbSizeDisAlarmCh, clientKV, clientMaintenance)
 					Expect(err).Should(HaveOccurred())
 				})
 			})
@@ -723,7 +2068,7 @@ var _ = Describe("Running Restorer", func() {
 					clientKV, err := factory.NewKV()
 					Expect(err).ShouldNot(HaveOccurred())
 
-					err = restorer.MakeEtcdLeanAndCheckAlarm(dummyRevisionNo, dummyEtcdEndpoints, dummyEmbeddedEtcdQuotaBytes, dbSizeAlarmCh, dbSizeDisAlarmCh, clientKV, clientMaintenance)
+					err = restorer.MakeEtcdLeanAndCheckAlarm(testCtx, dummyRevisionNo, dummyEtcdEndpoints, dummyEmbeddedEtcdQuotaBytes, dbSizeAlarmCh, dbSizeDisAlarmCh, clientKV, clientMaintenance)
 					Expect(err).ShouldNot(HaveOccurred())
 				})
 			})
@@ -769,7 +2114,7 @@ var _ = Describe("Running Restorer", func() {
 
 					go restorer.HandleAlarm(stopHandleAlarmCh, dbSizeAlarmCh, dbSizeDisAlarmCh, clientMaintenance)
 					defer close(stopHandleAlarmCh)
-					err = restorer.MakeEtcdLeanAndCheckAlarm(dummyRevisionNo, dummyEtcdEndpoints, dummyEmbeddedEtcdQuotaBytes, dbSizeAlarmCh, dbSizeDisAlarmCh, clientKV, clientMaintenance)
+					err = restorer.MakeEtcdLeanAndCheckAlarm(testCtx, dummyRevisionNo, dummyEtcdEndpoints, dummyEmbeddedEtcdQuotaBytes, dbSizeAlarmCh, dbSizeDisAlarmCh, clientKV, clientMaintenance)
 					Expect(err).Should(HaveOccurred())
 				})
 			})
@@ -809,7 +2154,7 @@ var _ = Describe("Running Restorer", func() {
 
 					go restorer.HandleAlarm(stopHandleAlarmCh, dbSizeAlarmCh, dbSizeDisAlarmCh, clientMaintenance)
 					defer close(stopHandleAlarmCh)
-					err = restorer.MakeEtcdLeanAndCheckAlarm(dummyRevisionNo, dummyEtcdEndpoints, dummyEmbeddedEtcdQuotaBytes, dbSizeAlarmCh, dbSizeDisAlarmCh, clientKV, clientMaintenance)
+					err = restorer.MakeEtcdLeanAndCheckAlarm(testCtx, dummyRevisionNo, dummyEtcdEndpoints, dummyEmbeddedEtcdQuotaBytes, dbSizeAlarmCh, dbSizeDisAlarmCh, clientKV, clientMaintenance)
 					Expect(err).ShouldNot(HaveOccurred())
 				})
 			})
@@ -927,7 +2272,7 @@ var _ = Describe("Running Restorer when both v1 and v2 directory structures are
 			err = os.RemoveAll(path.Join(etcdDataDir, "member"))
 			Expect(err).ShouldNot(HaveOccurred())
 
-			err = restorer.RestoreAndStopEtcd(restoreOpts, nil)
+			err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
 			Expect(err).ShouldNot(HaveOccurred())
 			err = utils.CheckDataConsistency(testCtx, restoreOpts.Config.DataDir, resp.KeyTo, logger)
 			Expect(err).ShouldNot(HaveOccurred())
@@ -960,7 +2305,7 @@ var _ = Describe("Running Restorer when both v1 and v2 directory structures are
 			err = os.RemoveAll(path.Join(etcdDataDir, "member"))
 			Expect(err).ShouldNot(HaveOccurred())
 
-			err = restorer.RestoreAndStopEtcd(restoreOpts, nil)
+			err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
 			Expect(err).ShouldNot(HaveOccurred())
 			err = utils.CheckDataConsistency(testCtx, restoreOpts.Config.DataDir, resp.KeyTo, logger)
 			Expect(err).ShouldNot(HaveOccurred())
@@ -993,7 +2338,7 @@ var _ = Describe("Running Restorer when both v1 and v2 directory structures are
 			err = os.RemoveAll(path.Join(etcdDataDir, "member"))
 			Expect(err).ShouldNot(HaveOccurred())
 
-			err = restorer.RestoreAndStopEtcd(restoreOpts, nil)
+			err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
 			Expect(err).ShouldNot(HaveOccurred())
 			err = utils.CheckDataConsistency(testCtx, restoreOpts.Config.DataDir, resp.KeyTo, logger)
 			Expect(err).ShouldNot(HaveOccurred())
@@ -1028,7 +2373,7 @@ var _ = Describe("Running Restorer when both v1 and v2 directory structures are
 			err = os.RemoveAll(path.Join(etcdDataDir, "member"))
 			Expect(err).ShouldNot(HaveOccurred())
 
-			err = restorer.RestoreAndStopEtcd(restoreOpts, nil)
+			err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
 			Expect(err).ShouldNot(HaveOccurred())
 			err = utils.CheckDataConsistency(testCtx, restoreOpts.Config.DataDir, resp.KeyTo, logger)
 			Expect(err).ShouldNot(HaveOccurred())
@@ -1062,7 +2407,7 @@ var _ = Describe("Running Restorer when both v1 and v2 directory structures are
 				err = os.RemoveAll(path.Join(etcdDataDir, "member"))
 				Expect(err).ShouldNot(HaveOccurred())
 
-				err = restorer.RestoreAndStopEtcd(restoreOpts, nil)
+				err = restorer.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
 				Expect(err).Should(HaveOccurred())
 			})
 		})
@@ -1108,6 +2453,327 @@ var _ = Describe("Unit testing individual functions for restorer package", func(
 
 })
 
+var _ = Describe("Exporting the base snapshot", func() {
+	var (
+		store        brtypes.SnapStore
+		snapshotData []byte
+	)
+
+	BeforeEach(func() {
+		store, err = snapstore.GetSnapstore(&brtypes.SnapstoreConfig{Container: GinkgoT().TempDir(), Provider: "Local"})
+		Expect(err).ShouldNot(HaveOccurred())
+		snapshotData = []byte(strings.Repeat("etcd-db-contents", 1024))
+	})
+
+	saveSnapshot := func(kind string, compressionPolicy string) *brtypes.Snapshot {
+		compressionSuffix, err := compressor.GetCompressionSuffix(compressionPolicy != "", compressionPolicy)
+		Expect(err).ShouldNot(HaveOccurred())
+		snap := snapstore.NewSnapshot(kind, 0, 1, compressionSuffix, false)
+
+		rc := io.NopCloser(strings.NewReader(string(snapshotData)))
+		if compressionPolicy != "" {
+			rc, err = compressor.CompressSnapshot(rc, compressionPolicy)
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+		Expect(store.Save(*snap, rc)).To(Succeed())
+
+		// return the snapshot as listed by the store, which sets the prefix required to fetch it
+		snapList, err := store.List()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(snapList).To(HaveLen(1))
+		return snapList[0]
+	}
+
+	Context("with an uncompressed base snapshot", func() {
+		It("should write the stored snapshot to the writer", func() {
+			snap := saveSnapshot(brtypes.SnapshotKindFull, "")
+
+			var buf strings.Builder
+			Expect(ExportBaseSnapshot(store, snap, &buf)).To(Succeed())
+			Expect(buf.String()).To(Equal(string(snapshotData)))
+		})
+	})
+
+	Context("with a compressed base snapshot", func() {
+		for _, policy := range []string{compressor.GzipCompressionPolicy, compressor.LzwCompressionPolicy, compressor.ZlibCompressionPolicy} {
+			policy := policy
+			It(fmt.Sprintf("should write the snapshot decompressed using %s to the writer", policy), func() {
+				snap := saveSnapshot(brtypes.SnapshotKindFull, policy)
+
+				var buf strings.Builder
+				Expect(ExportBaseSnapshot(store, snap, &buf)).To(Succeed())
+				Expect(buf.String()).To(Equal(string(snapshotData)))
+			})
+		}
+	})
+
+	Context("with a delta snapshot", func() {
+		It("should return an error", func() {
+			snap := saveSnapshot(brtypes.SnapshotKindDelta, "")
+
+			var buf strings.Builder
+			Expect(ExportBaseSnapshot(store, snap, &buf)).ShouldNot(Succeed())
+			Expect(buf.Len()).To(BeZero())
+		})
+	})
+
+	Context("with a snapshot missing from the store", func() {
+		It("should return an error", func() {
+			snap := snapstore.NewSnapshot(brtypes.SnapshotKindFull, 0, 1, "", false)
+			snap.Prefix = "v2"
+
+			var buf strings.Builder
+			Expect(ExportBaseSnapshot(store, snap, &buf)).ShouldNot(Succeed())
+		})
+	})
+})
+
+var _ = Describe("Exporting a delta snapshot", func() {
+	var (
+		store     brtypes.SnapStore
+		eventTime time.Time
+	)
+
+	// saveDeltaSnapshot saves a delta snapshot holding a put of a short and of a long value, and a delete, starting at
+	// revision 11, and returns it as listed by the store.
+	saveDeltaSnapshot := func(compressionPolicy string) *brtypes.Snapshot {
+		events := []brtypes.Event{
+			{
+				EtcdEvent: &clientv3.Event{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: []byte("/registry/foo"), Value: []byte("bar"), ModRevision: 11}},
+				Time:      eventTime,
+			},
+			{
+				EtcdEvent: &clientv3.Event{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: []byte("/registry/long"), Value: []byte(strings.Repeat("x", 100)), ModRevision: 12}},
+				Time:      eventTime.Add(time.Second),
+			},
+			{
+				EtcdEvent: &clientv3.Event{Type: mvccpb.DELETE, Kv: &mvccpb.KeyValue{Key: []byte("/registry/foo"), ModRevision: 13}},
+				Time:      eventTime.Add(2 * time.Second),
+			},
+		}
+		data, err := json.Marshal(events)
+		Expect(err).ShouldNot(HaveOccurred())
+		hash := sha256.Sum256(data)
+
+		compressionSuffix, err := compressor.GetCompressionSuffix(compressionPolicy != "", compressionPolicy)
+		Expect(err).ShouldNot(HaveOccurred())
+		snap := snapstore.NewSnapshot(brtypes.SnapshotKindDelta, 11, 13, compressionSuffix, false)
+		rc := io.NopCloser(bytes.NewReader(append(data, hash[:]...)))
+		if compressionPolicy != "" {
+			rc, err = compressor.CompressSnapshot(rc, compressionPolicy)
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+		Expect(store.Save(*snap, rc)).To(Succeed())
+
+		snapList, err := store.List()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(snapList).To(HaveLen(1))
+		return snapList[0]
+	}
+
+	BeforeEach(func() {
+		store, err = snapstore.GetSnapstore(&brtypes.SnapstoreConfig{Container: GinkgoT().TempDir(), Provider: "Local"})
+		Expect(err).ShouldNot(HaveOccurred())
+		eventTime = time.Date(2024, 5, 6, 14, 32, 0, 0, time.UTC)
+	})
+
+	It("should render the events as a table with truncated values", func() {
+		snap := saveDeltaSnapshot("")
+
+		var buf strings.Builder
+		Expect(ExportDeltaSnapshot(store, snap, DeltaExportFormatText, &buf)).To(Succeed())
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		Expect(lines).To(HaveLen(4))
+		Expect(strings.Fields(lines[0])).To(Equal([]string{"REVISION", "TIME", "OPERATION", "KEY", "VALUE"}))
+		Expect(strings.Fields(lines[1])).To(Equal([]string{"11", "2024-05-06T14:32:00Z", "PUT", `"/registry/foo"`, `"bar"`}))
+		Expect(strings.Fields(lines[2])).To(Equal([]string{"12", "2024-05-06T14:32:01Z", "PUT", `"/registry/long"`, `"` + strings.Repeat("x", 64) + `..."`}))
+		Expect(strings.Fields(lines[3])).To(Equal([]string{"13", "2024-05-06T14:32:02Z", "DELETE", `"/registry/foo"`, `""`}))
+	})
+
+	It("should render the events of a compressed delta snapshot as JSON lines", func() {
+		snap := saveDeltaSnapshot(compressor.GzipCompressionPolicy)
+
+		var buf strings.Builder
+		Expect(ExportDeltaSnapshot(store, snap, DeltaExportFormatJSONLines, &buf)).To(Succeed())
+		var changes []DeltaSnapshotChange
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var change DeltaSnapshotChange
+			Expect(json.Unmarshal([]byte(line), &change)).To(Succeed())
+			changes = append(changes, change)
+		}
+		Expect(changes).To(Equal([]DeltaSnapshotChange{
+			{Revision: 11, Time: eventTime, Operation: "PUT", Key: "/registry/foo", ValuePreview: "bar"},
+			{Revision: 12, Time: eventTime.Add(time.Second), Operation: "PUT", Key: "/registry/long", ValuePreview: strings.Repeat("x", 64) + "..."},
+			{Revision: 13, Time: eventTime.Add(2 * time.Second), Operation: "DELETE", Key: "/registry/foo"},
+		}))
+	})
+
+	It("should return an error for an unsupported format", func() {
+		snap := saveDeltaSnapshot("")
+
+		var buf strings.Builder
+		Expect(ExportDeltaSnapshot(store, snap, "yaml", &buf)).Should(MatchError(ContainSubstring("unsupported delta snapshot export format")))
+		Expect(buf.Len()).To(BeZero())
+	})
+
+	It("should return an error for a full snapshot", func() {
+		snap := snapstore.NewSnapshot(brtypes.SnapshotKindFull, 0, 10, "", false)
+
+		var buf strings.Builder
+		Expect(ExportDeltaSnapshot(store, snap, DeltaExportFormatText, &buf)).Should(MatchError(ContainSubstring("is not a delta snapshot")))
+	})
+})
+
+var _ = Describe("Restoring up to a wall-clock time", func() {
+	var (
+		store         brtypes.SnapStore
+		restorer      *Restorer
+		baseTime      time.Time
+		baseSnapshot  *brtypes.Snapshot
+		deltaSnapList brtypes.SnapList
+		// dictionary is the dictionary the delta snapshots are compressed with using the zlib-dict compression
+		// policy, if set.
+		dictionary []byte
+	)
+
+	// saveDeltaSnapshot saves a delta snapshot holding one event per given offset from the base time, starting at the
+	// given revision.
+	saveDeltaSnapshot := func(startRevision int64, offsets ...time.Duration) {
+		events := []brtypes.Event{}
+		for i, offset := range offsets {
+			revision := startRevision + int64(i)
+			events = append(events, brtypes.Event{
+				EtcdEvent: &clientv3.Event{
+					Type: mvccpb.PUT,
+					Kv:   &mvccpb.KeyValue{Key: []byte(fmt.Sprintf("key-%d", revision)), Value: []byte("value"), ModRevision: revision},
+				},
+				Time: baseTime.Add(offset),
+			})
+		}
+		data, err := json.Marshal(events)
+		Expect(err).ShouldNot(HaveOccurred())
+		hash := sha256.Sum256(data)
+
+		snap := brtypes.Snapshot{
+			Kind:          brtypes.SnapshotKindDelta,
+			CreatedOn:     baseTime.Add(offsets[len(offsets)-1]),
+			StartRevision: startRevision,
+			LastRevision:  startRevision + int64(len(offsets)) - 1,
+		}
+		rc := io.NopCloser(bytes.NewReader(append(data, hash[:]...)))
+		if dictionary != nil {
+			snap.CompressionSuffix = compressor.ZlibDictCompressionExtension
+			rc, err = compressor.CompressSnapshotWithDictionary(rc, compressor.ZlibDictCompressionPolicy, dictionary)
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+		snap.GenerateSnapshotName()
+		Expect(store.Save(snap, rc)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		store, err = snapstore.GetSnapstore(&brtypes.SnapstoreConfig{Container: GinkgoT().TempDir(), Provider: "Local"})
+		Expect(err).ShouldNot(HaveOccurred())
+		restorer, err = NewRestorer(store, logger)
+		Expect(err).ShouldNot(HaveOccurred())
+		dictionary = nil
+
+		baseTime = time.Now().Add(-time.Hour).Truncate(time.Second)
+		fullSnap := brtypes.Snapshot{
+			Kind:          brtypes.SnapshotKindFull,
+			CreatedOn:     baseTime,
+			StartRevision: 0,
+			LastRevision:  10,
+		}
+		fullSnap.GenerateSnapshotName()
+		Expect(store.Save(fullSnap, io.NopCloser(strings.NewReader("dummy-full-snapshot")))).To(Succeed())
+		saveDeltaSnapshot(11, 10*time.Second, 20*time.Second, 30*time.Second)
+		saveDeltaSnapshot(14, 40*time.Second, 50*time.Second, 60*time.Second)
+
+		baseSnapshot, deltaSnapList, err = miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(store)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(deltaSnapList).To(HaveLen(2))
+	})
+
+	It("should keep all delta snapshots if the time follows the last event", func() {
+		snaps, revision, err := restorer.GetDeltaSnapshotsUpToTime(baseSnapshot, deltaSnapList, baseTime.Add(time.Hour))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(revision).To(Equal(int64(16)))
+		Expect(snaps).To(Equal(deltaSnapList))
+	})
+
+	It("should stop at the last event at or before the time within a delta snapshot", func() {
+		snaps, revision, err := restorer.GetDeltaSnapshotsUpToTime(baseSnapshot, deltaSnapList, baseTime.Add(50*time.Second))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(revision).To(Equal(int64(15)))
+		Expect(snaps).To(HaveLen(2))
+		Expect(snaps[0]).To(Equal(deltaSnapList[0]))
+		Expect(snaps[1].SnapName).To(Equal(deltaSnapList[1].SnapName))
+		Expect(snaps[1].LastRevision).To(Equal(int64(15)))
+		// the listed delta snapshot is left untouched
+		Expect(deltaSnapList[1].LastRevision).To(Equal(int64(16)))
+
+		snaps, revision, err = restorer.GetDeltaSnapshotsUpToTime(baseSnapshot, deltaSnapList, baseTime.Add(25*time.Second))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(revision).To(Equal(int64(12)))
+		Expect(snaps).To(HaveLen(1))
+		Expect(snaps[0].LastRevision).To(Equal(int64(12)))
+	})
+
+	It("should drop the delta snapshots whose events all follow the time", func() {
+		snaps, revision, err := restorer.GetDeltaSnapshotsUpToTime(baseSnapshot, deltaSnapList, baseTime.Add(35*time.Second))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(revision).To(Equal(int64(13)))
+		Expect(snaps).To(Equal(deltaSnapList[:1]))
+
+		snaps, revision, err = restorer.GetDeltaSnapshotsUpToTime(baseSnapshot, deltaSnapList, baseTime.Add(5*time.Second))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(revision).To(Equal(baseSnapshot.LastRevision))
+		Expect(snaps).To(BeEmpty())
+	})
+
+	It("should read the delta snapshots compressed with a dictionary with the dictionary saved in the snapstore", func() {
+		dictionary = []byte(`[{"etcdEvent":{"type":0,"kv":{"key":"a2V5LTE=","mod_revision":1,"value":"dmFsdWU="}}}]`)
+		_, err := snapstore.SaveCompressionDictionary(store, dictionary)
+		Expect(err).ShouldNot(HaveOccurred())
+		saveDeltaSnapshot(17, 70*time.Second, 80*time.Second)
+		_, deltaSnapList, err = miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(store)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(deltaSnapList).To(HaveLen(3))
+		Expect(deltaSnapList[2].CompressionSuffix).To(Equal(compressor.ZlibDictCompressionExtension))
+
+		snaps, revision, err := restorer.GetDeltaSnapshotsUpToTime(baseSnapshot, deltaSnapList, baseTime.Add(75*time.Second))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(revision).To(Equal(int64(17)))
+		Expect(snaps).To(HaveLen(3))
+		Expect(snaps[2].LastRevision).To(Equal(int64(17)))
+	})
+
+	It("should return an error if the time precedes the base snapshot", func() {
+		_, _, err := restorer.GetDeltaSnapshotsUpToTime(baseSnapshot, deltaSnapList, baseTime.Add(-time.Second))
+		Expect(errors.Is(err, ErrRestoreToTimeBeforeBaseSnapshot)).To(BeTrue())
+	})
+})
+
+// readBucketsFromDB returns the contents of all buckets of the given db, keyed by bucket and key name.
+func readBucketsFromDB(dbPath string) (map[string][]byte, error) {
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	kvs := map[string][]byte{}
+	err = db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(bucket []byte, b *bolt.Bucket) error {
+			return b.ForEach(func(k, v []byte) error {
+				kvs[path.Join(string(bucket), string(k))] = append([]byte{}, v...)
+				return nil
+			})
+		})
+	})
+	return kvs, err
+}
+
 // corruptEtcdDir corrupts the etcd directory by deleting it
 func corruptEtcdDir() error {
 	if _, err := os.Stat(etcdDir); os.IsNotExist(err) {
@@ -1256,3 +2922,169 @@ func takeInvalidV1Snaps(container string) error {
 
 	return nil
 }
+
+// copySnapshotWithCompressionSuffix copies the given snapshot from the source to the destination snapstore,
+// recompressing it according to the given compression suffix, and returns the copied snapshot.
+func copySnapshotWithCompressionSuffix(src, dst brtypes.SnapStore, snap *brtypes.Snapshot, compressionSuffix string) *brtypes.Snapshot {
+	rc, err := src.Fetch(*snap)
+	Expect(err).ShouldNot(HaveOccurred())
+	defer rc.Close()
+
+	isCompressed, compressionPolicy, err := compressor.IsSnapshotCompressed(snap.CompressionSuffix)
+	Expect(err).ShouldNot(HaveOccurred())
+	if isCompressed {
+		rc, err = compressor.DecompressSnapshot(rc, compressionPolicy)
+		Expect(err).ShouldNot(HaveOccurred())
+	}
+
+	isCompressed, compressionPolicy, err = compressor.IsSnapshotCompressed(compressionSuffix)
+	Expect(err).ShouldNot(HaveOccurred())
+	if isCompressed {
+		rc, err = compressor.CompressSnapshot(rc, compressionPolicy)
+		Expect(err).ShouldNot(HaveOccurred())
+	}
+
+	copied := *snap
+	copied.CompressionSuffix = compressionSuffix
+	copied.GenerateSnapshotName()
+	Expect(dst.Save(copied, rc)).To(Succeed())
+
+	// the snapshot is listed from the destination snapstore to point to the right prefix
+	snapList, err := dst.List()
+	Expect(err).ShouldNot(HaveOccurred())
+	for _, s := range snapList {
+		if s.SnapName == copied.SnapName {
+			return s
+		}
+	}
+	Fail(fmt.Sprintf("copied snapshot %s not found in the destination snapstore", copied.SnapName))
+	return nil
+}
+
+// cancellingSnapStore is a snapstore which cancels a context once the configured
+// number of delta snapshots have been fetched from the underlying snapstore.
+type cancellingSnapStore struct {
+	brtypes.SnapStore
+	cancel             context.CancelFunc
+	cancelOnDeltaFetch int
+
+	mutex        sync.Mutex
+	deltaFetches int
+}
+
+func (c *cancellingSnapStore) Fetch(snap brtypes.Snapshot) (io.ReadCloser, error) {
+	if snap.Kind == brtypes.SnapshotKindDelta {
+		c.mutex.Lock()
+		c.deltaFetches++
+		if c.deltaFetches == c.cancelOnDeltaFetch {
+			c.cancel()
+		}
+		c.mutex.Unlock()
+	}
+	return c.SnapStore.Fetch(snap)
+}
+
+// fetchRecordingSnapStore is a snapstore which records the names of the snapshots fetched from the underlying
+// snapstore, and fails the configured delta snapshot fetch.
+type fetchRecordingSnapStore struct {
+	brtypes.SnapStore
+	failOnDeltaFetch int
+
+	mutex        sync.Mutex
+	deltaFetches int
+	fetched      []string
+}
+
+func (f *fetchRecordingSnapStore) Fetch(snap brtypes.Snapshot) (io.ReadCloser, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if snap.Kind == brtypes.SnapshotKindDelta {
+		f.deltaFetches++
+		if f.deltaFetches == f.failOnDeltaFetch {
+			return nil, fmt.Errorf("failed to fetch delta snapshot %s", snap.SnapName)
+		}
+	}
+	f.fetched = append(f.fetched, snap.SnapName)
+	return f.SnapStore.Fetch(snap)
+}
+
+// brokenStreamSnapStore is a snapstore which breaks the first stream of a full snapshot from the underlying snapstore
+// which is read beyond its first bytes.
+type brokenStreamSnapStore struct {
+	brtypes.SnapStore
+	broken      bool
+	baseFetches int
+}
+
+func (b *brokenStreamSnapStore) Fetch(snap brtypes.Snapshot) (io.ReadCloser, error) {
+	rc, err := b.SnapStore.Fetch(snap)
+	if err != nil || snap.Kind != brtypes.SnapshotKindFull {
+		return rc, err
+	}
+	b.baseFetches++
+	return &brokenStreamReader{ReadCloser: rc, store: b}, nil
+}
+
+// brokenStreamReader fails reading beyond the first bytes of a snapshot, unless a stream of its snapstore was broken.
+type brokenStreamReader struct {
+	io.ReadCloser
+	store *brokenStreamSnapStore
+	read  int
+}
+
+func (r *brokenStreamReader) Read(p []byte) (int, error) {
+	if r.read >= 1024 && !r.store.broken {
+		r.store.broken = true
+		return 0, fmt.Errorf("connection reset by peer")
+	}
+	n, err := r.ReadCloser.Read(p)
+	r.read += n
+	return n, err
+}
+
+// slowSnapStore is a snapstore which delays fetching each delta snapshot from the underlying snapstore.
+type slowSnapStore struct {
+	brtypes.SnapStore
+	fetchDelay time.Duration
+}
+
+func (s *slowSnapStore) Fetch(snap brtypes.Snapshot) (io.ReadCloser, error) {
+	if snap.Kind == brtypes.SnapshotKindDelta {
+		time.Sleep(s.fetchDelay)
+	}
+	return s.SnapStore.Fetch(snap)
+}
+
+// concurrentFetches tracks the number of delta snapshots being fetched in parallel across snapstores.
+type concurrentFetches struct {
+	mutex    sync.Mutex
+	inFlight int
+	max      int
+}
+
+// concurrencyTrackingSnapStore is a snapstore which delays fetching each delta snapshot from the underlying snapstore,
+// and tracks the number of delta snapshots being fetched in parallel with the snapstores sharing its fetches.
+type concurrencyTrackingSnapStore struct {
+	brtypes.SnapStore
+	fetches    *concurrentFetches
+	fetchDelay time.Duration
+}
+
+func (c *concurrencyTrackingSnapStore) Fetch(snap brtypes.Snapshot) (io.ReadCloser, error) {
+	if snap.Kind != brtypes.SnapshotKindDelta {
+		return c.SnapStore.Fetch(snap)
+	}
+	c.fetches.mutex.Lock()
+	c.fetches.inFlight++
+	if c.fetches.inFlight > c.fetches.max {
+		c.fetches.max = c.fetches.inFlight
+	}
+	c.fetches.mutex.Unlock()
+	defer func() {
+		c.fetches.mutex.Lock()
+		c.fetches.inFlight--
+		c.fetches.mutex.Unlock()
+	}()
+	time.Sleep(c.fetchDelay)
+	return c.SnapStore.Fetch(snap)
+}
@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restorer
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// ErrRestoreToTimeBeforeBaseSnapshot is returned if the time to restore to precedes the base snapshot, whose data
+// can't be restored partially.
+var ErrRestoreToTimeBeforeBaseSnapshot = errors.New("restore time precedes the base snapshot")
+
+// GetDeltaSnapshotsUpToTime returns the delta snapshots which hold the events up to the given time, along with the
+// revision of the last event at or before it, which is the revision the restoration stops at. The events are matched
+// by the timestamps recorded along with them, as the delta snapshots only record the time they were taken at. If the
+// time falls into a delta snapshot, the last returned delta snapshot is a copy of it whose last revision is lowered
+// to the revision of the last event at or before the time, so that its later events are not applied.
+func (r *Restorer) GetDeltaSnapshotsUpToTime(baseSnapshot *brtypes.Snapshot, deltaSnapList brtypes.SnapList, restoreToTime time.Time) (brtypes.SnapList, int64, error) {
+	var revision int64
+	if baseSnapshot != nil {
+		if restoreToTime.Before(baseSnapshot.CreatedOn) {
+			return nil, 0, fmt.Errorf("%w %s taken at %s", ErrRestoreToTimeBeforeBaseSnapshot, baseSnapshot.SnapName, baseSnapshot.CreatedOn)
+		}
+		revision = baseSnapshot.LastRevision
+	}
+
+	for i, snap := range deltaSnapList {
+		// the creation time of a listed snapshot is truncated to seconds, hence all its events precede the time only
+		// if it was created at least a second before
+		if !snap.CreatedOn.Add(time.Second).After(restoreToTime) {
+			revision = snap.LastRevision
+			continue
+		}
+
+		events, err := r.getEventsFromDeltaSnapshot(*snap)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read the events of delta snapshot %s: %v", snap.SnapName, err)
+		}
+		var lastRevision int64
+		for _, event := range events {
+			if event.Time.After(restoreToTime) {
+				break
+			}
+			lastRevision = event.EtcdEvent.Kv.ModRevision
+		}
+		if lastRevision == 0 {
+			return deltaSnapList[:i], revision, nil
+		}
+
+		partialSnap := *snap
+		partialSnap.LastRevision = lastRevision
+		snaps := append(brtypes.SnapList{}, deltaSnapList[:i]...)
+		return append(snaps, &partialSnap), lastRevision, nil
+	}
+	return deltaSnapList, revision, nil
+}
+
+// limitToRestoreTime drops the events after the restore time of the given restore options from its delta snapshots.
+// The restore time is reset afterwards, as the delta snapshots end at the restore time.
+func (r *Restorer) limitToRestoreTime(ro *brtypes.RestoreOptions) error {
+	if ro.RestoreToTime.IsZero() {
+		return nil
+	}
+	deltaSnapList, revision, err := r.GetDeltaSnapshotsUpToTime(ro.BaseSnapshot, ro.DeltaSnapList, ro.RestoreToTime)
+	if err != nil {
+		return err
+	}
+	r.logger.Infof("Restoring up to revision %d, the last revision at or before %s, from %d of %d delta snapshots.", revision, ro.RestoreToTime, len(deltaSnapList), len(ro.DeltaSnapList))
+	ro.DeltaSnapList = deltaSnapList
+	ro.RestoreToTime = time.Time{}
+	return nil
+}
+
+// eventsUpToRevision returns the events up to the given revision.
+func eventsUpToRevision(events []brtypes.Event, revision int64) []brtypes.Event {
+	for i, event := range events {
+		if event.EtcdEvent.Kv.ModRevision > revision {
+			return events[:i]
+		}
+	}
+	return events
+}
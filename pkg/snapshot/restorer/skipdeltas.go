@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restorer
+
+import (
+	"fmt"
+	"path"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// skipDeltaSnapshots drops the delta snapshots whose revision range holds any of the revisions to skip of the given
+// restore options, accepting the loss of their events, so that a delta snapshot with an event which can't be applied
+// doesn't block the restoration. As the revisions of the restored etcd fall behind the revisions of the delta
+// snapshots after a skipped delta snapshot, such a restoration can't be checkpointed.
+func (r *Restorer) skipDeltaSnapshots(ro *brtypes.RestoreOptions) error {
+	if len(ro.SkipDeltaRevisions) == 0 {
+		return nil
+	}
+	if ro.Config.RestoreCheckpointInterval > 0 {
+		return fmt.Errorf("restoration skipping the delta snapshots of revisions %v cannot be checkpointed", ro.SkipDeltaRevisions)
+	}
+
+	matched := make(map[int64]bool, len(ro.SkipDeltaRevisions))
+	deltaSnapList := make(brtypes.SnapList, 0, len(ro.DeltaSnapList))
+	for _, snap := range ro.DeltaSnapList {
+		var skip bool
+		for _, revision := range ro.SkipDeltaRevisions {
+			if revision >= snap.StartRevision && revision <= snap.LastRevision {
+				matched[revision] = true
+				skip = true
+			}
+		}
+		if !skip {
+			deltaSnapList = append(deltaSnapList, snap)
+			continue
+		}
+		if r.report != nil {
+			r.report.SkippedDeltaSnapshots = append(r.report.SkippedDeltaSnapshots, path.Join(snap.SnapDir, snap.SnapName))
+		}
+		r.warnf("Skipping delta snapshot %s with revisions %d to %d as requested. The events of these revisions are not restored, and the restored data has a gap.", path.Join(snap.SnapDir, snap.SnapName), snap.StartRevision, snap.LastRevision)
+	}
+	for _, revision := range ro.SkipDeltaRevisions {
+		if !matched[revision] {
+			r.warnf("No delta snapshot holds the revision %d to skip.", revision)
+		}
+	}
+	r.warnf("Skipped %d of %d delta snapshots for the revisions %v.", len(ro.DeltaSnapList)-len(deltaSnapList), len(ro.DeltaSnapList), ro.SkipDeltaRevisions)
+	ro.DeltaSnapList = deltaSnapList
+	return nil
+}
+
+// discardDeltaSnapshots drops all the delta snapshots of the given restore options if only the base snapshot is to be
+// restored, so that the data is restored up to the last revision of the base snapshot without applying any of the
+// delta snapshots, which may not be trusted.
+func (r *Restorer) discardDeltaSnapshots(ro *brtypes.RestoreOptions) error {
+	if !ro.BaseOnly {
+		return nil
+	}
+	if ro.BaseSnapshot == nil {
+		return fmt.Errorf("restoration of only the base snapshot requires a base snapshot")
+	}
+	if !ro.RestoreToTime.IsZero() || len(ro.SkipDeltaRevisions) > 0 {
+		return fmt.Errorf("restoration of only the base snapshot cannot be combined with a restore time or skipped delta snapshots")
+	}
+
+	if r.report != nil {
+		r.report.DiscardedDeltaSnapshots = len(ro.DeltaSnapList)
+	}
+	r.warnf("Restoring only the base snapshot %s up to revision %d, discarding %d delta snapshots.", path.Join(ro.BaseSnapshot.SnapDir, ro.BaseSnapshot.SnapName), ro.BaseSnapshot.LastRevision, len(ro.DeltaSnapList))
+	ro.DeltaSnapList = brtypes.SnapList{}
+	return nil
+}
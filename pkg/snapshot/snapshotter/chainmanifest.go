@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshotter
+
+import (
+	"path"
+
+	"github.com/gardener/etcd-backup-restore/pkg/snapstore"
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// updateChainManifest saves the chain manifest of the previous full snapshot and its delta snapshots, if enabled.
+// The snapshots already recorded in the saved chain manifest are carried over along with their object sizes, so that
+// only the sizes of the new snapshots are requested from the snapstore.
+func (ssr *Snapshotter) updateChainManifest() {
+	if !ssr.config.WriteChainManifest || ssr.PrevFullSnapshot == nil {
+		return
+	}
+	ssr.chainManifestMutex.Lock()
+	defer ssr.chainManifestMutex.Unlock()
+
+	recorded := map[string]*brtypes.ChainManifestSnapshot{}
+	if ssr.chainManifest != nil {
+		for _, snap := range ssr.chainManifest.Snapshots() {
+			recorded[snap.SnapName] = snap
+		}
+	}
+	chainManifestSnapshot := func(snap *brtypes.Snapshot) *brtypes.ChainManifestSnapshot {
+		if s, ok := recorded[snap.SnapName]; ok {
+			copied := *s
+			return &copied
+		}
+		return &brtypes.ChainManifestSnapshot{Snapshot: *snap}
+	}
+
+	manifest := &brtypes.ChainManifest{
+		UpdatedOn:      ssr.Clock.Now().UTC(),
+		LatestRevision: ssr.PrevFullSnapshot.LastRevision,
+		FullSnapshot:   chainManifestSnapshot(ssr.PrevFullSnapshot),
+		DeltaSnapshots: []*brtypes.ChainManifestSnapshot{},
+	}
+	for _, snap := range ssr.prevDeltaSnapshots() {
+		manifest.DeltaSnapshots = append(manifest.DeltaSnapshots, chainManifestSnapshot(snap))
+		if snap.LastRevision > manifest.LatestRevision {
+			manifest.LatestRevision = snap.LastRevision
+		}
+	}
+	ssr.saveChainManifest(manifest)
+}
+
+// reconcileChainManifest drops the snapshots which are no longer present in the snapstore from the saved chain
+// manifest, and saves it again. It is run after the garbage collection, so that the chain manifest doesn't keep
+// listing snapshots which were deleted from the snapstore, such as by another process. The latest revision of the
+// chain manifest is kept, so that a chain manifest which lost some of its snapshots fails its verification.
+func (ssr *Snapshotter) reconcileChainManifest() error {
+	if !ssr.config.WriteChainManifest {
+		return nil
+	}
+	ssr.chainManifestMutex.Lock()
+	defer ssr.chainManifestMutex.Unlock()
+	if ssr.chainManifest == nil {
+		return nil
+	}
+
+	snapList, err := ssr.store.List()
+	if err != nil {
+		return err
+	}
+	inStore := make(map[string]struct{}, len(snapList))
+	for _, snap := range snapList {
+		inStore[snap.SnapName] = struct{}{}
+	}
+
+	manifest := &brtypes.ChainManifest{
+		UpdatedOn:      ssr.Clock.Now().UTC(),
+		LatestRevision: ssr.chainManifest.LatestRevision,
+		DeltaSnapshots: []*brtypes.ChainManifestSnapshot{},
+	}
+	for _, snap := range ssr.chainManifest.Snapshots() {
+		if _, ok := inStore[snap.SnapName]; !ok {
+			ssr.logger.Warnf("GC: Snapshot %s of the chain manifest is missing from the snapstore. Dropping it from the chain manifest.", path.Join(snap.SnapDir, snap.SnapName))
+			continue
+		}
+		copied := *snap
+		if snap.Kind == brtypes.SnapshotKindFull {
+			manifest.FullSnapshot = &copied
+		} else {
+			manifest.DeltaSnapshots = append(manifest.DeltaSnapshots, &copied)
+		}
+	}
+	ssr.saveChainManifest(manifest)
+	return nil
+}
+
+// saveChainManifest saves the given chain manifest to the snapstore. A failure to save it doesn't fail the snapshot,
+// as the chain manifest is merely a hint for discovering the chain. The chain manifest saved before is deleted
+// instead, so that the restoration lists the snapstore rather than restoring the chain it lags behind.
+func (ssr *Snapshotter) saveChainManifest(manifest *brtypes.ChainManifest) {
+	if err := snapstore.SaveChainManifest(ssr.store, manifest); err != nil {
+		ssr.logger.Warnf("Failed to save the chain manifest, deleting it: %v", err)
+		ssr.chainManifest = nil
+		if err := snapstore.DeleteChainManifest(ssr.store); err != nil {
+			ssr.logger.Errorf("Failed to delete the chain manifest, which lags behind the chain: %v", err)
+		}
+		return
+	}
+	ssr.chainManifest = manifest
+}
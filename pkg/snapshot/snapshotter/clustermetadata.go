@@ -0,0 +1,62 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshotter
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	etcdClient "github.com/gardener/etcd-backup-restore/pkg/etcdutil/client"
+	"github.com/gardener/etcd-backup-restore/pkg/snapstore"
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// recordClusterMetadata saves the membership of the etcd cluster along with the given full snapshot, if enabled, so
+// that the cluster can be rebuilt with the same members and peer URLs from it. A failure to record the cluster metadata
+// doesn't fail the full snapshot, which can still be restored without it.
+func (ssr *Snapshotter) recordClusterMetadata(clientFactory etcdClient.Factory, snap *brtypes.Snapshot) {
+	if !ssr.config.RecordClusterMetadata {
+		return
+	}
+	metadata, err := ssr.getClusterMetadata(clientFactory)
+	if err != nil {
+		ssr.logger.Warnf("Failed to get the cluster metadata of full snapshot %s: %v", path.Join(snap.SnapDir, snap.SnapName), err)
+		return
+	}
+	if err := snapstore.SaveClusterMetadata(ssr.store, snap, metadata); err != nil {
+		ssr.logger.Warnf("Failed to save the cluster metadata of full snapshot %s: %v", path.Join(snap.SnapDir, snap.SnapName), err)
+		return
+	}
+	ssr.logger.Infof("Saved the cluster metadata of full snapshot %s with %d members", path.Join(snap.SnapDir, snap.SnapName), len(metadata.Members))
+}
+
+// getClusterMetadata returns the identifier and the members of the etcd cluster.
+func (ssr *Snapshotter) getClusterMetadata(clientFactory etcdClient.Factory) (*brtypes.ClusterMetadata, error) {
+	clientCluster, err := clientFactory.NewCluster()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build etcd cluster client: %v", err)
+	}
+	defer clientCluster.Close()
+
+	ctx, cancel := context.WithTimeout(context.TODO(), ssr.etcdConnectionConfig.ConnectionTimeout.Duration)
+	defer cancel()
+	resp, err := clientCluster.MemberList(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &brtypes.ClusterMetadata{ClusterID: resp.Header.GetClusterId()}
+	for _, member := range resp.Members {
+		metadata.Members = append(metadata.Members, brtypes.ClusterMember{
+			ID:         member.ID,
+			Name:       member.Name,
+			PeerURLs:   member.PeerURLs,
+			ClientURLs: member.ClientURLs,
+			IsLearner:  member.IsLearner,
+		})
+	}
+	return metadata, nil
+}
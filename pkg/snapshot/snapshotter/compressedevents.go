@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshotter
+
+import (
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"time"
+
+	"github.com/gardener/etcd-backup-restore/pkg/compressor"
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// compressedEventsBuffer compresses the events of a delta snapshot into a temporary file as they arrive, so that
+// the events are not held uncompressed in memory until the delta snapshot is taken. As for the delta snapshots
+// which are compressed at once, the hash is computed over the uncompressed events and appended to them.
+type compressedEventsBuffer struct {
+	file              *os.File
+	compressionWriter io.WriteCloser
+	hash              hash.Hash
+	hashAlgorithm     string
+	compressionPolicy string
+	size              int
+	// adaptiveLevel records the time spent compressing the events, if the events are compressed at it.
+	adaptiveLevel      *compressor.AdaptiveLevel
+	compressionElapsed time.Duration
+}
+
+// newCompressedEventsBuffer returns a buffer which compresses the events using the given compression policy, and the
+// given dictionary with the zlib-dict compression policy, into a temporary file in the given directory. The events are
+// compressed at the current level of the given adaptive compression level, if any, and hashed with the given hash
+// algorithm.
+func newCompressedEventsBuffer(dir, compressionPolicy string, dictionary []byte, adaptiveLevel *compressor.AdaptiveLevel, hashAlgorithm string) (*compressedEventsBuffer, error) {
+	h, err := brtypes.NewDeltaSnapshotHash(hashAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.CreateTemp(dir, "delta-events-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file for delta events: %v", err)
+	}
+	var compressionWriter io.WriteCloser
+	if adaptiveLevel != nil {
+		compressionWriter, err = compressor.NewCompressionWriterWithLevel(file, compressionPolicy, dictionary, adaptiveLevel.Level())
+	} else {
+		compressionWriter, err = compressor.NewCompressionWriterWithDictionary(file, compressionPolicy, dictionary)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return &compressedEventsBuffer{
+		file:              file,
+		compressionWriter: compressionWriter,
+		hash:              h,
+		hashAlgorithm:     hashAlgorithm,
+		compressionPolicy: compressionPolicy,
+		adaptiveLevel:     adaptiveLevel,
+	}, nil
+}
+
+// write compresses the given events into the buffer.
+func (b *compressedEventsBuffer) write(p []byte) error {
+	b.hash.Write(p)
+	start := time.Now()
+	n, err := b.compressionWriter.Write(p)
+	b.compressionElapsed += time.Since(start)
+	b.size += n
+	if err != nil {
+		return fmt.Errorf("failed to compress delta events: %v", err)
+	}
+	return nil
+}
+
+// finish appends the hash trailer of the events, completes the compression and returns a reader of the compressed events.
+func (b *compressedEventsBuffer) finish() (io.Reader, error) {
+	trailer, err := brtypes.DeltaSnapshotHashTrailer(b.hashAlgorithm, b.hash.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := b.compressionWriter.Write(trailer); err != nil {
+		return nil, fmt.Errorf("failed to compress hash of delta events: %v", err)
+	}
+	start := time.Now()
+	if err := b.compressionWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to complete compression of delta events: %v", err)
+	}
+	if b.adaptiveLevel != nil {
+		b.adaptiveLevel.Record(int64(b.size), b.compressionElapsed+time.Since(start))
+	}
+	if _, err := b.file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read compressed delta events: %v", err)
+	}
+	return b.file, nil
+}
+
+// discard removes the temporary file of the buffer.
+func (b *compressedEventsBuffer) discard() error {
+	b.file.Close()
+	return os.Remove(b.file.Name())
+}
@@ -0,0 +1,136 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshotter
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/gardener/etcd-backup-restore/pkg/compressor"
+	"github.com/gardener/etcd-backup-restore/pkg/snapstore"
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// noCompressionDictionary is the identifier of the compression dictionary in use if the delta snapshots are compressed
+// without a dictionary.
+const noCompressionDictionary = -1
+
+// usesCompressionDictionary returns whether the delta snapshots are compressed with a dictionary.
+func (ssr *Snapshotter) usesCompressionDictionary() bool {
+	return ssr.compressionConfig != nil && ssr.compressionConfig.Enabled && ssr.compressionConfig.CompressionPolicy == compressor.ZlibDictCompressionPolicy
+}
+
+// sampleForCompressionDictionary keeps the given events as a sample for training the compression dictionary of the
+// next chain, if the delta snapshots are compressed with a dictionary. Only the latest events that fit into a
+// dictionary are kept.
+func (ssr *Snapshotter) sampleForCompressionDictionary(data []byte) {
+	if !ssr.usesCompressionDictionary() {
+		return
+	}
+	ssr.dictionarySamples = append(ssr.dictionarySamples, data)
+	ssr.dictionarySamplesSize += len(data)
+	for ssr.dictionarySamplesSize-len(ssr.dictionarySamples[0]) >= compressor.MaxDictionarySize {
+		ssr.dictionarySamplesSize -= len(ssr.dictionarySamples[0])
+		ssr.dictionarySamples = ssr.dictionarySamples[1:]
+	}
+}
+
+// updateCompressionDictionary trains the compression dictionary for the delta snapshots of the chain started by the
+// full snapshot just taken from the sampled events, and saves it to the snapstore before any delta snapshot is
+// compressed with it. The delta snapshots are compressed without a dictionary until events were sampled, or if the
+// dictionary can't be saved.
+func (ssr *Snapshotter) updateCompressionDictionary() {
+	if !ssr.usesCompressionDictionary() {
+		return
+	}
+	ssr.compressionDictionary = nil
+	ssr.compressionDictionaryID.Store(noCompressionDictionary)
+
+	dictionary := compressor.TrainDictionary(ssr.dictionarySamples)
+	if dictionary == nil {
+		return
+	}
+	id, err := snapstore.SaveCompressionDictionary(ssr.store, dictionary)
+	if err != nil {
+		ssr.logger.Warnf("Failed to save the compression dictionary, compressing the delta snapshots without a dictionary: %v", err)
+		return
+	}
+	ssr.compressionDictionary = dictionary
+	ssr.compressionDictionaryID.Store(int64(id))
+	ssr.logger.Infof("Compressing the delta snapshots with compression dictionary %08x of %d bytes", id, len(dictionary))
+}
+
+// garbageCollectCompressionDictionaries deletes the compression dictionaries which none of the delta snapshots in
+// the snapstore were compressed with, as recorded in their zlib headers. The dictionary in use is never deleted, and
+// neither are the dictionaries of the delta snapshots still being uploaded. The delta snapshots are listed before the
+// dictionaries, so that a dictionary saved meanwhile is the one in use. No dictionary is deleted if any of the delta
+// snapshots can't be checked.
+func (ssr *Snapshotter) garbageCollectCompressionDictionaries() error {
+	if !ssr.usesCompressionDictionary() {
+		return nil
+	}
+	inUse := map[int64]struct{}{ssr.compressionDictionaryID.Load(): {}}
+	// the delta snapshots which are only uploaded once the snapshots were listed reference one of these dictionaries
+	for _, id := range ssr.pendingDeltaSnapshotDictionaryIDs() {
+		inUse[id] = struct{}{}
+	}
+	snapList, err := ssr.store.List()
+	if err != nil {
+		return err
+	}
+	dictionaries, err := snapstore.ListCompressionDictionaries(ssr.store)
+	if err != nil || len(dictionaries) == 0 {
+		return err
+	}
+	// the dictionary in use may have been replaced by the chain started while the snapshots and dictionaries were listed
+	inUse[ssr.compressionDictionaryID.Load()] = struct{}{}
+
+	referencedDictionaryIDs := make(map[string]int64, len(ssr.deltaSnapshotDictionaryIDs))
+	for _, snap := range snapList {
+		if snap.Kind != brtypes.SnapshotKindDelta || snap.IsChunk || snap.CompressionSuffix != compressor.ZlibDictCompressionExtension {
+			continue
+		}
+		snapPath := path.Join(snap.SnapDir, snap.SnapName)
+		id, ok := ssr.deltaSnapshotDictionaryIDs[snapPath]
+		if !ok {
+			if id, err = readDeltaSnapshotDictionaryID(ssr.store, snap); err != nil {
+				return fmt.Errorf("failed to read the compression dictionary of delta snapshot %s: %w", snapPath, err)
+			}
+		}
+		referencedDictionaryIDs[snapPath] = id
+		inUse[id] = struct{}{}
+	}
+	// the delta snapshots are immutable, hence the dictionaries they reference are only read once per snapshot
+	ssr.deltaSnapshotDictionaryIDs = referencedDictionaryIDs
+
+	for _, dictionary := range dictionaries {
+		if _, ok := inUse[int64(dictionary.ID)]; ok {
+			continue
+		}
+		ssr.logger.Infof("GC: Deleting compression dictionary %08x saved on %s, which none of the delta snapshots reference", dictionary.ID, dictionary.SavedOn.UTC())
+		if err := snapstore.DeleteCompressionDictionary(ssr.store, dictionary.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readDeltaSnapshotDictionaryID returns the identifier of the compression dictionary which the given delta snapshot
+// was compressed with, or noCompressionDictionary if it was compressed without one.
+func readDeltaSnapshotDictionaryID(store brtypes.SnapStore, snap *brtypes.Snapshot) (int64, error) {
+	rc, err := store.Fetch(*snap)
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	id, ok, err := compressor.ReadDictionaryID(rc)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return noCompressionDictionary, nil
+	}
+	return int64(id), nil
+}
@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshotter
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/gardener/etcd-backup-restore/pkg/metrics"
+	"github.com/gardener/etcd-backup-restore/pkg/snapstore"
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrSnapshotterDegraded is returned by the snapshots triggered out of schedule while the snapshotter is degraded
+// because the snapstore is out of quota or capacity.
+var ErrSnapshotterDegraded = stderrors.New("snapshotter is degraded as the snapstore is out of quota or capacity")
+
+// canDegrade checks whether the snapshotter can be degraded instead of failing on the given error of a snapshot, that
+// is if the degraded mode is enabled, there is a watch whose events can be buffered, and the snapstore is out of quota.
+func (ssr *Snapshotter) canDegrade(err error) bool {
+	return ssr.config.DegradedModeRetryPeriod.Duration > 0 &&
+		ssr.config.DeltaSnapshotPeriod.Duration >= brtypes.DeltaSnapshotIntervalThreshold &&
+		snapstore.IsQuotaExceededError(err)
+}
+
+// degradeOnQuotaExceeded puts the snapshotter into the degraded mode if the given error of a full or delta snapshot
+// was caused by the snapstore being out of quota or capacity. It returns the given error otherwise, or if the
+// snapshotter could not be degraded.
+func (ssr *Snapshotter) degradeOnQuotaExceeded(err error, isFullSnapshot bool) error {
+	if !ssr.canDegrade(err) {
+		return err
+	}
+	if isFullSnapshot {
+		ssr.fullSnapshotDeferred = true
+	}
+	return ssr.enterDegradedMode(err)
+}
+
+// enterDegradedMode degrades the snapshotter, in which the snapshots are no longer uploaded but retried periodically,
+// while the events are still collected. The events since the last snapshot saved in the snapstore are collected anew
+// from etcd, as the failed snapshot has consumed them, or has closed the watch in case of a full snapshot.
+func (ssr *Snapshotter) enterDegradedMode(err error) error {
+	if !ssr.degraded {
+		ssr.logger.Warnf("Snapstore is out of quota or capacity, buffering the events and retrying the snapshots every %s: %v", ssr.config.DegradedModeRetryPeriod.Duration, err)
+		metrics.SnapshotterDegraded.With(prometheus.Labels{}).Set(1)
+	} else {
+		ssr.logger.Warnf("Snapstore is still out of quota or capacity, retrying the snapshots in %s: %v", ssr.config.DegradedModeRetryPeriod.Duration, err)
+	}
+	ssr.degraded = true
+
+	if err := ssr.waitForDeltaSnapshotUploads(); err != nil {
+		ssr.logger.Warnf("Failed to upload delta snapshots before degrading the snapshotter: %v", err)
+	}
+	ssr.cleanupInMemoryEvents()
+	ssr.PrevSnapshot = ssr.lastSavedSnapshot()
+	ssr.closeEtcdClient()
+	clientFactory, _, err := ssr.getEtcdClientFactory()
+	if err != nil {
+		return err
+	}
+	if err := ssr.applyWatch(clientFactory); err != nil {
+		return err
+	}
+
+	if ssr.degradedRetryTimer == nil {
+		ssr.degradedRetryTimer = time.NewTimer(ssr.config.DegradedModeRetryPeriod.Duration)
+	} else {
+		ssr.degradedRetryTimer.Stop()
+		ssr.degradedRetryTimer.Reset(ssr.config.DegradedModeRetryPeriod.Duration)
+	}
+	return nil
+}
+
+// exitDegradedMode resets the snapshotter from the degraded mode.
+func (ssr *Snapshotter) exitDegradedMode() {
+	ssr.degraded = false
+	ssr.fullSnapshotDeferred = false
+	if ssr.degradedRetryTimer != nil {
+		ssr.degradedRetryTimer.Stop()
+	}
+	metrics.SnapshotterDegraded.With(prometheus.Labels{}).Set(0)
+}
+
+// retrySnapshotInDegradedMode retries the snapshots while the snapshotter is degraded. The deferred full snapshot is
+// retried if a full snapshot failed or was due meanwhile, otherwise a delta snapshot of the buffered events is taken.
+// The snapshotter is no longer degraded once the snapshot is saved, and is degraded anew if the snapstore is still out
+// of quota or capacity.
+func (ssr *Snapshotter) retrySnapshotInDegradedMode(ctx context.Context) (*brtypes.Snapshot, error) {
+	var (
+		s   *brtypes.Snapshot
+		err error
+	)
+	isFullSnapshot := ssr.fullSnapshotDeferred
+	if isFullSnapshot {
+		s, err = ssr.TakeFullSnapshotAndResetTimer(ctx, false)
+	} else {
+		s, err = ssr.takeDeltaSnapshotAndResetTimer(ctx, false)
+	}
+	if err != nil {
+		return nil, ssr.degradeOnQuotaExceeded(err, isFullSnapshot)
+	}
+	ssr.logger.Info("Snapstore accepts snapshots again, the snapshotter is no longer degraded")
+	ssr.exitDegradedMode()
+	return s, nil
+}
+
+// lastSavedSnapshot returns the latest snapshot known to be saved in the snapstore, which the snapshots are continued
+// from once the snapshotter is degraded. The previous snapshot can be ahead of it, if the upload of a delta snapshot
+// in the background has failed.
+func (ssr *Snapshotter) lastSavedSnapshot() *brtypes.Snapshot {
+	if prevDeltaSnapshots := ssr.prevDeltaSnapshots(); len(prevDeltaSnapshots) > 0 {
+		return prevDeltaSnapshots[len(prevDeltaSnapshots)-1]
+	}
+	if ssr.PrevFullSnapshot != nil {
+		return ssr.PrevFullSnapshot
+	}
+	if ssr.PrevSnapshot.Kind == brtypes.SnapshotKindFull {
+		return ssr.PrevSnapshot
+	}
+	return snapstore.NewSnapshot(brtypes.SnapshotKindFull, 0, 0, "", false)
+}
+
+// checkDegradedModeMemoryLimit returns an error if the events buffered while the snapshotter is degraded have crossed
+// the degraded mode memory limit.
+func (ssr *Snapshotter) checkDegradedModeMemoryLimit() error {
+	if ssr.eventsLen() >= int(ssr.config.DegradedModeMemoryLimit) {
+		return fmt.Errorf("events buffered while the snapshotter is degraded crossed the memory limit of %d bytes", ssr.config.DegradedModeMemoryLimit)
+	}
+	return nil
+}
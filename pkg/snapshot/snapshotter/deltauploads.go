@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshotter
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// deltaSnapshotUploads uploads the delta snapshots taken in a burst, for example while the events pile up beyond
+// the memory limit, concurrently to the snapstore. The delta snapshots are independent objects in the snapstore,
+// but they are recorded as the previous delta snapshots strictly in the order of their revisions, once all the
+// delta snapshots before them have been uploaded as well. If an upload fails, the delta snapshots before the failed
+// one are still recorded once uploaded, and the next delta snapshot starts after the last recorded one again.
+type deltaSnapshotUploads struct {
+	// slots bounds the number of concurrent uploads, and thereby the memory held by the events being uploaded.
+	slots   chan struct{}
+	wg      sync.WaitGroup
+	mutex   sync.Mutex
+	pending []*deltaSnapshotUpload
+	err     error
+}
+
+// deltaSnapshotUpload is a delta snapshot which is being uploaded.
+type deltaSnapshotUpload struct {
+	snap *brtypes.Snapshot
+	// dictionaryID is the identifier of the compression dictionary the delta snapshot was compressed with.
+	dictionaryID int64
+	done         bool
+	// dropped is set if an upload of a delta snapshot before this one failed, so that it is never recorded.
+	dropped bool
+}
+
+// newDeltaSnapshotUploads returns the uploads of the delta snapshots with at most maxParallelUploads concurrent uploads.
+func newDeltaSnapshotUploads(maxParallelUploads uint) *deltaSnapshotUploads {
+	if maxParallelUploads < 1 {
+		maxParallelUploads = 1
+	}
+	return &deltaSnapshotUploads{
+		slots: make(chan struct{}, maxParallelUploads),
+	}
+}
+
+// uploadDeltaSnapshotAsync uploads the given delta snapshot in the background, blocking until an upload slot is free.
+// The given cleanup func is called once the upload has finished.
+func (ssr *Snapshotter) uploadDeltaSnapshotAsync(snap *brtypes.Snapshot, rc io.ReadCloser, cleanup func()) {
+	uploads := ssr.deltaUploads
+	// The snapstore is replaced if its credentials are updated, hence the upload sticks to the current one.
+	store := ssr.store
+	upload := &deltaSnapshotUpload{snap: snap, dictionaryID: ssr.compressionDictionaryID.Load()}
+	uploads.mutex.Lock()
+	uploads.pending = append(uploads.pending, upload)
+	uploads.mutex.Unlock()
+
+	uploads.slots <- emptyStruct
+	uploads.wg.Add(1)
+	go func() {
+		defer uploads.wg.Done()
+		defer func() { <-uploads.slots }()
+		defer cleanup()
+		defer rc.Close()
+
+		err := ssr.saveDeltaSnapshot(store, snap, rc)
+
+		uploads.mutex.Lock()
+		defer uploads.mutex.Unlock()
+		if err != nil {
+			if uploads.err == nil {
+				uploads.err = fmt.Errorf("failed to upload delta snapshot %s: %w", snap.SnapName, err)
+			}
+			// The delta snapshots after the failed one can't be restored, hence none of them is recorded, and the
+			// ones already uploaded are deleted, as their events are taken into delta snapshots again.
+			for i, pending := range uploads.pending {
+				if pending != upload {
+					continue
+				}
+				for _, dropped := range uploads.pending[i+1:] {
+					dropped.dropped = true
+					if dropped.done {
+						ssr.deleteDroppedDeltaSnapshot(store, dropped.snap)
+					}
+				}
+				uploads.pending = uploads.pending[:i]
+				break
+			}
+			return
+		}
+		upload.done = true
+		if upload.dropped {
+			ssr.deleteDroppedDeltaSnapshot(store, snap)
+			return
+		}
+		for len(uploads.pending) > 0 && uploads.pending[0].done {
+			ssr.recordDeltaSnapshot(uploads.pending[0].snap)
+			uploads.pending = uploads.pending[1:]
+		}
+	}()
+}
+
+// deleteDroppedDeltaSnapshot deletes the given delta snapshot, which was uploaded after the upload of a delta
+// snapshot before it failed, from the given snapstore. The delta snapshot is deleted as listed, since the delta
+// snapshot taken in-process lacks the prefix it was saved under.
+func (ssr *Snapshotter) deleteDroppedDeltaSnapshot(store brtypes.SnapStore, snap *brtypes.Snapshot) {
+	ssr.logger.Warnf("Deleting delta snapshot %s, as the upload of a delta snapshot before it failed.", snap.SnapName)
+	snapList, err := store.List()
+	if err != nil {
+		ssr.logger.Warnf("Failed to list the snapstore to delete delta snapshot %s: %v", snap.SnapName, err)
+		return
+	}
+	for _, listed := range snapList {
+		if listed.Kind == brtypes.SnapshotKindDelta && listed.SnapName == snap.SnapName {
+			if err := store.Delete(*listed); err != nil {
+				ssr.logger.Warnf("Failed to delete delta snapshot %s: %v", snap.SnapName, err)
+			}
+			return
+		}
+	}
+}
+
+// waitForDeltaSnapshotUploads waits for the delta snapshots being uploaded in the background, and returns the
+// error of the first failed upload since the previous wait, if any. As the delta snapshots were taken one after the
+// other before being uploaded, the previous snapshot is then rolled back to the last recorded snapshot, so that the
+// events are collected again from the start revision of the failed delta snapshot instead of leaving a gap.
+func (ssr *Snapshotter) waitForDeltaSnapshotUploads() error {
+	uploads := ssr.deltaUploads
+	uploads.wg.Wait()
+
+	uploads.mutex.Lock()
+	defer uploads.mutex.Unlock()
+	err := uploads.err
+	uploads.err = nil
+	uploads.pending = nil
+	if err != nil {
+		lastRecordedSnapshot := ssr.getLastRecordedSnapshot()
+		if lastRecordedSnapshot != nil && ssr.PrevSnapshot != lastRecordedSnapshot {
+			ssr.logger.Warnf("Rolling the previous snapshot back to %s, which was recorded before the failed upload, to take the delta snapshots from revision %d again.", lastRecordedSnapshot.SnapName, lastRecordedSnapshot.LastRevision+1)
+			ssr.PrevSnapshot = lastRecordedSnapshot
+			ssr.prevDeltaOpsSHA256 = nil
+		}
+	}
+	return err
+}
+
+// deltaSnapshotUploadErr returns the error of the first failed upload of a delta snapshot since the previous wait, if any.
+func (ssr *Snapshotter) deltaSnapshotUploadErr() error {
+	uploads := ssr.deltaUploads
+	uploads.mutex.Lock()
+	defer uploads.mutex.Unlock()
+	return uploads.err
+}
+
+// pendingDeltaSnapshotDictionaryIDs returns the identifiers of the compression dictionaries which the delta snapshots
+// being uploaded were compressed with.
+func (ssr *Snapshotter) pendingDeltaSnapshotDictionaryIDs() []int64 {
+	uploads := ssr.deltaUploads
+	uploads.mutex.Lock()
+	defer uploads.mutex.Unlock()
+	ids := make([]int64, 0, len(uploads.pending))
+	for _, upload := range uploads.pending {
+		ids = append(ids, upload.dictionaryID)
+	}
+	return ids
+}
@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshotter
+
+import (
+	"context"
+	"fmt"
+
+	etcdClient "github.com/gardener/etcd-backup-restore/pkg/etcdutil/client"
+	"github.com/gardener/etcd-backup-restore/pkg/metrics"
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.etcd.io/etcd/etcdserver/etcdserverpb"
+)
+
+// checkEtcdAlarms queries the active etcd alarms as per the etcd alarm policy, and exposes them as a metric.
+// It returns ErrEtcdNoSpaceAlarm if a NOSPACE alarm is active and the full snapshots should fail in that case.
+// A failure to query the alarms is only logged, as it does not affect the full snapshot.
+func (ssr *Snapshotter) checkEtcdAlarms(clientFactory etcdClient.Factory) error {
+	if ssr.config.EtcdAlarmPolicy != brtypes.EtcdAlarmPolicyReport && ssr.config.EtcdAlarmPolicy != brtypes.EtcdAlarmPolicyFailOnNoSpace {
+		return nil
+	}
+
+	activeAlarms, err := ssr.getActiveEtcdAlarms(clientFactory)
+	if err != nil {
+		ssr.logger.Warnf("Failed to query the active etcd alarms: %v", err)
+		return nil
+	}
+	for _, alarmType := range []etcdserverpb.AlarmType{etcdserverpb.AlarmType_NOSPACE, etcdserverpb.AlarmType_CORRUPT} {
+		active := 0
+		if _, ok := activeAlarms[alarmType]; ok {
+			active = 1
+		}
+		metrics.EtcdAlarmActive.With(prometheus.Labels{metrics.LabelEtcdAlarm: alarmType.String()}).Set(float64(active))
+	}
+
+	if _, ok := activeAlarms[etcdserverpb.AlarmType_NOSPACE]; ok && ssr.config.EtcdAlarmPolicy == brtypes.EtcdAlarmPolicyFailOnNoSpace {
+		return ErrEtcdNoSpaceAlarm
+	}
+	return nil
+}
+
+// getActiveEtcdAlarms returns the types of the alarms active on any etcd member, logging each of them.
+func (ssr *Snapshotter) getActiveEtcdAlarms(clientFactory etcdClient.Factory) (map[etcdserverpb.AlarmType]struct{}, error) {
+	clientMaintenance, err := clientFactory.NewMaintenance()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build etcd maintenance client: %v", err)
+	}
+	defer clientMaintenance.Close()
+
+	ctx, cancel := context.WithTimeout(context.TODO(), ssr.etcdConnectionConfig.ConnectionTimeout.Duration)
+	defer cancel()
+	resp, err := clientMaintenance.AlarmList(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	activeAlarms := map[etcdserverpb.AlarmType]struct{}{}
+	for _, alarm := range resp.Alarms {
+		if alarm.Alarm == etcdserverpb.AlarmType_NONE {
+			continue
+		}
+		ssr.logger.Warnf("Alarm %s is active on etcd member %x.", alarm.Alarm, alarm.MemberID)
+		activeAlarms[alarm.Alarm] = struct{}{}
+	}
+	return activeAlarms, nil
+}
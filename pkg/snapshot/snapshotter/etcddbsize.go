@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshotter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gardener/etcd-backup-restore/pkg/etcdutil"
+	"github.com/gardener/etcd-backup-restore/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// updateEtcdDBSizeMetrics reads the size of the backend database of etcd and its size in use from the status of the
+// etcd member, and exposes them as metrics.
+func (ssr *Snapshotter) updateEtcdDBSizeMetrics() error {
+	if len(ssr.etcdConnectionConfig.Endpoints) == 0 {
+		return fmt.Errorf("no etcd endpoint to read the database size from")
+	}
+	// a factory of its own is used, as the factory of the snapshotter is only rebuilt by the snapshotting goroutine
+	clientFactory := etcdutil.NewClientFactory(ssr.NewClientFactory, *ssr.etcdConnectionConfig)
+	clientMaintenance, err := clientFactory.NewMaintenance()
+	if err != nil {
+		return fmt.Errorf("failed to build etcd maintenance client: %v", err)
+	}
+	defer clientMaintenance.Close()
+
+	ctx, cancel := context.WithTimeout(context.TODO(), ssr.etcdConnectionConfig.ConnectionTimeout.Duration)
+	defer cancel()
+	status, err := clientMaintenance.Status(ctx, ssr.etcdConnectionConfig.Endpoints[0])
+	if err != nil {
+		return fmt.Errorf("failed to get status of etcd endpoint %s: %v", ssr.etcdConnectionConfig.Endpoints[0], err)
+	}
+	metrics.EtcdDBSizeBytes.With(prometheus.Labels{}).Set(float64(status.DbSize))
+	metrics.EtcdDBSizeInUseBytes.With(prometheus.Labels{}).Set(float64(status.DbSizeInUse))
+	return nil
+}
+
+// UpdateEtcdDBSizeMetricsPeriodically refreshes the metrics of the size of the backend database of etcd every etcd db
+// size metrics period until it is stopped. A failure to read the size is only logged, and retried at the next period.
+func (ssr *Snapshotter) UpdateEtcdDBSizeMetricsPeriodically(stopCh <-chan struct{}) {
+	ticker := ssr.Clock.NewTicker(ssr.config.EtcdDBSizeMetricsPeriod.Duration)
+	defer ticker.Stop()
+
+	for {
+		if err := ssr.updateEtcdDBSizeMetrics(); err != nil {
+			ssr.logger.Warnf("Failed to update the etcd database size metrics: %v", err)
+		}
+		select {
+		case <-ticker.C():
+		case <-stopCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,29 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshotter
+
+import (
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// SetLastRecordedSnapshot sets the latest snapshot which has been uploaded and recorded, as if it was recorded by
+// the snapshotter itself.
+func (ssr *Snapshotter) SetLastRecordedSnapshot(snap *brtypes.Snapshot) {
+	ssr.prevDeltaSnapshotsMutex.Lock()
+	defer ssr.prevDeltaSnapshotsMutex.Unlock()
+	ssr.lastRecordedSnapshot = snap
+}
+
+// LastRecordedSnapshot returns the latest snapshot which has been uploaded and recorded.
+func (ssr *Snapshotter) LastRecordedSnapshot() *brtypes.Snapshot {
+	return ssr.getLastRecordedSnapshot()
+}
+
+// RetryFullSnapshotWithBackoff schedules the retry of a failed full snapshot after the given number of consecutive
+// failed full snapshots, and returns the error which the snapshotter would fail with instead, if any.
+func (ssr *Snapshotter) RetryFullSnapshotWithBackoff(failures uint, err error) error {
+	ssr.fullSnapshotFailures = failures
+	return ssr.retryFullSnapshotWithBackoff(err)
+}
@@ -0,0 +1,48 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshotter
+
+import (
+	"time"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// fullSnapshotSchedule merges the primary full snapshot schedule with the named additional full snapshot schedules,
+// firing at the earliest upcoming time across all of them.
+type fullSnapshotSchedule []brtypes.NamedSchedule
+
+// Next returns the earliest time after the given time at which any of the schedules fires, or the zero time if none of
+// them fires in the future.
+func (s fullSnapshotSchedule) Next(t time.Time) time.Time {
+	next, _ := s.next(t)
+	return next
+}
+
+// next returns the earliest time after the given time at which any of the schedules fires, along with the name of the
+// schedule firing at it. The schedule given first wins if several of them fire at the same time.
+func (s fullSnapshotSchedule) next(t time.Time) (time.Time, string) {
+	var (
+		earliest time.Time
+		name     string
+	)
+	for _, schedule := range s {
+		next := schedule.Next(t)
+		if next.IsZero() {
+			continue
+		}
+		if earliest.IsZero() || next.Before(earliest) {
+			earliest, name = next, schedule.Name
+		}
+	}
+	return earliest, name
+}
+
+// NextScheduledFullSnapshot returns the time of the next scheduled full snapshot after the given time, along with the
+// name of the full snapshot schedule it is scheduled by. The time is zero if no full snapshots are scheduled for the
+// future.
+func (ssr *Snapshotter) NextScheduledFullSnapshot(t time.Time) (time.Time, string) {
+	return ssr.schedule.next(t)
+}
@@ -5,11 +5,20 @@
 package snapshotter
 
 import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
 	"math"
 	"path"
 	"time"
 
+	"github.com/gardener/etcd-backup-restore/pkg/compressor"
 	"github.com/gardener/etcd-backup-restore/pkg/metrics"
+	"github.com/gardener/etcd-backup-restore/pkg/snapshot/restorer"
 	"github.com/gardener/etcd-backup-restore/pkg/snapstore"
 	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
 
@@ -23,162 +32,288 @@ func (ssr *Snapshotter) RunGarbageCollector(stopCh <-chan struct{}) {
 		return
 	}
 
+	// The context is cancelled once the stop signal is received, so that a garbage collection waiting for a throttled
+	// listing to be retried is not holding the garbage collector up.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
 	for {
 		select {
 		case <-stopCh:
 			ssr.logger.Info("GC: Stop signal received. Closing garbage collector.")
 			return
 		case <-time.After(ssr.config.GarbageCollectionPeriod.Duration):
+			ssr.runGarbageCollection(ctx)
+		}
+	}
+}
 
-			var err error
-			// Update the snapstore object before taking any action on object storage bucket.
-			// Refer: https://github.com/gardener/etcd-backup-restore/issues/422
-			ssr.store, err = snapstore.GetSnapstore(ssr.snapstoreConfig)
-			if err != nil {
-				ssr.logger.Warnf("GC: Failed to create snapstore from configured storage provider: %v", err)
-				continue
+// runGarbageCollection runs a single garbage collection cycle and records its outcome.
+func (ssr *Snapshotter) runGarbageCollection(ctx context.Context) {
+	start := time.Now()
+	succeeded := metrics.ValueSucceededTrue
+	if err := ssr.garbageCollect(ctx); err != nil {
+		ssr.logger.Warnf("GC: %v", err)
+		succeeded = metrics.ValueSucceededFalse
+	}
+	metrics.GarbageCollectionRunsTotal.With(prometheus.Labels{metrics.LabelSucceeded: succeeded}).Inc()
+	metrics.GarbageCollectionDurationSeconds.With(prometheus.Labels{metrics.LabelSucceeded: succeeded}).Observe(time.Since(start).Seconds())
+}
+
+// garbageCollect deletes the snapshots which are considered as garbage by the configured garbage collection policy.
+func (ssr *Snapshotter) garbageCollect(ctx context.Context) error {
+	var err error
+	// Update the snapstore object before taking any action on object storage bucket.
+	// Refer: https://github.com/gardener/etcd-backup-restore/issues/422
+	ssr.store, err = snapstore.GetSnapstore(ssr.snapstoreConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create snapstore from configured storage provider: %v", err)
+	}
+
+	var (
+		total           int
+		failedDeletions int
+	)
+	ssr.logger.Info("GC: Executing garbage collection...")
+	// listing the whole snapstore is likely to be throttled by the storage provider if it holds many objects
+	snapList, err := snapstore.ListWithThrottlingRetries(ctx, ssr.store)
+	if err != nil {
+		metrics.SnapshotterOperationFailure.With(prometheus.Labels{metrics.LabelError: err.Error()}).Inc()
+		return fmt.Errorf("failed to list snapshots: %v", err)
+	}
+
+	// Skip chunk deletion for openstack swift provider, since the manifest object is a virtual
+	// representation of the object, and the actual data is stored in the segment objects, aka chunks
+	// Chunk deletion for this provider is handled in regular snapshot deletion
+	if ssr.snapstoreConfig.Provider == brtypes.SnapstoreProviderSwift {
+		var filteredSnapList brtypes.SnapList
+		for _, snap := range snapList {
+			if !snap.IsChunk {
+				filteredSnapList = append(filteredSnapList, snap)
 			}
+		}
+		snapList = filteredSnapList
+	} else {
+		// chunksDeleted stores the no of chunks deleted in the current iteration of GC.
+		var chunksDeleted int
+		chunksDeleted, snapList = ssr.GarbageCollectChunks(snapList)
+		ssr.logger.Infof("GC: Total number garbage collected chunks: %d", chunksDeleted)
+	}
+
+	if ssr.config.GarbageCollectInvalidSnapshots {
+		var invalidDeleted int
+		invalidDeleted, snapList = ssr.GarbageCollectInvalidDeltaSnapshots(snapList)
+		total += invalidDeleted
+	}
 
-			total := 0
-			ssr.logger.Info("GC: Executing garbage collection...")
-			snapList, err := ssr.store.List()
+	// The storage budget covers all the stored snapshots, including the ones excluded from deletion below.
+	storedSnapList := snapList
+
+	// Delta snapshots which must be retained irrespective of their age are excluded from the
+	// list, so that none of the policies below consider them for deletion.
+	snapList = ssr.excludeMinDeltaSnapshotsToKeep(snapList)
+
+	snapStreamIndexList := getSnapStreamIndexList(snapList)
+
+	switch ssr.config.GarbageCollectionPolicy {
+	case brtypes.GarbageCollectionPolicyExponential:
+		// Overall policy:
+		// Delete delta snapshots in all snapStream but the latest one.
+		// Keep only the last 24 hourly backups and of all other backups only the last backup in a day.
+		// Keep only the last 7 daily backups and of all other backups only the last backup in a week.
+		// Keep only the last 4 weekly backups.
+		var (
+			deleteSnap bool
+			threshold  int
+			now        = time.Now().UTC()
+			// Round off current time to EOD
+			eod          = now.Truncate(24 * time.Hour).Add(23 * time.Hour).Add(59 * time.Minute).Add(59 * time.Second)
+			trackingWeek = 0
+		)
+		// Here we start processing from second last snapstream, because we want to keep last snapstream
+		// including delta snapshots in it.
+		for snapStreamIndex := len(snapStreamIndexList) - 1; snapStreamIndex > 0; snapStreamIndex-- {
+			snap := snapList[snapStreamIndexList[snapStreamIndex]]
+			nextSnap := snapList[snapStreamIndexList[snapStreamIndex-1]]
+
+			// garbage collect delta snapshots.
+			deletedSnap, err := ssr.GarbageCollectDeltaSnapshots(snapList[snapStreamIndexList[snapStreamIndex-1]:snapStreamIndexList[snapStreamIndex]])
+			total += deletedSnap
 			if err != nil {
-				metrics.SnapshotterOperationFailure.With(prometheus.Labels{metrics.LabelError: err.Error()}).Inc()
-				ssr.logger.Warnf("GC: Failed to list snapshots: %v", err)
+				failedDeletions++
 				continue
 			}
 
-			// Skip chunk deletion for openstack swift provider, since the manifest object is a virtual
-			// representation of the object, and the actual data is stored in the segment objects, aka chunks
-			// Chunk deletion for this provider is handled in regular snapshot deletion
-			if ssr.snapstoreConfig.Provider == brtypes.SnapstoreProviderSwift {
-				var filteredSnapList brtypes.SnapList
-				for _, snap := range snapList {
-					if !snap.IsChunk {
-						filteredSnapList = append(filteredSnapList, snap)
-					}
+			delta := eod.Sub(nextSnap.CreatedOn)
+			// Depending on how old the nextSnap is, decide what is the criteria of saving it (1 per hour or day or week)
+			switch {
+			case delta < time.Duration(24)*time.Hour:
+				// Snapshot of current day
+				if nextSnap.CreatedOn.Hour() == now.Hour() {
+					// Save snapshot of current hour
+					threshold = 0
+					break
+				}
+				threshold = 1
+			case delta < time.Duration(8*24)*time.Hour:
+				// Snapshot of week ending with previous day
+				threshold = 24
+			case delta < time.Duration(5*7*24)*time.Hour:
+				// Snapshot of month ending 8 days back (i.e., lesser than 5 weeks old)
+				if trackingWeek == 0 {
+					// As The week ends previous day, to keep track of change in week
+					// we shift eod to previous day's EOD when start tracking week
+					eod = eod.Add(-24 * time.Hour)
+					trackingWeek = 1
 				}
-				snapList = filteredSnapList
+				threshold = 24 * 7
+			default:
+				// Delete snapshots older than 4 weeks
+				threshold = math.MaxInt32
+			}
+
+			// Were snap and nextSnap created in different hour windows
+			hourChange := int(eod.Sub(nextSnap.CreatedOn).Hours()) - int(eod.Sub(snap.CreatedOn).Hours())
+			// Were snap and nextSnap created in different day windows
+			dayChange := int(eod.Sub(nextSnap.CreatedOn).Hours()/24) - int(eod.Sub(snap.CreatedOn).Hours()/24)
+			// Were snap and nextSnap created in different week windows
+			weekChange := int(eod.Sub(nextSnap.CreatedOn).Hours()/(24*7)) - int(eod.Sub(snap.CreatedOn).Hours()/(24*7))
+
+			if threshold == 0 || hourChange/threshold != 0 || dayChange*24/threshold != 0 || weekChange*24*7/threshold != 0 {
+				// The change in parameter was more than the threshold, so don't delete the snapshot
+				deleteSnap = false
 			} else {
-				// chunksDeleted stores the no of chunks deleted in the current iteration of GC.
-				var chunksDeleted int
-				chunksDeleted, snapList = ssr.GarbageCollectChunks(snapList)
-				ssr.logger.Infof("GC: Total number garbage collected chunks: %d", chunksDeleted)
+				// The change in parameter was less than the threshold, so delete the snapshot
+				deleteSnap = true
 			}
 
-			snapStreamIndexList := getSnapStreamIndexList(snapList)
-
-			switch ssr.config.GarbageCollectionPolicy {
-			case brtypes.GarbageCollectionPolicyExponential:
-				// Overall policy:
-				// Delete delta snapshots in all snapStream but the latest one.
-				// Keep only the last 24 hourly backups and of all other backups only the last backup in a day.
-				// Keep only the last 7 daily backups and of all other backups only the last backup in a week.
-				// Keep only the last 4 weekly backups.
-				var (
-					deleteSnap bool
-					threshold  int
-					now        = time.Now().UTC()
-					// Round off current time to EOD
-					eod          = now.Truncate(24 * time.Hour).Add(23 * time.Hour).Add(59 * time.Minute).Add(59 * time.Second)
-					trackingWeek = 0
-				)
-				// Here we start processing from second last snapstream, because we want to keep last snapstream
-				// including delta snapshots in it.
-				for snapStreamIndex := len(snapStreamIndexList) - 1; snapStreamIndex > 0; snapStreamIndex-- {
-					snap := snapList[snapStreamIndexList[snapStreamIndex]]
-					nextSnap := snapList[snapStreamIndexList[snapStreamIndex-1]]
-
-					// garbage collect delta snapshots.
-					deletedSnap, err := ssr.GarbageCollectDeltaSnapshots(snapList[snapStreamIndexList[snapStreamIndex-1]:snapStreamIndexList[snapStreamIndex]])
-					total += deletedSnap
-					if err != nil {
-						continue
-					}
-
-					delta := eod.Sub(nextSnap.CreatedOn)
-					// Depending on how old the nextSnap is, decide what is the criteria of saving it (1 per hour or day or week)
-					switch {
-					case delta < time.Duration(24)*time.Hour:
-						// Snapshot of current day
-						if nextSnap.CreatedOn.Hour() == now.Hour() {
-							// Save snapshot of current hour
-							threshold = 0
-							break
-						}
-						threshold = 1
-					case delta < time.Duration(8*24)*time.Hour:
-						// Snapshot of week ending with previous day
-						threshold = 24
-					case delta < time.Duration(5*7*24)*time.Hour:
-						// Snapshot of month ending 8 days back (i.e., lesser than 5 weeks old)
-						if trackingWeek == 0 {
-							// As The week ends previous day, to keep track of change in week
-							// we shift eod to previous day's EOD when start tracking week
-							eod = eod.Add(-24 * time.Hour)
-							trackingWeek = 1
-						}
-						threshold = 24 * 7
-					default:
-						// Delete snapshots older than 4 weeks
-						threshold = math.MaxInt32
-					}
-
-					// Were snap and nextSnap created in different hour windows
-					hourChange := int(eod.Sub(nextSnap.CreatedOn).Hours()) - int(eod.Sub(snap.CreatedOn).Hours())
-					// Were snap and nextSnap created in different day windows
-					dayChange := int(eod.Sub(nextSnap.CreatedOn).Hours()/24) - int(eod.Sub(snap.CreatedOn).Hours()/24)
-					// Were snap and nextSnap created in different week windows
-					weekChange := int(eod.Sub(nextSnap.CreatedOn).Hours()/(24*7)) - int(eod.Sub(snap.CreatedOn).Hours()/(24*7))
-
-					if threshold == 0 || hourChange/threshold != 0 || dayChange*24/threshold != 0 || weekChange*24*7/threshold != 0 {
-						// The change in parameter was more than the threshold, so don't delete the snapshot
-						deleteSnap = false
-					} else {
-						// The change in parameter was less than the threshold, so delete the snapshot
-						deleteSnap = true
-					}
-
-					if deleteSnap {
-						ssr.logger.Infof("GC: Deleting old full snapshot: %s %v", nextSnap.CreatedOn.UTC(), deleteSnap)
-						if err := ssr.store.Delete(*nextSnap); err != nil {
-							ssr.logger.Warnf("GC: Failed to delete snapshot %s: %v", path.Join(nextSnap.SnapDir, nextSnap.SnapName), err)
-							metrics.SnapshotterOperationFailure.With(prometheus.Labels{metrics.LabelError: err.Error()}).Inc()
-							metrics.GCSnapshotCounter.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindFull, metrics.LabelSucceeded: metrics.ValueSucceededFalse}).Inc()
-							continue
-						}
-						metrics.GCSnapshotCounter.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindFull, metrics.LabelSucceeded: metrics.ValueSucceededTrue}).Inc()
-						total++
-					}
+			if deleteSnap && !ssr.isRetained(nextSnap) {
+				ssr.logger.Infof("GC: Deleting old full snapshot: %s %v", nextSnap.CreatedOn.UTC(), deleteSnap)
+				if err := ssr.store.Delete(*nextSnap); err != nil {
+					ssr.logger.Warnf("GC: Failed to delete snapshot %s: %v", path.Join(nextSnap.SnapDir, nextSnap.SnapName), err)
+					metrics.SnapshotterOperationFailure.With(prometheus.Labels{metrics.LabelError: err.Error()}).Inc()
+					metrics.GCSnapshotCounter.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindFull, metrics.LabelSucceeded: metrics.ValueSucceededFalse}).Inc()
+					failedDeletions++
+					continue
 				}
+				metrics.GCSnapshotCounter.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindFull, metrics.LabelSucceeded: metrics.ValueSucceededTrue}).Inc()
+				metrics.GarbageCollectionDeletedSnapshotsTotal.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindFull}).Inc()
+				total++
+			}
+		}
 
-			case brtypes.GarbageCollectionPolicyLimitBased:
-				// Delete delta snapshots in all snapStream but the latest one.
-				// Delete all snapshots beyond limit set by ssr.maxBackups.
-				for snapStreamIndex := 0; snapStreamIndex < len(snapStreamIndexList)-1; snapStreamIndex++ {
-					deletedSnap, err := ssr.GarbageCollectDeltaSnapshots(snapList[snapStreamIndexList[snapStreamIndex]:snapStreamIndexList[snapStreamIndex+1]])
-					total += deletedSnap
-					if err != nil {
-						continue
-					}
-					if snapStreamIndex < len(snapStreamIndexList)-int(ssr.config.MaxBackups) {
-						snap := snapList[snapStreamIndexList[snapStreamIndex]]
-						snapPath := path.Join(snap.SnapDir, snap.SnapName)
-						ssr.logger.Infof("GC: Deleting old full snapshot: %s", snapPath)
-						if err := ssr.store.Delete(*snap); err != nil {
-							ssr.logger.Warnf("GC: Failed to delete snapshot %s: %v", snapPath, err)
-							metrics.SnapshotterOperationFailure.With(prometheus.Labels{metrics.LabelError: err.Error()}).Inc()
-							metrics.GCSnapshotCounter.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindFull, metrics.LabelSucceeded: metrics.ValueSucceededFalse}).Inc()
-							continue
-						}
-						metrics.GCSnapshotCounter.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindFull, metrics.LabelSucceeded: metrics.ValueSucceededTrue}).Inc()
-						total++
-					}
+	case brtypes.GarbageCollectionPolicyLimitBased:
+		// Delete delta snapshots in all snapStream but the latest one.
+		// Delete all snapshots beyond limit set by ssr.maxBackups.
+		for snapStreamIndex := 0; snapStreamIndex < len(snapStreamIndexList)-1; snapStreamIndex++ {
+			deletedSnap, err := ssr.GarbageCollectDeltaSnapshots(snapList[snapStreamIndexList[snapStreamIndex]:snapStreamIndexList[snapStreamIndex+1]])
+			total += deletedSnap
+			if err != nil {
+				failedDeletions++
+				continue
+			}
+			if snapStreamIndex < len(snapStreamIndexList)-int(ssr.config.MaxBackups) {
+				snap := snapList[snapStreamIndexList[snapStreamIndex]]
+				if ssr.isRetained(snap) {
+					continue
 				}
+				snapPath := path.Join(snap.SnapDir, snap.SnapName)
+				ssr.logger.Infof("GC: Deleting old full snapshot: %s", snapPath)
+				if err := ssr.store.Delete(*snap); err != nil {
+					ssr.logger.Warnf("GC: Failed to delete snapshot %s: %v", snapPath, err)
+					metrics.SnapshotterOperationFailure.With(prometheus.Labels{metrics.LabelError: err.Error()}).Inc()
+					metrics.GCSnapshotCounter.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindFull, metrics.LabelSucceeded: metrics.ValueSucceededFalse}).Inc()
+					failedDeletions++
+					continue
+				}
+				metrics.GCSnapshotCounter.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindFull, metrics.LabelSucceeded: metrics.ValueSucceededTrue}).Inc()
+				metrics.GarbageCollectionDeletedSnapshotsTotal.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindFull}).Inc()
+				total++
 			}
-			ssr.logger.Infof("GC: Total number garbage collected snapshots: %d", total)
 		}
+
+	case brtypes.GarbageCollectionPolicyStorageBudget:
+		deleted, failed, err := ssr.garbageCollectStorageBudget(storedSnapList, snapList, snapStreamIndexList)
+		total += deleted
+		failedDeletions += failed
+		if err != nil {
+			return err
+		}
+	}
+	ssr.logger.Infof("GC: Total number garbage collected snapshots: %d", total)
+	if err := ssr.reconcileChainManifest(); err != nil {
+		ssr.logger.Warnf("GC: Failed to reconcile the chain manifest: %v", err)
 	}
+	if err := ssr.garbageCollectCompressionDictionaries(); err != nil {
+		ssr.logger.Warnf("GC: Failed to garbage collect the compression dictionaries: %v", err)
+	}
+	if failedDeletions > 0 {
+		return fmt.Errorf("failed to delete %d snapshots", failedDeletions)
+	}
+	return nil
+}
+
+// garbageCollectStorageBudget deletes the oldest snapshots until the total size of the stored snapshots fits within
+// the configured MaxTotalStorageBytes, and returns the number of deleted snapshots and of failed deletions.
+// The snapStreams are pruned from the oldest one on, each from its latest delta snapshot back to its full snapshot,
+// so that the remaining snapshots of a partially pruned snapStream can still be restored. The latest snapStream is
+// never pruned, even if it doesn't fit within the budget on its own, and neither are the retained snapshots.
+// The sizes are taken from the listing of the snapstore, and only queried for the snapshots listed without their size.
+// No snapshot is deleted if the size of any of the stored snapshots can't be determined.
+func (ssr *Snapshotter) garbageCollectStorageBudget(storedSnapList, snapList brtypes.SnapList, snapStreamIndexList []int) (int, int, error) {
+	var (
+		totalSize       int64
+		deleted         int
+		failedDeletions int
+		sizes           = make(map[*brtypes.Snapshot]int64, len(storedSnapList))
+	)
+	for _, snap := range storedSnapList {
+		size := snap.SizeBytes
+		if size <= 0 {
+			var err error
+			if size, err = snapstore.SnapshotSize(ssr.store, *snap); err != nil {
+				return 0, 0, fmt.Errorf("failed to get size of snapshot %s: %v", path.Join(snap.SnapDir, snap.SnapName), err)
+			}
+		}
+		sizes[snap] = size
+		totalSize += size
+	}
+	ssr.logger.Infof("GC: Total size of the stored snapshots is %dB, with a storage budget of %dB", totalSize, ssr.config.MaxTotalStorageBytes)
+
+	for snapStreamIndex := 0; snapStreamIndex < len(snapStreamIndexList)-1 && totalSize > ssr.config.MaxTotalStorageBytes; snapStreamIndex++ {
+		snapStream := snapList[snapStreamIndexList[snapStreamIndex]:snapStreamIndexList[snapStreamIndex+1]]
+		for i := len(snapStream) - 1; i >= 0 && totalSize > ssr.config.MaxTotalStorageBytes; i-- {
+			snap := snapStream[i]
+			if ssr.isRetained(snap) {
+				continue
+			}
+			snapPath := path.Join(snap.SnapDir, snap.SnapName)
+			ssr.logger.Infof("GC: Deleting old %s snapshot to fit within the storage budget: %s", snap.Kind, snapPath)
+			if err := ssr.store.Delete(*snap); err != nil {
+				ssr.logger.Warnf("GC: Failed to delete snapshot %s: %v", snapPath, err)
+				metrics.SnapshotterOperationFailure.With(prometheus.Labels{metrics.LabelError: err.Error()}).Inc()
+				metrics.GCSnapshotCounter.With(prometheus.Labels{metrics.LabelKind: snap.Kind, metrics.LabelSucceeded: metrics.ValueSucceededFalse}).Inc()
+				failedDeletions++
+				continue
+			}
+			metrics.GCSnapshotCounter.With(prometheus.Labels{metrics.LabelKind: snap.Kind, metrics.LabelSucceeded: metrics.ValueSucceededTrue}).Inc()
+			metrics.GarbageCollectionDeletedSnapshotsTotal.With(prometheus.Labels{metrics.LabelKind: snap.Kind}).Inc()
+			totalSize -= sizes[snap]
+			deleted++
+		}
+	}
+
+	if totalSize > ssr.config.MaxTotalStorageBytes {
+		ssr.logger.Warnf("GC: Total size of the stored snapshots is %dB after garbage collection, which exceeds the storage budget of %dB", totalSize, ssr.config.MaxTotalStorageBytes)
+	}
+	return deleted, failedDeletions, nil
 }
 
 // getSnapStreamIndexList lists the index of snapStreams in snapList which consist of collection of snapStream.
@@ -198,12 +333,61 @@ func getSnapStreamIndexList(snapList brtypes.SnapList) []int {
 	return snapStreamIndexList
 }
 
+// excludeMinDeltaSnapshotsToKeep returns the snapList without the most recent delta snapshots which are to be
+// retained as per the configured MinDeltaSnapshotsToKeep. The delta snapshots of the latest snapStream are never
+// garbage collected, but count towards the delta snapshots to keep, hence only the most recent delta snapshots of the
+// previous snapStreams are excluded, once the latest full snapshot has fewer delta snapshots than are to be kept.
+func (ssr *Snapshotter) excludeMinDeltaSnapshotsToKeep(snapList brtypes.SnapList) brtypes.SnapList {
+	toKeep := ssr.config.MinDeltaSnapshotsToKeep
+	if toKeep == 0 {
+		return snapList
+	}
+	// At this stage, we assume the snapList is sorted in increasing order of last revision number.
+	retained := make(map[*brtypes.Snapshot]bool, toKeep)
+	for i := len(snapList) - 1; i >= 0 && uint(len(retained)) < toKeep; i-- {
+		if snapList[i].Kind == brtypes.SnapshotKindDelta && !snapList[i].IsChunk {
+			retained[snapList[i]] = true
+		}
+	}
+
+	var filteredSnapList brtypes.SnapList
+	for _, snap := range snapList {
+		if !retained[snap] {
+			filteredSnapList = append(filteredSnapList, snap)
+		}
+	}
+	ssr.logger.Infof("GC: Retaining %d most recent delta snapshots irrespective of their age", len(retained))
+	return filteredSnapList
+}
+
+// isRetained checks whether the snapshot is tagged to be retained irrespective of the garbage collection policy.
+// A snapshot is retained as well if its tags can't be checked, since a deleted snapshot can't be recovered, and while
+// it belongs to the chain of a restoration running in the process, which would fail if it was deleted mid-restore.
+func (ssr *Snapshotter) isRetained(snap *brtypes.Snapshot) bool {
+	snapPath := path.Join(snap.SnapDir, snap.SnapName)
+	if restorer.IsSnapshotInRestore(snap) {
+		ssr.logger.Infof("GC: Retaining snapshot %s which is being restored", snapPath)
+		return true
+	}
+	retained, err := snapstore.IsSnapshotRetained(ssr.store, *snap)
+	if err != nil {
+		ssr.logger.Warnf("GC: Failed to check whether snapshot %s is retained, hence retaining it: %v", snapPath, err)
+		return true
+	}
+	if retained {
+		ssr.logger.Infof("GC: Retaining snapshot %s tagged with %s", snapPath, brtypes.SnapshotExcludeTag)
+	}
+	return retained
+}
+
 // GarbageCollectChunks removes obsolete chunks based on the latest recorded snapshot.
 // It eliminates chunks associated with snapshots that have already been uploaded.
 // Additionally, it avoids deleting chunks linked to snapshots currently being uploaded to prevent the garbage collector from removing chunks before the composite is formed.
 func (ssr *Snapshotter) GarbageCollectChunks(snapList brtypes.SnapList) (int, brtypes.SnapList) {
 	var nonChunkSnapList brtypes.SnapList
 	chunksDeleted := 0
+	// The delta snapshots being uploaded in the background are not recorded yet, hence their chunks are kept.
+	lastRecordedSnapshot := ssr.getLastRecordedSnapshot()
 	for _, snap := range snapList {
 		// If not chunk, add to list and continue
 		if !snap.IsChunk {
@@ -211,7 +395,7 @@ func (ssr *Snapshotter) GarbageCollectChunks(snapList brtypes.SnapList) (int, br
 			continue
 		}
 		// Skip the chunk deletion if it's corresponding full/delta snapshot is not uploaded yet
-		if ssr.PrevSnapshot.LastRevision == 0 || snap.StartRevision > ssr.PrevSnapshot.LastRevision {
+		if lastRecordedSnapshot == nil || lastRecordedSnapshot.LastRevision == 0 || snap.StartRevision > lastRecordedSnapshot.LastRevision {
 			continue
 		}
 		// delete the chunk object
@@ -225,6 +409,7 @@ func (ssr *Snapshotter) GarbageCollectChunks(snapList brtypes.SnapList) (int, br
 		}
 		chunksDeleted++
 		metrics.GCSnapshotCounter.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindChunk, metrics.LabelSucceeded: metrics.ValueSucceededTrue}).Inc()
+		metrics.GarbageCollectionDeletedSnapshotsTotal.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindChunk}).Inc()
 	}
 	return chunksDeleted, nonChunkSnapList
 }
@@ -245,7 +430,7 @@ func (ssr *Snapshotter) GarbageCollectDeltaSnapshots(snapStream brtypes.SnapList
 	totalDeleted := 0
 	cutoffTime := time.Now().UTC().Add(-ssr.config.DeltaSnapshotRetentionPeriod.Duration)
 	for i := len(snapStream) - 1; i >= 0; i-- {
-		if (*snapStream[i]).Kind == brtypes.SnapshotKindDelta && snapStream[i].CreatedOn.Before(cutoffTime) {
+		if (*snapStream[i]).Kind == brtypes.SnapshotKindDelta && snapStream[i].CreatedOn.Before(cutoffTime) && !ssr.isRetained(snapStream[i]) {
 			snapPath := path.Join(snapStream[i].SnapDir, snapStream[i].SnapName)
 			ssr.logger.Infof("GC: Deleting old delta snapshot: %s", snapPath)
 
@@ -258,9 +443,116 @@ func (ssr *Snapshotter) GarbageCollectDeltaSnapshots(snapStream brtypes.SnapList
 			}
 
 			metrics.GCSnapshotCounter.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindDelta, metrics.LabelSucceeded: metrics.ValueSucceededTrue}).Inc()
+			metrics.GarbageCollectionDeletedSnapshotsTotal.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindDelta}).Inc()
 			totalDeleted++
 		}
 	}
 
 	return totalDeleted, nil
 }
+
+// GarbageCollectInvalidDeltaSnapshots deletes the structurally invalid delta snapshots, such as the partial objects left
+// behind by failed uploads, and returns the number of deleted snapshots along with the remaining snapshots.
+// It is conservative: a delta snapshot is only deleted if its contents are definitely invalid, whereas a snapshot
+// whose contents can't be checked is kept. The delta snapshots found to be valid are persisted, so that they are
+// neither fetched again by later runs nor after a restart of the snapshotter. The previous snapshot of the snapshotter and the retained snapshots are
+// never deleted, since the snapshotter continues its chain of snapshots from the former.
+func (ssr *Snapshotter) GarbageCollectInvalidDeltaSnapshots(snapList brtypes.SnapList) (int, brtypes.SnapList) {
+	var remainingSnapList brtypes.SnapList
+	deleted := 0
+	fetchDictionary := snapstore.NewCompressionDictionaryFetcher(ssr.store)
+	for _, snap := range snapList {
+		if snap.Kind != brtypes.SnapshotKindDelta || snap.IsChunk || ssr.isPrevSnapshot(snap) {
+			remainingSnapList = append(remainingSnapList, snap)
+			continue
+		}
+		snapPath := path.Join(snap.SnapDir, snap.SnapName)
+		if _, ok := ssr.validDeltaSnapshots[snapPath]; ok {
+			remainingSnapList = append(remainingSnapList, snap)
+			continue
+		}
+
+		err := validateDeltaSnapshot(ssr.store, snap, fetchDictionary)
+		if err == nil {
+			ssr.validDeltaSnapshots[snapPath] = struct{}{}
+			remainingSnapList = append(remainingSnapList, snap)
+			continue
+		}
+		if !stderrors.Is(err, errInvalidDeltaSnapshot) {
+			ssr.logger.Warnf("GC: Failed to check whether delta snapshot %s is valid, hence keeping it: %v", snapPath, err)
+			remainingSnapList = append(remainingSnapList, snap)
+			continue
+		}
+		if ssr.isRetained(snap) {
+			remainingSnapList = append(remainingSnapList, snap)
+			continue
+		}
+
+		ssr.logger.Warnf("GC: Deleting invalid delta snapshot %s: %v", snapPath, err)
+		if err := ssr.store.Delete(*snap); err != nil {
+			ssr.logger.Warnf("GC: Failed to delete invalid delta snapshot %s: %v", snapPath, err)
+			metrics.SnapshotterOperationFailure.With(prometheus.Labels{metrics.LabelError: err.Error()}).Inc()
+			metrics.GCSnapshotCounter.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindDelta, metrics.LabelSucceeded: metrics.ValueSucceededFalse}).Inc()
+			remainingSnapList = append(remainingSnapList, snap)
+			continue
+		}
+		deleted++
+		metrics.GCSnapshotCounter.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindDelta, metrics.LabelSucceeded: metrics.ValueSucceededTrue}).Inc()
+		metrics.GarbageCollectionDeletedSnapshotsTotal.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindDelta}).Inc()
+		metrics.GarbageCollectionInvalidSnapshotsTotal.With(prometheus.Labels{}).Inc()
+	}
+	ssr.saveValidDeltaSnapshots(remainingSnapList)
+	return deleted, remainingSnapList
+}
+
+// isPrevSnapshot checks whether the given snapshot is the latest recorded snapshot of the snapshotter.
+func (ssr *Snapshotter) isPrevSnapshot(snap *brtypes.Snapshot) bool {
+	prevSnapshot := ssr.getLastRecordedSnapshot()
+	return prevSnapshot != nil && prevSnapshot.SnapDir == snap.SnapDir && prevSnapshot.SnapName == snap.SnapName
+}
+
+// validateDeltaSnapshot checks whether the contents of the given delta snapshot are a JSON list of events followed by
+// their SHA256 hash. It returns an error wrapping errInvalidDeltaSnapshot only if the contents are definitely invalid.
+// The delta snapshots compressed with a dictionary are decompressed with the dictionary fetched by fetchDictionary.
+func validateDeltaSnapshot(store brtypes.SnapStore, snap *brtypes.Snapshot, fetchDictionary compressor.DictionaryFetcher) error {
+	isCompressed, compressionPolicy, err := compressor.IsSnapshotCompressed(snap.CompressionSuffix)
+	if err != nil {
+		return err
+	}
+	rc, err := store.Fetch(*snap)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	r := rc
+	if isCompressed {
+		if r, err = compressor.DecompressSnapshotWithDictionary(rc, compressionPolicy, fetchDictionary); err != nil {
+			return invalidDeltaSnapshotError(err)
+		}
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return invalidDeltaSnapshotError(err)
+	}
+
+	events, _, err := brtypes.VerifyDeltaSnapshotHash(data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidDeltaSnapshot, err)
+	}
+	if events[0] != '[' || events[len(events)-1] != ']' {
+		return fmt.Errorf("%w: events are not a list", errInvalidDeltaSnapshot)
+	}
+	return nil
+}
+
+// invalidDeltaSnapshotError wraps the given error of reading the contents of a delta snapshot with errInvalidDeltaSnapshot
+// if it indicates truncated or corrupt contents, and returns it as is otherwise.
+func invalidDeltaSnapshotError(err error) error {
+	var corruptInputErr flate.CorruptInputError
+	if stderrors.Is(err, io.ErrUnexpectedEOF) || stderrors.Is(err, gzip.ErrHeader) || stderrors.Is(err, gzip.ErrChecksum) ||
+		stderrors.Is(err, zlib.ErrHeader) || stderrors.Is(err, zlib.ErrChecksum) || stderrors.As(err, &corruptInputErr) {
+		return fmt.Errorf("%w: %v", errInvalidDeltaSnapshot, err)
+	}
+	return err
+}
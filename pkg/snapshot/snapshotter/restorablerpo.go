@@ -0,0 +1,48 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshotter
+
+import (
+	"time"
+
+	"github.com/gardener/etcd-backup-restore/pkg/metrics"
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// restorableRPOUpdateInterval is the interval at which the age of the latest restorable snapshot is refreshed.
+const restorableRPOUpdateInterval = 15 * time.Second
+
+// recordRestorableSnapshot records the given saved snapshot as the latest one up to which the data can be restored.
+func (ssr *Snapshotter) recordRestorableSnapshot(snap *brtypes.Snapshot) {
+	ssr.latestRestorableSnapshotTime.Store(snap.CreatedOn.UnixNano())
+	ssr.updateRestorableRPO()
+}
+
+// updateRestorableRPO sets the RPO metric to the age of the latest restorable snapshot, if there is one.
+func (ssr *Snapshotter) updateRestorableRPO() {
+	createdOn := ssr.latestRestorableSnapshotTime.Load()
+	if createdOn == 0 {
+		return
+	}
+	metrics.RestorableRPOSeconds.With(prometheus.Labels{}).Set(ssr.Clock.Since(time.Unix(0, createdOn)).Seconds())
+}
+
+// UpdateRestorableRPOPeriodically refreshes the RPO metric periodically until it is stopped, so that it keeps growing
+// while no snapshots are saved.
+func (ssr *Snapshotter) UpdateRestorableRPOPeriodically(stopCh <-chan struct{}) {
+	ticker := ssr.Clock.NewTicker(restorableRPOUpdateInterval)
+	defer ticker.Stop()
+
+	ssr.updateRestorableRPO()
+	for {
+		select {
+		case <-ticker.C():
+			ssr.updateRestorableRPO()
+		case <-stopCh:
+			return
+		}
+	}
+}
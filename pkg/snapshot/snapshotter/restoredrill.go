@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshotter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gardener/etcd-backup-restore/pkg/metrics"
+	"github.com/gardener/etcd-backup-restore/pkg/miscellaneous"
+	"github.com/gardener/etcd-backup-restore/pkg/snapshot/restorer"
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RunRestoreDrills runs the restore drills on the restore drill schedule until it is stopped. It returns right away if
+// no restore drill schedule is configured.
+func (ssr *Snapshotter) RunRestoreDrills(stopCh <-chan struct{}) {
+	if ssr.restoreDrillSchedule == nil {
+		return
+	}
+	if ssr.RestoreOptions == nil {
+		ssr.logger.Warn("Restore drill: Not running restore drills since no restore options are set.")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		now := ssr.Clock.Now()
+		timer := ssr.Clock.NewTimer(ssr.restoreDrillSchedule.Next(now).Sub(now))
+		select {
+		case <-stopCh:
+			timer.Stop()
+			ssr.logger.Info("Restore drill: Stop signal received. Closing restore drills.")
+			return
+		case <-timer.C():
+			ssr.RunRestoreDrill(ctx)
+		}
+	}
+}
+
+// RunRestoreDrill restores the latest snapshots into a throwaway directory next to the etcd data directory and boots
+// an embedded etcd on it, to verify that it reaches the revision of the latest snapshot, and records the outcome in the
+// restore drill metrics. The data directory of etcd is not touched. It returns nil without running a drill if there
+// are no snapshots to restore yet.
+func (ssr *Snapshotter) RunRestoreDrill(ctx context.Context) *brtypes.RestoreVerificationReport {
+	report := ssr.restoreDrill(ctx)
+	if report == nil {
+		return nil
+	}
+
+	succeeded, passed := metrics.ValueSucceededTrue, 1.0
+	if report.Passed {
+		ssr.logger.Infof("Restore drill: Restored the latest snapshots up to revision %d in %s.", report.RestoredRevision, report.Duration)
+	} else {
+		ssr.logger.Errorf("Restore drill: Failed to restore the latest snapshots: %s", report.Error)
+		succeeded, passed = metrics.ValueSucceededFalse, 0
+	}
+	metrics.RestoreDrillsTotal.With(prometheus.Labels{metrics.LabelSucceeded: succeeded}).Inc()
+	metrics.RestoreDrillPassed.With(prometheus.Labels{}).Set(passed)
+	return report
+}
+
+func (ssr *Snapshotter) restoreDrill(ctx context.Context) *brtypes.RestoreVerificationReport {
+	if ssr.config.RestoreDrillTimeout.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ssr.config.RestoreDrillTimeout.Duration)
+		defer cancel()
+	}
+
+	baseSnapshot, deltaSnapList, err := miscellaneous.GetLatestFullSnapshotAndDeltaSnapListWithContext(ctx, ssr.store)
+	if err != nil {
+		return &brtypes.RestoreVerificationReport{Error: fmt.Sprintf("failed to list the latest snapshots: %v", err)}
+	}
+	if baseSnapshot == nil && len(deltaSnapList) == 0 {
+		ssr.logger.Info("Restore drill: Skipping the restore drill since there are no snapshots to restore yet.")
+		return nil
+	}
+
+	rs, err := restorer.NewRestorer(ssr.store, ssr.logger)
+	if err != nil {
+		return &brtypes.RestoreVerificationReport{Error: fmt.Sprintf("failed to create the restorer: %v", err)}
+	}
+
+	ro := ssr.RestoreOptions.DeepCopy()
+	ro.BaseSnapshot = baseSnapshot
+	ro.DeltaSnapList = deltaSnapList
+	ro.Config.MaxFetchers = ssr.config.RestoreDrillMaxFetchers
+	// the drill verifies the latest snapshots, even if the restorations are configured to restore up to a time
+	ro.RestoreToTime = time.Time{}
+	ssr.logger.Infof("Restore drill: Restoring the latest snapshots with %d delta snapshots...", len(deltaSnapList))
+	return rs.VerifyRestore(ctx, *ro, true)
+}
@@ -8,29 +8,40 @@ import (
 	"bytes"
 	"context"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
+	"hash"
 	"io"
+	"math"
 	"path"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/gardener/etcd-backup-restore/pkg/backoff"
 	"github.com/gardener/etcd-backup-restore/pkg/compressor"
 	"github.com/gardener/etcd-backup-restore/pkg/errors"
 	"github.com/gardener/etcd-backup-restore/pkg/etcdutil"
+	"github.com/gardener/etcd-backup-restore/pkg/events"
 	"github.com/gardener/etcd-backup-restore/pkg/health/heartbeat"
 	"github.com/gardener/etcd-backup-restore/pkg/metrics"
 	"github.com/gardener/etcd-backup-restore/pkg/miscellaneous"
 	"github.com/gardener/etcd-backup-restore/pkg/snapstore"
+	"github.com/gardener/etcd-backup-restore/pkg/tracing"
 	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
 	"github.com/gardener/etcd-backup-restore/pkg/wrappers"
 
+	etcdClient "github.com/gardener/etcd-backup-restore/pkg/etcdutil/client"
 	"github.com/prometheus/client_golang/prometheus"
 	cron "github.com/robfig/cron/v3"
 	"github.com/sirupsen/logrus"
 	"go.etcd.io/etcd/clientv3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -45,6 +56,17 @@ const (
 
 var (
 	emptyStruct struct{}
+
+	// ErrDeltaEventsCollectionTimeout is returned if the events since the previous snapshot could not be collected
+	// up to the latest etcd revision within the configured delta events collection timeout.
+	ErrDeltaEventsCollectionTimeout = stderrors.New("timed out waiting for the watch to reach the latest etcd revision")
+
+	// ErrEtcdNoSpaceAlarm is returned by a full snapshot if a NOSPACE alarm is active on etcd and the etcd alarm
+	// policy is set to fail the full snapshots in that case.
+	ErrEtcdNoSpaceAlarm = stderrors.New("etcd NOSPACE alarm is active")
+
+	// errInvalidDeltaSnapshot is returned if the contents of a delta snapshot are not a list of events followed by their hash.
+	errInvalidDeltaSnapshot = stderrors.New("invalid delta snapshot")
 )
 
 // event is wrapper over etcd event to keep track of time of event
@@ -58,15 +80,38 @@ type result struct {
 	Err      error             `json:"error"`
 }
 
+// fullSnapshotRequest is a request to take an out of schedule full snapshot, which is final if isFinal is set, and
+// taken at the given historical revision unless it is 0.
+type fullSnapshotRequest struct {
+	// ctx is the context of the caller requesting the full snapshot, which the span of the full snapshot is started from.
+	ctx      context.Context
+	isFinal  bool
+	revision int64
+}
+
 // NewSnapshotterConfig returns the snapshotter config.
 func NewSnapshotterConfig() *brtypes.SnapshotterConfig {
 	return &brtypes.SnapshotterConfig{
-		FullSnapshotSchedule:     brtypes.DefaultFullSnapshotSchedule,
-		DeltaSnapshotPeriod:      wrappers.Duration{Duration: brtypes.DefaultDeltaSnapshotInterval},
-		DeltaSnapshotMemoryLimit: brtypes.DefaultDeltaSnapMemoryLimit,
-		GarbageCollectionPeriod:  wrappers.Duration{Duration: brtypes.DefaultGarbageCollectionPeriod},
-		GarbageCollectionPolicy:  brtypes.GarbageCollectionPolicyExponential,
-		MaxBackups:               brtypes.DefaultMaxBackups,
+		FullSnapshotSchedule:               brtypes.DefaultFullSnapshotSchedule,
+		DeltaSnapshotPeriod:                wrappers.Duration{Duration: brtypes.DefaultDeltaSnapshotInterval},
+		DeltaSnapshotMemoryLimit:           brtypes.DefaultDeltaSnapMemoryLimit,
+		GarbageCollectionPeriod:            wrappers.Duration{Duration: brtypes.DefaultGarbageCollectionPeriod},
+		GarbageCollectionPolicy:            brtypes.GarbageCollectionPolicyExponential,
+		MaxBackups:                         brtypes.DefaultMaxBackups,
+		BaseSnapshotCheckPeriod:            wrappers.Duration{Duration: brtypes.DefaultBaseSnapshotCheckPeriod},
+		DeltaSnapshotReconciliationPeriod:  wrappers.Duration{Duration: brtypes.DefaultDeltaSnapshotReconciliationPeriod},
+		DeltaEventsCollectionTimeout:       wrappers.Duration{Duration: brtypes.DefaultDeltaEventsCollectionTimeout},
+		MaxConsecutiveFullSnapshotFailures: brtypes.DefaultMaxConsecutiveFullSnapshotFailures,
+		MaxParallelDeltaSnapshotUploads:    brtypes.DefaultMaxParallelDeltaSnapshotUploads,
+		EtcdAlarmPolicy:                    brtypes.EtcdAlarmPolicyIgnore,
+		DegradedModeRetryPeriod:            wrappers.Duration{Duration: brtypes.DefaultDegradedModeRetryPeriod},
+		DegradedModeMemoryLimit:            brtypes.DefaultDegradedModeMemoryLimit,
+		FullSnapshotScheduleSkewTolerance:  wrappers.Duration{Duration: brtypes.DefaultFullSnapshotScheduleSkewTolerance},
+		RestoreDrillMaxFetchers:            brtypes.DefaultRestoreDrillMaxFetchers,
+		RestoreDrillTimeout:                wrappers.Duration{Duration: brtypes.DefaultRestoreDrillTimeout},
+		DeltaSnapshotHashAlgorithm:         brtypes.DefaultDeltaSnapshotHashAlgorithm,
+		KVExportPageSize:                   brtypes.DefaultKVExportPageSize,
+		TriggerCoalescingWindow:            wrappers.Duration{Duration: brtypes.DefaultTriggerCoalescingWindow},
 	}
 }
 
@@ -77,28 +122,64 @@ type Snapshotter struct {
 	store                        brtypes.SnapStore
 	config                       *brtypes.SnapshotterConfig
 	compressionConfig            *compressor.CompressionConfig
+	autoCompressionSelector      *compressor.AutoPolicySelector
+	adaptiveCompressionLevel     *compressor.AdaptiveLevel
 	HealthConfig                 *brtypes.HealthConfig
-	schedule                     cron.Schedule
+	schedule                     fullSnapshotSchedule
+	restoreDrillSchedule         cron.Schedule
+	RestoreOptions               *brtypes.RestoreOptions
 	PrevSnapshot                 *brtypes.Snapshot
 	PrevFullSnapshot             *brtypes.Snapshot
 	PrevDeltaSnapshots           brtypes.SnapList
-	fullSnapshotReqCh            chan bool
-	deltaSnapshotReqCh           chan struct{}
+	lastRecordedSnapshot         *brtypes.Snapshot
+	prevDeltaSnapshotsMutex      sync.Mutex
+	fullSnapshotReqCh            chan fullSnapshotRequest
+	deltaSnapshotReqCh           chan context.Context
 	fullSnapshotAckCh            chan result
 	deltaSnapshotAckCh           chan result
 	FullSnapshotLeaseUpdateTimer *time.Timer
 	fullSnapshotTimer            *time.Timer
+	fullSnapshotBackoff          *backoff.ExponentialBackoff
+	fullSnapshotFailures         uint
+	validDeltaSnapshots          map[string]struct{}
+	deltaUploads                 *deltaSnapshotUploads
+	triggerCoalescer             *triggerCoalescer
+	degraded                     bool
+	fullSnapshotDeferred         bool
+	degradedRetryTimer           *time.Timer
 	deltaSnapshotTimer           *time.Timer
+	baseSnapshotCheckTimer       *time.Timer
+	deltaReconciliationTimer     *time.Timer
 	events                       []byte
+	pendingEvents                int
+	compressedEvents             *compressedEventsBuffer
+	eventsOpsHash                hash.Hash
+	prevDeltaOpsSHA256           []byte
+	eventsMutex                  sync.Mutex
 	watchCh                      clientv3.WatchChan
 	etcdWatchClient              *clientv3.Watcher
 	cancelWatch                  context.CancelFunc
 	SsrStateMutex                *sync.Mutex
 	SsrState                     brtypes.SnapshotterState
+	ssrStateTransitionTime       time.Time
 	lastEventRevision            int64
 	K8sClientset                 client.Client
+	EventRecorder                events.Recorder
 	snapstoreConfig              *brtypes.SnapstoreConfig
 	lastSecretModifiedTime       time.Time
+	NewClientFactory             brtypes.NewClientFactoryFunc
+	CredentialsModTimeFunc       etcdutil.FileModTimeFunc
+	etcdClientFactory            etcdClient.Factory
+	lastCredentialsModifiedTime  time.Time
+	latestRestorableSnapshotTime atomic.Int64
+	chainManifest                *brtypes.ChainManifest
+	chainManifestMutex           sync.Mutex
+	compressionDictionary        []byte
+	compressionDictionaryID      atomic.Int64
+	deltaSnapshotDictionaryIDs   map[string]int64
+	dictionarySamples            [][]byte
+	dictionarySamplesSize        int
+	Clock                        clock.WithTicker
 }
 
 // NewSnapshotter returns the snapshotter object.
@@ -108,6 +189,20 @@ func NewSnapshotter(logger *logrus.Entry, config *brtypes.SnapshotterConfig, sto
 		// Ideally this should be validated before.
 		return nil, fmt.Errorf("invalid full snapshot schedule provided %s : %v", config.FullSnapshotSchedule, err)
 	}
+	additionalSchedules, err := config.ParseAdditionalFullSnapshotSchedules()
+	if err != nil {
+		// Ideally this should be validated before.
+		return nil, err
+	}
+	schedule := append(fullSnapshotSchedule{{Name: brtypes.PrimaryFullSnapshotScheduleName, Schedule: sdl}}, additionalSchedules...)
+
+	var restoreDrillSchedule cron.Schedule
+	if config.RestoreDrillSchedule != "" {
+		if restoreDrillSchedule, err = cron.ParseStandard(config.RestoreDrillSchedule); err != nil {
+			// Ideally this should be validated before.
+			return nil, fmt.Errorf("invalid restore drill schedule provided %s : %v", config.RestoreDrillSchedule, err)
+		}
+	}
 
 	var prevSnapshot *brtypes.Snapshot
 	fullSnap, deltaSnapList, err := miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(store)
@@ -129,6 +224,23 @@ func NewSnapshotter(logger *logrus.Entry, config *brtypes.SnapshotterConfig, sto
 
 	metrics.LatestSnapshotRevision.With(prometheus.Labels{metrics.LabelKind: prevSnapshot.Kind}).Set(float64(prevSnapshot.LastRevision))
 
+	if fullSnap != nil && fullSnap.SizeBytes <= 0 && etcdConnectionConfig.SnapshotTimeoutPerGB.Duration > 0 {
+		// The uncompressed size of a listed full snapshot is unknown, so the full snapshot timeout is scaled by its size
+		// as stored in the snapstore until the next full snapshot is taken, which is lower if it is compressed.
+		if size, err := snapstore.SnapshotSize(store, *fullSnap); err != nil {
+			logger.WithField("actor", "snapshotter").Warnf("Unable to get the size of the previous full snapshot %s to scale the full snapshot timeout: %v", fullSnap.SnapName, err)
+		} else {
+			fullSnap.SizeBytes = size
+		}
+	}
+
+	if config.DeltaSnapshotPeriod.Duration < brtypes.DeltaSnapshotIntervalThreshold {
+		logger.WithField("actor", "snapshotter").Warnf("Found delta snapshot period %s less than %s. Delta snapshotting is disabled, the data can only be restored up to the latest full snapshot.", config.DeltaSnapshotPeriod.Duration, brtypes.DeltaSnapshotIntervalThreshold)
+		metrics.DeltaSnapshottingEnabled.With(prometheus.Labels{}).Set(0)
+	} else {
+		metrics.DeltaSnapshottingEnabled.With(prometheus.Labels{}).Set(1)
+	}
+
 	//Attempt to create clientset only if `enable-snapshot-lease-renewal` flag of healthConfig is set
 	var clientSet client.Client
 	if healthConfig.SnapshotLeaseRenewalEnabled {
@@ -138,33 +250,72 @@ func NewSnapshotter(logger *logrus.Entry, config *brtypes.SnapshotterConfig, sto
 		}
 	}
 
-	return &Snapshotter{
-		logger:               logger.WithField("actor", "snapshotter"),
-		store:                store,
-		config:               config,
-		etcdConnectionConfig: etcdConnectionConfig,
-		compressionConfig:    compressionConfig,
-		HealthConfig:         healthConfig,
-		schedule:             sdl,
-		PrevSnapshot:         prevSnapshot,
-		PrevFullSnapshot:     fullSnap,
-		PrevDeltaSnapshots:   deltaSnapList,
-		SsrState:             brtypes.SnapshotterInactive,
-		SsrStateMutex:        &sync.Mutex{},
-		fullSnapshotReqCh:    make(chan bool),
-		deltaSnapshotReqCh:   make(chan struct{}),
-		fullSnapshotAckCh:    make(chan result),
-		deltaSnapshotAckCh:   make(chan result),
-		cancelWatch:          func() {},
-		K8sClientset:         clientSet,
-		snapstoreConfig:      storeConfig,
-	}, nil
+	var autoCompressionSelector *compressor.AutoPolicySelector
+	if compressionConfig.Enabled && compressionConfig.CompressionPolicy == compressor.AutoCompressionPolicy {
+		autoCompressionSelector = compressor.NewAutoPolicySelector(compressor.DefaultAutoCompressionSampleCount)
+	}
+
+	var adaptiveCompressionLevel *compressor.AdaptiveLevel
+	if compressionConfig.Enabled && compressionConfig.CompressionTimeBudget.Duration > 0 {
+		adaptiveCompressionLevel = compressor.NewAdaptiveLevel(compressionConfig.CompressionTimeBudget.Duration, func(level int) {
+			logger.Infof("Adjusted the compression level to %d to meet the compression time budget of %s per MiB", level, compressionConfig.CompressionTimeBudget.Duration)
+			metrics.SnapshotCompressionLevel.With(prometheus.Labels{}).Set(float64(level))
+		})
+		metrics.SnapshotCompressionLevel.With(prometheus.Labels{}).Set(float64(adaptiveCompressionLevel.Level()))
+	}
+
+	backoffConfig := brtypes.NewExponentialBackOffConfig()
+
+	ssr := &Snapshotter{
+		logger:                   logger.WithField("actor", "snapshotter"),
+		store:                    store,
+		config:                   config,
+		etcdConnectionConfig:     etcdConnectionConfig,
+		compressionConfig:        compressionConfig,
+		autoCompressionSelector:  autoCompressionSelector,
+		adaptiveCompressionLevel: adaptiveCompressionLevel,
+		HealthConfig:             healthConfig,
+		schedule:                 schedule,
+		restoreDrillSchedule:     restoreDrillSchedule,
+		PrevSnapshot:             prevSnapshot,
+		PrevFullSnapshot:         fullSnap,
+		PrevDeltaSnapshots:       deltaSnapList,
+		lastRecordedSnapshot:     prevSnapshot,
+		SsrState:                 brtypes.SnapshotterInactive,
+		ssrStateTransitionTime:   time.Now(),
+		SsrStateMutex:            &sync.Mutex{},
+		fullSnapshotReqCh:        make(chan fullSnapshotRequest),
+		deltaSnapshotReqCh:       make(chan context.Context),
+		fullSnapshotAckCh:        make(chan result),
+		deltaSnapshotAckCh:       make(chan result),
+		cancelWatch:              func() {},
+		K8sClientset:             clientSet,
+		EventRecorder:            events.NewNopRecorder(),
+		snapstoreConfig:          storeConfig,
+		NewClientFactory:         etcdutil.NewFactory,
+		validDeltaSnapshots:      map[string]struct{}{},
+		deltaUploads:             newDeltaSnapshotUploads(config.MaxParallelDeltaSnapshotUploads),
+		triggerCoalescer:         newTriggerCoalescer(config.TriggerCoalescingWindow.Duration),
+		fullSnapshotBackoff:      backoff.NewExponentialBackOffConfig(backoffConfig.AttemptLimit, backoffConfig.Multiplier, backoffConfig.ThresholdTime.Duration),
+		Clock:                    clock.RealClock{},
+	}
+	if fullSnap != nil {
+		ssr.latestRestorableSnapshotTime.Store(prevSnapshot.CreatedOn.UnixNano())
+	}
+	ssr.compressionDictionaryID.Store(noCompressionDictionary)
+	if config.GarbageCollectInvalidSnapshots {
+		ssr.loadValidDeltaSnapshots()
+	}
+	return ssr, nil
 }
 
 // Run process loop for scheduled backup
 // Setting startWithFullSnapshot to false will start the snapshotter without
 // taking the first full snapshot.
 func (ssr *Snapshotter) Run(stopCh <-chan struct{}, startWithFullSnapshot bool) error {
+	// ctx is the context of the snapshots taken by the loop itself, as opposed to the triggered ones.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	FullSnapshotLeaseStopCh := make(chan struct{})
 	defer ssr.stop(FullSnapshotLeaseStopCh)
 	if startWithFullSnapshot {
@@ -175,33 +326,76 @@ func (ssr *Snapshotter) Run(stopCh <-chan struct{}, startWithFullSnapshot bool)
 		// to take the first delta snapshot(s) initially and then set
 		// the full snapshot schedule
 		if ssr.watchCh == nil {
-			ssrStopped, err := ssr.CollectEventsSincePrevSnapshot(stopCh)
+			ssrStopped, err := ssr.CollectEventsSincePrevSnapshot(ctx, stopCh)
 			if ssrStopped {
 				return nil
 			}
-			if err != nil {
+			if stderrors.Is(err, ErrDeltaEventsCollectionTimeout) {
+				// the events since the previous snapshot can't be collected,
+				// hence take a full snapshot right away instead
+				ssr.logger.Warnf("Failed to collect events for first delta snapshot(s), taking a full snapshot instead: %v", err)
+				ssr.fullSnapshotTimer = time.NewTimer(0)
+			} else if err != nil {
 				return fmt.Errorf("failed to collect events for first delta snapshot(s): %v", err)
 			}
 		}
-		if err := ssr.resetFullSnapshotTimer(); err != nil {
-			return fmt.Errorf("failed to reset full snapshot timer: %v", err)
+		if ssr.fullSnapshotTimer == nil {
+			if err := ssr.resetFullSnapshotTimer(); err != nil {
+				return fmt.Errorf("failed to reset full snapshot timer: %v", err)
+			}
 		}
 	}
 	if ssr.HealthConfig.SnapshotLeaseRenewalEnabled {
 		go ssr.RenewFullSnapshotLeasePeriodically(FullSnapshotLeaseStopCh)
 	}
+	restorableRPOStopCh := make(chan struct{})
+	defer close(restorableRPOStopCh)
+	go ssr.UpdateRestorableRPOPeriodically(restorableRPOStopCh)
+	if ssr.config.EtcdDBSizeMetricsPeriod.Duration > 0 {
+		etcdDBSizeMetricsStopCh := make(chan struct{})
+		defer close(etcdDBSizeMetricsStopCh)
+		go ssr.UpdateEtcdDBSizeMetricsPeriodically(etcdDBSizeMetricsStopCh)
+	}
 	ssr.deltaSnapshotTimer = time.NewTimer(brtypes.DefaultDeltaSnapshotInterval)
 	if ssr.config.DeltaSnapshotPeriod.Duration >= brtypes.DeltaSnapshotIntervalThreshold {
 		ssr.deltaSnapshotTimer.Stop()
 		ssr.deltaSnapshotTimer.Reset(ssr.config.DeltaSnapshotPeriod.Duration)
 	}
+	if ssr.config.BaseSnapshotCheckPeriod.Duration > 0 {
+		ssr.baseSnapshotCheckTimer = time.NewTimer(ssr.config.BaseSnapshotCheckPeriod.Duration)
+	}
+	if ssr.config.DeltaSnapshotReconciliationPeriod.Duration > 0 {
+		ssr.deltaReconciliationTimer = time.NewTimer(ssr.config.DeltaSnapshotReconciliationPeriod.Duration)
+	}
 
-	return ssr.snapshotEventHandler(stopCh)
+	return ssr.snapshotEventHandler(ctx, stopCh)
 }
 
 // TriggerFullSnapshot sends the events to take full snapshot. This is to
 // trigger full snapshot externally out of regular schedule.
+// Concurrent triggers within the trigger coalescing window are coalesced into
+// a single snapshot, which is returned to all of them.
 func (ssr *Snapshotter) TriggerFullSnapshot(ctx context.Context, isFinal bool) (*brtypes.Snapshot, error) {
+	key := triggerKeyFull
+	if isFinal {
+		key = triggerKeyFinalFull
+	}
+	return ssr.triggerCoalescer.do(key, func() (*brtypes.Snapshot, error) {
+		return ssr.triggerFullSnapshot(fullSnapshotRequest{ctx: ctx, isFinal: isFinal})
+	})
+}
+
+// TriggerFullSnapshotAtRevision sends the events to take a full snapshot at the given historical revision, out of
+// regular schedule. Concurrent triggers of the same revision within the trigger coalescing window are coalesced into
+// a single snapshot, which is returned to all of them.
+func (ssr *Snapshotter) TriggerFullSnapshotAtRevision(ctx context.Context, revision int64) (*brtypes.Snapshot, error) {
+	return ssr.triggerCoalescer.do(fmt.Sprintf("%s-%d", triggerKeyFull, revision), func() (*brtypes.Snapshot, error) {
+		return ssr.triggerFullSnapshot(fullSnapshotRequest{ctx: ctx, revision: revision})
+	})
+}
+
+// triggerFullSnapshot sends the request to take a full snapshot to the snapshotter loop and waits for its result.
+func (ssr *Snapshotter) triggerFullSnapshot(req fullSnapshotRequest) (*brtypes.Snapshot, error) {
 	ssr.SsrStateMutex.Lock()
 	defer ssr.SsrStateMutex.Unlock()
 
@@ -209,14 +403,23 @@ func (ssr *Snapshotter) TriggerFullSnapshot(ctx context.Context, isFinal bool) (
 		return nil, fmt.Errorf("snapshotter is not active")
 	}
 	ssr.logger.Info("Triggering out of schedule full snapshot...")
-	ssr.fullSnapshotReqCh <- isFinal
+	ssr.fullSnapshotReqCh <- req
 	res := <-ssr.fullSnapshotAckCh
 	return res.Snapshot, res.Err
 }
 
 // TriggerDeltaSnapshot sends the events to take delta snapshot. This is to
 // trigger delta snapshot externally out of regular schedule.
-func (ssr *Snapshotter) TriggerDeltaSnapshot() (*brtypes.Snapshot, error) {
+// Concurrent triggers within the trigger coalescing window are coalesced into
+// a single snapshot, which is returned to all of them.
+func (ssr *Snapshotter) TriggerDeltaSnapshot(ctx context.Context) (*brtypes.Snapshot, error) {
+	return ssr.triggerCoalescer.do(triggerKeyDelta, func() (*brtypes.Snapshot, error) {
+		return ssr.triggerDeltaSnapshot(ctx)
+	})
+}
+
+// triggerDeltaSnapshot sends the request to take a delta snapshot to the snapshotter loop and waits for its result.
+func (ssr *Snapshotter) triggerDeltaSnapshot(ctx context.Context) (*brtypes.Snapshot, error) {
 	ssr.SsrStateMutex.Lock()
 	defer ssr.SsrStateMutex.Unlock()
 
@@ -227,7 +430,7 @@ func (ssr *Snapshotter) TriggerDeltaSnapshot() (*brtypes.Snapshot, error) {
 		return nil, fmt.Errorf("found delta snapshot interval %s less than %v. Delta snapshotting is disabled. ", ssr.config.DeltaSnapshotPeriod.Duration, time.Duration(brtypes.DeltaSnapshotIntervalThreshold))
 	}
 	ssr.logger.Info("Triggering out of schedule delta snapshot...")
-	ssr.deltaSnapshotReqCh <- emptyStruct
+	ssr.deltaSnapshotReqCh <- ctx
 	res := <-ssr.deltaSnapshotAckCh
 	return res.Snapshot, res.Err
 }
@@ -245,9 +448,22 @@ func (ssr *Snapshotter) stop(FullSnapshotLeaseStopCh chan struct{}) {
 		ssr.deltaSnapshotTimer.Stop()
 		ssr.deltaSnapshotTimer = nil
 	}
+	if ssr.baseSnapshotCheckTimer != nil {
+		ssr.baseSnapshotCheckTimer.Stop()
+		ssr.baseSnapshotCheckTimer = nil
+	}
+	if ssr.deltaReconciliationTimer != nil {
+		ssr.deltaReconciliationTimer.Stop()
+		ssr.deltaReconciliationTimer = nil
+	}
+	ssr.exitDegradedMode()
+	ssr.degradedRetryTimer = nil
 	if ssr.HealthConfig.SnapshotLeaseRenewalEnabled {
 		FullSnapshotLeaseStopCh <- emptyStruct
 	}
+	if err := ssr.waitForDeltaSnapshotUploads(); err != nil {
+		ssr.logger.Errorf("Failed to upload delta snapshots: %v", err)
+	}
 	ssr.SetSnapshotterInactive()
 	ssr.closeEtcdClient()
 }
@@ -256,14 +472,27 @@ func (ssr *Snapshotter) stop(FullSnapshotLeaseStopCh chan struct{}) {
 func (ssr *Snapshotter) SetSnapshotterInactive() {
 	ssr.SsrStateMutex.Lock()
 	defer ssr.SsrStateMutex.Unlock()
-	ssr.SsrState = brtypes.SnapshotterInactive
+	ssr.setSnapshotterState(brtypes.SnapshotterInactive)
 }
 
 // SetSnapshotterActive set the snapshotter state to active.
 func (ssr *Snapshotter) SetSnapshotterActive() {
 	ssr.SsrStateMutex.Lock()
 	defer ssr.SsrStateMutex.Unlock()
-	ssr.SsrState = brtypes.SnapshotterActive
+	ssr.setSnapshotterState(brtypes.SnapshotterActive)
+}
+
+// setSnapshotterState sets the snapshotter state, and accounts the time spent in the previous state.
+// The caller must hold the SsrStateMutex.
+func (ssr *Snapshotter) setSnapshotterState(state brtypes.SnapshotterState) {
+	now := time.Now()
+	stateLabel := metrics.ValueSnapshotterStateInactive
+	if ssr.SsrState == brtypes.SnapshotterActive {
+		stateLabel = metrics.ValueSnapshotterStateActive
+	}
+	metrics.SnapshotterStateDurationSeconds.With(prometheus.Labels{metrics.LabelSnapshotterState: stateLabel}).Add(now.Sub(ssr.ssrStateTransitionTime).Seconds())
+	ssr.ssrStateTransitionTime = now
+	ssr.SsrState = state
 }
 
 func (ssr *Snapshotter) closeEtcdClient() {
@@ -285,43 +514,101 @@ func (ssr *Snapshotter) closeEtcdClient() {
 
 // TakeFullSnapshotAndResetTimer takes a full snapshot and resets the full snapshot
 // timer as per the schedule.
-func (ssr *Snapshotter) TakeFullSnapshotAndResetTimer(isFinal bool) (*brtypes.Snapshot, error) {
+func (ssr *Snapshotter) TakeFullSnapshotAndResetTimer(ctx context.Context, isFinal bool) (*brtypes.Snapshot, error) {
+	return ssr.takeFullSnapshotAndResetTimer(fullSnapshotRequest{ctx: ctx, isFinal: isFinal})
+}
+
+// TakeFullSnapshotAtRevisionAndResetTimer takes a full snapshot at the given historical revision and resets the full
+// snapshot timer as per the schedule. The delta snapshots continue from the given revision.
+func (ssr *Snapshotter) TakeFullSnapshotAtRevisionAndResetTimer(ctx context.Context, revision int64) (*brtypes.Snapshot, error) {
+	if err := ssr.checkFullSnapshotRevision(revision); err != nil {
+		return nil, err
+	}
+	return ssr.takeFullSnapshotAndResetTimer(fullSnapshotRequest{ctx: ctx, revision: revision})
+}
+
+// takeFullSnapshotAndResetTimer takes the requested full snapshot and resets the full snapshot timer as per the schedule.
+func (ssr *Snapshotter) takeFullSnapshotAndResetTimer(req fullSnapshotRequest) (*brtypes.Snapshot, error) {
 	ssr.logger.Infof("Taking scheduled full snapshot for time: %s", time.Now().Local())
-	s, err := ssr.takeFullSnapshot(isFinal)
+	s, err := ssr.takeFullSnapshot(req.ctx, req.isFinal, req.revision)
 	if err != nil {
 		// As per design principle, in business critical service if backup is not working,
 		// it's better to fail the process. So, we are quiting here.
 		ssr.logger.Warnf("Taking scheduled full snapshot failed: %v", err)
+		ssr.EventRecorder.Eventf(corev1.EventTypeWarning, events.ReasonFullSnapshotFailed, "Taking full snapshot failed: %v", err)
+		ssr.fullSnapshotFailures++
+		metrics.FullSnapshotConsecutiveFailures.With(prometheus.Labels{}).Set(float64(ssr.fullSnapshotFailures))
 		return nil, err
 	}
+	ssr.fullSnapshotFailures = 0
+	metrics.FullSnapshotConsecutiveFailures.With(prometheus.Labels{}).Set(0)
+	ssr.fullSnapshotBackoff.ResetExponentialBackoff()
 
 	return s, ssr.resetFullSnapshotTimer()
 }
 
+// retryFullSnapshotWithBackoff schedules the retry of a failed full snapshot after an exponential backoff.
+// It returns the given error instead, once the maximum number of consecutive failed full snapshots
+// is reached, or as is if the error didn't originate from a failed full snapshot.
+func (ssr *Snapshotter) retryFullSnapshotWithBackoff(err error) error {
+	if ssr.fullSnapshotFailures == 0 {
+		return err
+	}
+	if ssr.fullSnapshotFailures >= ssr.config.MaxConsecutiveFullSnapshotFailures {
+		return fmt.Errorf("%d consecutive full snapshots failed: %w", ssr.fullSnapshotFailures, err)
+	}
+	backoffTime := ssr.fullSnapshotBackoff.GetNextBackoffTime()
+	ssr.logger.Infof("Retrying the failed full snapshot after %v, %d consecutive full snapshots failed so far", backoffTime, ssr.fullSnapshotFailures)
+	ssr.fullSnapshotTimer.Stop()
+	ssr.fullSnapshotTimer.Reset(backoffTime)
+	return nil
+}
+
 // takeFullSnapshot will store full snapshot of etcd to brtypes.
 // It basically will connect to etcd. Then ask for snapshot. And finally
 // store it to underlying snapstore on the fly.
-func (ssr *Snapshotter) takeFullSnapshot(isFinal bool) (*brtypes.Snapshot, error) {
+// The full snapshot is taken at the given historical revision instead of the latest revision, unless it is 0. As the
+// snapshot API of etcd always snapshots the latest revision, the keys are then exported with ranged gets at the
+// revision instead, i.e. the keys under the key prefix if the snapshots are scoped to one, and the whole keyspace
+// otherwise.
+func (ssr *Snapshotter) takeFullSnapshot(ctx context.Context, isFinal bool, revision int64) (snap *brtypes.Snapshot, err error) {
+	ctx, span := tracing.Start(ctx, tracing.SpanTakeFullSnapshot)
+	defer func() {
+		span.SetAttributes(tracing.SnapshotAttributes(snap)...)
+		tracing.End(span, err)
+	}()
 	defer ssr.cleanupInMemoryEvents()
-	// close previous watch and client.
-	ssr.closeEtcdClient()
+	// the delta snapshots being uploaded are superseded by the full snapshot, but the chain is only
+	// reset once they have been uploaded
+	if err := ssr.waitForDeltaSnapshotUploads(); err != nil {
+		ssr.logger.Warnf("Failed to upload delta snapshots before the full snapshot: %v", err)
+	}
+	// the previous watch and client are only kept until the collected events are flushed before the full snapshot,
+	// and are closed in any case, but without closing the watch applied after the full snapshot.
+	prevWatchClosed := false
+	closePrevWatch := func() {
+		ssr.closeEtcdClient()
+		prevWatchClosed = true
+	}
+	defer func() {
+		if !prevWatchClosed {
+			ssr.closeEtcdClient()
+		}
+	}()
 
 	// Update the snapstore object before taking a full snapshot if the credentials have changed
 	// Refer: https://github.com/gardener/etcd-backup-restore/issues/449
-	hasSecretUpdated, err := ssr.hasSnapStoreSecretUpdated()
+	if err := ssr.reloadSnapStoreIfSecretUpdated(); err != nil {
+		return nil, err
+	}
+
+	clientFactory, _, err := ssr.getEtcdClientFactory()
 	if err != nil {
-		return nil, fmt.Errorf("error checking if the credentials were updated %v", err)
+		return nil, err
 	}
-	if hasSecretUpdated {
-		var err error
-		ssr.store, err = snapstore.GetSnapstore(ssr.snapstoreConfig)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create snapstore from configured storage provider: %v", err)
-		}
-		ssr.logger.Info("Updated the snapstore object with new credentials")
+	if err := ssr.checkEtcdAlarms(clientFactory); err != nil {
+		return nil, err
 	}
-
-	clientFactory := etcdutil.NewFactory(*ssr.etcdConnectionConfig)
 	clientKV, err := clientFactory.NewKV()
 	if err != nil {
 		return nil, &errors.EtcdError{
@@ -330,11 +617,11 @@ func (ssr *Snapshotter) takeFullSnapshot(isFinal bool) (*brtypes.Snapshot, error
 	}
 	defer clientKV.Close()
 
-	ctx, cancel := context.WithTimeout(context.TODO(), ssr.etcdConnectionConfig.ConnectionTimeout.Duration)
+	kvCtx, cancel := context.WithTimeout(context.TODO(), ssr.etcdConnectionConfig.GetKVGetTimeout())
 	// Note: Although Get and snapshot call are not atomic, so revision number in snapshot file
 	// may be ahead of the revision found from GET call. But currently this is the only workaround available
 	// Refer: https://github.com/coreos/etcd/issues/9037
-	resp, err := clientKV.Get(ctx, "", clientv3.WithLastRev()...)
+	resp, err := clientKV.Get(kvCtx, "", clientv3.WithLastRev()...)
 	cancel()
 	if err != nil {
 		return nil, &errors.EtcdError{
@@ -342,41 +629,88 @@ func (ssr *Snapshotter) takeFullSnapshot(isFinal bool) (*brtypes.Snapshot, error
 		}
 	}
 	lastRevision := resp.Header.Revision
+	if revision != 0 {
+		if revision > lastRevision {
+			return nil, fmt.Errorf("full snapshot cannot be taken at revision %d which is ahead of the latest etcd revision %d", revision, lastRevision)
+		}
+		lastRevision = revision
+	}
 
-	if ssr.PrevSnapshot.Kind == brtypes.SnapshotKindFull && ssr.PrevSnapshot.LastRevision == lastRevision && ssr.PrevSnapshot.IsFinal == isFinal {
+	// the full snapshot is not skipped if the previous full snapshot is unknown or was found missing from the snapstore
+	if ssr.PrevFullSnapshot != nil && ssr.PrevSnapshot.Kind == brtypes.SnapshotKindFull && ssr.PrevSnapshot.LastRevision == lastRevision && ssr.PrevSnapshot.IsFinal == isFinal {
 		ssr.logger.Infof("There are no updates since last snapshot, skipping full snapshot.")
+		closePrevWatch()
 	} else {
+		if ssr.config.CanaryKeyPrefix != "" {
+			kvCtx, cancel = context.WithTimeout(context.TODO(), ssr.etcdConnectionConfig.ConnectionTimeout.Duration)
+			canaryRevision, err := etcdutil.PutCanary(kvCtx, clientKV, ssr.config.CanaryKeyPrefix)
+			cancel()
+			if err != nil {
+				return nil, &errors.EtcdError{
+					Message: fmt.Sprintf("failed to write canary key: %v", err),
+				}
+			}
+			// the full snapshot is named after the revision of the canary key, which is verified on restore
+			lastRevision = canaryRevision
+		}
+		if revision == 0 {
+			if err := ssr.flushEventsUntilRevision(ctx, lastRevision); err != nil {
+				ssr.logger.Warnf("Failed to flush the events until revision %d into a delta snapshot before the full snapshot: %v", lastRevision, err)
+			}
+		}
+		closePrevWatch()
+
 		// Note: As FullSnapshot size can be very large, so to avoid context timeout use "SnapshotTimeout" in context.WithTimeout()
-		ctx, cancel = context.WithTimeout(context.TODO(), ssr.etcdConnectionConfig.SnapshotTimeout.Duration)
+		snapshotCtx, cancel := context.WithTimeout(context.TODO(), ssr.GetFullSnapshotTimeout())
 		defer cancel()
+		compressionConfig, err := ssr.getCompressionConfig(nil)
+		if err != nil {
+			return nil, err
+		}
 		// compressionSuffix is useful in backward compatibility(restoring from uncompressed snapshots).
 		// it is also helpful in inferring which compression Policy to be used to decompress the snapshot.
-		compressionSuffix, err := compressor.GetCompressionSuffix(ssr.compressionConfig.Enabled, ssr.compressionConfig.CompressionPolicy)
+		compressionSuffix, err := compressor.GetCompressionSuffix(compressionConfig.Enabled, compressionConfig.CompressionPolicy)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get compressionSuffix: %v", err)
 		}
 
-		clientMaintenance, err := clientFactory.NewMaintenance()
-		if err != nil {
-			return nil, fmt.Errorf("failed to build etcd maintenance client")
-		}
-		defer clientMaintenance.Close()
+		var s *brtypes.Snapshot
+		if ssr.config.SnapshotKeyPrefix != "" || revision != 0 {
+			s, err = etcdutil.TakeAndSaveKeyPrefixSnapshot(snapshotCtx, clientKV, ssr.store, ssr.config.SnapshotKeyPrefix, revision, ssr.config.KVExportPageSize, compressionConfig, compressionSuffix, isFinal, ssr.logger)
+		} else {
+			var clientMaintenance etcdClient.MaintenanceCloser
+			clientMaintenance, err = clientFactory.NewMaintenance()
+			if err != nil {
+				return nil, fmt.Errorf("failed to build etcd maintenance client")
+			}
+			defer clientMaintenance.Close()
 
-		s, err := etcdutil.TakeAndSaveFullSnapshot(ctx, clientMaintenance, ssr.store, lastRevision, ssr.compressionConfig, compressionSuffix, isFinal, ssr.logger)
+			s, err = etcdutil.TakeAndSaveFullSnapshot(snapshotCtx, clientMaintenance, ssr.store, lastRevision, compressionConfig, compressionSuffix, isFinal, ssr.logger)
+		}
 		if err != nil {
 			return nil, err
 		}
 
 		ssr.PrevSnapshot = s
 		ssr.PrevFullSnapshot = s
-		ssr.PrevDeltaSnapshots = nil
+		ssr.prevDeltaOpsSHA256 = nil
+		ssr.recordFullSnapshot(s)
 
 		metrics.LatestSnapshotRevision.With(prometheus.Labels{metrics.LabelKind: ssr.PrevSnapshot.Kind}).Set(float64(ssr.PrevSnapshot.LastRevision))
 		metrics.LatestSnapshotTimestamp.With(prometheus.Labels{metrics.LabelKind: ssr.PrevSnapshot.Kind}).Set(float64(ssr.PrevSnapshot.CreatedOn.Unix()))
 		metrics.SnapstoreLatestDeltasTotal.With(prometheus.Labels{}).Set(0)
 		metrics.SnapstoreLatestDeltasRevisionsTotal.With(prometheus.Labels{}).Set(0)
+		ssr.recordRestorableSnapshot(s)
+		ssr.updateChainManifest()
+		ssr.updateCompressionDictionary()
+		ssr.recordClusterMetadata(clientFactory, s)
 
 		ssr.logger.Infof("Successfully saved full snapshot at: %s", path.Join(s.SnapDir, s.SnapName))
+		ssr.EventRecorder.Eventf(corev1.EventTypeNormal, events.ReasonFullSnapshotSucceeded, "Saved full snapshot %s at revision %d", path.Join(s.SnapDir, s.SnapName), s.LastRevision)
+	}
+	// the saved full snapshot is recorded, but it is not reported as successful if etcd ran out of space meanwhile
+	if err := ssr.checkEtcdAlarms(clientFactory); err != nil {
+		return nil, err
 	}
 	// setting `snapshotRequired` to 0 for both full and delta snapshot
 	// for the following cases:
@@ -390,28 +724,209 @@ func (ssr *Snapshotter) takeFullSnapshot(isFinal bool) (*brtypes.Snapshot, error
 		return ssr.PrevSnapshot, nil
 	}
 
-	ssrEtcdWatchClient, err := clientFactory.NewWatcher()
-	if err != nil {
-		return nil, &errors.EtcdError{
-			Message: fmt.Sprintf("failed to create etcd watch client for snapshotter: %v", err),
-		}
+	if err := ssr.applyWatch(clientFactory); err != nil {
+		return nil, err
 	}
-	watchCtx, cancelWatch := context.WithCancel(context.TODO())
-	ssr.cancelWatch = cancelWatch
-	ssr.etcdWatchClient = &ssrEtcdWatchClient
-	ssr.watchCh = ssrEtcdWatchClient.Watch(watchCtx, "", clientv3.WithPrefix(), clientv3.WithRev(ssr.PrevSnapshot.LastRevision+1))
-	ssr.logger.Infof("Applied watch on etcd from revision: %d", ssr.PrevSnapshot.LastRevision+1)
 
 	return ssr.PrevSnapshot, nil
 }
 
+// flushEventsUntilRevision takes a delta snapshot of the events until the given revision, at which the next full
+// snapshot is taken, so that the delta snapshots of the chain ended by the full snapshot reach its revision. The
+// events are otherwise dropped along with the watch when the full snapshot is taken, and the delta snapshots of the
+// chain end at an earlier revision, which a standby etcd following the delta snapshots can't move past. The watch is
+// drained until it delivered the events until the given revision, or until etcd notifies that it has progressed past
+// the given revision, for at most the etcd connection timeout. The events after the given revision are dropped, as
+// they are watched again from the full snapshot onwards.
+func (ssr *Snapshotter) flushEventsUntilRevision(ctx context.Context, revision int64) error {
+	if ssr.watchCh == nil || ssr.degraded || ssr.PrevSnapshot == nil || ssr.PrevSnapshot.LastRevision >= revision {
+		return nil
+	}
+	timer := time.NewTimer(ssr.etcdConnectionConfig.ConnectionTimeout.Duration)
+	defer timer.Stop()
+	if ssr.etcdWatchClient != nil {
+		progressCtx, cancel := context.WithTimeout(context.TODO(), ssr.etcdConnectionConfig.ConnectionTimeout.Duration)
+		err := (*ssr.etcdWatchClient).RequestProgress(progressCtx)
+		cancel()
+		if err != nil {
+			// the watch of the whole keyspace still delivers an event for each revision until the given revision
+			if ssr.config.SnapshotKeyPrefix != "" {
+				return fmt.Errorf("failed to request the progress of the watch: %v", err)
+			}
+			ssr.logger.Debugf("Failed to request the progress of the watch: %v", err)
+		}
+	}
+	for ssr.lastEventRevision < revision {
+		select {
+		case wr, ok := <-ssr.watchCh:
+			if !ok {
+				return fmt.Errorf("watch channel closed")
+			}
+			if err := wr.Err(); err != nil {
+				return err
+			}
+			evs := wr.Events
+			for len(evs) > 0 && evs[len(evs)-1].Kv.ModRevision > revision {
+				evs = evs[:len(evs)-1]
+			}
+			if err := ssr.appendWatchEvents(evs); err != nil {
+				return err
+			}
+			if len(evs) < len(wr.Events) || (wr.IsProgressNotify() && wr.Header.Revision >= revision) {
+				// there are no further events until the revision, e.g. as they were not watched under the key prefix
+				ssr.lastEventRevision = revision
+			}
+		case <-timer.C:
+			return fmt.Errorf("timed out waiting for the events until revision %d, got the events until revision %d", revision, ssr.lastEventRevision)
+		}
+	}
+	_, err := ssr.takeDeltaSnapshot(ctx, false)
+	return err
+}
+
+// checkFullSnapshotRevision checks that a full snapshot can be taken at the given historical revision, so that an
+// invalid request is rejected before it closes the watch, and without counting as a failed full snapshot. The
+// revision has to be after the previous snapshot, as the full snapshot would otherwise overlap with the snapshots
+// already saved in the snapstore.
+func (ssr *Snapshotter) checkFullSnapshotRevision(revision int64) error {
+	if ssr.PrevSnapshot != nil && revision <= ssr.PrevSnapshot.LastRevision {
+		return fmt.Errorf("full snapshot cannot be taken at revision %d which is not after the revision %d of the previous snapshot", revision, ssr.PrevSnapshot.LastRevision)
+	}
+	return nil
+}
+
+// GetFullSnapshotTimeout returns the timeout for taking a full snapshot, scaled by the size of the previous full snapshot if configured.
+func (ssr *Snapshotter) GetFullSnapshotTimeout() time.Duration {
+	var prevFullSnapshotSizeBytes int64
+	if ssr.PrevFullSnapshot != nil {
+		prevFullSnapshotSizeBytes = ssr.PrevFullSnapshot.SizeBytes
+	}
+	return ssr.etcdConnectionConfig.GetFullSnapshotTimeout(prevFullSnapshotSizeBytes)
+}
+
 func (ssr *Snapshotter) cleanupInMemoryEvents() {
+	ssr.eventsMutex.Lock()
 	ssr.events = []byte{}
+	ssr.eventsOpsHash = nil
+	if ssr.compressedEvents != nil {
+		if err := ssr.compressedEvents.discard(); err != nil {
+			ssr.logger.Warnf("Failed to remove compressed delta events: %v", err)
+		}
+		ssr.compressedEvents = nil
+	}
+	ssr.eventsMutex.Unlock()
 	ssr.lastEventRevision = -1
+	ssr.pendingEvents = 0
+	ssr.setPendingEventsMetrics()
+}
+
+// setPendingEventsMetrics exposes the number and the uncompressed size of the events collected for the next delta snapshot.
+func (ssr *Snapshotter) setPendingEventsMetrics() {
+	metrics.DeltaSnapshotPendingEvents.With(prometheus.Labels{}).Set(float64(ssr.pendingEvents))
+	metrics.DeltaSnapshotPendingBytes.With(prometheus.Labels{}).Set(float64(ssr.eventsLen()))
 }
 
-func (ssr *Snapshotter) takeDeltaSnapshotAndResetTimer() (*brtypes.Snapshot, error) {
-	s, err := ssr.TakeDeltaSnapshot()
+// eventsLen returns the uncompressed size of the events collected for the next delta snapshot.
+func (ssr *Snapshotter) eventsLen() int {
+	if ssr.compressedEvents != nil {
+		return ssr.compressedEvents.size
+	}
+	return len(ssr.events)
+}
+
+// DumpPendingEvents writes the events collected for the next delta snapshot to the given writer as a JSON array, without
+// flushing them. It is meant as a debugging aid, for instance to inspect the events of a stuck snapshotter. The events
+// cannot be dumped while they are compressed incrementally, as they are then only kept compressed on disk.
+func (ssr *Snapshotter) DumpPendingEvents(w io.Writer) error {
+	ssr.eventsMutex.Lock()
+	if ssr.compressedEvents != nil {
+		ssr.eventsMutex.Unlock()
+		return fmt.Errorf("pending events are compressed incrementally and cannot be dumped")
+	}
+	data := make([]byte, len(ssr.events))
+	copy(data, ssr.events)
+	ssr.eventsMutex.Unlock()
+
+	evs := []json.RawMessage{}
+	if len(data) != 0 {
+		// The collected events lack the closing bracket of the array until the delta snapshot is taken. Once it is
+		// taken, the closing bracket and the hash of the events follow, which are skipped after decoding the array.
+		dec := json.NewDecoder(io.MultiReader(bytes.NewReader(data), strings.NewReader("]")))
+		if err := dec.Decode(&evs); err != nil {
+			return fmt.Errorf("failed to decode pending events: %v", err)
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(evs); err != nil {
+		return fmt.Errorf("failed to dump pending events: %v", err)
+	}
+	return nil
+}
+
+// appendEvents appends the given data to the events collected for the next delta snapshot. The data is
+// compressed right away if the delta snapshots are to be compressed incrementally.
+func (ssr *Snapshotter) appendEvents(data []byte) error {
+	ssr.eventsMutex.Lock()
+	defer ssr.eventsMutex.Unlock()
+	// With a minimum compression size, the events are only compressed incrementally once they reach it, as the
+	// delta snapshot is stored uncompressed otherwise.
+	minCompressionSize := ssr.minCompressionSize()
+	if ssr.compressedEvents == nil && (ssr.eventsLen() == 0 || minCompressionSize > 0) && ssr.eventsLen()+len(data) >= minCompressionSize {
+		if compressionPolicy, ok := ssr.getIncrementalCompressionPolicy(); ok {
+			var tempDir string
+			if ssr.snapstoreConfig != nil {
+				tempDir = ssr.snapstoreConfig.TempDir
+			}
+			compressedEvents, err := newCompressedEventsBuffer(tempDir, compressionPolicy, ssr.compressionDictionary, ssr.adaptiveCompressionLevel, ssr.config.DeltaSnapshotHashAlgorithm)
+			if err != nil {
+				return err
+			}
+			if err := compressedEvents.write(ssr.events); err != nil {
+				if discardErr := compressedEvents.discard(); discardErr != nil {
+					ssr.logger.Warnf("Failed to remove compressed delta events: %v", discardErr)
+				}
+				return err
+			}
+			ssr.compressedEvents = compressedEvents
+			ssr.events = []byte{}
+		}
+	}
+	ssr.sampleForCompressionDictionary(data)
+	if ssr.compressedEvents != nil {
+		return ssr.compressedEvents.write(data)
+	}
+	ssr.events = append(ssr.events, data...)
+	return nil
+}
+
+// minCompressionSize returns the minimum size of the events of a delta snapshot for it to be compressed, which is zero
+// if all the delta snapshots are to be compressed.
+func (ssr *Snapshotter) minCompressionSize() int {
+	if ssr.compressionConfig == nil || !ssr.compressionConfig.Enabled || ssr.compressionConfig.MinCompressionSize < 0 {
+		return 0
+	}
+	return ssr.compressionConfig.MinCompressionSize
+}
+
+// getIncrementalCompressionPolicy returns the compression policy with which the events are to be compressed as they
+// arrive, and whether they are to be compressed incrementally at all. With the auto compression policy, the events
+// are only compressed incrementally once a compression policy is locked in, as they are sampled until then.
+func (ssr *Snapshotter) getIncrementalCompressionPolicy() (string, bool) {
+	if !ssr.config.IncrementalDeltaCompression || !ssr.compressionConfig.Enabled {
+		return "", false
+	}
+	if ssr.autoCompressionSelector == nil {
+		return ssr.compressionConfig.CompressionPolicy, true
+	}
+	policy, decided := ssr.autoCompressionSelector.Policy()
+	return policy, decided && policy != ""
+}
+
+// takeDeltaSnapshotAndResetTimer takes a delta snapshot and resets the delta snapshot timer. If async is set,
+// the delta snapshot is uploaded in the background, concurrently to the uploads of the other delta snapshots.
+func (ssr *Snapshotter) takeDeltaSnapshotAndResetTimer(ctx context.Context, async bool) (*brtypes.Snapshot, error) {
+	s, err := ssr.takeDeltaSnapshot(ctx, async)
 	if err != nil {
 		// As per design principle, in business critical service if backup is not working,
 		// it's better to fail the process. So, we are quiting here.
@@ -432,89 +947,393 @@ func (ssr *Snapshotter) takeDeltaSnapshotAndResetTimer() (*brtypes.Snapshot, err
 
 // TakeDeltaSnapshot takes a delta snapshot that contains
 // the etcd events collected up till now
-func (ssr *Snapshotter) TakeDeltaSnapshot() (*brtypes.Snapshot, error) {
-	defer ssr.cleanupInMemoryEvents()
+func (ssr *Snapshotter) TakeDeltaSnapshot(ctx context.Context) (*brtypes.Snapshot, error) {
+	return ssr.takeDeltaSnapshot(ctx, false)
+}
+
+// takeDeltaSnapshot takes a delta snapshot that contains the etcd events collected up till now. If async is set,
+// the delta snapshot is uploaded in the background and recorded as the previous delta snapshot once it and all
+// the delta snapshots before it have been uploaded. Otherwise the uploads in the background are waited for first.
+func (ssr *Snapshotter) takeDeltaSnapshot(ctx context.Context, async bool) (snap *brtypes.Snapshot, err error) {
+	_, span := tracing.Start(ctx, tracing.SpanTakeDeltaSnapshot, tracing.Int64(tracing.AttributeSnapshotSizeBytes, int64(ssr.eventsLen())))
+	defer func() {
+		span.SetAttributes(tracing.SnapshotAttributes(snap)...)
+		tracing.End(span, err)
+		if err != nil {
+			ssr.EventRecorder.Eventf(corev1.EventTypeWarning, events.ReasonDeltaSnapshotFailed, "Taking delta snapshot failed: %v", err)
+		}
+	}()
+	// the events of a skipped duplicate delta snapshot are carried over to the next delta snapshot
+	carryOverEvents := false
+	defer func() {
+		if !carryOverEvents {
+			ssr.cleanupInMemoryEvents()
+		}
+	}()
+	// a failed upload is waited for along with the other uploads, so that the previous snapshot is rolled back
+	if !async || ssr.deltaSnapshotUploadErr() != nil {
+		if err := ssr.waitForDeltaSnapshotUploads(); err != nil {
+			return nil, err
+		}
+	}
 	ssr.logger.Infof("Taking delta snapshot for time: %s", time.Now().Local())
 
-	if len(ssr.events) == 0 {
+	if ssr.eventsLen() == 0 {
 		ssr.logger.Infof("No events received to save snapshot. Skipping delta snapshot.")
 		metrics.SnapshotRequired.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindDelta}).Set(0)
 		return nil, nil
 	}
-	ssr.events = append(ssr.events, byte(']'))
+	opsSHA256 := ssr.eventsOpsSHA256()
+	if ssr.isDuplicateDeltaSnapshot(opsSHA256) {
+		ssr.logger.Infof("Events until revision %d repeat the operations of the previous delta snapshot %s. Skipping delta snapshot and carrying the events over to the next one.", ssr.lastEventRevision, ssr.PrevSnapshot.SnapName)
+		carryOverEvents = true
+		return nil, nil
+	}
+	if err := ssr.appendEvents([]byte{']'}); err != nil {
+		return nil, err
+	}
 
 	// Update the snapstore object before taking a delta snapshot if the credentials have changed
 	// Refer: https://github.com/gardener/etcd-backup-restore/issues/449
-	hasSecretUpdated, err := ssr.hasSnapStoreSecretUpdated()
+	if err := ssr.reloadSnapStoreIfSecretUpdated(); err != nil {
+		return nil, err
+	}
+
+	compressionConfig := &compressor.CompressionConfig{Enabled: true}
+	if ssr.compressedEvents != nil {
+		compressionConfig.CompressionPolicy = ssr.compressedEvents.compressionPolicy
+	} else if ssr.eventsLen() < ssr.minCompressionSize() {
+		// small delta snapshots are stored uncompressed, without sampling them for the auto compression policy
+		ssr.logger.Infof("Delta snapshot events of %d bytes are below the minimum compression size, storing them uncompressed", ssr.eventsLen())
+		compressionConfig.Enabled = false
+	} else if compressionConfig, err = ssr.getCompressionConfig(ssr.events); err != nil {
+		return nil, err
+	}
+	// compressionSuffix is useful in backward compatibility(restoring from uncompressed snapshots).
+	// it is also helpful in inferring which compression Policy to be used to decompress the snapshot.
+	compressionSuffix, err := compressor.GetCompressionSuffix(compressionConfig.Enabled, compressionConfig.CompressionPolicy)
 	if err != nil {
-		return nil, fmt.Errorf("error checking if the credentials were updated %v", err)
+		return nil, fmt.Errorf("failed to get compressionSuffix: %v", err)
 	}
-	if hasSecretUpdated {
-		var err error
-		ssr.store, err = snapstore.GetSnapstore(ssr.snapstoreConfig)
+	snap = snapstore.NewSnapshotForStore(ssr.store, brtypes.SnapshotKindDelta, ssr.PrevSnapshot.LastRevision+1, ssr.lastEventRevision, compressionSuffix, false)
+
+	var rc io.ReadCloser
+	if ssr.compressedEvents != nil {
+		// the events have already been compressed as they arrived
+		r, err := ssr.compressedEvents.finish()
 		if err != nil {
-			return nil, fmt.Errorf("failed to create snapstore from configured storage provider: %v", err)
+			return nil, err
+		}
+		// the temporary file of the compressed events is removed along with the events
+		rc = io.NopCloser(r)
+	} else {
+		// compute hash
+		hash, err := brtypes.NewDeltaSnapshotHash(ssr.config.DeltaSnapshotHashAlgorithm)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := hash.Write(ssr.events); err != nil {
+			return nil, fmt.Errorf("failed to compute hash of events: %v", err)
+		}
+		trailer, err := brtypes.DeltaSnapshotHashTrailer(ssr.config.DeltaSnapshotHashAlgorithm, hash.Sum(nil))
+		if err != nil {
+			return nil, err
+		}
+		ssr.eventsMutex.Lock()
+		ssr.events = append(ssr.events, trailer...)
+		ssr.eventsMutex.Unlock()
+
+		rc = io.NopCloser(bytes.NewReader(ssr.events))
+
+		// if compression is enabled
+		//    then compress the snapshot.
+		if compressionConfig.Enabled {
+			ssr.logger.Info("start the Compression of delta snapshot")
+			rc, err = compressor.CompressSnapshotAdaptively(rc, compressionConfig.CompressionPolicy, ssr.compressionDictionary, compressionConfig.AdaptiveLevel)
+			if err != nil {
+				return nil, fmt.Errorf("unable to compress delta snapshot: %v", err)
+			}
 		}
-		ssr.logger.Info("Updated the snapstore object with new credentials")
 	}
 
-	// compressionSuffix is useful in backward compatibility(restoring from uncompressed snapshots).
-	// it is also helpful in inferring which compression Policy to be used to decompress the snapshot.
-	compressionSuffix, err := compressor.GetCompressionSuffix(ssr.compressionConfig.Enabled, ssr.compressionConfig.CompressionPolicy)
+	if async {
+		// The events are handed over to the upload, hence they must not be removed along with the in-memory events.
+		ssr.eventsMutex.Lock()
+		compressedEvents := ssr.compressedEvents
+		ssr.compressedEvents = nil
+		ssr.eventsMutex.Unlock()
+		cleanup := func() {
+			if compressedEvents == nil {
+				return
+			}
+			if err := compressedEvents.discard(); err != nil {
+				ssr.logger.Warnf("Failed to remove compressed delta events: %v", err)
+			}
+		}
+		// The next delta snapshot starts after this one, even though this one might not have been uploaded yet.
+		// It is rolled back if the upload fails.
+		ssr.PrevSnapshot = snap
+		ssr.prevDeltaOpsSHA256 = opsSHA256
+		ssr.uploadDeltaSnapshotAsync(snap, rc, cleanup)
+	} else {
+		defer rc.Close()
+		if err := ssr.saveDeltaSnapshot(ssr.store, snap, rc); err != nil {
+			return nil, err
+		}
+		ssr.PrevSnapshot = snap
+		ssr.prevDeltaOpsSHA256 = opsSHA256
+		ssr.recordDeltaSnapshot(snap)
+	}
+
+	// re-apply the watch with fresh credentials if the etcd credentials were rotated
+	clientFactory, rebuilt, err := ssr.getEtcdClientFactory()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get compressionSuffix: %v", err)
+		return nil, err
 	}
-	snap := snapstore.NewSnapshot(brtypes.SnapshotKindDelta, ssr.PrevSnapshot.LastRevision+1, ssr.lastEventRevision, compressionSuffix, false)
+	if rebuilt {
+		ssr.closeEtcdClient()
+		if err := ssr.applyWatch(clientFactory); err != nil {
+			return nil, err
+		}
+	}
+	return snap, nil
+}
 
-	// compute hash
-	hash := sha256.New()
-	if _, err := hash.Write(ssr.events); err != nil {
-		return nil, fmt.Errorf("failed to compute hash of events: %v", err)
+// eventsOpsSHA256 returns the SHA256 hash of the operations of the events collected for the next delta snapshot.
+func (ssr *Snapshotter) eventsOpsSHA256() []byte {
+	ssr.eventsMutex.Lock()
+	defer ssr.eventsMutex.Unlock()
+	if ssr.eventsOpsHash == nil {
+		return nil
 	}
-	ssr.events = hash.Sum(ssr.events)
+	return ssr.eventsOpsHash.Sum(nil)
+}
 
-	startTime := time.Now()
-	rc := io.NopCloser(bytes.NewReader(ssr.events))
+// isDuplicateDeltaSnapshot checks whether the operations of the collected events, with the given SHA256 hash, are
+// identical to the ones of the previous delta snapshot, e.g. as retried writes reproduced them. As the revisions and
+// times of the events always differ from the ones of the previous delta snapshot, only the operations are compared.
+// The events are carried over instead of being dropped, since the restoration has to reach every revision, hence
+// they are only carried over while they stay below the delta snapshot memory limit.
+func (ssr *Snapshotter) isDuplicateDeltaSnapshot(opsSHA256 []byte) bool {
+	return ssr.config.DeduplicateDeltaSnapshots && opsSHA256 != nil && bytes.Equal(opsSHA256, ssr.prevDeltaOpsSHA256) &&
+		ssr.PrevSnapshot != nil && ssr.PrevSnapshot.Kind == brtypes.SnapshotKindDelta &&
+		ssr.eventsLen() < int(ssr.config.DeltaSnapshotMemoryLimit)
+}
 
-	// if compression is enabled
-	//    then compress the snapshot.
-	if ssr.compressionConfig.Enabled {
-		ssr.logger.Info("start the Compression of delta snapshot")
-		rc, err = compressor.CompressSnapshot(rc, ssr.compressionConfig.CompressionPolicy)
-		if err != nil {
-			return nil, fmt.Errorf("unable to compress delta snapshot: %v", err)
+// hashEventOps adds the operations of the given events, i.e. their types, keys and values, to the hash of the
+// operations of the events collected for the next delta snapshot.
+func (ssr *Snapshotter) hashEventOps(evs []*clientv3.Event) {
+	ssr.eventsMutex.Lock()
+	defer ssr.eventsMutex.Unlock()
+	if ssr.eventsOpsHash == nil {
+		ssr.eventsOpsHash = sha256.New()
+	}
+	var buf [binary.MaxVarintLen64]byte
+	for _, ev := range evs {
+		ssr.eventsOpsHash.Write([]byte{byte(ev.Type)})
+		for _, field := range [][]byte{ev.Kv.Key, ev.Kv.Value} {
+			n := binary.PutUvarint(buf[:], uint64(len(field)))
+			ssr.eventsOpsHash.Write(buf[:n])
+			ssr.eventsOpsHash.Write(field)
 		}
 	}
-	defer rc.Close()
+}
 
-	if err := ssr.store.Save(*snap, rc); err != nil {
+// saveDeltaSnapshot saves the given delta snapshot to the given snapstore.
+func (ssr *Snapshotter) saveDeltaSnapshot(store brtypes.SnapStore, snap *brtypes.Snapshot, rc io.ReadCloser) error {
+	startTime := time.Now()
+	if err := store.Save(*snap, rc); err != nil {
 		timeTaken := time.Since(startTime).Seconds()
 		metrics.SnapshotDurationSeconds.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindDelta, metrics.LabelSucceeded: metrics.ValueSucceededFalse}).Observe(timeTaken)
 		ssr.logger.Errorf("Error saving delta snapshots. %v", err)
-		return nil, err
+		return err
 	}
 	timeTaken := time.Since(startTime).Seconds()
 	metrics.SnapshotDurationSeconds.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindDelta, metrics.LabelSucceeded: metrics.ValueSucceededTrue}).Observe(timeTaken)
 	logrus.Infof("Total time to save delta snapshot: %f seconds.", timeTaken)
-	ssr.PrevSnapshot = snap
+	return nil
+}
+
+// recordDeltaSnapshot records the given saved delta snapshot as the latest of the previous delta snapshots.
+func (ssr *Snapshotter) recordDeltaSnapshot(snap *brtypes.Snapshot) {
+	ssr.prevDeltaSnapshotsMutex.Lock()
 	ssr.PrevDeltaSnapshots = append(ssr.PrevDeltaSnapshots, snap)
+	ssr.lastRecordedSnapshot = snap
+	ssr.prevDeltaSnapshotsMutex.Unlock()
 
-	metrics.LatestSnapshotRevision.With(prometheus.Labels{metrics.LabelKind: ssr.PrevSnapshot.Kind}).Set(float64(ssr.PrevSnapshot.LastRevision))
-	metrics.LatestSnapshotTimestamp.With(prometheus.Labels{metrics.LabelKind: ssr.PrevSnapshot.Kind}).Set(float64(ssr.PrevSnapshot.CreatedOn.Unix()))
+	metrics.LatestSnapshotRevision.With(prometheus.Labels{metrics.LabelKind: snap.Kind}).Set(float64(snap.LastRevision))
+	metrics.LatestSnapshotTimestamp.With(prometheus.Labels{metrics.LabelKind: snap.Kind}).Set(float64(snap.CreatedOn.Unix()))
 	metrics.SnapshotRequired.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindDelta}).Set(0)
 	metrics.SnapstoreLatestDeltasTotal.With(prometheus.Labels{}).Inc()
 	metrics.SnapstoreLatestDeltasRevisionsTotal.With(prometheus.Labels{}).Add(float64(snap.LastRevision - snap.StartRevision))
+	ssr.recordRestorableSnapshot(snap)
+	ssr.updateChainManifest()
 
 	ssr.logger.Infof("Successfully saved delta snapshot at: %s", path.Join(snap.SnapDir, snap.SnapName))
-	return snap, nil
+}
+
+// recordFullSnapshot records the given saved full snapshot as the latest recorded snapshot, and drops the previous
+// delta snapshots.
+func (ssr *Snapshotter) recordFullSnapshot(snap *brtypes.Snapshot) {
+	ssr.prevDeltaSnapshotsMutex.Lock()
+	defer ssr.prevDeltaSnapshotsMutex.Unlock()
+	ssr.PrevDeltaSnapshots = nil
+	ssr.lastRecordedSnapshot = snap
+}
+
+// getLastRecordedSnapshot returns the latest snapshot which has been uploaded and recorded, if any. Unlike
+// PrevSnapshot, it never refers to a delta snapshot which is still being uploaded in the background.
+func (ssr *Snapshotter) getLastRecordedSnapshot() *brtypes.Snapshot {
+	ssr.prevDeltaSnapshotsMutex.Lock()
+	defer ssr.prevDeltaSnapshotsMutex.Unlock()
+	return ssr.lastRecordedSnapshot
+}
+
+// prevDeltaSnapshots returns a copy of the previous delta snapshots.
+func (ssr *Snapshotter) prevDeltaSnapshots() brtypes.SnapList {
+	ssr.prevDeltaSnapshotsMutex.Lock()
+	defer ssr.prevDeltaSnapshotsMutex.Unlock()
+	return append(brtypes.SnapList(nil), ssr.PrevDeltaSnapshots...)
+}
+
+// numPrevDeltaSnapshots returns the number of the previous delta snapshots.
+func (ssr *Snapshotter) numPrevDeltaSnapshots() int {
+	ssr.prevDeltaSnapshotsMutex.Lock()
+	defer ssr.prevDeltaSnapshotsMutex.Unlock()
+	return len(ssr.PrevDeltaSnapshots)
+}
+
+// setPrevDeltaSnapshots replaces the previous delta snapshots with the given ones.
+func (ssr *Snapshotter) setPrevDeltaSnapshots(snapList brtypes.SnapList) {
+	ssr.prevDeltaSnapshotsMutex.Lock()
+	defer ssr.prevDeltaSnapshotsMutex.Unlock()
+	ssr.PrevDeltaSnapshots = snapList
+}
+
+// getLastKeyPrefixRevision returns the latest modification revision of the keys under the snapshot key prefix, or 0 if
+// there are no keys under the prefix.
+func (ssr *Snapshotter) getLastKeyPrefixRevision(clientKV etcdClient.KVCloser) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.TODO(), ssr.etcdConnectionConfig.GetKVGetTimeout())
+	defer cancel()
+	resp, err := clientKV.Get(ctx, ssr.config.SnapshotKeyPrefix, append(clientv3.WithLastRev(), clientv3.WithPrefix())...)
+	if err != nil {
+		return 0, &errors.EtcdError{
+			Message: fmt.Sprintf("failed to get the latest revision of the keys under %s: %v", ssr.config.SnapshotKeyPrefix, err),
+		}
+	}
+	if len(resp.Kvs) == 0 {
+		return 0, nil
+	}
+	return resp.Kvs[0].ModRevision, nil
+}
+
+// applyWatch applies a watch on etcd for the events after the previous snapshot.
+func (ssr *Snapshotter) applyWatch(clientFactory etcdClient.Factory) error {
+	ssrEtcdWatchClient, err := clientFactory.NewWatcher()
+	if err != nil {
+		return &errors.EtcdError{
+			Message: fmt.Sprintf("failed to create etcd watch client for snapshotter: %v", err),
+		}
+	}
+	// TODO: Use parent context. Passing parent context here directly requires some additional management of error handling.
+	watchCtx, cancelWatch := context.WithCancel(context.TODO())
+	ssr.cancelWatch = cancelWatch
+	ssr.etcdWatchClient = &ssrEtcdWatchClient
+	// an empty key prefix watches the whole keyspace
+	opts := []clientv3.OpOption{clientv3.WithPrefix(), clientv3.WithRev(ssr.PrevSnapshot.LastRevision + 1)}
+	watchSetupTimeout := ssr.etcdConnectionConfig.WatchSetupTimeout.Duration
+	if watchSetupTimeout > 0 {
+		opts = append(opts, clientv3.WithCreatedNotify())
+	}
+	ssr.watchCh = ssrEtcdWatchClient.Watch(watchCtx, ssr.config.SnapshotKeyPrefix, opts...)
+	if watchSetupTimeout > 0 {
+		if err := ssr.waitForWatchCreation(watchSetupTimeout); err != nil {
+			ssr.closeEtcdClient()
+			return err
+		}
+	}
+	ssr.logger.Infof("Applied watch on etcd from revision: %d", ssr.PrevSnapshot.LastRevision+1)
+	return nil
+}
+
+// waitForWatchCreation waits for the notification of the watch being established by etcd, which is the first response
+// of a watch requested with the created notification, for at most the given timeout.
+func (ssr *Snapshotter) waitForWatchCreation(timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case wr, ok := <-ssr.watchCh:
+		if !ok {
+			return &errors.EtcdError{
+				Message: "etcd watch was closed before it was established",
+			}
+		}
+		if err := wr.Err(); err != nil {
+			return &errors.EtcdError{
+				Message: fmt.Sprintf("failed to establish etcd watch: %v", err),
+			}
+		}
+		if !wr.Created {
+			return &errors.EtcdError{
+				Message: "etcd watch delivered events before it was established",
+			}
+		}
+		return nil
+	case <-timer.C:
+		return &errors.EtcdError{
+			Message: fmt.Sprintf("etcd watch was not established within the watch setup timeout of %s", timeout),
+		}
+	}
+}
+
+// getCompressionConfig returns the compression config to compress a snapshot with. If the auto compression policy
+// is configured, the given delta snapshot data is sampled to select the compression policy, until one is locked in.
+// Full snapshots are taken by passing nil data, in which case the currently selected compression policy is used.
+// The snapshots are compressed at the adaptive compression level if a compression time budget is configured.
+func (ssr *Snapshotter) getCompressionConfig(data []byte) (*compressor.CompressionConfig, error) {
+	if ssr.autoCompressionSelector == nil {
+		if ssr.adaptiveCompressionLevel == nil {
+			return ssr.compressionConfig, nil
+		}
+		compressionConfig := *ssr.compressionConfig
+		compressionConfig.AdaptiveLevel = ssr.adaptiveCompressionLevel
+		return &compressionConfig, nil
+	}
+
+	_, alreadyDecided := ssr.autoCompressionSelector.Policy()
+	policy, decided, err := ssr.autoCompressionSelector.Select(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select compression policy: %v", err)
+	}
+	if decided && !alreadyDecided {
+		policyLabel := policy
+		if policy == "" {
+			policyLabel = metrics.ValueCompressionPolicyNone
+			ssr.logger.Info("Auto compression policy locked in: snapshots are not worth compressing and will be stored uncompressed")
+		} else {
+			ssr.logger.Infof("Auto compression policy locked in: snapshots will be compressed using %v Compression Policy", policy)
+		}
+		metrics.AutoCompressionPolicySelected.With(prometheus.Labels{metrics.LabelCompressionPolicy: policyLabel}).Set(1)
+	}
+
+	return &compressor.CompressionConfig{
+		Enabled:           policy != "",
+		CompressionPolicy: policy,
+		AdaptiveLevel:     ssr.adaptiveCompressionLevel,
+	}, nil
 }
 
 // CollectEventsSincePrevSnapshot takes the first delta snapshot on etcd startup.
-func (ssr *Snapshotter) CollectEventsSincePrevSnapshot(stopCh <-chan struct{}) (bool, error) {
+func (ssr *Snapshotter) CollectEventsSincePrevSnapshot(ctx context.Context, stopCh <-chan struct{}) (bool, error) {
 	// close any previous watch and client.
 	ssr.closeEtcdClient()
+	// the events carried over from a skipped duplicate delta snapshot are delivered again by the new watch
+	ssr.cleanupInMemoryEvents()
 
-	clientFactory := etcdutil.NewFactory(*ssr.etcdConnectionConfig)
+	clientFactory, _, err := ssr.getEtcdClientFactory()
+	if err != nil {
+		return false, err
+	}
 	clientKV, err := clientFactory.NewKV()
 	if err != nil {
 		return false, &errors.EtcdError{
@@ -523,7 +1342,7 @@ func (ssr *Snapshotter) CollectEventsSincePrevSnapshot(stopCh <-chan struct{}) (
 	}
 	defer clientKV.Close()
 
-	ctx, cancel := context.WithTimeout(context.TODO(), ssr.etcdConnectionConfig.ConnectionTimeout.Duration)
+	ctx, cancel := context.WithTimeout(context.TODO(), ssr.etcdConnectionConfig.GetKVGetTimeout())
 	resp, err := clientKV.Get(ctx, "", clientv3.WithLastRev()...)
 	cancel()
 	if err != nil {
@@ -532,6 +1351,13 @@ func (ssr *Snapshotter) CollectEventsSincePrevSnapshot(stopCh <-chan struct{}) (
 		}
 	}
 	lastEtcdRevision := resp.Header.Revision
+	lastRevisionToWatch := lastEtcdRevision
+	if ssr.config.SnapshotKeyPrefix != "" {
+		// the watch only delivers the events of the keys under the prefix, so it cannot be expected to reach the latest etcd revision
+		if lastRevisionToWatch, err = ssr.getLastKeyPrefixRevision(clientKV); err != nil {
+			return false, err
+		}
+	}
 
 	metrics.SnapshotRequired.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindFull}).Set(0)
 	metrics.SnapshotRequired.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindDelta}).Set(0)
@@ -542,20 +1368,11 @@ func (ssr *Snapshotter) CollectEventsSincePrevSnapshot(stopCh <-chan struct{}) (
 		metrics.SnapshotRequired.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindFull}).Set(1)
 	}
 
-	ssrEtcdWatchClient, err := clientFactory.NewWatcher()
-	if err != nil {
-		return false, &errors.EtcdError{
-			Message: fmt.Sprintf("failed to create etcd watch client for snapshotter: %v", err),
-		}
+	if err := ssr.applyWatch(clientFactory); err != nil {
+		return false, err
 	}
-	// TODO: Use parent context. Passing parent context here directly requires some additional management of error handling.
-	watchCtx, cancelWatch := context.WithCancel(context.TODO())
-	ssr.cancelWatch = cancelWatch
-	ssr.etcdWatchClient = &ssrEtcdWatchClient
-	ssr.watchCh = ssrEtcdWatchClient.Watch(watchCtx, "", clientv3.WithPrefix(), clientv3.WithRev(ssr.PrevSnapshot.LastRevision+1))
-	ssr.logger.Infof("Applied watch on etcd from revision: %d", ssr.PrevSnapshot.LastRevision+1)
 
-	if ssr.PrevSnapshot.LastRevision == lastEtcdRevision {
+	if ssr.PrevSnapshot.LastRevision >= lastRevisionToWatch {
 		ssr.logger.Infof("No new events since last snapshot. Skipping initial delta snapshot.")
 		return false, nil
 	}
@@ -566,20 +1383,37 @@ func (ssr *Snapshotter) CollectEventsSincePrevSnapshot(stopCh <-chan struct{}) (
 	metrics.SnapshotRequired.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindDelta}).Set(1)
 	metrics.SnapshotRequired.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindFull}).Set(1)
 
+	// a nil channel blocks forever, so the collection doesn't time out if no timeout is configured.
+	var timeoutCh <-chan time.Time
+	if ssr.config.DeltaEventsCollectionTimeout.Duration > 0 {
+		timeoutTimer := time.NewTimer(ssr.config.DeltaEventsCollectionTimeout.Duration)
+		defer timeoutTimer.Stop()
+		timeoutCh = timeoutTimer.C
+	}
+
 	for {
 		select {
 		case wr, ok := <-ssr.watchCh:
 			if !ok {
 				return false, fmt.Errorf("watch channel closed")
 			}
-			if err := ssr.handleDeltaWatchEvents(wr); err != nil {
+			if err := ssr.handleDeltaWatchEvents(ctx, wr); err != nil {
 				return false, err
 			}
+			// watch responses such as progress notifications don't carry any events.
+			if len(wr.Events) == 0 {
+				continue
+			}
 
 			lastWatchRevision := wr.Events[len(wr.Events)-1].Kv.ModRevision
-			if lastWatchRevision >= lastEtcdRevision {
+			if lastWatchRevision >= lastRevisionToWatch {
 				return false, nil
 			}
+		case <-timeoutCh:
+			ssr.logger.Warnf("Watch did not reach the latest revision %d within %s", lastRevisionToWatch, ssr.config.DeltaEventsCollectionTimeout.Duration)
+			metrics.DeltaEventsCollectionTimeoutsTotal.With(prometheus.Labels{}).Inc()
+			ssr.cleanupInMemoryEvents()
+			return false, ErrDeltaEventsCollectionTimeout
 		case <-stopCh:
 			ssr.cleanupInMemoryEvents()
 			return true, nil
@@ -587,85 +1421,184 @@ func (ssr *Snapshotter) CollectEventsSincePrevSnapshot(stopCh <-chan struct{}) (
 	}
 }
 
-func (ssr *Snapshotter) handleDeltaWatchEvents(wr clientv3.WatchResponse) error {
+func (ssr *Snapshotter) handleDeltaWatchEvents(ctx context.Context, wr clientv3.WatchResponse) error {
 	if err := wr.Err(); err != nil {
 		return err
 	}
-	// aggregate events
-	for _, ev := range wr.Events {
-		timedEvent := newEvent(ev)
-		jsonByte, err := json.Marshal(timedEvent)
-		if err != nil {
-			return fmt.Errorf("failed to marshal events to json: %v", err)
+	if ssr.config.SplitDeltaSnapshotsAtMemoryLimit && !ssr.degraded {
+		return ssr.handleSplitDeltaWatchEvents(ctx, wr.Events)
+	}
+	// aggregate events, marshaling all the events of the watch response at once
+	if err := ssr.appendWatchEvents(wr.Events); err != nil {
+		return err
+	}
+	ssr.logger.Debugf("Added events till revision: %d", ssr.lastEventRevision)
+	if ssr.degraded {
+		// the events are only buffered until the snapstore accepts snapshots again
+		return ssr.checkDegradedModeMemoryLimit()
+	}
+	if ssr.eventsLen() >= int(ssr.config.DeltaSnapshotMemoryLimit) {
+		ssr.logger.Infof("Delta events memory crossed the memory limit: %d Bytes", ssr.eventsLen())
+		// The delta snapshots pile up while the events keep crossing the memory limit, hence upload them concurrently if configured.
+		_, err := ssr.takeDeltaSnapshotAndResetTimer(ctx, ssr.config.MaxParallelDeltaSnapshotUploads > 1)
+		return err
+	}
+	return nil
+}
+
+// handleSplitDeltaWatchEvents aggregates the given events of a watch response revision by revision, and takes a delta
+// snapshot as soon as the collected events cross the memory limit, carrying the remaining events of the response over
+// to the next delta snapshot. This keeps the delta snapshots near the memory limit even for large watch responses, at
+// the cost of marshaling the events of each revision separately. The events of a revision are never split, as a delta
+// snapshot always ends with the last event of its last revision.
+func (ssr *Snapshotter) handleSplitDeltaWatchEvents(ctx context.Context, evs []*clientv3.Event) error {
+	for len(evs) > 0 {
+		n := 1
+		for n < len(evs) && evs[n].Kv.ModRevision == evs[0].Kv.ModRevision {
+			n++
 		}
-		if len(ssr.events) == 0 {
-			ssr.events = append(ssr.events, byte('['))
-		} else {
-			ssr.events = append(ssr.events, byte(','))
+		if err := ssr.appendWatchEvents(evs[:n]); err != nil {
+			return err
+		}
+		evs = evs[n:]
+		if ssr.eventsLen() >= int(ssr.config.DeltaSnapshotMemoryLimit) {
+			ssr.logger.Infof("Delta events memory crossed the memory limit: %d Bytes, carrying %d events over to the next delta snapshot", ssr.eventsLen(), len(evs))
+			if _, err := ssr.takeDeltaSnapshotAndResetTimer(ctx, ssr.config.MaxParallelDeltaSnapshotUploads > 1); err != nil {
+				return err
+			}
 		}
-		ssr.events = append(ssr.events, jsonByte...)
-		ssr.lastEventRevision = ev.Kv.ModRevision
-		metrics.SnapshotRequired.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindFull}).Set(1)
-		metrics.SnapshotRequired.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindDelta}).Set(1)
 	}
 	ssr.logger.Debugf("Added events till revision: %d", ssr.lastEventRevision)
-	if len(ssr.events) >= int(ssr.config.DeltaSnapshotMemoryLimit) {
-		ssr.logger.Infof("Delta events memory crossed the memory limit: %d Bytes", len(ssr.events))
-		_, err := ssr.takeDeltaSnapshotAndResetTimer()
+	return nil
+}
+
+// appendWatchEvents appends the given events of a watch response to the events collected for the next delta
+// snapshot, marshaling them at once.
+func (ssr *Snapshotter) appendWatchEvents(evs []*clientv3.Event) error {
+	if len(evs) == 0 {
+		return nil
+	}
+	jsonByte, err := MarshalEvents(evs, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to marshal events to json: %v", err)
+	}
+	// the marshaled array is appended to the collected events without its closing bracket, and its opening
+	// bracket is replaced with a separator unless it starts the collected events.
+	jsonByte = jsonByte[:len(jsonByte)-1]
+	if ssr.eventsLen() != 0 {
+		jsonByte[0] = byte(',')
+	}
+	if err := ssr.appendEvents(jsonByte); err != nil {
 		return err
 	}
+	ssr.hashEventOps(evs)
+	ssr.lastEventRevision = evs[len(evs)-1].Kv.ModRevision
+	ssr.pendingEvents += len(evs)
+	ssr.setPendingEventsMetrics()
+	metrics.SnapshotRequired.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindFull}).Set(1)
+	metrics.SnapshotRequired.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindDelta}).Set(1)
 	return nil
 }
 
-func newEvent(e *clientv3.Event) *event {
-	return &event{
-		EtcdEvent: e,
-		Time:      time.Now(),
+// MarshalEvents marshals the given etcd events, timed at the given time, into the JSON array of events which makes up
+// the contents of a delta snapshot. Marshaling the events of a watch response as a single slice saves the allocations of
+// marshaling them one by one.
+func MarshalEvents(evs []*clientv3.Event, t time.Time) ([]byte, error) {
+	timedEvents := make([]event, len(evs))
+	for i, ev := range evs {
+		timedEvents[i] = event{
+			EtcdEvent: ev,
+			Time:      t,
+		}
 	}
+	return json.Marshal(timedEvents)
 }
 
-func (ssr *Snapshotter) snapshotEventHandler(stopCh <-chan struct{}) error {
-	leaseUpdateCtx, leaseUpdateCancel := context.WithCancel(context.TODO())
-	defer leaseUpdateCancel()
+func (ssr *Snapshotter) snapshotEventHandler(loopCtx context.Context, stopCh <-chan struct{}) error {
 	ssr.logger.Info("Starting the Snapshot EventHandler.")
+	var baseSnapshotCheckCh <-chan time.Time
+	if ssr.baseSnapshotCheckTimer != nil {
+		baseSnapshotCheckCh = ssr.baseSnapshotCheckTimer.C
+	}
+	var deltaReconciliationCh <-chan time.Time
+	if ssr.deltaReconciliationTimer != nil {
+		deltaReconciliationCh = ssr.deltaReconciliationTimer.C
+	}
 	for {
+		// the timer of the retries in the degraded mode is only created once the snapshotter is degraded
+		var degradedRetryCh <-chan time.Time
+		if ssr.degradedRetryTimer != nil {
+			degradedRetryCh = ssr.degradedRetryTimer.C
+		}
 		select {
-		case isFinal := <-ssr.fullSnapshotReqCh:
-			s, err := ssr.TakeFullSnapshotAndResetTimer(isFinal)
+		case req := <-ssr.fullSnapshotReqCh:
+			if ssr.degraded {
+				ssr.fullSnapshotAckCh <- result{Err: ErrSnapshotterDegraded}
+				continue
+			}
+			if req.revision != 0 {
+				if err := ssr.checkFullSnapshotRevision(req.revision); err != nil {
+					ssr.fullSnapshotAckCh <- result{Err: err}
+					continue
+				}
+			}
+			s, err := ssr.takeFullSnapshotAndResetTimer(req)
 			res := result{
 				Snapshot: s,
 				Err:      err,
 			}
 			ssr.fullSnapshotAckCh <- res
 			if err != nil {
-				return err
+				if err := ssr.degradeOnQuotaExceeded(err, true); err != nil {
+					if err := ssr.retryFullSnapshotWithBackoff(err); err != nil {
+						return err
+					}
+				}
+				continue
 			}
 			if ssr.HealthConfig.SnapshotLeaseRenewalEnabled {
 				ssr.FullSnapshotLeaseUpdateTimer.Stop()
 				ssr.FullSnapshotLeaseUpdateTimer.Reset(time.Nanosecond)
 			}
 
-		case <-ssr.deltaSnapshotReqCh:
-			s, err := ssr.takeDeltaSnapshotAndResetTimer()
+		case reqCtx := <-ssr.deltaSnapshotReqCh:
+			if ssr.degraded {
+				ssr.deltaSnapshotAckCh <- result{Err: ErrSnapshotterDegraded}
+				continue
+			}
+			s, err := ssr.takeDeltaSnapshotAndResetTimer(reqCtx, false)
 			res := result{
 				Snapshot: s,
 				Err:      err,
 			}
 			ssr.deltaSnapshotAckCh <- res
 			if err != nil {
-				return err
+				if err := ssr.degradeOnQuotaExceeded(err, false); err != nil {
+					return err
+				}
+				continue
 			}
 			if ssr.HealthConfig.SnapshotLeaseRenewalEnabled {
-				ctx, cancel := context.WithTimeout(leaseUpdateCtx, brtypes.LeaseUpdateTimeoutDuration)
-				if err = heartbeat.DeltaSnapshotCaseLeaseUpdate(ctx, ssr.logger, ssr.K8sClientset, ssr.HealthConfig.DeltaSnapshotLeaseName, ssr.store); err != nil {
+				ctx, cancel := context.WithTimeout(loopCtx, brtypes.LeaseUpdateTimeoutDuration)
+				if err = heartbeat.DeltaSnapshotCaseLeaseUpdate(ctx, ssr.logger, ssr.K8sClientset, ssr.HealthConfig.SnapshotLeaseNamespace, ssr.HealthConfig.DeltaSnapshotLeaseName, ssr.store); err != nil {
 					ssr.logger.Warnf("Snapshot lease update failed : %v", err)
 				}
 				cancel()
 			}
 
 		case <-ssr.fullSnapshotTimer.C:
-			if _, err := ssr.TakeFullSnapshotAndResetTimer(false); err != nil {
-				return err
+			if ssr.degraded {
+				// the full snapshot is taken by the next retry instead
+				ssr.fullSnapshotDeferred = true
+				continue
+			}
+			if _, err := ssr.TakeFullSnapshotAndResetTimer(loopCtx, false); err != nil {
+				if err := ssr.degradeOnQuotaExceeded(err, true); err != nil {
+					if err := ssr.retryFullSnapshotWithBackoff(err); err != nil {
+						return err
+					}
+				}
+				continue
 			}
 			if ssr.HealthConfig.SnapshotLeaseRenewalEnabled {
 				ssr.FullSnapshotLeaseUpdateTimer.Stop()
@@ -673,32 +1606,80 @@ func (ssr *Snapshotter) snapshotEventHandler(stopCh <-chan struct{}) error {
 			}
 
 		case <-ssr.deltaSnapshotTimer.C:
+			if ssr.degraded {
+				ssr.deltaSnapshotTimer.Reset(ssr.config.DeltaSnapshotPeriod.Duration)
+				continue
+			}
 			if ssr.config.DeltaSnapshotPeriod.Duration >= time.Second {
-				if _, err := ssr.takeDeltaSnapshotAndResetTimer(); err != nil {
-					return err
+				if _, err := ssr.takeDeltaSnapshotAndResetTimer(loopCtx, false); err != nil {
+					if err := ssr.degradeOnQuotaExceeded(err, false); err != nil {
+						return err
+					}
+					continue
 				}
 				if ssr.HealthConfig.SnapshotLeaseRenewalEnabled {
-					ctx, cancel := context.WithTimeout(leaseUpdateCtx, brtypes.LeaseUpdateTimeoutDuration)
-					if err := heartbeat.DeltaSnapshotCaseLeaseUpdate(ctx, ssr.logger, ssr.K8sClientset, ssr.HealthConfig.DeltaSnapshotLeaseName, ssr.store); err != nil {
+					ctx, cancel := context.WithTimeout(loopCtx, brtypes.LeaseUpdateTimeoutDuration)
+					if err := heartbeat.DeltaSnapshotCaseLeaseUpdate(ctx, ssr.logger, ssr.K8sClientset, ssr.HealthConfig.SnapshotLeaseNamespace, ssr.HealthConfig.DeltaSnapshotLeaseName, ssr.store); err != nil {
 						ssr.logger.Warnf("Snapshot lease update failed : %v", err)
 					}
 					cancel()
 				}
 			}
 
+		case <-baseSnapshotCheckCh:
+			if ssr.degraded {
+				ssr.baseSnapshotCheckTimer.Reset(ssr.config.BaseSnapshotCheckPeriod.Duration)
+				continue
+			}
+			s, err := ssr.checkBaseSnapshotAndResetTimer(loopCtx)
+			if err != nil {
+				return err
+			}
+			if s != nil && ssr.HealthConfig.SnapshotLeaseRenewalEnabled {
+				ssr.FullSnapshotLeaseUpdateTimer.Stop()
+				ssr.FullSnapshotLeaseUpdateTimer.Reset(time.Nanosecond)
+			}
+
+		case <-deltaReconciliationCh:
+			if err := ssr.ReconcileDeltaSnapshots(); err != nil {
+				ssr.logger.Warnf("Unable to reconcile the previous delta snapshots with the snapstore: %v", err)
+			}
+			ssr.deltaReconciliationTimer.Reset(ssr.config.DeltaSnapshotReconciliationPeriod.Duration)
+
 		case wr, ok := <-ssr.watchCh:
 			if !ok {
 				return fmt.Errorf("watch channel closed")
 			}
-			snapshots := len(ssr.PrevDeltaSnapshots)
-			if err := ssr.handleDeltaWatchEvents(wr); err != nil {
-				return err
+			snapshots := ssr.numPrevDeltaSnapshots()
+			if err := ssr.handleDeltaWatchEvents(loopCtx, wr); err != nil {
+				if err := ssr.degradeOnQuotaExceeded(err, false); err != nil {
+					return err
+				}
+				continue
 			}
 			if ssr.HealthConfig.SnapshotLeaseRenewalEnabled {
 				//Call UpdateDeltaSnapshotLease only if new delta snapshot taken
-				if snapshots < len(ssr.PrevDeltaSnapshots) {
-					ctx, cancel := context.WithTimeout(leaseUpdateCtx, brtypes.LeaseUpdateTimeoutDuration)
-					if err := heartbeat.DeltaSnapshotCaseLeaseUpdate(ctx, ssr.logger, ssr.K8sClientset, ssr.HealthConfig.DeltaSnapshotLeaseName, ssr.store); err != nil {
+				if snapshots < ssr.numPrevDeltaSnapshots() {
+					ctx, cancel := context.WithTimeout(loopCtx, brtypes.LeaseUpdateTimeoutDuration)
+					if err := heartbeat.DeltaSnapshotCaseLeaseUpdate(ctx, ssr.logger, ssr.K8sClientset, ssr.HealthConfig.SnapshotLeaseNamespace, ssr.HealthConfig.DeltaSnapshotLeaseName, ssr.store); err != nil {
+						ssr.logger.Warnf("Snapshot lease update failed : %v", err)
+					}
+					cancel()
+				}
+			}
+
+		case <-degradedRetryCh:
+			s, err := ssr.retrySnapshotInDegradedMode(loopCtx)
+			if err != nil {
+				return err
+			}
+			if s != nil && ssr.HealthConfig.SnapshotLeaseRenewalEnabled {
+				if s.Kind == brtypes.SnapshotKindFull {
+					ssr.FullSnapshotLeaseUpdateTimer.Stop()
+					ssr.FullSnapshotLeaseUpdateTimer.Reset(time.Nanosecond)
+				} else {
+					ctx, cancel := context.WithTimeout(loopCtx, brtypes.LeaseUpdateTimeoutDuration)
+					if err := heartbeat.DeltaSnapshotCaseLeaseUpdate(ctx, ssr.logger, ssr.K8sClientset, ssr.HealthConfig.SnapshotLeaseNamespace, ssr.HealthConfig.DeltaSnapshotLeaseName, ssr.store); err != nil {
 						ssr.logger.Warnf("Snapshot lease update failed : %v", err)
 					}
 					cancel()
@@ -708,14 +1689,104 @@ func (ssr *Snapshotter) snapshotEventHandler(stopCh <-chan struct{}) error {
 		case <-stopCh:
 			ssr.logger.Info("Closing the Snapshot EventHandler.")
 			ssr.cleanupInMemoryEvents()
-			return nil
+			return ssr.waitForDeltaSnapshotUploads()
 		}
 	}
 }
 
+// checkBaseSnapshotAndResetTimer verifies that the previous full snapshot, which the subsequent delta snapshots are
+// based on, is still present in the snapstore. If it has been deleted from the snapstore, a full snapshot is taken
+// right away instead of appending further delta snapshots to a chain which cannot be restored.
+// It returns the full snapshot if one was taken.
+func (ssr *Snapshotter) checkBaseSnapshotAndResetTimer(ctx context.Context) (*brtypes.Snapshot, error) {
+	defer ssr.baseSnapshotCheckTimer.Reset(ssr.config.BaseSnapshotCheckPeriod.Duration)
+
+	if ssr.PrevFullSnapshot == nil {
+		return nil, nil
+	}
+	isPresent, err := ssr.isSnapshotPresentInStore(ssr.PrevFullSnapshot)
+	if err != nil {
+		ssr.logger.Warnf("Unable to verify the presence of the previous full snapshot in the snapstore: %v", err)
+		return nil, nil
+	}
+	if isPresent {
+		return nil, nil
+	}
+
+	ssr.logger.Warnf("Previous full snapshot %s is missing from the snapstore. Taking a full snapshot to avoid orphaned delta snapshots.", path.Join(ssr.PrevFullSnapshot.SnapDir, ssr.PrevFullSnapshot.SnapName))
+	metrics.OrphanDeltaSnapshotChainsTotal.With(prometheus.Labels{}).Inc()
+	ssr.PrevFullSnapshot = nil
+	return ssr.TakeFullSnapshotAndResetTimer(ctx, false)
+}
+
+// ReconcileDeltaSnapshots lists the snapstore again and replaces the previous delta snapshots with the delta snapshots
+// of the previous full snapshot which are actually present in the snapstore, logging the delta snapshots which were
+// deleted from or added to the snapstore by another process. The delta snapshots are left as they are if the latest
+// full snapshot in the snapstore is not the previous full snapshot, or while delta snapshots are being uploaded.
+func (ssr *Snapshotter) ReconcileDeltaSnapshots() error {
+	if ssr.PrevFullSnapshot == nil {
+		return nil
+	}
+	uploads := ssr.deltaUploads
+	uploads.mutex.Lock()
+	defer uploads.mutex.Unlock()
+	if len(uploads.pending) > 0 {
+		return nil
+	}
+
+	fullSnap, deltaSnapList, err := miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(ssr.store)
+	if err != nil {
+		return err
+	}
+	if fullSnap == nil || fullSnap.SnapName != ssr.PrevFullSnapshot.SnapName {
+		ssr.logger.Warnf("Latest full snapshot in the snapstore is not the previous full snapshot %s. Skipping the reconciliation of the delta snapshots.", path.Join(ssr.PrevFullSnapshot.SnapDir, ssr.PrevFullSnapshot.SnapName))
+		return nil
+	}
+
+	inStore := make(map[string]struct{}, len(deltaSnapList))
+	for _, snap := range deltaSnapList {
+		inStore[snap.SnapName] = struct{}{}
+	}
+	prevDeltaSnapshots := ssr.prevDeltaSnapshots()
+	known := make(map[string]struct{}, len(prevDeltaSnapshots))
+	discrepancies := 0
+	for _, snap := range prevDeltaSnapshots {
+		known[snap.SnapName] = struct{}{}
+		if _, ok := inStore[snap.SnapName]; !ok {
+			ssr.logger.Warnf("Delta snapshot %s is missing from the snapstore.", path.Join(snap.SnapDir, snap.SnapName))
+			discrepancies++
+		}
+	}
+	for _, snap := range deltaSnapList {
+		if _, ok := known[snap.SnapName]; !ok {
+			ssr.logger.Warnf("Delta snapshot %s in the snapstore is unknown to the snapshotter.", path.Join(snap.SnapDir, snap.SnapName))
+			discrepancies++
+		}
+	}
+	if discrepancies > 0 {
+		ssr.logger.Infof("Reconciled the previous delta snapshots with the %d delta snapshots in the snapstore.", len(deltaSnapList))
+		ssr.setPrevDeltaSnapshots(deltaSnapList)
+
+		var revisions int64
+		for _, snap := range deltaSnapList {
+			revisions += snap.LastRevision - snap.StartRevision
+		}
+		metrics.SnapstoreLatestDeltasTotal.With(prometheus.Labels{}).Set(float64(len(deltaSnapList)))
+		metrics.SnapstoreLatestDeltasRevisionsTotal.With(prometheus.Labels{}).Set(float64(revisions))
+		ssr.updateChainManifest()
+	}
+	return nil
+}
+
+// isSnapshotPresentInStore checks whether the given snapshot is present in the snapstore, without listing the
+// snapstore if the snapstore is able to check the snapshot object on its own.
+func (ssr *Snapshotter) isSnapshotPresentInStore(snap *brtypes.Snapshot) (bool, error) {
+	return snapstore.SnapshotExists(ssr.store, *snap)
+}
+
 func (ssr *Snapshotter) resetFullSnapshotTimer() error {
 	now := time.Now()
-	effective := ssr.schedule.Next(now)
+	effective, scheduleName := ssr.NextScheduledFullSnapshot(now)
 	if effective.IsZero() {
 		ssr.logger.Info("There are no backups scheduled for the future. Stopping now.")
 		return fmt.Errorf("error in full snapshot schedule")
@@ -729,8 +1800,60 @@ func (ssr *Snapshotter) resetFullSnapshotTimer() error {
 		ssr.logger.Infof("Resetting full snapshot to run after %s", duration)
 		ssr.fullSnapshotTimer.Reset(duration)
 	}
-	ssr.logger.Infof("Will take next full snapshot at time: %s", effective)
+	ssr.logger.Infof("Will take next full snapshot at time: %s, as per the %s schedule", effective, scheduleName)
+
+	return nil
+}
+
+// getEtcdClientFactory returns the etcd client factory of the snapshotter. The factory is rebuilt with fresh
+// credentials if any of the etcd credential files was modified since the factory was built, in which case
+// rebuilt is true.
+func (ssr *Snapshotter) getEtcdClientFactory() (factory etcdClient.Factory, rebuilt bool, err error) {
+	credentialsModifiedTime, err := etcdutil.GetCredentialsModifiedTime(ssr.etcdConnectionConfig, ssr.CredentialsModTimeFunc)
+	if err != nil {
+		return nil, false, fmt.Errorf("error checking if the etcd credentials were updated: %v", err)
+	}
+
+	if ssr.etcdClientFactory != nil && !credentialsModifiedTime.After(ssr.lastCredentialsModifiedTime) {
+		return ssr.etcdClientFactory, false, nil
+	}
+
+	rebuilt = ssr.etcdClientFactory != nil
+	if rebuilt {
+		ssr.logger.Info("Etcd credentials were updated, rebuilding the etcd client factory")
+	}
+	ssr.etcdClientFactory = etcdutil.NewClientFactory(ssr.NewClientFactory, *ssr.etcdConnectionConfig)
+	ssr.lastCredentialsModifiedTime = credentialsModifiedTime
+	return ssr.etcdClientFactory, rebuilt, nil
+}
+
+// reloadSnapStoreIfSecretUpdated recreates the snapstore object if the snapstore secret has been updated, and counts
+// the reload in the SnapstoreCredentialReloadTotal metric. A failed reload is returned as a SnapstoreCredentialError,
+// to tell it apart from the other snapshot failures, and is retried on the next call.
+func (ssr *Snapshotter) reloadSnapStoreIfSecretUpdated() error {
+	prevSecretModifiedTime := ssr.lastSecretModifiedTime
+	hasSecretUpdated, err := ssr.hasSnapStoreSecretUpdated()
+	if err != nil {
+		metrics.SnapstoreCredentialReloadTotal.With(prometheus.Labels{metrics.LabelSucceeded: metrics.ValueSucceededFalse}).Inc()
+		return &errors.SnapstoreCredentialError{
+			Message: fmt.Sprintf("error checking if the credentials were updated: %v", err),
+		}
+	}
+	if !hasSecretUpdated {
+		return nil
+	}
 
+	store, err := snapstore.GetSnapstore(ssr.snapstoreConfig)
+	if err != nil {
+		ssr.lastSecretModifiedTime = prevSecretModifiedTime
+		metrics.SnapstoreCredentialReloadTotal.With(prometheus.Labels{metrics.LabelSucceeded: metrics.ValueSucceededFalse}).Inc()
+		return &errors.SnapstoreCredentialError{
+			Message: fmt.Sprintf("failed to create snapstore with the updated credentials: %v", err),
+		}
+	}
+	ssr.store = store
+	metrics.SnapstoreCredentialReloadTotal.With(prometheus.Labels{metrics.LabelSucceeded: metrics.ValueSucceededTrue}).Inc()
+	ssr.logger.Info("Updated the snapstore object with new credentials")
 	return nil
 }
 
@@ -753,8 +1876,14 @@ func (ssr *Snapshotter) hasSnapStoreSecretUpdated() (bool, error) {
 }
 
 // IsFullSnapshotRequiredAtStartup checks whether to take a full snapshot or not during the startup of backup-restore.
+// If the maximum full snapshot age is configured, it is used instead of the given time window to check
+// whether the previous full snapshot is too old.
 func (ssr *Snapshotter) IsFullSnapshotRequiredAtStartup(timeWindow float64) bool {
-	if ssr.PrevFullSnapshot == nil || ssr.PrevFullSnapshot.IsFinal || time.Since(ssr.PrevFullSnapshot.CreatedOn).Hours() > timeWindow {
+	maxFullSnapshotAge := timeWindow
+	if ssr.config.MaxFullSnapshotAge.Duration > 0 {
+		maxFullSnapshotAge = ssr.config.MaxFullSnapshotAge.Duration.Hours()
+	}
+	if ssr.PrevFullSnapshot == nil || ssr.PrevFullSnapshot.IsFinal || time.Since(ssr.PrevFullSnapshot.CreatedOn).Hours() > maxFullSnapshotAge {
 		return true
 	}
 
@@ -765,12 +1894,15 @@ func (ssr *Snapshotter) IsFullSnapshotRequiredAtStartup(timeWindow float64) bool
 }
 
 // WasScheduledFullSnapshotMissed determines whether the preceding full-snapshot was missed or not.
+// The preceding full snapshot is considered to be taken at the scheduled time if its creation time is within the
+// configured clock skew tolerance of the scheduled time, as the clock of the node taking the snapshot may be off.
 func (ssr *Snapshotter) WasScheduledFullSnapshotMissed(timeWindow float64) bool {
 	now := time.Now()
 	nextSnapSchedule := ssr.schedule.Next(now)
 
-	if miscellaneous.GetPrevScheduledSnapTime(nextSnapSchedule, timeWindow) == ssr.PrevFullSnapshot.CreatedOn {
-		ssr.logger.Info("previous full snapshot was taken at scheduled time, skipping the full snapshot at startup")
+	skew := ssr.PrevFullSnapshot.CreatedOn.Sub(miscellaneous.GetPrevScheduledSnapTime(nextSnapSchedule, timeWindow))
+	if skew.Abs() <= ssr.config.FullSnapshotScheduleSkewTolerance.Duration {
+		ssr.logger.Infof("previous full snapshot was taken at scheduled time with a skew of %s, skipping the full snapshot at startup", skew)
 		return false
 	}
 	return true
@@ -785,6 +1917,18 @@ func (ssr *Snapshotter) IsNextFullSnapshotBeyondTimeWindow(timeWindow float64) b
 	return timeLeftToTakeNextSnap.Hours()+time.Since(ssr.PrevFullSnapshot.CreatedOn).Hours() > timeWindow
 }
 
+// GetFullSnapshotsMaxTimeWindow returns the maximum time period in hours for which backup-restore must take atleast one
+// full snapshot across the primary and the additional full snapshot schedules. As the full snapshots are taken at the
+// earliest upcoming time across all the schedules, it is the shortest time window of any of them.
+func (ssr *Snapshotter) GetFullSnapshotsMaxTimeWindow() float64 {
+	timeWindow := ssr.GetFullSnapshotMaxTimeWindow(ssr.config.FullSnapshotSchedule)
+	for _, namedSchedule := range ssr.config.AdditionalFullSnapshotSchedules {
+		_, spec, _ := strings.Cut(namedSchedule, "=")
+		timeWindow = math.Min(timeWindow, ssr.GetFullSnapshotMaxTimeWindow(spec))
+	}
+	return timeWindow
+}
+
 // GetFullSnapshotMaxTimeWindow returns the maximum time period in hours for which backup-restore must take atleast one full snapshot.
 func (ssr *Snapshotter) GetFullSnapshotMaxTimeWindow(fullSnapScheduleSpec string) float64 {
 	// Split on whitespace.
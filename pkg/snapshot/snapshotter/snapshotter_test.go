@@ -5,29 +5,61 @@
 package snapshotter_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"path"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
+	"testing"
 
 	"strings"
 	"time"
 
 	"github.com/gardener/etcd-backup-restore/pkg/compressor"
+	brerrors "github.com/gardener/etcd-backup-restore/pkg/errors"
+	"github.com/gardener/etcd-backup-restore/pkg/etcdutil"
+	etcdClient "github.com/gardener/etcd-backup-restore/pkg/etcdutil/client"
+	"github.com/gardener/etcd-backup-restore/pkg/events"
+	"github.com/gardener/etcd-backup-restore/pkg/metrics"
+	"github.com/gardener/etcd-backup-restore/pkg/miscellaneous"
+	mockfactory "github.com/gardener/etcd-backup-restore/pkg/mock/etcdutil/client"
+	"github.com/gardener/etcd-backup-restore/pkg/snapshot/restorer"
 	. "github.com/gardener/etcd-backup-restore/pkg/snapshot/snapshotter"
 	"github.com/gardener/etcd-backup-restore/pkg/snapstore"
+	"github.com/gardener/etcd-backup-restore/pkg/tracing"
 	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
 	"github.com/gardener/etcd-backup-restore/pkg/wrappers"
 	v1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclock "k8s.io/utils/clock/testing"
 
 	"github.com/gardener/etcd-backup-restore/test/utils"
+	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/etcdserver/api/v3rpc/rpctypes"
+	"go.etcd.io/etcd/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/mvcc/mvccpb"
+	etcdtypes "go.etcd.io/etcd/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
@@ -100,6 +132,2134 @@ var _ = Describe("Snapshotter", func() {
 		})
 	})
 
+	Describe("changing the snapshotter state", func() {
+		It("should track the duration spent in each state", func() {
+			snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_1.bkp")}
+			store, err = snapstore.GetSnapstore(snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			ssr, err := NewSnapshotter(logger, NewSnapshotterConfig(), store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			activeDuration := metrics.SnapshotterStateDurationSeconds.With(prometheus.Labels{metrics.LabelSnapshotterState: metrics.ValueSnapshotterStateActive})
+			inactiveDuration := metrics.SnapshotterStateDurationSeconds.With(prometheus.Labels{metrics.LabelSnapshotterState: metrics.ValueSnapshotterStateInactive})
+			initialActiveDuration := testutil.ToFloat64(activeDuration)
+			initialInactiveDuration := testutil.ToFloat64(inactiveDuration)
+
+			time.Sleep(200 * time.Millisecond)
+			ssr.SetSnapshotterActive()
+			Expect(testutil.ToFloat64(inactiveDuration) - initialInactiveDuration).Should(BeNumerically(">=", 0.2))
+			Expect(testutil.ToFloat64(activeDuration)).Should(Equal(initialActiveDuration))
+
+			time.Sleep(400 * time.Millisecond)
+			ssr.SetSnapshotterInactive()
+			Expect(testutil.ToFloat64(activeDuration) - initialActiveDuration).Should(BeNumerically(">=", 0.4))
+
+			// setting the same state again accounts the time spent in it so far
+			inactiveDurationBeforeUpdate := testutil.ToFloat64(inactiveDuration)
+			time.Sleep(200 * time.Millisecond)
+			ssr.SetSnapshotterInactive()
+			Expect(testutil.ToFloat64(inactiveDuration) - inactiveDurationBeforeUpdate).Should(BeNumerically(">=", 0.2))
+		})
+	})
+
+	Describe("signalling whether delta snapshotting is enabled", func() {
+		var (
+			logs              *bytes.Buffer
+			snapshotterLogger *logrus.Entry
+			snapshotterConfig *brtypes.SnapshotterConfig
+		)
+		BeforeEach(func() {
+			snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_1.bkp")}
+			store, err = snapstore.GetSnapstore(snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			logs = &bytes.Buffer{}
+			testLogger := logrus.New()
+			testLogger.Out = logs
+			snapshotterLogger = testLogger.WithField("suite", "snapshotter")
+			snapshotterConfig = NewSnapshotterConfig()
+		})
+
+		Context("with the delta snapshot period below the threshold", func() {
+			It("should report delta snapshotting as disabled and warn about it", func() {
+				snapshotterConfig.DeltaSnapshotPeriod.Duration = 500 * time.Millisecond
+				_, err := NewSnapshotter(snapshotterLogger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(testutil.ToFloat64(metrics.DeltaSnapshottingEnabled.With(prometheus.Labels{}))).Should(Equal(float64(0)))
+				Expect(logs.String()).Should(ContainSubstring("level=warning"))
+				Expect(logs.String()).Should(ContainSubstring("Delta snapshotting is disabled"))
+			})
+		})
+
+		Context("with the delta snapshot period above the threshold", func() {
+			It("should report delta snapshotting as enabled without warning", func() {
+				snapshotterConfig.DeltaSnapshotPeriod.Duration = 10 * time.Second
+				_, err := NewSnapshotter(snapshotterLogger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(testutil.ToFloat64(metrics.DeltaSnapshottingEnabled.With(prometheus.Labels{}))).Should(Equal(float64(1)))
+				Expect(logs.String()).ShouldNot(ContainSubstring("Delta snapshotting is disabled"))
+			})
+		})
+	})
+
+	Describe("merging the full snapshot schedules", func() {
+		var snapshotterConfig *brtypes.SnapshotterConfig
+
+		BeforeEach(func() {
+			snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_schedules.bkp")}
+			store, err = snapstore.GetSnapstore(snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			DeferCleanup(os.RemoveAll, snapstoreConfig.Container)
+			snapshotterConfig = NewSnapshotterConfig()
+			// hourly on weekdays, and every six hours on weekends
+			snapshotterConfig.FullSnapshotSchedule = "0 * * * 1-5"
+			snapshotterConfig.AdditionalFullSnapshotSchedules = []string{"weekend=0 */6 * * 0,6"}
+		})
+
+		// 2024-01-05 is a Friday
+		at := func(day, hour, minute int) time.Time {
+			return time.Date(2024, time.January, day, hour, minute, 0, 0, time.Local)
+		}
+
+		DescribeTable("should schedule the next full snapshot at the earliest time across the schedules",
+			func(now, expectedTime time.Time, expectedSchedule string) {
+				ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				next, schedule := ssr.NextScheduledFullSnapshot(now)
+				Expect(next).To(Equal(expectedTime))
+				Expect(schedule).To(Equal(expectedSchedule))
+			},
+			Entry("on a weekday", at(5, 10, 30), at(5, 11, 0), brtypes.PrimaryFullSnapshotScheduleName),
+			Entry("on a Friday night", at(5, 23, 30), at(6, 0, 0), "weekend"),
+			Entry("on a weekend", at(6, 6, 30), at(6, 12, 0), "weekend"),
+			Entry("on a Sunday night", at(7, 23, 30), at(8, 0, 0), brtypes.PrimaryFullSnapshotScheduleName),
+		)
+
+		It("should only use the primary schedule without additional schedules", func() {
+			snapshotterConfig.AdditionalFullSnapshotSchedules = nil
+			ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			next, schedule := ssr.NextScheduledFullSnapshot(at(5, 23, 30))
+			Expect(next).To(Equal(at(8, 0, 0)))
+			Expect(schedule).To(Equal(brtypes.PrimaryFullSnapshotScheduleName))
+		})
+
+		It("should reject an invalid additional schedule", func() {
+			snapshotterConfig.AdditionalFullSnapshotSchedules = []string{"weekend=* * *"}
+			_, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid additional full snapshot schedule"))
+		})
+	})
+
+	Describe("computing the full snapshot timeout", func() {
+		const gib = int64(1 << 30)
+
+		BeforeEach(func() {
+			etcdConnectionConfig.SnapshotTimeout.Duration = 10 * time.Minute
+		})
+
+		It("should not scale the snapshot timeout if no timeout per GB is configured", func() {
+			Expect(etcdConnectionConfig.GetFullSnapshotTimeout(0)).Should(Equal(10 * time.Minute))
+			Expect(etcdConnectionConfig.GetFullSnapshotTimeout(8 * gib)).Should(Equal(10 * time.Minute))
+		})
+
+		It("should scale the snapshot timeout with the size of the previous full snapshot", func() {
+			etcdConnectionConfig.SnapshotTimeoutPerGB.Duration = 2 * time.Minute
+			Expect(etcdConnectionConfig.GetFullSnapshotTimeout(0)).Should(Equal(10 * time.Minute))
+			Expect(etcdConnectionConfig.GetFullSnapshotTimeout(gib / 2)).Should(Equal(11 * time.Minute))
+			Expect(etcdConnectionConfig.GetFullSnapshotTimeout(gib)).Should(Equal(12 * time.Minute))
+			Expect(etcdConnectionConfig.GetFullSnapshotTimeout(8 * gib)).Should(Equal(26 * time.Minute))
+		})
+
+		It("should bound the scaled snapshot timeout by the min and max snapshot timeouts", func() {
+			etcdConnectionConfig.SnapshotTimeoutPerGB.Duration = 2 * time.Minute
+			etcdConnectionConfig.MinSnapshotTimeout.Duration = 15 * time.Minute
+			etcdConnectionConfig.MaxSnapshotTimeout.Duration = 20 * time.Minute
+			Expect(etcdConnectionConfig.GetFullSnapshotTimeout(0)).Should(Equal(15 * time.Minute))
+			Expect(etcdConnectionConfig.GetFullSnapshotTimeout(3 * gib)).Should(Equal(16 * time.Minute))
+			Expect(etcdConnectionConfig.GetFullSnapshotTimeout(8 * gib)).Should(Equal(20 * time.Minute))
+		})
+	})
+
+	Describe("compressing the delta snapshots incrementally", func() {
+		It("should store the same events as the delta snapshots compressed at once", func() {
+			clientKV, err := etcdutil.NewFactory(*etcdConnectionConfig).NewKV()
+			Expect(err).ShouldNot(HaveOccurred())
+			defer clientKV.Close()
+			var startRevision int64
+			for i := 0; i < 100; i++ {
+				resp, err := clientKV.Put(testCtx, fmt.Sprintf("incremental-compression-key-%d", i), strings.Repeat(fmt.Sprintf("value-%d", i), 100))
+				Expect(err).ShouldNot(HaveOccurred())
+				if i == 0 {
+					startRevision = resp.Header.Revision
+				}
+			}
+
+			compressionConfig.Enabled = true
+			compressionConfig.CompressionPolicy = compressor.GzipCompressionPolicy
+			var storedEvents [][]deltaSnapshotEvent
+			for _, incremental := range []bool{false, true} {
+				tempDir := GinkgoT().TempDir()
+				snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, fmt.Sprintf("snapshotter_incremental_%t.bkp", incremental)), TempDir: tempDir}
+				store, err = snapstore.GetSnapstore(snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				snapshotterConfig := NewSnapshotterConfig()
+				snapshotterConfig.IncrementalDeltaCompression = incremental
+
+				ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				ssr.PrevSnapshot = &brtypes.Snapshot{Kind: brtypes.SnapshotKindFull, LastRevision: startRevision - 1}
+				stopped, err := ssr.CollectEventsSincePrevSnapshot(testCtx, make(chan struct{}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(stopped).Should(BeFalse())
+				// the events compressed as they arrived cannot be dumped
+				Expect(ssr.DumpPendingEvents(io.Discard) != nil).Should(Equal(incremental))
+
+				snap, err := ssr.TakeDeltaSnapshot(testCtx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(snap).ShouldNot(BeNil())
+				Expect(snap.StartRevision).Should(Equal(startRevision))
+				Expect(snap.CompressionSuffix).Should(Equal(compressor.GzipCompressionExtension))
+				// the temporary file of the compressed events is removed once the delta snapshot is saved
+				Expect(os.ReadDir(tempDir)).Should(BeEmpty())
+
+				snapList, err := store.List()
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(snapList).Should(HaveLen(1))
+				events := readDeltaSnapshotEvents(store, snapList[0])
+				Expect(events).Should(HaveLen(int(snap.LastRevision - snap.StartRevision + 1)))
+				storedEvents = append(storedEvents, events)
+			}
+			Expect(storedEvents[1]).Should(Equal(storedEvents[0]))
+		})
+	})
+
+	Describe("storing the delta snapshots below the minimum compression size uncompressed", func() {
+		DescribeTable("should only compress the large delta snapshots, and restore them along with the small ones",
+			func(incremental bool) {
+				snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_min_compression_size.bkp"), TempDir: GinkgoT().TempDir()}
+				store, err = snapstore.GetSnapstore(snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				DeferCleanup(os.RemoveAll, snapstoreConfig.Container)
+
+				clientKV, err := etcdutil.NewFactory(*etcdConnectionConfig).NewKV()
+				Expect(err).ShouldNot(HaveOccurred())
+				defer clientKV.Close()
+
+				compressionConfig.Enabled = true
+				compressionConfig.CompressionPolicy = compressor.GzipCompressionPolicy
+				compressionConfig.MinCompressionSize = 4096
+				snapshotterConfig := NewSnapshotterConfig()
+				snapshotterConfig.DeltaSnapshotPeriod.Duration = 0
+				snapshotterConfig.IncrementalDeltaCompression = incremental
+				ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				_, err = ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				_, err = clientKV.Put(testCtx, "min-compression-size-small-key", "small")
+				Expect(err).ShouldNot(HaveOccurred())
+				stopped, err := ssr.CollectEventsSincePrevSnapshot(testCtx, make(chan struct{}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(stopped).Should(BeFalse())
+				smallSnap, err := ssr.TakeDeltaSnapshot(testCtx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(smallSnap).ShouldNot(BeNil())
+				Expect(smallSnap.CompressionSuffix).Should(Equal(compressor.UnCompressSnapshotExtension))
+				listedSnapshot := func(snap *brtypes.Snapshot) *brtypes.Snapshot {
+					snapList, err := store.List()
+					Expect(err).ShouldNot(HaveOccurred())
+					for _, s := range snapList {
+						if s.SnapName == snap.SnapName {
+							return s
+						}
+					}
+					Fail(fmt.Sprintf("snapshot %s is not listed", snap.SnapName))
+					return nil
+				}
+				smallSnap = listedSnapshot(smallSnap)
+				// the small delta snapshot is stored as is, i.e. its events followed by their hash
+				rc, err := store.Fetch(*smallSnap)
+				Expect(err).ShouldNot(HaveOccurred())
+				data, err := io.ReadAll(rc)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(rc.Close()).To(Succeed())
+				Expect(len(data)).Should(BeNumerically("<", compressionConfig.MinCompressionSize+sha256.Size))
+				Expect(readDeltaSnapshotEvents(store, smallSnap)).Should(HaveLen(1))
+
+				for i := 0; i < 20; i++ {
+					_, err = clientKV.Put(testCtx, fmt.Sprintf("min-compression-size-large-key-%d", i), strings.Repeat(fmt.Sprintf("value-%d", i), 100))
+					Expect(err).ShouldNot(HaveOccurred())
+				}
+				stopped, err = ssr.CollectEventsSincePrevSnapshot(testCtx, make(chan struct{}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(stopped).Should(BeFalse())
+				largeSnap, err := ssr.TakeDeltaSnapshot(testCtx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(largeSnap).ShouldNot(BeNil())
+				Expect(largeSnap.CompressionSuffix).Should(Equal(compressor.GzipCompressionExtension))
+				Expect(readDeltaSnapshotEvents(store, listedSnapshot(largeSnap))).Should(HaveLen(20))
+
+				restorationConfig := brtypes.NewRestorationConfig()
+				restorationConfig.DataDir = path.Join(outputDir, "min_compression_size.etcd")
+				clusterURLs, err := etcdtypes.NewURLsMap(restorationConfig.InitialCluster)
+				Expect(err).ShouldNot(HaveOccurred())
+				peerURLs, err := etcdtypes.NewURLs(restorationConfig.InitialAdvertisePeerURLs)
+				Expect(err).ShouldNot(HaveOccurred())
+				ssr.RestoreOptions = &brtypes.RestoreOptions{
+					Config:      restorationConfig,
+					ClusterURLs: clusterURLs,
+					PeerURLs:    peerURLs,
+				}
+				report := ssr.RunRestoreDrill(testCtx)
+				Expect(report).ShouldNot(BeNil())
+				Expect(report.Error).Should(BeEmpty())
+				Expect(report.Passed).Should(BeTrue())
+				Expect(report.DeltaSnapshots).Should(Equal(2))
+				Expect(report.RestoredRevision).Should(Equal(largeSnap.LastRevision))
+			},
+			Entry("with the delta snapshots compressed at once", false),
+			Entry("with the delta snapshots compressed incrementally", true),
+		)
+	})
+
+	Describe("compressing the snapshots at the adaptive compression level", func() {
+		It("should lower the compression level while the snapshots exceed the compression time budget", func() {
+			snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_adaptive_compression_level.bkp"), TempDir: GinkgoT().TempDir()}
+			store, err = snapstore.GetSnapstore(snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			DeferCleanup(os.RemoveAll, snapstoreConfig.Container)
+
+			compressionConfig.Enabled = true
+			compressionConfig.CompressionPolicy = compressor.GzipCompressionPolicy
+			// a time budget which can't be met, as if the node was CPU constrained
+			compressionConfig.CompressionTimeBudget.Duration = time.Nanosecond
+			snapshotterConfig := NewSnapshotterConfig()
+			snapshotterConfig.DeltaSnapshotPeriod.Duration = 0
+			ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			compressionLevel := metrics.SnapshotCompressionLevel.With(prometheus.Labels{})
+			Expect(testutil.ToFloat64(compressionLevel)).Should(Equal(float64(compressor.InitialAdaptiveCompressionLevel)))
+
+			clientKV, err := etcdutil.NewFactory(*etcdConnectionConfig).NewKV()
+			Expect(err).ShouldNot(HaveOccurred())
+			defer clientKV.Close()
+			for i := 0; i < compressor.AdaptiveCompressionLevelWindow; i++ {
+				// the full snapshots are only taken if etcd was updated, and only recorded if they are large enough
+				for j := 0; j < 100; j++ {
+					_, err = clientKV.Put(testCtx, fmt.Sprintf("adaptive-compression-level-key-%d-%d", i, j), strings.Repeat(fmt.Sprintf("value-%d", j), 128))
+					Expect(err).ShouldNot(HaveOccurred())
+				}
+				snap, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(snap.CompressionSuffix).Should(Equal(compressor.GzipCompressionExtension))
+			}
+			Expect(testutil.ToFloat64(compressionLevel)).Should(Equal(float64(compressor.InitialAdaptiveCompressionLevel - 1)))
+		})
+	})
+
+	Describe("tracing the snapshots", func() {
+		var tracer *tracing.InMemoryTracer
+
+		BeforeEach(func() {
+			tracer = tracing.NewInMemoryTracer()
+			tracing.SetTracer(tracer)
+		})
+
+		AfterEach(func() {
+			tracing.SetTracer(nil)
+		})
+
+		It("should record the spans of the delta and full snapshots as children of the span of the caller", func() {
+			clientKV, err := etcdutil.NewFactory(*etcdConnectionConfig).NewKV()
+			Expect(err).ShouldNot(HaveOccurred())
+			defer clientKV.Close()
+			resp, err := clientKV.Put(testCtx, "tracing-key", "tracing-value")
+			Expect(err).ShouldNot(HaveOccurred())
+
+			snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_tracing.bkp")}
+			store, err = snapstore.GetSnapstore(snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			ssr, err := NewSnapshotter(logger, NewSnapshotterConfig(), store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			ssr.PrevSnapshot = &brtypes.Snapshot{Kind: brtypes.SnapshotKindFull, LastRevision: resp.Header.Revision - 1}
+			stopped, err := ssr.CollectEventsSincePrevSnapshot(testCtx, make(chan struct{}))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(stopped).Should(BeFalse())
+
+			ctx, callerSpan := tracing.Start(testCtx, "caller")
+			defer callerSpan.End()
+			deltaSnap, err := ssr.TakeDeltaSnapshot(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(deltaSnap).ShouldNot(BeNil())
+			fullSnap, err := ssr.TakeFullSnapshotAndResetTimer(ctx, false)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(fullSnap).ShouldNot(BeNil())
+
+			deltaSpans := tracer.SpansByName(tracing.SpanTakeDeltaSnapshot)
+			Expect(deltaSpans).Should(HaveLen(1))
+			Expect(deltaSpans[0].Ended).Should(BeTrue())
+			Expect(deltaSpans[0].Parent).Should(Equal("caller"))
+			Expect(deltaSpans[0].Err).ShouldNot(HaveOccurred())
+			Expect(deltaSpans[0].Attributes).Should(HaveKeyWithValue(tracing.AttributeSnapshotName, deltaSnap.SnapName))
+			Expect(deltaSpans[0].Attributes).Should(HaveKeyWithValue(tracing.AttributeStartRevision, resp.Header.Revision))
+			Expect(deltaSpans[0].Attributes).Should(HaveKeyWithValue(tracing.AttributeLastRevision, deltaSnap.LastRevision))
+			Expect(deltaSpans[0].Attributes[tracing.AttributeSnapshotSizeBytes]).Should(BeNumerically(">", 0))
+
+			fullSpans := tracer.SpansByName(tracing.SpanTakeFullSnapshot)
+			Expect(fullSpans).Should(HaveLen(1))
+			Expect(fullSpans[0].Ended).Should(BeTrue())
+			Expect(fullSpans[0].Parent).Should(Equal("caller"))
+			Expect(fullSpans[0].Err).ShouldNot(HaveOccurred())
+			Expect(fullSpans[0].Attributes).Should(HaveKeyWithValue(tracing.AttributeSnapshotName, fullSnap.SnapName))
+			Expect(fullSpans[0].Attributes).Should(HaveKeyWithValue(tracing.AttributeSnapshotKind, brtypes.SnapshotKindFull))
+			Expect(fullSpans[0].Attributes).Should(HaveKeyWithValue(tracing.AttributeLastRevision, fullSnap.LastRevision))
+		})
+	})
+
+	Describe("writing the canary keys", func() {
+		const canaryKeyPrefix = "/etcd-backup-restore/canary/"
+
+		It("should write a new canary key before each full snapshot and verify it against the full snapshot", func() {
+			clientKV, err := etcdutil.NewFactory(*etcdConnectionConfig).NewKV()
+			Expect(err).ShouldNot(HaveOccurred())
+			defer clientKV.Close()
+			defer func() {
+				_, err := clientKV.Delete(testCtx, canaryKeyPrefix, clientv3.WithPrefix())
+				Expect(err).ShouldNot(HaveOccurred())
+			}()
+
+			snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_canary.bkp")}
+			store, err = snapstore.GetSnapstore(snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			snapshotterConfig := NewSnapshotterConfig()
+			snapshotterConfig.DeltaSnapshotPeriod.Duration = 0
+			snapshotterConfig.CanaryKeyPrefix = canaryKeyPrefix
+			ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			firstSnap, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(etcdutil.VerifyCanary(testCtx, clientKV, canaryKeyPrefix, firstSnap)).To(Succeed())
+
+			// the full snapshot without any updates since the previous one is skipped, without writing a canary key
+			skippedSnap, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(skippedSnap.SnapName).Should(Equal(firstSnap.SnapName))
+
+			_, err = clientKV.Put(testCtx, "canary-test-key", "canary-test-value")
+			Expect(err).ShouldNot(HaveOccurred())
+			secondSnap, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(secondSnap.LastRevision).Should(BeNumerically(">", firstSnap.LastRevision))
+
+			// the previous canary key is replaced by the new one
+			resp, err := clientKV.Get(testCtx, canaryKeyPrefix, clientv3.WithPrefix())
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resp.Kvs).Should(HaveLen(1))
+			Expect(resp.Kvs[0].ModRevision).Should(Equal(secondSnap.LastRevision))
+			Expect(etcdutil.VerifyCanary(testCtx, clientKV, canaryKeyPrefix, secondSnap)).To(Succeed())
+			Expect(etcdutil.VerifyCanary(testCtx, clientKV, canaryKeyPrefix, firstSnap)).ShouldNot(Succeed())
+
+			_, err = clientKV.Put(testCtx, string(resp.Kvs[0].Key), "tampered")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(etcdutil.VerifyCanary(testCtx, clientKV, canaryKeyPrefix, secondSnap)).ShouldNot(Succeed())
+		})
+	})
+
+	Describe("exporting the keys under the snapshot key prefix in pages", func() {
+		const (
+			keyPrefix = "/paged/"
+			keyCount  = 2500
+			pageSize  = 100
+		)
+
+		It("should export all the keys at the revision of the first page while they are being written", func() {
+			clientKV, err := etcdutil.NewFactory(*etcdConnectionConfig).NewKV()
+			Expect(err).ShouldNot(HaveOccurred())
+			defer clientKV.Close()
+			defer func() {
+				_, err := clientKV.Delete(testCtx, keyPrefix, clientv3.WithPrefix())
+				Expect(err).ShouldNot(HaveOccurred())
+			}()
+			for i := 0; i < keyCount; i++ {
+				_, err = clientKV.Put(testCtx, fmt.Sprintf("%skey-%05d", keyPrefix, i), "exported")
+				Expect(err).ShouldNot(HaveOccurred())
+			}
+
+			snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_paged_export.bkp")}
+			store, err = snapstore.GetSnapstore(snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			snapshotterConfig := NewSnapshotterConfig()
+			snapshotterConfig.DeltaSnapshotPeriod.Duration = 0
+			snapshotterConfig.SnapshotKeyPrefix = keyPrefix
+			snapshotterConfig.KVExportPageSize = pageSize
+			ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			factory := &pagingKVFactory{
+				// each page is followed by writes to the keys which are yet to be exported, and new keys
+				afterPage: func(page int) {
+					_, err := clientKV.Put(testCtx, fmt.Sprintf("%skey-%05d", keyPrefix, keyCount-1-page), "overwritten")
+					Expect(err).ShouldNot(HaveOccurred())
+					_, err = clientKV.Put(testCtx, fmt.Sprintf("%snew-key-%05d", keyPrefix, page), "new")
+					Expect(err).ShouldNot(HaveOccurred())
+				},
+			}
+			ssr.NewClientFactory = func(cfg brtypes.EtcdConnectionConfig, opts ...etcdClient.Option) etcdClient.Factory {
+				factory.Factory = etcdutil.NewFactory(cfg, opts...)
+				return factory
+			}
+
+			snap, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+			Expect(err).ShouldNot(HaveOccurred())
+			// the export is preceded by the ranged gets of the latest revisions
+			Expect(len(factory.pageRevisions)).Should(BeNumerically(">", keyCount/pageSize))
+			pageRevisions := factory.pageRevisions[len(factory.pageRevisions)-keyCount/pageSize-1:]
+			Expect(pageRevisions[0]).Should(BeZero())
+			for _, revision := range pageRevisions[1:] {
+				Expect(revision).Should(Equal(snap.LastRevision))
+			}
+
+			// the full snapshot holds the keys exactly as they were at its revision, before the writes during the export
+			resp, err := clientKV.Get(testCtx, keyPrefix, clientv3.WithPrefix(), clientv3.WithRev(snap.LastRevision))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(len(resp.Kvs)).Should(BeNumerically(">=", keyCount))
+			Expect(resp.Header.Revision).Should(BeNumerically(">", snap.LastRevision))
+			events := readDeltaSnapshotEvents(store, getLatestFullSnapshot(store))
+			Expect(events).Should(HaveLen(len(resp.Kvs)))
+			for i, event := range events {
+				Expect(string(event.EtcdEvent.Kv.Key)).Should(Equal(string(resp.Kvs[i].Key)))
+				Expect(string(event.EtcdEvent.Kv.Value)).Should(Equal(string(resp.Kvs[i].Value)))
+				Expect(event.EtcdEvent.Kv.ModRevision).Should(Equal(resp.Kvs[i].ModRevision))
+			}
+		})
+	})
+
+	Describe("reconciling the previous delta snapshots with the snapstore", func() {
+		var (
+			fullSnap   *brtypes.Snapshot
+			deltaSnaps brtypes.SnapList
+			ssr        *Snapshotter
+		)
+		BeforeEach(func() {
+			snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_reconciliation.bkp")}
+			store, err = snapstore.GetSnapstore(snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			DeferCleanup(os.RemoveAll, snapstoreConfig.Container)
+
+			fullSnap = snapstore.NewSnapshot(brtypes.SnapshotKindFull, 0, 100, "", false)
+			Expect(store.Save(*fullSnap, io.NopCloser(strings.NewReader("dummy-full-snapshot")))).To(Succeed())
+			deltaSnaps = nil
+			for i := int64(0); i < 3; i++ {
+				deltaSnaps = append(deltaSnaps, saveDeltaSnapshot(store, 101+i*10, 110+i*10, "", withHash([]byte("[]"))))
+			}
+
+			ssr, err = NewSnapshotter(logger, NewSnapshotterConfig(), store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ssr.PrevFullSnapshot.SnapName).Should(Equal(fullSnap.SnapName))
+			Expect(ssr.PrevDeltaSnapshots).Should(HaveLen(3))
+		})
+
+		snapNames := func(snapList brtypes.SnapList) []string {
+			var names []string
+			for _, snap := range snapList {
+				names = append(names, snap.SnapName)
+			}
+			return names
+		}
+
+		It("should correct the delta snapshots deleted from and added to the snapstore out-of-band", func() {
+			Expect(store.Delete(*ssr.PrevDeltaSnapshots[1])).To(Succeed())
+			addedSnap := saveDeltaSnapshot(store, 131, 140, "", withHash([]byte("[]")))
+
+			Expect(ssr.ReconcileDeltaSnapshots()).To(Succeed())
+			Expect(snapNames(ssr.PrevDeltaSnapshots)).Should(Equal([]string{deltaSnaps[0].SnapName, deltaSnaps[2].SnapName, addedSnap.SnapName}))
+			Expect(testutil.ToFloat64(metrics.SnapstoreLatestDeltasTotal.With(prometheus.Labels{}))).Should(Equal(float64(3)))
+		})
+
+		It("should keep the delta snapshots if they match the snapstore", func() {
+			prevDeltaSnapshots := ssr.PrevDeltaSnapshots
+			Expect(ssr.ReconcileDeltaSnapshots()).To(Succeed())
+			Expect(ssr.PrevDeltaSnapshots).Should(Equal(prevDeltaSnapshots))
+		})
+
+		It("should keep the delta snapshots if the previous full snapshot is no longer the latest one in the snapstore", func() {
+			Expect(store.Delete(*ssr.PrevDeltaSnapshots[1])).To(Succeed())
+			newerFullSnap := snapstore.NewSnapshot(brtypes.SnapshotKindFull, 0, 200, "", false)
+			Expect(store.Save(*newerFullSnap, io.NopCloser(strings.NewReader("dummy-full-snapshot")))).To(Succeed())
+
+			Expect(ssr.ReconcileDeltaSnapshots()).To(Succeed())
+			Expect(snapNames(ssr.PrevDeltaSnapshots)).Should(Equal(snapNames(deltaSnaps)))
+		})
+	})
+
+	Describe("exporting the restorable RPO", func() {
+		var (
+			ssr       *Snapshotter
+			fakeClock *testclock.FakeClock
+			stopCh    chan struct{}
+		)
+		BeforeEach(func() {
+			snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_rpo.bkp")}
+			store, err = snapstore.GetSnapstore(snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			DeferCleanup(os.RemoveAll, snapstoreConfig.Container)
+
+			fullSnap := snapstore.NewSnapshot(brtypes.SnapshotKindFull, 0, 100, "", false)
+			Expect(store.Save(*fullSnap, io.NopCloser(strings.NewReader("dummy-full-snapshot")))).To(Succeed())
+			saveDeltaSnapshot(store, 101, 110, "", withHash([]byte("[]")))
+
+			ssr, err = NewSnapshotter(logger, NewSnapshotterConfig(), store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ssr.PrevSnapshot.Kind).Should(Equal(brtypes.SnapshotKindDelta))
+
+			fakeClock = testclock.NewFakeClock(ssr.PrevSnapshot.CreatedOn.Add(30 * time.Second))
+			ssr.Clock = fakeClock
+			stopCh = make(chan struct{})
+		})
+
+		restorableRPO := func() float64 {
+			return testutil.ToFloat64(metrics.RestorableRPOSeconds.With(prometheus.Labels{}))
+		}
+
+		It("should reflect the age of the latest snapshot as the clock advances", func() {
+			go ssr.UpdateRestorableRPOPeriodically(stopCh)
+			defer close(stopCh)
+
+			Eventually(restorableRPO).Should(Equal(float64(30)))
+			Eventually(fakeClock.HasWaiters).Should(BeTrue())
+
+			fakeClock.Step(time.Minute)
+			Eventually(restorableRPO).Should(Equal(float64(90)))
+
+			fakeClock.Step(time.Minute)
+			Eventually(restorableRPO).Should(Equal(float64(150)))
+		})
+
+		It("should stop refreshing the RPO once stopped", func() {
+			doneCh := make(chan struct{})
+			go func() {
+				defer close(doneCh)
+				ssr.UpdateRestorableRPOPeriodically(stopCh)
+			}()
+			Eventually(fakeClock.HasWaiters).Should(BeTrue())
+
+			close(stopCh)
+			Eventually(doneCh).Should(BeClosed())
+			fakeClock.Step(time.Minute)
+			Consistently(restorableRPO, 100*time.Millisecond).Should(Equal(float64(30)))
+		})
+	})
+
+	Describe("running the restore drills", func() {
+		var (
+			ssr       *Snapshotter
+			deltaSnap *brtypes.Snapshot
+			dataDir   string
+		)
+		BeforeEach(func() {
+			snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_restore_drill.bkp")}
+			store, err = snapstore.GetSnapstore(snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			DeferCleanup(os.RemoveAll, snapstoreConfig.Container)
+
+			clientKV, err := etcdutil.NewFactory(*etcdConnectionConfig).NewKV()
+			Expect(err).ShouldNot(HaveOccurred())
+			defer clientKV.Close()
+			_, err = clientKV.Put(testCtx, "restore-drill-full-key", "full")
+			Expect(err).ShouldNot(HaveOccurred())
+
+			snapshotterConfig := NewSnapshotterConfig()
+			snapshotterConfig.DeltaSnapshotPeriod.Duration = 0
+			snapshotterConfig.RestoreDrillSchedule = "0 3 * * *"
+			ssr, err = NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			_, err = ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+			Expect(err).ShouldNot(HaveOccurred())
+			_, err = clientKV.Put(testCtx, "restore-drill-delta-key", "delta")
+			Expect(err).ShouldNot(HaveOccurred())
+			stopped, err := ssr.CollectEventsSincePrevSnapshot(testCtx, make(chan struct{}))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(stopped).Should(BeFalse())
+			deltaSnap, err = ssr.TakeDeltaSnapshot(testCtx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(deltaSnap).ShouldNot(BeNil())
+
+			dataDir = path.Join(outputDir, "restore_drill.etcd")
+			restorationConfig := brtypes.NewRestorationConfig()
+			restorationConfig.DataDir = dataDir
+			clusterURLs, err := etcdtypes.NewURLsMap(restorationConfig.InitialCluster)
+			Expect(err).ShouldNot(HaveOccurred())
+			peerURLs, err := etcdtypes.NewURLs(restorationConfig.InitialAdvertisePeerURLs)
+			Expect(err).ShouldNot(HaveOccurred())
+			ssr.RestoreOptions = &brtypes.RestoreOptions{
+				Config:      restorationConfig,
+				ClusterURLs: clusterURLs,
+				PeerURLs:    peerURLs,
+			}
+		})
+
+		restoreDrills := func(succeeded string) float64 {
+			return testutil.ToFloat64(metrics.RestoreDrillsTotal.With(prometheus.Labels{metrics.LabelSucceeded: succeeded}))
+		}
+
+		It("should pass the restore drill of a restorable snapshot chain without touching the data directory", func() {
+			passedDrills, failedDrills := restoreDrills(metrics.ValueSucceededTrue), restoreDrills(metrics.ValueSucceededFalse)
+
+			report := ssr.RunRestoreDrill(testCtx)
+			Expect(report).ShouldNot(BeNil())
+			Expect(report.Error).Should(BeEmpty())
+			Expect(report.Passed).Should(BeTrue())
+			Expect(report.DeltaSnapshots).Should(Equal(1))
+			Expect(report.RestoredRevision).Should(Equal(deltaSnap.LastRevision))
+
+			Expect(restoreDrills(metrics.ValueSucceededTrue)).Should(Equal(passedDrills + 1))
+			Expect(restoreDrills(metrics.ValueSucceededFalse)).Should(Equal(failedDrills))
+			Expect(testutil.ToFloat64(metrics.RestoreDrillPassed.With(prometheus.Labels{}))).Should(Equal(float64(1)))
+			Expect(dataDir).ShouldNot(BeAnExistingFile())
+		})
+
+		It("should fail the restore drill of a broken snapshot chain", func() {
+			Expect(store.Save(*deltaSnap, io.NopCloser(strings.NewReader("corrupted-delta-snapshot")))).To(Succeed())
+			passedDrills, failedDrills := restoreDrills(metrics.ValueSucceededTrue), restoreDrills(metrics.ValueSucceededFalse)
+
+			report := ssr.RunRestoreDrill(testCtx)
+			Expect(report).ShouldNot(BeNil())
+			Expect(report.Passed).Should(BeFalse())
+			Expect(report.Error).ShouldNot(BeEmpty())
+
+			Expect(restoreDrills(metrics.ValueSucceededTrue)).Should(Equal(passedDrills))
+			Expect(restoreDrills(metrics.ValueSucceededFalse)).Should(Equal(failedDrills + 1))
+			Expect(testutil.ToFloat64(metrics.RestoreDrillPassed.With(prometheus.Labels{}))).Should(Equal(float64(0)))
+			Expect(dataDir).ShouldNot(BeAnExistingFile())
+		})
+
+		It("should run the restore drills on their schedule until stopped", func() {
+			fakeClock := testclock.NewFakeClock(time.Date(2024, 5, 6, 2, 0, 0, 0, time.Local))
+			ssr.Clock = fakeClock
+			passedDrills := restoreDrills(metrics.ValueSucceededTrue)
+
+			stopCh := make(chan struct{})
+			doneCh := make(chan struct{})
+			go func() {
+				defer close(doneCh)
+				ssr.RunRestoreDrills(stopCh)
+			}()
+			Eventually(fakeClock.HasWaiters).Should(BeTrue())
+			Consistently(func() float64 { return restoreDrills(metrics.ValueSucceededTrue) }, 100*time.Millisecond).Should(Equal(passedDrills))
+
+			fakeClock.Step(time.Hour)
+			Eventually(func() float64 { return restoreDrills(metrics.ValueSucceededTrue) }, 30*time.Second).Should(Equal(passedDrills + 1))
+
+			Eventually(fakeClock.HasWaiters).Should(BeTrue())
+			close(stopCh)
+			Eventually(doneCh).Should(BeClosed())
+		})
+	})
+
+	Describe("coalescing the concurrent snapshot triggers", func() {
+		const concurrentTriggers = 5
+		var (
+			ssr      *Snapshotter
+			clientKV etcdClient.KVCloser
+			stopCh   chan struct{}
+			ssrErrCh chan error
+		)
+
+		countSnapshots := func(kind string) int {
+			snapList, err := store.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			count := 0
+			for _, snap := range snapList {
+				if snap.Kind == kind {
+					count++
+				}
+			}
+			return count
+		}
+
+		triggerConcurrently := func(trigger func() (*brtypes.Snapshot, error)) []*brtypes.Snapshot {
+			var wg sync.WaitGroup
+			snaps := make([]*brtypes.Snapshot, concurrentTriggers)
+			errs := make([]error, concurrentTriggers)
+			for i := 0; i < concurrentTriggers; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					defer GinkgoRecover()
+					snaps[i], errs[i] = trigger()
+				}(i)
+			}
+			wg.Wait()
+			for _, err := range errs {
+				Expect(err).ShouldNot(HaveOccurred())
+			}
+			return snaps
+		}
+
+		BeforeEach(func() {
+			snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_coalescing.bkp")}
+			store, err = snapstore.GetSnapstore(snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			DeferCleanup(os.RemoveAll, snapstoreConfig.Container)
+
+			clientKV, err = etcdutil.NewFactory(*etcdConnectionConfig).NewKV()
+			Expect(err).ShouldNot(HaveOccurred())
+			DeferCleanup(clientKV.Close)
+
+			ssr, err = NewSnapshotter(logger, NewSnapshotterConfig(), store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			ssr.SetSnapshotterActive()
+			stopCh = make(chan struct{})
+			ssrErrCh = make(chan error, 1)
+			go func() {
+				ssrErrCh <- ssr.Run(stopCh, true)
+			}()
+			Eventually(func() int { return countSnapshots(brtypes.SnapshotKindFull) }, 30*time.Second).Should(Equal(1))
+		})
+
+		AfterEach(func() {
+			close(stopCh)
+			Eventually(ssrErrCh, 30*time.Second).Should(Receive(BeNil()))
+		})
+
+		It("should take a single full snapshot for the concurrent full snapshot triggers", func() {
+			_, err := clientKV.Put(testCtx, "coalescing-full-key", "coalescing-full-value")
+			Expect(err).ShouldNot(HaveOccurred())
+
+			snaps := triggerConcurrently(func() (*brtypes.Snapshot, error) {
+				return ssr.TriggerFullSnapshot(testCtx, false)
+			})
+			Expect(snaps[0]).ShouldNot(BeNil())
+			Expect(snaps[0].Kind).Should(Equal(brtypes.SnapshotKindFull))
+			for _, snap := range snaps {
+				Expect(snap).Should(BeIdenticalTo(snaps[0]))
+			}
+			Expect(countSnapshots(brtypes.SnapshotKindFull)).Should(Equal(2))
+		})
+
+		It("should take a single delta snapshot for the concurrent delta snapshot triggers", func() {
+			resp, err := clientKV.Put(testCtx, "coalescing-delta-key", "coalescing-delta-value")
+			Expect(err).ShouldNot(HaveOccurred())
+
+			snaps := triggerConcurrently(func() (*brtypes.Snapshot, error) {
+				return ssr.TriggerDeltaSnapshot(testCtx)
+			})
+			Expect(snaps[0]).ShouldNot(BeNil())
+			Expect(snaps[0].Kind).Should(Equal(brtypes.SnapshotKindDelta))
+			Expect(snaps[0].LastRevision).Should(BeNumerically(">=", resp.Header.Revision))
+			for _, snap := range snaps {
+				Expect(snap).Should(BeIdenticalTo(snaps[0]))
+			}
+			Eventually(func() int { return countSnapshots(brtypes.SnapshotKindDelta) }).Should(Equal(1))
+			Consistently(func() int { return countSnapshots(brtypes.SnapshotKindDelta) }, time.Second).Should(Equal(1))
+		})
+	})
+
+	Describe("degrading the snapshotter while the snapstore is out of quota", func() {
+		var (
+			ssr               *Snapshotter
+			quotaStore        *quotaExceededStore
+			snapshotterConfig *brtypes.SnapshotterConfig
+			clientKV          etcdClient.KVCloser
+			stopCh            chan struct{}
+			ssrErrCh          chan error
+		)
+
+		degraded := func() float64 {
+			return testutil.ToFloat64(metrics.SnapshotterDegraded.With(prometheus.Labels{}))
+		}
+
+		deltaSnapshots := func() brtypes.SnapList {
+			snapList, err := quotaStore.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			var deltaSnapList brtypes.SnapList
+			for _, snap := range snapList {
+				if snap.Kind == brtypes.SnapshotKindDelta {
+					deltaSnapList = append(deltaSnapList, snap)
+				}
+			}
+			return deltaSnapList
+		}
+
+		startSnapshotter := func() {
+			ssr, err = NewSnapshotter(logger, snapshotterConfig, quotaStore, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			ssr.SetSnapshotterActive()
+			go func() {
+				ssrErrCh <- ssr.Run(stopCh, true)
+			}()
+			Eventually(func() *brtypes.Snapshot { return getLatestFullSnapshot(quotaStore) }, 30*time.Second).ShouldNot(BeNil())
+		}
+
+		BeforeEach(func() {
+			snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_degraded.bkp")}
+			store, err = snapstore.GetSnapstore(snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			DeferCleanup(os.RemoveAll, snapstoreConfig.Container)
+			quotaStore = &quotaExceededStore{SnapStore: store}
+
+			clientKV, err = etcdutil.NewFactory(*etcdConnectionConfig).NewKV()
+			Expect(err).ShouldNot(HaveOccurred())
+			DeferCleanup(clientKV.Close)
+
+			snapshotterConfig = NewSnapshotterConfig()
+			snapshotterConfig.DeltaSnapshotPeriod.Duration = time.Second
+			snapshotterConfig.DegradedModeRetryPeriod.Duration = time.Second
+			stopCh = make(chan struct{})
+			ssrErrCh = make(chan error, 1)
+		})
+
+		AfterEach(func() {
+			close(stopCh)
+		})
+
+		It("should buffer the events instead of failing, and resume the delta snapshots once the snapstore accepts them again", func() {
+			startSnapshotter()
+			fullSnap := getLatestFullSnapshot(quotaStore)
+
+			quotaStore.exceeded.Store(true)
+			var lastRevision int64
+			for i := 0; i < 3; i++ {
+				resp, err := clientKV.Put(testCtx, fmt.Sprintf("degraded-key-%d", i), fmt.Sprintf("degraded-value-%d", i))
+				Expect(err).ShouldNot(HaveOccurred())
+				lastRevision = resp.Header.Revision
+			}
+
+			Eventually(degraded, 10*time.Second).Should(Equal(float64(1)))
+			// the snapshots are retried periodically while the snapshotter keeps running
+			Eventually(quotaStore.rejected.Load, 10*time.Second).Should(BeNumerically(">", 1))
+			Consistently(ssrErrCh, time.Second).ShouldNot(Receive())
+			_, err := ssr.TriggerDeltaSnapshot(testCtx)
+			Expect(err).Should(MatchError(ErrSnapshotterDegraded))
+			Expect(deltaSnapshots()).Should(BeEmpty())
+
+			quotaStore.exceeded.Store(false)
+			Eventually(degraded, 10*time.Second).Should(Equal(float64(0)))
+			Eventually(deltaSnapshots, 10*time.Second).ShouldNot(BeEmpty())
+			Consistently(ssrErrCh, time.Second).ShouldNot(Receive())
+
+			// none of the events collected while the snapshotter was degraded is lost
+			deltaSnapList := deltaSnapshots()
+			Expect(deltaSnapList[0].StartRevision).Should(Equal(fullSnap.LastRevision + 1))
+			for i := 1; i < len(deltaSnapList); i++ {
+				Expect(deltaSnapList[i].StartRevision).Should(Equal(deltaSnapList[i-1].LastRevision + 1))
+			}
+			Expect(deltaSnapList[len(deltaSnapList)-1].LastRevision).Should(BeNumerically(">=", lastRevision))
+			var keys []string
+			for _, snap := range deltaSnapList {
+				for _, ev := range readDeltaSnapshotEvents(quotaStore, snap) {
+					keys = append(keys, string(ev.EtcdEvent.Kv.Key))
+				}
+			}
+			Expect(keys).Should(ContainElements("degraded-key-0", "degraded-key-1", "degraded-key-2"))
+		})
+
+		It("should fail once the buffered events cross the degraded mode memory limit", func() {
+			snapshotterConfig.DegradedModeMemoryLimit = 1024
+			startSnapshotter()
+
+			quotaStore.exceeded.Store(true)
+			_, err := clientKV.Put(testCtx, "degraded-large-key", strings.Repeat("v", 2048))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Eventually(ssrErrCh, 15*time.Second).Should(Receive(MatchError(ContainSubstring("crossed the memory limit of 1024 bytes"))))
+		})
+
+		It("should fail on a quota error if the degraded mode is disabled", func() {
+			snapshotterConfig.DegradedModeRetryPeriod.Duration = 0
+			startSnapshotter()
+
+			quotaStore.exceeded.Store(true)
+			_, err := clientKV.Put(testCtx, "degraded-disabled-key", "degraded-disabled-value")
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Eventually(ssrErrCh, 15*time.Second).Should(Receive(MatchError(ContainSubstring("QuotaExceeded"))))
+			Expect(degraded()).Should(Equal(float64(0)))
+		})
+	})
+
+	Describe("exposing the events pending for the next delta snapshot", func() {
+		var (
+			ssr      *Snapshotter
+			clientKV etcdClient.KVCloser
+			stopCh   chan struct{}
+			ssrErrCh chan error
+		)
+
+		pendingEvents := func() float64 {
+			return testutil.ToFloat64(metrics.DeltaSnapshotPendingEvents.With(prometheus.Labels{}))
+		}
+		pendingBytes := func() float64 {
+			return testutil.ToFloat64(metrics.DeltaSnapshotPendingBytes.With(prometheus.Labels{}))
+		}
+
+		BeforeEach(func() {
+			snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_pending_events.bkp")}
+			store, err = snapstore.GetSnapstore(snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			DeferCleanup(os.RemoveAll, snapstoreConfig.Container)
+
+			clientKV, err = etcdutil.NewFactory(*etcdConnectionConfig).NewKV()
+			Expect(err).ShouldNot(HaveOccurred())
+			DeferCleanup(clientKV.Close)
+
+			snapshotterConfig := NewSnapshotterConfig()
+			// the events are only flushed when the delta snapshots are triggered
+			snapshotterConfig.DeltaSnapshotPeriod.Duration = time.Hour
+			ssr, err = NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			ssr.SetSnapshotterActive()
+			stopCh = make(chan struct{})
+			ssrErrCh = make(chan error, 1)
+			go func() {
+				ssrErrCh <- ssr.Run(stopCh, true)
+			}()
+			Eventually(func() *brtypes.Snapshot { return getLatestFullSnapshot(store) }, 30*time.Second).ShouldNot(BeNil())
+		})
+
+		AfterEach(func() {
+			close(stopCh)
+			Eventually(ssrErrCh, 30*time.Second).Should(Receive(BeNil()))
+		})
+
+		It("should track the events received from the watch and reset them once they are flushed", func() {
+			for i := 0; i < 3; i++ {
+				_, err := clientKV.Put(testCtx, fmt.Sprintf("pending-key-%d", i), fmt.Sprintf("pending-value-%d", i))
+				Expect(err).ShouldNot(HaveOccurred())
+			}
+			Eventually(pendingEvents, 10*time.Second).Should(Equal(float64(3)))
+			bytes := pendingBytes()
+			Expect(bytes).Should(BeNumerically(">", 0))
+
+			_, err := clientKV.Put(testCtx, "pending-key-3", "pending-value-3")
+			Expect(err).ShouldNot(HaveOccurred())
+			Eventually(pendingEvents, 10*time.Second).Should(Equal(float64(4)))
+			Expect(pendingBytes()).Should(BeNumerically(">", bytes))
+
+			snap, err := ssr.TriggerDeltaSnapshot(testCtx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(snap).ShouldNot(BeNil())
+			Expect(pendingEvents()).Should(Equal(float64(0)))
+			Expect(pendingBytes()).Should(Equal(float64(0)))
+		})
+
+		It("should reset the pending events once they are discarded along with a full snapshot", func() {
+			_, err := clientKV.Put(testCtx, "pending-full-key", "pending-full-value")
+			Expect(err).ShouldNot(HaveOccurred())
+			Eventually(pendingEvents, 10*time.Second).Should(Equal(float64(1)))
+
+			_, err = ssr.TriggerFullSnapshot(testCtx, false)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(pendingEvents()).Should(Equal(float64(0)))
+			Expect(pendingBytes()).Should(Equal(float64(0)))
+		})
+
+		dumpPendingEvents := func() []*clientv3.Event {
+			var buf bytes.Buffer
+			Expect(ssr.DumpPendingEvents(&buf)).To(Succeed())
+			Expect(json.Valid(buf.Bytes())).To(BeTrue())
+			var dumped []struct {
+				EtcdEvent *clientv3.Event `json:"etcdEvent"`
+				Time      time.Time       `json:"time"`
+			}
+			Expect(json.Unmarshal(buf.Bytes(), &dumped)).To(Succeed())
+			evs := make([]*clientv3.Event, 0, len(dumped))
+			for _, ev := range dumped {
+				Expect(ev.Time).ShouldNot(BeZero())
+				evs = append(evs, ev.EtcdEvent)
+			}
+			return evs
+		}
+
+		It("should dump the pending events as a JSON array without flushing them", func() {
+			Expect(dumpPendingEvents()).To(BeEmpty())
+
+			for i := 0; i < 3; i++ {
+				_, err := clientKV.Put(testCtx, fmt.Sprintf("dump-key-%d", i), fmt.Sprintf("dump-value-%d", i))
+				Expect(err).ShouldNot(HaveOccurred())
+			}
+			_, err := clientKV.Delete(testCtx, "dump-key-0")
+			Expect(err).ShouldNot(HaveOccurred())
+			Eventually(pendingEvents, 10*time.Second).Should(Equal(float64(4)))
+
+			evs := dumpPendingEvents()
+			Expect(evs).To(HaveLen(4))
+			for i := 0; i < 3; i++ {
+				Expect(evs[i].Type).To(Equal(mvccpb.PUT))
+				Expect(string(evs[i].Kv.Key)).To(Equal(fmt.Sprintf("dump-key-%d", i)))
+				Expect(string(evs[i].Kv.Value)).To(Equal(fmt.Sprintf("dump-value-%d", i)))
+			}
+			Expect(evs[3].Type).To(Equal(mvccpb.DELETE))
+			Expect(string(evs[3].Kv.Key)).To(Equal("dump-key-0"))
+			Expect(pendingEvents()).Should(Equal(float64(4)))
+
+			snap, err := ssr.TriggerDeltaSnapshot(testCtx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(snap).ShouldNot(BeNil())
+			Expect(snap.LastRevision).To(Equal(evs[3].Kv.ModRevision))
+			Expect(dumpPendingEvents()).To(BeEmpty())
+		})
+	})
+
+	Describe("handling the etcd alarms during full snapshots", func() {
+		var (
+			ctrl              *gomock.Controller
+			factory           *mockfactory.MockFactory
+			ckv               *mockfactory.MockKVCloser
+			cm                *mockfactory.MockMaintenanceCloser
+			snapshotterConfig *brtypes.SnapshotterConfig
+			noAlarms          *clientv3.AlarmResponse
+			noSpaceAlarm      *clientv3.AlarmResponse
+		)
+		BeforeEach(func() {
+			snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_alarms.bkp")}
+			store, err = snapstore.GetSnapstore(snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			DeferCleanup(os.RemoveAll, snapstoreConfig.Container)
+
+			ctrl = gomock.NewController(GinkgoT())
+			factory = mockfactory.NewMockFactory(ctrl)
+			ckv = mockfactory.NewMockKVCloser(ctrl)
+			cm = mockfactory.NewMockMaintenanceCloser(ctrl)
+			factory.EXPECT().NewMaintenance().Return(cm, nil).AnyTimes()
+			cm.EXPECT().Close().AnyTimes()
+			ckv.EXPECT().Close().AnyTimes()
+
+			snapshotterConfig = NewSnapshotterConfig()
+			snapshotterConfig.DeltaSnapshotPeriod.Duration = 0
+
+			noAlarms = &clientv3.AlarmResponse{}
+			noSpaceAlarm = &clientv3.AlarmResponse{Alarms: []*etcdserverpb.AlarmMember{{MemberID: 1, Alarm: etcdserverpb.AlarmType_NOSPACE}}}
+		})
+
+		newSnapshotter := func() *Snapshotter {
+			ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			ssr.NewClientFactory = func(brtypes.EtcdConnectionConfig, ...etcdClient.Option) etcdClient.Factory {
+				return factory
+			}
+			return ssr
+		}
+
+		expectFullSnapshot := func() {
+			factory.EXPECT().NewKV().Return(ckv, nil)
+			ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 100}}, nil)
+			cm.EXPECT().Snapshot(gomock.Any()).Return(io.NopCloser(strings.NewReader("dummy-full-snapshot")), nil)
+		}
+
+		alarmActive := func(alarmType etcdserverpb.AlarmType) float64 {
+			return testutil.ToFloat64(metrics.EtcdAlarmActive.With(prometheus.Labels{metrics.LabelEtcdAlarm: alarmType.String()}))
+		}
+
+		It("should not query the alarms with the ignore policy", func() {
+			snapshotterConfig.EtcdAlarmPolicy = brtypes.EtcdAlarmPolicyIgnore
+			expectFullSnapshot()
+
+			snap, err := newSnapshotter().TakeFullSnapshotAndResetTimer(testCtx, false)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(snap.LastRevision).Should(Equal(int64(100)))
+		})
+
+		It("should report an active NOSPACE alarm without failing the full snapshot with the report policy", func() {
+			snapshotterConfig.EtcdAlarmPolicy = brtypes.EtcdAlarmPolicyReport
+			cm.EXPECT().AlarmList(gomock.Any()).Return(noSpaceAlarm, nil).Times(2)
+			expectFullSnapshot()
+
+			_, err := newSnapshotter().TakeFullSnapshotAndResetTimer(testCtx, false)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(alarmActive(etcdserverpb.AlarmType_NOSPACE)).Should(Equal(float64(1)))
+			Expect(alarmActive(etcdserverpb.AlarmType_CORRUPT)).Should(Equal(float64(0)))
+		})
+
+		It("should report the disarmed alarms", func() {
+			snapshotterConfig.EtcdAlarmPolicy = brtypes.EtcdAlarmPolicyReport
+			metrics.EtcdAlarmActive.With(prometheus.Labels{metrics.LabelEtcdAlarm: etcdserverpb.AlarmType_NOSPACE.String()}).Set(1)
+			cm.EXPECT().AlarmList(gomock.Any()).Return(noAlarms, nil).Times(2)
+			expectFullSnapshot()
+
+			_, err := newSnapshotter().TakeFullSnapshotAndResetTimer(testCtx, false)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(alarmActive(etcdserverpb.AlarmType_NOSPACE)).Should(Equal(float64(0)))
+		})
+
+		It("should not take the full snapshot while a NOSPACE alarm is active with the fail on NOSPACE policy", func() {
+			snapshotterConfig.EtcdAlarmPolicy = brtypes.EtcdAlarmPolicyFailOnNoSpace
+			cm.EXPECT().AlarmList(gomock.Any()).Return(noSpaceAlarm, nil)
+
+			_, err := newSnapshotter().TakeFullSnapshotAndResetTimer(testCtx, false)
+			Expect(err).Should(MatchError(ErrEtcdNoSpaceAlarm))
+			Expect(alarmActive(etcdserverpb.AlarmType_NOSPACE)).Should(Equal(float64(1)))
+			snapList, err := store.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(snapList).Should(BeEmpty())
+		})
+
+		It("should fail the full snapshot if a NOSPACE alarm is raised meanwhile with the fail on NOSPACE policy", func() {
+			snapshotterConfig.EtcdAlarmPolicy = brtypes.EtcdAlarmPolicyFailOnNoSpace
+			gomock.InOrder(
+				cm.EXPECT().AlarmList(gomock.Any()).Return(noAlarms, nil),
+				cm.EXPECT().AlarmList(gomock.Any()).Return(noSpaceAlarm, nil),
+			)
+			expectFullSnapshot()
+
+			ssr := newSnapshotter()
+			_, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+			Expect(err).Should(MatchError(ErrEtcdNoSpaceAlarm))
+			Expect(testutil.ToFloat64(metrics.FullSnapshotConsecutiveFailures.With(prometheus.Labels{}))).Should(Equal(float64(1)))
+			// the saved full snapshot is still recorded as the base of the following delta snapshots
+			Expect(ssr.PrevFullSnapshot).ShouldNot(BeNil())
+			Expect(ssr.PrevFullSnapshot.LastRevision).Should(Equal(int64(100)))
+		})
+
+		It("should not fail the full snapshot if the alarms can't be queried with the fail on NOSPACE policy", func() {
+			snapshotterConfig.EtcdAlarmPolicy = brtypes.EtcdAlarmPolicyFailOnNoSpace
+			cm.EXPECT().AlarmList(gomock.Any()).Return(nil, errors.New("unavailable")).Times(2)
+			expectFullSnapshot()
+
+			_, err := newSnapshotter().TakeFullSnapshotAndResetTimer(testCtx, false)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+	})
+
+	Describe("taking the snapshots with a fake etcd client factory", func() {
+		var (
+			ctrl              *gomock.Controller
+			factory           *mockfactory.MockFactory
+			ckv               *mockfactory.MockKVCloser
+			cm                *mockfactory.MockMaintenanceCloser
+			watcher           *fakeWatcher
+			snapshotterConfig *brtypes.SnapshotterConfig
+		)
+		BeforeEach(func() {
+			snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_fake_factory.bkp")}
+			store, err = snapstore.GetSnapstore(snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			DeferCleanup(os.RemoveAll, snapstoreConfig.Container)
+
+			ctrl = gomock.NewController(GinkgoT())
+			factory = mockfactory.NewMockFactory(ctrl)
+			ckv = mockfactory.NewMockKVCloser(ctrl)
+			cm = mockfactory.NewMockMaintenanceCloser(ctrl)
+			watcher = newFakeWatcher()
+			factory.EXPECT().NewKV().Return(ckv, nil).AnyTimes()
+			factory.EXPECT().NewMaintenance().Return(cm, nil).AnyTimes()
+			factory.EXPECT().NewWatcher().Return(watcher, nil).AnyTimes()
+			ckv.EXPECT().Close().AnyTimes()
+			cm.EXPECT().Close().AnyTimes()
+
+			snapshotterConfig = NewSnapshotterConfig()
+		})
+
+		newSnapshotter := func() *Snapshotter {
+			ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			ssr.NewClientFactory = func(brtypes.EtcdConnectionConfig, ...etcdClient.Option) etcdClient.Factory {
+				return factory
+			}
+			return ssr
+		}
+
+		// readSnapshot returns the decompressed contents of the given snapshot, as listed in the snapstore.
+		readSnapshot := func(snap *brtypes.Snapshot) []byte {
+			list, err := store.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			for _, listed := range list {
+				if listed.SnapName != snap.SnapName {
+					continue
+				}
+				rc, err := store.Fetch(*listed)
+				Expect(err).ShouldNot(HaveOccurred())
+				defer rc.Close()
+				isCompressed, compressionPolicy, err := compressor.IsSnapshotCompressed(listed.CompressionSuffix)
+				Expect(err).ShouldNot(HaveOccurred())
+				if isCompressed {
+					rc, err = compressor.DecompressSnapshot(rc, compressionPolicy)
+					Expect(err).ShouldNot(HaveOccurred())
+				}
+				data, err := io.ReadAll(rc)
+				Expect(err).ShouldNot(HaveOccurred())
+				return data
+			}
+			Fail("snapshot " + snap.SnapName + " is not listed in the snapstore")
+			return nil
+		}
+
+		putEvent := func(key string, revision int64) *clientv3.Event {
+			return &clientv3.Event{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: []byte(key), Value: []byte("value-" + key), ModRevision: revision}}
+		}
+
+		Context("reading the size of the etcd database", func() {
+			var (
+				fakeClock *testclock.FakeClock
+				stopCh    chan struct{}
+			)
+			BeforeEach(func() {
+				snapshotterConfig.EtcdDBSizeMetricsPeriod.Duration = time.Minute
+				fakeClock = testclock.NewFakeClock(time.Now())
+				stopCh = make(chan struct{})
+			})
+
+			dbSize := func() float64 {
+				return testutil.ToFloat64(metrics.EtcdDBSizeBytes.With(prometheus.Labels{}))
+			}
+			dbSizeInUse := func() float64 {
+				return testutil.ToFloat64(metrics.EtcdDBSizeInUseBytes.With(prometheus.Labels{}))
+			}
+
+			It("should expose the size of the etcd database and its size in use every period", func() {
+				endpoint := etcdConnectionConfig.Endpoints[0]
+				gomock.InOrder(
+					cm.EXPECT().Status(gomock.Any(), endpoint).Return(&clientv3.StatusResponse{DbSize: 4096, DbSizeInUse: 1024}, nil),
+					cm.EXPECT().Status(gomock.Any(), endpoint).Return(&clientv3.StatusResponse{DbSize: 8192, DbSizeInUse: 6144}, nil),
+				)
+				ssr := newSnapshotter()
+				ssr.Clock = fakeClock
+				doneCh := make(chan struct{})
+				go func() {
+					defer close(doneCh)
+					ssr.UpdateEtcdDBSizeMetricsPeriodically(stopCh)
+				}()
+
+				Eventually(dbSize).Should(Equal(float64(4096)))
+				Expect(dbSizeInUse()).Should(Equal(float64(1024)))
+				Eventually(fakeClock.HasWaiters).Should(BeTrue())
+
+				fakeClock.Step(time.Minute)
+				Eventually(dbSize).Should(Equal(float64(8192)))
+				Expect(dbSizeInUse()).Should(Equal(float64(6144)))
+
+				close(stopCh)
+				Eventually(doneCh).Should(BeClosed())
+			})
+
+			It("should keep the previous sizes if the status of etcd can't be read", func() {
+				gomock.InOrder(
+					cm.EXPECT().Status(gomock.Any(), gomock.Any()).Return(&clientv3.StatusResponse{DbSize: 4096, DbSizeInUse: 1024}, nil),
+					cm.EXPECT().Status(gomock.Any(), gomock.Any()).Return(nil, errors.New("unavailable")),
+				)
+				ssr := newSnapshotter()
+				ssr.Clock = fakeClock
+				doneCh := make(chan struct{})
+				go func() {
+					defer close(doneCh)
+					ssr.UpdateEtcdDBSizeMetricsPeriodically(stopCh)
+				}()
+
+				Eventually(dbSize).Should(Equal(float64(4096)))
+				Eventually(fakeClock.HasWaiters).Should(BeTrue())
+				fakeClock.Step(time.Minute)
+				Eventually(fakeClock.HasWaiters).Should(BeTrue())
+				Consistently(dbSize, 100*time.Millisecond).Should(Equal(float64(4096)))
+				Expect(dbSizeInUse()).Should(Equal(float64(1024)))
+
+				close(stopCh)
+				Eventually(doneCh).Should(BeClosed())
+			})
+		})
+
+		It("should take a full snapshot and watch the events after it", func() {
+			ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 100}}, nil)
+			cm.EXPECT().Snapshot(gomock.Any()).Return(io.NopCloser(strings.NewReader("dummy-full-snapshot")), nil)
+
+			ssr := newSnapshotter()
+			snap, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(snap.Kind).Should(Equal(brtypes.SnapshotKindFull))
+			Expect(snap.LastRevision).Should(Equal(int64(100)))
+			Expect(watcher.watchedRevisions()).Should(Equal([]int64{101}))
+
+			data := readSnapshot(snap)
+			Expect(string(data)).Should(Equal("dummy-full-snapshot"))
+		})
+
+		It("should take a delta snapshot of the events collected since the previous snapshot", func() {
+			ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 100}}, nil)
+			cm.EXPECT().Snapshot(gomock.Any()).Return(io.NopCloser(strings.NewReader("dummy-full-snapshot")), nil)
+			ssr := newSnapshotter()
+			_, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			// etcd has moved on to revision 102 when the events are collected on startup
+			ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 102}}, nil)
+			watcher.watchCh <- clientv3.WatchResponse{Events: []*clientv3.Event{putEvent("foo", 101), putEvent("bar", 102)}}
+			stopped, err := ssr.CollectEventsSincePrevSnapshot(testCtx, testCtx.Done())
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(stopped).Should(BeFalse())
+			Expect(watcher.watchedRevisions()).Should(Equal([]int64{101, 101}))
+
+			snap, err := ssr.TakeDeltaSnapshot(testCtx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(snap.Kind).Should(Equal(brtypes.SnapshotKindDelta))
+			Expect(snap.StartRevision).Should(Equal(int64(101)))
+			Expect(snap.LastRevision).Should(Equal(int64(102)))
+			Expect(ssr.PrevSnapshot).Should(Equal(snap))
+
+			data := readSnapshot(snap)
+			Expect(len(data)).Should(BeNumerically(">", sha256.Size))
+			events, hash := data[:len(data)-sha256.Size], data[len(data)-sha256.Size:]
+			expectedHash := sha256.Sum256(events)
+			Expect(hash).Should(Equal(expectedHash[:]))
+			var timedEvents []struct {
+				EtcdEvent *clientv3.Event `json:"etcdEvent"`
+			}
+			Expect(json.Unmarshal(events, &timedEvents)).To(Succeed())
+			Expect(timedEvents).Should(HaveLen(2))
+			Expect(string(timedEvents[0].EtcdEvent.Kv.Key)).Should(Equal("foo"))
+			Expect(string(timedEvents[1].EtcdEvent.Kv.Key)).Should(Equal("bar"))
+		})
+
+		DescribeTable("should record the hash algorithm of the delta snapshots",
+			func(hashAlgorithm string) {
+				snapshotterConfig.DeltaSnapshotHashAlgorithm = hashAlgorithm
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 100}}, nil)
+				cm.EXPECT().Snapshot(gomock.Any()).Return(io.NopCloser(strings.NewReader("dummy-full-snapshot")), nil)
+				ssr := newSnapshotter()
+				_, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 101}}, nil)
+				watcher.watchCh <- clientv3.WatchResponse{Events: []*clientv3.Event{putEvent("foo", 101)}}
+				_, err = ssr.CollectEventsSincePrevSnapshot(testCtx, testCtx.Done())
+				Expect(err).ShouldNot(HaveOccurred())
+				snap, err := ssr.TakeDeltaSnapshot(testCtx)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				data := readSnapshot(snap)
+				events, algorithm, err := brtypes.VerifyDeltaSnapshotHash(data)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(algorithm).Should(Equal(hashAlgorithm))
+				Expect(string(events)).Should(ContainSubstring(base64.StdEncoding.EncodeToString([]byte("foo"))))
+			},
+			Entry("with SHA-256", brtypes.DeltaSnapshotHashSHA256),
+			Entry("with SHA-512", brtypes.DeltaSnapshotHashSHA512),
+			Entry("with BLAKE2b", brtypes.DeltaSnapshotHashBLAKE2b),
+		)
+
+		Context("with distinct timeouts for the etcd GET calls and the watch setup", func() {
+			// expectGetWithTimeout expects a GET of the latest revision whose context expires after the given timeout.
+			expectGetWithTimeout := func(timeout time.Duration, revision int64) {
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).DoAndReturn(func(ctx context.Context, _ string, _ ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+					deadline, ok := ctx.Deadline()
+					Expect(ok).Should(BeTrue())
+					Expect(deadline).Should(BeTemporally("~", time.Now().Add(timeout), time.Second))
+					return &clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: revision}}, nil
+				})
+			}
+
+			BeforeEach(func() {
+				etcdConnectionConfig.ConnectionTimeout.Duration = time.Minute
+			})
+
+			It("should apply the connection timeout to the GET calls unless a distinct timeout is configured", func() {
+				expectGetWithTimeout(time.Minute, 100)
+				cm.EXPECT().Snapshot(gomock.Any()).Return(io.NopCloser(strings.NewReader("dummy-full-snapshot")), nil)
+				_, err := newSnapshotter().TakeFullSnapshotAndResetTimer(testCtx, false)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+
+			It("should apply the KV GET timeout to the GET calls of the full snapshots and the collection of the events", func() {
+				etcdConnectionConfig.KVGetTimeout.Duration = 5 * time.Second
+				expectGetWithTimeout(5*time.Second, 100)
+				cm.EXPECT().Snapshot(gomock.Any()).Return(io.NopCloser(strings.NewReader("dummy-full-snapshot")), nil)
+				ssr := newSnapshotter()
+				_, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				expectGetWithTimeout(5*time.Second, 100)
+				stopped, err := ssr.CollectEventsSincePrevSnapshot(testCtx, testCtx.Done())
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(stopped).Should(BeFalse())
+			})
+
+			It("should wait for the watch to be established within the watch setup timeout", func() {
+				etcdConnectionConfig.KVGetTimeout.Duration = 5 * time.Second
+				etcdConnectionConfig.WatchSetupTimeout.Duration = 200 * time.Millisecond
+				expectGetWithTimeout(5*time.Second, 100)
+				cm.EXPECT().Snapshot(gomock.Any()).Return(io.NopCloser(strings.NewReader("dummy-full-snapshot")), nil)
+				ssr := newSnapshotter()
+				watcher.watchCh <- clientv3.WatchResponse{Created: true}
+				_, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				// the watch is not established this time, irrespective of the longer KV GET timeout
+				expectGetWithTimeout(5*time.Second, 100)
+				start := time.Now()
+				_, err = ssr.CollectEventsSincePrevSnapshot(testCtx, testCtx.Done())
+				Expect(err).Should(HaveOccurred())
+				Expect(err.Error()).Should(ContainSubstring("etcd watch was not established within the watch setup timeout of 200ms"))
+				Expect(time.Since(start)).Should(BeNumerically("<", 5*time.Second))
+			})
+		})
+
+		It("should skip the delta snapshot if no events were collected", func() {
+			ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 100}}, nil).Times(2)
+			cm.EXPECT().Snapshot(gomock.Any()).Return(io.NopCloser(strings.NewReader("dummy-full-snapshot")), nil)
+			ssr := newSnapshotter()
+			_, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			stopped, err := ssr.CollectEventsSincePrevSnapshot(testCtx, testCtx.Done())
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(stopped).Should(BeFalse())
+
+			snap, err := ssr.TakeDeltaSnapshot(testCtx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(snap).Should(BeNil())
+			Expect(ssr.PrevSnapshot.Kind).Should(Equal(brtypes.SnapshotKindFull))
+		})
+
+		Describe("taking a full snapshot at a historical revision", func() {
+			BeforeEach(func() {
+				snapshotterConfig.SnapshotKeyPrefix = "/scoped/"
+			})
+
+			It("should export the keys under the prefix at the revision", func() {
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 120}}, nil)
+				ckv.EXPECT().Get(gomock.Any(), "/scoped/", gomock.Any()).DoAndReturn(func(_ context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+					Expect(clientv3.OpGet(key, opts...).Rev()).Should(Equal(int64(105)))
+					return &clientv3.GetResponse{
+						Header: &etcdserverpb.ResponseHeader{Revision: 120},
+						Kvs:    []*mvccpb.KeyValue{{Key: []byte("/scoped/foo"), Value: []byte("value-at-105"), ModRevision: 105}},
+					}, nil
+				})
+				snapshotterConfig.DeltaSnapshotPeriod.Duration = 0
+
+				ssr := newSnapshotter()
+				snap, err := ssr.TakeFullSnapshotAtRevisionAndResetTimer(testCtx, 105)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(snap.LastRevision).Should(Equal(int64(105)))
+				Expect(string(readSnapshot(snap))).Should(ContainSubstring(base64.StdEncoding.EncodeToString([]byte("value-at-105"))))
+			})
+
+			It("should fail if the revision has been compacted", func() {
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 120}}, nil)
+				ckv.EXPECT().Get(gomock.Any(), "/scoped/", gomock.Any()).Return(nil, rpctypes.ErrCompacted)
+
+				ssr := newSnapshotter()
+				_, err := ssr.TakeFullSnapshotAtRevisionAndResetTimer(testCtx, 105)
+				Expect(err).Should(MatchError(ContainSubstring("revision 105 has been compacted")))
+			})
+
+			It("should fail if the revision is ahead of the latest etcd revision", func() {
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 120}}, nil)
+
+				ssr := newSnapshotter()
+				_, err := ssr.TakeFullSnapshotAtRevisionAndResetTimer(testCtx, 130)
+				Expect(err).Should(MatchError(ContainSubstring("ahead of the latest etcd revision 120")))
+			})
+
+			It("should reject a revision which is not after the previous snapshot without contacting etcd", func() {
+				ssr := newSnapshotter()
+				ssr.PrevSnapshot = snapstore.NewSnapshot(brtypes.SnapshotKindDelta, 101, 110, "", false)
+				_, err := ssr.TakeFullSnapshotAtRevisionAndResetTimer(testCtx, 105)
+				Expect(err).Should(MatchError(ContainSubstring("not after the revision 110 of the previous snapshot")))
+			})
+
+			It("should export the keys of the whole keyspace at the revision if the snapshots aren't scoped to a key prefix", func() {
+				snapshotterConfig.SnapshotKeyPrefix = ""
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 120}}, nil)
+				ckv.EXPECT().Get(gomock.Any(), "\x00", gomock.Any()).DoAndReturn(func(_ context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+					op := clientv3.OpGet(key, opts...)
+					Expect(op.Rev()).Should(Equal(int64(105)))
+					Expect(string(op.RangeBytes())).Should(Equal("\x00"))
+					return &clientv3.GetResponse{
+						Header: &etcdserverpb.ResponseHeader{Revision: 120},
+						Kvs:    []*mvccpb.KeyValue{{Key: []byte("/foo"), Value: []byte("value-at-105"), ModRevision: 105}},
+					}, nil
+				})
+				snapshotterConfig.DeltaSnapshotPeriod.Duration = 0
+
+				ssr := newSnapshotter()
+				snap, err := ssr.TakeFullSnapshotAtRevisionAndResetTimer(testCtx, 105)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(snap.LastRevision).Should(Equal(int64(105)))
+				Expect(string(readSnapshot(snap))).Should(ContainSubstring(base64.StdEncoding.EncodeToString([]byte("value-at-105"))))
+			})
+		})
+
+		Describe("splitting the delta snapshots at the memory limit", func() {
+			var (
+				watchEvents []*clientv3.Event
+				eventSize   int
+			)
+
+			BeforeEach(func() {
+				// the events of revisions 101-130, of which revision 111 is a transaction of 10 events
+				watchEvents = nil
+				for revision := int64(101); revision <= 130; revision++ {
+					count := 1
+					if revision == 111 {
+						count = 10
+					}
+					for i := 0; i < count; i++ {
+						watchEvents = append(watchEvents, putEvent(fmt.Sprintf("key-%d-%d", revision, i), revision))
+					}
+				}
+				data, err := MarshalEvents(watchEvents[:1], time.Now())
+				Expect(err).ShouldNot(HaveOccurred())
+				eventSize = len(data)
+				snapshotterConfig.DeltaSnapshotMemoryLimit = uint(5 * eventSize)
+				snapshotterConfig.SplitDeltaSnapshotsAtMemoryLimit = true
+			})
+
+			// collectEvents takes a full snapshot at revision 100, collects the events of a single watch response and
+			// takes a delta snapshot of the events left over, returning the delta snapshots in the snapstore.
+			collectEvents := func() brtypes.SnapList {
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 100}}, nil)
+				cm.EXPECT().Snapshot(gomock.Any()).Return(io.NopCloser(strings.NewReader("dummy-full-snapshot")), nil)
+				ssr := newSnapshotter()
+				_, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 130}}, nil)
+				watcher.watchCh <- clientv3.WatchResponse{Events: watchEvents}
+				_, err = ssr.CollectEventsSincePrevSnapshot(testCtx, testCtx.Done())
+				Expect(err).ShouldNot(HaveOccurred())
+				_, err = ssr.TakeDeltaSnapshot(testCtx)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				list, err := store.List()
+				Expect(err).ShouldNot(HaveOccurred())
+				var deltaSnapList brtypes.SnapList
+				for _, snap := range list {
+					if snap.Kind == brtypes.SnapshotKindDelta {
+						deltaSnapList = append(deltaSnapList, snap)
+					}
+				}
+				sort.Sort(deltaSnapList)
+				return deltaSnapList
+			}
+
+			// readEvents returns the marshaled events of the given delta snapshot, along with their number.
+			readEvents := func(snap *brtypes.Snapshot) ([]byte, int) {
+				data := readSnapshot(snap)
+				events := data[:len(data)-sha256.Size]
+				var timedEvents []json.RawMessage
+				Expect(json.Unmarshal(events, &timedEvents)).To(Succeed())
+				return events, len(timedEvents)
+			}
+
+			It("should carry the events beyond the memory limit of an oversized watch response over to the next delta snapshot", func() {
+				deltaSnapList := collectEvents()
+				Expect(len(deltaSnapList)).Should(BeNumerically(">", 5))
+
+				totalEvents := 0
+				for i, snap := range deltaSnapList {
+					if i == 0 {
+						Expect(snap.StartRevision).Should(Equal(int64(101)))
+					} else {
+						Expect(snap.StartRevision).Should(Equal(deltaSnapList[i-1].LastRevision + 1))
+					}
+					events, count := readEvents(snap)
+					totalEvents += count
+					// each delta snapshot crosses the memory limit by the events of its last revision at most
+					lastRevisionSize := eventSize
+					if snap.LastRevision == 111 {
+						lastRevisionSize = 10 * eventSize
+					}
+					Expect(len(events)).Should(BeNumerically("<=", int(snapshotterConfig.DeltaSnapshotMemoryLimit)+lastRevisionSize))
+					if i < len(deltaSnapList)-1 {
+						Expect(len(events)).Should(BeNumerically(">=", int(snapshotterConfig.DeltaSnapshotMemoryLimit)))
+					}
+				}
+				Expect(deltaSnapList[len(deltaSnapList)-1].LastRevision).Should(Equal(int64(130)))
+				Expect(totalEvents).Should(Equal(len(watchEvents)))
+			})
+
+			It("should take a single delta snapshot of an oversized watch response if disabled", func() {
+				snapshotterConfig.SplitDeltaSnapshotsAtMemoryLimit = false
+				deltaSnapList := collectEvents()
+				Expect(deltaSnapList).Should(HaveLen(1))
+				Expect(deltaSnapList[0].StartRevision).Should(Equal(int64(101)))
+				Expect(deltaSnapList[0].LastRevision).Should(Equal(int64(130)))
+				_, count := readEvents(deltaSnapList[0])
+				Expect(count).Should(Equal(len(watchEvents)))
+			})
+		})
+
+		Describe("deduplicating the delta snapshots", func() {
+			// collectEvents collects the given events of a single watch response up to the given etcd revision, and takes a
+			// delta snapshot of them.
+			collectEvents := func(ssr *Snapshotter, etcdRevision int64, evs ...*clientv3.Event) *brtypes.Snapshot {
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: etcdRevision}}, nil)
+				watcher.watchCh <- clientv3.WatchResponse{Events: evs}
+				_, err := ssr.CollectEventsSincePrevSnapshot(testCtx, testCtx.Done())
+				Expect(err).ShouldNot(HaveOccurred())
+				snap, err := ssr.TakeDeltaSnapshot(testCtx)
+				Expect(err).ShouldNot(HaveOccurred())
+				return snap
+			}
+			listDeltaSnapshots := func() brtypes.SnapList {
+				list, err := store.List()
+				Expect(err).ShouldNot(HaveOccurred())
+				var deltaSnapList brtypes.SnapList
+				for _, snap := range list {
+					if snap.Kind == brtypes.SnapshotKindDelta {
+						deltaSnapList = append(deltaSnapList, snap)
+					}
+				}
+				sort.Sort(deltaSnapList)
+				return deltaSnapList
+			}
+			// takeSnapshots takes a full snapshot at revision 100, a delta snapshot of the writes of keys a and b, and a
+			// delta snapshot of the same writes retried at the next revisions.
+			takeSnapshots := func() (*Snapshotter, *brtypes.Snapshot, *brtypes.Snapshot) {
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 100}}, nil)
+				cm.EXPECT().Snapshot(gomock.Any()).Return(io.NopCloser(strings.NewReader("dummy-full-snapshot")), nil)
+				ssr := newSnapshotter()
+				_, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				firstSnap := collectEvents(ssr, 102, putEvent("a", 101), putEvent("b", 102))
+				Expect(firstSnap).ShouldNot(BeNil())
+				retriedSnap := collectEvents(ssr, 104, putEvent("a", 103), putEvent("b", 104))
+				return ssr, firstSnap, retriedSnap
+			}
+
+			It("should skip saving a delta snapshot which repeats the previous delta snapshot, without dropping its revisions", func() {
+				snapshotterConfig.DeduplicateDeltaSnapshots = true
+				ssr, firstSnap, retriedSnap := takeSnapshots()
+				Expect(retriedSnap).Should(BeNil())
+				Expect(listDeltaSnapshots()).Should(HaveLen(1))
+				Expect(ssr.PrevSnapshot.SnapName).Should(Equal(firstSnap.SnapName))
+
+				// the next delta snapshot continues right after the previous saved one, hence covers the retried writes
+				snap := collectEvents(ssr, 105, putEvent("a", 103), putEvent("b", 104), putEvent("c", 105))
+				Expect(snap).ShouldNot(BeNil())
+				watchedRevisions := watcher.watchedRevisions()
+				Expect(watchedRevisions[len(watchedRevisions)-1]).Should(Equal(int64(103)))
+				Expect(snap.StartRevision).Should(Equal(int64(103)))
+				Expect(snap.LastRevision).Should(Equal(int64(105)))
+				deltaSnapList := listDeltaSnapshots()
+				Expect(deltaSnapList).Should(HaveLen(2))
+				Expect(readDeltaSnapshotEvents(store, deltaSnapList[1])).Should(HaveLen(3))
+			})
+
+			It("should save the delta snapshots which differ from the previous delta snapshot", func() {
+				snapshotterConfig.DeduplicateDeltaSnapshots = true
+				ssr, _, _ := takeSnapshots()
+				snap := collectEvents(ssr, 106, putEvent("a", 103), putEvent("b", 104), putEvent("b", 105), putEvent("a", 106))
+				Expect(snap).ShouldNot(BeNil())
+				Expect(listDeltaSnapshots()).Should(HaveLen(2))
+			})
+
+			It("should save the duplicate delta snapshots if disabled", func() {
+				_, _, retriedSnap := takeSnapshots()
+				Expect(retriedSnap).ShouldNot(BeNil())
+				Expect(retriedSnap.StartRevision).Should(Equal(int64(103)))
+				Expect(listDeltaSnapshots()).Should(HaveLen(2))
+			})
+		})
+
+		Describe("writing the chain manifest", func() {
+			BeforeEach(func() {
+				snapshotterConfig.WriteChainManifest = true
+			})
+
+			// takeSnapshots takes a full snapshot at revision 100, followed by a delta snapshot of revisions 101-102.
+			takeSnapshots := func(ssr *Snapshotter) (*brtypes.Snapshot, *brtypes.Snapshot) {
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 100}}, nil)
+				cm.EXPECT().Snapshot(gomock.Any()).Return(io.NopCloser(strings.NewReader("dummy-full-snapshot")), nil)
+				fullSnap, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 102}}, nil)
+				watcher.watchCh <- clientv3.WatchResponse{Events: []*clientv3.Event{putEvent("foo", 101), putEvent("bar", 102)}}
+				_, err = ssr.CollectEventsSincePrevSnapshot(testCtx, testCtx.Done())
+				Expect(err).ShouldNot(HaveOccurred())
+				deltaSnap, err := ssr.TakeDeltaSnapshot(testCtx)
+				Expect(err).ShouldNot(HaveOccurred())
+				return fullSnap, deltaSnap
+			}
+
+			// expectChainManifest expects the chain manifest to list the given snapshots, with the sizes of their objects.
+			expectChainManifest := func(fullSnap *brtypes.Snapshot, deltaSnaps ...*brtypes.Snapshot) *brtypes.ChainManifest {
+				manifest, err := snapstore.FetchChainManifest(store)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(manifest).ShouldNot(BeNil())
+				Expect(manifest.FullSnapshot.SnapName).Should(Equal(fullSnap.SnapName))
+				Expect(manifest.FullSnapshot.LastRevision).Should(Equal(fullSnap.LastRevision))
+				Expect(manifest.DeltaSnapshots).Should(HaveLen(len(deltaSnaps)))
+				if len(deltaSnaps) > 0 {
+					Expect(manifest.LatestRevision).Should(Equal(deltaSnaps[len(deltaSnaps)-1].LastRevision))
+				}
+				for i, deltaSnap := range deltaSnaps {
+					Expect(manifest.DeltaSnapshots[i].SnapName).Should(Equal(deltaSnap.SnapName))
+					Expect(manifest.DeltaSnapshots[i].StartRevision).Should(Equal(deltaSnap.StartRevision))
+					Expect(manifest.DeltaSnapshots[i].LastRevision).Should(Equal(deltaSnap.LastRevision))
+				}
+				for _, snap := range manifest.Snapshots() {
+					size, err := snapstore.SnapshotSize(store, snap.Snapshot)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(snap.ObjectSizeBytes).Should(Equal(size))
+				}
+				return manifest
+			}
+
+			It("should reflect the chain after each snapshot", func() {
+				ssr := newSnapshotter()
+				fullSnap, deltaSnap := takeSnapshots(ssr)
+				expectChainManifest(fullSnap, deltaSnap)
+
+				// a new full snapshot starts a new chain
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 110}}, nil)
+				cm.EXPECT().Snapshot(gomock.Any()).Return(io.NopCloser(strings.NewReader("next-full-snapshot")), nil)
+				nextFullSnap, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+				Expect(err).ShouldNot(HaveOccurred())
+				expectChainManifest(nextFullSnap)
+			})
+
+			It("should drop the snapshots deleted from the snapstore from the chain manifest after the garbage collection", func() {
+				gcPeriod := 2 * time.Second
+				snapshotterConfig.GarbageCollectionPeriod.Duration = gcPeriod
+				snapshotterConfig.GarbageCollectionPolicy = brtypes.GarbageCollectionPolicyLimitBased
+				snapshotterConfig.MaxBackups = 1
+				ssr := newSnapshotter()
+				fullSnap, deltaSnap := takeSnapshots(ssr)
+				manifest := expectChainManifest(fullSnap, deltaSnap)
+
+				// the delta snapshot is deleted by another process
+				Expect(store.Delete(manifest.DeltaSnapshots[0].Snapshot)).To(Succeed())
+
+				gcCtx, cancel := context.WithTimeout(testCtx, gcPeriod+time.Second)
+				defer cancel()
+				ssr.RunGarbageCollector(gcCtx.Done())
+
+				reconciled := expectChainManifest(fullSnap)
+				Expect(reconciled.UpdatedOn).Should(BeTemporally(">", manifest.UpdatedOn))
+				// the chain manifest no longer covers the chain up to the deleted delta snapshot
+				Expect(reconciled.LatestRevision).Should(Equal(deltaSnap.LastRevision))
+				Expect(snapstore.VerifyChainManifest(store, reconciled)).Should(MatchError(ContainSubstring("end at revision 100 instead of its latest revision 102")))
+			})
+
+			It("should delete the chain manifest once it fails to be updated", func() {
+				failingStore := &failingChainManifestStore{LocalSnapStore: store.(*snapstore.LocalSnapStore)}
+				store = failingStore
+				ssr := newSnapshotter()
+				fullSnap, deltaSnap := takeSnapshots(ssr)
+				expectChainManifest(fullSnap, deltaSnap)
+
+				failingStore.failing.Store(true)
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 103}}, nil)
+				watcher.watchCh <- clientv3.WatchResponse{Events: []*clientv3.Event{putEvent("baz", 103)}}
+				_, err := ssr.CollectEventsSincePrevSnapshot(testCtx, testCtx.Done())
+				Expect(err).ShouldNot(HaveOccurred())
+				_, err = ssr.TakeDeltaSnapshot(testCtx)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				// the restoration lists the snapstore instead of restoring the chain up to the previous delta snapshot
+				manifest, err := snapstore.FetchChainManifest(store)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(manifest).Should(BeNil())
+			})
+
+			It("should be usable by the restoration as a hint for the snapshots to restore", func() {
+				ssr := newSnapshotter()
+				fullSnap, deltaSnap := takeSnapshots(ssr)
+
+				baseSnap, deltaSnapList, fallback, err := miscellaneous.GetLatestFullSnapshotAndDeltaSnapListFromChainManifest(store, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(fallback).Should(BeEmpty())
+				Expect(baseSnap.SnapName).Should(Equal(fullSnap.SnapName))
+				Expect(deltaSnapList).Should(HaveLen(1))
+				Expect(deltaSnapList[0].SnapName).Should(Equal(deltaSnap.SnapName))
+				// the snapshots of the chain manifest can be fetched like the listed ones
+				for _, snap := range append(brtypes.SnapList{baseSnap}, deltaSnapList...) {
+					rc, err := store.Fetch(*snap)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(rc.Close()).To(Succeed())
+				}
+
+				// the snapstore is listed instead once the chain manifest drifted from the snapshots in the snapstore
+				Expect(store.Delete(*deltaSnapList[0])).To(Succeed())
+				baseSnap, deltaSnapList, fallback, err = miscellaneous.GetLatestFullSnapshotAndDeltaSnapListFromChainManifest(store, logger)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(fallback).Should(ContainSubstring("drifted"))
+				Expect(baseSnap.SnapName).Should(Equal(fullSnap.SnapName))
+				Expect(deltaSnapList).Should(BeEmpty())
+			})
+		})
+
+		Describe("compressing the delta snapshots with a dictionary", func() {
+			BeforeEach(func() {
+				compressionConfig.Enabled = true
+				compressionConfig.CompressionPolicy = compressor.ZlibDictCompressionPolicy
+			})
+
+			// takeChain takes a full snapshot at the given revision, followed by a delta snapshot of the next two revisions.
+			takeChain := func(ssr *Snapshotter, revision int64) *brtypes.Snapshot {
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: revision}}, nil)
+				cm.EXPECT().Snapshot(gomock.Any()).Return(io.NopCloser(strings.NewReader("dummy-full-snapshot")), nil)
+				_, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: revision + 2}}, nil)
+				watcher.watchCh <- clientv3.WatchResponse{Events: []*clientv3.Event{putEvent("foo", revision+1), putEvent("bar", revision+2)}}
+				_, err = ssr.CollectEventsSincePrevSnapshot(testCtx, testCtx.Done())
+				Expect(err).ShouldNot(HaveOccurred())
+				deltaSnap, err := ssr.TakeDeltaSnapshot(testCtx)
+				Expect(err).ShouldNot(HaveOccurred())
+				return deltaSnap
+			}
+
+			// fetchSnapshot returns the compressed contents of the given snapshot, as listed in the snapstore.
+			fetchSnapshot := func(snap *brtypes.Snapshot) []byte {
+				list, err := store.List()
+				Expect(err).ShouldNot(HaveOccurred())
+				for _, listed := range list {
+					if listed.SnapName == snap.SnapName {
+						rc, err := store.Fetch(*listed)
+						Expect(err).ShouldNot(HaveOccurred())
+						defer rc.Close()
+						data, err := io.ReadAll(rc)
+						Expect(err).ShouldNot(HaveOccurred())
+						return data
+					}
+				}
+				Fail("snapshot " + snap.SnapName + " is not listed in the snapstore")
+				return nil
+			}
+
+			It("should compress the delta snapshots of the next chain with a dictionary trained from the events", func() {
+				ssr := newSnapshotter()
+				firstDeltaSnap := takeChain(ssr, 100)
+				Expect(firstDeltaSnap.CompressionSuffix).Should(Equal(compressor.ZlibDictCompressionExtension))
+				// there are no events to train a dictionary from before the first chain
+				Expect(snapstore.ListCompressionDictionaries(store)).Should(BeEmpty())
+				Expect(readSnapshot(firstDeltaSnap)).ShouldNot(BeEmpty())
+
+				deltaSnap := takeChain(ssr, 110)
+				dictionaries, err := snapstore.ListCompressionDictionaries(store)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(dictionaries).Should(HaveLen(1))
+
+				// the zlib header of the delta snapshot records the identifier of its dictionary
+				data := fetchSnapshot(deltaSnap)
+				Expect(data[1] & 0x20).ShouldNot(BeZero())
+				Expect(binary.BigEndian.Uint32(data[2:6])).Should(Equal(dictionaries[0].ID))
+
+				rc, err := compressor.DecompressSnapshotWithDictionary(io.NopCloser(bytes.NewReader(data)), compressor.ZlibDictCompressionPolicy, snapstore.NewCompressionDictionaryFetcher(store))
+				Expect(err).ShouldNot(HaveOccurred())
+				decompressed, err := io.ReadAll(rc)
+				Expect(err).ShouldNot(HaveOccurred())
+				events, hash := decompressed[:len(decompressed)-sha256.Size], decompressed[len(decompressed)-sha256.Size:]
+				computedHash := sha256.Sum256(events)
+				Expect(hash).Should(Equal(computedHash[:]))
+				Expect(string(events)).Should(ContainSubstring(base64.StdEncoding.EncodeToString([]byte("bar"))))
+
+				// the garbage collection checks the delta snapshots with their dictionary
+				list, err := store.List()
+				Expect(err).ShouldNot(HaveOccurred())
+				deleted, _ := ssr.GarbageCollectInvalidDeltaSnapshots(list)
+				Expect(deleted).Should(BeZero())
+			})
+
+			It("should delete the compression dictionaries which none of the delta snapshots reference during the garbage collection", func() {
+				gcPeriod := 2 * time.Second
+				snapshotterConfig.GarbageCollectionPeriod.Duration = gcPeriod
+				snapshotterConfig.GarbageCollectionPolicy = brtypes.GarbageCollectionPolicyLimitBased
+				snapshotterConfig.MaxBackups = 2
+
+				// setDictionarySavedOn sets the time the given dictionary was saved on in the snapstore, whose clock
+				// need not agree with the one of the snapshotter.
+				setDictionarySavedOn := func(id uint32, savedOn time.Time) {
+					dictionaryPaths, err := filepath.Glob(path.Join(snapstoreConfig.Container, "*", fmt.Sprintf("%s%08x", brtypes.CompressionDictionaryPrefix, id)))
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(dictionaryPaths).Should(HaveLen(1))
+					Expect(os.Chtimes(dictionaryPaths[0], savedOn, savedOn)).To(Succeed())
+				}
+
+				// the stale dictionary looks recent to the snapstore, yet no delta snapshot references it
+				staleID, err := snapstore.SaveCompressionDictionary(store, []byte("stale dictionary"))
+				Expect(err).ShouldNot(HaveOccurred())
+				setDictionarySavedOn(staleID, time.Now().Add(time.Hour))
+
+				ssr := newSnapshotter()
+				takeChain(ssr, 100)
+				takeChain(ssr, 110)
+				retainedDeltaSnap := takeChain(ssr, 120)
+				retainedID, ok, err := compressor.ReadDictionaryID(bytes.NewReader(fetchSnapshot(retainedDeltaSnap)))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(ok).Should(BeTrue())
+				// the dictionary of the retained delta snapshot looks older than all the full snapshots to the snapstore
+				setDictionarySavedOn(retainedID, time.Now().Add(-time.Hour))
+				dictionaries, err := snapstore.ListCompressionDictionaries(store)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(dictionaries).Should(HaveLen(3))
+
+				// the garbage collection of a restarted snapshotter, which has no dictionary in use yet, only keeps
+				// the dictionary of the delta snapshot of the latest chain, as the older delta snapshots are deleted
+				restarted := newSnapshotter()
+				gcCtx, cancel := context.WithTimeout(testCtx, gcPeriod+time.Second)
+				defer cancel()
+				restarted.RunGarbageCollector(gcCtx.Done())
+
+				dictionaries, err = snapstore.ListCompressionDictionaries(store)
+				Expect(err).ShouldNot(HaveOccurred())
+				var ids []uint32
+				for _, dictionary := range dictionaries {
+					ids = append(ids, dictionary.ID)
+				}
+				Expect(ids).Should(ConsistOf(retainedID))
+			})
+		})
+
+		Describe("recording the cluster metadata", func() {
+			var cc *mockfactory.MockClusterCloser
+
+			BeforeEach(func() {
+				snapshotterConfig.RecordClusterMetadata = true
+				cc = mockfactory.NewMockClusterCloser(ctrl)
+				factory.EXPECT().NewCluster().Return(cc, nil).AnyTimes()
+				cc.EXPECT().Close().AnyTimes()
+			})
+
+			It("should save the members of the etcd cluster along with the full snapshot", func() {
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 100}}, nil)
+				cm.EXPECT().Snapshot(gomock.Any()).Return(io.NopCloser(strings.NewReader("dummy-full-snapshot")), nil)
+				cc.EXPECT().MemberList(gomock.Any()).Return(&clientv3.MemberListResponse{
+					Header: &etcdserverpb.ResponseHeader{ClusterId: 0x1c1ef1ab2e5b3a7d},
+					Members: []*etcdserverpb.Member{
+						{ID: 0x8e9e05c52164694d, Name: "etcd-main-0", PeerURLs: []string{"http://etcd-main-0:2380"}, ClientURLs: []string{"http://etcd-main-0:2379"}},
+						{ID: 0x91bc3c398fb3c146, Name: "etcd-main-1", PeerURLs: []string{"http://etcd-main-1:2380"}, IsLearner: true},
+					},
+				}, nil)
+
+				ssr := newSnapshotter()
+				snap, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				metadata, err := snapstore.FetchClusterMetadata(store, snap)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(metadata).Should(Equal(&brtypes.ClusterMetadata{
+					ClusterID: 0x1c1ef1ab2e5b3a7d,
+					Members: []brtypes.ClusterMember{
+						{ID: 0x8e9e05c52164694d, Name: "etcd-main-0", PeerURLs: []string{"http://etcd-main-0:2380"}, ClientURLs: []string{"http://etcd-main-0:2379"}},
+						{ID: 0x91bc3c398fb3c146, Name: "etcd-main-1", PeerURLs: []string{"http://etcd-main-1:2380"}, IsLearner: true},
+					},
+				}))
+			})
+
+			It("should take the full snapshot even if the cluster metadata can't be recorded", func() {
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 100}}, nil)
+				cm.EXPECT().Snapshot(gomock.Any()).Return(io.NopCloser(strings.NewReader("dummy-full-snapshot")), nil)
+				cc.EXPECT().MemberList(gomock.Any()).Return(nil, fmt.Errorf("etcdserver: request timed out"))
+
+				ssr := newSnapshotter()
+				snap, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(readSnapshot(snap)).Should(Equal([]byte("dummy-full-snapshot")))
+				Expect(snapstore.FetchClusterMetadata(store, snap)).Should(BeNil())
+			})
+		})
+
+		Describe("emitting the Kubernetes events", func() {
+			var recorder *events.FakeRecorder
+
+			BeforeEach(func() {
+				recorder = events.NewFakeRecorder()
+			})
+
+			It("should emit a normal event for a saved full snapshot", func() {
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 100}}, nil)
+				cm.EXPECT().Snapshot(gomock.Any()).Return(io.NopCloser(strings.NewReader("dummy-full-snapshot")), nil)
+				ssr := newSnapshotter()
+				ssr.EventRecorder = recorder
+
+				snap, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(recorder.Events()).Should(ConsistOf(
+					fmt.Sprintf("%s %s Saved full snapshot %s at revision 100", corev1.EventTypeNormal, events.ReasonFullSnapshotSucceeded, path.Join(snap.SnapDir, snap.SnapName)),
+				))
+			})
+
+			It("should emit a warning event for a failed full snapshot", func() {
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(nil, errors.New("unavailable"))
+				ssr := newSnapshotter()
+				ssr.EventRecorder = recorder
+
+				_, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+				Expect(err).Should(HaveOccurred())
+				Expect(recorder.Events()).Should(HaveLen(1))
+				Expect(recorder.Events()[0]).Should(HavePrefix(fmt.Sprintf("%s %s ", corev1.EventTypeWarning, events.ReasonFullSnapshotFailed)))
+				Expect(recorder.Events()[0]).Should(ContainSubstring("unavailable"))
+			})
+
+			It("should emit a warning event for a failed delta snapshot", func() {
+				quotaStore := &quotaExceededStore{SnapStore: store}
+				store = quotaStore
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 100}}, nil)
+				cm.EXPECT().Snapshot(gomock.Any()).Return(io.NopCloser(strings.NewReader("dummy-full-snapshot")), nil)
+				ssr := newSnapshotter()
+				ssr.EventRecorder = recorder
+				_, err := ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				ckv.EXPECT().Get(gomock.Any(), "", gomock.Any()).Return(&clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 101}}, nil)
+				watcher.watchCh <- clientv3.WatchResponse{Events: []*clientv3.Event{putEvent("foo", 101)}}
+				_, err = ssr.CollectEventsSincePrevSnapshot(testCtx, testCtx.Done())
+				Expect(err).ShouldNot(HaveOccurred())
+
+				quotaStore.exceeded.Store(true)
+				_, err = ssr.TakeDeltaSnapshot(testCtx)
+				Expect(err).Should(HaveOccurred())
+				Expect(recorder.Events()).Should(HaveLen(2))
+				Expect(recorder.Events()[1]).Should(HavePrefix(fmt.Sprintf("%s %s ", corev1.EventTypeWarning, events.ReasonDeltaSnapshotFailed)))
+				Expect(recorder.Events()[1]).Should(ContainSubstring("QuotaExceeded"))
+			})
+		})
+	})
+
+	Describe("reloading the snapstore credentials", func() {
+		var credentialsDir string
+
+		BeforeEach(func() {
+			credentialsDir = path.Join(outputDir, "snapstore_credentials")
+			Expect(os.MkdirAll(credentialsDir, 0700)).To(Succeed())
+			Expect(os.Setenv("AWS_APPLICATION_CREDENTIALS", credentialsDir)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(os.Unsetenv("AWS_APPLICATION_CREDENTIALS")).To(Succeed())
+			Expect(os.RemoveAll(credentialsDir)).To(Succeed())
+		})
+
+		It("should fail the snapshot with a snapstore credential error if the credential files have disappeared", func() {
+			snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_credentials.bkp")}
+			store, err = snapstore.GetSnapstore(snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			// the credentials of the S3 snapstore are checked, while the snapshots are saved in the local snapstore
+			snapstoreConfig.Provider = brtypes.SnapstoreProviderS3
+			snapshotterConfig := NewSnapshotterConfig()
+			snapshotterConfig.DeltaSnapshotPeriod.Duration = 0
+			ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			failedReloads := metrics.SnapstoreCredentialReloadTotal.With(prometheus.Labels{metrics.LabelSucceeded: metrics.ValueSucceededFalse})
+			succeededReloads := metrics.SnapstoreCredentialReloadTotal.With(prometheus.Labels{metrics.LabelSucceeded: metrics.ValueSucceededTrue})
+			initialFailedReloads := testutil.ToFloat64(failedReloads)
+			initialSucceededReloads := testutil.ToFloat64(succeededReloads)
+
+			_, err = ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+			Expect(err).Should(HaveOccurred())
+			var credentialErr *brerrors.SnapstoreCredentialError
+			Expect(errors.As(err, &credentialErr)).Should(BeTrue())
+			Expect(err).ShouldNot(BeAssignableToTypeOf(&brerrors.EtcdError{}))
+			Expect(testutil.ToFloat64(failedReloads) - initialFailedReloads).Should(Equal(float64(1)))
+			Expect(testutil.ToFloat64(succeededReloads)).Should(Equal(initialSucceededReloads))
+
+			// the reload is retried before the next snapshot
+			_, err = ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+			Expect(errors.As(err, &credentialErr)).Should(BeTrue())
+			Expect(testutil.ToFloat64(failedReloads) - initialFailedReloads).Should(Equal(float64(2)))
+
+			snapList, err := store.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(snapList).Should(BeEmpty())
+		})
+	})
+
 	Describe("running snapshotter", func() {
 		Context("with etcd not running at configured endpoint", func() {
 			BeforeEach(func() {
@@ -249,9 +2409,91 @@ var _ = Describe("Snapshotter", func() {
 						ssr, err = NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
 						Expect(err).ShouldNot(HaveOccurred())
 
-						_, err = ssr.TriggerDeltaSnapshot()
+						_, err = ssr.TriggerDeltaSnapshot(testCtx)
 						Expect(err).Should(HaveOccurred())
 					})
+
+					It("should rebuild the etcd client factory only when the etcd credentials are rotated", func() {
+						snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_credentials.bkp")}
+						store, err = snapstore.GetSnapstore(snapstoreConfig)
+						Expect(err).ShouldNot(HaveOccurred())
+						snapshotterConfig := &brtypes.SnapshotterConfig{
+							FullSnapshotSchedule:     schedule,
+							DeltaSnapshotPeriod:      wrappers.Duration{Duration: deltaSnapshotInterval},
+							DeltaSnapshotMemoryLimit: brtypes.DefaultDeltaSnapMemoryLimit,
+							GarbageCollectionPeriod:  wrappers.Duration{Duration: garbageCollectionPeriod},
+							GarbageCollectionPolicy:  brtypes.GarbageCollectionPolicyExponential,
+							MaxBackups:               maxBackups,
+						}
+						etcdConnectionConfig.UsernameFile = "username"
+						etcdConnectionConfig.PasswordFile = "password"
+
+						ssr, err = NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+						Expect(err).ShouldNot(HaveOccurred())
+
+						credentialsModifiedTime := time.Now()
+						ssr.CredentialsModTimeFunc = func(string) (time.Time, error) {
+							return credentialsModifiedTime, nil
+						}
+						factoryBuilds := 0
+						ssr.NewClientFactory = func(cfg brtypes.EtcdConnectionConfig, opts ...etcdClient.Option) etcdClient.Factory {
+							factoryBuilds++
+							// the etcd used by the tests does not have authentication enabled
+							cfg.UsernameFile, cfg.PasswordFile = "", ""
+							return etcdutil.NewFactory(cfg, opts...)
+						}
+
+						_, err = ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(factoryBuilds).Should(Equal(1))
+
+						_, err = ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(factoryBuilds).Should(Equal(1))
+
+						// rotate the credentials
+						credentialsModifiedTime = credentialsModifiedTime.Add(time.Minute)
+						_, err = ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(factoryBuilds).Should(Equal(2))
+					})
+
+					It("should scale the full snapshot timeout by the size of the previous full snapshot", func() {
+						snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_timeout.bkp")}
+						store, err = snapstore.GetSnapstore(snapstoreConfig)
+						Expect(err).ShouldNot(HaveOccurred())
+						snapshotterConfig := &brtypes.SnapshotterConfig{
+							FullSnapshotSchedule:     schedule,
+							DeltaSnapshotPeriod:      wrappers.Duration{Duration: deltaSnapshotInterval},
+							DeltaSnapshotMemoryLimit: brtypes.DefaultDeltaSnapMemoryLimit,
+							GarbageCollectionPeriod:  wrappers.Duration{Duration: garbageCollectionPeriod},
+							GarbageCollectionPolicy:  brtypes.GarbageCollectionPolicyExponential,
+							MaxBackups:               maxBackups,
+						}
+						etcdConnectionConfig.SnapshotTimeout.Duration = time.Minute
+						etcdConnectionConfig.SnapshotTimeoutPerGB.Duration = 1024 * time.Hour
+
+						ssr, err = NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(ssr.GetFullSnapshotTimeout()).Should(Equal(time.Minute))
+
+						_, err = ssr.TakeFullSnapshotAndResetTimer(testCtx, false)
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(ssr.PrevFullSnapshot.SizeBytes).Should(BeNumerically(">", 0))
+						// 1024 hours per GiB amount to about 3.5 seconds per KiB
+						expectedTimeout := time.Minute + time.Duration(float64(1024*time.Hour)*float64(ssr.PrevFullSnapshot.SizeBytes)/(1<<30))
+						Expect(ssr.GetFullSnapshotTimeout()).Should(Equal(expectedTimeout))
+						Expect(ssr.GetFullSnapshotTimeout()).Should(BeNumerically(">", time.Minute))
+
+						// the size of the previous full snapshot is taken from the snapstore after a restart
+						restartedSsr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+						Expect(err).ShouldNot(HaveOccurred())
+						storedSize, err := snapstore.SnapshotSize(store, *restartedSsr.PrevFullSnapshot)
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(restartedSsr.PrevFullSnapshot.SnapName).Should(Equal(ssr.PrevFullSnapshot.SnapName))
+						Expect(restartedSsr.PrevFullSnapshot.SizeBytes).Should(Equal(storedSize))
+						Expect(restartedSsr.GetFullSnapshotTimeout()).Should(BeNumerically(">", time.Minute))
+					})
 				})
 
 				Context("with delta snapshots enabled", func() {
@@ -293,40 +2535,420 @@ var _ = Describe("Snapshotter", func() {
 						})
 					})
 
-					Context("with snapshotter starting with full snapshot", func() {
-						It("should take periodic backups", func() {
-							snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_6.bkp")}
-							store, err = snapstore.GetSnapstore(snapstoreConfig)
-							Expect(err).ShouldNot(HaveOccurred())
-							snapshotterConfig := &brtypes.SnapshotterConfig{
-								FullSnapshotSchedule:     schedule,
-								DeltaSnapshotPeriod:      wrappers.Duration{Duration: deltaSnapshotInterval},
-								DeltaSnapshotMemoryLimit: brtypes.DefaultDeltaSnapMemoryLimit,
-								GarbageCollectionPeriod:  wrappers.Duration{Duration: garbageCollectionPeriod},
-								GarbageCollectionPolicy:  brtypes.GarbageCollectionPolicyExponential,
-								MaxBackups:               maxBackups,
-							}
+					Context("with snapshotter starting with full snapshot", func() {
+						It("should take periodic backups", func() {
+							snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_6.bkp")}
+							store, err = snapstore.GetSnapstore(snapstoreConfig)
+							Expect(err).ShouldNot(HaveOccurred())
+							snapshotterConfig := &brtypes.SnapshotterConfig{
+								FullSnapshotSchedule:     schedule,
+								DeltaSnapshotPeriod:      wrappers.Duration{Duration: deltaSnapshotInterval},
+								DeltaSnapshotMemoryLimit: brtypes.DefaultDeltaSnapMemoryLimit,
+								GarbageCollectionPeriod:  wrappers.Duration{Duration: garbageCollectionPeriod},
+								GarbageCollectionPolicy:  brtypes.GarbageCollectionPolicyExponential,
+								MaxBackups:               maxBackups,
+							}
+
+							populatorCtx, cancelPopulator := context.WithTimeout(testCtx, testTimeout)
+							defer cancelPopulator()
+							wg := &sync.WaitGroup{}
+							wg.Add(1)
+							// populating etcd so that snapshots will be taken
+							go utils.PopulateEtcdWithWaitGroup(populatorCtx, wg, logger, etcdConnectionConfig.Endpoints, nil)
+
+							ssr, err = NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+							Expect(err).ShouldNot(HaveOccurred())
+							ssrCtx := utils.ContextWithWaitGroup(testCtx, wg)
+							err = ssr.Run(ssrCtx.Done(), true)
+
+							Expect(err).ShouldNot(HaveOccurred())
+							list, err := store.List()
+							Expect(err).ShouldNot(HaveOccurred())
+							Expect(len(list)).ShouldNot(BeZero())
+							Expect(list[0].Kind).Should(Equal(brtypes.SnapshotKindFull))
+						})
+					})
+
+					Context("with the previous full snapshot deleted from the snapstore", func() {
+						It("should take a new full snapshot", func() {
+							snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_base_check.bkp")}
+							store, err = snapstore.GetSnapstore(snapstoreConfig)
+							Expect(err).ShouldNot(HaveOccurred())
+							snapshotterConfig := &brtypes.SnapshotterConfig{
+								FullSnapshotSchedule:     "0 0 1 1 *", // This makes sure that the full snapshot timer doesn't trigger a full snapshot.
+								DeltaSnapshotPeriod:      wrappers.Duration{Duration: deltaSnapshotInterval},
+								DeltaSnapshotMemoryLimit: brtypes.DefaultDeltaSnapMemoryLimit,
+								GarbageCollectionPeriod:  wrappers.Duration{Duration: garbageCollectionPeriod},
+								GarbageCollectionPolicy:  brtypes.GarbageCollectionPolicyExponential,
+								MaxBackups:               maxBackups,
+								BaseSnapshotCheckPeriod:  wrappers.Duration{Duration: 2 * time.Second},
+							}
+
+							ssr, err = NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+							Expect(err).ShouldNot(HaveOccurred())
+
+							ssrCtx, cancelSsr := context.WithTimeout(testCtx, time.Minute)
+							defer cancelSsr()
+							ssrErrCh := make(chan error, 1)
+							go func() {
+								ssrErrCh <- ssr.Run(ssrCtx.Done(), true)
+							}()
+
+							var baseSnapshot *brtypes.Snapshot
+							Eventually(func() *brtypes.Snapshot {
+								baseSnapshot = getLatestFullSnapshot(store)
+								return baseSnapshot
+							}, 30*time.Second, 500*time.Millisecond).ShouldNot(BeNil())
+							Expect(store.Delete(*baseSnapshot)).To(Succeed())
+
+							Eventually(func() string {
+								if s := getLatestFullSnapshot(store); s != nil {
+									return s.SnapName
+								}
+								return ""
+							}, 30*time.Second, 500*time.Millisecond).ShouldNot(Or(BeEmpty(), Equal(baseSnapshot.SnapName)))
+
+							cancelSsr()
+							Eventually(ssrErrCh, 30*time.Second).Should(Receive(BeNil()))
+						})
+					})
+
+					Context("with the watch stalling below the latest etcd revision", func() {
+						var snapshotterConfig *brtypes.SnapshotterConfig
+
+						BeforeEach(func() {
+							snapshotterConfig = &brtypes.SnapshotterConfig{
+								FullSnapshotSchedule:         "0 0 1 1 *", // This makes sure that the full snapshot timer doesn't trigger a full snapshot.
+								DeltaSnapshotPeriod:          wrappers.Duration{Duration: deltaSnapshotInterval},
+								DeltaSnapshotMemoryLimit:     brtypes.DefaultDeltaSnapMemoryLimit,
+								GarbageCollectionPeriod:      wrappers.Duration{Duration: garbageCollectionPeriod},
+								GarbageCollectionPolicy:      brtypes.GarbageCollectionPolicyExponential,
+								MaxBackups:                   maxBackups,
+								DeltaEventsCollectionTimeout: wrappers.Duration{Duration: 2 * time.Second},
+							}
+							// make sure that etcd is ahead of the dummy previous snapshot of an empty snapstore
+							populatorResp := &utils.EtcdDataPopulationResponse{}
+							utils.PopulateEtcd(testCtx, logger, etcdConnectionConfig.Endpoints, 0, 1, populatorResp)
+							Expect(populatorResp.Err).ShouldNot(HaveOccurred())
+						})
+
+						It("should time out collecting the events since the previous snapshot", func() {
+							snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_stalled_watch.bkp")}
+							store, err = snapstore.GetSnapstore(snapstoreConfig)
+							Expect(err).ShouldNot(HaveOccurred())
+
+							ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+							Expect(err).ShouldNot(HaveOccurred())
+							ssr.NewClientFactory = newStallingWatchFactory
+
+							timeouts := metrics.DeltaEventsCollectionTimeoutsTotal.With(prometheus.Labels{})
+							initialTimeouts := testutil.ToFloat64(timeouts)
+							ssrStopped, err := ssr.CollectEventsSincePrevSnapshot(testCtx, testCtx.Done())
+							Expect(ssrStopped).Should(BeFalse())
+							Expect(err).Should(MatchError(ErrDeltaEventsCollectionTimeout))
+							Expect(testutil.ToFloat64(timeouts) - initialTimeouts).Should(Equal(float64(1)))
+						})
+
+						It("should take a full snapshot instead of the first delta snapshot", func() {
+							snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_stalled_watch_recovery.bkp")}
+							store, err = snapstore.GetSnapstore(snapstoreConfig)
+							Expect(err).ShouldNot(HaveOccurred())
+
+							ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+							Expect(err).ShouldNot(HaveOccurred())
+							ssr.NewClientFactory = newStallingWatchFactory
+
+							ssrCtx, cancelSsr := context.WithTimeout(testCtx, time.Minute)
+							defer cancelSsr()
+							ssrErrCh := make(chan error, 1)
+							go func() {
+								ssrErrCh <- ssr.Run(ssrCtx.Done(), false)
+							}()
+
+							Eventually(func() *brtypes.Snapshot {
+								return getLatestFullSnapshot(store)
+							}, 30*time.Second, 500*time.Millisecond).ShouldNot(BeNil())
+
+							cancelSsr()
+							Eventually(ssrErrCh, 30*time.Second).Should(Receive(BeNil()))
+							list, err := store.List()
+							Expect(err).ShouldNot(HaveOccurred())
+							Expect(list[0].Kind).Should(Equal(brtypes.SnapshotKindFull))
+						})
+					})
+				})
+			})
+		})
+
+		Context("with the full snapshots failing", func() {
+			var snapshotterConfig *brtypes.SnapshotterConfig
+
+			BeforeEach(func() {
+				etcdConnectionConfig.Endpoints = []string{etcd.Clients[0].Addr().String()}
+				snapshotterConfig = &brtypes.SnapshotterConfig{
+					FullSnapshotSchedule:               "0 0 1 1 *", // This makes sure that only the retries trigger the full snapshots.
+					DeltaSnapshotPeriod:                wrappers.Duration{Duration: time.Minute},
+					DeltaSnapshotMemoryLimit:           brtypes.DefaultDeltaSnapMemoryLimit,
+					GarbageCollectionPeriod:            wrappers.Duration{Duration: garbageCollectionPeriod},
+					GarbageCollectionPolicy:            brtypes.GarbageCollectionPolicyExponential,
+					MaxBackups:                         maxBackups,
+					MaxConsecutiveFullSnapshotFailures: 3,
+				}
+			})
+
+			It("should retry the full snapshot with a backoff and fail after the maximum consecutive failures", func() {
+				snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_failing_full.bkp")}
+				localStore, err := snapstore.GetSnapstore(snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				store = &failingFullSnapshotStore{SnapStore: localStore, failures: -1}
+
+				ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				ctx, cancel := context.WithTimeout(testCtx, time.Minute)
+				defer cancel()
+				start := time.Now()
+				err = ssr.Run(ctx.Done(), true)
+				Expect(err).Should(MatchError(ContainSubstring("3 consecutive full snapshots failed")))
+				// the full snapshot is retried after 2s and 4s before failing hard
+				Expect(time.Since(start)).Should(BeNumerically(">=", 6*time.Second))
+				Expect(store.(*failingFullSnapshotStore).attempts).Should(Equal(3))
+				Expect(testutil.ToFloat64(metrics.FullSnapshotConsecutiveFailures.With(prometheus.Labels{}))).Should(Equal(float64(3)))
+			})
+
+			It("should return the error as is if it didn't originate from a failed full snapshot", func() {
+				snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_unwrapped_full.bkp")}
+				store, err = snapstore.GetSnapstore(snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				resetErr := fmt.Errorf("failed to reset the full snapshot timer")
+				Expect(ssr.RetryFullSnapshotWithBackoff(0, resetErr)).Should(BeIdenticalTo(resetErr))
+				Expect(ssr.RetryFullSnapshotWithBackoff(3, resetErr)).Should(MatchError(ContainSubstring("3 consecutive full snapshots failed")))
+			})
+
+			It("should take the full snapshot once the snapstore recovers", func() {
+				snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_recovering_full.bkp")}
+				localStore, err := snapstore.GetSnapstore(snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				store = &failingFullSnapshotStore{SnapStore: localStore, failures: 2}
+
+				ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				ssrCtx, cancelSsr := context.WithTimeout(testCtx, time.Minute)
+				defer cancelSsr()
+				ssrErrCh := make(chan error, 1)
+				go func() {
+					ssrErrCh <- ssr.Run(ssrCtx.Done(), true)
+				}()
+
+				Eventually(func() *brtypes.Snapshot {
+					return getLatestFullSnapshot(localStore)
+				}, 30*time.Second, 500*time.Millisecond).ShouldNot(BeNil())
+
+				cancelSsr()
+				Eventually(ssrErrCh, 30*time.Second).Should(Receive(BeNil()))
+				Expect(testutil.ToFloat64(metrics.FullSnapshotConsecutiveFailures.With(prometheus.Labels{}))).Should(Equal(float64(0)))
+			})
+		})
+
+		Context("with the delta snapshots uploaded concurrently", func() {
+			It("should upload the piled up delta snapshots in parallel and record them in the order of their revisions", func() {
+				etcdConnectionConfig.Endpoints = []string{etcd.Clients[0].Addr().String()}
+				snapshotterConfig := &brtypes.SnapshotterConfig{
+					FullSnapshotSchedule:            "0 0 1 1 *", // This makes sure that only the first full snapshot is taken.
+					DeltaSnapshotPeriod:             wrappers.Duration{Duration: time.Minute},
+					DeltaSnapshotMemoryLimit:        1024, // This makes sure that the delta snapshots pile up.
+					GarbageCollectionPeriod:         wrappers.Duration{Duration: garbageCollectionPeriod},
+					GarbageCollectionPolicy:         brtypes.GarbageCollectionPolicyExponential,
+					MaxBackups:                      maxBackups,
+					MaxParallelDeltaSnapshotUploads: 4,
+				}
+				snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_parallel_deltas.bkp")}
+				localStore, err := snapstore.GetSnapstore(snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				slowStore := &slowDeltaSnapshotStore{SnapStore: localStore, delay: 300 * time.Millisecond}
+
+				ssr, err := NewSnapshotter(logger, snapshotterConfig, slowStore, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				clientKV, err := etcdutil.NewFactory(*etcdConnectionConfig).NewKV()
+				Expect(err).ShouldNot(HaveOccurred())
+				defer clientKV.Close()
+
+				stopCh := make(chan struct{})
+				ssrErrCh := make(chan error, 1)
+				go func() {
+					ssrErrCh <- ssr.Run(stopCh, true)
+				}()
+				Eventually(func() *brtypes.Snapshot {
+					return getLatestFullSnapshot(localStore)
+				}, 30*time.Second, 100*time.Millisecond).ShouldNot(BeNil())
+
+				// the keys are put in a burst once the events are watched, so that the events pile up beyond the memory limit
+				for i := 0; i < 100; i++ {
+					_, err := clientKV.Put(testCtx, fmt.Sprintf("parallel-delta-key-%d", i), strings.Repeat(fmt.Sprintf("value-%d", i), 50))
+					Expect(err).ShouldNot(HaveOccurred())
+				}
+				time.Sleep(time.Second)
+				close(stopCh)
+				Eventually(ssrErrCh, 30*time.Second).Should(Receive(BeNil()))
+
+				Expect(slowStore.maxInFlight).Should(BeNumerically(">", 1))
+				Expect(slowStore.maxInFlight).Should(BeNumerically("<=", 4))
+
+				// the delta snapshots are chained to the full snapshot in the order of their revisions
+				Expect(len(ssr.PrevDeltaSnapshots)).Should(BeNumerically(">", 1))
+				prevSnap := ssr.PrevFullSnapshot
+				for _, snap := range ssr.PrevDeltaSnapshots {
+					Expect(snap.StartRevision).Should(Equal(prevSnap.LastRevision + 1))
+					prevSnap = snap
+				}
+				Expect(ssr.PrevSnapshot).Should(Equal(prevSnap))
+
+				list, err := localStore.List()
+				Expect(err).ShouldNot(HaveOccurred())
+				var deltaSnapshots brtypes.SnapList
+				for _, snap := range list {
+					if snap.Kind == brtypes.SnapshotKindDelta {
+						deltaSnapshots = append(deltaSnapshots, snap)
+					}
+				}
+				Expect(deltaSnapshots).Should(HaveLen(len(ssr.PrevDeltaSnapshots)))
+				for i, snap := range deltaSnapshots {
+					Expect(snap.SnapName).Should(Equal(ssr.PrevDeltaSnapshots[i].SnapName))
+				}
+			})
+
+			It("should take the delta snapshots again from the start revision of a delta snapshot whose upload failed", func() {
+				etcdConnectionConfig.Endpoints = []string{etcd.Clients[0].Addr().String()}
+				snapshotterConfig := &brtypes.SnapshotterConfig{
+					FullSnapshotSchedule:            "0 0 1 1 *", // This makes sure that only the first full snapshot is taken.
+					DeltaSnapshotPeriod:             wrappers.Duration{Duration: time.Minute},
+					DeltaSnapshotMemoryLimit:        1024, // This makes sure that the delta snapshots pile up.
+					GarbageCollectionPeriod:         wrappers.Duration{Duration: garbageCollectionPeriod},
+					GarbageCollectionPolicy:         brtypes.GarbageCollectionPolicyExponential,
+					MaxBackups:                      maxBackups,
+					MaxParallelDeltaSnapshotUploads: 4,
+				}
+				snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_failed_parallel_deltas.bkp")}
+				localStore, err := snapstore.GetSnapstore(snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				failingStore := &failingDeltaSnapshotStore{SnapStore: localStore, delay: 300 * time.Millisecond, failAt: 3}
+
+				ssr, err := NewSnapshotter(logger, snapshotterConfig, failingStore, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				clientKV, err := etcdutil.NewFactory(*etcdConnectionConfig).NewKV()
+				Expect(err).ShouldNot(HaveOccurred())
+				defer clientKV.Close()
+
+				ssrErrCh := make(chan error, 1)
+				go func() {
+					ssrErrCh <- ssr.Run(make(chan struct{}), true)
+				}()
+				Eventually(func() *brtypes.Snapshot {
+					return getLatestFullSnapshot(localStore)
+				}, 30*time.Second, 100*time.Millisecond).ShouldNot(BeNil())
+
+				for i := 0; i < 100; i++ {
+					_, err := clientKV.Put(testCtx, fmt.Sprintf("failed-parallel-delta-key-%d", i), strings.Repeat(fmt.Sprintf("value-%d", i), 50))
+					Expect(err).ShouldNot(HaveOccurred())
+				}
+				Eventually(ssrErrCh, 30*time.Second).Should(Receive(MatchError(ContainSubstring("failed to upload delta snapshot"))))
+
+				// the previous snapshot is rolled back to the one recorded right before the failed delta snapshot
+				failedSnap := failingStore.failed
+				Expect(failedSnap).ShouldNot(BeNil())
+				Expect(ssr.PrevSnapshot.LastRevision + 1).Should(Equal(failedSnap.StartRevision))
+				Expect(ssr.PrevSnapshot).Should(Equal(ssr.PrevDeltaSnapshots[len(ssr.PrevDeltaSnapshots)-1]))
+
+				// the restarted snapshotter takes the delta snapshots from the start revision of the failed one again
+				stopCh := make(chan struct{})
+				go func() {
+					ssrErrCh <- ssr.Run(stopCh, false)
+				}()
+				Eventually(func() int64 {
+					return ssr.LastRecordedSnapshot().LastRevision
+				}, 30*time.Second, 100*time.Millisecond).Should(BeNumerically(">=", failedSnap.LastRevision))
+				close(stopCh)
+				Eventually(ssrErrCh, 30*time.Second).Should(Receive(BeNil()))
+
+				list, err := localStore.List()
+				Expect(err).ShouldNot(HaveOccurred())
+				prevSnap := getLatestFullSnapshot(localStore)
+				for _, snap := range list {
+					if snap.Kind == brtypes.SnapshotKindDelta {
+						Expect(snap.StartRevision).Should(Equal(prevSnap.LastRevision + 1))
+						prevSnap = snap
+					}
+				}
+				Expect(prevSnap.LastRevision).Should(BeNumerically(">=", failedSnap.LastRevision))
+			})
 
-							populatorCtx, cancelPopulator := context.WithTimeout(testCtx, testTimeout)
-							defer cancelPopulator()
-							wg := &sync.WaitGroup{}
-							wg.Add(1)
-							// populating etcd so that snapshots will be taken
-							go utils.PopulateEtcdWithWaitGroup(populatorCtx, wg, logger, etcdConnectionConfig.Endpoints, nil)
+			It("should not garbage collect the chunks of a delta snapshot which is still being uploaded", func() {
+				etcdConnectionConfig.Endpoints = []string{etcd.Clients[0].Addr().String()}
+				snapshotterConfig := &brtypes.SnapshotterConfig{
+					FullSnapshotSchedule:            "0 0 1 1 *", // This makes sure that only the first full snapshot is taken.
+					DeltaSnapshotPeriod:             wrappers.Duration{Duration: time.Minute},
+					DeltaSnapshotMemoryLimit:        1024, // This makes sure that the delta snapshots are uploaded in the background.
+					GarbageCollectionPeriod:         wrappers.Duration{Duration: garbageCollectionPeriod},
+					GarbageCollectionPolicy:         brtypes.GarbageCollectionPolicyExponential,
+					MaxBackups:                      maxBackups,
+					MaxParallelDeltaSnapshotUploads: 4,
+				}
+				snapstoreConfig = &brtypes.SnapstoreConfig{Container: path.Join(outputDir, "snapshotter_inflight_deltas.bkp")}
+				localStore, err := snapstore.GetSnapstore(snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				blockingStore := &blockingDeltaSnapshotStore{
+					SnapStore: localStore,
+					saving:    make(chan brtypes.Snapshot, 1),
+					release:   make(chan struct{}),
+				}
 
-							ssr, err = NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
-							Expect(err).ShouldNot(HaveOccurred())
-							ssrCtx := utils.ContextWithWaitGroup(testCtx, wg)
-							err = ssr.Run(ssrCtx.Done(), true)
+				ssr, err := NewSnapshotter(logger, snapshotterConfig, blockingStore, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
 
-							Expect(err).ShouldNot(HaveOccurred())
-							list, err := store.List()
-							Expect(err).ShouldNot(HaveOccurred())
-							Expect(len(list)).ShouldNot(BeZero())
-							Expect(list[0].Kind).Should(Equal(brtypes.SnapshotKindFull))
-						})
-					})
-				})
+				clientKV, err := etcdutil.NewFactory(*etcdConnectionConfig).NewKV()
+				Expect(err).ShouldNot(HaveOccurred())
+				defer clientKV.Close()
+
+				stopCh := make(chan struct{})
+				ssrErrCh := make(chan error, 1)
+				go func() {
+					ssrErrCh <- ssr.Run(stopCh, true)
+				}()
+				Eventually(func() *brtypes.Snapshot {
+					return getLatestFullSnapshot(localStore)
+				}, 30*time.Second, 100*time.Millisecond).ShouldNot(BeNil())
+				fullSnap := getLatestFullSnapshot(localStore)
+
+				for i := 0; i < 20; i++ {
+					_, err := clientKV.Put(testCtx, fmt.Sprintf("inflight-delta-key-%d", i), strings.Repeat(fmt.Sprintf("value-%d", i), 50))
+					Expect(err).ShouldNot(HaveOccurred())
+				}
+				var inFlightSnap brtypes.Snapshot
+				Eventually(blockingStore.saving, 30*time.Second).Should(Receive(&inFlightSnap))
+
+				// the chunks of the delta snapshot being uploaded are present in the snapstore, as well as those of the
+				// recorded full snapshot, whose names must not clash with the snapshots themselves
+				Expect(addObjectsToStore(localStore, "Chunk", brtypes.SnapshotKindFull, int(fullSnap.StartRevision), int(fullSnap.LastRevision), 2, fullSnap.CreatedOn.Add(time.Second))).To(Succeed())
+				Expect(addObjectsToStore(localStore, "Chunk", brtypes.SnapshotKindDelta, int(inFlightSnap.StartRevision), int(inFlightSnap.LastRevision), 3, inFlightSnap.CreatedOn.Add(time.Second))).To(Succeed())
+				list, err := localStore.List()
+				Expect(err).ShouldNot(HaveOccurred())
+
+				deletedCount, _ := ssr.GarbageCollectChunks(list)
+				Expect(deletedCount).Should(Equal(2))
+				chunkCount, _, err := getObjectCount(localStore)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(chunkCount).Should(Equal(3))
+
+				close(blockingStore.release)
+				close(stopCh)
+				Eventually(ssrErrCh, 30*time.Second).Should(Receive(BeNil()))
 			})
 		})
 
@@ -422,6 +3044,138 @@ var _ = Describe("Snapshotter", func() {
 				}
 			})
 
+			It("should not garbage collect the retained snapshots", func() {
+				now := time.Now().UTC()
+				store, snapstoreConfig := prepareStoreForGarbageCollection(now, "garbagecollector_retained.bkp", "v2")
+				snapshotterConfig := &brtypes.SnapshotterConfig{
+					FullSnapshotSchedule:     schedule,
+					DeltaSnapshotPeriod:      wrappers.Duration{Duration: 10 * time.Second},
+					DeltaSnapshotMemoryLimit: brtypes.DefaultDeltaSnapMemoryLimit,
+					GarbageCollectionPeriod:  wrappers.Duration{Duration: garbageCollectionPeriod},
+					GarbageCollectionPolicy:  brtypes.GarbageCollectionPolicyLimitBased,
+					MaxBackups:               maxBackups,
+				}
+				ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				// retain the oldest full snapshot and the oldest delta snapshot, which are garbage otherwise
+				initialList, err := store.List()
+				Expect(err).ShouldNot(HaveOccurred())
+				var retainedFull, retainedDelta *brtypes.Snapshot
+				for _, snap := range initialList {
+					if snap.Kind == brtypes.SnapshotKindFull && retainedFull == nil {
+						retainedFull = snap
+					} else if snap.Kind == brtypes.SnapshotKindDelta && retainedDelta == nil {
+						retainedDelta = snap
+					}
+				}
+				Expect(retainedFull).ShouldNot(BeNil())
+				Expect(retainedDelta).ShouldNot(BeNil())
+				Expect(snapstore.SetSnapshotRetained(store, *retainedFull, true)).To(Succeed())
+				Expect(snapstore.SetSnapshotRetained(store, *retainedDelta, true)).To(Succeed())
+
+				gcCtx, cancel := context.WithTimeout(testCtx, testTimeout)
+				defer cancel()
+				ssr.RunGarbageCollector(gcCtx.Done())
+
+				list, err := store.List()
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(len(list)).Should(BeNumerically("<", len(initialList)))
+				var snapNames []string
+				for _, snap := range list {
+					snapNames = append(snapNames, snap.SnapName)
+				}
+				Expect(snapNames).Should(ContainElements(retainedFull.SnapName, retainedDelta.SnapName))
+
+				// the snapshots are garbage collected once they are no longer retained
+				Expect(snapstore.SetSnapshotRetained(store, *retainedFull, false)).To(Succeed())
+				Expect(snapstore.SetSnapshotRetained(store, *retainedDelta, false)).To(Succeed())
+				gcCtx, cancel = context.WithTimeout(testCtx, testTimeout)
+				defer cancel()
+				ssr.RunGarbageCollector(gcCtx.Done())
+
+				list, err = store.List()
+				Expect(err).ShouldNot(HaveOccurred())
+				snapNames = nil
+				for _, snap := range list {
+					snapNames = append(snapNames, snap.SnapName)
+				}
+				Expect(snapNames).ShouldNot(ContainElement(retainedFull.SnapName))
+				Expect(snapNames).ShouldNot(ContainElement(retainedDelta.SnapName))
+			})
+
+			It("should record the outcome and the deleted snapshots of the garbage collection runs", func() {
+				now := time.Now().UTC()
+				store, snapstoreConfig := prepareStoreForGarbageCollection(now, "garbagecollector_metrics.bkp", "v2")
+				snapshotterConfig := &brtypes.SnapshotterConfig{
+					FullSnapshotSchedule:     schedule,
+					DeltaSnapshotPeriod:      wrappers.Duration{Duration: 10 * time.Second},
+					DeltaSnapshotMemoryLimit: brtypes.DefaultDeltaSnapMemoryLimit,
+					GarbageCollectionPeriod:  wrappers.Duration{Duration: garbageCollectionPeriod},
+					GarbageCollectionPolicy:  brtypes.GarbageCollectionPolicyLimitBased,
+					MaxBackups:               maxBackups,
+				}
+				ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				initialList, err := store.List()
+				Expect(err).ShouldNot(HaveOccurred())
+				succeededRuns := metrics.GarbageCollectionRunsTotal.With(prometheus.Labels{metrics.LabelSucceeded: metrics.ValueSucceededTrue})
+				failedRuns := metrics.GarbageCollectionRunsTotal.With(prometheus.Labels{metrics.LabelSucceeded: metrics.ValueSucceededFalse})
+				deletedFull := metrics.GarbageCollectionDeletedSnapshotsTotal.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindFull})
+				deletedDelta := metrics.GarbageCollectionDeletedSnapshotsTotal.With(prometheus.Labels{metrics.LabelKind: brtypes.SnapshotKindDelta})
+				initialSucceededRuns := testutil.ToFloat64(succeededRuns)
+				initialFailedRuns := testutil.ToFloat64(failedRuns)
+				initialDeletedFull := testutil.ToFloat64(deletedFull)
+				initialDeletedDelta := testutil.ToFloat64(deletedDelta)
+				initialDurationCount := getHistogramSampleCount(metrics.GarbageCollectionDurationSeconds.With(prometheus.Labels{metrics.LabelSucceeded: metrics.ValueSucceededTrue}))
+
+				gcCtx, cancel := context.WithTimeout(testCtx, testTimeout)
+				defer cancel()
+				ssr.RunGarbageCollector(gcCtx.Done())
+
+				list, err := store.List()
+				Expect(err).ShouldNot(HaveOccurred())
+				runs := testutil.ToFloat64(succeededRuns) - initialSucceededRuns
+				Expect(runs).Should(BeNumerically(">=", 1))
+				Expect(testutil.ToFloat64(failedRuns)).Should(Equal(initialFailedRuns))
+				Expect(getHistogramSampleCount(metrics.GarbageCollectionDurationSeconds.With(prometheus.Labels{metrics.LabelSucceeded: metrics.ValueSucceededTrue})) - initialDurationCount).Should(Equal(uint64(runs)))
+				Expect(testutil.ToFloat64(deletedFull) - initialDeletedFull).Should(BeNumerically(">", 0))
+				Expect(testutil.ToFloat64(deletedDelta) - initialDeletedDelta).Should(BeNumerically(">", 0))
+				Expect(testutil.ToFloat64(deletedFull) - initialDeletedFull + testutil.ToFloat64(deletedDelta) - initialDeletedDelta).Should(Equal(float64(len(initialList) - len(list))))
+			})
+
+			It("should record the garbage collection runs which fail to list the snapshots as failed", func() {
+				now := time.Now().UTC()
+				store, snapstoreConfig := prepareStoreForGarbageCollection(now, "garbagecollector_metrics_failure.bkp", "v2")
+				// the snapstore can't be created over a regular file
+				invalidContainer := path.Join(outputDir, "garbagecollector_metrics_failure.file")
+				Expect(os.WriteFile(invalidContainer, []byte{}, 0600)).To(Succeed())
+				snapstoreConfig.Container = invalidContainer
+				snapshotterConfig := &brtypes.SnapshotterConfig{
+					FullSnapshotSchedule:     schedule,
+					DeltaSnapshotPeriod:      wrappers.Duration{Duration: 10 * time.Second},
+					DeltaSnapshotMemoryLimit: brtypes.DefaultDeltaSnapMemoryLimit,
+					GarbageCollectionPeriod:  wrappers.Duration{Duration: garbageCollectionPeriod},
+					GarbageCollectionPolicy:  brtypes.GarbageCollectionPolicyLimitBased,
+					MaxBackups:               maxBackups,
+				}
+				ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				succeededRuns := metrics.GarbageCollectionRunsTotal.With(prometheus.Labels{metrics.LabelSucceeded: metrics.ValueSucceededTrue})
+				failedRuns := metrics.GarbageCollectionRunsTotal.With(prometheus.Labels{metrics.LabelSucceeded: metrics.ValueSucceededFalse})
+				initialSucceededRuns := testutil.ToFloat64(succeededRuns)
+				initialFailedRuns := testutil.ToFloat64(failedRuns)
+
+				gcCtx, cancel := context.WithTimeout(testCtx, testTimeout)
+				defer cancel()
+				ssr.RunGarbageCollector(gcCtx.Done())
+
+				Expect(testutil.ToFloat64(failedRuns) - initialFailedRuns).Should(BeNumerically(">=", 1))
+				Expect(testutil.ToFloat64(succeededRuns)).Should(Equal(initialSucceededRuns))
+			})
+
 			Describe("###GarbageCollectDeltaSnapshots", func() {
 				const (
 					deltaSnapshotCount = 6
@@ -477,58 +3231,346 @@ var _ = Describe("Snapshotter", func() {
 						ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
 						Expect(err).ShouldNot(HaveOccurred())
 
-						deleted, err := ssr.GarbageCollectDeltaSnapshots(list)
+						deleted, err := ssr.GarbageCollectDeltaSnapshots(list)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(deleted).Should(BeZero())
+
+						list, err = store.List()
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(len(list)).Should(BeZero())
+					})
+				})
+
+				Context("with all delta snapshots younger than retention period", func() {
+					It("should not delete any snapshots", func() {
+						store := prepareStoreWithDeltaSnapshots(testDir, deltaSnapshotCount)
+						list, err := store.List()
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(len(list)).Should(Equal(6))
+
+						snapshotterConfig.DeltaSnapshotRetentionPeriod = wrappers.Duration{Duration: 600 * time.Minute}
+						ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+						Expect(err).ShouldNot(HaveOccurred())
+
+						deleted, err := ssr.GarbageCollectDeltaSnapshots(list)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(deleted).Should(BeZero())
+
+						list, err = store.List()
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(len(list)).Should(Equal(deltaSnapshotCount))
+					})
+				})
+
+				Context("with a mix of delta snapshots, some older and some younger than retention period", func() {
+					It("should delete only the delta snapshots older than the retention period", func() {
+						store := prepareStoreWithDeltaSnapshots(testDir, deltaSnapshotCount)
+						list, err := store.List()
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(len(list)).Should(Equal(6))
+
+						snapshotterConfig.DeltaSnapshotRetentionPeriod = wrappers.Duration{Duration: 35 * time.Minute}
+						ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+						Expect(err).ShouldNot(HaveOccurred())
+
+						deleted, err := ssr.GarbageCollectDeltaSnapshots(list)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(deleted).To(Equal(3))
+
+						list, err = store.List()
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(len(list)).Should(Equal(3))
+					})
+				})
+
+				Context("with the delta snapshots in the chain of a restoration running in the process", func() {
+					It("should not delete the delta snapshots until the restoration completes", func() {
+						store := prepareStoreWithDeltaSnapshots(testDir, deltaSnapshotCount)
+						list, err := store.List()
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(len(list)).Should(Equal(deltaSnapshotCount))
+
+						restoreStore := newBlockingFetchStore(store)
+						rs, err := restorer.NewRestorer(restoreStore, logger)
+						Expect(err).ShouldNot(HaveOccurred())
+						restorationConfig := brtypes.NewRestorationConfig()
+						restorationConfig.DataDir = path.Join(outputDir, "garbagecollector_restore.etcd")
+						defer os.RemoveAll(restorationConfig.DataDir)
+						clusterURLs, err := etcdtypes.NewURLsMap(restorationConfig.InitialCluster)
+						Expect(err).ShouldNot(HaveOccurred())
+						peerURLs, err := etcdtypes.NewURLs(restorationConfig.InitialAdvertisePeerURLs)
+						Expect(err).ShouldNot(HaveOccurred())
+						restoreOpts := brtypes.RestoreOptions{
+							Config:        restorationConfig,
+							BaseSnapshot:  &brtypes.Snapshot{},
+							DeltaSnapList: list,
+							ClusterURLs:   clusterURLs,
+							PeerURLs:      peerURLs,
+						}
+						restored := make(chan struct{})
+						go func() {
+							defer close(restored)
+							// the contents of the delta snapshots aren't restorable, only the chain they form matters
+							_ = rs.RestoreAndStopEtcd(testCtx, restoreOpts, nil)
+						}()
+						Eventually(restoreStore.fetching, 30*time.Second).Should(BeClosed())
+
+						ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+						Expect(err).ShouldNot(HaveOccurred())
+						for _, snap := range list {
+							Expect(restorer.IsSnapshotInRestore(snap)).To(BeTrue())
+						}
+						deleted, err := ssr.GarbageCollectDeltaSnapshots(list)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(deleted).Should(BeZero())
+						remaining, err := store.List()
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(len(remaining)).Should(Equal(deltaSnapshotCount))
+
+						close(restoreStore.unblocked)
+						Eventually(restored, 30*time.Second).Should(BeClosed())
+						Expect(restorer.IsSnapshotInRestore(list[0])).To(BeFalse())
+						deleted, err = ssr.GarbageCollectDeltaSnapshots(list)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(deleted).To(Equal(deltaSnapshotCount))
+					})
+				})
+			})
+			Describe("###GarbageCollectInvalidDeltaSnapshots", func() {
+				const testDir = "garbagecollector_invalid_deltasnapshots.bkp"
+
+				var (
+					snapshotterConfig *brtypes.SnapshotterConfig
+					store             brtypes.SnapStore
+				)
+
+				BeforeEach(func() {
+					snapshotterConfig = &brtypes.SnapshotterConfig{
+						FullSnapshotSchedule:           schedule,
+						DeltaSnapshotPeriod:            wrappers.Duration{Duration: 10 * time.Minute},
+						DeltaSnapshotMemoryLimit:       brtypes.DefaultDeltaSnapMemoryLimit,
+						GarbageCollectionPeriod:        wrappers.Duration{Duration: garbageCollectionPeriod},
+						GarbageCollectionPolicy:        brtypes.GarbageCollectionPolicyLimitBased,
+						MaxBackups:                     maxBackups,
+						GarbageCollectInvalidSnapshots: true,
+					}
+					store, err = snapstore.GetSnapstore(&brtypes.SnapstoreConfig{Container: path.Join(outputDir, testDir), Prefix: "v2"})
+					Expect(err).ShouldNot(HaveOccurred())
+				})
+
+				AfterEach(func() {
+					err = os.RemoveAll(path.Join(outputDir, testDir))
+					Expect(err).ShouldNot(HaveOccurred())
+				})
+
+				It("should delete the malformed delta snapshots while keeping the valid ones", func() {
+					events := []byte(`[{"etcdEvent":{"type":0,"kv":{"key":"Zm9v","value":"YmFy"}}}]`)
+					validSnaps := brtypes.SnapList{
+						saveDeltaSnapshot(store, 1, 10, compressor.UnCompressSnapshotExtension, withHash(events)),
+						saveDeltaSnapshot(store, 11, 20, compressor.GzipCompressionExtension, withHash(events)),
+						saveDeltaSnapshot(store, 21, 30, compressor.ZlibCompressionExtension, withHash(events)),
+					}
+					invalidSnaps := brtypes.SnapList{
+						saveDeltaSnapshot(store, 31, 40, compressor.UnCompressSnapshotExtension, events),
+						saveDeltaSnapshot(store, 41, 50, compressor.UnCompressSnapshotExtension, withHash(events[:len(events)-1])),
+						saveDeltaSnapshot(store, 51, 60, compressor.UnCompressSnapshotExtension, append(events, make([]byte, sha256.Size)...)),
+						saveTruncatedDeltaSnapshot(store, 61, 70, compressor.GzipCompressionExtension, withHash(events)),
+					}
+					// the previous snapshot of the snapshotter is never deleted, even if it is invalid
+					prevSnap := saveDeltaSnapshot(store, 71, 80, compressor.UnCompressSnapshotExtension, events)
+
+					ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+					Expect(err).ShouldNot(HaveOccurred())
+					ssr.SetLastRecordedSnapshot(prevSnap)
+
+					list, err := store.List()
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(list).Should(HaveLen(len(validSnaps) + len(invalidSnaps) + 1))
+
+					invalidSnapshots := metrics.GarbageCollectionInvalidSnapshotsTotal.With(prometheus.Labels{})
+					initialInvalidSnapshots := testutil.ToFloat64(invalidSnapshots)
+					deleted, remaining := ssr.GarbageCollectInvalidDeltaSnapshots(list)
+					Expect(deleted).Should(Equal(len(invalidSnaps)))
+					Expect(testutil.ToFloat64(invalidSnapshots) - initialInvalidSnapshots).Should(Equal(float64(len(invalidSnaps))))
+
+					expectedNames := []string{prevSnap.SnapName}
+					for _, snap := range validSnaps {
+						expectedNames = append(expectedNames, snap.SnapName)
+					}
+					var remainingNames []string
+					for _, snap := range remaining {
+						remainingNames = append(remainingNames, snap.SnapName)
+					}
+					Expect(remainingNames).Should(ConsistOf(expectedNames))
+
+					list, err = store.List()
+					Expect(err).ShouldNot(HaveOccurred())
+					var storedNames []string
+					for _, snap := range list {
+						storedNames = append(storedNames, snap.SnapName)
+					}
+					Expect(storedNames).Should(ConsistOf(expectedNames))
+				})
+
+				It("should not check the valid delta snapshots again after a restart, and forget the deleted ones", func() {
+					events := []byte(`[{"etcdEvent":{"type":0,"kv":{"key":"Zm9v","value":"YmFy"}}}]`)
+					validSnap := saveDeltaSnapshot(store, 1, 10, compressor.UnCompressSnapshotExtension, withHash(events))
+					deletedSnap := saveDeltaSnapshot(store, 11, 20, compressor.UnCompressSnapshotExtension, withHash(events))
+					snapstoreConf := &brtypes.SnapstoreConfig{Container: path.Join(outputDir, testDir), Prefix: "v2", TempDir: GinkgoT().TempDir()}
+					validDeltaSnapshotsFile := path.Join(snapstoreConf.TempDir, "valid-delta-snapshots.json")
+
+					ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConf)
+					Expect(err).ShouldNot(HaveOccurred())
+					list, err := store.List()
+					Expect(err).ShouldNot(HaveOccurred())
+					deleted, _ := ssr.GarbageCollectInvalidDeltaSnapshots(list)
+					Expect(deleted).Should(BeZero())
+					Expect(validDeltaSnapshotsFile).Should(BeAnExistingFile())
+
+					// the contents of the valid delta snapshot are not fetched again by the restarted snapshotter, which
+					// would delete it now
+					Expect(store.Save(*validSnap, io.NopCloser(bytes.NewReader(events)))).To(Succeed())
+					for _, snap := range list {
+						if snap.SnapName == deletedSnap.SnapName {
+							Expect(store.Delete(*snap)).To(Succeed())
+						}
+					}
+					ssr, err = NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConf)
+					Expect(err).ShouldNot(HaveOccurred())
+					list, err = store.List()
+					Expect(err).ShouldNot(HaveOccurred())
+					deleted, remaining := ssr.GarbageCollectInvalidDeltaSnapshots(list)
+					Expect(deleted).Should(BeZero())
+					Expect(remaining).Should(HaveLen(1))
+
+					data, err := os.ReadFile(validDeltaSnapshotsFile)
+					Expect(err).ShouldNot(HaveOccurred())
+					var snapPaths []string
+					Expect(json.Unmarshal(data, &snapPaths)).To(Succeed())
+					Expect(snapPaths).Should(ConsistOf(path.Join(validSnap.SnapDir, validSnap.SnapName)))
+				})
+			})
+
+			Describe("###MinDeltaSnapshotsToKeep", func() {
+				const (
+					testDir = "garbagecollector_mindeltasnapshots.bkp"
+				)
+
+				AfterEach(func() {
+					err = os.RemoveAll(path.Join(outputDir, testDir))
+					Expect(err).ShouldNot(HaveOccurred())
+				})
+
+				Context("with a low-traffic chain where all delta snapshots are older than the retention period", func() {
+					It("should retain the most recent delta snapshots of the previous full snapshots while the latest full snapshot has fewer", func() {
+						snapstoreConf := &brtypes.SnapstoreConfig{Container: path.Join(outputDir, testDir), Prefix: "v2"}
+						store, err := snapstore.GetSnapstore(snapstoreConf)
 						Expect(err).NotTo(HaveOccurred())
-						Expect(deleted).Should(BeZero())
 
-						list, err = store.List()
+						// old full snapshot followed by 3 delta snapshots older than the retention period, and a recent full
+						// snapshot followed by a single delta snapshot
+						Expect(addObjectsToStore(store, "Composite", brtypes.SnapshotKindFull, 0, 100, 1, now.Add(-5*time.Hour))).To(Succeed())
+						Expect(addObjectsToStore(store, "Composite", brtypes.SnapshotKindDelta, 101, 110, 1, now.Add(-290*time.Minute))).To(Succeed())
+						Expect(addObjectsToStore(store, "Composite", brtypes.SnapshotKindDelta, 111, 120, 1, now.Add(-280*time.Minute))).To(Succeed())
+						Expect(addObjectsToStore(store, "Composite", brtypes.SnapshotKindDelta, 121, 130, 1, now.Add(-270*time.Minute))).To(Succeed())
+						Expect(addObjectsToStore(store, "Composite", brtypes.SnapshotKindFull, 0, 130, 1, now.Add(-3*time.Hour))).To(Succeed())
+						Expect(addObjectsToStore(store, "Composite", brtypes.SnapshotKindDelta, 131, 140, 1, now.Add(-170*time.Minute))).To(Succeed())
+
+						snapshotterConfig := &brtypes.SnapshotterConfig{
+							FullSnapshotSchedule:         schedule,
+							DeltaSnapshotPeriod:          wrappers.Duration{Duration: 10 * time.Second},
+							DeltaSnapshotMemoryLimit:     brtypes.DefaultDeltaSnapMemoryLimit,
+							GarbageCollectionPeriod:      wrappers.Duration{Duration: garbageCollectionPeriod},
+							GarbageCollectionPolicy:      brtypes.GarbageCollectionPolicyLimitBased,
+							MaxBackups:                   maxBackups,
+							DeltaSnapshotRetentionPeriod: wrappers.Duration{Duration: time.Hour},
+							MinDeltaSnapshotsToKeep:      3,
+						}
+						ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConf)
 						Expect(err).ShouldNot(HaveOccurred())
-						Expect(len(list)).Should(BeZero())
-					})
-				})
 
-				Context("with all delta snapshots younger than retention period", func() {
-					It("should not delete any snapshots", func() {
-						store := prepareStoreWithDeltaSnapshots(testDir, deltaSnapshotCount)
+						gcCtx, cancel := context.WithTimeout(testCtx, testTimeout)
+						defer cancel()
+						ssr.RunGarbageCollector(gcCtx.Done())
+
 						list, err := store.List()
 						Expect(err).ShouldNot(HaveOccurred())
-						Expect(len(list)).Should(Equal(6))
+						Expect(len(list)).Should(Equal(5))
 
-						snapshotterConfig.DeltaSnapshotRetentionPeriod = wrappers.Duration{Duration: 600 * time.Minute}
-						ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
-						Expect(err).ShouldNot(HaveOccurred())
+						var deltaSnapshots brtypes.SnapList
+						for _, snap := range list {
+							if snap.Kind == brtypes.SnapshotKindDelta {
+								deltaSnapshots = append(deltaSnapshots, snap)
+							}
+						}
+						// the delta snapshot of the latest full snapshot counts towards the delta snapshots to keep, hence
+						// only the 2 most recent delta snapshots of the previous full snapshot are retained
+						Expect(len(deltaSnapshots)).Should(Equal(3))
+						Expect(deltaSnapshots[0].StartRevision).Should(Equal(int64(111)))
+						Expect(deltaSnapshots[1].StartRevision).Should(Equal(int64(121)))
+						Expect(deltaSnapshots[2].StartRevision).Should(Equal(int64(131)))
+					})
+				})
+			})
 
-						deleted, err := ssr.GarbageCollectDeltaSnapshots(list)
-						Expect(err).NotTo(HaveOccurred())
-						Expect(deleted).Should(BeZero())
+			Describe("###StorageBudget", func() {
+				const (
+					testDir = "garbagecollector_storagebudget.bkp"
+				)
 
-						list, err = store.List()
-						Expect(err).ShouldNot(HaveOccurred())
-						Expect(len(list)).Should(Equal(deltaSnapshotCount))
-					})
+				AfterEach(func() {
+					err = os.RemoveAll(path.Join(outputDir, testDir))
+					Expect(err).ShouldNot(HaveOccurred())
 				})
 
-				Context("with a mix of delta snapshots, some older and some younger than retention period", func() {
-					It("should delete only the delta snapshots older than the retention period", func() {
-						store := prepareStoreWithDeltaSnapshots(testDir, deltaSnapshotCount)
-						list, err := store.List()
-						Expect(err).ShouldNot(HaveOccurred())
-						Expect(len(list)).Should(Equal(6))
+				DescribeTable("should delete the oldest snapshots until the snapshots fit within the storage budget",
+					func(maxTotalStorageBytes int64, expectedSnapshots []string) {
+						snapstoreConf := &brtypes.SnapstoreConfig{Container: path.Join(outputDir, testDir), Prefix: "v2"}
+						store, err := snapstore.GetSnapstore(snapstoreConf)
+						Expect(err).NotTo(HaveOccurred())
 
-						snapshotterConfig.DeltaSnapshotRetentionPeriod = wrappers.Duration{Duration: 35 * time.Minute}
-						ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+						// three snapStreams of 3400B in total, with full snapshots of 1000B and delta snapshots of 100B
+						Expect(addSnapshotOfSizeToStore(store, brtypes.SnapshotKindFull, 0, 100, now.Add(-5*time.Hour), 1000)).To(Succeed())
+						Expect(addSnapshotOfSizeToStore(store, brtypes.SnapshotKindDelta, 101, 110, now.Add(-290*time.Minute), 100)).To(Succeed())
+						Expect(addSnapshotOfSizeToStore(store, brtypes.SnapshotKindDelta, 111, 120, now.Add(-280*time.Minute), 100)).To(Succeed())
+						Expect(addSnapshotOfSizeToStore(store, brtypes.SnapshotKindFull, 0, 120, now.Add(-3*time.Hour), 1000)).To(Succeed())
+						Expect(addSnapshotOfSizeToStore(store, brtypes.SnapshotKindDelta, 121, 130, now.Add(-170*time.Minute), 100)).To(Succeed())
+						Expect(addSnapshotOfSizeToStore(store, brtypes.SnapshotKindFull, 0, 130, now.Add(-1*time.Hour), 1000)).To(Succeed())
+						Expect(addSnapshotOfSizeToStore(store, brtypes.SnapshotKindDelta, 131, 140, now.Add(-50*time.Minute), 100)).To(Succeed())
+
+						gcPeriod := 2 * time.Second
+						snapshotterConfig := &brtypes.SnapshotterConfig{
+							FullSnapshotSchedule:     schedule,
+							DeltaSnapshotPeriod:      wrappers.Duration{Duration: time.Second},
+							DeltaSnapshotMemoryLimit: brtypes.DefaultDeltaSnapMemoryLimit,
+							GarbageCollectionPeriod:  wrappers.Duration{Duration: gcPeriod},
+							GarbageCollectionPolicy:  brtypes.GarbageCollectionPolicyStorageBudget,
+							MaxTotalStorageBytes:     maxTotalStorageBytes,
+						}
+						ssr, err := NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConf)
 						Expect(err).ShouldNot(HaveOccurred())
 
-						deleted, err := ssr.GarbageCollectDeltaSnapshots(list)
-						Expect(err).NotTo(HaveOccurred())
-						Expect(deleted).To(Equal(3))
+						gcCtx, cancel := context.WithTimeout(testCtx, gcPeriod+time.Second)
+						defer cancel()
+						ssr.RunGarbageCollector(gcCtx.Done())
 
-						list, err = store.List()
+						list, err := store.List()
 						Expect(err).ShouldNot(HaveOccurred())
-						Expect(len(list)).Should(Equal(3))
-					})
-				})
+						var snapshots []string
+						for _, snap := range list {
+							snapshots = append(snapshots, fmt.Sprintf("%s-%d-%d", snap.Kind, snap.StartRevision, snap.LastRevision))
+						}
+						Expect(snapshots).To(Equal(expectedSnapshots))
+					},
+					Entry("within the budget", int64(3400), []string{"Full-0-100", "Incr-101-110", "Incr-111-120", "Full-0-120", "Incr-121-130", "Full-0-130", "Incr-131-140"}),
+					Entry("exactly at the budget after deleting the latest delta snapshot of the oldest snapStream", int64(3300), []string{"Full-0-100", "Incr-101-110", "Full-0-120", "Incr-121-130", "Full-0-130", "Incr-131-140"}),
+					Entry("exactly at the budget after deleting the delta snapshots of the oldest snapStream", int64(3200), []string{"Full-0-100", "Full-0-120", "Incr-121-130", "Full-0-130", "Incr-131-140"}),
+					Entry("under the budget after deleting the delta snapshots of the oldest snapStream", int64(3250), []string{"Full-0-100", "Full-0-120", "Incr-121-130", "Full-0-130", "Incr-131-140"}),
+					Entry("exactly at the budget after deleting the oldest snapStream and part of the next one", int64(2100), []string{"Full-0-120", "Full-0-130", "Incr-131-140"}),
+					Entry("over the budget with only the latest snapStream left", int64(500), []string{"Full-0-130", "Incr-131-140"}),
+				)
 			})
+
 			Describe("###GarbageCollectChunkSnapshots", func() {
 				const (
 					testDir = "garbagecollector_chunksnapshots.bkp"
@@ -625,7 +3667,7 @@ var _ = Describe("Snapshotter", func() {
 						Expect(err).NotTo(HaveOccurred())
 						Expect(len(list)).To(Equal(10))
 
-						ssr.PrevSnapshot = &lastUploadedSnapshot
+						ssr.SetLastRecordedSnapshot(&lastUploadedSnapshot)
 						// snapList contains only non-chunk objects
 						deletedCount, snapList := ssr.GarbageCollectChunks(list)
 						Expect(deletedCount).To(Equal(9))
@@ -662,7 +3704,7 @@ var _ = Describe("Snapshotter", func() {
 						Expect(err).NotTo(HaveOccurred())
 						Expect(len(list)).To(Equal(10))
 
-						ssr.PrevSnapshot = &lastUploadedSnapshot
+						ssr.SetLastRecordedSnapshot(&lastUploadedSnapshot)
 						// snapList contains only non-chunk objects
 						deletedCount, snapList := ssr.GarbageCollectChunks(list)
 						Expect(deletedCount).To(Equal(5))
@@ -699,7 +3741,7 @@ var _ = Describe("Snapshotter", func() {
 						Expect(err).NotTo(HaveOccurred())
 						Expect(len(list)).To(Equal(3))
 
-						ssr.PrevSnapshot = &lastUploadedSnapshot
+						ssr.SetLastRecordedSnapshot(&lastUploadedSnapshot)
 						// snapList contains only non-chunk objects
 						deletedCount, snapList := ssr.GarbageCollectChunks(list)
 						Expect(deletedCount).To(BeZero())
@@ -799,6 +3841,53 @@ var _ = Describe("Snapshotter", func() {
 				})
 			})
 
+			Context("Previous full snapshot was taken slightly off the scheduled snapshot time due to clock skew", func() {
+				var (
+					snapshotterConfig *brtypes.SnapshotterConfig
+					scheduledTime     time.Time
+				)
+				BeforeEach(func() {
+					next := time.Now().Add(2*time.Hour + time.Minute)
+					snapshotterConfig = &brtypes.SnapshotterConfig{
+						FullSnapshotSchedule: fmt.Sprintf("%d %d * * *", next.Minute(), next.Hour()),
+					}
+					// the previous scheduled snapshot time, 1 day before the next one
+					scheduledTime = time.Date(next.Year(), next.Month(), next.Day()-1, next.Hour(), next.Minute(), 0, 0, time.Local)
+				})
+
+				isFullSnapshotRequiredAtStartup := func(skewTolerance, skew time.Duration) bool {
+					snapshotterConfig.FullSnapshotScheduleSkewTolerance = wrappers.Duration{Duration: skewTolerance}
+					ssr, err = NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+					Expect(err).ShouldNot(HaveOccurred())
+
+					ssr.PrevFullSnapshot = &brtypes.Snapshot{
+						CreatedOn: scheduledTime.Add(skew),
+					}
+					return ssr.IsFullSnapshotRequiredAtStartup(fullSnapshotTimeWindow)
+				}
+
+				It("should return true for a snapshot taken slightly before the scheduled time without a skew tolerance", func() {
+					Expect(isFullSnapshotRequiredAtStartup(0, -20*time.Second)).Should(BeTrue())
+				})
+
+				It("should return false for a snapshot taken slightly before the scheduled time within the skew tolerance", func() {
+					Expect(isFullSnapshotRequiredAtStartup(time.Minute, -20*time.Second)).Should(BeFalse())
+				})
+
+				It("should return false for a snapshot taken slightly after the scheduled time within the skew tolerance", func() {
+					Expect(isFullSnapshotRequiredAtStartup(time.Minute, 20*time.Second)).Should(BeFalse())
+				})
+
+				It("should return true for a snapshot taken before the scheduled time beyond the skew tolerance", func() {
+					Expect(isFullSnapshotRequiredAtStartup(time.Minute, -2*time.Minute)).Should(BeTrue())
+				})
+
+				It("should return false for a snapshot taken slightly before the scheduled time with the default skew tolerance", func() {
+					defaultConfig := NewSnapshotterConfig()
+					Expect(isFullSnapshotRequiredAtStartup(defaultConfig.FullSnapshotScheduleSkewTolerance.Duration, -20*time.Second)).Should(BeFalse())
+				})
+			})
+
 			Context("Previous snapshot was taken within 24hrs and next schedule full-snapshot will be taken within 24hs of time window", func() {
 				It("should return false", func() {
 					scheduleHour := (currentHour + 4) % 24
@@ -836,6 +3925,39 @@ var _ = Describe("Snapshotter", func() {
 					Expect(isFullSnapCanBeMissed).Should(BeTrue())
 				})
 			})
+
+			Context("Previous full snapshot was taken at scheduled snapshot time but is older than the maximum full snapshot age", func() {
+				var (
+					snapshotterConfig *brtypes.SnapshotterConfig
+					prevFullSnapshot  *brtypes.Snapshot
+				)
+				BeforeEach(func() {
+					snapshotterConfig = &brtypes.SnapshotterConfig{
+						FullSnapshotSchedule: fmt.Sprintf("%d %d * * *", (currentMin+1)%60, (currentHour+2)%24),
+					}
+					// Previous full snapshot was taken 1 day before at exactly at scheduled time
+					prevFullSnapshot = &brtypes.Snapshot{
+						CreatedOn: time.Date(time.Now().Year(), time.Now().Month(), time.Now().Day()-1, (currentHour+2)%24, (currentMin+1)%60, 0, 0, time.Local),
+					}
+				})
+
+				It("should return true if the maximum full snapshot age is configured", func() {
+					snapshotterConfig.MaxFullSnapshotAge = wrappers.Duration{Duration: 6 * time.Hour}
+					ssr, err = NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+					Expect(err).ShouldNot(HaveOccurred())
+
+					ssr.PrevFullSnapshot = prevFullSnapshot
+					Expect(ssr.IsFullSnapshotRequiredAtStartup(fullSnapshotTimeWindow)).Should(BeTrue())
+				})
+
+				It("should fall back to the time window and return false if the maximum full snapshot age is not configured", func() {
+					ssr, err = NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+					Expect(err).ShouldNot(HaveOccurred())
+
+					ssr.PrevFullSnapshot = prevFullSnapshot
+					Expect(ssr.IsFullSnapshotRequiredAtStartup(fullSnapshotTimeWindow)).Should(BeFalse())
+				})
+			})
 		})
 
 		Describe("Scenarios to get maximum time window for full snapshot", func() {
@@ -896,6 +4018,22 @@ var _ = Describe("Snapshotter", func() {
 					Expect(timeWindow).Should(Equal(float64(scheduleHour)))
 				})
 			})
+
+			Context("Full snapshot schedule for once a week with an additional schedule for every 6 hours", func() {
+				It("should return the shortest timeWindow across the schedules", func() {
+					scheduleHour := 6
+					snapshotterConfig := &brtypes.SnapshotterConfig{
+						FullSnapshotSchedule:            fmt.Sprintf("%d %d * * %d", currentMin, currentHour, time.Thursday),
+						AdditionalFullSnapshotSchedules: []string{fmt.Sprintf("frequent=0 */%d * * *", scheduleHour)},
+					}
+
+					ssr, err = NewSnapshotter(logger, snapshotterConfig, store, etcdConnectionConfig, compressionConfig, healthConfig, snapstoreConfig)
+					Expect(err).ShouldNot(HaveOccurred())
+
+					Expect(ssr.GetFullSnapshotMaxTimeWindow(snapshotterConfig.FullSnapshotSchedule)).Should(Equal(fullSnapshotTimeWindow * 7))
+					Expect(ssr.GetFullSnapshotsMaxTimeWindow()).Should(Equal(float64(scheduleHour)))
+				})
+			})
 		})
 
 		Describe("Scenarios to update full snapshot lease", func() {
@@ -1024,6 +4162,24 @@ var _ = Describe("Snapshotter", func() {
 	})
 })
 
+var _ = Describe("Marshaling the delta snapshot events", func() {
+	It("should serialize the events exactly like marshaling them one by one", func() {
+		evs := newWatchEvents(100)
+		eventTime := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+		batched, err := MarshalEvents(evs, eventTime)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(batched).To(Equal(marshalEventsOneByOne(evs, eventTime)))
+		Expect(json.Valid(batched)).To(BeTrue())
+	})
+
+	It("should serialize no events as an empty array", func() {
+		batched, err := MarshalEvents(nil, time.Now())
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(string(batched)).To(Equal("[]"))
+	})
+})
+
 // prepareExpectedSnapshotsList prepares the expected snapshot list based on directory structure
 func prepareExpectedSnapshotsList(snapTime time.Time, now time.Time, expectedSnapList brtypes.SnapList, directoryStruct string) brtypes.SnapList {
 	// weekly snapshot
@@ -1212,6 +4368,42 @@ func prepareStoreWithDeltaSnapshots(storeContainer string, numDeltaSnapshots int
 	return store
 }
 
+// withHash returns the given events followed by their SHA256 hash, the way they are stored in a delta snapshot.
+func withHash(events []byte) []byte {
+	hash := sha256.Sum256(events)
+	return append(append([]byte{}, events...), hash[:]...)
+}
+
+// saveDeltaSnapshot saves a delta snapshot with the given contents, compressed according to the given compression suffix.
+func saveDeltaSnapshot(store brtypes.SnapStore, startRevision, lastRevision int64, compressionSuffix string, contents []byte) *brtypes.Snapshot {
+	snap := snapstore.NewSnapshot(brtypes.SnapshotKindDelta, startRevision, lastRevision, compressionSuffix, false)
+	Expect(store.Save(*snap, io.NopCloser(bytes.NewReader(compressDeltaSnapshot(compressionSuffix, contents))))).To(Succeed())
+	return snap
+}
+
+// saveTruncatedDeltaSnapshot saves a delta snapshot with the given contents, compressed according to the given compression
+// suffix, of which only the first half is saved.
+func saveTruncatedDeltaSnapshot(store brtypes.SnapStore, startRevision, lastRevision int64, compressionSuffix string, contents []byte) *brtypes.Snapshot {
+	snap := snapstore.NewSnapshot(brtypes.SnapshotKindDelta, startRevision, lastRevision, compressionSuffix, false)
+	compressed := compressDeltaSnapshot(compressionSuffix, contents)
+	Expect(store.Save(*snap, io.NopCloser(bytes.NewReader(compressed[:len(compressed)/2])))).To(Succeed())
+	return snap
+}
+
+func compressDeltaSnapshot(compressionSuffix string, contents []byte) []byte {
+	isCompressed, compressionPolicy, err := compressor.IsSnapshotCompressed(compressionSuffix)
+	Expect(err).ShouldNot(HaveOccurred())
+	if !isCompressed {
+		return contents
+	}
+	rc, err := compressor.CompressSnapshot(io.NopCloser(bytes.NewReader(contents)), compressionPolicy)
+	Expect(err).ShouldNot(HaveOccurred())
+	defer rc.Close()
+	compressed, err := io.ReadAll(rc)
+	Expect(err).ShouldNot(HaveOccurred())
+	return compressed
+}
+
 // addObjectsToStore adds objects to the given store. It creates and saves objects based on the provided parameters.
 // The objectType can be either "Chunk" or "Composite". The kind specifies the type of the snapshot Full/delta
 // The startRevision and lastRevision define the revision range of the objects.
@@ -1244,6 +4436,18 @@ func addObjectsToStore(store brtypes.SnapStore, objectType string, kind string,
 	return nil
 }
 
+// addSnapshotOfSizeToStore saves a snapshot with contents of the given size to the store.
+func addSnapshotOfSizeToStore(store brtypes.SnapStore, kind string, startRevision, lastRevision int64, creationTime time.Time, size int) error {
+	snap := brtypes.Snapshot{
+		Kind:          kind,
+		CreatedOn:     creationTime,
+		StartRevision: startRevision,
+		LastRevision:  lastRevision,
+	}
+	snap.GenerateSnapshotName()
+	return store.Save(snap, io.NopCloser(bytes.NewReader(bytes.Repeat([]byte{'x'}, size))))
+}
+
 // getObjectCount returns counts of chunk and composite objects in the store
 func getObjectCount(store brtypes.SnapStore) (int, int, error) {
 	list, err := store.List()
@@ -1261,3 +4465,365 @@ func getObjectCount(store brtypes.SnapStore) (int, int, error) {
 	}
 	return chunkCount, compositeCount, nil
 }
+
+// fakeWatcher is an etcd watcher whose watches all deliver the watch responses sent to its channel, and which records
+// the revisions the watches were started from.
+type fakeWatcher struct {
+	clientv3.Watcher
+	mutex     sync.Mutex
+	revisions []int64
+	watchCh   chan clientv3.WatchResponse
+}
+
+func newFakeWatcher() *fakeWatcher {
+	return &fakeWatcher{watchCh: make(chan clientv3.WatchResponse, 10)}
+}
+
+func (w *fakeWatcher) Watch(_ context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.revisions = append(w.revisions, clientv3.OpGet(key, opts...).Rev())
+	return w.watchCh
+}
+
+func (w *fakeWatcher) Close() error {
+	return nil
+}
+
+// RequestProgress notifies the watches of their progress past any revision, as the fake watches have delivered all
+// the watch responses sent to them.
+func (w *fakeWatcher) RequestProgress(_ context.Context) error {
+	select {
+	case w.watchCh <- clientv3.WatchResponse{Header: etcdserverpb.ResponseHeader{Revision: math.MaxInt64}}:
+	default:
+	}
+	return nil
+}
+
+func (w *fakeWatcher) watchedRevisions() []int64 {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return append([]int64(nil), w.revisions...)
+}
+
+// pagingKVFactory is an etcd client factory whose KV clients record the revisions the ranged gets are read at, with 0
+// for the latest revision, and call afterPage after each of them
+type pagingKVFactory struct {
+	etcdClient.Factory
+	afterPage     func(page int)
+	pageRevisions []int64
+}
+
+func (f *pagingKVFactory) NewKV() (etcdClient.KVCloser, error) {
+	clientKV, err := f.Factory.NewKV()
+	if err != nil {
+		return nil, err
+	}
+	return &pagingKV{KVCloser: clientKV, factory: f}, nil
+}
+
+// pagingKV is a KV client recording the ranged gets in its pagingKVFactory
+type pagingKV struct {
+	etcdClient.KVCloser
+	factory *pagingKVFactory
+}
+
+func (kv *pagingKV) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	resp, err := kv.KVCloser.Get(ctx, key, opts...)
+	if err != nil || len(clientv3.OpGet(key, opts...).RangeBytes()) == 0 {
+		return resp, err
+	}
+	kv.factory.pageRevisions = append(kv.factory.pageRevisions, clientv3.OpGet(key, opts...).Rev())
+	kv.factory.afterPage(len(kv.factory.pageRevisions))
+	return resp, err
+}
+
+// stallingWatchFactory is an etcd client factory whose watchers never deliver any events.
+type stallingWatchFactory struct {
+	etcdClient.Factory
+}
+
+// newStallingWatchFactory returns a stallingWatchFactory for the given etcd connection config.
+func newStallingWatchFactory(cfg brtypes.EtcdConnectionConfig, opts ...etcdClient.Option) etcdClient.Factory {
+	return &stallingWatchFactory{Factory: etcdutil.NewFactory(cfg, opts...)}
+}
+
+func (f *stallingWatchFactory) NewWatcher() (clientv3.Watcher, error) {
+	watcher, err := f.Factory.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return &stallingWatcher{Watcher: watcher}, nil
+}
+
+// stallingWatcher is a watcher whose watch channels stay open without ever delivering any events.
+type stallingWatcher struct {
+	clientv3.Watcher
+}
+
+func (w *stallingWatcher) Watch(_ context.Context, _ string, _ ...clientv3.OpOption) clientv3.WatchChan {
+	return make(chan clientv3.WatchResponse)
+}
+
+// failingFullSnapshotStore is a snapstore which fails to save the given number of full snapshots,
+// or all of them if the number is negative.
+type failingFullSnapshotStore struct {
+	brtypes.SnapStore
+	failures int
+	attempts int
+}
+
+func (s *failingFullSnapshotStore) Save(snap brtypes.Snapshot, rc io.ReadCloser) error {
+	if snap.Kind == brtypes.SnapshotKindFull {
+		s.attempts++
+		if s.failures < 0 || s.attempts <= s.failures {
+			rc.Close()
+			return fmt.Errorf("failed to save full snapshot %s", snap.SnapName)
+		}
+	}
+	return s.SnapStore.Save(snap, rc)
+}
+
+// slowDeltaSnapshotStore delays saving the delta snapshots, and tracks the maximum number of delta snapshots saved concurrently
+type slowDeltaSnapshotStore struct {
+	brtypes.SnapStore
+	delay       time.Duration
+	mutex       sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (s *slowDeltaSnapshotStore) Save(snap brtypes.Snapshot, rc io.ReadCloser) error {
+	if snap.Kind != brtypes.SnapshotKindDelta {
+		return s.SnapStore.Save(snap, rc)
+	}
+	s.mutex.Lock()
+	s.inFlight++
+	if s.inFlight > s.maxInFlight {
+		s.maxInFlight = s.inFlight
+	}
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		s.inFlight--
+		s.mutex.Unlock()
+	}()
+	time.Sleep(s.delay)
+	return s.SnapStore.Save(snap, rc)
+}
+
+// failingDeltaSnapshotStore is a snapstore which delays saving the delta snapshots, and fails to save the delta
+// snapshot of the given attempt, which it keeps as the failed one.
+type failingDeltaSnapshotStore struct {
+	brtypes.SnapStore
+	delay    time.Duration
+	failAt   int
+	mutex    sync.Mutex
+	attempts int
+	failed   *brtypes.Snapshot
+}
+
+func (s *failingDeltaSnapshotStore) Save(snap brtypes.Snapshot, rc io.ReadCloser) error {
+	if snap.Kind != brtypes.SnapshotKindDelta {
+		return s.SnapStore.Save(snap, rc)
+	}
+	s.mutex.Lock()
+	s.attempts++
+	fail := s.attempts == s.failAt
+	if fail {
+		s.failed = &snap
+	}
+	s.mutex.Unlock()
+	time.Sleep(s.delay)
+	if fail {
+		rc.Close()
+		return fmt.Errorf("failed to save delta snapshot %s", snap.SnapName)
+	}
+	return s.SnapStore.Save(snap, rc)
+}
+
+// failingChainManifestStore is a snapstore which fails to save the chain manifest while failing is set
+type failingChainManifestStore struct {
+	*snapstore.LocalSnapStore
+	failing atomic.Bool
+}
+
+func (s *failingChainManifestStore) SaveChainManifest(manifest *brtypes.ChainManifest) error {
+	if s.failing.Load() {
+		return fmt.Errorf("failed to save chain manifest")
+	}
+	return s.LocalSnapStore.SaveChainManifest(manifest)
+}
+
+// blockingDeltaSnapshotStore is a snapstore which blocks saving the delta snapshots until it is released, and sends
+// the first delta snapshot being saved to its saving channel.
+type blockingDeltaSnapshotStore struct {
+	brtypes.SnapStore
+	saving  chan brtypes.Snapshot
+	release chan struct{}
+}
+
+func (s *blockingDeltaSnapshotStore) Save(snap brtypes.Snapshot, rc io.ReadCloser) error {
+	if snap.Kind != brtypes.SnapshotKindDelta {
+		return s.SnapStore.Save(snap, rc)
+	}
+	select {
+	case s.saving <- snap:
+	default:
+	}
+	<-s.release
+	return s.SnapStore.Save(snap, rc)
+}
+
+// quotaExceededStore is a snapstore which rejects saving the snapshots as out of quota while the quota is exceeded,
+// and counts the rejected snapshots
+type quotaExceededStore struct {
+	brtypes.SnapStore
+	exceeded atomic.Bool
+	rejected atomic.Int32
+}
+
+func (s *quotaExceededStore) Save(snap brtypes.Snapshot, rc io.ReadCloser) error {
+	if s.exceeded.Load() {
+		rc.Close()
+		s.rejected.Add(1)
+		return fmt.Errorf("failed to save snapshot %s: QuotaExceeded: bucket quota exceeded", snap.SnapName)
+	}
+	return s.SnapStore.Save(snap, rc)
+}
+
+// blockingFetchStore is a snapstore which blocks fetching the snapshots until it is unblocked, and signals the first
+// fetch
+type blockingFetchStore struct {
+	brtypes.SnapStore
+	fetching  chan struct{}
+	unblocked chan struct{}
+	once      sync.Once
+}
+
+func newBlockingFetchStore(store brtypes.SnapStore) *blockingFetchStore {
+	return &blockingFetchStore{SnapStore: store, fetching: make(chan struct{}), unblocked: make(chan struct{})}
+}
+
+func (s *blockingFetchStore) Fetch(snap brtypes.Snapshot) (io.ReadCloser, error) {
+	s.once.Do(func() { close(s.fetching) })
+	<-s.unblocked
+	return s.SnapStore.Fetch(snap)
+}
+
+// getLatestFullSnapshot returns the latest full snapshot in the store, or nil if there is none
+func getLatestFullSnapshot(store brtypes.SnapStore) *brtypes.Snapshot {
+	list, err := store.List()
+	Expect(err).ShouldNot(HaveOccurred())
+	var latest *brtypes.Snapshot
+	for _, snap := range list {
+		if snap.Kind == brtypes.SnapshotKindFull && !snap.IsChunk {
+			latest = snap
+		}
+	}
+	return latest
+}
+
+// getHistogramSampleCount returns the number of observations of the given histogram
+func getHistogramSampleCount(observer prometheus.Observer) uint64 {
+	histogram, ok := observer.(prometheus.Histogram)
+	Expect(ok).Should(BeTrue())
+	m := &dto.Metric{}
+	Expect(histogram.Write(m)).To(Succeed())
+	return m.GetHistogram().GetSampleCount()
+}
+
+// deltaSnapshotEvent holds the key-value of an event stored in a delta snapshot
+type deltaSnapshotEvent struct {
+	EtcdEvent struct {
+		Kv struct {
+			Key         []byte `json:"key"`
+			Value       []byte `json:"value"`
+			ModRevision int64  `json:"mod_revision"`
+		} `json:"kv"`
+	} `json:"etcdEvent"`
+}
+
+// readDeltaSnapshotEvents returns the events stored in the given delta snapshot, after verifying their hash
+func readDeltaSnapshotEvents(store brtypes.SnapStore, snap *brtypes.Snapshot) []deltaSnapshotEvent {
+	rc, err := store.Fetch(*snap)
+	Expect(err).ShouldNot(HaveOccurred())
+	compressed, compressionPolicy, err := compressor.IsSnapshotCompressed(snap.CompressionSuffix)
+	Expect(err).ShouldNot(HaveOccurred())
+	if compressed {
+		rc, err = compressor.DecompressSnapshot(rc, compressionPolicy)
+		Expect(err).ShouldNot(HaveOccurred())
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	Expect(err).ShouldNot(HaveOccurred())
+
+	Expect(len(data)).Should(BeNumerically(">", sha256.Size))
+	events, hash := data[:len(data)-sha256.Size], data[len(data)-sha256.Size:]
+	computedHash := sha256.Sum256(events)
+	Expect(hash).Should(Equal(computedHash[:]))
+
+	var deltaEvents []deltaSnapshotEvent
+	Expect(json.Unmarshal(events, &deltaEvents)).To(Succeed())
+	return deltaEvents
+}
+
+// BenchmarkMarshalEvents compares marshaling the events of a watch response one by one with marshaling them at once.
+func BenchmarkMarshalEvents(b *testing.B) {
+	evs := newWatchEvents(1000)
+	eventTime := time.Now()
+	b.Run("one-by-one", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			marshalEventsOneByOne(evs, eventTime)
+		}
+	})
+	b.Run("batched", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := MarshalEvents(evs, eventTime); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// newWatchEvents returns the given number of put events, as delivered by an etcd watch.
+func newWatchEvents(count int) []*clientv3.Event {
+	evs := make([]*clientv3.Event, count)
+	for i := range evs {
+		evs[i] = &clientv3.Event{
+			Type: mvccpb.PUT,
+			Kv: &mvccpb.KeyValue{
+				Key:            []byte(fmt.Sprintf("/registry/pods/default/pod-%d", i)),
+				Value:          []byte(fmt.Sprintf("value-%d", i)),
+				CreateRevision: int64(i + 1),
+				ModRevision:    int64(i + 1),
+				Version:        1,
+			},
+		}
+	}
+	return evs
+}
+
+// marshalEventsOneByOne marshals the given events individually and joins them into a JSON array, the way the delta
+// snapshot events used to be serialized.
+func marshalEventsOneByOne(evs []*clientv3.Event, eventTime time.Time) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, ev := range evs {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		jsonByte, err := json.Marshal(&struct {
+			EtcdEvent *clientv3.Event `json:"etcdEvent"`
+			Time      time.Time       `json:"time"`
+		}{EtcdEvent: ev, Time: eventTime})
+		if err != nil {
+			panic(err)
+		}
+		buf.Write(jsonByte)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
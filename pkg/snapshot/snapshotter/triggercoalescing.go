@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshotter
+
+import (
+	"sync"
+	"time"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+const (
+	triggerKeyFull      = "full"
+	triggerKeyFinalFull = "final-full"
+	triggerKeyDelta     = "delta"
+)
+
+// coalescedTrigger is an out of schedule snapshot trigger whose snapshot is shared by all the callers which joined it.
+type coalescedTrigger struct {
+	done     chan struct{}
+	snapshot *brtypes.Snapshot
+	err      error
+}
+
+// triggerCoalescer coalesces the concurrent out of schedule snapshot triggers of the same kind, so that triggers
+// which arrive within the coalescing window of a pending trigger share a single snapshot instead of each queueing
+// up a snapshot of their own. A trigger stops accepting further callers once its snapshot is started, so that every
+// caller gets a snapshot which was started after it triggered the snapshot.
+type triggerCoalescer struct {
+	mutex   sync.Mutex
+	window  time.Duration
+	pending map[string]*coalescedTrigger
+}
+
+// newTriggerCoalescer returns a trigger coalescer with the given coalescing window.
+func newTriggerCoalescer(window time.Duration) *triggerCoalescer {
+	return &triggerCoalescer{
+		window:  window,
+		pending: map[string]*coalescedTrigger{},
+	}
+}
+
+// do joins the pending trigger of the given key, if there is one, and returns its result. Otherwise it starts a new
+// trigger, which waits for the coalescing window, if any, and then takes the snapshot with the given function.
+func (c *triggerCoalescer) do(key string, take func() (*brtypes.Snapshot, error)) (*brtypes.Snapshot, error) {
+	c.mutex.Lock()
+	if trigger, ok := c.pending[key]; ok {
+		c.mutex.Unlock()
+		<-trigger.done
+		return trigger.snapshot, trigger.err
+	}
+	trigger := &coalescedTrigger{done: make(chan struct{})}
+	c.pending[key] = trigger
+	c.mutex.Unlock()
+
+	if c.window > 0 {
+		time.Sleep(c.window)
+	}
+
+	c.mutex.Lock()
+	delete(c.pending, key)
+	c.mutex.Unlock()
+
+	trigger.snapshot, trigger.err = take()
+	close(trigger.done)
+	return trigger.snapshot, trigger.err
+}
@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshotter
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// validDeltaSnapshotsFileName is the name of the file in the temporary directory of the snapstore in which the delta
+// snapshots found to be valid by the garbage collection are persisted, so that they are not fetched again after a restart.
+const validDeltaSnapshotsFileName = "valid-delta-snapshots.json"
+
+// validDeltaSnapshotsFile returns the path of the file in which the valid delta snapshots are persisted, or an empty
+// string if no temporary directory is configured for the snapstore, in which case they are only kept in memory.
+func (ssr *Snapshotter) validDeltaSnapshotsFile() string {
+	if ssr.snapstoreConfig == nil || ssr.snapstoreConfig.TempDir == "" {
+		return ""
+	}
+	return filepath.Join(ssr.snapstoreConfig.TempDir, validDeltaSnapshotsFileName)
+}
+
+// loadValidDeltaSnapshots loads the valid delta snapshots persisted by a previous run of the snapshotter. A file which
+// can't be read is ignored, in which case the delta snapshots are checked again.
+func (ssr *Snapshotter) loadValidDeltaSnapshots() {
+	file := ssr.validDeltaSnapshotsFile()
+	if file == "" {
+		return
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			ssr.logger.Warnf("GC: Failed to read the valid delta snapshots from %s, hence checking them again: %v", file, err)
+		}
+		return
+	}
+	var snapPaths []string
+	if err := json.Unmarshal(data, &snapPaths); err != nil {
+		ssr.logger.Warnf("GC: Failed to parse the valid delta snapshots from %s, hence checking them again: %v", file, err)
+		return
+	}
+	for _, snapPath := range snapPaths {
+		ssr.validDeltaSnapshots[snapPath] = struct{}{}
+	}
+}
+
+// saveValidDeltaSnapshots forgets the valid delta snapshots which are not part of the given snapshots anymore, and
+// persists the remaining ones, replacing the file atomically so that a crash never leaves a partial file behind.
+func (ssr *Snapshotter) saveValidDeltaSnapshots(snapList brtypes.SnapList) {
+	listed := make(map[string]struct{}, len(snapList))
+	for _, snap := range snapList {
+		listed[path.Join(snap.SnapDir, snap.SnapName)] = struct{}{}
+	}
+	snapPaths := make([]string, 0, len(ssr.validDeltaSnapshots))
+	for snapPath := range ssr.validDeltaSnapshots {
+		if _, ok := listed[snapPath]; !ok {
+			delete(ssr.validDeltaSnapshots, snapPath)
+			continue
+		}
+		snapPaths = append(snapPaths, snapPath)
+	}
+	sort.Strings(snapPaths)
+
+	file := ssr.validDeltaSnapshotsFile()
+	if file == "" {
+		return
+	}
+	data, err := json.Marshal(snapPaths)
+	if err != nil {
+		ssr.logger.Warnf("GC: Failed to marshal the valid delta snapshots: %v", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		ssr.logger.Warnf("GC: Failed to create the directory of %s: %v", file, err)
+		return
+	}
+	tmpFile := file + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0600); err != nil {
+		ssr.logger.Warnf("GC: Failed to persist the valid delta snapshots to %s: %v", file, err)
+		return
+	}
+	if err := os.Rename(tmpFile, file); err != nil {
+		ssr.logger.Warnf("GC: Failed to persist the valid delta snapshots to %s: %v", file, err)
+		_ = os.Remove(tmpFile)
+	}
+}
@@ -5,11 +5,14 @@
 package snapstore
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	"os"
 	"path"
@@ -20,6 +23,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-blob-go/azblob"
 	"github.com/sirupsen/logrus"
 
@@ -31,10 +35,15 @@ const (
 	absCredentialJSONFile  = "AZURE_APPLICATION_CREDENTIALS_JSON"
 	// AzuriteEndpoint is the environment variable which indicates the endpoint at which the Azurite emulator is hosted
 	AzuriteEndpoint = "AZURE_STORAGE_API_ENDPOINT"
+	// absSnapshotExcludeMetadataKey is the metadata key of the exclude tag on the blobs, as the names of the metadata
+	// of a blob must be valid C# identifiers, which can't contain the dashes of the tag.
+	absSnapshotExcludeMetadataKey = "x_etcd_snapshot_exclude"
 )
 
 // ABSSnapStore is an ABS backed snapstore.
 type ABSSnapStore struct {
+	snapshotNaming
+	listThrottling
 	containerURL *azblob.ContainerURL
 	prefix       string
 	// maxParallelChunkUploads hold the maximum number of parallel chunk uploads allowed.
@@ -61,22 +70,38 @@ func NewABSSnapStore(config *brtypes.SnapstoreConfig) (*ABSSnapStore, error) {
 		return nil, fmt.Errorf("failed to create shared key credentials: %v", err)
 	}
 
-	pipeline := azblob.NewPipeline(credentials, azblob.PipelineOptions{
+	p := azblob.NewPipeline(credentials, azblob.PipelineOptions{
 		Retry: azblob.RetryOptions{
 			TryTimeout: downloadTimeout,
-		}})
+		},
+		HTTPSender: newABSHTTPSender(&http.Client{Transport: sharedHTTPTransport(config)}),
+	})
 
 	blobURL, err := ConstructBlobServiceURL(credentials)
 	if err != nil {
 		return nil, err
 	}
 
-	serviceURL := azblob.NewServiceURL(*blobURL, pipeline)
+	serviceURL := azblob.NewServiceURL(*blobURL, p)
 	containerURL := serviceURL.NewContainerURL(config.Container)
 
 	return GetABSSnapstoreFromClient(config.Container, config.Prefix, config.TempDir, config.MaxParallelChunkUploads, config.MinChunkSize, &containerURL)
 }
 
+// newABSHTTPSender returns the factory of the pipeline policy sending the requests to the storage account with the
+// given HTTP client, so that its connections are pooled as per the connection pool settings of the snapstore config.
+func newABSHTTPSender(client *http.Client) pipeline.Factory {
+	return pipeline.FactoryFunc(func(_ pipeline.Policy, _ *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		return func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+			r, err := client.Do(request.WithContext(ctx))
+			if err != nil {
+				err = pipeline.NewError(err, "HTTP request failed")
+			}
+			return pipeline.NewHTTPResponse(r), err
+		}
+	})
+}
+
 // ConstructBlobServiceURL constructs the Blob Service URL based on the activation status of the Azurite Emulator.
 // It checks the environment variables for emulator configuration and constructs the URL accordingly.
 // The function expects two environment variables:
@@ -206,7 +231,7 @@ func GetABSSnapstoreFromClient(container, prefix, tempDir string, maxParallelChu
 		if aer.ServiceCode() != azblob.ServiceCodeContainerNotFound {
 			return nil, fmt.Errorf("failed to get properties of container %v with err, %v", container, aer.Error())
 		}
-		return nil, fmt.Errorf("container %s does not exist", container)
+		return nil, fmt.Errorf("container does not exist")
 	}
 	return &ABSSnapStore{
 		prefix:                  prefix,
@@ -230,10 +255,7 @@ func (a *ABSSnapStore) Fetch(snap brtypes.Snapshot) (io.ReadCloser, error) {
 
 // List will return sorted list with all snapshot files on store.
 func (a *ABSSnapStore) List() (brtypes.SnapList, error) {
-	prefixTokens := strings.Split(a.prefix, "/")
-	// Last element of the tokens is backup version
-	// Consider the parent of the backup version level (Required for Backward Compatibility)
-	prefix := path.Join(strings.Join(prefixTokens[:len(prefixTokens)-1], "/"))
+	prefix := a.listPrefix()
 	var snapList brtypes.SnapList
 	opts := azblob.ListBlobsSegmentOptions{Prefix: prefix}
 	for marker := (azblob.Marker{}); marker.NotDone(); {
@@ -245,23 +267,63 @@ func (a *ABSSnapStore) List() (brtypes.SnapList, error) {
 		marker = listBlob.NextMarker
 
 		// Process the blobs returned in this result segment
-		for _, blob := range listBlob.Segment.BlobItems {
-			if strings.Contains(blob.Name, backupVersionV1) || strings.Contains(blob.Name, backupVersionV2) {
-				//the blob may contain the full path in its name including the prefix
-				blobName := strings.TrimPrefix(blob.Name, prefix)
-				s, err := ParseSnapshot(path.Join(prefix, blobName))
-				if err != nil {
-					logrus.Warnf("Invalid snapshot found. Ignoring it:%s\n", blob.Name)
-				} else {
-					snapList = append(snapList, s)
-				}
-			}
-		}
+		snapList = append(snapList, a.parseSnapshotsFromBlobItems(prefix, listBlob.Segment.BlobItems)...)
 	}
 	sort.Sort(snapList)
 	return snapList, nil
 }
 
+// ListPaged will return sorted list with at most limit snapshot files on store, which are listed after the segment
+// of the given marker. The marker is the continuation marker of the blob listing, and is empty after the last page.
+func (a *ABSSnapStore) ListPaged(marker string, limit int) (brtypes.SnapList, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("limit should be greater than zero")
+	}
+	prefix := a.listPrefix()
+	segmentMarker := azblob.Marker{}
+	if marker != "" {
+		segmentMarker.Val = &marker
+	}
+	listBlob, err := a.containerURL.ListBlobsFlatSegment(context.TODO(), segmentMarker, azblob.ListBlobsSegmentOptions{Prefix: prefix, MaxResults: int32(limit)})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list the blobs, error: %v", err)
+	}
+
+	snapList := a.parseSnapshotsFromBlobItems(prefix, listBlob.Segment.BlobItems)
+	sort.Sort(snapList)
+	var nextMarker string
+	if listBlob.NextMarker.NotDone() && listBlob.NextMarker.Val != nil {
+		nextMarker = *listBlob.NextMarker.Val
+	}
+	return snapList, nextMarker, nil
+}
+
+// listPrefix returns the prefix the snapshots are listed under.
+func (a *ABSSnapStore) listPrefix() string {
+	prefixTokens := strings.Split(a.prefix, "/")
+	// Last element of the tokens is backup version
+	// Consider the parent of the backup version level (Required for Backward Compatibility)
+	return path.Join(strings.Join(prefixTokens[:len(prefixTokens)-1], "/"))
+}
+
+// parseSnapshotsFromBlobItems returns the snapshots of the given blobs, ignoring the blobs which aren't snapshots.
+func (a *ABSSnapStore) parseSnapshotsFromBlobItems(prefix string, blobItems []azblob.BlobItem) brtypes.SnapList {
+	var snapList brtypes.SnapList
+	for _, blob := range blobItems {
+		if strings.Contains(blob.Name, backupVersionV1) || strings.Contains(blob.Name, backupVersionV2) {
+			//the blob may contain the full path in its name including the prefix
+			blobName := strings.TrimPrefix(blob.Name, prefix)
+			s, err := a.parseSnapshot(path.Join(prefix, blobName))
+			if err != nil {
+				logrus.Warnf("Invalid snapshot found. Ignoring it:%s\n", blob.Name)
+			} else {
+				snapList = append(snapList, s)
+			}
+		}
+	}
+	return snapList
+}
+
 // Save will write the snapshot to store
 func (a *ABSSnapStore) Save(snap brtypes.Snapshot, rc io.ReadCloser) error {
 	// Save it locally
@@ -352,8 +414,12 @@ func (a *ABSSnapStore) uploadBlock(snap *brtypes.Snapshot, file *os.File, offset
 	blockID := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%010d", partNumber)))
 	ctx, cancel := context.WithTimeout(context.TODO(), chunkUploadTimeout)
 	defer cancel()
-	var transactionMD5 []byte
-	if _, err := blob.StageBlock(ctx, blockID, sr, azblob.LeaseAccessConditions{}, transactionMD5); err != nil {
+	// the transactional MD5 lets ABS reject the block if it was corrupted in transit.
+	transactionMD5 := md5.New()
+	if err := computeChecksum(sr, transactionMD5); err != nil {
+		return err
+	}
+	if _, err := blob.StageBlock(ctx, blockID, sr, azblob.LeaseAccessConditions{}, transactionMD5.Sum(nil)); err != nil {
 		return fmt.Errorf("failed to upload chunk offset: %d, blob: %s, error: %v", offset, blobName, err)
 	}
 	return nil
@@ -389,6 +455,69 @@ func (a *ABSSnapStore) Delete(snap brtypes.Snapshot) error {
 	return nil
 }
 
+// SetRetained sets the exclude tag as metadata on the snapshot blob if retained is true, and clears it otherwise.
+// The other metadata of the snapshot blob is left untouched.
+func (a *ABSSnapStore) SetRetained(snap brtypes.Snapshot, retained bool) error {
+	blobName := path.Join(snap.Prefix, snap.SnapDir, snap.SnapName)
+	blob := a.containerURL.NewBlobURL(blobName)
+	props, err := blob.GetProperties(context.TODO(), azblob.BlobAccessConditions{})
+	if err != nil {
+		return fmt.Errorf("failed to get properties of blob %s with error: %v", blobName, err)
+	}
+	metadata := props.NewMetadata()
+	delete(metadata, absSnapshotExcludeMetadataKey)
+	if retained {
+		metadata[absSnapshotExcludeMetadataKey] = "true"
+	}
+	// the metadata is only replaced if the blob wasn't modified in the meantime, to not lose concurrent changes
+	ac := azblob.BlobAccessConditions{ModifiedAccessConditions: azblob.ModifiedAccessConditions{IfMatch: props.ETag()}}
+	if _, err := blob.SetMetadata(context.TODO(), metadata, ac); err != nil {
+		return fmt.Errorf("failed to set metadata of blob %s with error: %v", blobName, err)
+	}
+	return nil
+}
+
+// IsRetained returns whether the exclude tag is set as metadata on the snapshot blob.
+func (a *ABSSnapStore) IsRetained(snap brtypes.Snapshot) (bool, error) {
+	blobName := path.Join(snap.Prefix, snap.SnapDir, snap.SnapName)
+	props, err := a.containerURL.NewBlobURL(blobName).GetProperties(context.TODO(), azblob.BlobAccessConditions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to get properties of blob %s with error: %v", blobName, err)
+	}
+	_, ok := props.NewMetadata()[absSnapshotExcludeMetadataKey]
+	return ok, nil
+}
+
+// Verify checks that the container exists and is accessible by listing a single blob under the prefix, and if write
+// is true, that a blob can be written under the prefix, by writing and deleting a small blob.
+func (a *ABSSnapStore) Verify(write bool) error {
+	ctx, cancel := context.WithTimeout(context.TODO(), providerConnectionTimeout)
+	defer cancel()
+	if _, err := a.containerURL.ListBlobsFlatSegment(ctx, azblob.Marker{}, azblob.ListBlobsSegmentOptions{Prefix: a.prefix, MaxResults: 1}); err != nil {
+		if aer, ok := err.(azblob.StorageError); ok {
+			switch {
+			case aer.ServiceCode() == azblob.ServiceCodeContainerNotFound:
+				return newVerificationError(CredentialsFailureBucketMissing, "container does not exist")
+			case aer.Response() != nil && (aer.Response().StatusCode == http.StatusUnauthorized || aer.Response().StatusCode == http.StatusForbidden):
+				return newVerificationError(CredentialsFailureAuth, "access to container is denied: %w", err)
+			}
+		}
+		return fmt.Errorf("failed to access container: %w", err)
+	}
+	if !write {
+		return nil
+	}
+
+	blob := a.containerURL.NewBlockBlobURL(path.Join(a.prefix, verificationObjectName))
+	if _, err := blob.Upload(ctx, bytes.NewReader(nil), azblob.BlobHTTPHeaders{}, azblob.Metadata{}, azblob.BlobAccessConditions{}); err != nil {
+		return fmt.Errorf("failed to write to container: %v", err)
+	}
+	if _, err := blob.Delete(ctx, azblob.DeleteSnapshotsOptionInclude, azblob.BlobAccessConditions{}); err != nil {
+		return fmt.Errorf("failed to delete from container: %v", err)
+	}
+	return nil
+}
+
 // GetABSCredentialsLastModifiedTime returns the latest modification timestamp of the ABS credential file(s)
 func GetABSCredentialsLastModifiedTime() (time.Time, error) {
 	// TODO: @renormalize Remove this extra handling in v0.31.0
@@ -7,6 +7,8 @@ package snapstore_test
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/base64"
 	"encoding/xml"
 	"fmt"
 	"io"
@@ -25,6 +27,20 @@ import (
 	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
 )
 
+// absMetadataHeaderPrefix is the prefix of the headers carrying the metadata of a blob.
+const absMetadataHeaderPrefix = "x-ms-meta-"
+
+var (
+	// blockContentMD5s holds the Content-MD5 header of the blocks staged on the fake ABS server by their block ID.
+	blockContentMD5s = map[string]string{}
+	// corruptNextBlock corrupts the content of the next block staged on the fake ABS server, as if it was corrupted
+	// in transit.
+	corruptNextBlock bool
+	// rejectedBlocks is the number of blocks rejected by the fake ABS server, as their content didn't match their
+	// Content-MD5 header.
+	rejectedBlocks int
+)
+
 func newFakeABSSnapstore() brtypes.SnapStore {
 	f := []pipeline.Factory{
 		pipeline.MethodFactoryMarker(),
@@ -45,24 +61,33 @@ func newFakeABSSnapstore() brtypes.SnapStore {
 
 // newFakePolicyFactory creates a 'Fake' policy factory.
 func newFakePolicyFactory(bucket, prefix string, objectMap map[string]*[]byte) pipeline.Factory {
-	return &fakePolicyFactory{bucket, prefix, objectMap}
+	return &fakePolicyFactory{
+		bucket:           bucket,
+		prefix:           prefix,
+		objectMap:        objectMap,
+		multiPartUploads: make(map[string]map[string][]byte, 0),
+	}
 }
 
 type fakePolicyFactory struct {
 	bucket    string
 	prefix    string
 	objectMap map[string]*[]byte
+	// multiPartUploads holds the staged blocks across the requests, as the pipeline creates a new policy per request.
+	multiPartUploads      map[string]map[string][]byte
+	multiPartUploadsMutex sync.Mutex
 }
 
 // New initializes a Fake policy object.
 func (f *fakePolicyFactory) New(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.Policy {
 	return &fakePolicy{
-		next:             next,
-		po:               po,
-		bucket:           f.bucket,
-		prefix:           f.prefix,
-		objectMap:        f.objectMap,
-		multiPartUploads: make(map[string]map[string][]byte, 0),
+		next:                  next,
+		po:                    po,
+		bucket:                f.bucket,
+		prefix:                f.prefix,
+		objectMap:             f.objectMap,
+		multiPartUploads:      f.multiPartUploads,
+		multiPartUploadsMutex: &f.multiPartUploadsMutex,
 	}
 }
 
@@ -73,7 +98,7 @@ type fakePolicy struct {
 	prefix                string
 	objectMap             map[string]*[]byte
 	multiPartUploads      map[string]map[string][]byte
-	multiPartUploadsMutex sync.Mutex
+	multiPartUploadsMutex *sync.Mutex
 }
 
 // Do method is called on pipeline to process the request. This will internally call the `Do` method
@@ -84,6 +109,7 @@ func (p *fakePolicy) Do(ctx context.Context, request pipeline.Request) (response
 		return nil, err
 	}
 	httpReq.ContentLength = request.ContentLength
+	httpReq.Header = request.Header
 
 	httpResp := &http.Response{
 		Request: httpReq,
@@ -98,6 +124,8 @@ func (p *fakePolicy) Do(ctx context.Context, request pipeline.Request) (response
 		} else {
 			p.handleBlobGetOperation(httpResp)
 		}
+	case "HEAD":
+		p.handleBlobGetPropertiesOperation(httpResp)
 	case "PUT":
 		p.handleBlobPutOperation(httpResp)
 	case "DELETE":
@@ -194,15 +222,58 @@ func (p *fakePolicy) handleBlobPutOperation(w *http.Response) {
 	)
 
 	switch comp {
+	case "":
+		content := []byte{}
+		if w.Request.Body != nil {
+			var err error
+			if content, err = io.ReadAll(w.Request.Body); err != nil {
+				w.StatusCode = http.StatusBadRequest
+				w.Body = io.NopCloser(strings.NewReader(fmt.Sprintf("failed to read content %v", err)))
+				return
+			}
+		}
+		p.objectMap[key] = &content
+		w.StatusCode = http.StatusCreated
+
+	case "metadata":
+		if _, ok := p.objectMap[key]; !ok {
+			w.StatusCode = http.StatusNotFound
+			break
+		}
+		metadata := map[string]string{}
+		for k, v := range w.Request.Header {
+			if strings.HasPrefix(strings.ToLower(k), absMetadataHeaderPrefix) {
+				metadata[strings.ToLower(k[len(absMetadataHeaderPrefix):])] = v[0]
+			}
+		}
+		objectMetadata[key] = metadata
+		w.StatusCode = http.StatusOK
+
 	case "block":
 		content := make([]byte, w.Request.ContentLength)
-		if _, err := w.Request.Body.Read(content); err != nil {
+		if _, err := io.ReadFull(w.Request.Body, content); err != nil {
 			w.StatusCode = http.StatusBadRequest
 			w.Body = io.NopCloser(strings.NewReader(fmt.Sprintf("failed to read content %v", err)))
 			return
 		}
 
 		p.multiPartUploadsMutex.Lock()
+		if corruptNextBlock {
+			corruptNextBlock = false
+			content[0] ^= 0xff
+		}
+		if contentMD5 := w.Request.Header.Get("Content-MD5"); contentMD5 != "" {
+			sum := md5.Sum(content)
+			if contentMD5 != base64.StdEncoding.EncodeToString(sum[:]) {
+				rejectedBlocks++
+				p.multiPartUploadsMutex.Unlock()
+				w.StatusCode = http.StatusBadRequest
+				w.Header = http.Header{"X-Ms-Error-Code": []string{"Md5Mismatch"}}
+				w.Body = http.NoBody
+				return
+			}
+			blockContentMD5s[blockid] = contentMD5
+		}
 		blockList, ok := p.multiPartUploads[key]
 		if !ok {
 			blockList = make(map[string][]byte, 0)
@@ -249,6 +320,22 @@ func (p *fakePolicy) handleBlobGetOperation(w *http.Response) {
 	}
 }
 
+// handleBlobGetPropertiesOperation on HEAD request `/testContainer/testObject` responds with a `GetProperties` response
+// carrying the metadata of the blob.
+func (p *fakePolicy) handleBlobGetPropertiesOperation(w *http.Response) {
+	key := parseObjectNamefromURL(w.Request.URL)
+	w.Body = http.NoBody
+	if _, ok := p.objectMap[key]; !ok {
+		w.StatusCode = http.StatusNotFound
+		return
+	}
+	w.Header = http.Header{}
+	for k, v := range objectMetadata[key] {
+		w.Header.Set(absMetadataHeaderPrefix+k, v)
+	}
+	w.StatusCode = http.StatusOK
+}
+
 // handleDeleteObject on delete request `/testContainer/testObject` responds with a `Delete` response.
 func (p *fakePolicy) handleDeleteObject(w *http.Response) {
 	key := parseObjectNamefromURL(w.Request.URL)
@@ -0,0 +1,191 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+const cacheTempFilePrefix = ".tmp-"
+
+// CachingSnapStore is a snapstore which keeps the snapshots fetched from the underlying snapstore in a local
+// cache directory, so that the snapshots fetched again, e.g. by the restorations of the other members on the
+// same node, are read from the disk instead of the storage provider.
+type CachingSnapStore struct {
+	brtypes.SnapStore
+	dir     string
+	maxSize int64
+	ttl     time.Duration
+	mutex   sync.Mutex
+}
+
+// NewCachingSnapStore returns a caching snapstore which caches the snapshots fetched from the given snapstore
+// in the given directory. The cached snapshots are fetched again once they are older than the given ttl,
+// and the oldest cached snapshots are evicted once the cache grows beyond the given maximum size in bytes.
+func NewCachingSnapStore(store brtypes.SnapStore, dir string, maxSize int64, ttl time.Duration) (*CachingSnapStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot cache directory %s: %v", dir, err)
+	}
+	return &CachingSnapStore{
+		SnapStore: store,
+		dir:       dir,
+		maxSize:   maxSize,
+		ttl:       ttl,
+	}, nil
+}
+
+// Fetch should open reader for the snapshot file from the cache, fetching it from the underlying snapstore
+// if it isn't cached or the cached snapshot has expired. If the underlying snapstore returns the size of the snapshots,
+// the cached snapshot is only read if the size of the snapshot object didn't change, so that a snapshot overwritten
+// in the underlying snapstore is fetched again instead of being read from the cache until it expires.
+func (s *CachingSnapStore) Fetch(snap brtypes.Snapshot) (io.ReadCloser, error) {
+	size := int64(-1)
+	if _, ok := unwrapSnapStore(s.SnapStore).(brtypes.SizingSnapStore); ok {
+		var err error
+		if size, err = SnapshotSize(s.SnapStore, snap); err != nil {
+			return nil, err
+		}
+	}
+	cachePath := s.cachePath(snap, size)
+	if fi, err := os.Stat(cachePath); err == nil {
+		if time.Since(fi.ModTime()) < s.ttl {
+			if f, err := os.Open(cachePath); err == nil {
+				return f, nil
+			}
+		}
+		_ = os.Remove(cachePath)
+	}
+
+	rc, err := s.SnapStore.Fetch(snap)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	// The snapshot is written to a temporary file first, so that concurrent fetches of the same snapshot
+	// never read a partially written cache entry.
+	tmpFile, err := os.CreateTemp(s.dir, cacheTempFilePrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot cache file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := io.Copy(tmpFile, rc); err != nil {
+		tmpFile.Close()
+		return nil, fmt.Errorf("failed to cache snapshot %s: %v", snap.SnapName, err)
+	}
+	// The temporary file is kept open and read from the start, so that the snapshot can be read even if the cache
+	// entry is evicted or replaced by concurrent fetches right after it is renamed into place.
+	if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
+		tmpFile.Close()
+		return nil, fmt.Errorf("failed to cache snapshot %s: %v", snap.SnapName, err)
+	}
+	// the entries of the previous versions of the snapshot are not read anymore
+	s.removeCacheEntries(snap)
+	if err := os.Rename(tmpFile.Name(), cachePath); err != nil {
+		tmpFile.Close()
+		return nil, fmt.Errorf("failed to cache snapshot %s: %v", snap.SnapName, err)
+	}
+	if err := s.evict(); err != nil {
+		tmpFile.Close()
+		return nil, err
+	}
+	return tmpFile, nil
+}
+
+// Save will write the snapshot to the underlying snapstore, invalidating its cache entries.
+func (s *CachingSnapStore) Save(snap brtypes.Snapshot, rc io.ReadCloser) error {
+	s.removeCacheEntries(snap)
+	return s.SnapStore.Save(snap, rc)
+}
+
+// Delete should delete the snapshot file from the underlying snapstore and from the cache.
+func (s *CachingSnapStore) Delete(snap brtypes.Snapshot) error {
+	s.removeCacheEntries(snap)
+	return s.SnapStore.Delete(snap)
+}
+
+// cachePath returns the path of the cache entry of the given snapshot with the given object size, which is keyed by
+// the hash of the full object name of the snapshot and by the object size, if it is known, i.e. not negative.
+func (s *CachingSnapStore) cachePath(snap brtypes.Snapshot, size int64) string {
+	cachePath := s.cachePathPrefix(snap)
+	if size >= 0 {
+		cachePath += "-" + strconv.FormatInt(size, 10)
+	}
+	return cachePath
+}
+
+// cachePathPrefix returns the prefix of the paths of the cache entries of the given snapshot, which is shared by the
+// entries of all the object sizes of the snapshot.
+func (s *CachingSnapStore) cachePathPrefix(snap brtypes.Snapshot) string {
+	sum := sha256.Sum256([]byte(path.Join(snap.Prefix, snap.SnapDir, snap.SnapName)))
+	return filepath.Join(s.dir, path.Base(snap.SnapName)+"-"+hex.EncodeToString(sum[:]))
+}
+
+// removeCacheEntries removes the cache entries of the given snapshot of all its object sizes.
+func (s *CachingSnapStore) removeCacheEntries(snap brtypes.Snapshot) {
+	prefix := filepath.Base(s.cachePathPrefix(snap))
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if name := entry.Name(); name == prefix || strings.HasPrefix(name, prefix+"-") {
+			_ = os.Remove(filepath.Join(s.dir, name))
+		}
+	}
+}
+
+// evict removes the oldest cache entries until the size of the cache doesn't exceed its maximum size.
+func (s *CachingSnapStore) evict() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot cache directory %s: %v", s.dir, err)
+	}
+	var (
+		files []os.FileInfo
+		size  int64
+	)
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), cacheTempFilePrefix) {
+			continue
+		}
+		fi, err := entry.Info()
+		if err != nil {
+			// The entry has been removed concurrently.
+			continue
+		}
+		files = append(files, fi)
+		size += fi.Size()
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+	for _, fi := range files {
+		if size <= s.maxSize {
+			break
+		}
+		if err := os.Remove(filepath.Join(s.dir, fi.Name())); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to evict cached snapshot %s: %v", fi.Name(), err)
+		}
+		size -= fi.Size()
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapstore
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+	"github.com/sirupsen/logrus"
+)
+
+// SaveChainManifest atomically replaces the chain manifest in the given snapstore with the given manifest.
+// Returns an error if the snapstore doesn't support chain manifests.
+func SaveChainManifest(store brtypes.SnapStore, manifest *brtypes.ChainManifest) error {
+	if _, ok := store.(*DatePartitionedSnapStore); ok {
+		// the snapshots which weren't created for the snapstore don't know the partition they were saved to
+		for _, snap := range manifest.Snapshots() {
+			if snap.SnapDir == "" {
+				snap.SnapDir = GetDatePartition(snap.CreatedOn)
+			}
+		}
+	}
+	completeKindPrefixes(store, manifest)
+	cs, ok := unwrapSnapStore(store).(brtypes.ChainManifestSnapStore)
+	if !ok {
+		return fmt.Errorf("snapstore does not support chain manifests")
+	}
+	return cs.SaveChainManifest(manifest)
+}
+
+// FetchChainManifest returns the chain manifest from the given snapstore, or nil if no chain manifest was saved.
+// Returns an error if the snapstore doesn't support chain manifests.
+func FetchChainManifest(store brtypes.SnapStore) (*brtypes.ChainManifest, error) {
+	cs, ok := unwrapSnapStore(store).(brtypes.ChainManifestSnapStore)
+	if !ok {
+		return nil, fmt.Errorf("snapstore does not support chain manifests")
+	}
+	return cs.FetchChainManifest()
+}
+
+// DeleteChainManifest deletes the chain manifest from the given snapstore, if any.
+// Returns an error if the snapstore doesn't support chain manifests.
+func DeleteChainManifest(store brtypes.SnapStore) error {
+	cs, ok := unwrapSnapStore(store).(brtypes.ChainManifestSnapStore)
+	if !ok {
+		return fmt.Errorf("snapstore does not support chain manifests")
+	}
+	return cs.DeleteChainManifest()
+}
+
+// VerifyChainManifest verifies that the snapshots of the given chain manifest cover the revisions from its full
+// snapshot up to its latest revision without a gap, and that all of them are present in the given snapstore with the
+// object size recorded in the manifest. An error means that the manifest drifted from the snapshots in the snapstore,
+// which have to be listed instead.
+func VerifyChainManifest(store brtypes.SnapStore, manifest *brtypes.ChainManifest) error {
+	if manifest.FullSnapshot == nil {
+		return fmt.Errorf("chain manifest has no full snapshot")
+	}
+	if manifest.LatestRevision == 0 {
+		return fmt.Errorf("chain manifest has no latest revision")
+	}
+	deltaSnaps := append([]*brtypes.ChainManifestSnapshot(nil), manifest.DeltaSnapshots...)
+	sort.Slice(deltaSnaps, func(i, j int) bool { return deltaSnaps[i].StartRevision < deltaSnaps[j].StartRevision })
+	revision := manifest.FullSnapshot.LastRevision
+	for _, snap := range deltaSnaps {
+		if snap.StartRevision != revision+1 {
+			return fmt.Errorf("chain manifest misses the snapshots of revisions %d to %d", revision+1, snap.StartRevision-1)
+		}
+		revision = snap.LastRevision
+	}
+	if revision != manifest.LatestRevision {
+		return fmt.Errorf("snapshots of chain manifest end at revision %d instead of its latest revision %d", revision, manifest.LatestRevision)
+	}
+	for _, snap := range manifest.Snapshots() {
+		size, err := SnapshotSize(store, snap.Snapshot)
+		if err != nil {
+			return fmt.Errorf("failed to get size of snapshot %s of chain manifest: %v", path.Join(snap.SnapDir, snap.SnapName), err)
+		}
+		if size != snap.ObjectSizeBytes {
+			return fmt.Errorf("snapshot %s of chain manifest has size %d in snapstore instead of %d", path.Join(snap.SnapDir, snap.SnapName), size, snap.ObjectSizeBytes)
+		}
+	}
+	return nil
+}
+
+// completeChainManifest sets the given prefix of the snapstore on the snapshots of the chain manifest without a prefix,
+// such as the ones taken by the snapshotter, and the size of their object on the snapshots without an object size.
+// The object size of a snapshot whose size can't be determined, such as one deleted from the snapstore, is left unset,
+// so that the chain manifest fails its verification instead of failing to be saved and lagging behind the chain.
+func completeChainManifest(manifest *brtypes.ChainManifest, prefix string, size func(brtypes.Snapshot) (int64, error)) {
+	for _, snap := range manifest.Snapshots() {
+		if snap.Prefix == "" {
+			snap.Prefix = prefix
+		}
+		if snap.ObjectSizeBytes > 0 {
+			continue
+		}
+		objectSize, err := size(snap.Snapshot)
+		if err != nil {
+			logrus.Warnf("Failed to get size of snapshot %s of chain manifest: %v", path.Join(snap.SnapDir, snap.SnapName), err)
+			continue
+		}
+		snap.ObjectSizeBytes = objectSize
+	}
+}
+
+// isChainManifestObject returns whether the object at the given path is the chain manifest, or a temporary file
+// written while replacing it, which are stored alongside the snapshots but aren't snapshots.
+func isChainManifestObject(objectPath string) bool {
+	return strings.HasPrefix(path.Base(objectPath), brtypes.ChainManifestName)
+}
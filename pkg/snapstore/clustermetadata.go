@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapstore
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// SaveClusterMetadata saves the given cluster metadata along with the given full snapshot in the given snapstore.
+// Returns an error if the snapstore doesn't support cluster metadata.
+func SaveClusterMetadata(store brtypes.SnapStore, snap *brtypes.Snapshot, metadata *brtypes.ClusterMetadata) error {
+	cs, s, err := clusterMetadataSnapStore(store, snap)
+	if err != nil {
+		return err
+	}
+	return cs.SaveClusterMetadata(s, metadata)
+}
+
+// FetchClusterMetadata returns the cluster metadata saved along with the given full snapshot in the given snapstore,
+// or nil if none was saved. Returns an error if the snapstore doesn't support cluster metadata.
+func FetchClusterMetadata(store brtypes.SnapStore, snap *brtypes.Snapshot) (*brtypes.ClusterMetadata, error) {
+	cs, s, err := clusterMetadataSnapStore(store, snap)
+	if err != nil {
+		return nil, err
+	}
+	return cs.FetchClusterMetadata(s)
+}
+
+// CopyClusterMetadata copies the cluster metadata saved along with the given full snapshot in the source snapstore,
+// if any, to the destination snapstore, along with the copy of the snapshot. Nothing is copied if the source
+// snapstore doesn't support cluster metadata.
+func CopyClusterMetadata(source, destination brtypes.SnapStore, snap *brtypes.Snapshot) error {
+	sourceStore, s, err := clusterMetadataSnapStore(source, snap)
+	if err != nil {
+		return nil
+	}
+	metadata, err := sourceStore.FetchClusterMetadata(s)
+	if err != nil || metadata == nil {
+		return err
+	}
+	// the copy of the snapshot is saved under the prefix of the destination snapstore
+	copied := *snap
+	copied.Prefix = ""
+	return SaveClusterMetadata(destination, &copied, metadata)
+}
+
+// ValidateRecordClusterMetadata returns an error if the cluster metadata is recorded as per the given snapshotter
+// config, but the snapstores of the given storage provider can't save it.
+func ValidateRecordClusterMetadata(provider string, config *brtypes.SnapshotterConfig) error {
+	if config == nil || !config.RecordClusterMetadata {
+		return nil
+	}
+	if !supportsObjectsAlongsideSnapshots(provider) {
+		return fmt.Errorf("recording the cluster metadata is not supported by storage provider %s, only by the Local and the S3 compatible storage providers", provider)
+	}
+	return nil
+}
+
+// clusterMetadataSnapStore returns the given snapstore as a snapstore of cluster metadata, which is saved next to the
+// full snapshots, but isn't fetched through the fetch cache. The given snapshot is returned along with the partition
+// it was saved to by a date partitioned snapstore.
+func clusterMetadataSnapStore(store brtypes.SnapStore, snap *brtypes.Snapshot) (brtypes.ClusterMetadataSnapStore, brtypes.Snapshot, error) {
+	s := *snap
+	// the snapshots copied from another snapstore don't know the partition they were saved to
+	SetDatePartition(store, &s)
+	// the cluster metadata is saved next to the full snapshots, also if they are saved under a prefix of their own
+	cs, ok := snapStoreOfKind(store, brtypes.SnapshotKindFull).(brtypes.ClusterMetadataSnapStore)
+	if !ok {
+		return nil, s, fmt.Errorf("snapstore does not support cluster metadata")
+	}
+	return cs, s, nil
+}
+
+// clusterMetadataPath returns the path of the cluster metadata object of the given full snapshot, which is saved under
+// the given prefix if the snapshot doesn't know its prefix.
+func clusterMetadataPath(snap brtypes.Snapshot, prefix string) string {
+	if snap.Prefix != "" {
+		prefix = snap.Prefix
+	}
+	return path.Join(prefix, snap.SnapDir, snap.SnapName+brtypes.ClusterMetadataSuffix)
+}
+
+// isClusterMetadataObject returns whether the object at the given path is the cluster metadata of a full snapshot, or a
+// temporary file written while saving it, which are stored next to the snapshots but aren't snapshots.
+func isClusterMetadataObject(objectPath string) bool {
+	return strings.Contains(path.Base(objectPath), brtypes.ClusterMetadataSuffix)
+}
@@ -0,0 +1,162 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapstore
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gardener/etcd-backup-restore/pkg/compressor"
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// SaveCompressionDictionary saves the given compression dictionary in the given snapstore and returns its identifier.
+// Returns an error if the snapstore doesn't support compression dictionaries.
+func SaveCompressionDictionary(store brtypes.SnapStore, dictionary []byte) (uint32, error) {
+	ds, err := compressionDictionarySnapStore(store)
+	if err != nil {
+		return 0, err
+	}
+	id := compressor.DictionaryID(dictionary)
+	return id, ds.SaveCompressionDictionary(id, dictionary)
+}
+
+// ListCompressionDictionaries returns the compression dictionaries saved in the given snapstore.
+// Returns an error if the snapstore doesn't support compression dictionaries.
+func ListCompressionDictionaries(store brtypes.SnapStore) ([]brtypes.CompressionDictionary, error) {
+	ds, err := compressionDictionarySnapStore(store)
+	if err != nil {
+		return nil, err
+	}
+	return ds.ListCompressionDictionaries()
+}
+
+// DeleteCompressionDictionary deletes the compression dictionary with the given identifier from the given snapstore.
+// Returns an error if the snapstore doesn't support compression dictionaries.
+func DeleteCompressionDictionary(store brtypes.SnapStore, id uint32) error {
+	ds, err := compressionDictionarySnapStore(store)
+	if err != nil {
+		return err
+	}
+	return ds.DeleteCompressionDictionary(id)
+}
+
+// ValidateCompressionPolicy returns an error if the delta snapshots are compressed with a dictionary as per the given
+// compression config, but the snapstores of the given storage provider can't save the compression dictionaries.
+func ValidateCompressionPolicy(provider string, config *compressor.CompressionConfig) error {
+	if config == nil || !config.Enabled || config.CompressionPolicy != compressor.ZlibDictCompressionPolicy {
+		return nil
+	}
+	if !supportsObjectsAlongsideSnapshots(provider) {
+		return fmt.Errorf("compression policy %s is not supported by storage provider %s, only by the Local and the S3 compatible storage providers", config.CompressionPolicy, provider)
+	}
+	return nil
+}
+
+// CopyCompressionDictionaries copies the compression dictionaries of the source snapstore which are missing in the
+// destination snapstore, so that the delta snapshots copied along with them can be decompressed. Nothing is copied if
+// the source snapstore doesn't support compression dictionaries.
+func CopyCompressionDictionaries(source, destination brtypes.SnapStore) error {
+	sourceStore, err := compressionDictionarySnapStore(source)
+	if err != nil {
+		return nil
+	}
+	dictionaries, err := sourceStore.ListCompressionDictionaries()
+	if err != nil || len(dictionaries) == 0 {
+		return err
+	}
+	destinationStore, err := compressionDictionarySnapStore(destination)
+	if err != nil {
+		return fmt.Errorf("destination snapstore does not support compression dictionaries")
+	}
+	existingDictionaries, err := destinationStore.ListCompressionDictionaries()
+	if err != nil {
+		return err
+	}
+	existing := make(map[uint32]struct{}, len(existingDictionaries))
+	for _, dictionary := range existingDictionaries {
+		existing[dictionary.ID] = struct{}{}
+	}
+	for _, dictionary := range dictionaries {
+		if _, ok := existing[dictionary.ID]; ok {
+			continue
+		}
+		data, err := sourceStore.FetchCompressionDictionary(dictionary.ID)
+		if err != nil {
+			return err
+		}
+		if err := destinationStore.SaveCompressionDictionary(dictionary.ID, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewCompressionDictionaryFetcher returns a fetcher of the compression dictionaries saved in the given snapstore,
+// which fetches each dictionary only once, as all the delta snapshots of a chain are compressed with the same one.
+func NewCompressionDictionaryFetcher(store brtypes.SnapStore) compressor.DictionaryFetcher {
+	var (
+		mutex        sync.Mutex
+		dictionaries = map[uint32][]byte{}
+	)
+	return func(id uint32) ([]byte, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if dictionary, ok := dictionaries[id]; ok {
+			return dictionary, nil
+		}
+		ds, err := compressionDictionarySnapStore(store)
+		if err != nil {
+			return nil, err
+		}
+		dictionary, err := ds.FetchCompressionDictionary(id)
+		if err != nil {
+			return nil, err
+		}
+		if compressor.DictionaryID(dictionary) != id {
+			return nil, fmt.Errorf("compression dictionary %08x is corrupt", id)
+		}
+		dictionaries[id] = dictionary
+		return dictionary, nil
+	}
+}
+
+// compressionDictionarySnapStore returns the given snapstore as a snapstore of compression dictionaries, which are
+// saved under the prefix of the snapstore, outside of the date based partitions and the fetch cache.
+func compressionDictionarySnapStore(store brtypes.SnapStore) (brtypes.CompressionDictionarySnapStore, error) {
+	ds, ok := unwrapSnapStore(store).(brtypes.CompressionDictionarySnapStore)
+	if !ok {
+		return nil, fmt.Errorf("snapstore does not support compression dictionaries")
+	}
+	return ds, nil
+}
+
+// compressionDictionaryName returns the name of the object of the compression dictionary with the given identifier.
+func compressionDictionaryName(id uint32) string {
+	return fmt.Sprintf("%s%08x", brtypes.CompressionDictionaryPrefix, id)
+}
+
+// parseCompressionDictionaryName returns the identifier of the compression dictionary stored at the given path,
+// and whether the object is a compression dictionary at all.
+func parseCompressionDictionaryName(objectPath string) (uint32, bool) {
+	name := path.Base(objectPath)
+	if !strings.HasPrefix(name, brtypes.CompressionDictionaryPrefix) {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(name, brtypes.CompressionDictionaryPrefix), 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint32(id), true
+}
+
+// isCompressionDictionaryObject returns whether the object at the given path is a compression dictionary, or a
+// temporary file written while saving one, which are stored alongside the snapshots but aren't snapshots.
+func isCompressionDictionaryObject(objectPath string) bool {
+	return strings.HasPrefix(path.Base(objectPath), brtypes.CompressionDictionaryPrefix)
+}
@@ -0,0 +1,144 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapstore
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// CredentialsFailure is the class of the error with which the verification of the credentials of a snapstore failed.
+type CredentialsFailure string
+
+const (
+	// CredentialsFailureAuth indicates that the storage provider rejected the credentials, or denied the access with them.
+	CredentialsFailureAuth CredentialsFailure = "auth"
+	// CredentialsFailureNetwork indicates that the storage provider could not be reached.
+	CredentialsFailureNetwork CredentialsFailure = "network"
+	// CredentialsFailureBucketMissing indicates that the bucket or container of the snapstore does not exist.
+	CredentialsFailureBucketMissing CredentialsFailure = "bucket-missing"
+	// CredentialsFailureUnsupported indicates that the snapstore doesn't support the verification of its credentials.
+	CredentialsFailureUnsupported CredentialsFailure = "unsupported"
+	// CredentialsFailureUnknown indicates that the error could not be classified.
+	CredentialsFailureUnknown CredentialsFailure = "unknown"
+)
+
+// verificationError is the error of the verification of a snapstore whose failure was classified by the snapstore.
+type verificationError struct {
+	failure CredentialsFailure
+	err     error
+}
+
+func (e *verificationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *verificationError) Unwrap() error {
+	return e.err
+}
+
+// newVerificationError returns the error of the verification of a snapstore with the given class of failure.
+func newVerificationError(failure CredentialsFailure, format string, args ...interface{}) error {
+	return &verificationError{failure: failure, err: fmt.Errorf(format, args...)}
+}
+
+// CredentialsVerificationResult is the result of the verification of the credentials of a snapstore.
+type CredentialsVerificationResult struct {
+	// Passed is true if an authenticated request to the storage provider succeeded.
+	Passed bool `json:"passed"`
+	// Failure is the class of the error with which the verification failed.
+	Failure CredentialsFailure `json:"failure,omitempty"`
+	// Err is the error with which the verification failed.
+	Err error `json:"-"`
+}
+
+// VerifyCredentials verifies that the credentials of the snapstore of the given config are accepted by its storage
+// provider, with the read-only probe of VerifySnapstoreCredentials. Nothing is written to the snapstore and no snapshot
+// is taken, so that the credentials of a new bucket can be validated before the snapshotter is enabled.
+func VerifyCredentials(config *brtypes.SnapstoreConfig) *CredentialsVerificationResult {
+	store, err := GetSnapstore(config)
+	if err != nil {
+		return newFailedCredentialsVerificationResult(fmt.Errorf("failed to create snapstore: %w", err))
+	}
+	return VerifySnapstoreCredentials(store)
+}
+
+// VerifySnapstoreCredentials verifies that the credentials of the given snapstore are accepted by its storage provider,
+// by verifying that the bucket or container of each of the prefixes of the snapstore can be read, as VerifySnapstore
+// does for a source snapstore, or by listing a single page of one snapshot if the snapstore can't be verified but
+// listed in pages. The verification fails as unsupported for the other snapstores, which could only be listed in full.
+func VerifySnapstoreCredentials(store brtypes.SnapStore) *CredentialsVerificationResult {
+	stores := []brtypes.SnapStore{unwrapSnapStore(store)}
+	if ks, ok := unwrapKindPrefixedSnapStore(store); ok {
+		// the snapshots of each kind are saved under a prefix of their own
+		stores = ks.snapStores()
+	}
+	for _, s := range stores {
+		var err error
+		switch ps := s.(type) {
+		case brtypes.VerifiableSnapStore:
+			err = ps.Verify(false)
+		case brtypes.PagedSnapStore:
+			_, _, err = ps.ListPaged("", 1)
+		default:
+			return &CredentialsVerificationResult{
+				Failure: CredentialsFailureUnsupported,
+				Err:     fmt.Errorf("the verification of the credentials is not supported by the snapstore"),
+			}
+		}
+		if err != nil {
+			return newFailedCredentialsVerificationResult(err)
+		}
+	}
+	return &CredentialsVerificationResult{Passed: true}
+}
+
+func newFailedCredentialsVerificationResult(err error) *CredentialsVerificationResult {
+	return &CredentialsVerificationResult{
+		Failure: ClassifyCredentialsError(err),
+		Err:     err,
+	}
+}
+
+// ClassifyCredentialsError returns the class of the given error of an authenticated request to a storage provider,
+// as classified by the verification of the snapstore, or by the network error or the HTTP status code it wraps.
+func ClassifyCredentialsError(err error) CredentialsFailure {
+	var verificationErr *verificationError
+	if errors.As(err, &verificationErr) {
+		return verificationErr.failure
+	}
+	for e := err; e != nil; e = causeOf(e) {
+		var netErr net.Error
+		if errors.As(e, &netErr) {
+			return CredentialsFailureNetwork
+		}
+		var statusErr interface{ StatusCode() int }
+		if errors.As(e, &statusErr) {
+			switch statusErr.StatusCode() {
+			case http.StatusNotFound:
+				return CredentialsFailureBucketMissing
+			case http.StatusUnauthorized, http.StatusForbidden:
+				return CredentialsFailureAuth
+			}
+		}
+	}
+	return CredentialsFailureUnknown
+}
+
+// causeOf returns the error wrapped by the given error, also through the original errors of the AWS SDK errors, which
+// are not unwrappable.
+func causeOf(err error) error {
+	if cause := errors.Unwrap(err); cause != nil {
+		return cause
+	}
+	if awsErr, ok := err.(interface{ OrigErr() error }); ok {
+		return awsErr.OrigErr()
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapstore
+
+import (
+	"io"
+	"sort"
+	"time"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// partitionLister is implemented by the snapstores which are able to list a single partition
+// without listing the whole snapstore.
+type partitionLister interface {
+	listPartitions() ([]string, error)
+	listPartition(partition string) (brtypes.SnapList, error)
+}
+
+// DatePartitionedSnapStore is a snapstore which saves the snapshots into monthly partitions of the form
+// YYYY/MM under the prefix of the underlying snapstore.
+type DatePartitionedSnapStore struct {
+	brtypes.SnapStore
+}
+
+// NewDatePartitionedSnapStore returns a date partitioned snapstore which saves the snapshots to the given snapstore.
+func NewDatePartitionedSnapStore(store brtypes.SnapStore) *DatePartitionedSnapStore {
+	return &DatePartitionedSnapStore{
+		SnapStore: store,
+	}
+}
+
+// Save will write the snapshot to the partition of its creation time.
+func (s *DatePartitionedSnapStore) Save(snap brtypes.Snapshot, rc io.ReadCloser) error {
+	SetDatePartition(s, &snap)
+	return s.SnapStore.Save(snap, rc)
+}
+
+// ListPartitions will return the partitions present on store, sorted from oldest to newest.
+func (s *DatePartitionedSnapStore) ListPartitions() ([]string, error) {
+	if pl, ok := asPartitionLister(s.SnapStore); ok {
+		return pl.listPartitions()
+	}
+
+	// The underlying snapstore can't list partitions on its own, hence derive them from the whole snapstore.
+	snapList, err := s.SnapStore.List()
+	if err != nil {
+		return nil, err
+	}
+	var partitions []string
+	seen := map[string]bool{}
+	for _, snap := range snapList {
+		if isDatePartition(snap.SnapDir) && !seen[snap.SnapDir] {
+			seen[snap.SnapDir] = true
+			partitions = append(partitions, snap.SnapDir)
+		}
+	}
+	sort.Strings(partitions)
+	return partitions, nil
+}
+
+// ListPartition will return sorted list with all snapshot files in the given partition.
+func (s *DatePartitionedSnapStore) ListPartition(partition string) (brtypes.SnapList, error) {
+	if pl, ok := asPartitionLister(s.SnapStore); ok {
+		return pl.listPartition(partition)
+	}
+
+	snapList, err := s.SnapStore.List()
+	if err != nil {
+		return nil, err
+	}
+	partitionSnapList := brtypes.SnapList{}
+	for _, snap := range snapList {
+		if snap.SnapDir == partition {
+			partitionSnapList = append(partitionSnapList, snap)
+		}
+	}
+	return partitionSnapList, nil
+}
+
+// IsPartitionListingSupported returns whether the partitions of the given date partitioned snapstore are listed one
+// at a time by the underlying snapstore. Otherwise each partition is listed by listing the whole snapstore, and the
+// whole snapstore is better listed once.
+func IsPartitionListingSupported(store brtypes.SnapStore) bool {
+	ds, ok := store.(*DatePartitionedSnapStore)
+	if !ok {
+		return false
+	}
+	_, ok = asPartitionLister(ds.SnapStore)
+	return ok
+}
+
+// asPartitionLister returns the given snapstore as a partition lister, looking through a caching snapstore. A kind
+// prefixed snapstore isn't a partition lister, as its snapshots are spread across several prefixes.
+func asPartitionLister(store brtypes.SnapStore) (partitionLister, bool) {
+	if cs, ok := store.(*CachingSnapStore); ok {
+		store = cs.SnapStore
+	}
+	pl, ok := store.(partitionLister)
+	return pl, ok
+}
+
+// SetDatePartition sets the directory of the given snapshot without one to the partition of its creation time if the
+// given snapstore is date partitioned, as the snapshot is saved to that partition, so that it can be looked up and
+// deleted again without being listed.
+func SetDatePartition(store brtypes.SnapStore, snap *brtypes.Snapshot) {
+	if _, ok := store.(*DatePartitionedSnapStore); ok && snap.SnapDir == "" {
+		snap.SnapDir = GetDatePartition(snap.CreatedOn)
+	}
+}
+
+// GetDatePartition returns the date partition for snapshots created at the given time.
+func GetDatePartition(t time.Time) string {
+	return t.UTC().Format(brtypes.DatePartitionLayout)
+}
+
+// isDatePartition checks whether the given directory is a date partition.
+func isDatePartition(dir string) bool {
+	_, err := time.Parse(brtypes.DatePartitionLayout, dir)
+	return err == nil
+}
@@ -25,7 +25,17 @@ func NewECSSnapStore(config *brtypes.SnapstoreConfig) (*S3SnapStore, error) {
 	if err != nil {
 		return nil, err
 	}
-	return newGenericS3FromAuthOpt(config.Container, config.Prefix, config.TempDir, config.MaxParallelChunkUploads, config.MinChunkSize, ao)
+	if config.S3Region != "" {
+		ao.region = config.S3Region
+	}
+	store, err := newGenericS3FromAuthOpt(config.Container, config.Prefix, config.TempDir, config.MaxParallelChunkUploads, config.MinChunkSize, genericS3HTTPTransport(config, ao), ao)
+	if err != nil {
+		return nil, err
+	}
+	store.ObjectACL = config.ObjectACL
+	store.ObjectLockProtection = config.ObjectLockProtection
+	store.MultipartThreshold = config.MultipartThreshold
+	return store, nil
 }
 
 // ecsAuthOptionsFromEnv gets ECS provider configuration from environment variables.
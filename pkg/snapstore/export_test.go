@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapstore
+
+import (
+	"net/http"
+	"time"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// SetMaxS3SingleCopyObjectSize sets the upper size limit of an object copied with a single server-side copy by the
+// S3 snapstore, and returns a func restoring the previous limit.
+func SetMaxS3SingleCopyObjectSize(size int64) func() {
+	previous := maxS3SingleCopyObjectSize
+	maxS3SingleCopyObjectSize = size
+	return func() { maxS3SingleCopyObjectSize = previous }
+}
+
+// SinglePutTimeout returns the timeout of the single put of a snapshot of the given size by the S3 snapstore.
+func SinglePutTimeout(size int64) time.Duration {
+	return singlePutTimeout(size)
+}
+
+// SetListThrottlingRetries sets the retries of the throttled listings of the given snapstore.
+func SetListThrottlingRetries(store brtypes.SnapStore, retries int, backoff time.Duration) {
+	unwrapSnapStore(store).(listThrottlingConfigurableSnapStore).setListThrottlingRetries(retries, backoff)
+}
+
+// SetListThrottlingPageSize sets the number of snapshots listed at once by ListWithThrottlingRetries, and returns a
+// func restoring the previous page size.
+func SetListThrottlingPageSize(size int) func() {
+	previous := listThrottlingPageSize
+	listThrottlingPageSize = size
+	return func() { listThrottlingPageSize = previous }
+}
+
+// SharedHTTPTransport returns the HTTP transport shared by the snapstores with the settings of the given snapstore
+// config and TLS settings.
+func SharedHTTPTransport(config *brtypes.SnapstoreConfig, insecureSkipVerify bool, trustedCACert *string) *http.Transport {
+	return sharedTLSHTTPTransport(config, insecureSkipVerify, trustedCACert)
+}
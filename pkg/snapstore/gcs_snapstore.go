@@ -6,9 +6,12 @@ package snapstore
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"math"
+	"net/http"
 	"os"
 	"path"
 	"sort"
@@ -20,8 +23,10 @@ import (
 	"cloud.google.com/go/storage"
 	stiface "github.com/gardener/etcd-backup-restore/pkg/snapstore/gcs"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
 
 	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
 )
@@ -34,6 +39,8 @@ const (
 
 // GCSSnapStore is snapstore with GCS object store as backend.
 type GCSSnapStore struct {
+	snapshotNaming
+	listThrottling
 	client stiface.Client
 	prefix string
 	bucket string
@@ -42,6 +49,9 @@ type GCSSnapStore struct {
 	minChunkSize            int64
 	tempDir                 string
 	chunkDirSuffix          string
+	// ObjectACL is the predefined ACL set on the objects written to the snapstore. The default ACL of the bucket
+	// applies if it is empty.
+	ObjectACL string
 }
 
 // gcsEmulatorConfig holds the configuration for the fake GCS emulator
@@ -87,13 +97,25 @@ func NewGCSSnapStore(config *brtypes.SnapstoreConfig) (*GCSSnapStore, error) {
 		opts = append(opts, option.WithCredentialsFile(filename))
 	}
 
+	if !emulatorConfig.enabled {
+		// the authenticated transport is built over the pooled transport, since the client is used as is if passed
+		// along with the options.
+		transport, err := htransport.NewTransport(ctx, sharedHTTPTransport(config), append([]option.ClientOption{option.WithScopes(storage.ScopeFullControl)}, opts...)...)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, option.WithHTTPClient(&http.Client{Transport: transport}))
+	}
+
 	cli, err := storage.NewClient(ctx, opts...)
 	if err != nil {
 		return nil, err
 	}
 	gcsClient := stiface.AdaptClient(cli)
 
-	return NewGCSSnapStoreFromClient(config.Container, config.Prefix, config.TempDir, config.MaxParallelChunkUploads, config.MinChunkSize, chunkDirSuffix, gcsClient), nil
+	store := NewGCSSnapStoreFromClient(config.Container, config.Prefix, config.TempDir, config.MaxParallelChunkUploads, config.MinChunkSize, chunkDirSuffix, gcsClient)
+	store.ObjectACL = config.ObjectACL
+	return store, nil
 }
 
 // NewGCSSnapStoreFromClient create new GCSSnapStore from shared configuration with specified bucket.
@@ -132,6 +154,11 @@ func (e *gcsEmulatorConfig) configureClient(opts []option.ClientOption) error {
 	return nil
 }
 
+// snapshotPrefix returns the prefix under which the snapshots are saved.
+func (s *GCSSnapStore) snapshotPrefix() string {
+	return s.prefix
+}
+
 // Fetch should open reader for the snapshot file from store.
 func (s *GCSSnapStore) Fetch(snap brtypes.Snapshot) (io.ReadCloser, error) {
 	objectName := path.Join(snap.Prefix, snap.SnapDir, snap.SnapName)
@@ -206,6 +233,7 @@ func (s *GCSSnapStore) Save(snap brtypes.Snapshot, rc io.ReadCloser) error {
 	name := path.Join(prefix, snap.SnapDir, snap.SnapName)
 	obj := bh.Object(name)
 	c := obj.ComposerFrom(subObjects...)
+	c.ObjectAttrs().PredefinedACL = s.ObjectACL
 	ctx, cancel := context.WithTimeout(context.TODO(), chunkUploadTimeout)
 	defer cancel()
 	if _, err := c.Run(ctx); err != nil {
@@ -232,7 +260,14 @@ func (s *GCSSnapStore) uploadComponent(snap *brtypes.Snapshot, file *os.File, of
 	obj := bh.Object(name)
 	ctx, cancel := context.WithTimeout(context.TODO(), chunkUploadTimeout)
 	defer cancel()
+	// the CRC32C checksum lets GCS reject the component if it was corrupted in transit.
+	crc32c := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	if err := computeChecksum(sr, crc32c); err != nil {
+		return err
+	}
 	w := obj.NewWriter(ctx)
+	w.SetCRC32C(crc32c.Sum32())
+	w.ObjectAttrs().PredefinedACL = s.ObjectACL
 	if _, err := io.Copy(w, sr); err != nil {
 		w.Close()
 		return err
@@ -262,29 +297,108 @@ func (s *GCSSnapStore) componentUploader(wg *sync.WaitGroup, stopCh <-chan struc
 
 // List will return sorted list with all snapshot files on store.
 func (s *GCSSnapStore) List() (brtypes.SnapList, error) {
-	prefixTokens := strings.Split(s.prefix, "/")
-	// Last element of the tokens is backup version
-	// Consider the parent of the backup version level (Required for Backward Compatibility)
-	prefix := path.Join(strings.Join(prefixTokens[:len(prefixTokens)-1], "/"))
+	attrs, err := s.listObjectAttrs(s.listPrefix())
+	if err != nil {
+		return nil, err
+	}
 
-	it := s.client.Bucket(s.bucket).Objects(context.TODO(), &storage.Query{Prefix: prefix})
+	snapList := s.parseSnapshotsFromObjectAttrs(attrs)
+	sort.Sort(snapList)
+	return snapList, nil
+}
+
+// ListPaged will return sorted list with at most limit snapshot files on store, which are listed after the page of
+// the given marker. The marker is the page token of the GCS listing, and is empty after the last page.
+func (s *GCSSnapStore) ListPaged(marker string, limit int) (brtypes.SnapList, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("limit should be greater than zero")
+	}
+	it := s.client.Bucket(s.bucket).Objects(context.TODO(), &storage.Query{Prefix: s.listPrefix()})
 
+	var attrs []*storage.ObjectAttrs
+	nextMarker, err := iterator.NewPager(it, limit, marker).NextPage(&attrs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	snapList := s.parseSnapshotsFromObjectAttrs(attrs)
+	sort.Sort(snapList)
+	return snapList, nextMarker, nil
+}
+
+// ListPrefix will return sorted list with all snapshot files stored under the given prefix.
+func (s *GCSSnapStore) ListPrefix(prefix string) (brtypes.SnapList, error) {
+	attrs, err := s.listObjectAttrs(strings.TrimSuffix(prefix, "/") + "/")
+	if err != nil {
+		return nil, err
+	}
+	snapList := s.parseSnapshotsFromObjectAttrs(attrs)
+	sort.Sort(snapList)
+	return snapList, nil
+}
+
+// CopyToPrefix copies the snapshot to the given prefix with a server-side rewrite, which preserves the metadata of
+// the snapshot object, and thereby its exclude tag, regardless of its size.
+func (s *GCSSnapStore) CopyToPrefix(snap brtypes.Snapshot, prefix string) error {
+	if err := s.CopyObject(path.Join(snap.Prefix, snap.SnapDir, snap.SnapName), path.Join(prefix, snap.SnapDir, snap.SnapName)); err != nil {
+		return fmt.Errorf("error while copying %s to prefix %s: %v", path.Join(snap.Prefix, snap.SnapDir, snap.SnapName), prefix, err)
+	}
+	return nil
+}
+
+// ListPrefixObjects returns the names of the objects stored alongside the snapshot objects under the given prefix,
+// of which there are none, as the GCS snapstore stores nothing but the snapshots.
+func (s *GCSSnapStore) ListPrefixObjects(string) ([]string, error) {
+	return nil, nil
+}
+
+// CopyObject copies the object with the given name to the other given name with a server-side rewrite, which
+// preserves the metadata of the object.
+func (s *GCSSnapStore) CopyObject(srcName, dstName string) error {
+	bh := s.client.Bucket(s.bucket)
+	c := bh.Object(dstName).CopierFrom(bh.Object(srcName))
+	c.ObjectAttrs().PredefinedACL = s.ObjectACL
+	ctx, cancel := context.WithTimeout(context.TODO(), chunkUploadTimeout)
+	defer cancel()
+	_, err := c.Run(ctx)
+	return err
+}
+
+// DeleteObject deletes the object with the given name.
+func (s *GCSSnapStore) DeleteObject(name string) error {
+	return s.client.Bucket(s.bucket).Object(name).Delete(context.TODO())
+}
+
+// listObjectAttrs returns the attributes of all the objects under the given prefix.
+func (s *GCSSnapStore) listObjectAttrs(prefix string) ([]*storage.ObjectAttrs, error) {
+	it := s.client.Bucket(s.bucket).Objects(context.TODO(), &storage.Query{Prefix: prefix})
 	var attrs []*storage.ObjectAttrs
 	for {
 		attr, err := it.Next()
 		if err == iterator.Done {
-			break
+			return attrs, nil
 		}
 		if err != nil {
 			return nil, err
 		}
 		attrs = append(attrs, attr)
 	}
+}
 
+// listPrefix returns the prefix the snapshots are listed under.
+func (s *GCSSnapStore) listPrefix() string {
+	prefixTokens := strings.Split(s.prefix, "/")
+	// Last element of the tokens is backup version
+	// Consider the parent of the backup version level (Required for Backward Compatibility)
+	return path.Join(strings.Join(prefixTokens[:len(prefixTokens)-1], "/"))
+}
+
+// parseSnapshotsFromObjectAttrs returns the snapshots of the given objects, ignoring the objects which aren't snapshots.
+func (s *GCSSnapStore) parseSnapshotsFromObjectAttrs(attrs []*storage.ObjectAttrs) brtypes.SnapList {
 	var snapList brtypes.SnapList
 	for _, v := range attrs {
 		if strings.Contains(v.Name, backupVersionV1) || strings.Contains(v.Name, backupVersionV2) {
-			snap, err := ParseSnapshot(v.Name)
+			snap, err := s.parseSnapshot(v.Name)
 			if err != nil {
 				// Warning
 				logrus.Warnf("Invalid snapshot %s found, ignoring it: %v", v.Name, err)
@@ -293,9 +407,7 @@ func (s *GCSSnapStore) List() (brtypes.SnapList, error) {
 			snapList = append(snapList, snap)
 		}
 	}
-
-	sort.Sort(snapList)
-	return snapList, nil
+	return snapList
 }
 
 // Delete should delete the snapshot file from store.
@@ -304,6 +416,60 @@ func (s *GCSSnapStore) Delete(snap brtypes.Snapshot) error {
 	return s.client.Bucket(s.bucket).Object(objectName).Delete(context.TODO())
 }
 
+// SetRetained sets the exclude tag as custom metadata on the snapshot object if retained is true, and clears it
+// otherwise. The tag is cleared by setting it to false, as an update of the metadata can't remove a single key.
+// The other metadata of the snapshot object is left untouched.
+func (s *GCSSnapStore) SetRetained(snap brtypes.Snapshot, retained bool) error {
+	objectName := path.Join(snap.Prefix, snap.SnapDir, snap.SnapName)
+	_, err := s.client.Bucket(s.bucket).Object(objectName).Update(context.TODO(), storage.ObjectAttrsToUpdate{
+		Metadata: map[string]string{brtypes.SnapshotExcludeTag: strconv.FormatBool(retained)},
+	})
+	return err
+}
+
+// IsRetained returns whether the exclude tag is set on the snapshot object.
+func (s *GCSSnapStore) IsRetained(snap brtypes.Snapshot) (bool, error) {
+	objectName := path.Join(snap.Prefix, snap.SnapDir, snap.SnapName)
+	attrs, err := s.client.Bucket(s.bucket).Object(objectName).Attrs(context.TODO())
+	if err != nil {
+		return false, err
+	}
+	return attrs.Metadata[brtypes.SnapshotExcludeTag] == "true", nil
+}
+
+// Verify checks that the bucket exists and is accessible by listing a single object under the prefix, which requires
+// no more than the permissions on the objects, and if write is true, that an object can be written under the prefix,
+// by writing and deleting a small object.
+func (s *GCSSnapStore) Verify(write bool) error {
+	ctx, cancel := context.WithTimeout(context.TODO(), providerConnectionTimeout)
+	defer cancel()
+	bucket := s.client.Bucket(s.bucket)
+	if _, err := bucket.Objects(ctx, &storage.Query{Prefix: s.prefix}).Next(); err != nil && err != iterator.Done {
+		var apiErr *googleapi.Error
+		switch {
+		case errors.Is(err, storage.ErrBucketNotExist):
+			return newVerificationError(CredentialsFailureBucketMissing, "bucket %s does not exist", s.bucket)
+		case errors.As(err, &apiErr) && (apiErr.Code == http.StatusUnauthorized || apiErr.Code == http.StatusForbidden):
+			return newVerificationError(CredentialsFailureAuth, "access to bucket %s is denied: %w", s.bucket, err)
+		}
+		return fmt.Errorf("failed to access bucket %s: %w", s.bucket, err)
+	}
+	if !write {
+		return nil
+	}
+
+	obj := bucket.Object(path.Join(s.prefix, verificationObjectName))
+	w := obj.NewWriter(ctx)
+	w.ObjectAttrs().PredefinedACL = s.ObjectACL
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to write to bucket %s: %v", s.bucket, err)
+	}
+	if err := obj.Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete from bucket %s: %v", s.bucket, err)
+	}
+	return nil
+}
+
 // GetGCSCredentialsLastModifiedTime returns the latest modification timestamp of the GCS credential file
 func GetGCSCredentialsLastModifiedTime() (time.Time, error) {
 	if filename, isSet := os.LookupEnv(envStoreCredentials); isSet {
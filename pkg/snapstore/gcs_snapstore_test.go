@@ -8,8 +8,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 
 	"cloud.google.com/go/storage"
@@ -23,6 +26,15 @@ type mockGCSClient struct {
 	objects     map[string]*[]byte
 	prefix      string
 	objectMutex sync.Mutex
+	// objectCRC32Cs holds the CRC32C checksum sent along with the uploaded objects by their name.
+	objectCRC32Cs map[string]uint32
+	// corruptNextUpload corrupts the content of the next uploaded object, as if it was corrupted in transit.
+	corruptNextUpload bool
+	rejectedUploads   int
+	// objectACLs holds the predefined ACL set on the uploaded objects by their name.
+	objectACLs map[string]string
+	// objectMetadata holds the custom metadata of the objects by their name.
+	objectMetadata map[string]map[string]string
 }
 
 func (m *mockGCSClient) Bucket(name string) stiface.BucketHandle {
@@ -39,15 +51,17 @@ func (m *mockBucketHandle) Object(name string) stiface.ObjectHandle {
 	return &mockObjectHandle{object: name, client: m.client}
 }
 
-func (m *mockBucketHandle) Objects(context.Context, *storage.Query) stiface.ObjectIterator {
+func (m *mockBucketHandle) Objects(_ context.Context, query *storage.Query) stiface.ObjectIterator {
 	m.client.objectMutex.Lock()
 	defer m.client.objectMutex.Unlock()
 	var keys []string
 	for key := range m.client.objects {
-		keys = append(keys, key)
+		if query == nil || strings.HasPrefix(key, query.Prefix) {
+			keys = append(keys, key)
+		}
 	}
 	sort.Strings(keys)
-	return &mockObjectIterator{keys: keys}
+	return newMockObjectIterator(keys)
 }
 
 type mockObjectHandle struct {
@@ -77,6 +91,10 @@ func (m *mockObjectHandle) ComposerFrom(objects ...stiface.ObjectHandle) stiface
 	}
 }
 
+func (m *mockObjectHandle) CopierFrom(src stiface.ObjectHandle) stiface.Copier {
+	return &mockCopier{src: src.(*mockObjectHandle), dst: m}
+}
+
 func (m *mockObjectHandle) Delete(context.Context) error {
 	m.client.objectMutex.Lock()
 	defer m.client.objectMutex.Unlock()
@@ -87,21 +105,122 @@ func (m *mockObjectHandle) Delete(context.Context) error {
 	return fmt.Errorf("object %s not found", m.object)
 }
 
+func (m *mockObjectHandle) Attrs(context.Context) (*storage.ObjectAttrs, error) {
+	m.client.objectMutex.Lock()
+	defer m.client.objectMutex.Unlock()
+	value, ok := m.client.objects[m.object]
+	if !ok {
+		return nil, storage.ErrObjectNotExist
+	}
+	return &storage.ObjectAttrs{Name: m.object, Size: int64(len(*value)), Metadata: m.client.objectMetadata[m.object]}, nil
+}
+
+// Update merges the given metadata into the metadata of the object, like the patch request of GCS.
+func (m *mockObjectHandle) Update(_ context.Context, attrs storage.ObjectAttrsToUpdate) (*storage.ObjectAttrs, error) {
+	m.client.objectMutex.Lock()
+	defer m.client.objectMutex.Unlock()
+	if _, ok := m.client.objects[m.object]; !ok {
+		return nil, storage.ErrObjectNotExist
+	}
+	if m.client.objectMetadata == nil {
+		m.client.objectMetadata = map[string]map[string]string{}
+	}
+	metadata := m.client.objectMetadata[m.object]
+	if metadata == nil {
+		metadata = map[string]string{}
+		m.client.objectMetadata[m.object] = metadata
+	}
+	for k, v := range attrs.Metadata {
+		metadata[k] = v
+	}
+	return &storage.ObjectAttrs{Name: m.object, Metadata: metadata}, nil
+}
+
+// mockObjectIterator lists the objects page by page, with the index of the first object of a page as its page token.
 type mockObjectIterator struct {
 	stiface.ObjectIterator
-	currentIndex int
-	keys         []string
+	keys     []string
+	items    []*storage.ObjectAttrs
+	pageInfo *iterator.PageInfo
+	nextFunc func() error
+}
+
+func newMockObjectIterator(keys []string) *mockObjectIterator {
+	m := &mockObjectIterator{keys: keys}
+	m.pageInfo, m.nextFunc = iterator.NewPageInfo(m.fetch, func() int { return len(m.items) }, func() interface{} {
+		items := m.items
+		m.items = nil
+		return items
+	})
+	return m
+}
+
+func (m *mockObjectIterator) fetch(pageSize int, pageToken string) (string, error) {
+	start := 0
+	if pageToken != "" {
+		var err error
+		if start, err = strconv.Atoi(pageToken); err != nil {
+			return "", err
+		}
+	}
+	end := len(m.keys)
+	if pageSize > 0 && start+pageSize < end {
+		end = start + pageSize
+	}
+	for _, key := range m.keys[start:end] {
+		m.items = append(m.items, &storage.ObjectAttrs{Name: key})
+	}
+	if end < len(m.keys) {
+		return strconv.Itoa(end), nil
+	}
+	return "", nil
 }
 
 func (m *mockObjectIterator) Next() (*storage.ObjectAttrs, error) {
-	if m.currentIndex < len(m.keys) {
-		obj := &storage.ObjectAttrs{
-			Name: m.keys[m.currentIndex],
+	if err := m.nextFunc(); err != nil {
+		return nil, err
+	}
+	item := m.items[0]
+	m.items = m.items[1:]
+	return item, nil
+}
+
+func (m *mockObjectIterator) PageInfo() *iterator.PageInfo {
+	return m.pageInfo
+}
+
+// mockCopier copies the object along with its metadata, like the rewrite request of GCS.
+type mockCopier struct {
+	stiface.Copier
+	src   *mockObjectHandle
+	dst   *mockObjectHandle
+	attrs storage.ObjectAttrs
+}
+
+func (m *mockCopier) ObjectAttrs() *storage.ObjectAttrs {
+	return &m.attrs
+}
+
+func (m *mockCopier) Run(context.Context) (*storage.ObjectAttrs, error) {
+	client := m.src.client
+	client.objectMutex.Lock()
+	defer client.objectMutex.Unlock()
+	value, ok := client.objects[m.src.object]
+	if !ok {
+		return nil, storage.ErrObjectNotExist
+	}
+	content := append([]byte{}, *value...)
+	client.objects[m.dst.object] = &content
+	if metadata, ok := client.objectMetadata[m.src.object]; ok {
+		client.objectMetadata[m.dst.object] = metadata
+	}
+	if m.attrs.PredefinedACL != "" {
+		if client.objectACLs == nil {
+			client.objectACLs = map[string]string{}
 		}
-		m.currentIndex++
-		return obj, nil
+		client.objectACLs[m.dst.object] = m.attrs.PredefinedACL
 	}
-	return nil, iterator.Done
+	return &storage.ObjectAttrs{Name: m.dst.object, Size: int64(len(content))}, nil
 }
 
 type mockComposer struct {
@@ -109,10 +228,16 @@ type mockComposer struct {
 	objectHandles []stiface.ObjectHandle
 	client        *mockGCSClient
 	dst           *mockObjectHandle
+	attrs         storage.ObjectAttrs
+}
+
+func (m *mockComposer) ObjectAttrs() *storage.ObjectAttrs {
+	return &m.attrs
 }
 
 func (m *mockComposer) Run(ctx context.Context) (*storage.ObjectAttrs, error) {
 	dstWriter := m.dst.NewWriter(ctx)
+	dstWriter.ObjectAttrs().PredefinedACL = m.attrs.PredefinedACL
 	defer dstWriter.Close()
 	for _, obj := range m.objectHandles {
 		r, err := obj.NewReader(ctx)
@@ -145,17 +270,45 @@ type mockObjectWriter struct {
 	stiface.Writer
 	object string
 	data   []byte
+	crc32c *uint32
+	attrs  storage.ObjectAttrs
 	client *mockGCSClient
 }
 
+func (m *mockObjectWriter) ObjectAttrs() *storage.ObjectAttrs {
+	return &m.attrs
+}
+
 func (m *mockObjectWriter) Write(p []byte) (n int, err error) {
 	m.data = append(m.data, p...)
 	return len(p), nil
 }
 
+func (m *mockObjectWriter) SetCRC32C(c uint32) {
+	m.crc32c = &c
+}
+
 func (m *mockObjectWriter) Close() error {
 	m.client.objectMutex.Lock()
+	defer m.client.objectMutex.Unlock()
+	if m.client.corruptNextUpload && len(m.data) > 0 {
+		m.client.corruptNextUpload = false
+		m.data[0] ^= 0xff
+	}
+	if m.crc32c != nil {
+		if *m.crc32c != crc32.Checksum(m.data, crc32.MakeTable(crc32.Castagnoli)) {
+			m.client.rejectedUploads++
+			return fmt.Errorf("provided CRC32C %d doesn't match the calculated CRC32C of object %s", *m.crc32c, m.object)
+		}
+		if m.client.objectCRC32Cs == nil {
+			m.client.objectCRC32Cs = map[string]uint32{}
+		}
+		m.client.objectCRC32Cs[m.object] = *m.crc32c
+	}
 	m.client.objects[m.object] = &m.data
-	m.client.objectMutex.Unlock()
+	if m.client.objectACLs == nil {
+		m.client.objectACLs = map[string]string{}
+	}
+	m.client.objectACLs[m.object] = m.attrs.PredefinedACL
 	return nil
 }
@@ -5,7 +5,6 @@
 package snapstore
 
 import (
-	"crypto/tls"
 	"fmt"
 	"net/http"
 
@@ -13,6 +12,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
 )
 
 // s3AuthOptions contains all needed options to authenticate against a S3-compatible store.
@@ -25,14 +25,13 @@ type s3AuthOptions struct {
 	secretAccessKey    string
 }
 
-// newGenericS3FromAuthOpt creates a new S3 snapstore object from the specified authentication options.
-func newGenericS3FromAuthOpt(bucket, prefix, tempDir string, maxParallelChunkUploads uint, minChunkSize int64, ao s3AuthOptions) (*S3SnapStore, error) {
-	httpClient := http.DefaultClient
-	if !ao.disableSSL {
-		httpClient.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: ao.insecureSkipVerify},
-		}
+// newGenericS3FromAuthOpt creates a new S3 snapstore object from the specified authentication options. The given
+// transport is shared with other snapstores, see genericS3HTTPTransport.
+func newGenericS3FromAuthOpt(bucket, prefix, tempDir string, maxParallelChunkUploads uint, minChunkSize int64, transport *http.Transport, ao s3AuthOptions) (*S3SnapStore, error) {
+	if err := validateS3Region(ao.endpoint, ao.region); err != nil {
+		return nil, err
 	}
+	httpClient := &http.Client{Transport: transport}
 
 	sess, err := session.NewSession(&aws.Config{
 		Credentials:      credentials.NewStaticCredentials(ao.accessKeyID, ao.secretAccessKey, ""),
@@ -48,3 +47,18 @@ func newGenericS3FromAuthOpt(bucket, prefix, tempDir string, maxParallelChunkUpl
 	cli := s3.New(sess)
 	return NewS3FromClient(bucket, prefix, tempDir, maxParallelChunkUploads, minChunkSize, cli, SSECredentials{}), nil
 }
+
+// validateS3Region checks that a region is configured for a custom endpoint of an S3 compatible store, as the requests
+// are signed with the region, which the AWS SDK can't resolve from a custom endpoint.
+func validateS3Region(endpoint, region string) error {
+	if endpoint != "" && region == "" {
+		return fmt.Errorf("region is required for the custom S3 endpoint %s", endpoint)
+	}
+	return nil
+}
+
+// genericS3HTTPTransport returns the shared HTTP transport of the S3-compatible store with the given connection pool
+// settings and authentication options, which skips verifying the certificate of the store if configured.
+func genericS3HTTPTransport(config *brtypes.SnapstoreConfig, ao s3AuthOptions) *http.Transport {
+	return sharedTLSHTTPTransport(config, !ao.disableSSL && ao.insecureSkipVerify, nil)
+}
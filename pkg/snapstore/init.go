@@ -6,6 +6,7 @@ package snapstore
 
 import (
 	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+	"github.com/gardener/etcd-backup-restore/pkg/wrappers"
 )
 
 // NewSnapstoreConfig returns the snapstore config.
@@ -14,5 +15,10 @@ func NewSnapstoreConfig() *brtypes.SnapstoreConfig {
 		MaxParallelChunkUploads: 5,
 		MinChunkSize:            brtypes.MinChunkSize,
 		TempDir:                 "/tmp",
+		MaxIdleConns:            brtypes.DefaultMaxIdleConns,
+		MaxIdleConnsPerHost:     brtypes.DefaultMaxIdleConnsPerHost,
+		IdleConnTimeout:         wrappers.Duration{Duration: brtypes.DefaultIdleConnTimeout},
+		ListThrottlingRetries:   brtypes.DefaultListThrottlingRetries,
+		ListThrottlingBackoff:   wrappers.Duration{Duration: brtypes.DefaultListThrottlingBackoff},
 	}
 }
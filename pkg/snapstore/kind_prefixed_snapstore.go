@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapstore
+
+import (
+	"io"
+	"path"
+	"sort"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// prefixedSnapStore is implemented by the snapstores which save the snapshots under a prefix they know.
+type prefixedSnapStore interface {
+	snapshotPrefix() string
+}
+
+// KindPrefixedSnapStore is a snapstore which saves the full and the delta snapshots under distinct prefixes of the
+// same container, each through a snapstore of its own, so that a chain of snapshots spans both prefixes. The objects
+// other than the snapshots, such as the chain manifest, are saved under the prefix of the underlying snapstore.
+type KindPrefixedSnapStore struct {
+	brtypes.SnapStore
+	fullStore  brtypes.SnapStore
+	deltaStore brtypes.SnapStore
+}
+
+// NewKindPrefixedSnapStore returns a kind prefixed snapstore which saves the full snapshots to the given full snapstore
+// and the delta snapshots to the given delta snapstore. The snapshots of a kind whose snapstore is nil are saved to
+// the given snapstore.
+func NewKindPrefixedSnapStore(store, fullStore, deltaStore brtypes.SnapStore) *KindPrefixedSnapStore {
+	if fullStore == nil {
+		fullStore = store
+	}
+	if deltaStore == nil {
+		deltaStore = store
+	}
+	return &KindPrefixedSnapStore{
+		SnapStore:  store,
+		fullStore:  fullStore,
+		deltaStore: deltaStore,
+	}
+}
+
+// snapStoreOf returns the snapstore of the snapshots of the given kind.
+func (s *KindPrefixedSnapStore) snapStoreOf(kind string) brtypes.SnapStore {
+	switch kind {
+	case brtypes.SnapshotKindFull, brtypes.SnapshotKindChunk:
+		return s.fullStore
+	case brtypes.SnapshotKindDelta:
+		return s.deltaStore
+	default:
+		return s.SnapStore
+	}
+}
+
+// snapStores returns the distinct snapstores of the prefix and of the kinds.
+func (s *KindPrefixedSnapStore) snapStores() []brtypes.SnapStore {
+	stores := []brtypes.SnapStore{s.SnapStore}
+	if s.fullStore != s.SnapStore {
+		stores = append(stores, s.fullStore)
+	}
+	if s.deltaStore != s.SnapStore && s.deltaStore != s.fullStore {
+		stores = append(stores, s.deltaStore)
+	}
+	return stores
+}
+
+// Fetch should open reader for the snapshot file from the snapstore of its kind.
+func (s *KindPrefixedSnapStore) Fetch(snap brtypes.Snapshot) (io.ReadCloser, error) {
+	return s.snapStoreOf(snap.Kind).Fetch(snap)
+}
+
+// Save will write the snapshot to the snapstore of its kind.
+func (s *KindPrefixedSnapStore) Save(snap brtypes.Snapshot, rc io.ReadCloser) error {
+	return s.snapStoreOf(snap.Kind).Save(snap, rc)
+}
+
+// Delete should delete the snapshot file from the snapstore of its kind.
+func (s *KindPrefixedSnapStore) Delete(snap brtypes.Snapshot) error {
+	return s.snapStoreOf(snap.Kind).Delete(snap)
+}
+
+// List will return sorted list with all snapshot files of the snapstores of both kinds, along with the ones saved
+// under the prefix of the underlying snapstore, e.g. before the prefixes of the kinds were configured.
+func (s *KindPrefixedSnapStore) List() (brtypes.SnapList, error) {
+	snapList := brtypes.SnapList{}
+	seen := map[string]bool{}
+	for _, store := range s.snapStores() {
+		storeSnapList, err := store.List()
+		if err != nil {
+			return nil, err
+		}
+		// the listings overlap if the prefixes are nested
+		for _, snap := range storeSnapList {
+			snapPath := path.Join(snap.Prefix, snap.SnapDir, snap.SnapName)
+			if !seen[snapPath] {
+				seen[snapPath] = true
+				snapList = append(snapList, snap)
+			}
+		}
+	}
+	sort.Sort(snapList)
+	return snapList, nil
+}
+
+// snapStoreOfKind returns the snapstore of the snapshots of the given kind if the given snapstore is a kind prefixed
+// snapstore, and the snapstore underlying the given snapstore otherwise.
+func snapStoreOfKind(store brtypes.SnapStore, kind string) brtypes.SnapStore {
+	if ks, ok := unwrapKindPrefixedSnapStore(store); ok {
+		return ks.snapStoreOf(kind)
+	}
+	return unwrapSnapStore(store)
+}
+
+// completeKindPrefixes completes the snapshots of the given chain manifest without a prefix with the prefix of the
+// snapstore of their kind, if the given snapstore is a kind prefixed snapstore.
+func completeKindPrefixes(store brtypes.SnapStore, manifest *brtypes.ChainManifest) {
+	if _, ok := unwrapKindPrefixedSnapStore(store); !ok {
+		return
+	}
+	for _, snap := range manifest.Snapshots() {
+		if snap.Prefix != "" {
+			continue
+		}
+		if ps, ok := snapStoreOfKind(store, snap.Kind).(prefixedSnapStore); ok {
+			snap.Prefix = ps.snapshotPrefix()
+		}
+	}
+}
@@ -5,6 +5,10 @@
 package snapstore
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -13,13 +17,24 @@ import (
 	"sort"
 	"strings"
 	"syscall"
+	"time"
 
 	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
 	"github.com/sirupsen/logrus"
 )
 
+// localExcludeTagDir is the directory under the prefix of the local snapstore, which holds an empty marker
+// file for every snapshot tagged with the exclude tag, as local files can't be tagged.
+const localExcludeTagDir = "." + brtypes.SnapshotExcludeTag
+
+// localTemporaryFileSuffix is the suffix of the temporary files which are written and renamed over the files of the
+// local snapstore, so that a partially written file is never listed or fetched.
+const localTemporaryFileSuffix = ".tmp"
+
 // LocalSnapStore is snapstore with local disk as backend
 type LocalSnapStore struct {
+	snapshotNaming
+	listThrottling
 	prefix string
 }
 
@@ -36,12 +51,29 @@ func NewLocalSnapStore(prefix string) (*LocalSnapStore, error) {
 	}, nil
 }
 
+// snapshotPrefix returns the prefix under which the snapshots are saved.
+func (s *LocalSnapStore) snapshotPrefix() string {
+	return s.prefix
+}
+
 // Fetch should open reader for the snapshot file from store
 func (s *LocalSnapStore) Fetch(snap brtypes.Snapshot) (io.ReadCloser, error) {
 	return os.Open(path.Join(snap.Prefix, snap.SnapDir, snap.SnapName))
 }
 
-// Save will write the snapshot to store
+// FetchRange should open reader for the given range of the snapshot file from store
+func (s *LocalSnapStore) FetchRange(snap brtypes.Snapshot, offset, length int64) (io.ReadCloser, error) {
+	f, err := os.Open(path.Join(snap.Prefix, snap.SnapDir, snap.SnapName))
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.NewSectionReader(f, offset, length), f}, nil
+}
+
+// Save will atomically write the snapshot to store, by renaming a fully written and synced temporary file over it.
 func (s *LocalSnapStore) Save(snap brtypes.Snapshot, rc io.ReadCloser) error {
 	defer rc.Close()
 	err := os.MkdirAll(path.Join(s.prefix, snap.SnapDir), 0700)
@@ -55,25 +87,48 @@ func (s *LocalSnapStore) Save(snap brtypes.Snapshot, rc io.ReadCloser) error {
 			return err
 		}
 	}
-	f, err := os.Create(path.Join(s.prefix, snap.SnapDir, snap.SnapName))
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	_, err = io.Copy(f, rc)
-	if err != nil {
-		return err
-	}
-	return f.Sync()
+	return writeAtomically(path.Join(s.prefix, snap.SnapDir, snap.SnapName), rc)
 }
 
 // List will return sorted list with all snapshot files on store.
 func (s *LocalSnapStore) List() (brtypes.SnapList, error) {
+	snapList, _, err := s.walkSnapshots("", 0)
+	return snapList, err
+}
+
+// ListPaged will return sorted list with at most limit snapshot files on store, which are stored after the given marker
+// in the lexical order of the file paths. The returned marker is empty after the last page.
+func (s *LocalSnapStore) ListPaged(marker string, limit int) (brtypes.SnapList, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("limit should be greater than zero")
+	}
+	return s.walkSnapshots(marker, limit)
+}
+
+// isWalkedAfter returns whether the file at the given path is walked after the file at the given marker path, if any,
+// by comparing the paths element by element, as the entries of each directory are walked in lexical order.
+func isWalkedAfter(path, marker string) bool {
+	if marker == "" {
+		return true
+	}
+	elems, markerElems := strings.Split(path, string(filepath.Separator)), strings.Split(marker, string(filepath.Separator))
+	for i := 0; i < len(elems) && i < len(markerElems); i++ {
+		if elems[i] != markerElems[i] {
+			return elems[i] > markerElems[i]
+		}
+	}
+	return len(elems) > len(markerElems)
+}
+
+// walkSnapshots walks the snapshot files on store in the lexical order of their paths, which are stored after the given
+// marker, and returns them sorted along with the path of the last one if the walk stopped at the given limit, if any.
+func (s *LocalSnapStore) walkSnapshots(marker string, limit int) (brtypes.SnapList, string, error) {
 	prefixTokens := strings.Split(s.prefix, "/")
 	// Last element of the tokens is backup version
 	// Consider the parent of the backup version level (Required for Backward Compatibility)
 	prefix := path.Join(strings.Join(prefixTokens[:len(prefixTokens)-1], "/"))
 
+	var nextMarker string
 	snapList := brtypes.SnapList{}
 	err := filepath.Walk(prefix, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -81,32 +136,206 @@ func (s *LocalSnapStore) List() (brtypes.SnapList, error) {
 			return err
 		}
 		if info.IsDir() {
+			if info.Name() == localExcludeTagDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isWalkedAfter(path, marker) || isObjectAlongsideSnapshots(path) || isLocalTemporaryFile(path) {
 			return nil
 		}
 		if strings.Contains(path, backupVersionV1) || strings.Contains(path, backupVersionV2) {
-			snap, err := ParseSnapshot(path)
+			snap, err := s.parseSnapshot(path)
 			if err != nil {
 				// Warning
 				logrus.Warnf("Invalid snapshot found. Ignoring it:%s\n", path)
 			} else {
+				snap.SizeBytes = info.Size()
 				snapList = append(snapList, snap)
 			}
+			if limit > 0 && len(snapList) == limit {
+				nextMarker = path
+				return filepath.SkipAll
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("error walking the path %q: %v", prefix, err)
+	}
+
+	sort.Sort(snapList)
+	return snapList, nextMarker, nil
+}
+
+// listPartitions returns the date partitions present under the prefix, sorted from oldest to newest.
+func (s *LocalSnapStore) listPartitions() ([]string, error) {
+	years, err := os.ReadDir(s.prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var partitions []string
+	for _, year := range years {
+		if !year.IsDir() {
+			continue
+		}
+		months, err := os.ReadDir(path.Join(s.prefix, year.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, month := range months {
+			if partition := path.Join(year.Name(), month.Name()); month.IsDir() && isDatePartition(partition) {
+				partitions = append(partitions, partition)
+			}
+		}
+	}
+	sort.Strings(partitions)
+	return partitions, nil
+}
+
+// listPartition returns sorted list with all snapshot files in the given date partition.
+func (s *LocalSnapStore) listPartition(partition string) (brtypes.SnapList, error) {
+	return s.listSnapshotsInDir(path.Join(s.prefix, partition))
+}
+
+// ListPrefix will return sorted list with all snapshot files stored under the given prefix.
+func (s *LocalSnapStore) ListPrefix(prefix string) (brtypes.SnapList, error) {
+	return s.listSnapshotsInDir(prefix)
+}
+
+// CopyToPrefix copies the snapshot to the given prefix, preserving the modification time of the snapshot file and
+// its exclude tag.
+func (s *LocalSnapStore) CopyToPrefix(snap brtypes.Snapshot, prefix string) error {
+	srcPath := path.Join(snap.Prefix, snap.SnapDir, snap.SnapName)
+	info, err := os.Stat(srcPath)
+	if err != nil {
+		return err
+	}
+	copied := snap
+	copied.Prefix = prefix
+	if err := copyFile(srcPath, path.Join(prefix, snap.SnapDir, snap.SnapName), info.ModTime()); err != nil {
+		return err
+	}
+	retained, err := s.IsRetained(snap)
+	if err != nil {
+		return err
+	}
+	return s.SetRetained(copied, retained)
+}
+
+// ListPrefixObjects returns the paths of the files stored alongside the snapshot files under the given prefix.
+func (s *LocalSnapStore) ListPrefixObjects(prefix string) ([]string, error) {
+	var objectPaths []string
+	if _, err := os.Stat(prefix); os.IsNotExist(err) {
+		return objectPaths, nil
+	}
+	err := filepath.Walk(prefix, func(objectPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == localExcludeTagDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isObjectAlongsideSnapshots(objectPath) && !isLocalTemporaryFile(objectPath) {
+			objectPaths = append(objectPaths, objectPath)
 		}
 		return nil
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error walking the path %q: %v", prefix, err)
 	}
+	return objectPaths, nil
+}
+
+// CopyObject copies the file at the given path to the other given path, preserving its modification time.
+func (s *LocalSnapStore) CopyObject(srcPath, dstPath string) error {
+	info, err := os.Stat(srcPath)
+	if err != nil {
+		return err
+	}
+	return copyFile(srcPath, dstPath, info.ModTime())
+}
+
+// DeleteObject deletes the file at the given path, if any.
+func (s *LocalSnapStore) DeleteObject(objectPath string) error {
+	if err := os.Remove(objectPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// copyFile atomically copies the file to the destination path, creating its parent directories, and sets the given
+// modification time on the copy.
+func copyFile(srcPath, dstPath string, modTime time.Time) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if err := os.MkdirAll(path.Dir(dstPath), 0700); err != nil {
+		return err
+	}
+	if err := writeAtomically(dstPath, src); err != nil {
+		return err
+	}
+	return os.Chtimes(dstPath, modTime, modTime)
+}
+
+// listSnapshotsInDir returns sorted list with all snapshot files under the given directory, which is empty if the
+// directory doesn't exist.
+func (s *LocalSnapStore) listSnapshotsInDir(dir string) (brtypes.SnapList, error) {
+	snapList := brtypes.SnapList{}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return snapList, nil
+	}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == localExcludeTagDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isObjectAlongsideSnapshots(path) || isLocalTemporaryFile(path) {
+			return nil
+		}
+		snap, err := s.parseSnapshot(path)
+		if err != nil {
+			logrus.Warnf("Invalid snapshot found. Ignoring it:%s\n", path)
+		} else {
+			snap.SizeBytes = info.Size()
+			snapList = append(snapList, snap)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walking the path %q: %v", dir, err)
+	}
 
 	sort.Sort(snapList)
 	return snapList, nil
 }
 
-// Delete should delete the snapshot file from store
+// Delete should delete the snapshot file from store, along with the cluster metadata of a full snapshot
 func (s *LocalSnapStore) Delete(snap brtypes.Snapshot) error {
 	if err := os.Remove(path.Join(snap.Prefix, snap.SnapDir, snap.SnapName)); err != nil {
 		return err
 	}
+	if snap.Kind == brtypes.SnapshotKindFull {
+		if err := os.Remove(clusterMetadataPath(snap, s.prefix)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Remove(excludeTagMarkerPath(snap)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
 	err := os.Remove(path.Join(snap.Prefix, snap.SnapDir))
 	if pathErr, ok := err.(*os.PathError); ok && pathErr.Err != syscall.ENOTEMPTY {
 		return err
@@ -116,9 +345,343 @@ func (s *LocalSnapStore) Delete(snap brtypes.Snapshot) error {
 
 // Size should return size of the snapshot file from store
 func (s *LocalSnapStore) Size(snap brtypes.Snapshot) (int64, error) {
-	fileInfo, err := os.Stat(path.Join(s.prefix, snap.SnapDir, snap.SnapName))
+	fileInfo, err := os.Stat(path.Join(snap.Prefix, snap.SnapDir, snap.SnapName))
 	if err != nil {
 		return -1, err
 	}
 	return fileInfo.Size(), nil
 }
+
+// Exists returns whether the snapshot file is present in the store.
+func (s *LocalSnapStore) Exists(snap brtypes.Snapshot) (bool, error) {
+	if _, err := os.Stat(path.Join(adaptPrefix(&snap, s.prefix), snap.SnapDir, snap.SnapName)); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// SaveChainManifest atomically replaces the chain manifest file under the prefix, by renaming a fully written
+// temporary file over it.
+func (s *LocalSnapStore) SaveChainManifest(manifest *brtypes.ChainManifest) error {
+	completeChainManifest(manifest, s.prefix, s.Size)
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to marshal chain manifest: %v", err)
+	}
+	return writeFileAtomically(s.prefix, brtypes.ChainManifestName, data)
+}
+
+// FetchChainManifest returns the chain manifest file under the prefix, or nil if there is none.
+func (s *LocalSnapStore) FetchChainManifest() (*brtypes.ChainManifest, error) {
+	data, err := os.ReadFile(path.Join(s.prefix, brtypes.ChainManifestName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	manifest := &brtypes.ChainManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal chain manifest: %v", err)
+	}
+	return manifest, nil
+}
+
+// DeleteChainManifest deletes the chain manifest file under the prefix, if any.
+func (s *LocalSnapStore) DeleteChainManifest() error {
+	if err := os.Remove(path.Join(s.prefix, brtypes.ChainManifestName)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// SaveClusterMetadata atomically replaces the cluster metadata file of the full snapshot next to the snapshot file, by
+// renaming a fully written temporary file over it.
+func (s *LocalSnapStore) SaveClusterMetadata(snap brtypes.Snapshot, metadata *brtypes.ClusterMetadata) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cluster metadata: %v", err)
+	}
+	metadataPath := clusterMetadataPath(snap, s.prefix)
+	return writeFileAtomically(path.Dir(metadataPath), path.Base(metadataPath), data)
+}
+
+// FetchClusterMetadata returns the cluster metadata file of the full snapshot, or nil if there is none.
+func (s *LocalSnapStore) FetchClusterMetadata(snap brtypes.Snapshot) (*brtypes.ClusterMetadata, error) {
+	data, err := os.ReadFile(clusterMetadataPath(snap, s.prefix))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	metadata := &brtypes.ClusterMetadata{}
+	if err := json.Unmarshal(data, metadata); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cluster metadata: %v", err)
+	}
+	return metadata, nil
+}
+
+// CreateRestoreLock atomically creates the restore lock file under the prefix, by linking a fully written temporary
+// file to it, which fails if the restore lock file exists.
+func (s *LocalSnapStore) CreateRestoreLock(lock *brtypes.RestoreLock) error {
+	data, err := json.Marshal(lock)
+	if err != nil {
+		return fmt.Errorf("failed to marshal restore lock: %v", err)
+	}
+	lockPath := path.Join(s.prefix, brtypes.RestoreLockName)
+	f, err := os.CreateTemp(s.prefix, brtypes.RestoreLockName+".*"+localTemporaryFileSuffix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Link(f.Name(), lockPath); err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("%w: %s exists", ErrRestoreLockExists, lockPath)
+		}
+		return err
+	}
+	return syncDir(s.prefix)
+}
+
+// FetchRestoreLock returns the restore lock file under the prefix along with the checksum of its content as its
+// version, or nil if there is none.
+func (s *LocalSnapStore) FetchRestoreLock() (*brtypes.RestoreLock, error) {
+	data, err := os.ReadFile(path.Join(s.prefix, brtypes.RestoreLockName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return unmarshalLocalRestoreLock(data)
+}
+
+// ReplaceRestoreLock atomically replaces the restore lock file under the prefix with the given lock, unless the
+// restore lock file changed since the given restore lock was fetched. The restore lock file is checked and replaced
+// while holding the guard of the restore lock, so that no other restoration replaces or deletes it in between.
+func (s *LocalSnapStore) ReplaceRestoreLock(current, lock *brtypes.RestoreLock) error {
+	return s.guardRestoreLock(func() error {
+		stored, err := s.FetchRestoreLock()
+		if err != nil {
+			return err
+		}
+		if stored == nil || stored.Version != current.Version {
+			return fmt.Errorf("%w: %s was replaced or deleted", ErrRestoreLockExists, path.Join(s.prefix, brtypes.RestoreLockName))
+		}
+		data, err := json.Marshal(lock)
+		if err != nil {
+			return fmt.Errorf("failed to marshal restore lock: %v", err)
+		}
+		return writeFileAtomically(s.prefix, brtypes.RestoreLockName, data)
+	})
+}
+
+// DeleteRestoreLock deletes the restore lock file under the prefix if it is held by the given holder. The restore lock
+// file is checked and deleted while holding the guard of the restore lock, so that a restore lock file taken over in
+// the meantime is kept.
+func (s *LocalSnapStore) DeleteRestoreLock(holder string) error {
+	return s.guardRestoreLock(func() error {
+		lock, err := s.FetchRestoreLock()
+		if err != nil || lock == nil || lock.Holder != holder {
+			return err
+		}
+		if err := os.Remove(path.Join(s.prefix, brtypes.RestoreLockName)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return syncDir(s.prefix)
+	})
+}
+
+// guardRestoreLock runs the given func while holding an exclusive lock on the guard file of the restore lock under
+// the prefix, which all the processes replacing or deleting the restore lock file take.
+func (s *LocalSnapStore) guardRestoreLock(fn func() error) error {
+	// the guard file is named as a temporary file, so that it is neither listed nor moved along with the snapshots
+	guard, err := os.OpenFile(path.Join(s.prefix, brtypes.RestoreLockName+".guard"+localTemporaryFileSuffix), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	defer guard.Close()
+	if err := syscall.Flock(int(guard.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock the guard of the restore lock: %v", err)
+	}
+	defer syscall.Flock(int(guard.Fd()), syscall.LOCK_UN)
+	return fn()
+}
+
+// unmarshalLocalRestoreLock returns the restore lock of the given content of a restore lock file, with the checksum
+// of the content as its version.
+func unmarshalLocalRestoreLock(data []byte) (*brtypes.RestoreLock, error) {
+	lock := &brtypes.RestoreLock{}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal restore lock: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	lock.Version = hex.EncodeToString(sum[:])
+	return lock, nil
+}
+
+// SaveCompressionDictionary atomically replaces the compression dictionary file with the given identifier under
+// the prefix, by renaming a fully written temporary file over it.
+func (s *LocalSnapStore) SaveCompressionDictionary(id uint32, dictionary []byte) error {
+	return writeFileAtomically(s.prefix, compressionDictionaryName(id), dictionary)
+}
+
+// FetchCompressionDictionary returns the compression dictionary file with the given identifier under the prefix.
+func (s *LocalSnapStore) FetchCompressionDictionary(id uint32) ([]byte, error) {
+	return os.ReadFile(path.Join(s.prefix, compressionDictionaryName(id)))
+}
+
+// ListCompressionDictionaries returns the compression dictionary files under the prefix, along with the time they
+// were last modified on.
+func (s *LocalSnapStore) ListCompressionDictionaries() ([]brtypes.CompressionDictionary, error) {
+	entries, err := os.ReadDir(s.prefix)
+	if err != nil {
+		return nil, err
+	}
+	var dictionaries []brtypes.CompressionDictionary
+	for _, entry := range entries {
+		id, ok := parseCompressionDictionaryName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		dictionaries = append(dictionaries, brtypes.CompressionDictionary{ID: id, SavedOn: info.ModTime()})
+	}
+	return dictionaries, nil
+}
+
+// DeleteCompressionDictionary deletes the compression dictionary file with the given identifier under the prefix.
+func (s *LocalSnapStore) DeleteCompressionDictionary(id uint32) error {
+	return os.Remove(path.Join(s.prefix, compressionDictionaryName(id)))
+}
+
+// Verify checks that the prefix directory exists, and if write is true, that a file can be written into it.
+func (s *LocalSnapStore) Verify(write bool) error {
+	info, err := os.Stat(s.prefix)
+	if err != nil {
+		switch {
+		case os.IsNotExist(err):
+			return newVerificationError(CredentialsFailureBucketMissing, "%w", err)
+		case os.IsPermission(err):
+			return newVerificationError(CredentialsFailureAuth, "%w", err)
+		}
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", s.prefix)
+	}
+	if !write {
+		return nil
+	}
+	f, err := os.CreateTemp(s.prefix, verificationObjectName+".*")
+	if err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Remove(f.Name())
+}
+
+// writeFileAtomically replaces the file with the given name in the given directory with the given data, by renaming
+// a fully written temporary file over it.
+func writeFileAtomically(dir, name string, data []byte) error {
+	return writeAtomically(path.Join(dir, name), bytes.NewReader(data))
+}
+
+// writeAtomically replaces the file at the given path with the data read from the reader, by renaming a fully written
+// and synced temporary file over it. The directory of the file is synced as well, so that the rename is durable.
+func writeAtomically(filePath string, r io.Reader) error {
+	dir := path.Dir(filePath)
+	f, err := os.CreateTemp(dir, path.Base(filePath)+".*"+localTemporaryFileSuffix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), filePath); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir flushes the entries of the directory to the disk.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// isLocalTemporaryFile checks whether the file is a temporary file of the local snapstore.
+func isLocalTemporaryFile(filePath string) bool {
+	return strings.HasSuffix(filePath, localTemporaryFileSuffix)
+}
+
+// SetRetained sets the exclude tag on the snapshot if retained is true, and clears it otherwise.
+func (s *LocalSnapStore) SetRetained(snap brtypes.Snapshot, retained bool) error {
+	if _, err := os.Stat(path.Join(snap.Prefix, snap.SnapDir, snap.SnapName)); err != nil {
+		return err
+	}
+	markerPath := excludeTagMarkerPath(snap)
+	if !retained {
+		if err := os.Remove(markerPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(path.Dir(markerPath), 0700); err != nil {
+		return err
+	}
+	f, err := os.Create(markerPath)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// IsRetained returns whether the exclude tag is set on the snapshot.
+func (s *LocalSnapStore) IsRetained(snap brtypes.Snapshot) (bool, error) {
+	if _, err := os.Stat(excludeTagMarkerPath(snap)); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// excludeTagMarkerPath returns the path of the marker file of the exclude tag of the snapshot.
+func excludeTagMarkerPath(snap brtypes.Snapshot) string {
+	return path.Join(snap.Prefix, localExcludeTagDir, snap.SnapDir, snap.SnapName)
+}
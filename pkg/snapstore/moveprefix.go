@@ -0,0 +1,275 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapstore
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+	"github.com/sirupsen/logrus"
+)
+
+// CopyPrefix copies all the snapshots stored under the old prefix to the new prefix of the same container, preserving
+// the metadata and the tags of the snapshot objects, and returns the copied snapshots as listed under the new prefix.
+// The objects stored alongside the snapshots, i.e. the compression dictionaries and the cluster metadata of the full
+// snapshots which the snapshots can't be restored without, and the restore lock, are copied before them. The chain
+// manifest isn't copied, as it names the snapshots under the old prefix, and is written anew by the snapshotter.
+// The prefixes are the prefixes under which the backup version directories of a snapshot chain are stored. The copy is
+// verified by listing the snapshots under the new prefix, which must hold exactly the snapshots of the old prefix.
+// Returns an error if the snapstore doesn't support copying the snapshots, if the prefixes overlap, or if the new
+// prefix already holds snapshots. The snapshot chain of a kind prefixed snapstore, whose prefixes aren't all under
+// the old prefix, has to be copied with CopyPrefixes instead.
+func CopyPrefix(store brtypes.SnapStore, oldPrefix, newPrefix string) (brtypes.SnapList, error) {
+	return CopyPrefixes(store, map[string]string{oldPrefix: newPrefix})
+}
+
+// CopyPrefixes copies all the snapshots stored under each of the old prefixes of the given map to its new prefix like
+// CopyPrefix, and returns the copied snapshots as listed under the new prefixes. A snapshot chain spanning several
+// prefixes, such as the one of a kind prefixed snapstore, is copied by giving all of its prefixes. Returns an error if
+// any of the prefixes overlap, or if the old prefixes hold only a part of the prefixes of a kind prefixed snapstore,
+// as the copied snapshot chain would be incomplete.
+func CopyPrefixes(store brtypes.SnapStore, prefixes map[string]string) (brtypes.SnapList, error) {
+	ps, ok := prefixCopyingSnapStore(store)
+	if !ok {
+		return nil, fmt.Errorf("snapstore does not support copying snapshots to another prefix")
+	}
+	oldPrefixes, prefixes, err := cleanPrefixes(prefixes)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkKindPrefixesCovered(store, oldPrefixes); err != nil {
+		return nil, err
+	}
+
+	snapshots := 0
+	for _, oldPrefix := range oldPrefixes {
+		snapList, err := ps.ListPrefix(oldPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the snapshots under prefix %s: %v", oldPrefix, err)
+		}
+		snapshots += len(snapList)
+		newPrefix := prefixes[oldPrefix]
+		existingSnapList, err := ps.ListPrefix(newPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the snapshots under prefix %s: %v", newPrefix, err)
+		}
+		if len(existingSnapList) > 0 {
+			return nil, fmt.Errorf("prefix %s already holds %d snapshots", newPrefix, len(existingSnapList))
+		}
+	}
+	if snapshots == 0 {
+		return nil, fmt.Errorf("no snapshots found under prefixes %s", strings.Join(oldPrefixes, ", "))
+	}
+
+	copiedSnapList := brtypes.SnapList{}
+	for _, oldPrefix := range oldPrefixes {
+		snapList, err := copyPrefix(ps, oldPrefix, prefixes[oldPrefix])
+		if err != nil {
+			return nil, err
+		}
+		copiedSnapList = append(copiedSnapList, snapList...)
+	}
+	sort.Sort(copiedSnapList)
+	return copiedSnapList, nil
+}
+
+// copyPrefix copies all the snapshots and the objects stored alongside them under the old prefix to the new prefix,
+// and returns the copied snapshots as listed under the new prefix.
+func copyPrefix(ps brtypes.PrefixCopyingSnapStore, oldPrefix, newPrefix string) (brtypes.SnapList, error) {
+	snapList, err := ps.ListPrefix(oldPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the snapshots under prefix %s: %v", oldPrefix, err)
+	}
+
+	objectPaths, err := ps.ListPrefixObjects(oldPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the objects under prefix %s: %v", oldPrefix, err)
+	}
+	copiedObjects := 0
+	for _, objectPath := range objectPaths {
+		if isChainManifestObject(objectPath) {
+			continue
+		}
+		copiedObjects++
+		if err := ps.CopyObject(objectPath, path.Join(newPrefix, strings.TrimPrefix(objectPath, oldPrefix))); err != nil {
+			return nil, fmt.Errorf("failed to copy object %s: %v", objectPath, err)
+		}
+	}
+	for _, snap := range snapList {
+		snapPrefix := path.Join(newPrefix, strings.TrimPrefix(path.Clean(snap.Prefix), oldPrefix))
+		if err := ps.CopyToPrefix(*snap, snapPrefix); err != nil {
+			return nil, fmt.Errorf("failed to copy snapshot %s: %v", path.Join(snap.SnapDir, snap.SnapName), err)
+		}
+	}
+	logrus.Infof("Copied %d snapshots and %d other objects from prefix %s to prefix %s", len(snapList), copiedObjects, oldPrefix, newPrefix)
+
+	copiedSnapList, err := ps.ListPrefix(newPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the copied snapshots under prefix %s: %v", newPrefix, err)
+	}
+	if err := verifyCopiedSnapshots(snapList, copiedSnapList); err != nil {
+		return nil, fmt.Errorf("failed to verify the snapshots copied to prefix %s: %v", newPrefix, err)
+	}
+	return copiedSnapList, nil
+}
+
+// MovePrefix moves all the snapshots stored under the old prefix to the new prefix of the same container, preserving
+// the metadata and the tags of the snapshot objects. The snapshots are first copied with CopyPrefix, and are deleted
+// from the old prefix only once all of them are verified to be listed under the new prefix, so that the snapshot
+// chain remains restorable from one of the prefixes if the move fails. The objects stored alongside the snapshots,
+// including the chain manifest, are deleted from the old prefix after the snapshots, so that nothing is left behind.
+// The snapshot chain of a kind prefixed snapstore, whose prefixes aren't all under the old prefix, has to be moved
+// with MovePrefixes instead.
+func MovePrefix(store brtypes.SnapStore, oldPrefix, newPrefix string) error {
+	return MovePrefixes(store, map[string]string{oldPrefix: newPrefix})
+}
+
+// MovePrefixes moves all the snapshots stored under each of the old prefixes of the given map to its new prefix like
+// MovePrefix. The snapshots are deleted from the old prefixes only once the snapshots of all of them have been copied,
+// so that a snapshot chain spanning several prefixes, such as the one of a kind prefixed snapstore, remains
+// restorable from one of the sets of prefixes if the move fails.
+func MovePrefixes(store brtypes.SnapStore, prefixes map[string]string) error {
+	ps, ok := prefixCopyingSnapStore(store)
+	if !ok {
+		return fmt.Errorf("snapstore does not support copying snapshots to another prefix")
+	}
+	var (
+		snapList    brtypes.SnapList
+		objectPaths []string
+	)
+	for oldPrefix := range prefixes {
+		prefixSnapList, err := ps.ListPrefix(path.Clean(oldPrefix))
+		if err != nil {
+			return fmt.Errorf("failed to list the snapshots under prefix %s: %v", oldPrefix, err)
+		}
+		prefixObjectPaths, err := ps.ListPrefixObjects(path.Clean(oldPrefix))
+		if err != nil {
+			return fmt.Errorf("failed to list the objects under prefix %s: %v", oldPrefix, err)
+		}
+		snapList = append(snapList, prefixSnapList...)
+		objectPaths = append(objectPaths, prefixObjectPaths...)
+	}
+	if _, err := CopyPrefixes(store, prefixes); err != nil {
+		return err
+	}
+	for _, snap := range snapList {
+		if err := store.Delete(*snap); err != nil {
+			return fmt.Errorf("failed to delete snapshot %s from prefix %s after copying it: %v", path.Join(snap.SnapDir, snap.SnapName), snap.Prefix, err)
+		}
+	}
+	for _, objectPath := range objectPaths {
+		if err := ps.DeleteObject(objectPath); err != nil {
+			return fmt.Errorf("failed to delete object %s after copying it: %v", objectPath, err)
+		}
+	}
+	for oldPrefix, newPrefix := range prefixes {
+		logrus.Infof("Moved the snapshots from prefix %s to prefix %s", oldPrefix, newPrefix)
+	}
+	return nil
+}
+
+// cleanPrefixes returns the cleaned old prefixes of the given map in order, along with the map of the cleaned prefixes.
+// Returns an error if no prefixes are given, or if any of the old and new prefixes overlap.
+func cleanPrefixes(prefixes map[string]string) ([]string, map[string]string, error) {
+	if len(prefixes) == 0 {
+		return nil, nil, fmt.Errorf("no prefixes given")
+	}
+	var (
+		oldPrefixes []string
+		cleaned     = make(map[string]string, len(prefixes))
+		all         []string
+	)
+	for oldPrefix, newPrefix := range prefixes {
+		oldPrefix, newPrefix = path.Clean(oldPrefix), path.Clean(newPrefix)
+		oldPrefixes = append(oldPrefixes, oldPrefix)
+		cleaned[oldPrefix] = newPrefix
+		all = append(all, oldPrefix, newPrefix)
+	}
+	sort.Strings(oldPrefixes)
+	for i := range all {
+		for j := i + 1; j < len(all); j++ {
+			if isPrefixOf(all[i], all[j]) || isPrefixOf(all[j], all[i]) {
+				return nil, nil, fmt.Errorf("prefixes %s and %s overlap", all[i], all[j])
+			}
+		}
+	}
+	return oldPrefixes, cleaned, nil
+}
+
+// checkKindPrefixesCovered returns an error if the given snapstore is a kind prefixed snapstore, and the given old
+// prefixes hold some but not all of its prefixes, as the snapshot chain spanning them would then be split.
+func checkKindPrefixesCovered(store brtypes.SnapStore, oldPrefixes []string) error {
+	ks, ok := unwrapKindPrefixedSnapStore(store)
+	if !ok {
+		return nil
+	}
+	var covered, uncovered []string
+	for _, s := range ks.snapStores() {
+		p, ok := s.(prefixedSnapStore)
+		if !ok {
+			return fmt.Errorf("snapstore does not support copying the snapshots of its kinds to another prefix")
+		}
+		prefix := path.Clean(p.snapshotPrefix())
+		isCovered := false
+		for _, oldPrefix := range oldPrefixes {
+			if isPrefixOf(oldPrefix, prefix) {
+				isCovered = true
+				break
+			}
+		}
+		if isCovered {
+			covered = append(covered, prefix)
+		} else {
+			uncovered = append(uncovered, prefix)
+		}
+	}
+	if len(covered) > 0 && len(uncovered) > 0 {
+		return fmt.Errorf("the snapshot chain of the snapstore is stored under prefixes %s, of which %s are not given, and would be split", strings.Join(append(covered, uncovered...), ", "), strings.Join(uncovered, ", "))
+	}
+	return nil
+}
+
+// verifyCopiedSnapshots checks that the copied snapshots are exactly the snapshots which were copied.
+func verifyCopiedSnapshots(snapList, copiedSnapList brtypes.SnapList) error {
+	if len(copiedSnapList) != len(snapList) {
+		return fmt.Errorf("found %d snapshots instead of %d", len(copiedSnapList), len(snapList))
+	}
+	copied := make(map[string]*brtypes.Snapshot, len(copiedSnapList))
+	for _, snap := range copiedSnapList {
+		copied[path.Join(snap.SnapDir, snap.SnapName)] = snap
+	}
+	for _, snap := range snapList {
+		name := path.Join(snap.SnapDir, snap.SnapName)
+		copiedSnap, ok := copied[name]
+		if !ok {
+			return fmt.Errorf("snapshot %s is missing", name)
+		}
+		if copiedSnap.Kind != snap.Kind || copiedSnap.StartRevision != snap.StartRevision || copiedSnap.LastRevision != snap.LastRevision {
+			return fmt.Errorf("snapshot %s is listed as %s snapshot of revisions %d-%d instead of %s snapshot of revisions %d-%d", name,
+				copiedSnap.Kind, copiedSnap.StartRevision, copiedSnap.LastRevision, snap.Kind, snap.StartRevision, snap.LastRevision)
+		}
+	}
+	return nil
+}
+
+// isObjectAlongsideSnapshots returns whether the object at the given path is one of the objects which are stored
+// alongside the snapshots but aren't snapshots.
+func isObjectAlongsideSnapshots(objectPath string) bool {
+	return isChainManifestObject(objectPath) || isCompressionDictionaryObject(objectPath) || isClusterMetadataObject(objectPath) || isRestoreLockObject(objectPath)
+}
+
+// isPrefixOf returns whether the given prefix is the path or a parent path of the given path.
+func isPrefixOf(prefix, p string) bool {
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
+
+// prefixCopyingSnapStore returns the snapstore underlying the given snapstore as a PrefixCopyingSnapStore.
+func prefixCopyingSnapStore(store brtypes.SnapStore) (brtypes.PrefixCopyingSnapStore, bool) {
+	ps, ok := unwrapSnapStore(store).(brtypes.PrefixCopyingSnapStore)
+	return ps, ok
+}
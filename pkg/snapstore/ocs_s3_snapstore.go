@@ -36,7 +36,18 @@ func NewOCSSnapStore(config *brtypes.SnapstoreConfig) (*S3SnapStore, error) {
 		return nil, err
 	}
 
-	return newGenericS3FromAuthOpt(config.Container, config.Prefix, config.TempDir, config.MaxParallelChunkUploads, config.MinChunkSize, ocsAuthOptionsToGenericS3(*credentials))
+	ao := ocsAuthOptionsToGenericS3(*credentials)
+	if config.S3Region != "" {
+		ao.region = config.S3Region
+	}
+	store, err := newGenericS3FromAuthOpt(config.Container, config.Prefix, config.TempDir, config.MaxParallelChunkUploads, config.MinChunkSize, genericS3HTTPTransport(config, ao), ao)
+	if err != nil {
+		return nil, err
+	}
+	store.ObjectACL = config.ObjectACL
+	store.ObjectLockProtection = config.ObjectLockProtection
+	store.MultipartThreshold = config.MultipartThreshold
+	return store, nil
 }
 
 func getOCSAuthOptions(prefix string) (*ocsAuthOptions, error) {
@@ -5,10 +5,13 @@
 package snapstore
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
+	"net/http"
 	"os"
 	"path"
 	"path/filepath"
@@ -30,8 +33,11 @@ type OSSBucket interface {
 	CompleteMultipartUpload(imur oss.InitiateMultipartUploadResult, parts []oss.UploadPart, options ...oss.Option) (oss.CompleteMultipartUploadResult, error)
 	ListObjects(options ...oss.Option) (oss.ListObjectsResult, error)
 	DeleteObject(objectKey string, options ...oss.Option) error
+	PutObject(objectKey string, reader io.Reader, options ...oss.Option) error
 	UploadPart(imur oss.InitiateMultipartUploadResult, reader io.Reader, partSize int64, partNumber int, options ...oss.Option) (oss.UploadPart, error)
 	AbortMultipartUpload(imur oss.InitiateMultipartUploadResult, options ...oss.Option) error
+	GetObjectTagging(objectKey string, options ...oss.Option) (oss.GetObjectTaggingResult, error)
+	PutObjectTagging(objectKey string, tagging oss.Tagging, options ...oss.Option) error
 }
 
 const (
@@ -50,6 +56,8 @@ type authOptions struct {
 
 // OSSSnapStore is snapstore with Alicloud OSS object store as backend
 type OSSSnapStore struct {
+	snapshotNaming
+	listThrottling
 	prefix                  string
 	bucket                  OSSBucket
 	multiPart               sync.Mutex
@@ -64,11 +72,11 @@ func NewOSSSnapStore(config *brtypes.SnapstoreConfig) (*OSSSnapStore, error) {
 	if err != nil {
 		return nil, err
 	}
-	return newOSSFromAuthOpt(config.Container, config.Prefix, config.TempDir, config.MaxParallelChunkUploads, config.MinChunkSize, *ao)
+	return newOSSFromAuthOpt(config.Container, config.Prefix, config.TempDir, config.MaxParallelChunkUploads, config.MinChunkSize, *ao, oss.HTTPClient(&http.Client{Transport: sharedHTTPTransport(config)}))
 }
 
-func newOSSFromAuthOpt(bucket, prefix, tempDir string, maxParallelChunkUploads uint, minChunkSize int64, ao authOptions) (*OSSSnapStore, error) {
-	client, err := oss.New(ao.Endpoint, ao.AccessID, ao.AccessKey)
+func newOSSFromAuthOpt(bucket, prefix, tempDir string, maxParallelChunkUploads uint, minChunkSize int64, ao authOptions, options ...oss.ClientOption) (*OSSSnapStore, error) {
+	client, err := oss.New(ao.Endpoint, ao.AccessID, ao.AccessKey, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -217,30 +225,15 @@ func (s *OSSSnapStore) uploadPart(imur oss.InitiateMultipartUploadResult, file *
 
 // List will return sorted list with all snapshot files on store.
 func (s *OSSSnapStore) List() (brtypes.SnapList, error) {
-	prefixTokens := strings.Split(s.prefix, "/")
-	// Last element of the tokens is backup version
-	// Consider the parent of the backup version level (Required for Backward Compatibility)
-	prefix := path.Join(strings.Join(prefixTokens[:len(prefixTokens)-1], "/"))
-
 	var snapList brtypes.SnapList
 
 	marker := ""
 	for {
-		lsRes, err := s.bucket.ListObjects(oss.Marker(marker), oss.Prefix(prefix))
+		lsRes, err := s.bucket.ListObjects(oss.Marker(marker), oss.Prefix(s.listPrefix()))
 		if err != nil {
 			return nil, err
 		}
-		for _, object := range lsRes.Objects {
-			if strings.Contains(object.Key, backupVersionV1) || strings.Contains(object.Key, backupVersionV2) {
-				snap, err := ParseSnapshot(object.Key)
-				if err != nil {
-					// Warning
-					logrus.Warnf("Invalid snapshot found. Ignoring it: %s", object.Key)
-				} else {
-					snapList = append(snapList, snap)
-				}
-			}
-		}
+		snapList = append(snapList, s.parseSnapshotsFromObjects(lsRes.Objects)...)
 		if lsRes.IsTruncated {
 			marker = lsRes.NextMarker
 		} else {
@@ -252,11 +245,118 @@ func (s *OSSSnapStore) List() (brtypes.SnapList, error) {
 	return snapList, nil
 }
 
+// ListPaged will return sorted list with at most limit snapshot files on store, which are stored after the given marker
+// in the lexicographical order of the object keys. The returned marker is empty after the last page.
+func (s *OSSSnapStore) ListPaged(marker string, limit int) (brtypes.SnapList, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("limit should be greater than zero")
+	}
+	lsRes, err := s.bucket.ListObjects(oss.Marker(marker), oss.Prefix(s.listPrefix()), oss.MaxKeys(limit))
+	if err != nil {
+		return nil, "", err
+	}
+	snapList := s.parseSnapshotsFromObjects(lsRes.Objects)
+	sort.Sort(snapList)
+	var nextMarker string
+	if lsRes.IsTruncated {
+		nextMarker = lsRes.NextMarker
+	}
+	return snapList, nextMarker, nil
+}
+
+// listPrefix returns the prefix the snapshots are listed under.
+func (s *OSSSnapStore) listPrefix() string {
+	prefixTokens := strings.Split(s.prefix, "/")
+	// Last element of the tokens is backup version
+	// Consider the parent of the backup version level (Required for Backward Compatibility)
+	return path.Join(strings.Join(prefixTokens[:len(prefixTokens)-1], "/"))
+}
+
+// parseSnapshotsFromObjects returns the snapshots of the given objects, ignoring the objects which aren't snapshots.
+func (s *OSSSnapStore) parseSnapshotsFromObjects(objects []oss.ObjectProperties) brtypes.SnapList {
+	var snapList brtypes.SnapList
+	for _, object := range objects {
+		if strings.Contains(object.Key, backupVersionV1) || strings.Contains(object.Key, backupVersionV2) {
+			snap, err := s.parseSnapshot(object.Key)
+			if err != nil {
+				// Warning
+				logrus.Warnf("Invalid snapshot found. Ignoring it: %s", object.Key)
+			} else {
+				snapList = append(snapList, snap)
+			}
+		}
+	}
+	return snapList
+}
+
 // Delete should delete the snapshot file from store
 func (s *OSSSnapStore) Delete(snap brtypes.Snapshot) error {
 	return s.bucket.DeleteObject(path.Join(snap.Prefix, snap.SnapDir, snap.SnapName))
 }
 
+// SetRetained sets the exclude tag on the snapshot if retained is true, and clears it otherwise.
+// The other tags of the snapshot object are left untouched.
+func (s *OSSSnapStore) SetRetained(snap brtypes.Snapshot, retained bool) error {
+	objectKey := path.Join(snap.Prefix, snap.SnapDir, snap.SnapName)
+	tagging, err := s.bucket.GetObjectTagging(objectKey)
+	if err != nil {
+		return err
+	}
+	var tags []oss.Tag
+	for _, tag := range tagging.Tags {
+		if tag.Key != brtypes.SnapshotExcludeTag {
+			tags = append(tags, tag)
+		}
+	}
+	if retained {
+		tags = append(tags, oss.Tag{Key: brtypes.SnapshotExcludeTag, Value: "true"})
+	}
+	return s.bucket.PutObjectTagging(objectKey, oss.Tagging{Tags: tags})
+}
+
+// IsRetained returns whether the exclude tag is set on the snapshot.
+func (s *OSSSnapStore) IsRetained(snap brtypes.Snapshot) (bool, error) {
+	tagging, err := s.bucket.GetObjectTagging(path.Join(snap.Prefix, snap.SnapDir, snap.SnapName))
+	if err != nil {
+		return false, err
+	}
+	for _, tag := range tagging.Tags {
+		if tag.Key == brtypes.SnapshotExcludeTag {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Verify checks that the bucket exists and is accessible by listing a single object under the prefix, and if write is
+// true, that an object can be written under the prefix, by writing and deleting a small object.
+func (s *OSSSnapStore) Verify(write bool) error {
+	if _, err := s.bucket.ListObjects(oss.Prefix(s.prefix), oss.MaxKeys(1)); err != nil {
+		var serviceErr oss.ServiceError
+		if errors.As(err, &serviceErr) {
+			switch {
+			case serviceErr.Code == "NoSuchBucket":
+				return newVerificationError(CredentialsFailureBucketMissing, "bucket does not exist")
+			case serviceErr.StatusCode == http.StatusUnauthorized || serviceErr.StatusCode == http.StatusForbidden:
+				return newVerificationError(CredentialsFailureAuth, "access to bucket is denied: %w", err)
+			}
+		}
+		return fmt.Errorf("failed to access bucket: %w", err)
+	}
+	if !write {
+		return nil
+	}
+
+	key := path.Join(s.prefix, verificationObjectName)
+	if err := s.bucket.PutObject(key, bytes.NewReader(nil)); err != nil {
+		return fmt.Errorf("failed to write to bucket: %v", err)
+	}
+	if err := s.bucket.DeleteObject(key); err != nil {
+		return fmt.Errorf("failed to delete from bucket: %v", err)
+	}
+	return nil
+}
+
 func getAuthOptions(prefix string) (*authOptions, error) {
 	if filename, isSet := os.LookupEnv(prefix + aliCredentialJSONFile); isSet {
 		ao, err := readALICredentialsJSON(filename)
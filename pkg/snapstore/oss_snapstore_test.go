@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"io"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -37,6 +39,11 @@ type mockOSSBucket struct {
 	multiPartUploads      map[string]*[][]byte
 	multiPartUploadsMutex sync.Mutex
 	bucketName            string
+	// tags holds the tags of the objects by their key.
+	tags map[string][]oss.Tag
+	// listObjectsErr and putObjectErr are returned by ListObjects and PutObject if set.
+	listObjectsErr error
+	putObjectErr   error
 }
 
 // GetObject returns the object from map for mock test
@@ -129,24 +136,85 @@ func (m *mockOSSBucket) AbortMultipartUpload(imur oss.InitiateMultipartUploadRes
 	return nil
 }
 
-// ListObject returns the objects from map for mock test
+// ListObject returns the objects from map for mock test, which are stored after the marker under the prefix, in pages
+// of at most max-keys objects.
 func (m *mockOSSBucket) ListObjects(options ...oss.Option) (oss.ListObjectsResult, error) {
-	var contents []oss.ObjectProperties
+	if m.listObjectsErr != nil {
+		return oss.ListObjectsResult{}, m.listObjectsErr
+	}
+	marker, err := oss.FindOption(options, "marker", "")
+	if err != nil {
+		return oss.ListObjectsResult{}, err
+	}
+	prefix, err := oss.FindOption(options, "prefix", "")
+	if err != nil {
+		return oss.ListObjectsResult{}, err
+	}
+	maxKeys, err := oss.FindOption(options, "max-keys", "")
+	if err != nil {
+		return oss.ListObjectsResult{}, err
+	}
+	limit := len(m.objects)
+	if maxKeys.(string) != "" {
+		if limit, err = strconv.Atoi(maxKeys.(string)); err != nil {
+			return oss.ListObjectsResult{}, err
+		}
+	}
+
+	var keys []string
 	for key := range m.objects {
-		tempObj := oss.ObjectProperties{
-			Key: key,
+		if strings.HasPrefix(key, prefix.(string)) && key > marker.(string) {
+			keys = append(keys, key)
 		}
-		contents = append(contents, tempObj)
 	}
-	out := oss.ListObjectsResult{
-		Objects:     contents,
-		IsTruncated: false,
+	sort.Strings(keys)
+	out := oss.ListObjectsResult{}
+	for i, key := range keys {
+		if i == limit {
+			out.IsTruncated = true
+			out.NextMarker = keys[i-1]
+			break
+		}
+		out.Objects = append(out.Objects, oss.ObjectProperties{Key: key})
 	}
 	return out, nil
 }
 
+// PutObject writes the object to map for mock test
+func (m *mockOSSBucket) PutObject(objectKey string, reader io.Reader, options ...oss.Option) error {
+	if m.putObjectErr != nil {
+		return m.putObjectErr
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	m.objects[objectKey] = &content
+	return nil
+}
+
 // DeleteObject deletes the object from map for mock test
 func (m *mockOSSBucket) DeleteObject(objectKey string, options ...oss.Option) error {
 	delete(m.objects, objectKey)
 	return nil
 }
+
+// GetObjectTagging returns the tags of the object from map for mock test
+func (m *mockOSSBucket) GetObjectTagging(objectKey string, options ...oss.Option) (oss.GetObjectTaggingResult, error) {
+	if m.objects[objectKey] == nil {
+		return oss.GetObjectTaggingResult{}, fmt.Errorf("object not found")
+	}
+	return oss.GetObjectTaggingResult{Tags: m.tags[objectKey]}, nil
+}
+
+// PutObjectTagging replaces the tags of the object in map for mock test
+func (m *mockOSSBucket) PutObjectTagging(objectKey string, tagging oss.Tagging, options ...oss.Option) error {
+	if m.objects[objectKey] == nil {
+		return fmt.Errorf("object not found")
+	}
+	if m.tags == nil {
+		m.tags = map[string][]oss.Tag{}
+	}
+	m.tags[objectKey] = tagging.Tags
+	return nil
+}
@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapstore
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+)
+
+// quotaExceededMarkers are the lower-cased fragments of the error messages with which the object stores and file
+// systems reject writes because the bucket, the account or the volume is out of quota or capacity. The errors are
+// matched by their message, as most of the snapstores don't wrap the errors of the provider SDKs.
+var quotaExceededMarkers = []string{
+	"quotaexceeded",
+	"quota exceeded",
+	"storagefull",
+	"storage full",
+	"insufficientstorage",
+	"insufficient storage",
+	"no space left on device",
+	"disk quota exceeded",
+}
+
+// IsQuotaExceededError checks whether the given error of a snapstore operation is caused by the snapstore being out of
+// quota or capacity, which is expected to be resolved without restarting, for example by the garbage collection or by
+// an operator raising the quota.
+func IsQuotaExceededError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EDQUOT) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, marker := range quotaExceededMarkers {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
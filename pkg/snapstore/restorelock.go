@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapstore
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrRestoreLockExists is returned when the restore lock is created while another restoration holds it.
+var ErrRestoreLockExists = errors.New("restore lock is held by another restoration")
+
+// AcquireRestoreLock acquires the restore lock of the given snapstore for the given holder until the given TTL has
+// passed. A stale restore lock, which has expired, is taken over. Returns an error wrapping ErrRestoreLockExists if
+// another restoration holds the restore lock, or an error if the snapstore doesn't support the restore lock.
+func AcquireRestoreLock(store brtypes.SnapStore, holder, host string, ttl time.Duration) (*brtypes.RestoreLock, error) {
+	ls, err := restoreLockSnapStore(store)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	lock := &brtypes.RestoreLock{
+		Holder:     holder,
+		Host:       host,
+		AcquiredOn: now,
+		ExpiresOn:  now.Add(ttl),
+	}
+	err = ls.CreateRestoreLock(lock)
+	if !errors.Is(err, ErrRestoreLockExists) {
+		return lock, err
+	}
+
+	current, err := ls.FetchRestoreLock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the restore lock: %v", err)
+	}
+	if current != nil {
+		if current.ExpiresOn.After(now) {
+			return nil, fmt.Errorf("%w: held by %s on host %s since %s until %s", ErrRestoreLockExists, current.Holder, current.Host, current.AcquiredOn, current.ExpiresOn)
+		}
+		logrus.Warnf("Taking over the stale restore lock held by %s on host %s, which expired on %s", current.Holder, current.Host, current.ExpiresOn)
+		// the stale restore lock is only replaced if no other restoration took it over or released it in the meantime,
+		// so that exactly one of the restorations taking it over concurrently succeeds
+		if err := ls.ReplaceRestoreLock(current, lock); err != nil {
+			return nil, err
+		}
+		return lock, nil
+	}
+	// the restore lock was released in the meantime, and may be created concurrently by another restoration
+	if err := ls.CreateRestoreLock(lock); err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+// ReleaseRestoreLock releases the restore lock of the given snapstore if it is held by the given holder.
+func ReleaseRestoreLock(store brtypes.SnapStore, holder string) error {
+	ls, err := restoreLockSnapStore(store)
+	if err != nil {
+		return err
+	}
+	return ls.DeleteRestoreLock(holder)
+}
+
+// FetchRestoreLock returns the restore lock of the given snapstore, or nil if no restoration holds it. Returns an error
+// if the snapstore doesn't support the restore lock.
+func FetchRestoreLock(store brtypes.SnapStore) (*brtypes.RestoreLock, error) {
+	ls, err := restoreLockSnapStore(store)
+	if err != nil {
+		return nil, err
+	}
+	return ls.FetchRestoreLock()
+}
+
+// restoreLockSnapStore returns the given snapstore as a snapstore of the restore lock, which is saved under the prefix
+// of the snapstore rather than a date partition, and isn't fetched through the fetch cache.
+func restoreLockSnapStore(store brtypes.SnapStore) (brtypes.RestoreLockSnapStore, error) {
+	ls, ok := unwrapSnapStore(store).(brtypes.RestoreLockSnapStore)
+	if !ok {
+		return nil, fmt.Errorf("snapstore does not support the restore lock")
+	}
+	return ls, nil
+}
+
+// isRestoreLockObject returns whether the object at the given path is the restore lock, or a temporary file written
+// while creating it, which are stored next to the snapshots but aren't snapshots.
+func isRestoreLockObject(objectPath string) bool {
+	return strings.HasPrefix(path.Base(objectPath), brtypes.RestoreLockName)
+}
@@ -0,0 +1,38 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapstore
+
+import (
+	"fmt"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// SetSnapshotRetained sets the exclude tag on the given snapshot if retained is true, which excludes the snapshot
+// from garbage collection until the tag is cleared again by setting retained to false.
+// Returns an error if the snapstore doesn't support tagging the snapshots.
+func SetSnapshotRetained(store brtypes.SnapStore, snap brtypes.Snapshot, retained bool) error {
+	rs, ok := retainableSnapStore(store)
+	if !ok {
+		return fmt.Errorf("snapstore does not support retaining snapshots")
+	}
+	return rs.SetRetained(snap, retained)
+}
+
+// IsSnapshotRetained returns whether the exclude tag is set on the given snapshot.
+// Snapshots of a snapstore which doesn't support tagging the snapshots are never retained.
+func IsSnapshotRetained(store brtypes.SnapStore, snap brtypes.Snapshot) (bool, error) {
+	rs, ok := retainableSnapStore(store)
+	if !ok {
+		return false, nil
+	}
+	return rs.IsRetained(snap)
+}
+
+// retainableSnapStore returns the snapstore underlying the given snapstore as a RetainableSnapStore.
+func retainableSnapStore(store brtypes.SnapStore) (brtypes.RetainableSnapStore, bool) {
+	rs, ok := unwrapSnapStore(store).(brtypes.RetainableSnapStore)
+	return rs, ok
+}
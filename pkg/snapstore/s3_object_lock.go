@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapstore
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// errCodeObjectLockConfigurationNotFound is the error code returned for the object lock configuration of a bucket
+// without object lock enabled.
+const errCodeObjectLockConfigurationNotFound = "ObjectLockConfigurationNotFoundError"
+
+// objectLockDetection caches whether object lock is enabled on the bucket, once it has been detected.
+type objectLockDetection struct {
+	mutex    sync.Mutex
+	detected bool
+	enabled  bool
+}
+
+// isObjectLockEnabled returns whether object lock is enabled on the bucket. It is only detected once, unless the
+// detection fails.
+func (s *S3SnapStore) isObjectLockEnabled() (bool, error) {
+	s.objectLock.mutex.Lock()
+	defer s.objectLock.mutex.Unlock()
+	if s.objectLock.detected {
+		return s.objectLock.enabled, nil
+	}
+
+	out, err := s.client.GetObjectLockConfiguration(&s3.GetObjectLockConfigurationInput{
+		Bucket: aws.String(s.bucket),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != errCodeObjectLockConfigurationNotFound {
+			return false, fmt.Errorf("failed to get the object lock configuration of bucket %s: %v", s.bucket, err)
+		}
+	} else if out.ObjectLockConfiguration != nil {
+		s.objectLock.enabled = aws.StringValue(out.ObjectLockConfiguration.ObjectLockEnabled) == s3.ObjectLockEnabledEnabled
+	}
+	s.objectLock.detected = true
+	return s.objectLock.enabled, nil
+}
+
+// isObjectLocked returns whether the object with the given key is under object lock retention or legal hold, so that
+// it can neither be overwritten nor deleted. Objects are never considered locked if the object lock protection is
+// disabled, or if object lock is not enabled on the bucket.
+func (s *S3SnapStore) isObjectLocked(key string) (bool, error) {
+	if !s.ObjectLockProtection {
+		return false, nil
+	}
+	enabled, err := s.isObjectLockEnabled()
+	if err != nil || !enabled {
+		return false, err
+	}
+
+	headObjectInput := &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+	if s.sseCustomerKey != "" {
+		// Customer managed Server Side Encryption
+		headObjectInput.SSECustomerAlgorithm = aws.String(s.sseCustomerAlgorithm)
+		headObjectInput.SSECustomerKey = aws.String(s.sseCustomerKey)
+		headObjectInput.SSECustomerKeyMD5 = aws.String(s.sseCustomerKeyMD5)
+	}
+	headObjectOutput, err := s.client.HeadObject(headObjectInput)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && (aerr.Code() == "NotFound" || aerr.Code() == s3.ErrCodeNoSuchKey) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get the object lock retention of %s: %v", key, err)
+	}
+	if aws.StringValue(headObjectOutput.ObjectLockLegalHoldStatus) == s3.ObjectLockLegalHoldStatusOn {
+		return true, nil
+	}
+	return aws.TimeValue(headObjectOutput.ObjectLockRetainUntilDate).After(time.Now()), nil
+}
+
+// checkObjectUnlocked returns an error if the object with the given key is under object lock retention or legal hold,
+// so that it is not attempted to be overwritten or deleted, which would fail anyway.
+func (s *S3SnapStore) checkObjectUnlocked(key, operation string) error {
+	locked, err := s.isObjectLocked(key)
+	if err != nil {
+		return err
+	}
+	if locked {
+		return fmt.Errorf("object %s is under object lock retention or legal hold and cannot be %s", key, operation)
+	}
+	return nil
+}
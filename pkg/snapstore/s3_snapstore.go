@@ -5,16 +5,16 @@
 package snapstore
 
 import (
+	"bytes"
 	"context"
 	"crypto/md5"
-	"crypto/tls"
-	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"math"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
@@ -25,10 +25,14 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/sirupsen/logrus"
 	"k8s.io/utils/pointer"
 
@@ -40,8 +44,20 @@ const (
 	s3NoOfChunk            int64 = 9999
 	awsCredentialDirectory       = "AWS_APPLICATION_CREDENTIALS"
 	awsCredentialJSONFile        = "AWS_APPLICATION_CREDENTIALS_JSON"
+	// assumeRoleExpiryWindow is the time before the expiry of the credentials of an assumed role at which they are
+	// refreshed, so that no request is signed with credentials which expire while it is in flight.
+	assumeRoleExpiryWindow = 5 * time.Minute
+	// errCodePreconditionFailed is the error code returned for a conditional write of an object which exists.
+	errCodePreconditionFailed = "PreconditionFailed"
+	// errCodeConditionalRequestConflict is the error code returned for a conditional write of an object which is
+	// written concurrently.
+	errCodeConditionalRequestConflict = "ConditionalRequestConflict"
 )
 
+// maxS3SingleCopyObjectSize is the upper size limit of an object copied with a single server-side copy, above which
+// the object is copied with a multipart upload.
+var maxS3SingleCopyObjectSize = brtypes.MaxS3SinglePutObjectSize
+
 type awsCredentials struct {
 	AccessKeyID          string  `json:"accessKeyID"`
 	Region               string  `json:"region"`
@@ -53,6 +69,10 @@ type awsCredentials struct {
 	S3ForcePathStyle     *bool   `json:"s3ForcePathStyle,omitempty"`
 	InsecureSkipVerify   *bool   `json:"insecureSkipVerify,omitempty"`
 	TrustedCaCert        *string `json:"trustedCaCert,omitempty"`
+	RoleARN              *string `json:"roleARN,omitempty"`
+	RoleSessionName      *string `json:"roleSessionName,omitempty"`
+	ExternalID           *string `json:"externalID,omitempty"`
+	STSEndpoint          *string `json:"stsEndpoint,omitempty"`
 }
 
 // SSECredentials to hold fields for server-side encryption in I/O operations
@@ -64,6 +84,8 @@ type SSECredentials struct {
 
 // S3SnapStore is snapstore with AWS S3 object store as backend
 type S3SnapStore struct {
+	snapshotNaming
+	listThrottling
 	prefix    string
 	client    s3iface.S3API
 	bucket    string
@@ -73,25 +95,45 @@ type S3SnapStore struct {
 	minChunkSize            int64
 	tempDir                 string
 	SSECredentials
+	// ObjectACL is the canned ACL set on the objects written to the snapstore. The default ACL of the bucket applies
+	// if it is empty.
+	ObjectACL string
+	// ObjectLockProtection prevents the snapshots which are under object lock retention or legal hold from being
+	// overwritten or deleted, if object lock is enabled on the bucket.
+	ObjectLockProtection bool
+	objectLock           objectLockDetection
+	// MultipartThreshold is the size from which the snapshots are uploaded in multiple parts, while the smaller
+	// snapshots are uploaded with a single put. All the snapshots are uploaded in multiple parts if it is zero.
+	MultipartThreshold int64
 }
 
 // NewS3SnapStore create new S3SnapStore from shared configuration with specified bucket
 func NewS3SnapStore(config *brtypes.SnapstoreConfig) (*S3SnapStore, error) {
-	sessionOpts, sseCreds, err := getSessionOptions(getEnvPrefixString(config.IsSource))
+	sessionOpts, sseCreds, err := getSessionOptions(getEnvPrefixString(config.IsSource), config)
 	if err != nil {
 		return nil, err
 	}
+	if config.S3Region != "" {
+		sessionOpts.Config.Region = pointer.String(config.S3Region)
+	}
 	sess, err := session.NewSessionWithOptions(sessionOpts)
 	if err != nil {
 		return nil, fmt.Errorf("new AWS session failed: %v", err)
 	}
+	if err := validateS3Region(aws.StringValue(sess.Config.Endpoint), aws.StringValue(sess.Config.Region)); err != nil {
+		return nil, err
+	}
 	cli := s3.New(sess)
-	return NewS3FromClient(config.Container, config.Prefix, config.TempDir, config.MaxParallelChunkUploads, config.MinChunkSize, cli, sseCreds), nil
+	store := NewS3FromClient(config.Container, config.Prefix, config.TempDir, config.MaxParallelChunkUploads, config.MinChunkSize, cli, sseCreds)
+	store.ObjectACL = config.ObjectACL
+	store.ObjectLockProtection = config.ObjectLockProtection
+	store.MultipartThreshold = config.MultipartThreshold
+	return store, nil
 }
 
-func getSessionOptions(prefixString string) (session.Options, SSECredentials, error) {
+func getSessionOptions(prefixString string, config *brtypes.SnapstoreConfig) (session.Options, SSECredentials, error) {
 	if filename, isSet := os.LookupEnv(prefixString + awsCredentialJSONFile); isSet {
-		creds, sseCreds, err := readAWSCredentialsJSONFile(filename)
+		creds, sseCreds, err := readAWSCredentialsJSONFile(filename, config)
 		if err != nil {
 			return session.Options{}, SSECredentials{}, fmt.Errorf("error getting credentials using %v file with error %w", filename, err)
 		}
@@ -106,7 +148,7 @@ func getSessionOptions(prefixString string) (session.Options, SSECredentials, er
 			return session.Options{}, SSECredentials{}, fmt.Errorf("error while finding a JSON credential file in %v directory with error: %w", dir, err)
 		}
 		if jsonCredentialFile != "" {
-			creds, sseCreds, err := readAWSCredentialsJSONFile(jsonCredentialFile)
+			creds, sseCreds, err := readAWSCredentialsJSONFile(jsonCredentialFile, config)
 			if err != nil {
 				return session.Options{}, SSECredentials{}, fmt.Errorf("error getting credentials using %v JSON file in a directory with error: %w", jsonCredentialFile, err)
 			}
@@ -116,7 +158,7 @@ func getSessionOptions(prefixString string) (session.Options, SSECredentials, er
 	}
 
 	if dir, isSet := os.LookupEnv(prefixString + awsCredentialDirectory); isSet {
-		creds, sseCreds, err := readAWSCredentialFiles(dir)
+		creds, sseCreds, err := readAWSCredentialFiles(dir, config)
 		if err != nil {
 			return session.Options{}, SSECredentials{}, fmt.Errorf("error getting credentials from %v directory with error %w", dir, err)
 		}
@@ -127,42 +169,34 @@ func getSessionOptions(prefixString string) (session.Options, SSECredentials, er
 		// Setting this is equal to the AWS_SDK_LOAD_CONFIG environment variable was set.
 		// We want to save the work to set AWS_SDK_LOAD_CONFIG=1 outside.
 		SharedConfigState: session.SharedConfigEnable,
+		Config: aws.Config{
+			HTTPClient: &http.Client{Transport: sharedHTTPTransport(config)},
+		},
 	}, SSECredentials{}, nil
 }
 
-func readAWSCredentialsJSONFile(filename string) (session.Options, SSECredentials, error) {
+func readAWSCredentialsJSONFile(filename string, config *brtypes.SnapstoreConfig) (session.Options, SSECredentials, error) {
 	awsConfig, err := credentialsFromJSON(filename)
 	if err != nil {
 		return session.Options{}, SSECredentials{}, err
 	}
 
-	httpClient := http.DefaultClient
-	if awsConfig.InsecureSkipVerify != nil && *awsConfig.InsecureSkipVerify == true {
-		httpClient.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: *awsConfig.InsecureSkipVerify},
-		}
-	}
+	insecureSkipVerify := awsConfig.InsecureSkipVerify != nil && *awsConfig.InsecureSkipVerify
+	httpClient := &http.Client{Transport: sharedTLSHTTPTransport(config, insecureSkipVerify, awsConfig.TrustedCaCert)}
 
-	if awsConfig.TrustedCaCert != nil {
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM([]byte(*awsConfig.TrustedCaCert))
-		httpClient.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				RootCAs:            caCertPool,
-				InsecureSkipVerify: false,
-				MinVersion:         tls.VersionTLS13,
-			},
-		}
+	sseCreds, err := getSSECreds(awsConfig.SSECustomerKey, awsConfig.SSECustomerAlgorithm)
+	if err != nil {
+		return session.Options{}, SSECredentials{}, err
 	}
 
-	sseCreds, err := getSSECreds(awsConfig.SSECustomerKey, awsConfig.SSECustomerAlgorithm)
+	creds, err := getAWSCredentials(awsConfig, httpClient)
 	if err != nil {
 		return session.Options{}, SSECredentials{}, err
 	}
 
 	return session.Options{
 		Config: aws.Config{
-			Credentials:      credentials.NewStaticCredentials(awsConfig.AccessKeyID, awsConfig.SecretAccessKey, ""),
+			Credentials:      creds,
 			Region:           pointer.String(awsConfig.Region),
 			Endpoint:         awsConfig.Endpoint,
 			S3ForcePathStyle: awsConfig.S3ForcePathStyle,
@@ -171,6 +205,40 @@ func readAWSCredentialsJSONFile(filename string) (session.Options, SSECredential
 	}, sseCreds, nil
 }
 
+// getAWSCredentials returns the credentials for the S3 requests. If a role is configured, these are the credentials of
+// the assumed role, which are refreshed ahead of their expiry. The role is assumed with the access key, if any, or else
+// with the credentials resolved by the default credential chain of the AWS SDK, e.g. from a web identity token.
+func getAWSCredentials(awsConfig *awsCredentials, httpClient *http.Client) (*credentials.Credentials, error) {
+	if awsConfig.RoleARN == nil {
+		return credentials.NewStaticCredentials(awsConfig.AccessKeyID, awsConfig.SecretAccessKey, ""), nil
+	}
+
+	var sourceCredentials *credentials.Credentials
+	if len(awsConfig.AccessKeyID) != 0 {
+		sourceCredentials = credentials.NewStaticCredentials(awsConfig.AccessKeyID, awsConfig.SecretAccessKey, "")
+	}
+
+	sourceSession, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+		Config: aws.Config{
+			Credentials: sourceCredentials,
+			Region:      pointer.String(awsConfig.Region),
+			HTTPClient:  httpClient,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("new AWS session for assuming the role %s failed: %v", *awsConfig.RoleARN, err)
+	}
+	stsClient := sts.New(sourceSession, &aws.Config{Endpoint: awsConfig.STSEndpoint})
+	return stscreds.NewCredentialsWithClient(stsClient, *awsConfig.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+		if awsConfig.RoleSessionName != nil {
+			p.RoleSessionName = *awsConfig.RoleSessionName
+		}
+		p.ExternalID = awsConfig.ExternalID
+		p.ExpiryWindow = assumeRoleExpiryWindow
+	}), nil
+}
+
 // credentialsFromJSON obtains AWS credentials from a JSON value.
 func credentialsFromJSON(filename string) (*awsCredentials, error) {
 	jsonData, err := os.ReadFile(filename)
@@ -184,38 +252,27 @@ func credentialsFromJSON(filename string) (*awsCredentials, error) {
 	return awsConfig, nil
 }
 
-func readAWSCredentialFiles(dirname string) (session.Options, SSECredentials, error) {
+func readAWSCredentialFiles(dirname string, config *brtypes.SnapstoreConfig) (session.Options, SSECredentials, error) {
 	awsConfig, err := readAWSCredentialFromDir(dirname)
 	if err != nil {
 		return session.Options{}, SSECredentials{}, err
 	}
 
-	httpClient := http.DefaultClient
-	if awsConfig.InsecureSkipVerify != nil && *awsConfig.InsecureSkipVerify == true {
-		httpClient.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: *awsConfig.InsecureSkipVerify},
-		}
+	insecureSkipVerify := awsConfig.InsecureSkipVerify != nil && *awsConfig.InsecureSkipVerify
+	httpClient := &http.Client{Transport: sharedTLSHTTPTransport(config, insecureSkipVerify, awsConfig.TrustedCaCert)}
+	sseCreds, err := getSSECreds(awsConfig.SSECustomerKey, awsConfig.SSECustomerAlgorithm)
+	if err != nil {
+		return session.Options{}, SSECredentials{}, err
 	}
 
-	if awsConfig.TrustedCaCert != nil {
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM([]byte(*awsConfig.TrustedCaCert))
-		httpClient.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				RootCAs:            caCertPool,
-				InsecureSkipVerify: false,
-				MinVersion:         tls.VersionTLS13,
-			},
-		}
-	}
-	sseCreds, err := getSSECreds(awsConfig.SSECustomerKey, awsConfig.SSECustomerAlgorithm)
+	creds, err := getAWSCredentials(awsConfig, httpClient)
 	if err != nil {
 		return session.Options{}, SSECredentials{}, err
 	}
 
 	return session.Options{
 		Config: aws.Config{
-			Credentials:      credentials.NewStaticCredentials(awsConfig.AccessKeyID, awsConfig.SecretAccessKey, ""),
+			Credentials:      creds,
 			Region:           pointer.String(awsConfig.Region),
 			Endpoint:         awsConfig.Endpoint,
 			S3ForcePathStyle: awsConfig.S3ForcePathStyle,
@@ -296,6 +353,30 @@ func readAWSCredentialFromDir(dirname string) (*awsCredentials, error) {
 				return nil, err
 			}
 			awsConfig.SSECustomerAlgorithm = pointer.String(string(data))
+		case "roleARN":
+			data, err := os.ReadFile(dirname + "/roleARN")
+			if err != nil {
+				return nil, err
+			}
+			awsConfig.RoleARN = pointer.String(string(data))
+		case "roleSessionName":
+			data, err := os.ReadFile(dirname + "/roleSessionName")
+			if err != nil {
+				return nil, err
+			}
+			awsConfig.RoleSessionName = pointer.String(string(data))
+		case "externalID":
+			data, err := os.ReadFile(dirname + "/externalID")
+			if err != nil {
+				return nil, err
+			}
+			awsConfig.ExternalID = pointer.String(string(data))
+		case "stsEndpoint":
+			data, err := os.ReadFile(dirname + "/stsEndpoint")
+			if err != nil {
+				return nil, err
+			}
+			awsConfig.STSEndpoint = pointer.String(string(data))
 		}
 	}
 
@@ -318,6 +399,19 @@ func NewS3FromClient(bucket, prefix, tempDir string, maxParallelChunkUploads uin
 	}
 }
 
+// objectACL returns the canned ACL to set on the objects written to the snapstore, or nil if none is configured.
+func (s *S3SnapStore) objectACL() *string {
+	if s.ObjectACL == "" {
+		return nil
+	}
+	return aws.String(s.ObjectACL)
+}
+
+// snapshotPrefix returns the prefix under which the snapshots are saved.
+func (s *S3SnapStore) snapshotPrefix() string {
+	return s.prefix
+}
+
 // Fetch should open reader for the snapshot file from store
 func (s *S3SnapStore) Fetch(snap brtypes.Snapshot) (io.ReadCloser, error) {
 	getObjectInput := &s3.GetObjectInput{
@@ -337,8 +431,32 @@ func (s *S3SnapStore) Fetch(snap brtypes.Snapshot) (io.ReadCloser, error) {
 	return getObjecOutput.Body, nil
 }
 
+// FetchRange should open reader for the given range of the snapshot object from store
+func (s *S3SnapStore) FetchRange(snap brtypes.Snapshot, offset, length int64) (io.ReadCloser, error) {
+	getObjectInput := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path.Join(snap.Prefix, snap.SnapDir, snap.SnapName)),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)),
+	}
+	if s.sseCustomerKey != "" {
+		// Customer managed Server Side Encryption
+		getObjectInput.SSECustomerAlgorithm = aws.String(s.sseCustomerAlgorithm)
+		getObjectInput.SSECustomerKey = aws.String(s.sseCustomerKey)
+		getObjectInput.SSECustomerKeyMD5 = aws.String(s.sseCustomerKeyMD5)
+	}
+	getObjecOutput, err := s.client.GetObject(getObjectInput)
+	if err != nil {
+		return nil, fmt.Errorf("error while accessing range %d-%d of %s: %v", offset, offset+length-1, path.Join(snap.Prefix, snap.SnapDir, snap.SnapName), err)
+	}
+	return getObjecOutput.Body, nil
+}
+
 // Save will write the snapshot to store
 func (s *S3SnapStore) Save(snap brtypes.Snapshot, rc io.ReadCloser) error {
+	if err := s.checkObjectUnlocked(path.Join(snap.Prefix, snap.SnapDir, snap.SnapName), "overwritten"); err != nil {
+		rc.Close()
+		return err
+	}
 	tmpfile, err := os.CreateTemp(s.tempDir, tmpBackupFilePrefix)
 	if err != nil {
 		rc.Close()
@@ -358,15 +476,22 @@ func (s *S3SnapStore) Save(snap brtypes.Snapshot, rc io.ReadCloser) error {
 	if err != nil {
 		return err
 	}
-	// Initiate multi part upload
+	prefix := adaptPrefix(&snap, s.prefix)
+	if s.MultipartThreshold > 0 && size < s.MultipartThreshold {
+		ctx, cancel := context.WithTimeout(context.TODO(), singlePutTimeout(size))
+		defer cancel()
+		return s.putSnapshot(ctx, path.Join(prefix, snap.SnapDir, snap.SnapName), tmpfile, size)
+	}
 	ctx := context.TODO()
 	ctx, cancel := context.WithTimeout(ctx, chunkUploadTimeout)
 	defer cancel()
-	prefix := adaptPrefix(&snap, s.prefix)
+
+	// Initiate multi part upload
 
 	createMultipartUploadInput := &s3.CreateMultipartUploadInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(path.Join(prefix, snap.SnapDir, snap.SnapName)),
+		ACL:    s.objectACL(),
 	}
 	if s.sseCustomerKey != "" {
 		// Customer managed Server Side Encryption
@@ -449,6 +574,41 @@ func (s *S3SnapStore) Save(snap brtypes.Snapshot, rc io.ReadCloser) error {
 	return nil
 }
 
+// singlePutTimeout returns the timeout of the single put of a snapshot of the given size, which is the timeout of a
+// chunk upload plus as much again for every whole GiB of the snapshot, as the multipart threshold below which the snapshots are uploaded
+// with a single put can be as high as the upper size limit of a single put.
+func singlePutTimeout(size int64) time.Duration {
+	return chunkUploadTimeout * time.Duration(1+size/(1<<30))
+}
+
+// putSnapshot uploads the snapshot of the given size from the given file with a single put, as it is below the
+// multipart threshold.
+func (s *S3SnapStore) putSnapshot(ctx context.Context, key string, file *os.File, size int64) error {
+	// the Content-MD5 header lets S3 reject the snapshot if it was corrupted in transit.
+	contentMD5 := md5.New()
+	if err := computeChecksum(file, contentMD5); err != nil {
+		return err
+	}
+	putObjectInput := &s3.PutObjectInput{
+		Bucket:     aws.String(s.bucket),
+		Key:        aws.String(key),
+		Body:       file,
+		ContentMD5: aws.String(base64.StdEncoding.EncodeToString(contentMD5.Sum(nil))),
+		ACL:        s.objectACL(),
+	}
+	if s.sseCustomerKey != "" {
+		// Customer managed Server Side Encryption
+		putObjectInput.SSECustomerAlgorithm = aws.String(s.sseCustomerAlgorithm)
+		putObjectInput.SSECustomerKey = aws.String(s.sseCustomerKey)
+		putObjectInput.SSECustomerKeyMD5 = aws.String(s.sseCustomerKeyMD5)
+	}
+	logrus.Infof("Uploading snapshot of size: %d with a single put, below the multipart threshold of %d", size, s.MultipartThreshold)
+	if _, err := s.client.PutObjectWithContext(ctx, putObjectInput); err != nil {
+		return fmt.Errorf("failed uploading snapshot with a single put: %v", err)
+	}
+	return nil
+}
+
 func (s *S3SnapStore) uploadPart(snap *brtypes.Snapshot, file *os.File, uploadID *string, completedParts []*s3.CompletedPart, offset, chunkSize int64) error {
 	fileInfo, err := file.Stat()
 	if err != nil {
@@ -464,12 +624,19 @@ func (s *S3SnapStore) uploadPart(snap *brtypes.Snapshot, file *os.File, uploadID
 	defer cancel()
 	partNumber := ((offset / chunkSize) + 1)
 
+	// the Content-MD5 header lets S3 reject the part if it was corrupted in transit.
+	contentMD5 := md5.New()
+	if err := computeChecksum(sr, contentMD5); err != nil {
+		return err
+	}
+
 	uploadPartInput := &s3.UploadPartInput{
 		Bucket:     aws.String(s.bucket),
 		Key:        aws.String(path.Join(adaptPrefix(snap, s.prefix), snap.SnapDir, snap.SnapName)),
 		PartNumber: &partNumber,
 		UploadId:   uploadID,
 		Body:       sr,
+		ContentMD5: aws.String(base64.StdEncoding.EncodeToString(contentMD5.Sum(nil))),
 	}
 
 	if s.sseCustomerKey != "" {
@@ -523,18 +690,7 @@ func (s *S3SnapStore) List() (brtypes.SnapList, error) {
 		Prefix: aws.String(prefix),
 	}
 	err := s.client.ListObjectsPages(in, func(page *s3.ListObjectsOutput, lastPage bool) bool {
-		for _, key := range page.Contents {
-			k := (*key.Key)[len(*page.Prefix):]
-			if strings.Contains(k, backupVersionV1) || strings.Contains(k, backupVersionV2) {
-				snap, err := ParseSnapshot(path.Join(prefix, k))
-				if err != nil {
-					// Warning
-					logrus.Warnf("Invalid snapshot found. Ignoring it: %s", k)
-				} else {
-					snapList = append(snapList, snap)
-				}
-			}
-		}
+		snapList = append(snapList, s.parseSnapshotsFromObjects(prefix, page)...)
 		return !lastPage
 	})
 	if err != nil {
@@ -545,15 +701,712 @@ func (s *S3SnapStore) List() (brtypes.SnapList, error) {
 	return snapList, nil
 }
 
-// Delete should delete the snapshot file from store
+// ListPaged will return sorted list with at most limit snapshot files on store, which come after the given marker
+// in the lexicographical order of the object keys. The returned marker is empty after the last page.
+func (s *S3SnapStore) ListPaged(marker string, limit int) (brtypes.SnapList, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("limit should be greater than zero")
+	}
+	prefixTokens := strings.Split(s.prefix, "/")
+	// Last element of the tokens is backup version
+	// Consider the parent of the backup version level (Required for Backward Compatibility)
+	prefix := path.Join(strings.Join(prefixTokens[:len(prefixTokens)-1], "/"))
+
+	in := &s3.ListObjectsInput{
+		Bucket:  aws.String(s.bucket),
+		Prefix:  aws.String(prefix),
+		MaxKeys: aws.Int64(int64(limit)),
+	}
+	if marker != "" {
+		in.Marker = aws.String(marker)
+	}
+	page, err := s.client.ListObjects(in)
+	if err != nil {
+		return nil, "", err
+	}
+
+	snapList := s.parseSnapshotsFromObjects(prefix, page)
+	sort.Sort(snapList)
+
+	var nextMarker string
+	if aws.BoolValue(page.IsTruncated) && len(page.Contents) > 0 {
+		nextMarker = aws.StringValue(page.Contents[len(page.Contents)-1].Key)
+	}
+	return snapList, nextMarker, nil
+}
+
+// ListPrefix will return sorted list with all snapshot files stored under the given prefix.
+func (s *S3SnapStore) ListPrefix(prefix string) (brtypes.SnapList, error) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	snapList := brtypes.SnapList{}
+	in := &s3.ListObjectsInput{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix + "/"),
+	}
+	err := s.client.ListObjectsPages(in, func(page *s3.ListObjectsOutput, lastPage bool) bool {
+		snapList = append(snapList, s.parseSnapshotsFromObjects(prefix, page)...)
+		return !lastPage
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Sort(snapList)
+	return snapList, nil
+}
+
+// CopyToPrefix copies the snapshot to the given prefix with a server-side copy, which preserves the metadata and
+// the tags of the snapshot object.
+func (s *S3SnapStore) CopyToPrefix(snap brtypes.Snapshot, prefix string) error {
+	if err := s.CopyObject(path.Join(snap.Prefix, snap.SnapDir, snap.SnapName), path.Join(prefix, snap.SnapDir, snap.SnapName)); err != nil {
+		return fmt.Errorf("error while copying %s to prefix %s: %v", path.Join(snap.Prefix, snap.SnapDir, snap.SnapName), prefix, err)
+	}
+	return nil
+}
+
+// ListPrefixObjects returns the keys of the objects stored alongside the snapshot objects under the given prefix.
+func (s *S3SnapStore) ListPrefixObjects(prefix string) ([]string, error) {
+	var keys []string
+	in := &s3.ListObjectsInput{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(strings.TrimSuffix(prefix, "/") + "/"),
+	}
+	err := s.client.ListObjectsPages(in, func(page *s3.ListObjectsOutput, lastPage bool) bool {
+		for _, object := range page.Contents {
+			if isObjectAlongsideSnapshots(*object.Key) {
+				keys = append(keys, *object.Key)
+			}
+		}
+		return !lastPage
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// CopyObject copies the object with the given key to the other given key with a server-side copy, which preserves
+// the metadata and the tags of the object. As a single server-side copy is limited by S3 to objects of up to 5 GiB,
+// larger objects are copied part by part with a multipart upload.
+func (s *S3SnapStore) CopyObject(srcKey, dstKey string) error {
+	headObjectInput := &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(srcKey),
+	}
+	if s.sseCustomerKey != "" {
+		// Customer managed Server Side Encryption
+		headObjectInput.SSECustomerAlgorithm = aws.String(s.sseCustomerAlgorithm)
+		headObjectInput.SSECustomerKey = aws.String(s.sseCustomerKey)
+		headObjectInput.SSECustomerKeyMD5 = aws.String(s.sseCustomerKeyMD5)
+	}
+	headObjectOutput, err := s.client.HeadObject(headObjectInput)
+	if err != nil {
+		return err
+	}
+	if size := aws.Int64Value(headObjectOutput.ContentLength); size > maxS3SingleCopyObjectSize {
+		return s.copyObjectMultipart(srcKey, dstKey, size, headObjectOutput.Metadata)
+	}
+
+	copyObjectInput := &s3.CopyObjectInput{
+		Bucket:            aws.String(s.bucket),
+		CopySource:        aws.String(url.PathEscape(path.Join(s.bucket, srcKey))),
+		Key:               aws.String(dstKey),
+		MetadataDirective: aws.String(s3.MetadataDirectiveCopy),
+		TaggingDirective:  aws.String(s3.TaggingDirectiveCopy),
+		ACL:               s.objectACL(),
+	}
+	if s.sseCustomerKey != "" {
+		// Customer managed Server Side Encryption, with which both the source and the copy are encrypted
+		copyObjectInput.CopySourceSSECustomerAlgorithm = aws.String(s.sseCustomerAlgorithm)
+		copyObjectInput.CopySourceSSECustomerKey = aws.String(s.sseCustomerKey)
+		copyObjectInput.CopySourceSSECustomerKeyMD5 = aws.String(s.sseCustomerKeyMD5)
+		copyObjectInput.SSECustomerAlgorithm = aws.String(s.sseCustomerAlgorithm)
+		copyObjectInput.SSECustomerKey = aws.String(s.sseCustomerKey)
+		copyObjectInput.SSECustomerKeyMD5 = aws.String(s.sseCustomerKeyMD5)
+	}
+	_, err = s.client.CopyObject(copyObjectInput)
+	return err
+}
+
+// copyObjectMultipart copies the object of the given size with the given key to the other given key with a multipart
+// upload, whose parts are copied server-side from the ranges of the object. The metadata and the tags of the object,
+// which a multipart upload doesn't copy, are set on the multipart upload. The multipart upload is aborted if the copy
+// of a part fails.
+func (s *S3SnapStore) copyObjectMultipart(srcKey, dstKey string, size int64, metadata map[string]*string) error {
+	taggingOutput, err := s.client.GetObjectTagging(&s3.GetObjectTaggingInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(srcKey),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get the tags of %s: %v", srcKey, err)
+	}
+	tags := url.Values{}
+	for _, tag := range taggingOutput.TagSet {
+		tags.Set(aws.StringValue(tag.Key), aws.StringValue(tag.Value))
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), chunkUploadTimeout)
+	defer cancel()
+	createMultipartUploadInput := &s3.CreateMultipartUploadInput{
+		Bucket:   aws.String(s.bucket),
+		Key:      aws.String(dstKey),
+		ACL:      s.objectACL(),
+		Metadata: metadata,
+		Tagging:  aws.String(tags.Encode()),
+	}
+	if s.sseCustomerKey != "" {
+		// Customer managed Server Side Encryption
+		createMultipartUploadInput.SSECustomerAlgorithm = aws.String(s.sseCustomerAlgorithm)
+		createMultipartUploadInput.SSECustomerKey = aws.String(s.sseCustomerKey)
+		createMultipartUploadInput.SSECustomerKeyMD5 = aws.String(s.sseCustomerKeyMD5)
+	}
+	uploadOutput, err := s.client.CreateMultipartUploadWithContext(ctx, createMultipartUploadInput)
+	if err != nil {
+		return fmt.Errorf("failed to initiate multipart upload %v", err)
+	}
+
+	partSize := int64(math.Max(float64(s.minChunkSize), float64(size/s3NoOfChunk)))
+	logrus.Infof("Copying %s of size: %d to %s with a multipart upload, partSize: %d", srcKey, size, dstKey, partSize)
+	var completedParts []*s3.CompletedPart
+	for offset, partNumber := int64(0), int64(1); offset < size; offset, partNumber = offset+partSize, partNumber+1 {
+		completedPart, err := s.copyPart(srcKey, dstKey, uploadOutput.UploadId, partNumber, offset, int64(math.Min(float64(partSize), float64(size-offset))))
+		if err != nil {
+			ctx, cancel := context.WithTimeout(context.TODO(), chunkUploadTimeout)
+			defer cancel()
+			logrus.Infof("Aborting the multipart upload with upload ID : %s", *uploadOutput.UploadId)
+			if _, abortErr := s.client.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+				Bucket:   aws.String(s.bucket),
+				Key:      aws.String(dstKey),
+				UploadId: uploadOutput.UploadId,
+			}); abortErr != nil {
+				logrus.Warnf("Failed to abort the multipart upload with upload ID %s: %v", *uploadOutput.UploadId, abortErr)
+			}
+			return fmt.Errorf("failed copying part %d at offset %d: %v", partNumber, offset, err)
+		}
+		completedParts = append(completedParts, completedPart)
+	}
+
+	ctx, cancel = context.WithTimeout(context.TODO(), 30*time.Second)
+	defer cancel()
+	_, err = s.client.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucket),
+		Key:             aws.String(dstKey),
+		UploadId:        uploadOutput.UploadId,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: completedParts},
+	})
+	if err != nil {
+		return fmt.Errorf("failed completing the multipart copy with error %v", err)
+	}
+	return nil
+}
+
+// copyPart copies the given range of the object with the given key server-side as the part with the given number of
+// the multipart upload with the given ID.
+func (s *S3SnapStore) copyPart(srcKey, dstKey string, uploadID *string, partNumber, offset, partSize int64) (*s3.CompletedPart, error) {
+	ctx, cancel := context.WithTimeout(context.TODO(), chunkUploadTimeout)
+	defer cancel()
+	uploadPartCopyInput := &s3.UploadPartCopyInput{
+		Bucket:          aws.String(s.bucket),
+		CopySource:      aws.String(url.PathEscape(path.Join(s.bucket, srcKey))),
+		CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+partSize-1)),
+		Key:             aws.String(dstKey),
+		PartNumber:      aws.Int64(partNumber),
+		UploadId:        uploadID,
+	}
+	if s.sseCustomerKey != "" {
+		// Customer managed Server Side Encryption, with which both the source and the copy are encrypted
+		uploadPartCopyInput.CopySourceSSECustomerAlgorithm = aws.String(s.sseCustomerAlgorithm)
+		uploadPartCopyInput.CopySourceSSECustomerKey = aws.String(s.sseCustomerKey)
+		uploadPartCopyInput.CopySourceSSECustomerKeyMD5 = aws.String(s.sseCustomerKeyMD5)
+		uploadPartCopyInput.SSECustomerAlgorithm = aws.String(s.sseCustomerAlgorithm)
+		uploadPartCopyInput.SSECustomerKey = aws.String(s.sseCustomerKey)
+		uploadPartCopyInput.SSECustomerKeyMD5 = aws.String(s.sseCustomerKeyMD5)
+	}
+	out, err := s.client.UploadPartCopyWithContext(ctx, uploadPartCopyInput)
+	if err != nil {
+		return nil, err
+	}
+	return &s3.CompletedPart{ETag: out.CopyPartResult.ETag, PartNumber: aws.Int64(partNumber)}, nil
+}
+
+// DeleteObject deletes the object with the given key.
+func (s *S3SnapStore) DeleteObject(key string) error {
+	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+// parseSnapshotsFromObjects returns the snapshots among the objects of the given page of a listing.
+func (s *S3SnapStore) parseSnapshotsFromObjects(prefix string, page *s3.ListObjectsOutput) brtypes.SnapList {
+	snapList := brtypes.SnapList{}
+	for _, key := range page.Contents {
+		k := (*key.Key)[len(*page.Prefix):]
+		if isObjectAlongsideSnapshots(k) {
+			continue
+		}
+		if strings.Contains(k, backupVersionV1) || strings.Contains(k, backupVersionV2) {
+			snap, err := s.parseSnapshot(path.Join(prefix, k))
+			if err != nil {
+				// Warning
+				logrus.Warnf("Invalid snapshot found. Ignoring it: %s", k)
+			} else {
+				snap.SizeBytes = aws.Int64Value(key.Size)
+				snapList = append(snapList, snap)
+			}
+		}
+	}
+	return snapList
+}
+
+// Delete should delete the snapshot file from store, along with the cluster metadata of a full snapshot
 func (s *S3SnapStore) Delete(snap brtypes.Snapshot) error {
+	if err := s.checkObjectUnlocked(path.Join(snap.Prefix, snap.SnapDir, snap.SnapName), "deleted"); err != nil {
+		return err
+	}
 	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(path.Join(snap.Prefix, snap.SnapDir, snap.SnapName)),
 	})
+	if err != nil || snap.Kind != brtypes.SnapshotKindFull {
+		return err
+	}
+	if err := s.checkObjectUnlocked(clusterMetadataPath(snap, s.prefix), "deleted"); err != nil {
+		return err
+	}
+	// deleting an object which doesn't exist succeeds
+	_, err = s.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(clusterMetadataPath(snap, s.prefix)),
+	})
+	return err
+}
+
+// Size should return size of the snapshot object from store
+func (s *S3SnapStore) Size(snap brtypes.Snapshot) (int64, error) {
+	headObjectInput := &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path.Join(snap.Prefix, snap.SnapDir, snap.SnapName)),
+	}
+	if s.sseCustomerKey != "" {
+		// Customer managed Server Side Encryption
+		headObjectInput.SSECustomerAlgorithm = aws.String(s.sseCustomerAlgorithm)
+		headObjectInput.SSECustomerKey = aws.String(s.sseCustomerKey)
+		headObjectInput.SSECustomerKeyMD5 = aws.String(s.sseCustomerKeyMD5)
+	}
+	headObjectOutput, err := s.client.HeadObject(headObjectInput)
+	if err != nil {
+		return -1, fmt.Errorf("error while accessing %s: %v", path.Join(snap.Prefix, snap.SnapDir, snap.SnapName), err)
+	}
+	return aws.Int64Value(headObjectOutput.ContentLength), nil
+}
+
+// Exists returns whether the snapshot object is present in the store, with a single HEAD request.
+func (s *S3SnapStore) Exists(snap brtypes.Snapshot) (bool, error) {
+	key := path.Join(adaptPrefix(&snap, s.prefix), snap.SnapDir, snap.SnapName)
+	headObjectInput := &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+	if s.sseCustomerKey != "" {
+		// Customer managed Server Side Encryption
+		headObjectInput.SSECustomerAlgorithm = aws.String(s.sseCustomerAlgorithm)
+		headObjectInput.SSECustomerKey = aws.String(s.sseCustomerKey)
+		headObjectInput.SSECustomerKeyMD5 = aws.String(s.sseCustomerKeyMD5)
+	}
+	if _, err := s.client.HeadObject(headObjectInput); err != nil {
+		// the response to a HEAD request has no body, hence no S3 error code
+		if aerr, ok := err.(awserr.Error); ok && (aerr.Code() == "NotFound" || aerr.Code() == s3.ErrCodeNoSuchKey) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error while accessing %s: %v", key, err)
+	}
+	return true, nil
+}
+
+// SaveChainManifest replaces the chain manifest object under the prefix, which S3 does atomically.
+func (s *S3SnapStore) SaveChainManifest(manifest *brtypes.ChainManifest) error {
+	completeChainManifest(manifest, s.prefix, s.Size)
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to marshal chain manifest: %v", err)
+	}
+	putObjectInput := &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path.Join(s.prefix, brtypes.ChainManifestName)),
+		Body:   bytes.NewReader(data),
+		ACL:    s.objectACL(),
+	}
+	if s.sseCustomerKey != "" {
+		// Customer managed Server Side Encryption
+		putObjectInput.SSECustomerAlgorithm = aws.String(s.sseCustomerAlgorithm)
+		putObjectInput.SSECustomerKey = aws.String(s.sseCustomerKey)
+		putObjectInput.SSECustomerKeyMD5 = aws.String(s.sseCustomerKeyMD5)
+	}
+	if _, err := s.client.PutObject(putObjectInput); err != nil {
+		return fmt.Errorf("error while saving chain manifest: %v", err)
+	}
+	return nil
+}
+
+// FetchChainManifest returns the chain manifest object under the prefix, or nil if there is none.
+func (s *S3SnapStore) FetchChainManifest() (*brtypes.ChainManifest, error) {
+	getObjectInput := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path.Join(s.prefix, brtypes.ChainManifestName)),
+	}
+	if s.sseCustomerKey != "" {
+		// Customer managed Server Side Encryption
+		getObjectInput.SSECustomerAlgorithm = aws.String(s.sseCustomerAlgorithm)
+		getObjectInput.SSECustomerKey = aws.String(s.sseCustomerKey)
+		getObjectInput.SSECustomerKeyMD5 = aws.String(s.sseCustomerKeyMD5)
+	}
+	getObjectOutput, err := s.client.GetObject(getObjectInput)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error while fetching chain manifest: %v", err)
+	}
+	defer getObjectOutput.Body.Close()
+	manifest := &brtypes.ChainManifest{}
+	if err := json.NewDecoder(getObjectOutput.Body).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal chain manifest: %v", err)
+	}
+	return manifest, nil
+}
+
+// DeleteChainManifest deletes the chain manifest object under the prefix, if any.
+func (s *S3SnapStore) DeleteChainManifest() error {
+	// deleting an object which doesn't exist succeeds
+	if _, err := s.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path.Join(s.prefix, brtypes.ChainManifestName)),
+	}); err != nil {
+		return fmt.Errorf("error while deleting chain manifest: %v", err)
+	}
+	return nil
+}
+
+// SaveClusterMetadata saves the cluster metadata object of the full snapshot next to the snapshot object.
+func (s *S3SnapStore) SaveClusterMetadata(snap brtypes.Snapshot, metadata *brtypes.ClusterMetadata) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cluster metadata: %v", err)
+	}
+	putObjectInput := &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(clusterMetadataPath(snap, s.prefix)),
+		Body:   bytes.NewReader(data),
+		ACL:    s.objectACL(),
+	}
+	if s.sseCustomerKey != "" {
+		// Customer managed Server Side Encryption
+		putObjectInput.SSECustomerAlgorithm = aws.String(s.sseCustomerAlgorithm)
+		putObjectInput.SSECustomerKey = aws.String(s.sseCustomerKey)
+		putObjectInput.SSECustomerKeyMD5 = aws.String(s.sseCustomerKeyMD5)
+	}
+	if _, err := s.client.PutObject(putObjectInput); err != nil {
+		return fmt.Errorf("error while saving cluster metadata: %v", err)
+	}
+	return nil
+}
+
+// FetchClusterMetadata returns the cluster metadata object of the full snapshot, or nil if there is none.
+func (s *S3SnapStore) FetchClusterMetadata(snap brtypes.Snapshot) (*brtypes.ClusterMetadata, error) {
+	getObjectInput := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(clusterMetadataPath(snap, s.prefix)),
+	}
+	if s.sseCustomerKey != "" {
+		// Customer managed Server Side Encryption
+		getObjectInput.SSECustomerAlgorithm = aws.String(s.sseCustomerAlgorithm)
+		getObjectInput.SSECustomerKey = aws.String(s.sseCustomerKey)
+		getObjectInput.SSECustomerKeyMD5 = aws.String(s.sseCustomerKeyMD5)
+	}
+	getObjectOutput, err := s.client.GetObject(getObjectInput)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error while fetching cluster metadata: %v", err)
+	}
+	defer getObjectOutput.Body.Close()
+	metadata := &brtypes.ClusterMetadata{}
+	if err := json.NewDecoder(getObjectOutput.Body).Decode(metadata); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cluster metadata: %v", err)
+	}
+	return metadata, nil
+}
+
+// CreateRestoreLock creates the restore lock object under the prefix with a conditional write, which fails if the
+// restore lock object exists.
+func (s *S3SnapStore) CreateRestoreLock(lock *brtypes.RestoreLock) error {
+	// the vendored SDK doesn't model conditional writes yet, so the precondition header is set on the request directly
+	return s.putRestoreLock(lock, withHeader("If-None-Match", "*"))
+}
+
+// ReplaceRestoreLock replaces the restore lock object under the prefix with a conditional write, which fails if the
+// restore lock object changed since the given restore lock was fetched, i.e. if its ETag differs or it was deleted.
+func (s *S3SnapStore) ReplaceRestoreLock(current, lock *brtypes.RestoreLock) error {
+	return s.putRestoreLock(lock, withHeader("If-Match", current.Version))
+}
+
+// putRestoreLock writes the restore lock object under the prefix with the given precondition, and returns an error
+// wrapping ErrRestoreLockExists if the precondition doesn't hold.
+func (s *S3SnapStore) putRestoreLock(lock *brtypes.RestoreLock, precondition request.Option) error {
+	data, err := json.Marshal(lock)
+	if err != nil {
+		return fmt.Errorf("failed to marshal restore lock: %v", err)
+	}
+	putObjectInput := &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path.Join(s.prefix, brtypes.RestoreLockName)),
+		Body:   bytes.NewReader(data),
+		ACL:    s.objectACL(),
+	}
+	if s.sseCustomerKey != "" {
+		// Customer managed Server Side Encryption
+		putObjectInput.SSECustomerAlgorithm = aws.String(s.sseCustomerAlgorithm)
+		putObjectInput.SSECustomerKey = aws.String(s.sseCustomerKey)
+		putObjectInput.SSECustomerKeyMD5 = aws.String(s.sseCustomerKeyMD5)
+	}
+	if _, err := s.client.PutObjectWithContext(context.TODO(), putObjectInput, precondition); err != nil {
+		if isPreconditionError(err) {
+			return fmt.Errorf("%w: %v", ErrRestoreLockExists, err)
+		}
+		return fmt.Errorf("error while writing restore lock: %v", err)
+	}
+	return nil
+}
+
+// FetchRestoreLock returns the restore lock object under the prefix along with its ETag, or nil if there is none.
+func (s *S3SnapStore) FetchRestoreLock() (*brtypes.RestoreLock, error) {
+	getObjectInput := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path.Join(s.prefix, brtypes.RestoreLockName)),
+	}
+	if s.sseCustomerKey != "" {
+		// Customer managed Server Side Encryption
+		getObjectInput.SSECustomerAlgorithm = aws.String(s.sseCustomerAlgorithm)
+		getObjectInput.SSECustomerKey = aws.String(s.sseCustomerKey)
+		getObjectInput.SSECustomerKeyMD5 = aws.String(s.sseCustomerKeyMD5)
+	}
+	getObjectOutput, err := s.client.GetObject(getObjectInput)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error while fetching restore lock: %v", err)
+	}
+	defer getObjectOutput.Body.Close()
+	lock := &brtypes.RestoreLock{}
+	if err := json.NewDecoder(getObjectOutput.Body).Decode(lock); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal restore lock: %v", err)
+	}
+	lock.Version = aws.StringValue(getObjectOutput.ETag)
+	return lock, nil
+}
+
+// DeleteRestoreLock deletes the restore lock object under the prefix if it is held by the given holder, with a
+// conditional delete on the ETag it was fetched with, so that a restore lock taken over in the meantime is kept.
+func (s *S3SnapStore) DeleteRestoreLock(holder string) error {
+	lock, err := s.FetchRestoreLock()
+	if err != nil || lock == nil || lock.Holder != holder {
+		return err
+	}
+	if _, err := s.client.DeleteObjectWithContext(context.TODO(), &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path.Join(s.prefix, brtypes.RestoreLockName)),
+	}, withHeader("If-Match", lock.Version)); err != nil {
+		if isPreconditionError(err) {
+			logrus.Warnf("Restore lock of %s was taken over before it was released: %v", holder, err)
+			return nil
+		}
+		return fmt.Errorf("error while deleting restore lock: %v", err)
+	}
+	return nil
+}
+
+// withHeader returns a request option setting the given header on the request, such as a precondition header, which
+// the vendored SDK doesn't model yet.
+func withHeader(key, value string) request.Option {
+	return func(r *request.Request) {
+		r.HTTPRequest.Header.Set(key, value)
+	}
+}
+
+// isPreconditionError returns whether the given error is returned for a conditional write or delete of an object
+// whose precondition doesn't hold, as the object exists, changed or was deleted, or is written concurrently.
+func isPreconditionError(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && (aerr.Code() == errCodePreconditionFailed || aerr.Code() == errCodeConditionalRequestConflict || aerr.Code() == s3.ErrCodeNoSuchKey)
+}
+
+// SaveCompressionDictionary replaces the compression dictionary object with the given identifier under the prefix.
+func (s *S3SnapStore) SaveCompressionDictionary(id uint32, dictionary []byte) error {
+	putObjectInput := &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path.Join(s.prefix, compressionDictionaryName(id))),
+		Body:   bytes.NewReader(dictionary),
+		ACL:    s.objectACL(),
+	}
+	if s.sseCustomerKey != "" {
+		// Customer managed Server Side Encryption
+		putObjectInput.SSECustomerAlgorithm = aws.String(s.sseCustomerAlgorithm)
+		putObjectInput.SSECustomerKey = aws.String(s.sseCustomerKey)
+		putObjectInput.SSECustomerKeyMD5 = aws.String(s.sseCustomerKeyMD5)
+	}
+	if _, err := s.client.PutObject(putObjectInput); err != nil {
+		return fmt.Errorf("error while saving compression dictionary %08x: %v", id, err)
+	}
+	return nil
+}
+
+// FetchCompressionDictionary returns the compression dictionary object with the given identifier under the prefix.
+func (s *S3SnapStore) FetchCompressionDictionary(id uint32) ([]byte, error) {
+	getObjectInput := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path.Join(s.prefix, compressionDictionaryName(id))),
+	}
+	if s.sseCustomerKey != "" {
+		// Customer managed Server Side Encryption
+		getObjectInput.SSECustomerAlgorithm = aws.String(s.sseCustomerAlgorithm)
+		getObjectInput.SSECustomerKey = aws.String(s.sseCustomerKey)
+		getObjectInput.SSECustomerKeyMD5 = aws.String(s.sseCustomerKeyMD5)
+	}
+	getObjectOutput, err := s.client.GetObject(getObjectInput)
+	if err != nil {
+		return nil, fmt.Errorf("error while fetching compression dictionary %08x: %v", id, err)
+	}
+	defer getObjectOutput.Body.Close()
+	return io.ReadAll(getObjectOutput.Body)
+}
+
+// ListCompressionDictionaries returns the compression dictionary objects under the prefix, along with the time they
+// were last modified on. An object without a modification time is listed as saved now, so that it isn't deleted.
+func (s *S3SnapStore) ListCompressionDictionaries() ([]brtypes.CompressionDictionary, error) {
+	var dictionaries []brtypes.CompressionDictionary
+	in := &s3.ListObjectsInput{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(path.Join(s.prefix, brtypes.CompressionDictionaryPrefix)),
+	}
+	err := s.client.ListObjectsPages(in, func(page *s3.ListObjectsOutput, lastPage bool) bool {
+		for _, object := range page.Contents {
+			id, ok := parseCompressionDictionaryName(aws.StringValue(object.Key))
+			if !ok {
+				continue
+			}
+			savedOn := time.Now()
+			if object.LastModified != nil {
+				savedOn = *object.LastModified
+			}
+			dictionaries = append(dictionaries, brtypes.CompressionDictionary{ID: id, SavedOn: savedOn})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing compression dictionaries: %v", err)
+	}
+	return dictionaries, nil
+}
+
+// DeleteCompressionDictionary deletes the compression dictionary object with the given identifier under the prefix.
+func (s *S3SnapStore) DeleteCompressionDictionary(id uint32) error {
+	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path.Join(s.prefix, compressionDictionaryName(id))),
+	})
 	return err
 }
 
+// Verify checks that the bucket exists and is accessible, and if write is true, that an object can be written under
+// the prefix, by writing and deleting a small object.
+func (s *S3SnapStore) Verify(write bool) error {
+	if _, err := s.client.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String(s.bucket)}); err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case s3.ErrCodeNoSuchBucket, "NotFound":
+				return newVerificationError(CredentialsFailureBucketMissing, "bucket %s does not exist", s.bucket)
+			case "Forbidden", "AccessDenied", "InvalidAccessKeyId", "SignatureDoesNotMatch", "ExpiredToken", "InvalidToken":
+				return newVerificationError(CredentialsFailureAuth, "access to bucket %s is denied: %w", s.bucket, err)
+			}
+		}
+		return fmt.Errorf("failed to access bucket %s: %w", s.bucket, err)
+	}
+	if !write {
+		return nil
+	}
+
+	key := aws.String(path.Join(s.prefix, verificationObjectName))
+	putObjectInput := &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    key,
+		Body:   bytes.NewReader(nil),
+		ACL:    s.objectACL(),
+	}
+	if s.sseCustomerKey != "" {
+		// Customer managed Server Side Encryption
+		putObjectInput.SSECustomerAlgorithm = aws.String(s.sseCustomerAlgorithm)
+		putObjectInput.SSECustomerKey = aws.String(s.sseCustomerKey)
+		putObjectInput.SSECustomerKeyMD5 = aws.String(s.sseCustomerKeyMD5)
+	}
+	if _, err := s.client.PutObject(putObjectInput); err != nil {
+		return fmt.Errorf("failed to write to bucket %s: %v", s.bucket, err)
+	}
+	if _, err := s.client.DeleteObject(&s3.DeleteObjectInput{Bucket: aws.String(s.bucket), Key: key}); err != nil {
+		return fmt.Errorf("failed to delete from bucket %s: %v", s.bucket, err)
+	}
+	return nil
+}
+
+// SetRetained sets the exclude tag on the snapshot if retained is true, and clears it otherwise.
+// The other tags of the snapshot object are left untouched.
+func (s *S3SnapStore) SetRetained(snap brtypes.Snapshot, retained bool) error {
+	key := aws.String(path.Join(snap.Prefix, snap.SnapDir, snap.SnapName))
+	tagging, err := s.client.GetObjectTagging(&s3.GetObjectTaggingInput{
+		Bucket: aws.String(s.bucket),
+		Key:    key,
+	})
+	if err != nil {
+		return err
+	}
+	var tagSet []*s3.Tag
+	for _, tag := range tagging.TagSet {
+		if aws.StringValue(tag.Key) != brtypes.SnapshotExcludeTag {
+			tagSet = append(tagSet, tag)
+		}
+	}
+	if retained {
+		tagSet = append(tagSet, &s3.Tag{Key: aws.String(brtypes.SnapshotExcludeTag), Value: aws.String("true")})
+	}
+	_, err = s.client.PutObjectTagging(&s3.PutObjectTaggingInput{
+		Bucket:  aws.String(s.bucket),
+		Key:     key,
+		Tagging: &s3.Tagging{TagSet: tagSet},
+	})
+	return err
+}
+
+// IsRetained returns whether the exclude tag is set on the snapshot, or whether the snapshot is still under object
+// lock retention or legal hold if the object lock protection is enabled.
+func (s *S3SnapStore) IsRetained(snap brtypes.Snapshot) (bool, error) {
+	tagging, err := s.client.GetObjectTagging(&s3.GetObjectTaggingInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path.Join(snap.Prefix, snap.SnapDir, snap.SnapName)),
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, tag := range tagging.TagSet {
+		if aws.StringValue(tag.Key) == brtypes.SnapshotExcludeTag {
+			return true, nil
+		}
+	}
+	return s.isObjectLocked(path.Join(snap.Prefix, snap.SnapDir, snap.SnapName))
+}
+
 // GetS3CredentialsLastModifiedTime returns the latest modification timestamp of the AWS credential file(s)
 func GetS3CredentialsLastModifiedTime() (time.Time, error) {
 	// TODO: @renormalize Remove this extra handling in v0.31.0
@@ -598,6 +1451,10 @@ func isAWSConfigEmpty(config *awsCredentials) error {
 	if len(config.AccessKeyID) != 0 && len(config.Region) != 0 && len(config.SecretAccessKey) != 0 {
 		return nil
 	}
+	// the role can also be assumed with the credentials of the default credential chain
+	if config.RoleARN != nil && len(config.Region) != 0 && len(config.AccessKeyID) == 0 && len(config.SecretAccessKey) == 0 {
+		return nil
+	}
 	return fmt.Errorf("aws s3 credentials: region, secretAccessKey or accessKeyID is missing")
 }
 
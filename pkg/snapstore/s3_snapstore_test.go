@@ -6,14 +6,20 @@ package snapstore_test
 
 import (
 	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
@@ -26,22 +32,117 @@ type mockS3Client struct {
 	prefix                string
 	multiPartUploads      map[string]*[][]byte
 	multiPartUploadsMutex sync.Mutex
+	tags                  map[string][]*s3.Tag
+	metadata              map[string]map[string]*string
+	// partContentMD5s holds the Content-MD5 header of the uploaded parts by their part number.
+	partContentMD5s map[int64]string
+	// corruptNextPart corrupts the content of the next uploaded part, as if it was corrupted in transit.
+	corruptNextPart bool
+	rejectedParts   int
+	// throttledListPages fails the listing with a throttling error after each of the next list pages, and each of the
+	// next listings of single pages.
+	throttledListPages int
+	// listObjectsMarkers records the markers of the listings of single pages.
+	listObjectsMarkers []string
+	// headBucketErr is returned by the check whether the bucket exists and is accessible.
+	headBucketErr error
+	// listObjectsErr is returned by the listings of single pages of objects.
+	listObjectsErr error
+	// putObjectErr is returned by the writes of single objects.
+	putObjectErr error
+	// createdMultipartUploads is the number of multipart uploads initiated.
+	createdMultipartUploads int
+	// copiedParts is the number of parts copied server-side to multipart uploads.
+	copiedParts int
+	// objectACLs holds the canned ACL set on the written objects by their key.
+	objectACLs map[string]string
+	// objectLockEnabled enables object lock on the bucket.
+	objectLockEnabled bool
+	// retainUntil holds the end of the object lock retention period of the objects by their key.
+	retainUntil map[string]time.Time
+	// legalHolds holds the objects under a legal hold by their key.
+	legalHolds map[string]bool
+	// lockedObjectWrites holds the keys of the locked objects which were attempted to be overwritten or deleted.
+	lockedObjectWrites []string
+}
+
+// isLocked checks whether the object is under object lock retention or legal hold for mock test
+func (m *mockS3Client) isLocked(key string) bool {
+	return m.objectLockEnabled && m.objects[key] != nil && (m.retainUntil[key].After(time.Now()) || m.legalHolds[key])
+}
+
+// rejectLockedObjectWrite records and rejects the attempt to overwrite or delete a locked object for mock test, as S3 does
+func (m *mockS3Client) rejectLockedObjectWrite(key string) error {
+	if !m.isLocked(key) {
+		return nil
+	}
+	m.lockedObjectWrites = append(m.lockedObjectWrites, key)
+	return awserr.New("AccessDenied", "object is protected by object lock", nil)
+}
+
+// GetObjectLockConfiguration returns whether object lock is enabled on the bucket for mock test
+func (m *mockS3Client) GetObjectLockConfiguration(*s3.GetObjectLockConfigurationInput) (*s3.GetObjectLockConfigurationOutput, error) {
+	if !m.objectLockEnabled {
+		return nil, awserr.New("ObjectLockConfigurationNotFoundError", "object lock configuration does not exist for this bucket", nil)
+	}
+	return &s3.GetObjectLockConfigurationOutput{
+		ObjectLockConfiguration: &s3.ObjectLockConfiguration{ObjectLockEnabled: aws.String(s3.ObjectLockEnabledEnabled)},
+	}, nil
+}
+
+// recordObjectACL records the canned ACL set on the written object for mock test
+func (m *mockS3Client) recordObjectACL(key string, acl *string) {
+	if m.objectACLs == nil {
+		m.objectACLs = map[string]string{}
+	}
+	m.objectACLs[key] = aws.StringValue(acl)
+}
+
+// HeadBucket returns the configured error for mock test
+func (m *mockS3Client) HeadBucket(*s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+	if m.headBucketErr != nil {
+		return nil, m.headBucketErr
+	}
+	return &s3.HeadBucketOutput{}, nil
 }
 
 // GetObject returns the object from map for mock test
 func (m *mockS3Client) GetObject(in *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
 	if m.objects[*in.Key] == nil {
-		return nil, fmt.Errorf("object not found")
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "object not found", nil)
 	}
 	// Only need to return mocked response output
 	out := s3.GetObjectOutput{
 		Body: io.NopCloser(bytes.NewReader(*m.objects[*in.Key])),
+		ETag: aws.String(m.eTag(*in.Key)),
 	}
 	return &out, nil
 }
 
+// HeadObject returns the size and the object lock retention of the object from map for mock test
+func (m *mockS3Client) HeadObject(in *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	if m.objects[*in.Key] == nil {
+		return nil, awserr.New("NotFound", "object not found", nil)
+	}
+	out := &s3.HeadObjectOutput{ContentLength: aws.Int64(int64(len(*m.objects[*in.Key]))), Metadata: m.metadata[*in.Key]}
+	if retainUntil, ok := m.retainUntil[*in.Key]; ok {
+		out.ObjectLockMode = aws.String(s3.ObjectLockModeCompliance)
+		out.ObjectLockRetainUntilDate = aws.Time(retainUntil)
+	}
+	if m.legalHolds[*in.Key] {
+		out.ObjectLockLegalHoldStatus = aws.String(s3.ObjectLockLegalHoldStatusOn)
+	}
+	return out, nil
+}
+
 // PutObject adds the object to the map for mock test
 func (m *mockS3Client) PutObject(in *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	if m.putObjectErr != nil {
+		return nil, m.putObjectErr
+	}
+	if err := m.rejectLockedObjectWrite(*in.Key); err != nil {
+		return nil, err
+	}
 	size, err := in.Body.Seek(0, io.SeekEnd)
 	if err != nil {
 		return nil, fmt.Errorf("failed to seek at the end of body %v", err)
@@ -50,18 +151,82 @@ func (m *mockS3Client) PutObject(in *s3.PutObjectInput) (*s3.PutObjectOutput, er
 		return nil, fmt.Errorf("failed to seek at the start of body %v", err)
 	}
 	content := make([]byte, size)
-	if _, err := in.Body.Read(content); err != nil {
+	if _, err := io.ReadFull(in.Body, content); err != nil {
 		return nil, fmt.Errorf("failed to read complete body %v", err)
 	}
+	if in.ContentMD5 != nil {
+		if sum := md5.Sum(content); *in.ContentMD5 != base64.StdEncoding.EncodeToString(sum[:]) {
+			return nil, fmt.Errorf("BadDigest: the Content-MD5 you specified did not match what was received")
+		}
+	}
 	m.objects[*in.Key] = &content
+	m.recordObjectACL(*in.Key, in.ACL)
 	out := s3.PutObjectOutput{}
 	return &out, nil
 }
 
+// PutObjectWithContext adds the object to the map for mock test, unless the preconditions of the request on the
+// object don't hold
+func (m *mockS3Client) PutObjectWithContext(ctx aws.Context, in *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+	if err := m.checkPreconditions(*in.Key, opts...); err != nil {
+		return nil, err
+	}
+	return m.PutObject(in)
+}
+
+// DeleteObjectWithContext deletes the object from map for mock test, unless the preconditions of the request on the
+// object don't hold
+func (m *mockS3Client) DeleteObjectWithContext(ctx aws.Context, in *s3.DeleteObjectInput, opts ...request.Option) (*s3.DeleteObjectOutput, error) {
+	if err := m.checkPreconditions(*in.Key, opts...); err != nil {
+		return nil, err
+	}
+	return m.DeleteObject(in)
+}
+
+// checkPreconditions checks the If-None-Match and If-Match headers set on the request by the given options against
+// the object for mock test, as S3 does
+func (m *mockS3Client) checkPreconditions(key string, opts ...request.Option) error {
+	r := &request.Request{HTTPRequest: &http.Request{Header: http.Header{}}}
+	r.ApplyOptions(opts...)
+	if r.HTTPRequest.Header.Get("If-None-Match") == "*" && m.objects[key] != nil {
+		return awserr.New("PreconditionFailed", "at least one of the pre-conditions you specified did not hold", nil)
+	}
+	if eTag := r.HTTPRequest.Header.Get("If-Match"); eTag != "" {
+		if m.objects[key] == nil {
+			return awserr.New(s3.ErrCodeNoSuchKey, "object not found", nil)
+		}
+		if eTag != m.eTag(key) {
+			return awserr.New("PreconditionFailed", "at least one of the pre-conditions you specified did not hold", nil)
+		}
+	}
+	return nil
+}
+
+// eTag returns the ETag of the object in map for mock test, which is the MD5 checksum of its content as for S3
+func (m *mockS3Client) eTag(key string) string {
+	sum := md5.Sum(*m.objects[key])
+	return fmt.Sprintf("%q", hex.EncodeToString(sum[:]))
+}
+
 func (m *mockS3Client) CreateMultipartUploadWithContext(ctx aws.Context, in *s3.CreateMultipartUploadInput, opts ...request.Option) (*s3.CreateMultipartUploadOutput, error) {
+	if err := m.rejectLockedObjectWrite(*in.Key); err != nil {
+		return nil, err
+	}
 	uploadID := time.Now().String()
 	var parts [][]byte
 	m.multiPartUploads[uploadID] = &parts
+	m.createdMultipartUploads++
+	m.recordObjectACL(*in.Key, in.ACL)
+	if in.Metadata != nil {
+		m.metadata[*in.Key] = in.Metadata
+	}
+	if tagging, err := url.ParseQuery(aws.StringValue(in.Tagging)); err == nil && len(tagging) > 0 {
+		var tags []*s3.Tag
+		for key := range tagging {
+			tags = append(tags, &s3.Tag{Key: aws.String(key), Value: aws.String(tagging.Get(key))})
+		}
+		m.tags[*in.Key] = tags
+	}
 	out := &s3.CreateMultipartUploadOutput{
 		Bucket:   in.Bucket,
 		UploadId: &uploadID,
@@ -99,6 +264,22 @@ func (m *mockS3Client) UploadPartWithContext(ctx aws.Context, in *s3.UploadPartI
 	}
 
 	m.multiPartUploadsMutex.Lock()
+	if m.corruptNextPart {
+		m.corruptNextPart = false
+		content[0] ^= 0xff
+	}
+	if in.ContentMD5 != nil {
+		sum := md5.Sum(content)
+		if *in.ContentMD5 != base64.StdEncoding.EncodeToString(sum[:]) {
+			m.rejectedParts++
+			m.multiPartUploadsMutex.Unlock()
+			return nil, fmt.Errorf("BadDigest: the Content-MD5 you specified did not match what was received")
+		}
+		if m.partContentMD5s == nil {
+			m.partContentMD5s = map[int64]string{}
+		}
+		m.partContentMD5s[*in.PartNumber] = *in.ContentMD5
+	}
 	(*m.multiPartUploads[*in.UploadId])[*in.PartNumber-1] = content
 	m.multiPartUploadsMutex.Unlock()
 
@@ -109,6 +290,35 @@ func (m *mockS3Client) UploadPartWithContext(ctx aws.Context, in *s3.UploadPartI
 	return out, nil
 }
 
+// UploadPartCopyWithContext copies the given range of the object in map as a part of the multipart upload for mock test
+func (m *mockS3Client) UploadPartCopyWithContext(ctx aws.Context, in *s3.UploadPartCopyInput, opts ...request.Option) (*s3.UploadPartCopyOutput, error) {
+	source, err := url.PathUnescape(*in.CopySource)
+	if err != nil {
+		return nil, err
+	}
+	key := strings.TrimPrefix(source, *in.Bucket+"/")
+	if m.objects[key] == nil {
+		return nil, fmt.Errorf("object not found")
+	}
+	var start, end int
+	if _, err := fmt.Sscanf(*in.CopySourceRange, "bytes=%d-%d", &start, &end); err != nil {
+		return nil, fmt.Errorf("invalid copy source range %s: %v", *in.CopySourceRange, err)
+	}
+	m.multiPartUploadsMutex.Lock()
+	defer m.multiPartUploadsMutex.Unlock()
+	parts := m.multiPartUploads[*in.UploadId]
+	if parts == nil {
+		return nil, fmt.Errorf("multipart upload not initiated")
+	}
+	for int64(len(*parts)) < *in.PartNumber {
+		*parts = append(*parts, nil)
+	}
+	(*parts)[*in.PartNumber-1] = append([]byte{}, (*m.objects[key])[start:end+1]...)
+	m.copiedParts++
+	eTag := fmt.Sprint(*in.PartNumber)
+	return &s3.UploadPartCopyOutput{CopyPartResult: &s3.CopyPartResult{ETag: &eTag}}, nil
+}
+
 func (m *mockS3Client) CompleteMultipartUploadWithContext(ctx aws.Context, in *s3.CompleteMultipartUploadInput, opts ...request.Option) (*s3.CompleteMultipartUploadOutput, error) {
 	if m.multiPartUploads[*in.UploadId] == nil {
 		return nil, fmt.Errorf("multipart upload not initiated")
@@ -141,20 +351,41 @@ func (m *mockS3Client) AbortMultipartUploadWithContext(ctx aws.Context, in *s3.A
 
 // ListObject returns the objects from map for mock test
 func (m *mockS3Client) ListObjects(in *s3.ListObjectsInput) (*s3.ListObjectsOutput, error) {
-	var contents []*s3.Object
+	var (
+		limit int64 = 1000 // aws default is 1000.
+		keys  []string
+	)
+	m.listObjectsMarkers = append(m.listObjectsMarkers, aws.StringValue(in.Marker))
+	if m.listObjectsErr != nil {
+		return nil, m.listObjectsErr
+	}
+	if m.throttledListPages > 0 {
+		m.throttledListPages--
+		return nil, awserr.New("SlowDown", "Please reduce your request rate.", nil)
+	}
+	if in.MaxKeys != nil {
+		limit = *in.MaxKeys
+	}
 	for key := range m.objects {
-		if strings.HasPrefix(key, *in.Prefix) {
-			keyPtr := new(string)
-			*keyPtr = key
-			tempObj := &s3.Object{
-				Key: keyPtr,
-			}
-			contents = append(contents, tempObj)
+		if strings.HasPrefix(key, *in.Prefix) && (in.Marker == nil || key > *in.Marker) {
+			keys = append(keys, key)
 		}
 	}
+	sort.Strings(keys)
+
 	out := &s3.ListObjectsOutput{
-		Prefix:   in.Prefix,
-		Contents: contents,
+		Prefix:      in.Prefix,
+		Marker:      in.Marker,
+		IsTruncated: aws.Bool(int64(len(keys)) > limit),
+	}
+	for index, key := range keys {
+		if int64(index) == limit {
+			break
+		}
+		out.Contents = append(out.Contents, &s3.Object{
+			Key:  aws.String(key),
+			Size: aws.Int64(int64(len(*m.objects[key]))),
+		})
 	}
 	return out, nil
 }
@@ -184,7 +415,8 @@ func (m *mockS3Client) ListObjectsPages(in *s3.ListObjectsInput, callback func(*
 			keyPtr := new(string)
 			*keyPtr = key
 			tempObj := &s3.Object{
-				Key: keyPtr,
+				Key:  keyPtr,
+				Size: aws.Int64(int64(len(*m.objects[key]))),
 			}
 			out.Contents = append(out.Contents, tempObj)
 			count++
@@ -196,6 +428,10 @@ func (m *mockS3Client) ListObjectsPages(in *s3.ListObjectsInput, callback func(*
 			if !callback(out, lastPage) {
 				return nil
 			}
+			if m.throttledListPages > 0 {
+				m.throttledListPages--
+				return awserr.New("SlowDown", "Please reduce your request rate.", nil)
+			}
 			count = 0
 			out = &s3.ListObjectsOutput{
 				Prefix:     in.Prefix,
@@ -209,6 +445,54 @@ func (m *mockS3Client) ListObjectsPages(in *s3.ListObjectsInput, callback func(*
 
 // DeleteObject deletes the object from map for mock test
 func (m *mockS3Client) DeleteObject(in *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	if err := m.rejectLockedObjectWrite(*in.Key); err != nil {
+		return nil, err
+	}
 	delete(m.objects, *in.Key)
 	return &s3.DeleteObjectOutput{}, nil
 }
+
+// GetObjectTagging returns the tags of the object from map for mock test
+func (m *mockS3Client) GetObjectTagging(in *s3.GetObjectTaggingInput) (*s3.GetObjectTaggingOutput, error) {
+	if m.objects[*in.Key] == nil {
+		return nil, fmt.Errorf("object not found")
+	}
+	return &s3.GetObjectTaggingOutput{TagSet: m.tags[*in.Key]}, nil
+}
+
+// PutObjectTagging replaces the tags of the object in map for mock test
+func (m *mockS3Client) PutObjectTagging(in *s3.PutObjectTaggingInput) (*s3.PutObjectTaggingOutput, error) {
+	if m.objects[*in.Key] == nil {
+		return nil, fmt.Errorf("object not found")
+	}
+	if m.tags == nil {
+		m.tags = map[string][]*s3.Tag{}
+	}
+	m.tags[*in.Key] = in.Tagging.TagSet
+	return &s3.PutObjectTaggingOutput{}, nil
+}
+
+// CopyObject copies the object in map for mock test, along with its metadata and tags if the directives say so
+func (m *mockS3Client) CopyObject(in *s3.CopyObjectInput) (*s3.CopyObjectOutput, error) {
+	source, err := url.PathUnescape(*in.CopySource)
+	if err != nil {
+		return nil, err
+	}
+	key := strings.TrimPrefix(source, *in.Bucket+"/")
+	if m.objects[key] == nil {
+		return nil, fmt.Errorf("object not found")
+	}
+	if err := m.rejectLockedObjectWrite(*in.Key); err != nil {
+		return nil, err
+	}
+	content := append([]byte{}, *m.objects[key]...)
+	m.objects[*in.Key] = &content
+	m.recordObjectACL(*in.Key, in.ACL)
+	if aws.StringValue(in.MetadataDirective) == s3.MetadataDirectiveCopy && m.metadata[key] != nil {
+		m.metadata[*in.Key] = m.metadata[key]
+	}
+	if aws.StringValue(in.TaggingDirective) == s3.TaggingDirectiveCopy && m.tags[key] != nil {
+		m.tags[*in.Key] = m.tags[key]
+	}
+	return &s3.CopyObjectOutput{}, nil
+}
@@ -11,13 +11,135 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gardener/etcd-backup-restore/pkg/compressor"
 	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
 
 	"github.com/sirupsen/logrus"
 )
 
-// NewSnapshot returns the snapshot object.
+// DefaultSnapshotNamer names the snapshots <kind>-<start revision>-<last revision>-<creation time><compression suffix><final suffix>.
+type DefaultSnapshotNamer struct{}
+
+// GenerateName returns the name of the given snapshot.
+func (DefaultSnapshotNamer) GenerateName(snap *brtypes.Snapshot) string {
+	s := *snap
+	s.GenerateSnapshotName()
+	return s.SnapName
+}
+
+// Parse returns the snapshot parsed from the given snapshot name.
+func (DefaultSnapshotNamer) Parse(snapName string) (*brtypes.Snapshot, error) {
+	var err error
+	s := &brtypes.Snapshot{}
+	tokens := strings.Split(snapName, "-")
+	if len(tokens) != 4 {
+		return nil, fmt.Errorf("invalid snapshot name: %s", snapName)
+	}
+
+	//parse kind
+	switch tokens[0] {
+	case brtypes.SnapshotKindFull:
+		s.Kind = brtypes.SnapshotKindFull
+	case brtypes.SnapshotKindDelta:
+		s.Kind = brtypes.SnapshotKindDelta
+	default:
+		return nil, fmt.Errorf("unknown snapshot kind: %s", tokens[0])
+	}
+
+	//parse start revision
+	s.StartRevision, err = strconv.ParseInt(tokens[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start revision: %s", tokens[1])
+	}
+	//parse last revision
+	s.LastRevision, err = strconv.ParseInt(tokens[2], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid last revision: %s", tokens[2])
+	}
+
+	//parse creation time as well as parse the Snapshot compression suffix
+	timeWithSnapSuffix := strings.Split(tokens[3], ".")
+	// Check & remove if the last token is a chunk directory. The chunkDirSuffix is set by only GCS snapstore when using a emulator for testing.
+	if fmt.Sprintf(".%s", timeWithSnapSuffix[len(timeWithSnapSuffix)-1]) == brtypes.ChunkDirSuffix {
+		timeWithSnapSuffix = timeWithSnapSuffix[:len(timeWithSnapSuffix)-1]
+	}
+	// The creation time is followed by an optional compression suffix, which is in turn followed by an optional final suffix.
+	// Any other suffix is rejected rather than mistaken for the compression suffix, since the snapshots are decompressed
+	// according to their own compression suffix regardless of the currently configured compression policy.
+	for i, suffix := range timeWithSnapSuffix[1:] {
+		suffix = "." + suffix
+		switch {
+		case suffix == brtypes.FinalSuffix && i == len(timeWithSnapSuffix)-2:
+			s.IsFinal = true
+		case i == 0 && isCompressionSuffix(suffix):
+			s.CompressionSuffix = suffix
+		default:
+			return nil, fmt.Errorf("invalid snapshot suffix %s: %s", suffix, snapName)
+		}
+	}
+	unixTime, err := strconv.ParseInt(timeWithSnapSuffix[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid creation time: %s", tokens[3])
+	}
+	s.CreatedOn = time.Unix(unixTime, 0).UTC()
+	s.SnapName = snapName
+	return s, nil
+}
+
+// isCompressionSuffix returns true if the given suffix is the suffix of a supported compression policy.
+func isCompressionSuffix(suffix string) bool {
+	isCompressed, _, err := compressor.IsSnapshotCompressed(suffix)
+	return err == nil && isCompressed
+}
+
+// snapshotNaming holds the namer with which a snapstore names and parses its snapshots, which is the
+// DefaultSnapshotNamer unless the snapstore is configured with another one.
+type snapshotNaming struct {
+	namer brtypes.SnapshotNamer
+}
+
+// snapshotNamer returns the namer of the snapstore.
+func (n *snapshotNaming) snapshotNamer() brtypes.SnapshotNamer {
+	if n.namer == nil {
+		return DefaultSnapshotNamer{}
+	}
+	return n.namer
+}
+
+// setSnapshotNamer sets the namer of the snapstore.
+func (n *snapshotNaming) setSnapshotNamer(namer brtypes.SnapshotNamer) {
+	n.namer = namer
+}
+
+// parseSnapshot parses the snapshot at the given path with the namer of the snapstore.
+func (n *snapshotNaming) parseSnapshot(snapPath string) (*brtypes.Snapshot, error) {
+	return parseSnapshot(snapPath, n.snapshotNamer())
+}
+
+// namingSnapStore is a snapstore which names and parses its snapshots with a configurable namer.
+type namingSnapStore interface {
+	snapshotNamer() brtypes.SnapshotNamer
+	setSnapshotNamer(namer brtypes.SnapshotNamer)
+}
+
+// NewSnapshot returns the snapshot object, named by the DefaultSnapshotNamer.
 func NewSnapshot(kind string, startRevision, lastRevision int64, compressionSuffix string, isFinal bool) *brtypes.Snapshot {
+	return newSnapshot(DefaultSnapshotNamer{}, kind, startRevision, lastRevision, compressionSuffix, isFinal)
+}
+
+// NewSnapshotForStore returns the snapshot object, named by the namer of the given snapstore, in the partition it is
+// saved to if the given snapstore is date partitioned.
+func NewSnapshotForStore(store brtypes.SnapStore, kind string, startRevision, lastRevision int64, compressionSuffix string, isFinal bool) *brtypes.Snapshot {
+	var namer brtypes.SnapshotNamer = DefaultSnapshotNamer{}
+	if ns, ok := unwrapSnapStore(store).(namingSnapStore); ok {
+		namer = ns.snapshotNamer()
+	}
+	snap := newSnapshot(namer, kind, startRevision, lastRevision, compressionSuffix, isFinal)
+	SetDatePartition(store, snap)
+	return snap
+}
+
+func newSnapshot(namer brtypes.SnapshotNamer, kind string, startRevision, lastRevision int64, compressionSuffix string, isFinal bool) *brtypes.Snapshot {
 	snap := &brtypes.Snapshot{
 		Kind:              kind,
 		StartRevision:     startRevision,
@@ -26,16 +148,19 @@ func NewSnapshot(kind string, startRevision, lastRevision int64, compressionSuff
 		CompressionSuffix: compressionSuffix,
 		IsFinal:           isFinal,
 	}
-	snap.GenerateSnapshotName()
+	snap.SnapName = namer.GenerateName(snap)
 	return snap
 }
 
-// ParseSnapshot parse <snapPath> to create snapshot structure
+// ParseSnapshot parse <snapPath> to create snapshot structure, whose name is parsed by the DefaultSnapshotNamer.
 func ParseSnapshot(snapPath string) (*brtypes.Snapshot, error) {
+	return parseSnapshot(snapPath, DefaultSnapshotNamer{})
+}
+
+// parseSnapshot parses <snapPath> to create snapshot structure, whose name is parsed by the given namer.
+func parseSnapshot(snapPath string, namer brtypes.SnapshotNamer) (*brtypes.Snapshot, error) {
 	logrus.Debugf("Snap path: %s", snapPath)
-	var err error
 	var backupVersion string = ""
-	s := &brtypes.Snapshot{}
 	// First try if the path contains v1
 	lastIndex := strings.LastIndex(snapPath, "v1/")
 	if lastIndex >= 0 {
@@ -59,11 +184,20 @@ func ParseSnapshot(snapPath string) (*brtypes.Snapshot, error) {
 	snapPath = snapPath[lastIndex:]
 
 	tok := strings.Split(snapPath, "/")
+	var snapDir string
+	// Snapshots of date partitioned snapstores are placed under a YYYY/MM partition
+	if backupVersion == backupVersionV2 && len(tok) > 2 && isDatePartition(path.Join(tok[0], tok[1])) {
+		snapDir = path.Join(tok[0], tok[1])
+		tok = tok[2:]
+	}
 	if len(tok) < 1 || len(tok) > 3 {
 		return nil, fmt.Errorf("invalid snapshot name: %s", snapPath)
 	}
 
-	var snapName, snapDir string = "", ""
+	var (
+		snapName string
+		isChunk  bool
+	)
 	// Get snap name from the tokens
 	// Consider the token before snap name
 	// If it's v1, then consider the token as snapDir
@@ -71,68 +205,29 @@ func ParseSnapshot(snapPath string) (*brtypes.Snapshot, error) {
 	switch backupVersion {
 	case backupVersionV1:
 		if len(tok) == 3 {
-			s.IsChunk = true
+			isChunk = true
 		}
 		snapDir = tok[0]
-		snapName = path.Join(tok[1:]...)
+		tok = tok[1:]
 	case backupVersionV2:
 		if len(tok) == 2 {
-			s.IsChunk = true
+			isChunk = true
 		}
-		snapName = path.Join(tok[0:]...)
 	}
+	snapName = path.Join(tok...)
 
 	logrus.Debugf("Prefix: %s, Snap Directory: %s, Snap Name: %s", prefix, snapDir, snapName)
-	tokens := strings.Split(snapName, "-")
-	if len(tokens) != 4 {
-		return nil, fmt.Errorf("invalid snapshot name: %s", snapName)
-	}
-
-	//parse kind
-	switch tokens[0] {
-	case brtypes.SnapshotKindFull:
-		s.Kind = brtypes.SnapshotKindFull
-	case brtypes.SnapshotKindDelta:
-		s.Kind = brtypes.SnapshotKindDelta
-	default:
-		return nil, fmt.Errorf("unknown snapshot kind: %s", tokens[0])
-	}
-
-	//parse start revision
-	s.StartRevision, err = strconv.ParseInt(tokens[1], 10, 64)
+	// The chunks of a snapshot are placed under the snapshot name, which is hence parsed without the chunk.
+	s, err := namer.Parse(tok[0])
 	if err != nil {
-		return nil, fmt.Errorf("invalid start revision: %s", tokens[1])
-	}
-	//parse last revision
-	s.LastRevision, err = strconv.ParseInt(tokens[2], 10, 64)
-	if err != nil {
-		return nil, fmt.Errorf("invalid last revision: %s", tokens[2])
+		return nil, err
 	}
 
 	if s.StartRevision > s.LastRevision {
-		return nil, fmt.Errorf("last revision (%s) should be at least start revision(%s) ", tokens[2], tokens[1])
+		return nil, fmt.Errorf("last revision (%d) should be at least start revision(%d) ", s.LastRevision, s.StartRevision)
 	}
 
-	//parse creation time as well as parse the Snapshot compression suffix
-	lastNameToken := strings.Split(tokens[3], "/")
-	timeWithSnapSuffix := strings.Split(lastNameToken[0], ".")
-	// Check & remove if the last token is a chunk directory. The chunkDirSuffix is set by only GCS snapstore when using a emulator for testing.
-	if fmt.Sprintf(".%s", timeWithSnapSuffix[len(timeWithSnapSuffix)-1]) == brtypes.ChunkDirSuffix {
-		timeWithSnapSuffix = timeWithSnapSuffix[:len(timeWithSnapSuffix)-1]
-	}
-	if len(timeWithSnapSuffix) >= 2 {
-		if "."+timeWithSnapSuffix[1] != brtypes.FinalSuffix {
-			s.CompressionSuffix = "." + timeWithSnapSuffix[1]
-		}
-		if "."+timeWithSnapSuffix[len(timeWithSnapSuffix)-1] == brtypes.FinalSuffix {
-			s.IsFinal = true
-		}
-	}
-	unixTime, err := strconv.ParseInt(timeWithSnapSuffix[0], 10, 64)
-	if err != nil {
-		return nil, fmt.Errorf("invalid creation time: %s", tokens[3])
-	}
-	s.CreatedOn = time.Unix(unixTime, 0).UTC()
+	s.IsChunk = isChunk
 	s.SnapName = snapName
 	s.SnapDir = snapDir
 	s.Prefix = prefix
@@ -255,6 +255,36 @@ var _ = Describe("Snapshot", func() {
 			})
 		})
 
+		Context("when snapshot names with different compression suffixes provided", func() {
+			It("parses the compression suffix of each snapshot independently", func() {
+				for snapName, compressionSuffix := range map[string]string{
+					"Incr-00030010-00030100-1518427675":            compressor.UnCompressSnapshotExtension,
+					"Incr-00030101-00030200-1518427676.gz":         compressor.GzipCompressionExtension,
+					"Incr-00030201-00030300-1518427677.Z":          compressor.LzwCompressionExtension,
+					"Incr-00030301-00030400-1518427678.zlib":       compressor.ZlibCompressionExtension,
+					"Full-00000000-00030400-1518427679.zlib.final": compressor.ZlibCompressionExtension,
+				} {
+					s, err := ParseSnapshot("v2/" + snapName)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(s.CompressionSuffix).To(Equal(compressionSuffix), snapName)
+				}
+			})
+		})
+
+		Context("when unknown suffix specified", func() {
+			It("returns error", func() {
+				for _, snapPath := range []string{
+					"v2/Full-00000000-00030009-1518427675.bz2",
+					"v2/Full-00000000-00030009-1518427675.gz.gz",
+					"v2/Full-00000000-00030009-1518427675.final.gz",
+					"v2/Full-00000000-00030009-1518427675.gz.final.tmp",
+				} {
+					_, err := ParseSnapshot(snapPath)
+					Expect(err).Should(HaveOccurred(), snapPath)
+				}
+			})
+		})
+
 		Context("when number of separated tokens not equal to 4", func() {
 			It("returns error", func() {
 				snapPath := "v2/Full-00000000-00002088-2387428-43"
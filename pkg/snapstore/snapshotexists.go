@@ -0,0 +1,32 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapstore
+
+import (
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// SnapshotExists returns whether the given snapshot object is present in the snapstore. The object is checked on its
+// own if the snapstore supports it, and the snapstore is listed otherwise. A snapshot taken in-process, which hasn't
+// been listed and hence has no prefix, is looked up in the partition of its creation time if the snapstore is date
+// partitioned, as it was saved there.
+func SnapshotExists(store brtypes.SnapStore, snap brtypes.Snapshot) (bool, error) {
+	if _, ok := store.(*DatePartitionedSnapStore); ok && snap.Prefix == "" && snap.SnapDir == "" {
+		snap.SnapDir = GetDatePartition(snap.CreatedOn)
+	}
+	if es, ok := unwrapSnapStore(snapStoreOfKind(store, snap.Kind)).(brtypes.ExistenceCheckingSnapStore); ok {
+		return es.Exists(snap)
+	}
+	snapList, err := store.List()
+	if err != nil {
+		return false, err
+	}
+	for _, s := range snapList {
+		if s.SnapName == snap.SnapName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
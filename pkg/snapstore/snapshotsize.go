@@ -0,0 +1,34 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapstore
+
+import (
+	"fmt"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// SnapshotSize returns the size of the given snapshot object, as stored in the snapstore, i.e. after compression.
+// Returns an error if the snapstore doesn't support returning the size of the snapshots.
+func SnapshotSize(store brtypes.SnapStore, snap brtypes.Snapshot) (int64, error) {
+	ss, ok := unwrapSnapStore(store).(brtypes.SizingSnapStore)
+	if !ok {
+		return -1, fmt.Errorf("snapstore does not support returning the size of snapshots")
+	}
+	return ss.Size(snap)
+}
+
+// ValidateStorageBudget returns an error if the snapshots are garbage collected within a storage budget as per the
+// given snapshotter config, but the snapstores of the given storage provider can't return the size of the snapshots.
+func ValidateStorageBudget(provider string, config *brtypes.SnapshotterConfig) error {
+	if config == nil || config.GarbageCollectionPolicy != brtypes.GarbageCollectionPolicyStorageBudget {
+		return nil
+	}
+	switch provider {
+	case brtypes.SnapstoreProviderLocal, "", brtypes.SnapstoreProviderS3, brtypes.SnapstoreProviderECS, brtypes.SnapstoreProviderOCS:
+		return nil
+	}
+	return fmt.Errorf("garbage collection policy %s is not supported by storage provider %s, only by the Local and the S3 compatible storage providers", brtypes.GarbageCollectionPolicyStorageBudget, provider)
+}
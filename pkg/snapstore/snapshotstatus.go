@@ -0,0 +1,329 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"os"
+
+	"github.com/gardener/etcd-backup-restore/pkg/compressor"
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// The layout of the pages of a bbolt database, as written by go.etcd.io/bbolt. The database is written in the native
+// byte order of the etcd host, which is little endian on all the platforms supported by etcd.
+const (
+	boltPageHeaderSize   = 16
+	boltMetaSize         = 64
+	boltElementSize      = 16
+	boltBucketHeaderSize = 16
+	boltMagic            = 0xED0CDAED
+
+	boltBranchPageFlag = 0x01
+	boltLeafPageFlag   = 0x02
+	boltBucketLeafFlag = 0x01
+
+	// statusRangeBlockSize is the size of the blocks in which the pages of a snapshot are fetched from a snapstore
+	// supporting ranged reads, so that neighbouring pages don't need a request each.
+	statusRangeBlockSize = 1 << 20 // 1 MiB
+	// statusRangeCachedBlocks is the number of fetched blocks which are kept in memory while computing the status.
+	statusRangeCachedBlocks = 16
+)
+
+// boltMeta is the meta page of a bbolt database.
+type boltMeta struct {
+	pageSize int64
+	root     uint64
+	pgid     uint64
+	txid     uint64
+}
+
+// SnapshotStatus computes the status of the database of the given full snapshot, as reported by
+// `etcdctl snapshot status`, without downloading the whole snapshot where possible.
+// The status is computed by walking the B+ tree of the bbolt database from its meta page, so only the pages which are
+// reachable from the meta page are read, and the free pages and the hash appended to the snapshot are skipped.
+// The pages of an uncompressed snapshot are fetched with ranged reads if the snapstore supports them. A compressed
+// snapshot, or a snapshot in a snapstore without ranged reads, is decompressed as a stream, which is read only up to
+// the high water mark of the database and spooled to a temporary file.
+// The hash and the total number of keys cover all the keys of the database, so all the reachable pages are read.
+func SnapshotStatus(store brtypes.SnapStore, snap brtypes.Snapshot) (*brtypes.SnapshotStatus, error) {
+	if snap.Kind != brtypes.SnapshotKindFull {
+		return nil, fmt.Errorf("status can only be computed for full snapshots, snapshot %s is of kind %s", snap.SnapName, snap.Kind)
+	}
+	isCompressed, compressionPolicy, err := compressor.IsSnapshotCompressed(snap.CompressionSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	if rs, ok := unwrapSnapStore(store).(brtypes.RangeFetchingSnapStore); ok && !isCompressed {
+		r := &rangeReaderAt{store: rs, snap: snap, blocks: map[int64][]byte{}}
+		return computeSnapshotStatus(r)
+	}
+
+	rc, err := store.Fetch(snap)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch snapshot %s: %v", snap.SnapName, err)
+	}
+	defer rc.Close()
+	r := io.Reader(rc)
+	if isCompressed {
+		dc, err := compressor.DecompressSnapshot(rc, compressionPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decompress snapshot %s: %v", snap.SnapName, err)
+		}
+		defer dc.Close()
+		r = dc
+	}
+
+	f, err := os.CreateTemp("", "snapshot-status-")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create temporary file for snapshot %s: %v", snap.SnapName, err)
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+	if err := spoolSnapshotDatabase(r, f); err != nil {
+		return nil, fmt.Errorf("unable to read snapshot %s: %v", snap.SnapName, err)
+	}
+	return computeSnapshotStatus(f)
+}
+
+// spoolSnapshotDatabase copies the bbolt database from the given stream of a snapshot to the given file. The stream
+// is read only up to the high water mark of the database recorded in its meta pages.
+func spoolSnapshotDatabase(r io.Reader, w io.Writer) error {
+	header := make([]byte, boltPageHeaderSize+boltMetaSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return err
+	}
+	pageSize := int64(binary.LittleEndian.Uint32(header[boltPageHeaderSize+8:]))
+	if pageSize < boltPageHeaderSize+boltMetaSize {
+		return fmt.Errorf("invalid page size %d of the bbolt database", pageSize)
+	}
+	metaPages := make([]byte, 2*pageSize)
+	copy(metaPages, header)
+	if _, err := io.ReadFull(r, metaPages[len(header):]); err != nil {
+		return err
+	}
+	meta, err := readBoltMeta(bytes.NewReader(metaPages))
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(metaPages); err != nil {
+		return err
+	}
+	_, err = io.CopyN(w, r, int64(meta.pgid)*meta.pageSize-2*pageSize)
+	return err
+}
+
+// computeSnapshotStatus computes the status of the bbolt database read from the given reader, in the same way as
+// `etcdctl snapshot status`.
+func computeSnapshotStatus(r io.ReaderAt) (*brtypes.SnapshotStatus, error) {
+	meta, err := readBoltMeta(r)
+	if err != nil {
+		return nil, err
+	}
+	w := &boltWalker{r: r, pageSize: meta.pageSize}
+	status := &brtypes.SnapshotStatus{TotalSize: int64(meta.pgid) * meta.pageSize}
+	h := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+
+	// the buckets of etcd are the keys of the root bucket
+	err = w.forEach(meta.root, nil, func(name, value []byte, flags uint32) error {
+		if flags&boltBucketLeafFlag == 0 {
+			return fmt.Errorf("cannot get hash of bucket %s", string(name))
+		}
+		h.Write(name)
+		return w.forEachInBucket(value, func(k, v []byte) error {
+			h.Write(k)
+			h.Write(v)
+			if string(name) == "key" && len(k) >= 8 {
+				status.Revision = int64(binary.BigEndian.Uint64(k[0:8]))
+			}
+			status.TotalKey++
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	status.Hash = h.Sum32()
+	return status, nil
+}
+
+// readBoltMeta reads the valid meta page of the bbolt database with the latest transaction.
+func readBoltMeta(r io.ReaderAt) (*boltMeta, error) {
+	meta0, err0 := readBoltMetaAt(r, 0)
+	if err0 != nil {
+		return nil, fmt.Errorf("unable to read meta page of the bbolt database: %v", err0)
+	}
+	meta1, err1 := readBoltMetaAt(r, meta0.pageSize)
+	if err1 != nil || meta1.txid < meta0.txid {
+		return meta0, nil
+	}
+	return meta1, nil
+}
+
+// readBoltMetaAt reads the meta page of the bbolt database at the given offset, and validates its checksum.
+func readBoltMetaAt(r io.ReaderAt, offset int64) (*boltMeta, error) {
+	buf := make([]byte, boltMetaSize)
+	if _, err := r.ReadAt(buf, offset+boltPageHeaderSize); err != nil {
+		return nil, err
+	}
+	if magic := binary.LittleEndian.Uint32(buf[0:]); magic != boltMagic {
+		return nil, fmt.Errorf("invalid magic %x, not a bbolt database", magic)
+	}
+	checksum := fnv.New64a()
+	checksum.Write(buf[:56])
+	if checksum.Sum64() != binary.LittleEndian.Uint64(buf[56:]) {
+		return nil, fmt.Errorf("checksum mismatch of the meta page")
+	}
+	return &boltMeta{
+		pageSize: int64(binary.LittleEndian.Uint32(buf[8:])),
+		root:     binary.LittleEndian.Uint64(buf[16:]),
+		pgid:     binary.LittleEndian.Uint64(buf[40:]),
+		txid:     binary.LittleEndian.Uint64(buf[48:]),
+	}, nil
+}
+
+// boltWalker walks the B+ trees of the buckets of a bbolt database.
+type boltWalker struct {
+	r        io.ReaderAt
+	pageSize int64
+}
+
+// readPage reads the page with the given id, including its overflow pages.
+func (w *boltWalker) readPage(id uint64) ([]byte, error) {
+	offset := int64(id) * w.pageSize
+	p := make([]byte, w.pageSize)
+	if _, err := w.r.ReadAt(p, offset); err != nil {
+		return nil, fmt.Errorf("unable to read page %d: %v", id, err)
+	}
+	if overflow := int64(binary.LittleEndian.Uint32(p[12:])); overflow > 0 {
+		p = make([]byte, (overflow+1)*w.pageSize)
+		if _, err := w.r.ReadAt(p, offset); err != nil {
+			return nil, fmt.Errorf("unable to read page %d: %v", id, err)
+		}
+	}
+	return p, nil
+}
+
+// forEachInBucket calls fn for each key of the bucket with the given bucket header, in order. The value of the keys
+// holding nested buckets is nil, as for bbolt.Bucket.ForEach.
+func (w *boltWalker) forEachInBucket(header []byte, fn func(k, v []byte) error) error {
+	if len(header) < boltBucketHeaderSize {
+		return fmt.Errorf("invalid bucket header")
+	}
+	fnElement := func(k, v []byte, flags uint32) error {
+		if flags&boltBucketLeafFlag != 0 {
+			v = nil
+		}
+		return fn(k, v)
+	}
+	if root := binary.LittleEndian.Uint64(header[0:]); root != 0 {
+		return w.forEach(root, nil, fnElement)
+	}
+	// the page of an inline bucket follows its header
+	return w.forEach(0, header[boltBucketHeaderSize:], fnElement)
+}
+
+// forEach calls fn for each element of the leaf pages under the page with the given id, or under the given page if it
+// is not nil, in order.
+func (w *boltWalker) forEach(id uint64, p []byte, fn func(k, v []byte, flags uint32) error) error {
+	if p == nil {
+		var err error
+		if p, err = w.readPage(id); err != nil {
+			return err
+		}
+	}
+	if len(p) < boltPageHeaderSize {
+		return fmt.Errorf("invalid page %d", id)
+	}
+	flags := binary.LittleEndian.Uint16(p[8:])
+	count := int(binary.LittleEndian.Uint16(p[10:]))
+	if len(p) < boltPageHeaderSize+count*boltElementSize {
+		return fmt.Errorf("invalid page %d with %d elements", id, count)
+	}
+
+	for i := 0; i < count; i++ {
+		elem := boltPageHeaderSize + i*boltElementSize
+		switch {
+		case flags&boltBranchPageFlag != 0:
+			child := binary.LittleEndian.Uint64(p[elem+8:])
+			if err := w.forEach(child, nil, fn); err != nil {
+				return err
+			}
+		case flags&boltLeafPageFlag != 0:
+			elemFlags := binary.LittleEndian.Uint32(p[elem:])
+			pos := elem + int(binary.LittleEndian.Uint32(p[elem+4:]))
+			ksize := int(binary.LittleEndian.Uint32(p[elem+8:]))
+			vsize := int(binary.LittleEndian.Uint32(p[elem+12:]))
+			if pos+ksize+vsize > len(p) {
+				return fmt.Errorf("invalid element %d of page %d", i, id)
+			}
+			if err := fn(p[pos:pos+ksize], p[pos+ksize:pos+ksize+vsize], elemFlags); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unexpected flags %x of page %d", flags, id)
+		}
+	}
+	return nil
+}
+
+// rangeReaderAt reads a snapshot from a snapstore with ranged reads, in blocks of statusRangeBlockSize, keeping the
+// most recently fetched blocks in memory.
+type rangeReaderAt struct {
+	store  brtypes.RangeFetchingSnapStore
+	snap   brtypes.Snapshot
+	blocks map[int64][]byte
+	order  []int64
+}
+
+// ReadAt reads len(p) bytes of the snapshot at the given offset.
+func (r *rangeReaderAt) ReadAt(p []byte, offset int64) (int, error) {
+	n := 0
+	for n < len(p) {
+		index := (offset + int64(n)) / statusRangeBlockSize
+		block, err := r.block(index)
+		if err != nil {
+			return n, err
+		}
+		start := offset + int64(n) - index*statusRangeBlockSize
+		if start >= int64(len(block)) {
+			return n, io.EOF
+		}
+		n += copy(p[n:], block[start:])
+	}
+	return n, nil
+}
+
+// block returns the block with the given index, fetching it if it is not in memory.
+func (r *rangeReaderAt) block(index int64) ([]byte, error) {
+	if block, ok := r.blocks[index]; ok {
+		return block, nil
+	}
+	rc, err := r.store.FetchRange(r.snap, index*statusRangeBlockSize, statusRangeBlockSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	block, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(r.order) >= statusRangeCachedBlocks {
+		delete(r.blocks, r.order[0])
+		r.order = r.order[1:]
+	}
+	r.blocks[index] = block
+	r.order = append(r.order, index)
+	return block, nil
+}
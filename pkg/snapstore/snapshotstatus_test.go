@@ -0,0 +1,202 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapstore_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gardener/etcd-backup-restore/pkg/compressor"
+	. "github.com/gardener/etcd-backup-restore/pkg/snapstore"
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	bolt "go.etcd.io/bbolt"
+)
+
+var _ = Describe("Computing the status of a snapshot", func() {
+	var (
+		store    *LocalSnapStore
+		prefix   string
+		db       []byte
+		expected *brtypes.SnapshotStatus
+	)
+
+	BeforeEach(func() {
+		dir := GinkgoT().TempDir()
+		dbPath := filepath.Join(dir, "db")
+		createSnapshotDatabase(dbPath)
+		expected = decodeSnapshotStatus(dbPath)
+
+		var err error
+		db, err = os.ReadFile(dbPath)
+		Expect(err).ShouldNot(HaveOccurred())
+		// the snapshots streamed by etcd have the sha256 of the database appended to it
+		sha := sha256.Sum256(db)
+		db = append(db, sha[:]...)
+
+		prefix = filepath.Join(dir, "store")
+		store, err = NewLocalSnapStore(prefix)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	saveSnapshot := func(compressionPolicy string) brtypes.Snapshot {
+		compressionSuffix, err := compressor.GetCompressionSuffix(compressionPolicy != "", compressionPolicy)
+		Expect(err).ShouldNot(HaveOccurred())
+		snap := NewSnapshot(brtypes.SnapshotKindFull, 0, expected.Revision, compressionSuffix, false)
+		rc := io.NopCloser(bytes.NewReader(db))
+		if compressionPolicy != "" {
+			rc, err = compressor.CompressSnapshot(rc, compressionPolicy)
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+		Expect(store.Save(*snap, rc)).To(Succeed())
+		snap.Prefix = prefix
+		return *snap
+	}
+
+	It("should match the status of the decoded database for an uncompressed snapshot read with ranged reads", func() {
+		snap := saveSnapshot("")
+		status, err := SnapshotStatus(store, snap)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(status).To(Equal(expected))
+	})
+
+	It("should read an uncompressed snapshot with ranged reads behind the caching and date partitioned snapstores", func() {
+		snap := saveSnapshot("")
+		cacheDir := GinkgoT().TempDir()
+		cachingStore, err := NewCachingSnapStore(store, cacheDir, 1<<30, time.Hour)
+		Expect(err).ShouldNot(HaveOccurred())
+		status, err := SnapshotStatus(NewDatePartitionedSnapStore(cachingStore), snap)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(status).To(Equal(expected))
+		// the snapshot isn't fetched, and thereby cached, as a whole
+		Expect(os.ReadDir(cacheDir)).To(BeEmpty())
+	})
+
+	It("should match the status of the decoded database for an uncompressed snapshot in a snapstore without ranged reads", func() {
+		snap := saveSnapshot("")
+		status, err := SnapshotStatus(struct{ brtypes.SnapStore }{store}, snap)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(status).To(Equal(expected))
+	})
+
+	DescribeTable("should match the status of the decoded database for a compressed snapshot",
+		func(compressionPolicy string) {
+			snap := saveSnapshot(compressionPolicy)
+			status, err := SnapshotStatus(store, snap)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(status).To(Equal(expected))
+		},
+		Entry("gzip", compressor.GzipCompressionPolicy),
+		Entry("lzw", compressor.LzwCompressionPolicy),
+		Entry("zlib", compressor.ZlibCompressionPolicy),
+	)
+
+	It("should fail for a delta snapshot", func() {
+		snap := NewSnapshot(brtypes.SnapshotKindDelta, 1, 2, "", false)
+		_, err := SnapshotStatus(store, *snap)
+		Expect(err).Should(MatchError(ContainSubstring("status can only be computed for full snapshots")))
+	})
+
+	It("should fail for a snapshot which is not a bbolt database", func() {
+		db = bytes.Repeat([]byte("not a bbolt database"), 1024)
+		snap := saveSnapshot("")
+		_, err := SnapshotStatus(store, snap)
+		Expect(err).Should(MatchError(ContainSubstring("not a bbolt database")))
+	})
+})
+
+// createSnapshotDatabase creates a bbolt database laid out like the one of etcd, with a key bucket spanning branch,
+// leaf and overflow pages, inline buckets, and free pages left behind by the deleted keys.
+func createSnapshotDatabase(dbPath string) {
+	db, err := bolt.Open(dbPath, 0600, nil)
+	Expect(err).ShouldNot(HaveOccurred())
+	defer db.Close()
+
+	Expect(db.Update(func(tx *bolt.Tx) error {
+		for _, name := range []string{"alarm", "auth", "cluster", "key", "lease", "members", "meta"} {
+			if _, err := tx.CreateBucket([]byte(name)); err != nil {
+				return err
+			}
+		}
+		if err := tx.Bucket([]byte("meta")).Put([]byte("consistent_index"), []byte{0, 0, 0, 0, 0, 0, 0, 42}); err != nil {
+			return err
+		}
+		return tx.Bucket([]byte("cluster")).Put([]byte("clusterVersion"), []byte("3.4.0"))
+	})).To(Succeed())
+
+	for rev := 1; rev <= 5000; rev += 500 {
+		Expect(db.Update(func(tx *bolt.Tx) error {
+			b := tx.Bucket([]byte("key"))
+			for i := rev; i < rev+500; i++ {
+				value := []byte(fmt.Sprintf("value-%d", i))
+				if i%997 == 0 {
+					value = bytes.Repeat(value, 2000)
+				}
+				if err := b.Put(revisionKey(int64(i)), value); err != nil {
+					return err
+				}
+			}
+			return nil
+		})).To(Succeed())
+	}
+	Expect(db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("key"))
+		for i := 1000; i < 2000; i++ {
+			if err := b.Delete(revisionKey(int64(i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})).To(Succeed())
+}
+
+// revisionKey returns the key of the given revision in the key bucket of etcd.
+func revisionKey(rev int64) []byte {
+	k := make([]byte, 17)
+	binary.BigEndian.PutUint64(k[0:8], uint64(rev))
+	k[8] = '_'
+	return k
+}
+
+// decodeSnapshotStatus opens the given bbolt database and computes its status, as `etcdctl snapshot status` does.
+func decodeSnapshotStatus(dbPath string) *brtypes.SnapshotStatus {
+	db, err := bolt.Open(dbPath, 0400, &bolt.Options{ReadOnly: true})
+	Expect(err).ShouldNot(HaveOccurred())
+	defer db.Close()
+
+	status := &brtypes.SnapshotStatus{}
+	h := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	Expect(db.View(func(tx *bolt.Tx) error {
+		status.TotalSize = tx.Size()
+		c := tx.Cursor()
+		for next, _ := c.First(); next != nil; next, _ = c.Next() {
+			b := tx.Bucket(next)
+			h.Write(next)
+			isKeyBucket := string(next) == "key"
+			if err := b.ForEach(func(k, v []byte) error {
+				h.Write(k)
+				h.Write(v)
+				if isKeyBucket {
+					status.Revision = int64(binary.BigEndian.Uint64(k[0:8]))
+				}
+				status.TotalKey++
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})).To(Succeed())
+	status.Hash = h.Sum32()
+	return status
+}
@@ -6,21 +6,42 @@ package snapstore_test
 
 import (
 	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
+	"hash/crc32"
 	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing/iotest"
 	"time"
 
 	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gardener/etcd-backup-restore/pkg/compressor"
+	"github.com/gardener/etcd-backup-restore/pkg/miscellaneous"
 	. "github.com/gardener/etcd-backup-restore/pkg/snapstore"
 	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
 	fake "github.com/gophercloud/gophercloud/testhelper/client"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
 	"github.com/sirupsen/logrus"
 )
 
@@ -32,6 +53,8 @@ const (
 var (
 	bucket    string = "mock-bucket"
 	objectMap        = map[string]*[]byte{}
+	// objectMetadata holds the custom metadata of the objects of the fake ABS and Swift servers by their name.
+	objectMetadata = map[string]map[string]string{}
 )
 
 // testSnapStore embedds brtypes.Snapstore and contains the number of
@@ -622,8 +645,2330 @@ var _ = Describe("Server Side Encryption Customer Managed Key for S3", func() {
 	})
 })
 
+var _ = Describe("Assuming a role for S3", func() {
+	const (
+		roleARN           = "arn:aws:iam::123456789012:role/etcd-backup"
+		sourceAccessKeyID = "SOURCEACCESSKEYID"
+		assumedAccessKey  = "ASSUMEDACCESSKEYID"
+		assumedToken      = "assumed-session-token"
+	)
+	var (
+		server             *httptest.Server
+		credentialFilePath string
+		assumeRoleRequests []url.Values
+		stsAuthorizations  []string
+		s3Authorizations   []string
+		s3SecurityTokens   []string
+		expiration         time.Time
+		mutex              sync.Mutex
+	)
+
+	BeforeEach(func() {
+		assumeRoleRequests, stsAuthorizations, s3Authorizations, s3SecurityTokens = nil, nil, nil, nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			if r.Method == http.MethodPost {
+				if err := r.ParseForm(); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				assumeRoleRequests = append(assumeRoleRequests, r.PostForm)
+				stsAuthorizations = append(stsAuthorizations, r.Header.Get("Authorization"))
+				fmt.Fprintf(w, `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><AssumeRoleResult><Credentials><AccessKeyId>%s</AccessKeyId><SecretAccessKey>assumed-secret</SecretAccessKey><SessionToken>%s</SessionToken><Expiration>%s</Expiration></Credentials></AssumeRoleResult></AssumeRoleResponse>`,
+					assumedAccessKey, assumedToken, expiration.UTC().Format(time.RFC3339))
+				return
+			}
+			s3Authorizations = append(s3Authorizations, r.Header.Get("Authorization"))
+			s3SecurityTokens = append(s3SecurityTokens, r.Header.Get("X-Amz-Security-Token"))
+			fmt.Fprint(w, `<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Name>etcd-test</Name><IsTruncated>false</IsTruncated></ListBucketResult>`)
+		}))
+		DeferCleanup(server.Close)
+
+		credentialFilePath = filepath.Join(GinkgoT().TempDir(), "credentials.json")
+		GinkgoT().Setenv("AWS_APPLICATION_CREDENTIALS_JSON", credentialFilePath)
+		Expect(os.WriteFile(credentialFilePath, []byte(fmt.Sprintf(`{
+  "accessKeyID": "%s",
+  "secretAccessKey": "XXXXXXXXXXXXXXXXXXXX",
+  "region": "eu-west-1",
+  "endpoint": "%s",
+  "s3ForcePathStyle": true,
+  "roleARN": "%s",
+  "roleSessionName": "etcd-backup-restore",
+  "externalID": "external-id",
+  "stsEndpoint": "%s"
+}`, sourceAccessKeyID, server.URL, roleARN, server.URL)), os.ModePerm)).To(Succeed())
+	})
+
+	It("should sign the requests with the credentials of the role assumed with the configured session", func() {
+		expiration = time.Now().Add(time.Hour)
+		store, err := NewS3SnapStore(&brtypes.SnapstoreConfig{Provider: "S3", Container: "etcd-test", Prefix: "v2"})
+		Expect(err).ShouldNot(HaveOccurred())
+		for i := 0; i < 2; i++ {
+			_, err = store.List()
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		// the credentials of the assumed role are reused until they are about to expire
+		Expect(assumeRoleRequests).Should(HaveLen(1))
+		Expect(assumeRoleRequests[0].Get("Action")).Should(Equal("AssumeRole"))
+		Expect(assumeRoleRequests[0].Get("RoleArn")).Should(Equal(roleARN))
+		Expect(assumeRoleRequests[0].Get("RoleSessionName")).Should(Equal("etcd-backup-restore"))
+		Expect(assumeRoleRequests[0].Get("ExternalId")).Should(Equal("external-id"))
+		// the role is assumed with the configured access key
+		Expect(stsAuthorizations[0]).Should(ContainSubstring("Credential=" + sourceAccessKeyID + "/"))
+		Expect(s3Authorizations).Should(HaveLen(2))
+		for i := range s3Authorizations {
+			Expect(s3Authorizations[i]).Should(ContainSubstring("Credential=" + assumedAccessKey + "/"))
+			Expect(s3SecurityTokens[i]).Should(Equal(assumedToken))
+		}
+	})
+
+	It("should assume the role again before the credentials of the assumed role expire", func() {
+		expiration = time.Now().Add(time.Minute)
+		store, err := NewS3SnapStore(&brtypes.SnapstoreConfig{Provider: "S3", Container: "etcd-test", Prefix: "v2"})
+		Expect(err).ShouldNot(HaveOccurred())
+		for i := 0; i < 2; i++ {
+			_, err = store.List()
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(assumeRoleRequests).Should(HaveLen(2))
+		Expect(s3Authorizations).Should(HaveLen(2))
+	})
+})
+
+var _ = Describe("Configuring the region of S3", func() {
+	var (
+		server             *httptest.Server
+		credentialFilePath string
+		authorizations     []string
+		mutex              sync.Mutex
+	)
+
+	BeforeEach(func() {
+		authorizations = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			authorizations = append(authorizations, r.Header.Get("Authorization"))
+			fmt.Fprint(w, `<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Name>etcd-test</Name><IsTruncated>false</IsTruncated></ListBucketResult>`)
+		}))
+		DeferCleanup(server.Close)
+		credentialFilePath = filepath.Join(GinkgoT().TempDir(), "credentials.json")
+	})
+
+	writeCredentials := func(region string) {
+		GinkgoT().Setenv("AWS_APPLICATION_CREDENTIALS_JSON", credentialFilePath)
+		Expect(os.WriteFile(credentialFilePath, []byte(fmt.Sprintf(`{
+  "accessKeyID": "XXXXXXXXXXXXXXXXXXXX",
+  "secretAccessKey": "XXXXXXXXXXXXXXXXXXXX",
+  "region": "%s",
+  "endpoint": "%s",
+  "s3ForcePathStyle": true
+}`, region, server.URL)), os.ModePerm)).To(Succeed())
+	}
+
+	// expectSignedWithRegion lists the given snapstore and expects the request to be signed with the given region.
+	expectSignedWithRegion := func(store brtypes.SnapStore, region string) {
+		_, err := store.List()
+		Expect(err).ShouldNot(HaveOccurred())
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(authorizations).Should(HaveLen(1))
+		Expect(authorizations[0]).Should(ContainSubstring("/%s/s3/aws4_request", region))
+	}
+
+	It("should sign the requests with the region of the credentials if no region is configured", func() {
+		writeCredentials("eu-west-1")
+		store, err := NewS3SnapStore(&brtypes.SnapstoreConfig{Provider: "S3", Container: "etcd-test", Prefix: "v2"})
+		Expect(err).ShouldNot(HaveOccurred())
+		expectSignedWithRegion(store, "eu-west-1")
+	})
+
+	It("should sign the requests with the configured region instead of the region of the credentials and the environment", func() {
+		GinkgoT().Setenv("AWS_REGION", "us-west-2")
+		writeCredentials("eu-west-1")
+		store, err := NewS3SnapStore(&brtypes.SnapstoreConfig{Provider: "S3", Container: "etcd-test", Prefix: "v2", S3Region: "custom-region-1"})
+		Expect(err).ShouldNot(HaveOccurred())
+		expectSignedWithRegion(store, "custom-region-1")
+	})
+
+	It("should fail if no region is configured for a custom endpoint", func() {
+		writeCredentials("")
+		_, err := NewS3SnapStore(&brtypes.SnapstoreConfig{Provider: "S3", Container: "etcd-test", Prefix: "v2"})
+		Expect(err).Should(MatchError(ContainSubstring("region is required")))
+
+		store, err := NewS3SnapStore(&brtypes.SnapstoreConfig{Provider: "S3", Container: "etcd-test", Prefix: "v2", S3Region: "custom-region-1"})
+		Expect(err).ShouldNot(HaveOccurred())
+		expectSignedWithRegion(store, "custom-region-1")
+	})
+
+	It("should sign the requests to ECS with the configured region instead of its default region", func() {
+		GinkgoT().Setenv("ECS_ENDPOINT", server.URL)
+		GinkgoT().Setenv("ECS_ACCESS_KEY_ID", "XXXXXXXXXXXXXXXXXXXX")
+		GinkgoT().Setenv("ECS_SECRET_ACCESS_KEY", "XXXXXXXXXXXXXXXXXXXX")
+		GinkgoT().Setenv("ECS_DISABLE_SSL", "true")
+		store, err := NewECSSnapStore(&brtypes.SnapstoreConfig{Provider: "ECS", Container: "etcd-test", Prefix: "v2", S3Region: "custom-region-1"})
+		Expect(err).ShouldNot(HaveOccurred())
+		expectSignedWithRegion(store, "custom-region-1")
+	})
+})
+
+var _ = Describe("HTTP connection pooling for snapstores", func() {
+	Context("when the snapstore config is created with defaults", func() {
+		It("should set the default connection pool settings", func() {
+			config := NewSnapstoreConfig()
+			Expect(config.MaxIdleConns).To(Equal(brtypes.DefaultMaxIdleConns))
+			Expect(config.MaxIdleConnsPerHost).To(Equal(brtypes.DefaultMaxIdleConnsPerHost))
+			Expect(config.IdleConnTimeout.Duration).To(Equal(brtypes.DefaultIdleConnTimeout))
+		})
+	})
+	Context("when the connection pool settings are configured", func() {
+		It("should create a transport with the configured settings", func() {
+			config := NewSnapstoreConfig()
+			config.MaxIdleConns = 42
+			config.MaxIdleConnsPerHost = 7
+			config.IdleConnTimeout.Duration = 15 * time.Second
+			transport := NewHTTPTransport(config)
+			Expect(transport.MaxIdleConns).To(Equal(42))
+			Expect(transport.MaxIdleConnsPerHost).To(Equal(7))
+			Expect(transport.IdleConnTimeout).To(Equal(15 * time.Second))
+		})
+		It("should not share the transport between snapstores", func() {
+			config := NewSnapstoreConfig()
+			Expect(NewHTTPTransport(config)).NotTo(BeIdenticalTo(NewHTTPTransport(config)))
+		})
+		It("should share the transport between snapstores with the same settings", func() {
+			config := NewSnapstoreConfig()
+			Expect(SharedHTTPTransport(config, false, nil)).To(BeIdenticalTo(SharedHTTPTransport(NewSnapstoreConfig(), false, nil)))
+		})
+		It("should not share the transport between snapstores with different settings", func() {
+			config := NewSnapstoreConfig()
+			transport := SharedHTTPTransport(config, false, nil)
+			Expect(SharedHTTPTransport(config, true, nil)).NotTo(BeIdenticalTo(transport))
+			Expect(SharedHTTPTransport(config, true, nil).TLSClientConfig.InsecureSkipVerify).To(BeTrue())
+			caCert := "ca-cert"
+			Expect(SharedHTTPTransport(config, false, &caCert)).NotTo(BeIdenticalTo(transport))
+
+			config.MaxIdleConnsPerHost++
+			pooled := SharedHTTPTransport(config, false, nil)
+			Expect(pooled).NotTo(BeIdenticalTo(transport))
+			Expect(pooled.MaxIdleConnsPerHost).To(Equal(config.MaxIdleConnsPerHost))
+		})
+	})
+})
+
+var _ = Describe("Retaining snapshots", func() {
+	var snap *brtypes.Snapshot
+
+	BeforeEach(func() {
+		snap = &brtypes.Snapshot{
+			CreatedOn:     time.Now().UTC(),
+			StartRevision: 0,
+			LastRevision:  2088,
+			Kind:          brtypes.SnapshotKindFull,
+			Prefix:        prefixV2,
+		}
+		snap.GenerateSnapshotName()
+	})
+
+	Context("with the mock S3 snapstore", func() {
+		var (
+			store  brtypes.SnapStore
+			client *mockS3Client
+		)
+
+		BeforeEach(func() {
+			resetObjectMap()
+			client = &mockS3Client{
+				objects:          objectMap,
+				prefix:           prefixV2,
+				multiPartUploads: map[string]*[][]byte{},
+			}
+			store = NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+			Expect(setObjectMap("s3", brtypes.SnapList{snap})).To(Equal(1))
+		})
+
+		AfterEach(func() {
+			resetObjectMap()
+		})
+
+		It("should set and clear the exclude tag", func() {
+			retained, err := IsSnapshotRetained(store, *snap)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(retained).To(BeFalse())
+
+			Expect(SetSnapshotRetained(store, *snap, true)).To(Succeed())
+			retained, err = IsSnapshotRetained(store, *snap)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(retained).To(BeTrue())
+
+			Expect(SetSnapshotRetained(store, *snap, false)).To(Succeed())
+			retained, err = IsSnapshotRetained(store, *snap)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(retained).To(BeFalse())
+		})
+
+		It("should leave the other tags of the snapshot untouched", func() {
+			key := path.Join(snap.Prefix, snap.SnapDir, snap.SnapName)
+			client.tags = map[string][]*s3.Tag{key: {{Key: aws.String("owner"), Value: aws.String("etcd")}}}
+
+			Expect(SetSnapshotRetained(store, *snap, true)).To(Succeed())
+			Expect(client.tags[key]).To(HaveLen(2))
+			Expect(SetSnapshotRetained(store, *snap, false)).To(Succeed())
+			Expect(client.tags[key]).To(HaveLen(1))
+			Expect(aws.StringValue(client.tags[key][0].Key)).To(Equal("owner"))
+		})
+	})
+
+	Context("with the local snapstore", func() {
+		var store brtypes.SnapStore
+
+		BeforeEach(func() {
+			var err error
+			snap.Prefix = path.Join(GinkgoT().TempDir(), prefixV2)
+			store, err = NewLocalSnapStore(snap.Prefix)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(store.Save(*snap, io.NopCloser(strings.NewReader("snapshot")))).To(Succeed())
+		})
+
+		It("should set and clear the exclude tag without listing the marker as a snapshot", func() {
+			Expect(SetSnapshotRetained(NewDatePartitionedSnapStore(store), *snap, true)).To(Succeed())
+			retained, err := IsSnapshotRetained(store, *snap)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(retained).To(BeTrue())
+
+			snapList, err := store.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(snapList).To(HaveLen(1))
+			Expect(snapList[0].SnapName).To(Equal(snap.SnapName))
+
+			Expect(SetSnapshotRetained(store, *snap, false)).To(Succeed())
+			retained, err = IsSnapshotRetained(store, *snap)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(retained).To(BeFalse())
+		})
+
+		It("should fail to retain a snapshot which doesn't exist", func() {
+			snap.SnapName += "-missing"
+			Expect(SetSnapshotRetained(store, *snap, true)).NotTo(Succeed())
+		})
+	})
+
+	Context("with the mock GCS, ABS, Swift and OSS snapstores", func() {
+		BeforeEach(func() {
+			resetObjectMap()
+		})
+
+		AfterEach(func() {
+			resetObjectMap()
+		})
+
+		DescribeTable("should set and clear the exclude tag",
+			func(newStore func() brtypes.SnapStore) {
+				store := newStore()
+				Expect(store.Save(*snap, io.NopCloser(strings.NewReader("snapshot")))).To(Succeed())
+
+				retained, err := IsSnapshotRetained(store, *snap)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(retained).To(BeFalse())
+
+				Expect(SetSnapshotRetained(store, *snap, true)).To(Succeed())
+				retained, err = IsSnapshotRetained(store, *snap)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(retained).To(BeTrue())
+
+				Expect(SetSnapshotRetained(store, *snap, false)).To(Succeed())
+				retained, err = IsSnapshotRetained(store, *snap)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(retained).To(BeFalse())
+
+				missing := *snap
+				missing.SnapName += "-missing"
+				Expect(SetSnapshotRetained(store, missing, true)).NotTo(Succeed())
+			},
+			Entry("GCS", func() brtypes.SnapStore {
+				return NewGCSSnapStoreFromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, "", &mockGCSClient{
+					objects: objectMap,
+					prefix:  prefixV2,
+				})
+			}),
+			Entry("ABS", newFakeABSSnapstore),
+			Entry("swift", func() brtypes.SnapStore {
+				return NewSwiftSnapstoreFromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, fake.ServiceClient())
+			}),
+			Entry("OSS", func() brtypes.SnapStore {
+				return NewOSSFromBucket(prefixV2, "/tmp", 5, brtypes.MinChunkSize, &mockOSSBucket{
+					objects:          objectMap,
+					prefix:           prefixV2,
+					multiPartUploads: map[string]*[][]byte{},
+					bucketName:       bucket,
+				})
+			}),
+		)
+
+		It("should leave the other metadata of a GCS object untouched", func() {
+			client := &mockGCSClient{objects: objectMap, prefix: prefixV2}
+			store := NewGCSSnapStoreFromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, "", client)
+			Expect(store.Save(*snap, io.NopCloser(strings.NewReader("snapshot")))).To(Succeed())
+			key := path.Join(snap.Prefix, snap.SnapDir, snap.SnapName)
+			client.objectMetadata = map[string]map[string]string{key: {"owner": "etcd"}}
+
+			Expect(SetSnapshotRetained(store, *snap, true)).To(Succeed())
+			Expect(SetSnapshotRetained(store, *snap, false)).To(Succeed())
+			Expect(client.objectMetadata[key]).To(HaveKeyWithValue("owner", "etcd"))
+		})
+
+		It("should leave the other metadata of a Swift object untouched", func() {
+			store := NewSwiftSnapstoreFromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, fake.ServiceClient())
+			Expect(store.Save(*snap, io.NopCloser(strings.NewReader("snapshot")))).To(Succeed())
+			key := path.Join(snap.Prefix, snap.SnapDir, snap.SnapName)
+			objectMetadata[key] = map[string]string{"Owner": "etcd"}
+
+			Expect(SetSnapshotRetained(store, *snap, true)).To(Succeed())
+			Expect(objectMetadata[key]).To(HaveKeyWithValue("Owner", "etcd"))
+			Expect(SetSnapshotRetained(store, *snap, false)).To(Succeed())
+			Expect(objectMetadata[key]).To(Equal(map[string]string{"Owner": "etcd"}))
+		})
+	})
+
+	Context("with a snapstore which doesn't support tagging the snapshots", func() {
+		It("should fail to retain the snapshot, and report it as not retained", func() {
+			store := NewFailedSnapStore()
+			Expect(SetSnapshotRetained(store, *snap, true)).NotTo(Succeed())
+			retained, err := IsSnapshotRetained(store, *snap)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(retained).To(BeFalse())
+		})
+	})
+})
+
+var _ = Describe("Getting the size of snapshots", func() {
+	var snap *brtypes.Snapshot
+
+	BeforeEach(func() {
+		snap = &brtypes.Snapshot{
+			CreatedOn:     time.Now().UTC(),
+			StartRevision: 0,
+			LastRevision:  2088,
+			Kind:          brtypes.SnapshotKindFull,
+			Prefix:        prefixV2,
+		}
+		snap.GenerateSnapshotName()
+	})
+
+	Context("with the mock S3 snapstore", func() {
+		var store brtypes.SnapStore
+
+		BeforeEach(func() {
+			resetObjectMap()
+			client := &mockS3Client{
+				objects:          objectMap,
+				prefix:           prefixV2,
+				multiPartUploads: map[string]*[][]byte{},
+			}
+			store = NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+			Expect(setObjectMap("s3", brtypes.SnapList{snap})).To(Equal(1))
+		})
+
+		AfterEach(func() {
+			resetObjectMap()
+		})
+
+		It("should return the size of the snapshot object", func() {
+			size, err := SnapshotSize(store, *snap)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(size).To(Equal(int64(len(generateContentsForSnapshot(snap)))))
+		})
+
+		It("should fail for a snapshot which doesn't exist", func() {
+			snap.SnapName += "-missing"
+			_, err := SnapshotSize(store, *snap)
+			Expect(err).Should(HaveOccurred())
+		})
+
+		It("should list the snapshots along with the size of their objects", func() {
+			snapList, err := store.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(snapList).To(HaveLen(1))
+			Expect(snapList[0].SizeBytes).To(Equal(int64(len(generateContentsForSnapshot(snap)))))
+		})
+	})
+
+	Context("with the local snapstore", func() {
+		It("should list the snapshots along with the size of their files", func() {
+			snap.Prefix = path.Join(GinkgoT().TempDir(), prefixV2)
+			store, err := NewLocalSnapStore(snap.Prefix)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(store.Save(*snap, io.NopCloser(strings.NewReader("snapshot")))).To(Succeed())
+
+			snapList, err := store.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(snapList).To(HaveLen(1))
+			Expect(snapList[0].SizeBytes).To(Equal(int64(len("snapshot"))))
+		})
+
+		It("should return the size of the snapshot file through a date partitioned snapstore", func() {
+			snap.Prefix = path.Join(GinkgoT().TempDir(), prefixV2)
+			store, err := NewLocalSnapStore(snap.Prefix)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(store.Save(*snap, io.NopCloser(strings.NewReader("snapshot")))).To(Succeed())
+
+			size, err := SnapshotSize(NewDatePartitionedSnapStore(store), *snap)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(size).To(Equal(int64(len("snapshot"))))
+		})
+	})
+
+	Context("with a snapstore which doesn't support returning the size of the snapshots", func() {
+		It("should fail", func() {
+			_, err := SnapshotSize(NewFailedSnapStore(), *snap)
+			Expect(err).Should(MatchError(ContainSubstring("snapstore does not support returning the size of snapshots")))
+		})
+	})
+})
+
+var _ = Describe("Checking the presence of snapshots", func() {
+	var snap *brtypes.Snapshot
+
+	BeforeEach(func() {
+		snap = &brtypes.Snapshot{
+			CreatedOn:     time.Now().UTC(),
+			StartRevision: 0,
+			LastRevision:  2088,
+			Kind:          brtypes.SnapshotKindFull,
+			Prefix:        prefixV2,
+		}
+		snap.GenerateSnapshotName()
+	})
+
+	Context("with the mock S3 snapstore", func() {
+		var store brtypes.SnapStore
+
+		BeforeEach(func() {
+			resetObjectMap()
+			client := &mockS3Client{
+				objects:          objectMap,
+				prefix:           prefixV2,
+				multiPartUploads: map[string]*[][]byte{},
+				// the snapshot object is checked on its own, without listing the snapstore
+				listObjectsErr: fmt.Errorf("listing is not expected"),
+			}
+			store = NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+			Expect(setObjectMap("s3", brtypes.SnapList{snap})).To(Equal(1))
+		})
+
+		AfterEach(func() {
+			resetObjectMap()
+		})
+
+		It("should return whether the snapshot object exists", func() {
+			exists, err := SnapshotExists(store, *snap)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(exists).To(BeTrue())
+
+			snap.SnapName += "-missing"
+			exists, err = SnapshotExists(store, *snap)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(exists).To(BeFalse())
+		})
+
+		It("should return whether the object of a snapshot taken in-process exists", func() {
+			// a snapshot taken in-process has no prefix, as it hasn't been listed
+			snap.Prefix = ""
+			exists, err := SnapshotExists(store, *snap)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(exists).To(BeTrue())
+		})
+	})
+
+	Context("with the local snapstore", func() {
+		It("should return whether the snapshot file exists through a date partitioned snapstore", func() {
+			snap.Prefix = path.Join(GinkgoT().TempDir(), prefixV2)
+			store, err := NewLocalSnapStore(snap.Prefix)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(store.Save(*snap, io.NopCloser(strings.NewReader("snapshot")))).To(Succeed())
+
+			exists, err := SnapshotExists(NewDatePartitionedSnapStore(store), *snap)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(exists).To(BeTrue())
+
+			Expect(store.Delete(*snap)).To(Succeed())
+			exists, err = SnapshotExists(NewDatePartitionedSnapStore(store), *snap)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(exists).To(BeFalse())
+		})
+
+		It("should create the snapshots of a date partitioned snapstore in the partition they are saved to", func() {
+			prefix := path.Join(GinkgoT().TempDir(), prefixV2)
+			localStore, err := NewLocalSnapStore(prefix)
+			Expect(err).ShouldNot(HaveOccurred())
+			store := NewDatePartitionedSnapStore(localStore)
+			snap := NewSnapshotForStore(store, brtypes.SnapshotKindDelta, 1, 10, "", false)
+			Expect(snap.SnapDir).To(Equal(GetDatePartition(snap.CreatedOn)))
+			Expect(store.Save(*snap, io.NopCloser(strings.NewReader("snapshot")))).To(Succeed())
+
+			snapList, err := store.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(snapList).To(HaveLen(1))
+			Expect(snapList[0].SnapDir).To(Equal(snap.SnapDir))
+			Expect(NewSnapshotForStore(localStore, brtypes.SnapshotKindDelta, 1, 10, "", false).SnapDir).To(BeEmpty())
+		})
+
+		It("should return whether the file of a snapshot taken in-process exists in its date partition", func() {
+			prefix := path.Join(GinkgoT().TempDir(), prefixV2)
+			localStore, err := NewLocalSnapStore(prefix)
+			Expect(err).ShouldNot(HaveOccurred())
+			store := NewDatePartitionedSnapStore(localStore)
+			// a snapshot taken in-process has neither a prefix nor the partition it was saved to
+			snap.Prefix = ""
+			Expect(store.Save(*snap, io.NopCloser(strings.NewReader("snapshot")))).To(Succeed())
+			Expect(path.Join(prefix, GetDatePartition(snap.CreatedOn), snap.SnapName)).To(BeARegularFile())
+
+			exists, err := SnapshotExists(store, *snap)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(exists).To(BeTrue())
+
+			snap.SnapName += "-missing"
+			exists, err = SnapshotExists(store, *snap)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(exists).To(BeFalse())
+		})
+	})
+})
+
+var _ = Describe("Saving the chain manifest", func() {
+	var fullSnap, deltaSnap *brtypes.Snapshot
+
+	BeforeEach(func() {
+		now := time.Now().UTC()
+		fullSnap = &brtypes.Snapshot{
+			CreatedOn:     now.Add(-time.Minute),
+			StartRevision: 0,
+			LastRevision:  100,
+			Kind:          brtypes.SnapshotKindFull,
+		}
+		fullSnap.GenerateSnapshotName()
+		deltaSnap = &brtypes.Snapshot{
+			CreatedOn:     now,
+			StartRevision: 101,
+			LastRevision:  150,
+			Kind:          brtypes.SnapshotKindDelta,
+		}
+		deltaSnap.GenerateSnapshotName()
+	})
+
+	// newChainManifest returns the chain manifest of the snapshots as recorded by the snapshotter, without their
+	// prefix and object size.
+	newChainManifest := func() *brtypes.ChainManifest {
+		full, delta := *fullSnap, *deltaSnap
+		full.Prefix, delta.Prefix = "", ""
+		return &brtypes.ChainManifest{
+			UpdatedOn:      time.Now().UTC(),
+			LatestRevision: delta.LastRevision,
+			FullSnapshot:   &brtypes.ChainManifestSnapshot{Snapshot: full},
+			DeltaSnapshots: []*brtypes.ChainManifestSnapshot{{Snapshot: delta}},
+		}
+	}
+
+	Context("with the mock S3 snapstore", func() {
+		var store brtypes.SnapStore
+
+		BeforeEach(func() {
+			resetObjectMap()
+			client := &mockS3Client{
+				objects:          objectMap,
+				prefix:           prefixV2,
+				multiPartUploads: map[string]*[][]byte{},
+			}
+			store = NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+			fullSnap.Prefix, deltaSnap.Prefix = prefixV2, prefixV2
+			Expect(setObjectMap("s3", brtypes.SnapList{fullSnap, deltaSnap})).To(Equal(2))
+		})
+
+		AfterEach(func() {
+			resetObjectMap()
+		})
+
+		It("should return no chain manifest if none was saved", func() {
+			manifest, err := FetchChainManifest(store)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(manifest).To(BeNil())
+		})
+
+		It("should complete the snapshots with their prefix and object size, and not list the chain manifest as a snapshot", func() {
+			Expect(SaveChainManifest(store, newChainManifest())).To(Succeed())
+
+			manifest, err := FetchChainManifest(store)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(manifest.FullSnapshot.SnapName).To(Equal(fullSnap.SnapName))
+			Expect(manifest.FullSnapshot.Prefix).To(Equal(prefixV2))
+			Expect(manifest.FullSnapshot.ObjectSizeBytes).To(Equal(int64(len(generateContentsForSnapshot(fullSnap)))))
+			Expect(manifest.DeltaSnapshots).To(HaveLen(1))
+			Expect(manifest.DeltaSnapshots[0].SnapName).To(Equal(deltaSnap.SnapName))
+			Expect(manifest.DeltaSnapshots[0].LastRevision).To(Equal(int64(150)))
+			Expect(manifest.DeltaSnapshots[0].ObjectSizeBytes).To(Equal(int64(len(generateContentsForSnapshot(deltaSnap)))))
+			Expect(VerifyChainManifest(store, manifest)).To(Succeed())
+
+			snapList, err := store.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(snapList).To(HaveLen(2))
+		})
+
+		It("should fail the verification of a chain manifest which drifted from the snapshots in the snapstore", func() {
+			Expect(SaveChainManifest(store, newChainManifest())).To(Succeed())
+			manifest, err := FetchChainManifest(store)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			overwritten := []byte("overwritten")
+			objectMap[path.Join(deltaSnap.Prefix, deltaSnap.SnapDir, deltaSnap.SnapName)] = &overwritten
+			Expect(VerifyChainManifest(store, manifest)).Should(MatchError(ContainSubstring("has size 11 in snapstore")))
+
+			Expect(store.Delete(*deltaSnap)).To(Succeed())
+			Expect(VerifyChainManifest(store, manifest)).Should(MatchError(ContainSubstring("failed to get size of snapshot")))
+		})
+
+		It("should fail the verification of a chain manifest which misses snapshots up to its latest revision", func() {
+			Expect(SaveChainManifest(store, newChainManifest())).To(Succeed())
+			manifest, err := FetchChainManifest(store)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			// a delta snapshot taken after the ones listed in the manifest
+			manifest.LatestRevision = 200
+			Expect(VerifyChainManifest(store, manifest)).Should(MatchError(ContainSubstring("end at revision 150 instead of its latest revision 200")))
+
+			// a delta snapshot dropped from the middle of the chain
+			manifest.LatestRevision = 150
+			manifest.DeltaSnapshots[0].StartRevision = 121
+			Expect(VerifyChainManifest(store, manifest)).Should(MatchError(ContainSubstring("misses the snapshots of revisions 101 to 120")))
+
+			// a chain manifest saved without its latest revision
+			manifest.LatestRevision = 0
+			Expect(VerifyChainManifest(store, manifest)).Should(MatchError(ContainSubstring("has no latest revision")))
+		})
+
+		It("should delete the chain manifest", func() {
+			Expect(SaveChainManifest(store, newChainManifest())).To(Succeed())
+			Expect(DeleteChainManifest(store)).To(Succeed())
+			manifest, err := FetchChainManifest(store)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(manifest).To(BeNil())
+
+			// deleting a chain manifest which doesn't exist succeeds
+			Expect(DeleteChainManifest(store)).To(Succeed())
+		})
+	})
+
+	Context("with the local snapstore", func() {
+		It("should save the chain manifest of the snapshots saved to a date partitioned snapstore", func() {
+			prefix := path.Join(GinkgoT().TempDir(), prefixV2)
+			localStore, err := NewLocalSnapStore(prefix)
+			Expect(err).ShouldNot(HaveOccurred())
+			store := NewDatePartitionedSnapStore(localStore)
+			Expect(store.Save(*fullSnap, io.NopCloser(strings.NewReader("full snapshot")))).To(Succeed())
+			Expect(store.Save(*deltaSnap, io.NopCloser(strings.NewReader("delta snapshot")))).To(Succeed())
+
+			Expect(SaveChainManifest(store, newChainManifest())).To(Succeed())
+			manifest, err := FetchChainManifest(store)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(manifest.FullSnapshot.SnapDir).To(Equal(GetDatePartition(fullSnap.CreatedOn)))
+			Expect(manifest.FullSnapshot.Prefix).To(Equal(prefix))
+			Expect(manifest.FullSnapshot.ObjectSizeBytes).To(Equal(int64(len("full snapshot"))))
+			Expect(manifest.DeltaSnapshots[0].ObjectSizeBytes).To(Equal(int64(len("delta snapshot"))))
+			Expect(VerifyChainManifest(store, manifest)).To(Succeed())
+
+			snapList, err := store.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(snapList).To(HaveLen(2))
+			// the temporary file of the chain manifest is renamed over it
+			Expect(filepath.Glob(path.Join(prefix, brtypes.ChainManifestName+"*"))).To(ConsistOf(path.Join(prefix, brtypes.ChainManifestName)))
+		})
+	})
+
+	Context("with a snapstore which doesn't support chain manifests", func() {
+		It("should fail", func() {
+			Expect(SaveChainManifest(NewFailedSnapStore(), newChainManifest())).Should(MatchError(ContainSubstring("snapstore does not support chain manifests")))
+		})
+	})
+})
+
+var _ = Describe("Saving the compression dictionaries", func() {
+	var (
+		deltaSnap  *brtypes.Snapshot
+		dictionary = []byte("compression dictionary")
+	)
+
+	BeforeEach(func() {
+		deltaSnap = &brtypes.Snapshot{
+			CreatedOn:     time.Now().UTC(),
+			StartRevision: 101,
+			LastRevision:  150,
+			Kind:          brtypes.SnapshotKindDelta,
+		}
+		deltaSnap.GenerateSnapshotName()
+	})
+
+	// expectCompressionDictionaries saves the compression dictionary, and expects it to be fetched, listed apart
+	// from the snapshots, and deleted.
+	expectCompressionDictionaries := func(store brtypes.SnapStore) {
+		id, err := SaveCompressionDictionary(store, dictionary)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(id).To(Equal(compressor.DictionaryID(dictionary)))
+
+		fetchDictionary := NewCompressionDictionaryFetcher(store)
+		Expect(fetchDictionary(id)).To(Equal(dictionary))
+		_, err = fetchDictionary(id + 1)
+		Expect(err).Should(HaveOccurred())
+
+		dictionaries, err := ListCompressionDictionaries(store)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(dictionaries).To(HaveLen(1))
+		Expect(dictionaries[0].ID).To(Equal(id))
+		snapList, err := store.List()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(snapList).To(HaveLen(1))
+		Expect(snapList[0].SnapName).To(Equal(deltaSnap.SnapName))
+
+		Expect(DeleteCompressionDictionary(store, id)).To(Succeed())
+		Expect(ListCompressionDictionaries(store)).To(BeEmpty())
+		// a fetched dictionary is still served to the fetcher
+		Expect(fetchDictionary(id)).To(Equal(dictionary))
+	}
+
+	Context("with the mock S3 snapstore", func() {
+		It("should save, fetch, list and delete the compression dictionaries apart from the snapshots", func() {
+			resetObjectMap()
+			DeferCleanup(resetObjectMap)
+			client := &mockS3Client{
+				objects:          objectMap,
+				prefix:           prefixV2,
+				multiPartUploads: map[string]*[][]byte{},
+			}
+			store := NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+			deltaSnap.Prefix = prefixV2
+			Expect(setObjectMap("s3", brtypes.SnapList{deltaSnap})).To(Equal(1))
+
+			expectCompressionDictionaries(store)
+		})
+	})
+
+	Context("with the local snapstore", func() {
+		It("should save, fetch, list and delete the compression dictionaries of a date partitioned snapstore apart from the snapshots", func() {
+			prefix := path.Join(GinkgoT().TempDir(), prefixV2)
+			localStore, err := NewLocalSnapStore(prefix)
+			Expect(err).ShouldNot(HaveOccurred())
+			store := NewDatePartitionedSnapStore(localStore)
+			Expect(store.Save(*deltaSnap, io.NopCloser(strings.NewReader("delta snapshot")))).To(Succeed())
+
+			expectCompressionDictionaries(store)
+		})
+	})
+
+	Context("when copying the compression dictionaries", func() {
+		It("should copy the compression dictionaries missing in the destination snapstore", func() {
+			source, err := NewLocalSnapStore(path.Join(GinkgoT().TempDir(), prefixV2))
+			Expect(err).ShouldNot(HaveOccurred())
+			destination, err := NewLocalSnapStore(path.Join(GinkgoT().TempDir(), prefixV2))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(CopyCompressionDictionaries(source, destination)).To(Succeed())
+			Expect(ListCompressionDictionaries(destination)).To(BeEmpty())
+
+			id, err := SaveCompressionDictionary(source, dictionary)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(CopyCompressionDictionaries(source, destination)).To(Succeed())
+			Expect(NewCompressionDictionaryFetcher(destination)(id)).To(Equal(dictionary))
+
+			Expect(CopyCompressionDictionaries(source, NewFailedSnapStore())).Should(MatchError(ContainSubstring("destination snapstore does not support compression dictionaries")))
+		})
+	})
+
+	Context("with a snapstore which doesn't support compression dictionaries", func() {
+		It("should fail", func() {
+			_, err := SaveCompressionDictionary(NewFailedSnapStore(), dictionary)
+			Expect(err).Should(MatchError(ContainSubstring("snapstore does not support compression dictionaries")))
+			Expect(CopyCompressionDictionaries(NewFailedSnapStore(), NewFailedSnapStore())).To(Succeed())
+		})
+	})
+})
+
+var _ = Describe("Saving the cluster metadata", func() {
+	var (
+		fullSnap *brtypes.Snapshot
+		metadata *brtypes.ClusterMetadata
+	)
+
+	BeforeEach(func() {
+		fullSnap = &brtypes.Snapshot{
+			CreatedOn:    time.Now().UTC(),
+			LastRevision: 100,
+			Kind:         brtypes.SnapshotKindFull,
+		}
+		fullSnap.GenerateSnapshotName()
+		metadata = &brtypes.ClusterMetadata{
+			ClusterID: 0xcdf818194e3a8c32,
+			Members: []brtypes.ClusterMember{
+				{ID: 0x8e9e05c52164694d, Name: "etcd-main-0", PeerURLs: []string{"https://etcd-main-0.etcd-main-peer:2380"}, ClientURLs: []string{"https://etcd-main-0.etcd-main-client:2379"}},
+				{ID: 0x91bc3c398fb3c146, Name: "etcd-main-1", PeerURLs: []string{"https://etcd-main-1.etcd-main-peer:2380"}},
+				{ID: 0xfd422379fda50e48, Name: "etcd-main-2", PeerURLs: []string{"https://etcd-main-2.etcd-main-peer:2380"}, IsLearner: true},
+			},
+		}
+	})
+
+	// expectClusterMetadata saves the cluster metadata along with the full snapshot, and expects it to be fetched,
+	// listed apart from the snapshots, and deleted along with the full snapshot.
+	expectClusterMetadata := func(store brtypes.SnapStore) {
+		Expect(FetchClusterMetadata(store, fullSnap)).To(BeNil())
+		Expect(SaveClusterMetadata(store, fullSnap, metadata)).To(Succeed())
+
+		snapList, err := store.List()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(snapList).To(HaveLen(1))
+		Expect(snapList[0].SnapName).To(Equal(fullSnap.SnapName))
+		// the listed snapshot knows its prefix and partition, unlike the snapshot taken by the snapshotter
+		Expect(FetchClusterMetadata(store, snapList[0])).To(Equal(metadata))
+		Expect(FetchClusterMetadata(store, fullSnap)).To(Equal(metadata))
+		Expect(metadata.Member("etcd-main-1").ID).To(Equal(uint64(0x91bc3c398fb3c146)))
+		Expect(metadata.Member("etcd-main-3")).To(BeNil())
+
+		Expect(store.Delete(*snapList[0])).To(Succeed())
+		Expect(FetchClusterMetadata(store, snapList[0])).To(BeNil())
+	}
+
+	Context("with the mock S3 snapstore", func() {
+		It("should save and fetch the cluster metadata along with the full snapshot", func() {
+			resetObjectMap()
+			DeferCleanup(resetObjectMap)
+			client := &mockS3Client{
+				objects:          objectMap,
+				prefix:           prefixV2,
+				multiPartUploads: map[string]*[][]byte{},
+			}
+			store := NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+			fullSnap.Prefix = prefixV2
+			Expect(setObjectMap("s3", brtypes.SnapList{fullSnap})).To(Equal(1))
+
+			expectClusterMetadata(store)
+			Expect(objectMap).To(BeEmpty())
+		})
+	})
+
+	Context("with the local snapstore", func() {
+		It("should save and fetch the cluster metadata along with the full snapshot of a date partitioned snapstore", func() {
+			prefix := path.Join(GinkgoT().TempDir(), prefixV2)
+			localStore, err := NewLocalSnapStore(prefix)
+			Expect(err).ShouldNot(HaveOccurred())
+			store := NewDatePartitionedSnapStore(localStore)
+			Expect(store.Save(*fullSnap, io.NopCloser(strings.NewReader("full snapshot")))).To(Succeed())
+
+			expectClusterMetadata(store)
+			var files []string
+			Expect(filepath.WalkDir(prefix, func(filePath string, d fs.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					files = append(files, filePath)
+				}
+				return err
+			})).To(Succeed())
+			Expect(files).To(BeEmpty())
+		})
+	})
+
+	Context("when copying the snapshots to another snapstore", func() {
+		It("should copy the cluster metadata along with the full snapshot", func() {
+			source, err := NewLocalSnapStore(path.Join(GinkgoT().TempDir(), prefixV2))
+			Expect(err).ShouldNot(HaveOccurred())
+			destination, err := NewLocalSnapStore(path.Join(GinkgoT().TempDir(), prefixV2))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(source.Save(*fullSnap, io.NopCloser(strings.NewReader("full snapshot")))).To(Succeed())
+
+			Expect(CopyClusterMetadata(source, destination, fullSnap)).To(Succeed())
+			Expect(FetchClusterMetadata(destination, fullSnap)).To(BeNil())
+
+			Expect(SaveClusterMetadata(source, fullSnap, metadata)).To(Succeed())
+			Expect(CopyClusterMetadata(source, destination, fullSnap)).To(Succeed())
+			Expect(FetchClusterMetadata(destination, fullSnap)).To(Equal(metadata))
+		})
+	})
+
+	Context("with a snapstore which doesn't support cluster metadata", func() {
+		It("should fail", func() {
+			Expect(SaveClusterMetadata(NewFailedSnapStore(), fullSnap, metadata)).Should(MatchError(ContainSubstring("snapstore does not support cluster metadata")))
+		})
+	})
+})
+
+var _ = Describe("Verifying the snapstore at startup", func() {
+	var (
+		client *mockS3Client
+		config *brtypes.SnapstoreConfig
+	)
+
+	BeforeEach(func() {
+		resetObjectMap()
+		DeferCleanup(resetObjectMap)
+		client = &mockS3Client{
+			objects:          objectMap,
+			prefix:           prefixV2,
+			multiPartUploads: map[string]*[][]byte{},
+		}
+		config = &brtypes.SnapstoreConfig{Provider: brtypes.SnapstoreProviderS3, Container: bucket}
+	})
+
+	Context("with the mock S3 snapstore", func() {
+		It("should succeed if the bucket exists and is writable, without leaving any object behind", func() {
+			store := NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+			Expect(VerifySnapstore(store, config)).To(Succeed())
+			Expect(objectMap).To(BeEmpty())
+		})
+
+		It("should fail if the bucket does not exist", func() {
+			client.headBucketErr = awserr.New("NotFound", "Not Found", nil)
+			store := NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+			Expect(VerifySnapstore(store, config)).Should(MatchError(ContainSubstring("bucket mock-bucket does not exist")))
+		})
+
+		It("should fail if the access to the bucket is denied", func() {
+			client.headBucketErr = awserr.New("Forbidden", "Forbidden", nil)
+			store := NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+			Expect(VerifySnapstore(store, config)).Should(MatchError(ContainSubstring("access to bucket mock-bucket is denied")))
+		})
+
+		It("should fail if the bucket is not writable, unless it is the source snapstore", func() {
+			client.putObjectErr = awserr.New("AccessDenied", "Access Denied", nil)
+			store := NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+			Expect(VerifySnapstore(store, config)).Should(MatchError(ContainSubstring("failed to write to bucket mock-bucket")))
+
+			config.IsSource = true
+			Expect(VerifySnapstore(store, config)).To(Succeed())
+		})
+
+		It("should not verify the bucket if the verification is skipped", func() {
+			client.headBucketErr = awserr.New("NotFound", "Not Found", nil)
+			config.SkipVerification = true
+			store := NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+			Expect(VerifySnapstore(store, config)).To(Succeed())
+		})
+	})
+
+	Context("with the local snapstore", func() {
+		It("should succeed if the directory exists and is writable, and fail once it was removed", func() {
+			dir := GinkgoT().TempDir()
+			config.Provider = brtypes.SnapstoreProviderLocal
+			config.Container = path.Join(dir, "backup")
+			localStore, err := NewLocalSnapStore(path.Join(config.Container, prefixV2))
+			Expect(err).ShouldNot(HaveOccurred())
+			store := NewDatePartitionedSnapStore(localStore)
+			Expect(VerifySnapstore(store, config)).To(Succeed())
+			Expect(os.ReadDir(path.Join(config.Container, prefixV2))).To(BeEmpty())
+
+			Expect(os.RemoveAll(config.Container)).To(Succeed())
+			Expect(VerifySnapstore(store, config)).Should(MatchError(ContainSubstring("no such file or directory")))
+		})
+	})
+
+	Context("with the mock GCS, ABS, Swift and OSS snapstores", func() {
+		DescribeTable("should succeed if the bucket exists and is writable, without leaving any object behind",
+			func(provider string, newStore func() brtypes.SnapStore) {
+				config.Provider = provider
+				Expect(VerifySnapstore(newStore(), config)).To(Succeed())
+				Expect(objectMap).To(BeEmpty())
+			},
+			Entry("GCS", brtypes.SnapstoreProviderGCS, func() brtypes.SnapStore {
+				return NewGCSSnapStoreFromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, "", &mockGCSClient{
+					objects: objectMap,
+					prefix:  prefixV2,
+				})
+			}),
+			Entry("ABS", brtypes.SnapstoreProviderABS, newFakeABSSnapstore),
+			Entry("swift", brtypes.SnapstoreProviderSwift, func() brtypes.SnapStore {
+				return NewSwiftSnapstoreFromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, fake.ServiceClient())
+			}),
+			Entry("OSS", brtypes.SnapstoreProviderOSS, func() brtypes.SnapStore {
+				return NewOSSFromBucket(prefixV2, "/tmp", 5, brtypes.MinChunkSize, &mockOSSBucket{
+					objects:          objectMap,
+					prefix:           prefixV2,
+					multiPartUploads: map[string]*[][]byte{},
+					bucketName:       bucket,
+				})
+			}),
+		)
+
+		It("should fail if the OSS bucket does not exist", func() {
+			config.Provider = brtypes.SnapstoreProviderOSS
+			store := NewOSSFromBucket(prefixV2, "/tmp", 5, brtypes.MinChunkSize, &mockOSSBucket{
+				objects:        objectMap,
+				prefix:         prefixV2,
+				bucketName:     bucket,
+				listObjectsErr: oss.ServiceError{Code: "NoSuchBucket", StatusCode: http.StatusNotFound},
+			})
+			err := VerifySnapstore(store, config)
+			Expect(err).Should(MatchError(ContainSubstring("bucket does not exist")))
+			Expect(ClassifyCredentialsError(err)).To(Equal(CredentialsFailureBucketMissing))
+		})
+
+		It("should fail if the OSS bucket is not writable, unless it is the source snapstore", func() {
+			config.Provider = brtypes.SnapstoreProviderOSS
+			store := NewOSSFromBucket(prefixV2, "/tmp", 5, brtypes.MinChunkSize, &mockOSSBucket{
+				objects:      objectMap,
+				prefix:       prefixV2,
+				bucketName:   bucket,
+				putObjectErr: oss.ServiceError{Code: "AccessDenied", StatusCode: http.StatusForbidden},
+			})
+			Expect(VerifySnapstore(store, config)).Should(MatchError(ContainSubstring("failed to write to bucket")))
+
+			config.IsSource = true
+			Expect(VerifySnapstore(store, config)).To(Succeed())
+		})
+	})
+
+	Context("with a snapstore which doesn't support the verification", func() {
+		It("should succeed", func() {
+			Expect(VerifySnapstore(NewFailedSnapStore(), config)).To(Succeed())
+		})
+	})
+})
+
+var _ = Describe("Verifying the credentials of the snapstore", func() {
+	var client *mockS3Client
+
+	BeforeEach(func() {
+		resetObjectMap()
+		DeferCleanup(resetObjectMap)
+		client = &mockS3Client{
+			objects:          objectMap,
+			prefix:           prefixV2,
+			multiPartUploads: map[string]*[][]byte{},
+		}
+	})
+
+	Context("with the mock S3 snapstore", func() {
+		It("should pass if the bucket can be accessed, without writing or listing any object", func() {
+			client.listObjectsErr = fmt.Errorf("listing is not expected")
+			store := NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+			result := VerifySnapstoreCredentials(store)
+			Expect(result.Passed).To(BeTrue())
+			Expect(result.Failure).To(BeEmpty())
+			Expect(result.Err).ShouldNot(HaveOccurred())
+			Expect(objectMap).To(BeEmpty())
+		})
+
+		It("should fail with an auth error if the credentials are rejected", func() {
+			client.headBucketErr = awserr.New("InvalidAccessKeyId", "The AWS Access Key Id you provided does not exist in our records.", nil)
+			store := NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+			result := VerifySnapstoreCredentials(store)
+			Expect(result.Passed).To(BeFalse())
+			Expect(result.Failure).To(Equal(CredentialsFailureAuth))
+			Expect(result.Err).Should(MatchError(ContainSubstring("InvalidAccessKeyId")))
+		})
+
+		It("should fail with an auth error if the access is denied", func() {
+			client.headBucketErr = awserr.NewRequestFailure(awserr.New("AccessDenied", "Access Denied", nil), http.StatusForbidden, "request-id")
+			store := NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+			Expect(VerifySnapstoreCredentials(store).Failure).To(Equal(CredentialsFailureAuth))
+		})
+
+		It("should fail with a bucket missing error if the bucket does not exist", func() {
+			client.headBucketErr = awserr.NewRequestFailure(awserr.New(s3.ErrCodeNoSuchBucket, "The specified bucket does not exist", nil), http.StatusNotFound, "request-id")
+			store := NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+			result := VerifySnapstoreCredentials(store)
+			Expect(result.Passed).To(BeFalse())
+			Expect(result.Failure).To(Equal(CredentialsFailureBucketMissing))
+		})
+
+		It("should fail with a network error if the storage provider can't be reached", func() {
+			dialErr := &url.Error{Op: "Get", URL: "https://mock-bucket.s3.amazonaws.com", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}
+			client.headBucketErr = awserr.New(request.ErrCodeRequestError, "send request failed", dialErr)
+			store := NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+			result := VerifySnapstoreCredentials(store)
+			Expect(result.Passed).To(BeFalse())
+			Expect(result.Failure).To(Equal(CredentialsFailureNetwork))
+		})
+
+		It("should fail with an unknown error if the error can't be classified", func() {
+			client.headBucketErr = awserr.New("InternalError", "We encountered an internal error. Please try again.", nil)
+			store := NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+			Expect(VerifySnapstoreCredentials(store).Failure).To(Equal(CredentialsFailureUnknown))
+		})
+	})
+
+	Context("with a config of the local snapstore", func() {
+		It("should pass", func() {
+			config := &brtypes.SnapstoreConfig{
+				Provider:  brtypes.SnapstoreProviderLocal,
+				Container: "../../../test/output/credentials",
+				TempDir:   GinkgoT().TempDir(),
+			}
+			defer os.RemoveAll(config.Container)
+			Expect(VerifyCredentials(config).Passed).To(BeTrue())
+		})
+	})
+
+	Context("with a local snapstore whose directory does not exist", func() {
+		It("should fail with a bucket missing error", func() {
+			dir := GinkgoT().TempDir()
+			store, err := NewLocalSnapStore(path.Join(dir, prefixV2))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(os.RemoveAll(dir)).To(Succeed())
+
+			result := VerifySnapstoreCredentials(store)
+			Expect(result.Passed).To(BeFalse())
+			Expect(result.Failure).To(Equal(CredentialsFailureBucketMissing))
+		})
+	})
+
+	Context("with a snapstore which can neither be verified nor listed in pages", func() {
+		It("should fail as unsupported instead of listing all the snapshots", func() {
+			result := VerifySnapstoreCredentials(NewFailedSnapStore())
+			Expect(result.Passed).To(BeFalse())
+			Expect(result.Failure).To(Equal(CredentialsFailureUnsupported))
+		})
+	})
+})
+
+var _ = Describe("Moving the snapshots to another prefix", func() {
+	const (
+		oldPrefix = "old-cluster"
+		newPrefix = "new-cluster"
+	)
+	var (
+		snapList brtypes.SnapList
+		now      time.Time
+	)
+
+	BeforeEach(func() {
+		now = time.Now().UTC().Truncate(time.Second)
+		snapList = brtypes.SnapList{
+			{Kind: brtypes.SnapshotKindFull, StartRevision: 0, LastRevision: 100, CreatedOn: now},
+			{Kind: brtypes.SnapshotKindDelta, StartRevision: 101, LastRevision: 200, CreatedOn: now.Add(time.Minute)},
+			{Kind: brtypes.SnapshotKindDelta, StartRevision: 201, LastRevision: 300, CreatedOn: now.Add(2 * time.Minute)},
+		}
+		for _, snap := range snapList {
+			snap.GenerateSnapshotName()
+		}
+	})
+
+	// expectChain checks that the given snapshots are the snapshot chain which was moved.
+	expectChain := func(movedSnapList brtypes.SnapList) {
+		Expect(movedSnapList).To(HaveLen(len(snapList)))
+		for i, snap := range movedSnapList {
+			Expect(snap.SnapName).To(Equal(snapList[i].SnapName))
+			Expect(snap.Kind).To(Equal(snapList[i].Kind))
+			Expect(snap.StartRevision).To(Equal(snapList[i].StartRevision))
+			Expect(snap.LastRevision).To(Equal(snapList[i].LastRevision))
+		}
+	}
+
+	Context("with the mock S3 snapstore", func() {
+		var (
+			store  brtypes.SnapStore
+			client *mockS3Client
+		)
+
+		BeforeEach(func() {
+			resetObjectMap()
+			for _, snap := range snapList {
+				snap.Prefix = path.Join(oldPrefix, prefixV2)
+			}
+			Expect(setObjectMap("s3", snapList)).To(Equal(len(snapList)))
+
+			fullSnapKey := path.Join(oldPrefix, prefixV2, snapList[0].SnapName)
+			client = &mockS3Client{
+				objects:          objectMap,
+				prefix:           path.Join(oldPrefix, prefixV2),
+				multiPartUploads: map[string]*[][]byte{},
+				tags:             map[string][]*s3.Tag{fullSnapKey: {{Key: aws.String(brtypes.SnapshotExcludeTag), Value: aws.String("true")}}},
+				metadata:         map[string]map[string]*string{fullSnapKey: {"owner": aws.String("etcd")}},
+			}
+			store = NewS3FromClient(bucket, path.Join(oldPrefix, prefixV2), "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+		})
+
+		AfterEach(func() {
+			resetObjectMap()
+		})
+
+		It("should move all the objects to the new prefix along with their metadata and tags", func() {
+			contents := map[string][]byte{}
+			for _, snap := range snapList {
+				contents[snap.SnapName] = *objectMap[path.Join(snap.Prefix, snap.SnapName)]
+			}
+
+			Expect(MovePrefix(store, oldPrefix, newPrefix)).To(Succeed())
+
+			Expect(objectMap).To(HaveLen(len(snapList)))
+			for key, content := range objectMap {
+				Expect(key).To(HavePrefix(path.Join(newPrefix, prefixV2) + "/"))
+				Expect(*content).To(Equal(contents[path.Base(key)]))
+			}
+			fullSnapKey := path.Join(newPrefix, prefixV2, snapList[0].SnapName)
+			Expect(client.metadata[fullSnapKey]).To(HaveKeyWithValue("owner", PointTo(Equal("etcd"))))
+			Expect(client.tags[fullSnapKey]).To(HaveLen(1))
+
+			newStore := NewS3FromClient(bucket, path.Join(newPrefix, prefixV2), "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+			movedSnapList, err := newStore.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			expectChain(movedSnapList)
+			retained, err := IsSnapshotRetained(newStore, *movedSnapList[0])
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(retained).To(BeTrue())
+		})
+
+		It("should keep the original objects when only copying them", func() {
+			copiedSnapList, err := CopyPrefix(store, oldPrefix, newPrefix)
+			Expect(err).ShouldNot(HaveOccurred())
+			expectChain(copiedSnapList)
+			Expect(objectMap).To(HaveLen(2 * len(snapList)))
+
+			snaps, err := store.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			expectChain(snaps)
+		})
+
+		It("should move the compression dictionaries along with the snapshots", func() {
+			dictionary := []byte("compression dictionary")
+			id, err := SaveCompressionDictionary(store, dictionary)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(MovePrefix(store, oldPrefix, newPrefix)).To(Succeed())
+
+			Expect(objectMap).To(HaveLen(len(snapList) + 1))
+			dictionaries, err := ListCompressionDictionaries(store)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(dictionaries).To(BeEmpty())
+			newStore := NewS3FromClient(bucket, path.Join(newPrefix, prefixV2), "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+			Expect(newStore.FetchCompressionDictionary(id)).To(Equal(dictionary))
+		})
+
+		It("should copy the objects above the limit of a single copy part by part along with their metadata and tags", func() {
+			defer SetMaxS3SingleCopyObjectSize(1)()
+			contents := map[string][]byte{}
+			for _, snap := range snapList {
+				contents[snap.SnapName] = *objectMap[path.Join(snap.Prefix, snap.SnapName)]
+			}
+			store = NewS3FromClient(bucket, path.Join(oldPrefix, prefixV2), "/tmp", 5, 4, client, SSECredentials{})
+
+			Expect(MovePrefix(store, oldPrefix, newPrefix)).To(Succeed())
+
+			Expect(client.copiedParts).To(BeNumerically(">", len(snapList)))
+			Expect(client.multiPartUploads).To(BeEmpty())
+			Expect(objectMap).To(HaveLen(len(snapList)))
+			for key, content := range objectMap {
+				Expect(key).To(HavePrefix(path.Join(newPrefix, prefixV2) + "/"))
+				Expect(*content).To(Equal(contents[path.Base(key)]))
+			}
+			fullSnapKey := path.Join(newPrefix, prefixV2, snapList[0].SnapName)
+			Expect(client.metadata[fullSnapKey]).To(HaveKeyWithValue("owner", PointTo(Equal("etcd"))))
+			Expect(client.tags[fullSnapKey]).To(ConsistOf(PointTo(MatchFields(IgnoreExtras, Fields{
+				"Key":   PointTo(Equal(brtypes.SnapshotExcludeTag)),
+				"Value": PointTo(Equal("true")),
+			}))))
+		})
+
+		It("should move the restore lock, and delete the chain manifest which names the snapshots under the old prefix", func() {
+			manifest := &brtypes.ChainManifest{
+				LatestRevision: snapList[2].LastRevision,
+				FullSnapshot:   &brtypes.ChainManifestSnapshot{Snapshot: *snapList[0]},
+				DeltaSnapshots: []*brtypes.ChainManifestSnapshot{{Snapshot: *snapList[1]}, {Snapshot: *snapList[2]}},
+			}
+			Expect(SaveChainManifest(store, manifest)).To(Succeed())
+			lock, err := AcquireRestoreLock(store, "restorer", "host-0", time.Hour)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(MovePrefix(store, oldPrefix, newPrefix)).To(Succeed())
+
+			Expect(objectMap).To(HaveLen(len(snapList) + 1))
+			Expect(FetchChainManifest(store)).To(BeNil())
+			Expect(FetchRestoreLock(store)).To(BeNil())
+			newStore := NewS3FromClient(bucket, path.Join(newPrefix, prefixV2), "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+			Expect(FetchChainManifest(newStore)).To(BeNil())
+			movedLock, err := FetchRestoreLock(newStore)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(movedLock.Holder).To(Equal(lock.Holder))
+			movedSnapList, err := newStore.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			expectChain(movedSnapList)
+		})
+
+		It("should move the cluster metadata along with the full snapshot", func() {
+			metadata := &brtypes.ClusterMetadata{ClusterID: 0xcdf818194e3a8c32, Members: []brtypes.ClusterMember{{ID: 0x8e9e05c52164694d, Name: "etcd-main-0"}}}
+			Expect(SaveClusterMetadata(store, snapList[0], metadata)).To(Succeed())
+
+			Expect(MovePrefix(store, oldPrefix, newPrefix)).To(Succeed())
+
+			Expect(objectMap).To(HaveLen(len(snapList) + 1))
+			newStore := NewS3FromClient(bucket, path.Join(newPrefix, prefixV2), "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+			movedSnapList, err := newStore.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			expectChain(movedSnapList)
+			Expect(FetchClusterMetadata(newStore, movedSnapList[0])).To(Equal(metadata))
+		})
+
+		It("should refuse to move the objects to a prefix which already holds snapshots", func() {
+			existing := &brtypes.Snapshot{Kind: brtypes.SnapshotKindFull, LastRevision: 50, CreatedOn: now, Prefix: path.Join(newPrefix, prefixV2)}
+			existing.GenerateSnapshotName()
+			Expect(setObjectMap("s3", brtypes.SnapList{existing})).To(Equal(1))
+
+			Expect(MovePrefix(store, oldPrefix, newPrefix)).NotTo(Succeed())
+			Expect(objectMap).To(HaveLen(len(snapList) + 1))
+		})
+
+		It("should refuse to move the objects to an overlapping prefix", func() {
+			Expect(MovePrefix(store, oldPrefix, path.Join(oldPrefix, "nested"))).NotTo(Succeed())
+			Expect(objectMap).To(HaveLen(len(snapList)))
+		})
+	})
+
+	Context("with the mock GCS snapstore", func() {
+		var (
+			store  brtypes.SnapStore
+			client *mockGCSClient
+		)
+
+		BeforeEach(func() {
+			resetObjectMap()
+			for _, snap := range snapList {
+				snap.Prefix = path.Join(oldPrefix, prefixV2)
+			}
+			Expect(setObjectMap("gcs", snapList)).To(Equal(len(snapList)))
+			client = &mockGCSClient{
+				objects: objectMap,
+				prefix:  path.Join(oldPrefix, prefixV2),
+			}
+			store = NewGCSSnapStoreFromClient(bucket, path.Join(oldPrefix, prefixV2), "/tmp", 5, brtypes.MinChunkSize, "", client)
+			Expect(SetSnapshotRetained(store, *snapList[0], true)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resetObjectMap()
+		})
+
+		It("should move all the objects to the new prefix along with their exclude tags", func() {
+			contents := map[string][]byte{}
+			for _, snap := range snapList {
+				contents[snap.SnapName] = *objectMap[path.Join(snap.Prefix, snap.SnapName)]
+			}
+
+			Expect(MovePrefix(store, oldPrefix, newPrefix)).To(Succeed())
+
+			Expect(objectMap).To(HaveLen(len(snapList)))
+			for key, content := range objectMap {
+				Expect(key).To(HavePrefix(path.Join(newPrefix, prefixV2) + "/"))
+				Expect(*content).To(Equal(contents[path.Base(key)]))
+			}
+			newStore := NewGCSSnapStoreFromClient(bucket, path.Join(newPrefix, prefixV2), "/tmp", 5, brtypes.MinChunkSize, "", client)
+			movedSnapList, err := newStore.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			expectChain(movedSnapList)
+			retained, err := IsSnapshotRetained(newStore, *movedSnapList[0])
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(retained).To(BeTrue())
+		})
+	})
+
+	Context("with the local snapstore", func() {
+		var (
+			store    brtypes.SnapStore
+			dir      string
+			contents map[string]string
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir = GinkgoT().TempDir()
+			store, err = NewLocalSnapStore(path.Join(dir, oldPrefix, prefixV2))
+			Expect(err).ShouldNot(HaveOccurred())
+			contents = map[string]string{}
+			for _, snap := range snapList {
+				contents[snap.SnapName] = generateContentsForSnapshot(snap)
+				Expect(store.Save(*snap, io.NopCloser(strings.NewReader(contents[snap.SnapName])))).To(Succeed())
+				snap.Prefix = path.Join(dir, oldPrefix, prefixV2)
+			}
+			Expect(SetSnapshotRetained(store, *snapList[0], true)).To(Succeed())
+		})
+
+		It("should move all the snapshot files to the new prefix along with their exclude tags", func() {
+			Expect(MovePrefix(store, path.Join(dir, oldPrefix), path.Join(dir, newPrefix))).To(Succeed())
+
+			oldSnapList, err := store.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(oldSnapList).To(BeEmpty())
+
+			newStore, err := NewLocalSnapStore(path.Join(dir, newPrefix, prefixV2))
+			Expect(err).ShouldNot(HaveOccurred())
+			movedSnapList, err := newStore.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			expectChain(movedSnapList)
+			for _, snap := range movedSnapList {
+				rc, err := newStore.Fetch(*snap)
+				Expect(err).ShouldNot(HaveOccurred())
+				content, err := io.ReadAll(rc)
+				Expect(rc.Close()).To(Succeed())
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(string(content)).To(Equal(contents[snap.SnapName]))
+			}
+			retained, err := IsSnapshotRetained(newStore, *movedSnapList[0])
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(retained).To(BeTrue())
+		})
+
+		It("should copy the compression dictionaries along with the snapshots", func() {
+			dictionary := []byte("compression dictionary")
+			id, err := SaveCompressionDictionary(store, dictionary)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			_, err = CopyPrefix(store, path.Join(dir, oldPrefix), path.Join(dir, newPrefix))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			newStore, err := NewLocalSnapStore(path.Join(dir, newPrefix, prefixV2))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(newStore.FetchCompressionDictionary(id)).To(Equal(dictionary))
+			copied, err := newStore.ListCompressionDictionaries()
+			Expect(err).ShouldNot(HaveOccurred())
+			original, err := ListCompressionDictionaries(store)
+			Expect(err).ShouldNot(HaveOccurred())
+			// the garbage collection relies on the time the dictionary was saved on
+			Expect(copied).To(Equal(original))
+		})
+	})
+
+	Context("with a kind prefixed local snapstore", func() {
+		var (
+			store brtypes.SnapStore
+			dir   string
+		)
+
+		// newKindPrefixedStore returns the kind prefixed snapstore saving the snapshots of each kind under the given
+		// prefix followed by the name of the kind.
+		newKindPrefixedStore := func(prefix string) brtypes.SnapStore {
+			mainStore, err := NewLocalSnapStore(path.Join(dir, prefix, "main", prefixV2))
+			Expect(err).ShouldNot(HaveOccurred())
+			fullStore, err := NewLocalSnapStore(path.Join(dir, prefix, "fulls", prefixV2))
+			Expect(err).ShouldNot(HaveOccurred())
+			deltaStore, err := NewLocalSnapStore(path.Join(dir, prefix, "deltas", prefixV2))
+			Expect(err).ShouldNot(HaveOccurred())
+			return NewKindPrefixedSnapStore(mainStore, fullStore, deltaStore)
+		}
+
+		// kindPrefixes returns the prefixes of the kinds of the snapstores created with newKindPrefixedStore, mapped to
+		// the ones of the new prefix.
+		kindPrefixes := func(oldPrefix, newPrefix string) map[string]string {
+			prefixes := map[string]string{}
+			for _, kind := range []string{"main", "fulls", "deltas"} {
+				prefixes[path.Join(dir, oldPrefix, kind)] = path.Join(dir, newPrefix, kind)
+			}
+			return prefixes
+		}
+
+		BeforeEach(func() {
+			dir = GinkgoT().TempDir()
+			store = newKindPrefixedStore(oldPrefix)
+			for _, snap := range snapList {
+				Expect(store.Save(*snap, io.NopCloser(strings.NewReader(generateContentsForSnapshot(snap))))).To(Succeed())
+			}
+			_, err := SaveCompressionDictionary(store, []byte("compression dictionary"))
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("should move the snapshots under the prefixes of all the kinds", func() {
+			Expect(MovePrefixes(store, kindPrefixes(oldPrefix, newPrefix))).To(Succeed())
+
+			oldSnapList, err := store.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(oldSnapList).To(BeEmpty())
+
+			newStore := newKindPrefixedStore(newPrefix)
+			movedSnapList, err := newStore.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			expectChain(movedSnapList)
+			Expect(path.Clean(movedSnapList[0].Prefix)).To(Equal(path.Join(dir, newPrefix, "fulls", prefixV2)))
+			Expect(path.Clean(movedSnapList[1].Prefix)).To(Equal(path.Join(dir, newPrefix, "deltas", prefixV2)))
+			dictionaries, err := ListCompressionDictionaries(newStore)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(dictionaries).To(HaveLen(1))
+		})
+
+		It("should move the snapshots under a prefix holding the prefixes of all the kinds", func() {
+			Expect(MovePrefix(store, path.Join(dir, oldPrefix), path.Join(dir, newPrefix))).To(Succeed())
+
+			movedSnapList, err := newKindPrefixedStore(newPrefix).List()
+			Expect(err).ShouldNot(HaveOccurred())
+			expectChain(movedSnapList)
+		})
+
+		It("should refuse to move the snapshots under the prefixes of only some of the kinds", func() {
+			err := MovePrefix(store, path.Join(dir, oldPrefix, "main"), path.Join(dir, newPrefix, "main"))
+			Expect(err).Should(MatchError(ContainSubstring("would be split")))
+
+			prefixes := kindPrefixes(oldPrefix, newPrefix)
+			delete(prefixes, path.Join(dir, oldPrefix, "deltas"))
+			_, err = CopyPrefixes(store, prefixes)
+			Expect(err).Should(MatchError(ContainSubstring("would be split")))
+
+			snapList, err := store.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			expectChain(snapList)
+			Expect(path.Join(dir, newPrefix)).NotTo(BeAnExistingFile())
+		})
+	})
+
+	Context("with a snapstore which doesn't support copying the snapshots", func() {
+		It("should fail to move the snapshots", func() {
+			Expect(MovePrefix(NewFailedSnapStore(), oldPrefix, newPrefix)).NotTo(Succeed())
+		})
+	})
+})
+
+var _ = Describe("Saving the snapshots to the local snapstore", func() {
+	var (
+		prefix string
+		store  *LocalSnapStore
+	)
+
+	BeforeEach(func() {
+		var err error
+		prefix = path.Join(GinkgoT().TempDir(), prefixV2)
+		store, err = NewLocalSnapStore(prefix)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	newSnapshot := func(kind string, startRevision, lastRevision int64) *brtypes.Snapshot {
+		snap := &brtypes.Snapshot{
+			CreatedOn:     time.Now().UTC(),
+			StartRevision: startRevision,
+			LastRevision:  lastRevision,
+			Kind:          kind,
+			Prefix:        prefix,
+		}
+		snap.GenerateSnapshotName()
+		return snap
+	}
+
+	It("should not leave a partially written snapshot behind if the save fails", func() {
+		snap := newSnapshot(brtypes.SnapshotKindFull, 0, 10)
+		Expect(store.Save(*snap, io.NopCloser(strings.NewReader("snapshot")))).To(Succeed())
+
+		failingReader := io.MultiReader(strings.NewReader("partial"), iotest.ErrReader(fmt.Errorf("connection reset")))
+		Expect(store.Save(*snap, io.NopCloser(failingReader))).ShouldNot(Succeed())
+		Expect(store.Save(*newSnapshot(brtypes.SnapshotKindDelta, 11, 20), io.NopCloser(failingReader))).ShouldNot(Succeed())
+
+		// the snapshot saved before is neither replaced nor accompanied by the temporary files of the failed saves
+		rc, err := store.Fetch(*snap)
+		Expect(err).ShouldNot(HaveOccurred())
+		defer rc.Close()
+		Expect(io.ReadAll(rc)).To(Equal([]byte("snapshot")))
+
+		var files []string
+		Expect(filepath.WalkDir(prefix, func(filePath string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				files = append(files, filePath)
+			}
+			return err
+		})).To(Succeed())
+		Expect(files).To(ConsistOf(path.Join(prefix, snap.SnapName)))
+
+		snapList, err := store.List()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(snapList).To(HaveLen(1))
+		Expect(snapList[0].SnapName).To(Equal(snap.SnapName))
+	})
+
+	It("should list the snapshots ordered by their revisions irrespective of the order they were saved in", func() {
+		snaps := brtypes.SnapList{
+			newSnapshot(brtypes.SnapshotKindDelta, 21, 30),
+			newSnapshot(brtypes.SnapshotKindFull, 0, 10),
+			newSnapshot(brtypes.SnapshotKindFull, 0, 30),
+			newSnapshot(brtypes.SnapshotKindDelta, 11, 20),
+		}
+		for _, snap := range snaps {
+			Expect(store.Save(*snap, io.NopCloser(strings.NewReader(generateContentsForSnapshot(snap))))).To(Succeed())
+		}
+		// a temporary file left behind by a crash during a save is ignored
+		Expect(os.WriteFile(path.Join(prefix, snaps[0].SnapName+".123.tmp"), []byte("partial"), 0600)).To(Succeed())
+
+		snapList, err := store.List()
+		Expect(err).ShouldNot(HaveOccurred())
+		var lastRevisions []int64
+		for _, snap := range snapList {
+			lastRevisions = append(lastRevisions, snap.LastRevision)
+		}
+		Expect(lastRevisions).To(Equal([]int64{10, 20, 30, 30}))
+		// the delta snapshot ending at the revision of the full snapshot belongs to the chain before it
+		Expect(snapList[2].Kind).To(Equal(brtypes.SnapshotKindDelta))
+		Expect(snapList[3].Kind).To(Equal(brtypes.SnapshotKindFull))
+	})
+
+	It("should fetch the listed snapshots for a restoration", func() {
+		snaps := brtypes.SnapList{
+			newSnapshot(brtypes.SnapshotKindFull, 0, 10),
+			newSnapshot(brtypes.SnapshotKindDelta, 11, 20),
+		}
+		for _, snap := range snaps {
+			Expect(store.Save(*snap, io.NopCloser(strings.NewReader(generateContentsForSnapshot(snap))))).To(Succeed())
+		}
+
+		fullSnap, deltaSnaps, err := miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(store)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(deltaSnaps).To(HaveLen(1))
+		for i, snap := range []*brtypes.Snapshot{fullSnap, deltaSnaps[0]} {
+			rc, err := store.Fetch(*snap)
+			Expect(err).ShouldNot(HaveOccurred())
+			data, err := io.ReadAll(rc)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rc.Close()).To(Succeed())
+			Expect(string(data)).To(Equal(generateContentsForSnapshot(snaps[i])))
+		}
+	})
+})
+
+var _ = Describe("Caching the fetched snapshots", func() {
+	var (
+		store    *countingSnapStore
+		cacheDir string
+		snaps    brtypes.SnapList
+	)
+
+	BeforeEach(func() {
+		prefix := path.Join(GinkgoT().TempDir(), prefixV2)
+		localStore, err := NewLocalSnapStore(prefix)
+		Expect(err).ShouldNot(HaveOccurred())
+		store = &countingSnapStore{SnapStore: localStore}
+		cacheDir = GinkgoT().TempDir()
+
+		snaps = brtypes.SnapList{}
+		for i := int64(0); i < 2; i++ {
+			snap := &brtypes.Snapshot{
+				CreatedOn:     time.Now().UTC(),
+				StartRevision: i * 10,
+				LastRevision:  i*10 + 9,
+				Kind:          brtypes.SnapshotKindDelta,
+				Prefix:        prefix,
+			}
+			snap.GenerateSnapshotName()
+			Expect(store.Save(*snap, io.NopCloser(strings.NewReader(generateContentsForSnapshot(snap))))).To(Succeed())
+			snaps = append(snaps, snap)
+		}
+	})
+
+	fetch := func(cs brtypes.SnapStore, snap *brtypes.Snapshot) string {
+		rc, err := cs.Fetch(*snap)
+		Expect(err).ShouldNot(HaveOccurred())
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		Expect(err).ShouldNot(HaveOccurred())
+		return string(data)
+	}
+
+	It("should serve the second fetch of a snapshot from the cache instead of the snapstore", func() {
+		cs, err := NewCachingSnapStore(store, cacheDir, 1<<20, time.Hour)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		Expect(fetch(cs, snaps[0])).To(Equal(generateContentsForSnapshot(snaps[0])))
+		Expect(fetch(cs, snaps[0])).To(Equal(generateContentsForSnapshot(snaps[0])))
+		Expect(store.fetches).To(Equal(1))
+
+		// The cache is shared with the caching snapstores of the other restorations using the same directory.
+		other, err := NewCachingSnapStore(store, cacheDir, 1<<20, time.Hour)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(fetch(other, snaps[0])).To(Equal(generateContentsForSnapshot(snaps[0])))
+		Expect(store.fetches).To(Equal(1))
+
+		Expect(fetch(cs, snaps[1])).To(Equal(generateContentsForSnapshot(snaps[1])))
+		Expect(store.fetches).To(Equal(2))
+	})
+
+	It("should fetch a snapshot from the snapstore again once its cache entry has expired", func() {
+		cs, err := NewCachingSnapStore(store, cacheDir, 1<<20, time.Nanosecond)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		Expect(fetch(cs, snaps[0])).To(Equal(generateContentsForSnapshot(snaps[0])))
+		time.Sleep(time.Millisecond)
+		Expect(fetch(cs, snaps[0])).To(Equal(generateContentsForSnapshot(snaps[0])))
+		Expect(store.fetches).To(Equal(2))
+	})
+
+	It("should evict the oldest cached snapshots beyond the maximum size of the cache", func() {
+		cs, err := NewCachingSnapStore(store, cacheDir, int64(len(generateContentsForSnapshot(snaps[1]))), time.Hour)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		Expect(fetch(cs, snaps[0])).To(Equal(generateContentsForSnapshot(snaps[0])))
+		Expect(fetch(cs, snaps[1])).To(Equal(generateContentsForSnapshot(snaps[1])))
+		entries, err := os.ReadDir(cacheDir)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+
+		Expect(fetch(cs, snaps[1])).To(Equal(generateContentsForSnapshot(snaps[1])))
+		Expect(store.fetches).To(Equal(2))
+		Expect(fetch(cs, snaps[0])).To(Equal(generateContentsForSnapshot(snaps[0])))
+		Expect(store.fetches).To(Equal(3))
+	})
+
+	It("should fetch a snapshot overwritten in the snapstore again if its size changed", func() {
+		sizingStore := &sizingCountingSnapStore{countingSnapStore: store}
+		cs, err := NewCachingSnapStore(sizingStore, cacheDir, 1<<20, time.Hour)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		Expect(fetch(cs, snaps[0])).To(Equal(generateContentsForSnapshot(snaps[0])))
+		// the snapshot is overwritten by another snapstore, which bypasses the cache
+		Expect(store.Save(*snaps[0], io.NopCloser(strings.NewReader("overwritten")))).To(Succeed())
+		Expect(fetch(cs, snaps[0])).To(Equal("overwritten"))
+		Expect(fetch(cs, snaps[0])).To(Equal("overwritten"))
+		Expect(store.fetches).To(Equal(2))
+
+		// the cache entry of the overwritten snapshot is removed
+		entries, err := os.ReadDir(cacheDir)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+	})
+
+	It("should read the fetched snapshots while concurrent fetches replace and evict their cache entries", func() {
+		// The cache entries expire right away and exceed the maximum size of the cache, hence every fetch replaces
+		// the cache entry of the snapshot and evicts the cache entries of the other fetches.
+		cs, err := NewCachingSnapStore(store.SnapStore, cacheDir, 1, time.Nanosecond)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			snap := snaps[i%len(snaps)]
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				Expect(fetch(cs, snap)).To(Equal(generateContentsForSnapshot(snap)))
+			}()
+		}
+		wg.Wait()
+	})
+
+	It("should not serve a deleted snapshot from the cache", func() {
+		cs, err := NewCachingSnapStore(store, cacheDir, 1<<20, time.Hour)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		Expect(fetch(cs, snaps[0])).To(Equal(generateContentsForSnapshot(snaps[0])))
+		Expect(cs.Delete(*snaps[0])).To(Succeed())
+		_, err = cs.Fetch(*snaps[0])
+		Expect(err).Should(HaveOccurred())
+	})
+
+	It("should cache the fetched snapshots only if the fetch cache is enabled in the snapstore config", func() {
+		config := &brtypes.SnapstoreConfig{Provider: brtypes.SnapstoreProviderFakeFailed, Container: bucket, TempDir: cacheDir}
+		ss, err := GetSnapstore(config)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ss).NotTo(BeAssignableToTypeOf(&CachingSnapStore{}))
+
+		config.FetchCacheSize = 1 << 20
+		ss, err = GetSnapstore(config)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ss).To(BeAssignableToTypeOf(&CachingSnapStore{}))
+		Expect(config.FetchCacheTTL.Duration).To(Equal(brtypes.DefaultFetchCacheTTL))
+	})
+})
+
+var _ = Describe("Paged listing from mock S3 snapstore", func() {
+	var (
+		store     brtypes.PagedSnapStore
+		snapshots brtypes.SnapList
+	)
+
+	BeforeEach(func() {
+		resetObjectMap()
+		store = NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, &mockS3Client{
+			objects:          objectMap,
+			prefix:           prefixV2,
+			multiPartUploads: map[string]*[][]byte{},
+		}, SSECredentials{})
+
+		now := time.Now().Unix()
+		snapshots = brtypes.SnapList{}
+		for i := int64(0); i < 5; i++ {
+			snap := &brtypes.Snapshot{
+				CreatedOn:     time.Unix(now+i*100, 0).UTC(),
+				StartRevision: 1000 * i,
+				LastRevision:  1000*i + 500,
+				Kind:          brtypes.SnapshotKindDelta,
+				Prefix:        prefixV2,
+			}
+			if i == 0 {
+				snap.Kind = brtypes.SnapshotKindFull
+				snap.StartRevision = 0
+			}
+			snap.GenerateSnapshotName()
+			snapshots = append(snapshots, snap)
+		}
+		Expect(setObjectMap("s3", snapshots)).To(Equal(len(snapshots)))
+	})
+
+	AfterEach(func() {
+		resetObjectMap()
+	})
+
+	It("should list all the snapshots page by page", func() {
+		var (
+			pagedSnapList brtypes.SnapList
+			marker        string
+			pages         int
+		)
+		for {
+			snapList, nextMarker, err := store.ListPaged(marker, 2)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(len(snapList)).To(BeNumerically("<=", 2))
+			pagedSnapList = append(pagedSnapList, snapList...)
+			pages++
+			if nextMarker == "" {
+				break
+			}
+			Expect(nextMarker).To(Equal(path.Join(snapList[len(snapList)-1].Prefix, snapList[len(snapList)-1].SnapName)))
+			marker = nextMarker
+		}
+		Expect(pages).To(Equal(3))
+		Expect(pagedSnapList).To(HaveLen(len(snapshots)))
+
+		snapList, err := store.List()
+		Expect(err).ShouldNot(HaveOccurred())
+		for i := range snapList {
+			Expect(pagedSnapList[i].SnapName).To(Equal(snapList[i].SnapName))
+		}
+	})
+
+	It("should return the snapshots after the given marker", func() {
+		snapList, err := store.List()
+		Expect(err).ShouldNot(HaveOccurred())
+
+		pagedSnapList, nextMarker, err := store.ListPaged(path.Join(snapList[2].Prefix, snapList[2].SnapName), 10)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(nextMarker).To(BeEmpty())
+		Expect(pagedSnapList).To(HaveLen(2))
+		Expect(pagedSnapList[0].SnapName).To(Equal(snapList[3].SnapName))
+		Expect(pagedSnapList[1].SnapName).To(Equal(snapList[4].SnapName))
+	})
+
+	It("should return an empty page and no marker after the last snapshot", func() {
+		snapList, err := store.List()
+		Expect(err).ShouldNot(HaveOccurred())
+
+		pagedSnapList, nextMarker, err := store.ListPaged(path.Join(snapList[4].Prefix, snapList[4].SnapName), 2)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(pagedSnapList).To(BeEmpty())
+		Expect(nextMarker).To(BeEmpty())
+	})
+
+	It("should return an error if the limit is not positive", func() {
+		_, _, err := store.ListPaged("", 0)
+		Expect(err).Should(HaveOccurred())
+	})
+})
+
+var _ = Describe("Paged listing from the mock GCS, ABS, Swift, OSS and local snapstores", func() {
+	var snapshots brtypes.SnapList
+
+	BeforeEach(func() {
+		resetObjectMap()
+		now := time.Now().Unix()
+		snapshots = brtypes.SnapList{}
+		for i := int64(0); i < 5; i++ {
+			snap := &brtypes.Snapshot{
+				CreatedOn:     time.Unix(now+i*100, 0).UTC(),
+				StartRevision: 1000 * i,
+				LastRevision:  1000*i + 500,
+				Kind:          brtypes.SnapshotKindDelta,
+				Prefix:        prefixV2,
+			}
+			if i == 0 {
+				snap.Kind = brtypes.SnapshotKindFull
+				snap.StartRevision = 0
+			}
+			snap.GenerateSnapshotName()
+			snapshots = append(snapshots, snap)
+		}
+	})
+
+	AfterEach(func() {
+		resetObjectMap()
+	})
+
+	DescribeTable("should list all the snapshots page by page",
+		func(provider string, newStore func() brtypes.SnapStore) {
+			Expect(setObjectMap(provider, snapshots)).To(Equal(len(snapshots)))
+			store, ok := newStore().(brtypes.PagedSnapStore)
+			Expect(ok).To(BeTrue())
+
+			var (
+				pagedSnapList brtypes.SnapList
+				marker        string
+				pages         int
+			)
+			for {
+				snapList, nextMarker, err := store.ListPaged(marker, 2)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(len(snapList)).To(BeNumerically("<=", 2))
+				pagedSnapList = append(pagedSnapList, snapList...)
+				pages++
+				Expect(pages).To(BeNumerically("<=", len(objectMap)))
+				if nextMarker == "" {
+					break
+				}
+				marker = nextMarker
+			}
+			Expect(pages).To(BeNumerically(">", 1))
+
+			snapList, err := store.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(snapshotNames(pagedSnapList)).To(ConsistOf(snapshotNames(snapList)))
+
+			_, _, err = store.ListPaged("", 0)
+			Expect(err).Should(HaveOccurred())
+		},
+		Entry("GCS", "GCS", func() brtypes.SnapStore {
+			return NewGCSSnapStoreFromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, "", &mockGCSClient{objects: objectMap, prefix: prefixV2})
+		}),
+		Entry("ABS", "ABS", newFakeABSSnapstore),
+		Entry("Swift", "swift", func() brtypes.SnapStore {
+			return NewSwiftSnapstoreFromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, fake.ServiceClient())
+		}),
+		Entry("Swift capping the pages below the limit", "swift", func() brtypes.SnapStore {
+			swiftContainerListingLimit = 1
+			DeferCleanup(func() { swiftContainerListingLimit = 0 })
+			return NewSwiftSnapstoreFromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, fake.ServiceClient())
+		}),
+		Entry("OSS", "OSS", func() brtypes.SnapStore {
+			return NewOSSFromBucket(prefixV2, "/tmp", 5, brtypes.MinChunkSize, &mockOSSBucket{
+				objects:          objectMap,
+				prefix:           prefixV2,
+				multiPartUploads: map[string]*[][]byte{},
+				bucketName:       bucket,
+			})
+		}),
+		Entry("Local", "Local", func() brtypes.SnapStore {
+			store, err := NewLocalSnapStore(path.Join(GinkgoT().TempDir(), prefixV2))
+			Expect(err).ShouldNot(HaveOccurred())
+			for _, snap := range snapshots {
+				Expect(store.Save(*snap, io.NopCloser(strings.NewReader(generateContentsForSnapshot(snap))))).To(Succeed())
+			}
+			return store
+		}),
+	)
+})
+
+var _ = Describe("Provider-native integrity of the uploaded snapshots", func() {
+	var (
+		snap *brtypes.Snapshot
+		data []byte
+	)
+
+	BeforeEach(func() {
+		snap = &brtypes.Snapshot{
+			CreatedOn:     time.Now().UTC(),
+			StartRevision: 0,
+			LastRevision:  2088,
+			Kind:          brtypes.SnapshotKindFull,
+			Prefix:        prefixV2,
+		}
+		snap.GenerateSnapshotName()
+		// the snapshot is uploaded in two chunks
+		data = make([]byte, brtypes.MinChunkSize+1024)
+		_, err := rand.Read(data)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	Context("with the mock S3 snapstore", func() {
+		var (
+			store  brtypes.SnapStore
+			client *mockS3Client
+		)
+
+		BeforeEach(func() {
+			client = &mockS3Client{
+				objects:          map[string]*[]byte{},
+				prefix:           prefixV2,
+				multiPartUploads: map[string]*[][]byte{},
+			}
+			store = NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+		})
+
+		It("should set the Content-MD5 header of each uploaded part", func() {
+			Expect(store.Save(*snap, io.NopCloser(bytes.NewReader(data)))).To(Succeed())
+
+			firstPart, secondPart := md5.Sum(data[:brtypes.MinChunkSize]), md5.Sum(data[brtypes.MinChunkSize:])
+			Expect(client.partContentMD5s).To(Equal(map[int64]string{
+				1: base64.StdEncoding.EncodeToString(firstPart[:]),
+				2: base64.StdEncoding.EncodeToString(secondPart[:]),
+			}))
+		})
+
+		It("should re-upload a part which was rejected by the provider as corrupted", func() {
+			client.corruptNextPart = true
+			Expect(store.Save(*snap, io.NopCloser(bytes.NewReader(data)))).To(Succeed())
+
+			Expect(client.rejectedParts).To(Equal(1))
+			Expect(*client.objects[path.Join(prefixV2, snap.SnapDir, snap.SnapName)]).To(Equal(data))
+		})
+	})
+
+	Context("with the mock GCS snapstore", func() {
+		var (
+			store  brtypes.SnapStore
+			client *mockGCSClient
+		)
+
+		BeforeEach(func() {
+			client = &mockGCSClient{
+				objects: map[string]*[]byte{},
+				prefix:  prefixV2,
+			}
+			store = NewGCSSnapStoreFromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, "", client)
+		})
+
+		It("should send the CRC32C checksum of each uploaded component", func() {
+			Expect(store.Save(*snap, io.NopCloser(bytes.NewReader(data)))).To(Succeed())
+
+			table := crc32.MakeTable(crc32.Castagnoli)
+			Expect(client.objectCRC32Cs).To(Equal(map[string]uint32{
+				path.Join(prefixV2, snap.SnapDir, snap.SnapName, "0000000001"): crc32.Checksum(data[:brtypes.MinChunkSize], table),
+				path.Join(prefixV2, snap.SnapDir, snap.SnapName, "0000000002"): crc32.Checksum(data[brtypes.MinChunkSize:], table),
+			}))
+		})
+
+		It("should re-upload a component which was rejected by the provider as corrupted", func() {
+			client.corruptNextUpload = true
+			Expect(store.Save(*snap, io.NopCloser(bytes.NewReader(data)))).To(Succeed())
+
+			Expect(client.rejectedUploads).To(Equal(1))
+			Expect(*client.objects[path.Join(prefixV2, snap.SnapDir, snap.SnapName)]).To(Equal(data))
+		})
+	})
+
+	Context("with the fake ABS snapstore", func() {
+		var store brtypes.SnapStore
+
+		BeforeEach(func() {
+			resetObjectMap()
+			store = newFakeABSSnapstore()
+		})
+
+		AfterEach(func() {
+			resetObjectMap()
+		})
+
+		It("should set the Content-MD5 header of each staged block", func() {
+			Expect(store.Save(*snap, io.NopCloser(bytes.NewReader(data)))).To(Succeed())
+
+			firstBlock, secondBlock := md5.Sum(data[:brtypes.MinChunkSize]), md5.Sum(data[brtypes.MinChunkSize:])
+			Expect(blockContentMD5s).To(Equal(map[string]string{
+				base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%010d", 1))): base64.StdEncoding.EncodeToString(firstBlock[:]),
+				base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%010d", 2))): base64.StdEncoding.EncodeToString(secondBlock[:]),
+			}))
+		})
+
+		It("should re-stage a block which was rejected by the provider as corrupted", func() {
+			corruptNextBlock = true
+			Expect(store.Save(*snap, io.NopCloser(bytes.NewReader(data)))).To(Succeed())
+
+			Expect(rejectedBlocks).To(Equal(1))
+			Expect(*objectMap[path.Join(prefixV2, snap.SnapDir, snap.SnapName)]).To(Equal(data))
+		})
+	})
+
+	Context("with the fake Swift snapstore", func() {
+		BeforeEach(func() {
+			resetObjectMap()
+		})
+
+		AfterEach(func() {
+			resetObjectMap()
+		})
+
+		It("should send the ETag of each uploaded segment", func() {
+			store := NewSwiftSnapstoreFromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, fake.ServiceClient())
+			Expect(store.Save(*snap, io.NopCloser(bytes.NewReader(data)))).To(Succeed())
+
+			Expect(objectETags).To(Equal(map[string]string{
+				path.Join(prefixV2, snap.SnapDir, snap.SnapName, "0000000001"): fmt.Sprintf("%x", md5.Sum(data[:brtypes.MinChunkSize])),
+				path.Join(prefixV2, snap.SnapDir, snap.SnapName, "0000000002"): fmt.Sprintf("%x", md5.Sum(data[brtypes.MinChunkSize:])),
+			}))
+		})
+	})
+})
+
+var _ = Describe("Switching over to multipart uploads at the multipart threshold", func() {
+	const threshold = brtypes.MinChunkSize + 1024
+
+	var (
+		client *mockS3Client
+		store  *S3SnapStore
+		snap   *brtypes.Snapshot
+	)
+
+	BeforeEach(func() {
+		client = &mockS3Client{
+			objects:          map[string]*[]byte{},
+			prefix:           prefixV2,
+			multiPartUploads: map[string]*[][]byte{},
+		}
+		store = NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+		store.MultipartThreshold = threshold
+		snap = &brtypes.Snapshot{
+			CreatedOn:     time.Now().UTC(),
+			StartRevision: 0,
+			LastRevision:  2088,
+			Kind:          brtypes.SnapshotKindFull,
+			Prefix:        prefixV2,
+		}
+		snap.GenerateSnapshotName()
+	})
+
+	saveSnapshotOfSize := func(size int64) []byte {
+		data := make([]byte, size)
+		_, err := rand.Read(data)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(store.Save(*snap, io.NopCloser(bytes.NewReader(data)))).To(Succeed())
+		return data
+	}
+
+	It("should upload a snapshot just below the threshold with a single put", func() {
+		data := saveSnapshotOfSize(threshold - 1)
+		Expect(client.createdMultipartUploads).To(BeZero())
+		Expect(*client.objects[path.Join(prefixV2, snap.SnapDir, snap.SnapName)]).To(Equal(data))
+	})
+
+	It("should upload a snapshot of the size of the threshold in multiple parts", func() {
+		data := saveSnapshotOfSize(threshold)
+		Expect(client.createdMultipartUploads).To(Equal(1))
+		Expect(*client.objects[path.Join(prefixV2, snap.SnapDir, snap.SnapName)]).To(Equal(data))
+	})
+
+	It("should upload every snapshot in multiple parts without a threshold", func() {
+		store.MultipartThreshold = 0
+		saveSnapshotOfSize(1024)
+		Expect(client.createdMultipartUploads).To(Equal(1))
+	})
+
+	It("should set the ACL on a snapshot uploaded with a single put", func() {
+		store.ObjectACL = s3.ObjectCannedACLBucketOwnerFullControl
+		saveSnapshotOfSize(1024)
+		Expect(client.createdMultipartUploads).To(BeZero())
+		Expect(client.objectACLs).To(HaveKeyWithValue(path.Join(prefixV2, snap.SnapDir, snap.SnapName), s3.ObjectCannedACLBucketOwnerFullControl))
+	})
+
+	DescribeTable("should scale the timeout of a single put with the size of the snapshot",
+		func(size int64, expectedTimeout time.Duration) {
+			Expect(SinglePutTimeout(size)).To(Equal(expectedTimeout))
+		},
+		Entry("below 1 GiB", int64(1024), 180*time.Second),
+		Entry("of 1 GiB", int64(1<<30), 360*time.Second),
+		Entry("of the upper size limit of a single put", brtypes.MaxS3SinglePutObjectSize, 18*time.Minute),
+	)
+})
+
+var _ = Describe("Setting the ACL of the objects written to the snapstore", func() {
+	var snap *brtypes.Snapshot
+
+	BeforeEach(func() {
+		snap = &brtypes.Snapshot{
+			CreatedOn:     time.Now().UTC(),
+			StartRevision: 0,
+			LastRevision:  2088,
+			Kind:          brtypes.SnapshotKindFull,
+			Prefix:        prefixV2,
+		}
+		snap.GenerateSnapshotName()
+	})
+
+	Context("with the mock S3 snapstore", func() {
+		var (
+			store  *S3SnapStore
+			client *mockS3Client
+		)
+
+		BeforeEach(func() {
+			client = &mockS3Client{
+				objects:          map[string]*[]byte{},
+				prefix:           prefixV2,
+				multiPartUploads: map[string]*[][]byte{},
+				tags:             map[string][]*s3.Tag{},
+				metadata:         map[string]map[string]*string{},
+			}
+			store = NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+		})
+
+		It("should set the configured canned ACL on all the objects written to the snapstore", func() {
+			store.ObjectACL = s3.ObjectCannedACLBucketOwnerFullControl
+			Expect(store.Save(*snap, io.NopCloser(strings.NewReader("snapshot")))).To(Succeed())
+			Expect(store.SaveChainManifest(&brtypes.ChainManifest{})).To(Succeed())
+			Expect(store.SaveClusterMetadata(*snap, &brtypes.ClusterMetadata{})).To(Succeed())
+			Expect(store.SaveCompressionDictionary(1, []byte("dictionary"))).To(Succeed())
+			Expect(store.CopyToPrefix(*snap, "other")).To(Succeed())
+
+			Expect(client.objectACLs).To(HaveLen(5))
+			for key, acl := range client.objectACLs {
+				Expect(acl).To(Equal(s3.ObjectCannedACLBucketOwnerFullControl), "object %s", key)
+			}
+			Expect(client.objectACLs).To(HaveKey(path.Join(prefixV2, snap.SnapDir, snap.SnapName)))
+			Expect(client.objectACLs).To(HaveKey(path.Join("other", snap.SnapDir, snap.SnapName)))
+		})
+
+		It("should leave the ACL of the objects to the bucket if none is configured", func() {
+			Expect(store.Save(*snap, io.NopCloser(strings.NewReader("snapshot")))).To(Succeed())
+			Expect(client.objectACLs).To(Equal(map[string]string{path.Join(prefixV2, snap.SnapDir, snap.SnapName): ""}))
+		})
+	})
+
+	Context("with the mock GCS snapstore", func() {
+		var (
+			store  *GCSSnapStore
+			client *mockGCSClient
+		)
+
+		BeforeEach(func() {
+			client = &mockGCSClient{
+				objects: map[string]*[]byte{},
+				prefix:  prefixV2,
+			}
+			store = NewGCSSnapStoreFromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, "", client)
+		})
+
+		It("should set the configured predefined ACL on the uploaded components and the composite snapshot", func() {
+			store.ObjectACL = "bucketOwnerFullControl"
+			Expect(store.Save(*snap, io.NopCloser(strings.NewReader("snapshot")))).To(Succeed())
+
+			Expect(client.objectACLs).To(Equal(map[string]string{
+				path.Join(prefixV2, snap.SnapDir, snap.SnapName, "0000000001"): "bucketOwnerFullControl",
+				path.Join(prefixV2, snap.SnapDir, snap.SnapName):               "bucketOwnerFullControl",
+			}))
+		})
+	})
+
+	It("should set the configured ACL on the snapstore of the S3 provider", func() {
+		config := &brtypes.SnapstoreConfig{Provider: brtypes.SnapstoreProviderS3, Container: bucket, ObjectACL: s3.ObjectCannedACLBucketOwnerFullControl}
+		ss, err := NewS3SnapStore(config)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ss.ObjectACL).To(Equal(s3.ObjectCannedACLBucketOwnerFullControl))
+	})
+})
+
 // createCredentialFilesInDirectory creates access credential files in the
 // specified directory and returns the timestamp of the last modified file.
+// countingSnapStore counts the fetches of the snapshots from the embedded snapstore.
+type countingSnapStore struct {
+	brtypes.SnapStore
+	fetches int
+}
+
+func (s *countingSnapStore) Fetch(snap brtypes.Snapshot) (io.ReadCloser, error) {
+	s.fetches++
+	return s.SnapStore.Fetch(snap)
+}
+
+// sizingCountingSnapStore is a countingSnapStore which returns the size of the snapshots of the underlying snapstore.
+type sizingCountingSnapStore struct {
+	*countingSnapStore
+}
+
+func (s *sizingCountingSnapStore) Size(snap brtypes.Snapshot) (int64, error) {
+	return SnapshotSize(s.countingSnapStore.SnapStore, snap)
+}
+
 func createCredentialFilesInDirectory(directory string, filenames []string) (time.Time, error) {
 	var fullFilePath string
 	for _, filename := range filenames {
@@ -657,6 +3002,92 @@ func generateContentsForSnapshot(snapshot *brtypes.Snapshot) string {
 }
 
 // Changes the contents of the objectMap according the to the snapshots and the provider and returns the number of snapshots added
+var _ = Describe("Protecting the snapshots in an object-locked bucket", func() {
+	var (
+		snap   *brtypes.Snapshot
+		key    string
+		store  *S3SnapStore
+		client *mockS3Client
+	)
+
+	BeforeEach(func() {
+		snap = &brtypes.Snapshot{
+			CreatedOn:     time.Now().UTC(),
+			StartRevision: 0,
+			LastRevision:  2088,
+			Kind:          brtypes.SnapshotKindFull,
+			Prefix:        prefixV2,
+		}
+		snap.GenerateSnapshotName()
+		key = path.Join(prefixV2, snap.SnapDir, snap.SnapName)
+		client = &mockS3Client{
+			objects:           map[string]*[]byte{key: {}},
+			prefix:            prefixV2,
+			multiPartUploads:  map[string]*[][]byte{},
+			tags:              map[string][]*s3.Tag{},
+			metadata:          map[string]map[string]*string{},
+			objectLockEnabled: true,
+			retainUntil:       map[string]time.Time{key: time.Now().Add(time.Hour)},
+		}
+		store = NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+		store.ObjectLockProtection = true
+	})
+
+	It("should neither overwrite nor delete a snapshot under retention", func() {
+		err := store.Save(*snap, io.NopCloser(strings.NewReader("snapshot")))
+		Expect(err).Should(MatchError(ContainSubstring("cannot be overwritten")))
+		Expect(store.Delete(*snap)).Should(MatchError(ContainSubstring("cannot be deleted")))
+
+		Expect(client.lockedObjectWrites).To(BeEmpty())
+		Expect(client.objects).To(HaveKeyWithValue(key, Equal(&[]byte{})))
+	})
+
+	It("should report a snapshot under retention or legal hold as retained, so that it is skipped by the garbage collection", func() {
+		Expect(IsSnapshotRetained(store, *snap)).To(BeTrue())
+
+		delete(client.retainUntil, key)
+		Expect(IsSnapshotRetained(store, *snap)).To(BeFalse())
+
+		client.legalHolds = map[string]bool{key: true}
+		Expect(IsSnapshotRetained(store, *snap)).To(BeTrue())
+		Expect(store.Delete(*snap)).ShouldNot(Succeed())
+		Expect(client.lockedObjectWrites).To(BeEmpty())
+	})
+
+	It("should overwrite and delete a snapshot whose retention has expired", func() {
+		client.retainUntil[key] = time.Now().Add(-time.Minute)
+
+		Expect(IsSnapshotRetained(store, *snap)).To(BeFalse())
+		Expect(store.Save(*snap, io.NopCloser(strings.NewReader("snapshot")))).To(Succeed())
+		Expect(store.Delete(*snap)).To(Succeed())
+		Expect(client.objects).NotTo(HaveKey(key))
+		Expect(client.lockedObjectWrites).To(BeEmpty())
+	})
+
+	It("should save new snapshots into an object-locked bucket", func() {
+		newSnap := *snap
+		newSnap.LastRevision++
+		newSnap.GenerateSnapshotName()
+		Expect(store.Save(newSnap, io.NopCloser(strings.NewReader("snapshot")))).To(Succeed())
+		Expect(client.objects).To(HaveKey(path.Join(prefixV2, newSnap.SnapDir, newSnap.SnapName)))
+	})
+
+	It("should attempt to overwrite and delete the snapshots under retention if the protection is disabled", func() {
+		store.ObjectLockProtection = false
+		Expect(IsSnapshotRetained(store, *snap)).To(BeFalse())
+		Expect(store.Save(*snap, io.NopCloser(strings.NewReader("snapshot")))).ShouldNot(Succeed())
+		Expect(store.Delete(*snap)).ShouldNot(Succeed())
+		Expect(client.lockedObjectWrites).To(Equal([]string{key, key}))
+	})
+
+	It("should not consider the snapshots locked if object lock is not enabled on the bucket", func() {
+		client.objectLockEnabled = false
+		Expect(IsSnapshotRetained(store, *snap)).To(BeFalse())
+		Expect(store.Save(*snap, io.NopCloser(strings.NewReader("snapshot")))).To(Succeed())
+		Expect(store.Delete(*snap)).To(Succeed())
+	})
+})
+
 func setObjectMap(provider string, snapshots brtypes.SnapList) int {
 	var numberSnapshotsAdded int
 	for _, snapshot := range snapshots {
@@ -679,10 +3110,32 @@ func setObjectMap(provider string, snapshots brtypes.SnapList) int {
 	return numberSnapshotsAdded
 }
 
+func snapshotNames(snapList brtypes.SnapList) []string {
+	var names []string
+	for _, snap := range snapList {
+		names = append(names, path.Join(snap.SnapDir, snap.SnapName))
+	}
+	return names
+}
+
 func resetObjectMap() {
 	for k := range objectMap {
 		delete(objectMap, k)
 	}
+	for k := range objectMetadata {
+		delete(objectMetadata, k)
+	}
+	for k := range objectManifests {
+		delete(objectManifests, k)
+	}
+	for k := range objectETags {
+		delete(objectETags, k)
+	}
+	for k := range blockContentMD5s {
+		delete(blockContentMD5s, k)
+	}
+	corruptNextBlock = false
+	rejectedBlocks = 0
 }
 
 func parseObjectNamefromURL(u *url.URL) string {
@@ -698,3 +3151,427 @@ func parseObjectNamefromURL(u *url.URL) string {
 		return ""
 	}
 }
+
+var _ = Describe("Classifying the snapstore errors", func() {
+	DescribeTable("should recognize the errors of a snapstore out of quota or capacity",
+		func(err error, quotaExceeded bool) {
+			Expect(IsQuotaExceededError(err)).To(Equal(quotaExceeded))
+		},
+		Entry("no error", nil, false),
+		Entry("S3 quota exceeded", awserr.New("QuotaExceeded", "bucket quota exceeded", nil), true),
+		Entry("MinIO storage full", fmt.Errorf("failed uploading chunk: %v", awserr.New("XMinioStorageFull", "storage backend has reached its minimum free drive threshold", nil)), true),
+		Entry("HTTP insufficient storage", fmt.Errorf("507 Insufficient Storage"), true),
+		Entry("local volume full", fmt.Errorf("failed to save snapshot: %w", &os.PathError{Op: "write", Path: "/backup/v2/Full-00000000-00000001-1", Err: syscall.ENOSPC}), true),
+		Entry("local disk quota", fmt.Errorf("failed to save snapshot: %w", syscall.EDQUOT), true),
+		Entry("other error", fmt.Errorf("failed to save snapshot: %w", syscall.ECONNREFUSED), false),
+		Entry("S3 access denied", awserr.New("AccessDenied", "access denied", nil), false),
+	)
+})
+
+var _ = Describe("Classifying the throttling errors", func() {
+	DescribeTable("should recognize the errors of a throttled snapstore",
+		func(err error, throttled bool) {
+			Expect(IsThrottlingError(err)).To(Equal(throttled))
+		},
+		Entry("no error", nil, false),
+		Entry("S3 slow down", awserr.New("SlowDown", "Please reduce your request rate.", nil), true),
+		Entry("S3 request failure with status 429", awserr.NewRequestFailure(awserr.New("TooManyRequests", "", nil), http.StatusTooManyRequests, "request-id"), true),
+		Entry("GCS rate limit", fmt.Errorf("googleapi: Error 429: The rate of change requests to the object is too high, rateLimitExceeded"), true),
+		Entry("Swift too many requests", fmt.Errorf("Expected HTTP response code [200 204 300] when accessing [GET https://swift/v1/container], but got 429 instead: Too Many Requests"), true),
+		Entry("S3 access denied", awserr.New("AccessDenied", "access denied", nil), false),
+		Entry("other error", fmt.Errorf("failed to list snapshots: %w", syscall.ECONNREFUSED), false),
+	)
+})
+
+var _ = Describe("Retrying throttled listings", func() {
+	var (
+		client *mockS3Client
+		store  brtypes.SnapStore
+	)
+
+	BeforeEach(func() {
+		resetObjectMap()
+		DeferCleanup(resetObjectMap)
+		client = &mockS3Client{
+			objects:          objectMap,
+			prefix:           prefixV2,
+			multiPartUploads: map[string]*[][]byte{},
+		}
+		store = NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+		SetListThrottlingRetries(store, 3, time.Millisecond)
+		var snapList brtypes.SnapList
+		for i := 0; i < 3; i++ {
+			snap := &brtypes.Snapshot{
+				CreatedOn:     time.Now().UTC().Add(time.Duration(i) * time.Second),
+				StartRevision: int64(i*10 + 1),
+				LastRevision:  int64(i*10 + 10),
+				Kind:          brtypes.SnapshotKindDelta,
+				Prefix:        prefixV2,
+			}
+			snap.GenerateSnapshotName()
+			snapList = append(snapList, snap)
+		}
+		Expect(setObjectMap("s3", snapList)).To(Equal(3))
+	})
+
+	It("should retry the listing throttled on its first pages until it succeeds", func() {
+		client.throttledListPages = 2
+
+		snapList, err := ListWithThrottlingRetries(context.TODO(), store)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(snapList).To(HaveLen(3))
+		Expect(client.throttledListPages).To(BeZero())
+	})
+
+	It("should only retry the throttled page of the listing", func() {
+		DeferCleanup(SetListThrottlingPageSize(1))
+		firstPage, _, err := store.(brtypes.PagedSnapStore).ListPaged("", 1)
+		Expect(err).ShouldNot(HaveOccurred())
+		client.listObjectsMarkers = nil
+		client.throttledListPages = 1
+
+		snapList, err := ListWithThrottlingRetries(context.TODO(), store)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(snapList).To(HaveLen(3))
+		Expect(client.listObjectsMarkers).To(HaveLen(4))
+		// the throttled first page is listed again, and the pages after it are listed once
+		Expect(client.listObjectsMarkers[0]).To(BeEmpty())
+		Expect(client.listObjectsMarkers[1]).To(BeEmpty())
+		Expect(client.listObjectsMarkers[2]).To(HaveSuffix(firstPage[0].SnapName))
+	})
+
+	It("should not retry the listing of another snapstore with the retries of the snapstore", func() {
+		otherStore := NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+		SetListThrottlingRetries(otherStore, 0, time.Millisecond)
+		client.throttledListPages = 2
+
+		_, err := ListWithThrottlingRetries(context.TODO(), otherStore)
+		Expect(IsThrottlingError(err)).To(BeTrue())
+		snapList, err := ListWithThrottlingRetries(context.TODO(), store)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(snapList).To(HaveLen(3))
+	})
+
+	It("should stop retrying the listing once the context is done", func() {
+		SetListThrottlingRetries(store, 3, time.Hour)
+		client.throttledListPages = 1
+		ctx, cancel := context.WithCancel(context.TODO())
+		cancel()
+
+		_, err := ListWithThrottlingRetries(ctx, store)
+		Expect(IsThrottlingError(err)).To(BeTrue())
+		Expect(client.throttledListPages).To(BeZero())
+	})
+
+	It("should fail once the listing is throttled more often than it is retried", func() {
+		client.throttledListPages = 5
+
+		_, err := ListWithThrottlingRetries(context.TODO(), store)
+		Expect(IsThrottlingError(err)).To(BeTrue())
+		// the listing is run once, and retried 3 times
+		Expect(client.throttledListPages).To(Equal(1))
+	})
+
+	It("should not retry the listing if the retries are disabled", func() {
+		SetListThrottlingRetries(store, 0, time.Millisecond)
+		client.throttledListPages = 1
+
+		_, err := ListWithThrottlingRetries(context.TODO(), store)
+		Expect(IsThrottlingError(err)).To(BeTrue())
+		Expect(client.throttledListPages).To(BeZero())
+	})
+
+	It("should not retry the listing if it fails for another reason", func() {
+		var calls int
+		err := RetryListOnThrottling(context.TODO(), store, func() error {
+			calls++
+			return awserr.New("AccessDenied", "access denied", nil)
+		})
+		Expect(err).Should(MatchError(ContainSubstring("AccessDenied")))
+		Expect(calls).To(Equal(1))
+	})
+})
+
+var _ = Describe("Holding the restore lock", func() {
+	var (
+		store     brtypes.SnapStore
+		lockStore brtypes.RestoreLockSnapStore
+	)
+
+	itShouldHoldTheRestoreLock := func() {
+		It("should reject another restoration while the restore lock is held", func() {
+			lock, err := AcquireRestoreLock(store, "first", "host-0", time.Hour)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(lock.Holder).To(Equal("first"))
+
+			_, err = AcquireRestoreLock(store, "second", "host-1", time.Hour)
+			Expect(err).Should(MatchError(ErrRestoreLockExists))
+			Expect(err).Should(MatchError(ContainSubstring("held by first on host host-0")))
+
+			// the restore lock isn't released by another restoration
+			Expect(ReleaseRestoreLock(store, "second")).To(Succeed())
+			current, err := FetchRestoreLock(store)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(current.Holder).To(Equal("first"))
+
+			Expect(ReleaseRestoreLock(store, "first")).To(Succeed())
+			current, err = FetchRestoreLock(store)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(current).To(BeNil())
+
+			_, err = AcquireRestoreLock(store, "second", "host-1", time.Hour)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("should take over a stale restore lock", func() {
+			_, err := AcquireRestoreLock(store, "first", "host-0", -time.Second)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			_, err = AcquireRestoreLock(store, "second", "host-1", time.Hour)
+			Expect(err).ShouldNot(HaveOccurred())
+			current, err := FetchRestoreLock(store)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(current.Holder).To(Equal("second"))
+		})
+
+		It("should neither replace nor release a stale restore lock which was taken over in the meantime", func() {
+			_, err := AcquireRestoreLock(store, "first", "host-0", -time.Second)
+			Expect(err).ShouldNot(HaveOccurred())
+			stale, err := lockStore.FetchRestoreLock()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(stale.Version).NotTo(BeEmpty())
+
+			_, err = AcquireRestoreLock(store, "second", "host-1", time.Hour)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			// a restoration which found the same stale restore lock fails to take it over late
+			Expect(lockStore.ReplaceRestoreLock(stale, &brtypes.RestoreLock{Holder: "third", ExpiresOn: time.Now().Add(time.Hour)})).Should(MatchError(ErrRestoreLockExists))
+			current, err := FetchRestoreLock(store)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(current.Holder).To(Equal("second"))
+
+			// the restoration which held the stale restore lock doesn't release the taken over one
+			Expect(ReleaseRestoreLock(store, "first")).To(Succeed())
+			current, err = FetchRestoreLock(store)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(current.Holder).To(Equal("second"))
+		})
+
+		It("should not list the restore lock as a snapshot", func() {
+			_, err := AcquireRestoreLock(store, "first", "host-0", time.Hour)
+			Expect(err).ShouldNot(HaveOccurred())
+			snapList, err := store.List()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(snapList).To(BeEmpty())
+		})
+	}
+
+	Context("with the mock S3 snapstore", func() {
+		BeforeEach(func() {
+			resetObjectMap()
+			client := &mockS3Client{
+				objects:          objectMap,
+				prefix:           prefixV2,
+				multiPartUploads: map[string]*[][]byte{},
+			}
+			s3Store := NewS3FromClient(bucket, prefixV2, "/tmp", 5, brtypes.MinChunkSize, client, SSECredentials{})
+			store, lockStore = s3Store, s3Store
+		})
+
+		AfterEach(func() {
+			resetObjectMap()
+		})
+
+		itShouldHoldTheRestoreLock()
+	})
+
+	Context("with the local snapstore", func() {
+		BeforeEach(func() {
+			localStore, err := NewLocalSnapStore(path.Join(GinkgoT().TempDir(), prefixV2))
+			Expect(err).ShouldNot(HaveOccurred())
+			store, lockStore = NewDatePartitionedSnapStore(localStore), localStore
+		})
+
+		itShouldHoldTheRestoreLock()
+
+		It("should let only one of the concurrent restorations take over a stale restore lock", func() {
+			_, err := AcquireRestoreLock(store, "stale", "host", -time.Second)
+			Expect(err).ShouldNot(HaveOccurred())
+			var (
+				wg       sync.WaitGroup
+				acquired atomic.Int32
+			)
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer GinkgoRecover()
+					defer wg.Done()
+					if _, err := AcquireRestoreLock(store, fmt.Sprintf("restoration-%d", i), "host", time.Hour); err == nil {
+						acquired.Add(1)
+					} else {
+						Expect(err).Should(MatchError(ErrRestoreLockExists))
+					}
+				}(i)
+			}
+			wg.Wait()
+			Expect(acquired.Load()).To(Equal(int32(1)))
+		})
+
+		It("should let only one of the concurrent restorations acquire the restore lock", func() {
+			var (
+				wg       sync.WaitGroup
+				acquired atomic.Int32
+			)
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer GinkgoRecover()
+					defer wg.Done()
+					if _, err := AcquireRestoreLock(store, fmt.Sprintf("restoration-%d", i), "host", time.Hour); err == nil {
+						acquired.Add(1)
+					} else {
+						Expect(err).Should(MatchError(ErrRestoreLockExists))
+					}
+				}(i)
+			}
+			wg.Wait()
+			Expect(acquired.Load()).To(Equal(int32(1)))
+		})
+	})
+
+	Context("with a snapstore which doesn't support the restore lock", func() {
+		It("should fail to acquire the restore lock", func() {
+			_, err := AcquireRestoreLock(NewFailedSnapStore(), "first", "host-0", time.Hour)
+			Expect(err).Should(MatchError(ContainSubstring("snapstore does not support the restore lock")))
+		})
+	})
+})
+
+var _ = Describe("Saving the snapshots of each kind under a prefix of their own", func() {
+	var (
+		dir                                 string
+		store                               brtypes.SnapStore
+		fullSnap, deltaSnap1, deltaSnap2    *brtypes.Snapshot
+		mainPrefix, fullPrefix, deltaPrefix string
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		mainPrefix = path.Join(dir, prefixV2)
+		fullPrefix = path.Join(dir, "fulls", prefixV2)
+		deltaPrefix = path.Join(dir, "deltas", prefixV2)
+		mainStore, err := NewLocalSnapStore(mainPrefix)
+		Expect(err).ShouldNot(HaveOccurred())
+		fullStore, err := NewLocalSnapStore(fullPrefix)
+		Expect(err).ShouldNot(HaveOccurred())
+		deltaStore, err := NewLocalSnapStore(deltaPrefix)
+		Expect(err).ShouldNot(HaveOccurred())
+		store = NewKindPrefixedSnapStore(mainStore, fullStore, deltaStore)
+
+		now := time.Now().UTC().Truncate(time.Second)
+		fullSnap = &brtypes.Snapshot{Kind: brtypes.SnapshotKindFull, StartRevision: 0, LastRevision: 100, CreatedOn: now.Add(-2 * time.Minute)}
+		deltaSnap1 = &brtypes.Snapshot{Kind: brtypes.SnapshotKindDelta, StartRevision: 101, LastRevision: 200, CreatedOn: now.Add(-time.Minute)}
+		deltaSnap2 = &brtypes.Snapshot{Kind: brtypes.SnapshotKindDelta, StartRevision: 201, LastRevision: 300, CreatedOn: now}
+		for _, snap := range []*brtypes.Snapshot{fullSnap, deltaSnap1, deltaSnap2} {
+			snap.GenerateSnapshotName()
+			Expect(store.Save(*snap, io.NopCloser(strings.NewReader(snap.SnapName)))).To(Succeed())
+		}
+	})
+
+	It("should save the snapshots of each kind under the prefix of their kind", func() {
+		Expect(path.Join(fullPrefix, fullSnap.SnapName)).To(BeARegularFile())
+		Expect(path.Join(deltaPrefix, deltaSnap1.SnapName)).To(BeARegularFile())
+		Expect(path.Join(deltaPrefix, deltaSnap2.SnapName)).To(BeARegularFile())
+		Expect(os.ReadDir(mainPrefix)).To(BeEmpty())
+	})
+
+	It("should list, fetch and delete the snapshots of both prefixes", func() {
+		snapList, err := store.List()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(snapList).To(HaveLen(3))
+		Expect(snapList[0].SnapName).To(Equal(fullSnap.SnapName))
+		Expect(path.Clean(snapList[0].Prefix)).To(Equal(fullPrefix))
+		Expect(snapList[1].SnapName).To(Equal(deltaSnap1.SnapName))
+		Expect(path.Clean(snapList[1].Prefix)).To(Equal(deltaPrefix))
+		Expect(snapList[2].SnapName).To(Equal(deltaSnap2.SnapName))
+
+		for _, snap := range snapList {
+			rc, err := store.Fetch(*snap)
+			Expect(err).ShouldNot(HaveOccurred())
+			content, err := io.ReadAll(rc)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rc.Close()).To(Succeed())
+			Expect(string(content)).To(Equal(snap.SnapName))
+		}
+
+		Expect(store.Delete(*snapList[1])).To(Succeed())
+		Expect(path.Join(deltaPrefix, deltaSnap1.SnapName)).NotTo(BeAnExistingFile())
+		snapList, err = store.List()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(snapList).To(HaveLen(2))
+	})
+
+	It("should assemble the chain of snapshots spanning both prefixes", func() {
+		baseSnap, deltaSnapList, err := miscellaneous.GetLatestFullSnapshotAndDeltaSnapList(NewDatePartitionedSnapStore(store))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(baseSnap.SnapName).To(Equal(fullSnap.SnapName))
+		Expect(path.Clean(baseSnap.Prefix)).To(Equal(fullPrefix))
+		Expect(deltaSnapList).To(HaveLen(2))
+		Expect(deltaSnapList[0].SnapName).To(Equal(deltaSnap1.SnapName))
+		Expect(path.Clean(deltaSnapList[0].Prefix)).To(Equal(deltaPrefix))
+		Expect(deltaSnapList[1].SnapName).To(Equal(deltaSnap2.SnapName))
+	})
+
+	It("should list the snapshots saved under the prefix of the snapstore before the prefixes of the kinds were configured", func() {
+		mainStore, err := NewLocalSnapStore(mainPrefix)
+		Expect(err).ShouldNot(HaveOccurred())
+		oldSnap := &brtypes.Snapshot{Kind: brtypes.SnapshotKindFull, StartRevision: 0, LastRevision: 50, CreatedOn: fullSnap.CreatedOn.Add(-time.Hour)}
+		oldSnap.GenerateSnapshotName()
+		Expect(mainStore.Save(*oldSnap, io.NopCloser(strings.NewReader(oldSnap.SnapName)))).To(Succeed())
+
+		snapList, err := store.List()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(snapList).To(HaveLen(4))
+		Expect(snapList[0].SnapName).To(Equal(oldSnap.SnapName))
+		Expect(path.Clean(snapList[0].Prefix)).To(Equal(mainPrefix))
+	})
+
+	It("should save the chain manifest with the prefixes of the kinds of the snapshots", func() {
+		full, delta := *fullSnap, *deltaSnap1
+		full.Prefix, delta.Prefix = "", ""
+		manifest := &brtypes.ChainManifest{
+			UpdatedOn:      time.Now().UTC(),
+			LatestRevision: delta.LastRevision,
+			FullSnapshot:   &brtypes.ChainManifestSnapshot{Snapshot: full},
+			DeltaSnapshots: []*brtypes.ChainManifestSnapshot{{Snapshot: delta}},
+		}
+		Expect(SaveChainManifest(store, manifest)).To(Succeed())
+		Expect(path.Join(mainPrefix, brtypes.ChainManifestName)).To(BeARegularFile())
+
+		manifest, err := FetchChainManifest(store)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(manifest.FullSnapshot.Prefix).To(Equal(fullPrefix))
+		Expect(manifest.DeltaSnapshots[0].Prefix).To(Equal(deltaPrefix))
+		Expect(VerifyChainManifest(store, manifest)).To(Succeed())
+	})
+
+	It("should create the snapstores of the configured prefixes", func() {
+		config := &brtypes.SnapstoreConfig{
+			Provider:            brtypes.SnapstoreProviderLocal,
+			Container:           "../../../test/output/kind-prefixes",
+			FullSnapshotPrefix:  path.Join("fulls", prefixV2),
+			DeltaSnapshotPrefix: path.Join("deltas", prefixV2),
+		}
+		defer os.RemoveAll(config.Container)
+		configuredStore, err := GetSnapstore(config)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(configuredStore.Save(*fullSnap, io.NopCloser(strings.NewReader(fullSnap.SnapName)))).To(Succeed())
+		Expect(configuredStore.Save(*deltaSnap1, io.NopCloser(strings.NewReader(deltaSnap1.SnapName)))).To(Succeed())
+
+		Expect(path.Join(config.Container, "fulls", prefixV2, fullSnap.SnapDir, fullSnap.SnapName)).To(BeARegularFile())
+		Expect(path.Join(config.Container, "deltas", prefixV2, deltaSnap1.SnapDir, deltaSnap1.SnapName)).To(BeARegularFile())
+		snapList, err := configuredStore.List()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(snapList).To(HaveLen(2))
+	})
+})
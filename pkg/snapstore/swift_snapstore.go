@@ -6,12 +6,14 @@ package snapstore
 
 import (
 	"bytes"
+	"crypto/md5"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"math"
+	"net/http"
 	"os"
 	"path"
 	"path/filepath"
@@ -36,10 +38,16 @@ const (
 	authTypeV3ApplicationCredential = "v3applicationcredential"
 	swiftCredentialDirectory        = "OPENSTACK_APPLICATION_CREDENTIALS"
 	swiftCredentialJSONFile         = "OPENSTACK_APPLICATION_CREDENTIALS_JSON"
+	swiftObjectManifestHeader       = "X-Object-Manifest"
+	// swiftSnapshotExcludeMetadataKey is the metadata key of the exclude tag in its canonical header form, in which
+	// the metadata of an object is returned.
+	swiftSnapshotExcludeMetadataKey = "X-Etcd-Snapshot-Exclude"
 )
 
 // SwiftSnapStore is snapstore with Openstack Swift as backend
 type SwiftSnapStore struct {
+	snapshotNaming
+	listThrottling
 	prefix string
 	client *gophercloud.ServiceClient
 	bucket string
@@ -91,10 +99,13 @@ func NewSwiftSnapStore(config *brtypes.SnapstoreConfig) (*SwiftSnapStore, error)
 	// cache your credentials in memory, and to allow Gophercloud to attempt to
 	// re-authenticate automatically if/when your token expires.
 	authOpts.AllowReauth = true
-	provider, err := openstack.AuthenticatedClient(*authOpts)
+	provider, err := openstack.NewClient(authOpts.IdentityEndpoint)
 	if err != nil {
 		return nil, err
-
+	}
+	provider.HTTPClient = http.Client{Transport: sharedHTTPTransport(config)}
+	if err := openstack.Authenticate(provider, *authOpts); err != nil {
+		return nil, err
 	}
 	client, err := openstack.NewObjectStorageV1(provider, gophercloud.EndpointOpts{
 		Region: os.Getenv("OS_REGION_NAME"),
@@ -420,10 +431,16 @@ func (s *SwiftSnapStore) uploadChunk(snap *brtypes.Snapshot, file *os.File, offs
 	}
 
 	sr := io.NewSectionReader(file, offset, size)
+	// the ETag lets Swift reject the segment if it was corrupted in transit.
+	etag := md5.New()
+	if err := computeChecksum(sr, etag); err != nil {
+		return err
+	}
 
 	opts := objects.CreateOpts{
 		Content:       sr,
 		ContentLength: size,
+		ETag:          fmt.Sprintf("%x", etag.Sum(nil)),
 	}
 	partNumber := ((offset / chunkSize) + 1)
 	res := objects.Create(s.client, s.bucket, path.Join(adaptPrefix(snap, s.prefix), snap.SnapDir, snap.SnapName, fmt.Sprintf("%010d", partNumber)), opts)
@@ -452,14 +469,9 @@ func (s *SwiftSnapStore) chunkUploader(wg *sync.WaitGroup, stopCh <-chan struct{
 
 // List will return sorted list with all snapshot files on store.
 func (s *SwiftSnapStore) List() (brtypes.SnapList, error) {
-	prefixTokens := strings.Split(s.prefix, "/")
-	// Last element of the tokens is backup version
-	// Consider the parent of the backup version level (Required for Backward Compatibility)
-	prefix := path.Join(strings.Join(prefixTokens[:len(prefixTokens)-1], "/"))
-
 	opts := &objects.ListOpts{
 		Full:   false,
-		Prefix: prefix,
+		Prefix: s.listPrefix(),
 	}
 	// Retrieve a pager (i.e. a paginated collection)
 	pager := objects.List(s.client, s.bucket, opts)
@@ -471,17 +483,7 @@ func (s *SwiftSnapStore) List() (brtypes.SnapList, error) {
 		if err != nil {
 			return false, err
 		}
-		for _, object := range objectList {
-			if strings.Contains(object, backupVersionV1) || strings.Contains(object, backupVersionV2) {
-				snap, err := ParseSnapshot(object)
-				if err != nil {
-					// Warning: the file can be a non snapshot file. Do not return error.
-					logrus.Warnf("Invalid snapshot found. Ignoring it:%s, %v", object, err)
-				} else {
-					snapList = append(snapList, snap)
-				}
-			}
-		}
+		snapList = append(snapList, s.parseSnapshotsFromObjectNames(objectList)...)
 		return true, nil
 
 	})
@@ -493,6 +495,68 @@ func (s *SwiftSnapStore) List() (brtypes.SnapList, error) {
 	return snapList, nil
 }
 
+// ListPaged will return sorted list with at most limit snapshot files on store, which are stored after the given marker
+// in the lexicographical order of the object names. The returned marker is empty after the last page.
+func (s *SwiftSnapStore) ListPaged(marker string, limit int) (brtypes.SnapList, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("limit should be greater than zero")
+	}
+	opts := &objects.ListOpts{
+		Full:   false,
+		Prefix: s.listPrefix(),
+		Marker: marker,
+		Limit:  limit,
+	}
+	var objectList []string
+	// The server may cap the pages below the limit, hence the pages are listed until the limit is reached or the
+	// last page has been listed.
+	err := objects.List(s.client, s.bucket, opts).EachPage(func(page pagination.Page) (bool, error) {
+		names, err := objects.ExtractNames(page)
+		if err != nil {
+			return false, err
+		}
+		objectList = append(objectList, names...)
+		return len(objectList) < limit, nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	var nextMarker string
+	if len(objectList) >= limit {
+		objectList = objectList[:limit]
+		nextMarker = objectList[len(objectList)-1]
+	}
+
+	snapList := s.parseSnapshotsFromObjectNames(objectList)
+	sort.Sort(snapList)
+	return snapList, nextMarker, nil
+}
+
+// listPrefix returns the prefix the snapshots are listed under.
+func (s *SwiftSnapStore) listPrefix() string {
+	prefixTokens := strings.Split(s.prefix, "/")
+	// Last element of the tokens is backup version
+	// Consider the parent of the backup version level (Required for Backward Compatibility)
+	return path.Join(strings.Join(prefixTokens[:len(prefixTokens)-1], "/"))
+}
+
+// parseSnapshotsFromObjectNames returns the snapshots of the given objects, ignoring the objects which aren't snapshots.
+func (s *SwiftSnapStore) parseSnapshotsFromObjectNames(objectList []string) brtypes.SnapList {
+	var snapList brtypes.SnapList
+	for _, object := range objectList {
+		if strings.Contains(object, backupVersionV1) || strings.Contains(object, backupVersionV2) {
+			snap, err := s.parseSnapshot(object)
+			if err != nil {
+				// Warning: the file can be a non snapshot file. Do not return error.
+				logrus.Warnf("Invalid snapshot found. Ignoring it:%s, %v", object, err)
+			} else {
+				snapList = append(snapList, snap)
+			}
+		}
+	}
+	return snapList
+}
+
 func (s *SwiftSnapStore) getSnapshotChunks(snapshot brtypes.Snapshot) (brtypes.SnapList, error) {
 	snaps, err := s.List()
 	if err != nil {
@@ -563,6 +627,97 @@ func (s *SwiftSnapStore) Delete(snap brtypes.Snapshot) error {
 	return objects.Delete(s.client, s.bucket, path.Join(snap.Prefix, snap.SnapDir, snap.SnapName), nil).Err
 }
 
+// SetRetained sets the exclude tag as metadata on the snapshot object if retained is true, and clears it otherwise.
+// The other metadata of the snapshot object is left untouched.
+func (s *SwiftSnapStore) SetRetained(snap brtypes.Snapshot, retained bool) error {
+	objectName := path.Join(snap.Prefix, snap.SnapDir, snap.SnapName)
+	result := objects.Get(s.client, s.bucket, objectName, nil)
+	metadata, err := result.ExtractMetadata()
+	if err != nil {
+		return fmt.Errorf("failed to get metadata of object %s: %v", objectName, err)
+	}
+	delete(metadata, swiftSnapshotExcludeMetadataKey)
+	opts := swiftObjectUpdateOpts{
+		UpdateOpts: objects.UpdateOpts{Metadata: metadata},
+		manifest:   result.Header.Get(swiftObjectManifestHeader),
+	}
+	if retained {
+		metadata[swiftSnapshotExcludeMetadataKey] = "true"
+	} else {
+		opts.RemoveMetadata = []string{swiftSnapshotExcludeMetadataKey}
+	}
+	if err := objects.Update(s.client, s.bucket, objectName, opts).Err; err != nil {
+		return fmt.Errorf("failed to update metadata of object %s: %v", objectName, err)
+	}
+	return nil
+}
+
+// IsRetained returns whether the exclude tag is set as metadata on the snapshot object.
+func (s *SwiftSnapStore) IsRetained(snap brtypes.Snapshot) (bool, error) {
+	objectName := path.Join(snap.Prefix, snap.SnapDir, snap.SnapName)
+	metadata, err := objects.Get(s.client, s.bucket, objectName, nil).ExtractMetadata()
+	if err != nil {
+		return false, fmt.Errorf("failed to get metadata of object %s: %v", objectName, err)
+	}
+	_, ok := metadata[swiftSnapshotExcludeMetadataKey]
+	return ok, nil
+}
+
+// Verify checks that the container exists and is accessible by listing a single object under the prefix, and if write
+// is true, that an object can be written under the prefix, by writing and deleting a small object.
+func (s *SwiftSnapStore) Verify(write bool) error {
+	opts := &objects.ListOpts{Prefix: s.prefix, Limit: 1}
+	// only the first page is listed, as a single object is enough to verify the access to the container
+	err := objects.List(s.client, s.bucket, opts).EachPage(func(pagination.Page) (bool, error) {
+		return false, nil
+	})
+	if err != nil {
+		var (
+			notFoundErr     gophercloud.ErrDefault404
+			unauthorizedErr gophercloud.ErrDefault401
+			forbiddenErr    gophercloud.ErrDefault403
+		)
+		switch {
+		case errors.As(err, &notFoundErr):
+			return newVerificationError(CredentialsFailureBucketMissing, "container %s does not exist", s.bucket)
+		case errors.As(err, &unauthorizedErr), errors.As(err, &forbiddenErr):
+			return newVerificationError(CredentialsFailureAuth, "access to container %s is denied: %w", s.bucket, err)
+		}
+		return fmt.Errorf("failed to access container %s: %w", s.bucket, err)
+	}
+	if !write {
+		return nil
+	}
+
+	name := path.Join(s.prefix, verificationObjectName)
+	if res := objects.Create(s.client, s.bucket, name, objects.CreateOpts{Content: bytes.NewReader(nil)}); res.Err != nil {
+		return fmt.Errorf("failed to write to container %s: %v", s.bucket, res.Err)
+	}
+	if res := objects.Delete(s.client, s.bucket, name, nil); res.Err != nil {
+		return fmt.Errorf("failed to delete from container %s: %v", s.bucket, res.Err)
+	}
+	return nil
+}
+
+// swiftObjectUpdateOpts are the options to update the metadata of an object, which keep the manifest of an object
+// uploaded in segments, as updating the metadata of the manifest object without it turns it into a plain object.
+type swiftObjectUpdateOpts struct {
+	objects.UpdateOpts
+	manifest string
+}
+
+// ToObjectUpdateMap formats the swiftObjectUpdateOpts into a map of headers.
+func (opts swiftObjectUpdateOpts) ToObjectUpdateMap() (map[string]string, error) {
+	h, err := opts.UpdateOpts.ToObjectUpdateMap()
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.manifest) != 0 {
+		h[swiftObjectManifestHeader] = opts.manifest
+	}
+	return h, nil
+}
+
 // GetSwiftCredentialsLastModifiedTime returns the latest modification timestamp of the Swift credential file(s)
 func GetSwiftCredentialsLastModifiedTime() (time.Time, error) {
 	// TODO: @renormalize Remove this extra handling in v0.31.0
@@ -13,6 +13,7 @@ import (
 	"net/http"
 	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -23,7 +24,16 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-var objectMapMutex sync.Mutex
+var (
+	objectMapMutex sync.Mutex
+	// objectManifests holds the manifest of the manifest objects of the snapshots uploaded in segments by their name.
+	objectManifests = map[string]string{}
+	// objectETags holds the ETag header sent along with the uploaded objects by their name.
+	objectETags = map[string]string{}
+)
+
+// swiftObjectMetadataHeaderPrefix is the prefix of the headers carrying the metadata of an object.
+const swiftObjectMetadataHeaderPrefix = "X-Object-Meta-"
 
 // initializeMockSwiftServer registers the handlers for different operation on swift
 func initializeMockSwiftServer(t *testing.T) {
@@ -49,10 +59,15 @@ func initializeMockSwiftServer(t *testing.T) {
 				handleBulkDeleteObject(w, r)
 			}
 			handleDeleteObject(w, r)
+		case "HEAD":
+			th.TestMethod(t, r, "HEAD")
+			handleGetObjectMetadata(w, r)
 		case "POST":
 			th.TestMethod(t, r, "POST")
 			if r.URL.RawQuery == "bulk-delete=true" {
 				handleBulkDeleteObject(w, r)
+			} else {
+				handleUpdateObjectMetadata(w, r)
 			}
 		default:
 			w.WriteHeader(http.StatusMethodNotAllowed)
@@ -82,6 +97,15 @@ func handleCreateTextObject(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		content = buf.Bytes()
+		if etag := r.Header.Get("ETag"); etag != "" {
+			if etag != fmt.Sprintf("%x", md5.Sum(content)) {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				return
+			}
+			objectMapMutex.Lock()
+			objectETags[key] = etag
+			objectMapMutex.Unlock()
+		}
 		objectMapMutex.Lock()
 		objectMap[key] = &content
 		objectMapMutex.Unlock()
@@ -90,6 +114,7 @@ func handleCreateTextObject(w http.ResponseWriter, r *http.Request) {
 		content = make([]byte, 0)
 		objectMapMutex.Lock()
 		objectMap[key] = &content
+		objectManifests[key] = r.Header.Get("X-Object-Manifest")
 		objectMapMutex.Unlock()
 	}
 
@@ -100,6 +125,51 @@ func handleCreateTextObject(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusCreated)
 }
 
+// handleGetObjectMetadata responds with a `Get` response carrying the metadata of the object.
+func handleGetObjectMetadata(w http.ResponseWriter, r *http.Request) {
+	objectMapMutex.Lock()
+	defer objectMapMutex.Unlock()
+
+	key := parseObjectNamefromURL(r.URL)
+	if _, ok := objectMap[key]; !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	for k, v := range objectMetadata[key] {
+		w.Header().Set(swiftObjectMetadataHeaderPrefix+k, v)
+	}
+	if manifest, ok := objectManifests[key]; ok {
+		w.Header().Set("X-Object-Manifest", manifest)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleUpdateObjectMetadata responds with an `Update` response, replacing the metadata of the object by the metadata
+// sent along with the request.
+func handleUpdateObjectMetadata(w http.ResponseWriter, r *http.Request) {
+	objectMapMutex.Lock()
+	defer objectMapMutex.Unlock()
+
+	key := parseObjectNamefromURL(r.URL)
+	if _, ok := objectMap[key]; !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if manifest, ok := objectManifests[key]; ok && r.Header.Get("X-Object-Manifest") != manifest {
+		// the update would turn the manifest object into a plain object
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	metadata := map[string]string{}
+	for k, v := range r.Header {
+		if strings.HasPrefix(k, swiftObjectMetadataHeaderPrefix) {
+			metadata[strings.TrimPrefix(k, swiftObjectMetadataHeaderPrefix)] = v[0]
+		}
+	}
+	objectMetadata[key] = metadata
+	w.WriteHeader(http.StatusAccepted)
+}
+
 // handleDownloadObject creates an HTTP handler at `/testContainer/testObject` on the test handler mux that
 // responds with a `Download` response.
 func handleDownloadObject(w http.ResponseWriter, r *http.Request) {
@@ -125,6 +195,10 @@ func handleDownloadObject(w http.ResponseWriter, r *http.Request) {
 	w.Write(contents)
 }
 
+// swiftContainerListingLimit caps the number of objects listed per page by the fake Swift server if positive, as the
+// container listing limit of a Swift server does.
+var swiftContainerListingLimit int
+
 // handleListObjectNames creates an HTTP handler at `/testContainer` on the test handler mux that
 // responds with a `List` response when only object names are requested.
 func handleListObjectNames(w http.ResponseWriter, r *http.Request) {
@@ -139,8 +213,19 @@ func handleListObjectNames(w http.ResponseWriter, r *http.Request) {
 	}
 	sort.Strings(keys)
 
+	limit := len(keys)
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	if swiftContainerListingLimit > 0 && limit > swiftContainerListingLimit {
+		limit = swiftContainerListingLimit
+	}
 	for _, key := range keys {
-		if strings.Compare(key, marker) > 0 {
+		if strings.Compare(key, marker) > 0 && len(contents) < limit {
 			contents = append(contents, key)
 		}
 	}
@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapstore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+	"github.com/sirupsen/logrus"
+)
+
+// throttlingMarkers are the lower-cased fragments of the error messages with which the object stores reject requests
+// because their request rate is exceeded. The errors are matched by their message, as most of the snapstores don't
+// wrap the errors of the provider SDKs.
+var throttlingMarkers = []string{
+	"slowdown",
+	"slow down",
+	"throttl",
+	"toomanyrequests",
+	"too many requests",
+	"requestlimitexceeded",
+	"ratelimitexceeded",
+	"rate exceeded",
+}
+
+// listThrottlingPageSize is the number of snapshots listed at once by ListWithThrottlingRetries from a snapstore which
+// lists the snapshots page by page, so that only the throttled page is listed again instead of the whole snapstore.
+var listThrottlingPageSize = 1000
+
+// listThrottling holds the retries of the throttled listings of a snapstore, which are the default ones unless the
+// snapstore is configured with others.
+type listThrottling struct {
+	configured bool
+	retries    int
+	backoff    time.Duration
+}
+
+// ListThrottlingRetries returns the number of times a throttled listing of the snapstore is retried, and the backoff
+// before its first retry, which is doubled for every further retry.
+func (t *listThrottling) ListThrottlingRetries() (int, time.Duration) {
+	if !t.configured {
+		return brtypes.DefaultListThrottlingRetries, brtypes.DefaultListThrottlingBackoff
+	}
+	return t.retries, t.backoff
+}
+
+// setListThrottlingRetries sets the retries of the throttled listings of the snapstore.
+func (t *listThrottling) setListThrottlingRetries(retries int, backoff time.Duration) {
+	t.configured, t.retries, t.backoff = true, retries, backoff
+}
+
+// listThrottlingConfigurableSnapStore is a snapstore whose retries of the throttled listings can be configured.
+type listThrottlingConfigurableSnapStore interface {
+	setListThrottlingRetries(retries int, backoff time.Duration)
+}
+
+// getListThrottlingRetries returns the retries of the throttled listings of the given snapstore, looking through the
+// snapstores wrapping the snapstore of the storage provider, or the default ones if it isn't configured with any.
+func getListThrottlingRetries(store brtypes.SnapStore) (int, time.Duration) {
+	if ts, ok := store.(brtypes.ListThrottlingSnapStore); ok {
+		return ts.ListThrottlingRetries()
+	}
+	if ts, ok := unwrapSnapStore(store).(brtypes.ListThrottlingSnapStore); ok {
+		return ts.ListThrottlingRetries()
+	}
+	return brtypes.DefaultListThrottlingRetries, brtypes.DefaultListThrottlingBackoff
+}
+
+// IsThrottlingError checks whether the given error of a snapstore operation is caused by the storage provider
+// throttling the requests, which is expected to be resolved by retrying the operation at a lower rate.
+func IsThrottlingError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var statusErr interface{ StatusCode() int }
+	if errors.As(err, &statusErr) && statusErr.StatusCode() == http.StatusTooManyRequests {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, marker := range throttlingMarkers {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryListOnThrottling runs the given listing of the given snapstore, and retries it with an exponential backoff as
+// long as it fails because the storage provider throttles it, up to the number of retries the snapstore is configured
+// with. The retries are given up once the given context is done.
+func RetryListOnThrottling(ctx context.Context, store brtypes.SnapStore, list func() error) error {
+	retries, backoff := getListThrottlingRetries(store)
+	for retry := 1; ; retry++ {
+		err := list()
+		if err == nil || !IsThrottlingError(err) || retry > retries {
+			return err
+		}
+		logrus.Warnf("Listing of the snapstore is throttled, retrying in %s (retry %d of %d): %v", backoff, retry, retries, err)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// ListWithThrottlingRetries lists the snapshots of the given snapstore, retrying the listing as long as it is throttled.
+// Listing a large snapstore takes many requests, which are more likely to be throttled than a single request, hence
+// the snapstores which list the snapshots page by page are listed one page at a time, and only the throttled page is
+// listed again.
+func ListWithThrottlingRetries(ctx context.Context, store brtypes.SnapStore) (brtypes.SnapList, error) {
+	stores := []brtypes.SnapStore{unwrapPartitionedAndCachingSnapStore(store)}
+	if ks, ok := unwrapKindPrefixedSnapStore(store); ok {
+		stores = ks.snapStores()
+	}
+	snapList := brtypes.SnapList{}
+	seen := map[string]bool{}
+	for _, s := range stores {
+		storeSnapList, err := listPagesWithThrottlingRetries(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		// the listings overlap if the prefixes of a kind prefixed snapstore are nested
+		for _, snap := range storeSnapList {
+			snapPath := path.Join(snap.Prefix, snap.SnapDir, snap.SnapName)
+			if !seen[snapPath] {
+				seen[snapPath] = true
+				snapList = append(snapList, snap)
+			}
+		}
+	}
+	sort.Sort(snapList)
+	return snapList, nil
+}
+
+// listPagesWithThrottlingRetries lists the snapshots of the given snapstore page by page if it supports it, retrying
+// each page as long as it is throttled, and lists the whole snapstore at once otherwise.
+func listPagesWithThrottlingRetries(ctx context.Context, store brtypes.SnapStore) (brtypes.SnapList, error) {
+	var snapList brtypes.SnapList
+	ps, ok := store.(brtypes.PagedSnapStore)
+	if !ok {
+		err := RetryListOnThrottling(ctx, store, func() error {
+			var err error
+			snapList, err = store.List()
+			return err
+		})
+		return snapList, err
+	}
+	var marker string
+	for {
+		var (
+			page       brtypes.SnapList
+			nextMarker string
+		)
+		if err := RetryListOnThrottling(ctx, store, func() error {
+			var err error
+			page, nextMarker, err = ps.ListPaged(marker, listThrottlingPageSize)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		snapList = append(snapList, page...)
+		if nextMarker == "" {
+			return snapList, nil
+		}
+		marker = nextMarker
+	}
+}
@@ -0,0 +1,40 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapstore
+
+import (
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+// unwrapSnapStore returns the snapstore of the storage provider underlying the given snapstore, looking through a date
+// partitioned, a caching and a kind prefixed snapstore, so that the optional capabilities of the storage provider can
+// be asserted on it. The snapstore of the prefix of a kind prefixed snapstore is returned, which holds the objects
+// other than the snapshots.
+func unwrapSnapStore(store brtypes.SnapStore) brtypes.SnapStore {
+	store = unwrapPartitionedAndCachingSnapStore(store)
+	if ks, ok := store.(*KindPrefixedSnapStore); ok {
+		return ks.SnapStore
+	}
+	return store
+}
+
+// unwrapKindPrefixedSnapStore returns the given snapstore as a kind prefixed snapstore, looking through a date
+// partitioned and a caching snapstore.
+func unwrapKindPrefixedSnapStore(store brtypes.SnapStore) (*KindPrefixedSnapStore, bool) {
+	ks, ok := unwrapPartitionedAndCachingSnapStore(store).(*KindPrefixedSnapStore)
+	return ks, ok
+}
+
+// unwrapPartitionedAndCachingSnapStore returns the snapstore underlying the given snapstore if it is a date
+// partitioned and/or a caching snapstore, and the given snapstore otherwise.
+func unwrapPartitionedAndCachingSnapStore(store brtypes.SnapStore) brtypes.SnapStore {
+	if ds, ok := store.(*DatePartitionedSnapStore); ok {
+		store = ds.SnapStore
+	}
+	if cs, ok := store.(*CachingSnapStore); ok {
+		store = cs.SnapStore
+	}
+	return store
+}
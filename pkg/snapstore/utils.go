@@ -5,12 +5,18 @@
 package snapstore
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"hash"
+	"io"
+	"net/http"
 	"os"
 	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
@@ -23,6 +29,7 @@ const (
 	defaultLocalStore         = "default.bkp"
 	backupVersion             = backupVersionV2
 	sourcePrefixString        = "SOURCE_"
+	fetchCacheDir             = "fetch-cache"
 )
 
 // GetSnapstore returns the snapstore object for give storageProvider with specified container
@@ -61,6 +68,84 @@ func GetSnapstore(config *brtypes.SnapstoreConfig) (brtypes.SnapStore, error) {
 		config.MaxParallelChunkUploads = 5
 	}
 
+	if config.MaxIdleConns <= 0 {
+		config.MaxIdleConns = brtypes.DefaultMaxIdleConns
+	}
+	if config.MaxIdleConnsPerHost <= 0 {
+		config.MaxIdleConnsPerHost = brtypes.DefaultMaxIdleConnsPerHost
+	}
+	if config.IdleConnTimeout.Duration <= 0 {
+		config.IdleConnTimeout.Duration = brtypes.DefaultIdleConnTimeout
+	}
+
+	if config.FetchCacheTTL.Duration <= 0 {
+		config.FetchCacheTTL.Duration = brtypes.DefaultFetchCacheTTL
+	}
+
+	store, err := newSnapstore(config)
+	if err != nil {
+		return nil, err
+	}
+	if config.FullSnapshotPrefix != "" || config.DeltaSnapshotPrefix != "" {
+		if store, err = newKindPrefixedSnapStore(config, store); err != nil {
+			return nil, err
+		}
+	}
+	if config.FetchCacheSize > 0 {
+		// The snapshots of different containers are cached separately, as the temporary directory may be shared.
+		cacheDir := path.Join(config.TempDir, fetchCacheDir, config.Provider+"-"+strings.ReplaceAll(config.Container, "/", "_"))
+		if store, err = NewCachingSnapStore(store, cacheDir, config.FetchCacheSize, config.FetchCacheTTL.Duration); err != nil {
+			return nil, err
+		}
+	}
+	if !config.DatePartitionedPrefix {
+		return store, nil
+	}
+	return NewDatePartitionedSnapStore(store), nil
+}
+
+// newKindPrefixedSnapStore returns a kind prefixed snapstore which saves the snapshots of each kind with a prefix of
+// its own in the given config through a snapstore of that prefix, and the other snapshots to the given snapstore.
+func newKindPrefixedSnapStore(config *brtypes.SnapstoreConfig, store brtypes.SnapStore) (brtypes.SnapStore, error) {
+	var fullStore, deltaStore brtypes.SnapStore
+	for _, k := range []struct {
+		prefix string
+		store  *brtypes.SnapStore
+	}{
+		{config.FullSnapshotPrefix, &fullStore},
+		{config.DeltaSnapshotPrefix, &deltaStore},
+	} {
+		if k.prefix == "" || k.prefix == config.Prefix {
+			continue
+		}
+		kindConfig := *config
+		kindConfig.Prefix = k.prefix
+		s, err := newSnapstore(&kindConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create snapstore of prefix %s: %v", k.prefix, err)
+		}
+		*k.store = s
+	}
+	return NewKindPrefixedSnapStore(store, fullStore, deltaStore), nil
+}
+
+// newSnapstore returns the snapstore object of the storage provider of the given config.
+func newSnapstore(config *brtypes.SnapstoreConfig) (brtypes.SnapStore, error) {
+	store, err := newProviderSnapstore(config)
+	if err != nil {
+		return nil, err
+	}
+	if ns, ok := store.(namingSnapStore); ok && config.SnapshotNamer != nil {
+		ns.setSnapshotNamer(config.SnapshotNamer)
+	}
+	if ts, ok := store.(listThrottlingConfigurableSnapStore); ok && config.ListThrottlingBackoff.Duration > 0 {
+		ts.setListThrottlingRetries(config.ListThrottlingRetries, config.ListThrottlingBackoff.Duration)
+	}
+	return store, nil
+}
+
+// newProviderSnapstore returns the snapstore of the storage provider of the given config.
+func newProviderSnapstore(config *brtypes.SnapstoreConfig) (brtypes.SnapStore, error) {
 	switch config.Provider {
 	case brtypes.SnapstoreProviderLocal, "":
 		if config.Container == "" {
@@ -96,6 +181,83 @@ func GetSnapstore(config *brtypes.SnapstoreConfig) (brtypes.SnapStore, error) {
 	}
 }
 
+// NewHTTPTransport returns a new HTTP transport which pools and reuses connections as per the
+// connection pool settings of the given snapstore config. The snapstores share the transports
+// of the same settings, see sharedHTTPTransport.
+func NewHTTPTransport(config *brtypes.SnapstoreConfig) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = config.MaxIdleConns
+	transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = config.IdleConnTimeout.Duration
+	return transport
+}
+
+// httpTransportKey identifies a shared HTTP transport by its connection pool and TLS settings.
+type httpTransportKey struct {
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	idleConnTimeout     time.Duration
+	insecureSkipVerify  bool
+	trustedCACert       string
+}
+
+var (
+	httpTransportsMutex sync.Mutex
+	httpTransports      = map[httpTransportKey]*http.Transport{}
+)
+
+// sharedHTTPTransport returns the HTTP transport shared by the snapstores with the connection pool settings of the
+// given snapstore config, so that the snapstores which are created anew, e.g. by every garbage collection, reuse the
+// pooled connections instead of each leaving a pool of idle connections behind until they time out.
+func sharedHTTPTransport(config *brtypes.SnapstoreConfig) *http.Transport {
+	return sharedTLSHTTPTransport(config, false, nil)
+}
+
+// sharedTLSHTTPTransport returns the HTTP transport like sharedHTTPTransport, which skips verifying the certificates
+// of the servers if insecureSkipVerify is set, or trusts the servers signed by the given CA certificate if it is given.
+func sharedTLSHTTPTransport(config *brtypes.SnapstoreConfig, insecureSkipVerify bool, trustedCACert *string) *http.Transport {
+	key := httpTransportKey{
+		maxIdleConns:        config.MaxIdleConns,
+		maxIdleConnsPerHost: config.MaxIdleConnsPerHost,
+		idleConnTimeout:     config.IdleConnTimeout.Duration,
+		insecureSkipVerify:  insecureSkipVerify,
+	}
+	if trustedCACert != nil {
+		key.insecureSkipVerify, key.trustedCACert = false, *trustedCACert
+	}
+
+	httpTransportsMutex.Lock()
+	defer httpTransportsMutex.Unlock()
+	if transport, ok := httpTransports[key]; ok {
+		return transport
+	}
+	transport := NewHTTPTransport(config)
+	if insecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	if trustedCACert != nil {
+		caCertPool := x509.NewCertPool()
+		caCertPool.AppendCertsFromPEM([]byte(*trustedCACert))
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:            caCertPool,
+			InsecureSkipVerify: false,
+			MinVersion:         tls.VersionTLS13,
+		}
+	}
+	httpTransports[key] = transport
+	return transport
+}
+
+// supportsObjectsAlongsideSnapshots returns whether the snapstores of the given storage provider are able to save the
+// objects other than the snapshots alongside them, which only the Local and the S3 compatible snapstores are.
+func supportsObjectsAlongsideSnapshots(provider string) bool {
+	switch provider {
+	case brtypes.SnapstoreProviderLocal, "", brtypes.SnapstoreProviderS3, brtypes.SnapstoreProviderECS, brtypes.SnapstoreProviderOCS:
+		return true
+	}
+	return false
+}
+
 // GetEnvVarOrError returns the value of specified environment variable or terminates if it's not defined.
 func GetEnvVarOrError(varName string) (string, error) {
 	value := os.Getenv(varName)
@@ -154,6 +316,18 @@ func collectChunkUploadError(chunkUploadCh chan<- chunk, resCh <-chan chunkUploa
 	return nil
 }
 
+// computeChecksum writes the contents of the given reader into the given hash, and seeks back to the start of the
+// reader so that the contents can be uploaded along with their checksum.
+func computeChecksum(r io.ReadSeeker, h hash.Hash) error {
+	if _, err := io.Copy(h, r); err != nil {
+		return fmt.Errorf("failed to compute the checksum of the chunk: %v", err)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek at the start of the chunk: %v", err)
+	}
+	return nil
+}
+
 func getEnvPrefixString(isSource bool) string {
 	if isSource {
 		return sourcePrefixString
@@ -0,0 +1,43 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapstore
+
+import (
+	"fmt"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+	"github.com/sirupsen/logrus"
+)
+
+// verificationObjectName is the name of the object written and deleted under the prefix of a snapstore to verify that
+// objects can be written to it.
+const verificationObjectName = "snapstore-verification"
+
+// VerifySnapstore verifies that the bucket or container of the given snapstore exists and is accessible, so that a
+// misconfigured snapstore fails the process at startup instead of the first snapshot saved hours later. The snapstore
+// is also verified to be writable, unless it is the source of a copy operation or a restoration, which is only read.
+// Nothing is verified if the verification is skipped by the given config, and a snapstore which doesn't support the
+// verification is reported as unverified.
+func VerifySnapstore(store brtypes.SnapStore, config *brtypes.SnapstoreConfig) error {
+	if config.SkipVerification {
+		return nil
+	}
+	stores := []brtypes.SnapStore{unwrapSnapStore(store)}
+	if ks, ok := unwrapKindPrefixedSnapStore(store); ok {
+		// the snapshots of each kind are saved under a prefix of their own
+		stores = ks.snapStores()
+	}
+	for _, s := range stores {
+		vs, ok := s.(brtypes.VerifiableSnapStore)
+		if !ok {
+			logrus.Warnf("Storage provider %s does not support the verification of the snapstore, container %s is left unverified", config.Provider, config.Container)
+			return nil
+		}
+		if err := vs.Verify(!config.IsSource); err != nil {
+			return fmt.Errorf("failed to verify container %s of storage provider %s: %w", config.Container, config.Provider, err)
+		}
+	}
+	return nil
+}
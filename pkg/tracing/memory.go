@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"context"
+	"sync"
+)
+
+// RecordedSpan is a span recorded by the InMemoryTracer.
+type RecordedSpan struct {
+	Name       string
+	Parent     string
+	Attributes map[string]interface{}
+	Err        error
+	Ended      bool
+}
+
+// InMemoryTracer is a tracer which records the spans in memory instead of exporting them.
+type InMemoryTracer struct {
+	mutex sync.Mutex
+	spans []*recordingSpan
+}
+
+// NewInMemoryTracer returns a new InMemoryTracer.
+func NewInMemoryTracer() *InMemoryTracer {
+	return &InMemoryTracer{}
+}
+
+type spanContextKey struct{}
+
+// Start starts a span which is recorded by the tracer.
+func (t *InMemoryTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	span := &recordingSpan{tracer: t, span: RecordedSpan{Name: name, Attributes: map[string]interface{}{}}}
+	if parent, ok := ctx.Value(spanContextKey{}).(*recordingSpan); ok {
+		span.span.Parent = parent.span.Name
+	}
+	span.SetAttributes(attrs...)
+
+	t.mutex.Lock()
+	t.spans = append(t.spans, span)
+	t.mutex.Unlock()
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// Spans returns the spans recorded so far, in the order in which they were started.
+func (t *InMemoryTracer) Spans() []RecordedSpan {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	spans := make([]RecordedSpan, 0, len(t.spans))
+	for _, span := range t.spans {
+		attrs := make(map[string]interface{}, len(span.span.Attributes))
+		for k, v := range span.span.Attributes {
+			attrs[k] = v
+		}
+		recorded := span.span
+		recorded.Attributes = attrs
+		spans = append(spans, recorded)
+	}
+	return spans
+}
+
+// SpansByName returns the recorded spans with the given name.
+func (t *InMemoryTracer) SpansByName(name string) []RecordedSpan {
+	var spans []RecordedSpan
+	for _, span := range t.Spans() {
+		if span.Name == name {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+type recordingSpan struct {
+	tracer *InMemoryTracer
+	span   RecordedSpan
+}
+
+func (s *recordingSpan) SetAttributes(attrs ...Attribute) {
+	s.tracer.mutex.Lock()
+	defer s.tracer.mutex.Unlock()
+	for _, attr := range attrs {
+		s.span.Attributes[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordingSpan) RecordError(err error) {
+	s.tracer.mutex.Lock()
+	defer s.tracer.mutex.Unlock()
+	s.span.Err = err
+}
+
+func (s *recordingSpan) End() {
+	s.tracer.mutex.Lock()
+	defer s.tracer.mutex.Unlock()
+	s.span.Ended = true
+}
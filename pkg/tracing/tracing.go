@@ -0,0 +1,136 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"context"
+	"sync"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+)
+
+const (
+	// SpanTakeFullSnapshot is the name of the span of taking a full snapshot.
+	SpanTakeFullSnapshot = "snapshotter/take-full-snapshot"
+	// SpanTakeDeltaSnapshot is the name of the span of taking a delta snapshot.
+	SpanTakeDeltaSnapshot = "snapshotter/take-delta-snapshot"
+	// SpanRestoreBaseSnapshot is the name of the span of fetching the base snapshot into the data directory during a restoration.
+	SpanRestoreBaseSnapshot = "restorer/restore-base-snapshot"
+	// SpanApplyDeltaSnapshots is the name of the span of replaying the delta snapshots during a restoration.
+	SpanApplyDeltaSnapshots = "restorer/apply-delta-snapshots"
+	// SpanCompact is the name of the span of compacting the restored etcd during a compaction.
+	SpanCompact = "compactor/compact"
+	// SpanDefragment is the name of the span of defragmenting the restored etcd during a compaction.
+	SpanDefragment = "compactor/defragment"
+
+	// AttributeSnapshotName is the name of the traced snapshot.
+	AttributeSnapshotName = "snapshot.name"
+	// AttributeSnapshotKind is the kind of the traced snapshot.
+	AttributeSnapshotKind = "snapshot.kind"
+	// AttributeSnapshotSizeBytes is the uncompressed size of the traced snapshot.
+	AttributeSnapshotSizeBytes = "snapshot.size_bytes"
+	// AttributeStartRevision is the first etcd revision of the traced snapshot(s).
+	AttributeStartRevision = "etcd.revision.start"
+	// AttributeLastRevision is the last etcd revision of the traced snapshot(s).
+	AttributeLastRevision = "etcd.revision.last"
+	// AttributeSnapshotCount is the number of the traced snapshots.
+	AttributeSnapshotCount = "snapshot.count"
+)
+
+// Attribute is a key value pair describing a span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Int64 returns an attribute with the given integer value.
+func Int64(key string, value int64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// String returns an attribute with the given string value.
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// SnapshotAttributes returns the attributes describing the given snapshot.
+func SnapshotAttributes(snap *brtypes.Snapshot) []Attribute {
+	if snap == nil {
+		return nil
+	}
+	attrs := []Attribute{
+		String(AttributeSnapshotName, snap.SnapName),
+		String(AttributeSnapshotKind, snap.Kind),
+		Int64(AttributeStartRevision, snap.StartRevision),
+		Int64(AttributeLastRevision, snap.LastRevision),
+	}
+	if snap.SizeBytes > 0 {
+		attrs = append(attrs, Int64(AttributeSnapshotSizeBytes, snap.SizeBytes))
+	}
+	return attrs
+}
+
+// Span is a traced operation.
+type Span interface {
+	// SetAttributes sets the given attributes on the span.
+	SetAttributes(attrs ...Attribute)
+	// RecordError records the given error as the outcome of the span.
+	RecordError(err error)
+	// End ends the span.
+	End()
+}
+
+// Tracer starts the spans of the traced operations. It is the extension point through which the
+// spans are handed to a tracing backend, for example an OpenTelemetry tracer exporting via OTLP.
+type Tracer interface {
+	// Start starts a span with the given name and attributes as a child of the span in the given context, if any,
+	// and returns the context carrying the started span.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+var (
+	tracerMutex sync.RWMutex
+	tracer      Tracer = noopTracer{}
+)
+
+// SetTracer sets the tracer with which the spans are started. Tracing is disabled if the given tracer is nil.
+func SetTracer(t Tracer) {
+	tracerMutex.Lock()
+	defer tracerMutex.Unlock()
+	if t == nil {
+		t = noopTracer{}
+	}
+	tracer = t
+}
+
+// Start starts a span with the configured tracer. The returned span is a no-op if tracing is disabled.
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	tracerMutex.RLock()
+	t := tracer
+	tracerMutex.RUnlock()
+	return t.Start(ctx, name, attrs...)
+}
+
+// End records the given error, if any, and ends the given span.
+func End(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(_ ...Attribute) {}
+
+func (noopSpan) RecordError(_ error) {}
+
+func (noopSpan) End() {}
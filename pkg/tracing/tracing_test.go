@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing_test
+
+import (
+	"context"
+	"fmt"
+
+	brtypes "github.com/gardener/etcd-backup-restore/pkg/types"
+
+	. "github.com/gardener/etcd-backup-restore/pkg/tracing"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tracing", func() {
+	AfterEach(func() {
+		SetTracer(nil)
+	})
+
+	Context("with tracing disabled", func() {
+		It("should start no-op spans", func() {
+			ctx := context.TODO()
+			spanCtx, span := Start(ctx, SpanTakeFullSnapshot, Int64(AttributeLastRevision, 10))
+			Expect(spanCtx).Should(Equal(ctx))
+			span.SetAttributes(String(AttributeSnapshotName, "Full-00000000-00000010-1518427675"))
+			End(span, fmt.Errorf("failed"))
+		})
+	})
+
+	Context("with an in-memory tracer", func() {
+		var tracer *InMemoryTracer
+
+		BeforeEach(func() {
+			tracer = NewInMemoryTracer()
+			SetTracer(tracer)
+		})
+
+		It("should record the spans along with their attributes and errors", func() {
+			ctx, parent := Start(context.TODO(), SpanRestoreBaseSnapshot, Int64(AttributeLastRevision, 10))
+			_, child := Start(ctx, SpanCompact)
+			child.SetAttributes(Int64(AttributeStartRevision, 1))
+			End(child, fmt.Errorf("failed"))
+			End(parent, nil)
+
+			spans := tracer.Spans()
+			Expect(spans).Should(HaveLen(2))
+			Expect(spans[0]).Should(Equal(RecordedSpan{
+				Name:       SpanRestoreBaseSnapshot,
+				Attributes: map[string]interface{}{AttributeLastRevision: int64(10)},
+				Ended:      true,
+			}))
+			Expect(spans[1].Name).Should(Equal(SpanCompact))
+			Expect(spans[1].Parent).Should(Equal(SpanRestoreBaseSnapshot))
+			Expect(spans[1].Attributes).Should(Equal(map[string]interface{}{AttributeStartRevision: int64(1)}))
+			Expect(spans[1].Err).Should(MatchError("failed"))
+			Expect(spans[1].Ended).Should(BeTrue())
+			Expect(tracer.SpansByName(SpanCompact)).Should(Equal(spans[1:]))
+		})
+	})
+
+	Describe("describing a snapshot", func() {
+		It("should return the attributes of the snapshot", func() {
+			snap := &brtypes.Snapshot{Kind: brtypes.SnapshotKindFull, StartRevision: 0, LastRevision: 10, SnapName: "Full-00000000-00000010-1518427675", SizeBytes: 1024}
+			Expect(SnapshotAttributes(snap)).Should(ConsistOf(
+				String(AttributeSnapshotName, snap.SnapName),
+				String(AttributeSnapshotKind, brtypes.SnapshotKindFull),
+				Int64(AttributeStartRevision, 0),
+				Int64(AttributeLastRevision, 10),
+				Int64(AttributeSnapshotSizeBytes, 1024),
+			))
+		})
+
+		It("should return no attributes without a snapshot", func() {
+			Expect(SnapshotAttributes(nil)).Should(BeEmpty())
+		})
+	})
+})
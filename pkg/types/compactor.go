@@ -35,8 +35,12 @@ type CompactorConfig struct {
 	FullSnapshotLeaseName  string            `json:"fullSnapshotLeaseName,omitempty"`
 	DeltaSnapshotLeaseName string            `json:"deltaSnapshotLeaseName,omitempty"`
 	EnabledLeaseRenewal    bool              `json:"enabledLeaseRenewal"`
+	// SnapshotLeaseNamespace is the namespace of the full and delta snapshot leases. It defaults to the namespace of the pod.
+	SnapshotLeaseNamespace string `json:"snapshotLeaseNamespace,omitempty"`
 	// see https://github.com/gardener/etcd-druid/issues/648
 	MetricsScrapeWaitDuration wrappers.Duration `json:"metricsScrapeWaitDuration,omitempty"`
+	// DeleteCompactedDeltas deletes the delta snapshots compacted into the new base snapshot, which shortens the restoration chain.
+	DeleteCompactedDeltas bool `json:"deleteCompactedDeltas,omitempty"`
 }
 
 // NewCompactorConfig returns the CompactorConfig.
@@ -59,8 +63,10 @@ func (c *CompactorConfig) AddFlags(fs *flag.FlagSet) {
 	fs.DurationVar(&c.DefragTimeout.Duration, "etcd-defrag-timeout", c.DefragTimeout.Duration, "timeout duration for etcd defrag call during compaction.")
 	fs.StringVar(&c.FullSnapshotLeaseName, "full-snapshot-lease-name", c.FullSnapshotLeaseName, "full snapshot lease name")
 	fs.StringVar(&c.DeltaSnapshotLeaseName, "delta-snapshot-lease-name", c.DeltaSnapshotLeaseName, "delta snapshot lease name")
+	fs.StringVar(&c.SnapshotLeaseNamespace, "snapshot-lease-namespace", c.SnapshotLeaseNamespace, "namespace of the full and delta snapshot leases (defaults to the namespace of the pod)")
 	fs.BoolVar(&c.EnabledLeaseRenewal, "enable-snapshot-lease-renewal", c.EnabledLeaseRenewal, "Allows compactor to renew the full snapshot lease when successfully compacted snapshot is uploaded")
 	fs.DurationVar(&c.MetricsScrapeWaitDuration.Duration, "metrics-scrape-wait-duration", c.MetricsScrapeWaitDuration.Duration, "The duration to wait for after compaction is completed, to allow Prometheus metrics to be scraped")
+	fs.BoolVar(&c.DeleteCompactedDeltas, "delete-compacted-deltas", c.DeleteCompactedDeltas, "delete the delta snapshots compacted into the new base snapshot")
 }
 
 // Validate validates the config.
@@ -79,6 +85,7 @@ func (c *CompactorConfig) Validate() error {
 		if len(c.DeltaSnapshotLeaseName) == 0 {
 			return fmt.Errorf("DeltaSnapshotLeaseName can not be an empty string when enable-snapshot-lease-renewal is true")
 		}
+		return validateSnapshotLeases(c.FullSnapshotLeaseName, c.DeltaSnapshotLeaseName, c.SnapshotLeaseNamespace)
 	}
 	return nil
 }
@@ -0,0 +1,144 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	// DeltaSnapshotHashSHA256 is the hash algorithm of the delta snapshots hashed with SHA-256, which is the default.
+	DeltaSnapshotHashSHA256 = "sha256"
+	// DeltaSnapshotHashSHA512 is the hash algorithm of the delta snapshots hashed with SHA-512.
+	DeltaSnapshotHashSHA512 = "sha512"
+	// DeltaSnapshotHashBLAKE2b is the hash algorithm of the delta snapshots hashed with BLAKE2b-256.
+	DeltaSnapshotHashBLAKE2b = "blake2b"
+	// DefaultDeltaSnapshotHashAlgorithm is the default hash algorithm of the delta snapshots.
+	DefaultDeltaSnapshotHashAlgorithm = DeltaSnapshotHashSHA256
+
+	// deltaSnapshotHashTrailerMagic ends the hash trailer of the delta snapshots which are hashed with another hash
+	// algorithm than SHA-256, after the hash and the identifier of the hash algorithm. The delta snapshots hashed with
+	// SHA-256 end with the hash alone, as they did before the hash algorithm was configurable.
+	deltaSnapshotHashTrailerMagic = "\x00ETCDBRH"
+)
+
+// ErrDeltaSnapshotHashMismatch is returned when the hash of the events of a delta snapshot doesn't match its recorded hash.
+var ErrDeltaSnapshotHashMismatch = errors.New("mismatched hash of delta snapshot")
+
+// deltaSnapshotHashAlgorithm describes a hash algorithm of the delta snapshots.
+type deltaSnapshotHashAlgorithm struct {
+	// id identifies the hash algorithm in the hash trailer. It is 0 for SHA-256, which has no hash trailer.
+	id      byte
+	size    int
+	newHash func() hash.Hash
+}
+
+var deltaSnapshotHashAlgorithms = map[string]deltaSnapshotHashAlgorithm{
+	DeltaSnapshotHashSHA256:  {id: 0, size: sha256.Size, newHash: sha256.New},
+	DeltaSnapshotHashSHA512:  {id: 1, size: sha512.Size, newHash: sha512.New},
+	DeltaSnapshotHashBLAKE2b: {id: 2, size: blake2b.Size256, newHash: newBLAKE2b256},
+}
+
+// lookupDeltaSnapshotHashAlgorithm returns the given hash algorithm of the delta snapshots, which is the default one if
+// empty.
+func lookupDeltaSnapshotHashAlgorithm(algorithm string) (deltaSnapshotHashAlgorithm, bool) {
+	if algorithm == "" {
+		algorithm = DefaultDeltaSnapshotHashAlgorithm
+	}
+	a, ok := deltaSnapshotHashAlgorithms[algorithm]
+	return a, ok
+}
+
+func newBLAKE2b256() hash.Hash {
+	// the error is only returned for a key longer than 64 bytes
+	h, _ := blake2b.New256(nil)
+	return h
+}
+
+// ValidateDeltaSnapshotHashAlgorithm returns an error if the given hash algorithm of the delta snapshots isn't supported.
+// An empty hash algorithm stands for the default one.
+func ValidateDeltaSnapshotHashAlgorithm(algorithm string) error {
+	if _, ok := lookupDeltaSnapshotHashAlgorithm(algorithm); !ok {
+		return fmt.Errorf("unsupported delta snapshot hash algorithm %s, should be one of %s, %s or %s", algorithm, DeltaSnapshotHashSHA256, DeltaSnapshotHashSHA512, DeltaSnapshotHashBLAKE2b)
+	}
+	return nil
+}
+
+// NewDeltaSnapshotHash returns a hash of the events of a delta snapshot with the given hash algorithm, whose trailer
+// is appended to the events with DeltaSnapshotHashTrailer.
+func NewDeltaSnapshotHash(algorithm string) (hash.Hash, error) {
+	a, ok := lookupDeltaSnapshotHashAlgorithm(algorithm)
+	if !ok {
+		return nil, ValidateDeltaSnapshotHashAlgorithm(algorithm)
+	}
+	return a.newHash(), nil
+}
+
+// DeltaSnapshotHashTrailer returns the trailer appended to the events of a delta snapshot, which holds the given hash
+// of the events along with the hash algorithm it was computed with, unless it is SHA-256.
+func DeltaSnapshotHashTrailer(algorithm string, sum []byte) ([]byte, error) {
+	a, ok := lookupDeltaSnapshotHashAlgorithm(algorithm)
+	if !ok {
+		return nil, ValidateDeltaSnapshotHashAlgorithm(algorithm)
+	}
+	if a.id == 0 {
+		return sum, nil
+	}
+	trailer := append([]byte{}, sum...)
+	trailer = append(trailer, a.id)
+	return append(trailer, deltaSnapshotHashTrailerMagic...), nil
+}
+
+// DeltaSnapshotHashTrailerSize returns the size of the trailer appended to the events of a delta snapshot hashed with
+// the given hash algorithm, or the size for SHA-256 if the hash algorithm isn't supported.
+func DeltaSnapshotHashTrailerSize(algorithm string) int {
+	a, ok := lookupDeltaSnapshotHashAlgorithm(algorithm)
+	if !ok || a.id == 0 {
+		return sha256.Size
+	}
+	return a.size + 1 + len(deltaSnapshotHashTrailerMagic)
+}
+
+// VerifyDeltaSnapshotHash verifies the contents of a delta snapshot against the hash trailer appended to its events,
+// with the hash algorithm recorded in the trailer, and returns the events. Returns an error wrapping
+// ErrDeltaSnapshotHashMismatch if the hash doesn't match.
+func VerifyDeltaSnapshotHash(data []byte) ([]byte, string, error) {
+	algorithm := DeltaSnapshotHashSHA256
+	a := deltaSnapshotHashAlgorithms[algorithm]
+	trailerSize := a.size
+	if bytes.HasSuffix(data, []byte(deltaSnapshotHashTrailerMagic)) && len(data) > len(deltaSnapshotHashTrailerMagic) {
+		id := data[len(data)-len(deltaSnapshotHashTrailerMagic)-1]
+		var found bool
+		for name, candidate := range deltaSnapshotHashAlgorithms {
+			if candidate.id == id && id != 0 {
+				algorithm, a, found = name, candidate, true
+				break
+			}
+		}
+		if !found {
+			return nil, "", fmt.Errorf("unknown hash algorithm %d of delta snapshot", id)
+		}
+		trailerSize = a.size + 1 + len(deltaSnapshotHashTrailerMagic)
+	}
+	if len(data) <= trailerSize {
+		return nil, algorithm, fmt.Errorf("delta snapshot is missing hash")
+	}
+
+	events := data[:len(data)-trailerSize]
+	sum := data[len(data)-trailerSize : len(data)-trailerSize+a.size]
+	h := a.newHash()
+	h.Write(events)
+	if computed := h.Sum(nil); !bytes.Equal(sum, computed) {
+		return nil, algorithm, fmt.Errorf("%w: expected %s %v, got %v", ErrDeltaSnapshotHashMismatch, algorithm, sum, computed)
+	}
+	return events, algorithm, nil
+}
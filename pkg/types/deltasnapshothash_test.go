@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types_test
+
+import (
+	"crypto/sha256"
+
+	. "github.com/gardener/etcd-backup-restore/pkg/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Hashing the delta snapshots", func() {
+	var events = []byte(`[{"etcdEvent":{"kv":{"key":"Zm9v","value":"YmFy","mod_revision":2}}}]`)
+
+	// hashEvents returns the events of a delta snapshot followed by their hash trailer with the given hash algorithm.
+	hashEvents := func(algorithm string) []byte {
+		h, err := NewDeltaSnapshotHash(algorithm)
+		Expect(err).ShouldNot(HaveOccurred())
+		h.Write(events)
+		trailer, err := DeltaSnapshotHashTrailer(algorithm, h.Sum(nil))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(trailer).Should(HaveLen(DeltaSnapshotHashTrailerSize(algorithm)))
+		return append(append([]byte{}, events...), trailer...)
+	}
+
+	DescribeTable("should verify the delta snapshots with the hash algorithm they were hashed with",
+		func(algorithm string) {
+			verified, verifiedAlgorithm, err := VerifyDeltaSnapshotHash(hashEvents(algorithm))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(verifiedAlgorithm).Should(Equal(algorithm))
+			Expect(verified).Should(Equal(events))
+		},
+		Entry("with SHA-256", DeltaSnapshotHashSHA256),
+		Entry("with SHA-512", DeltaSnapshotHashSHA512),
+		Entry("with BLAKE2b", DeltaSnapshotHashBLAKE2b),
+	)
+
+	DescribeTable("should detect tampered delta snapshots",
+		func(algorithm string) {
+			data := hashEvents(algorithm)
+			data[len(events)/2] ^= 0xff
+			_, _, err := VerifyDeltaSnapshotHash(data)
+			Expect(err).Should(MatchError(ErrDeltaSnapshotHashMismatch))
+		},
+		Entry("with SHA-256", DeltaSnapshotHashSHA256),
+		Entry("with SHA-512", DeltaSnapshotHashSHA512),
+		Entry("with BLAKE2b", DeltaSnapshotHashBLAKE2b),
+	)
+
+	It("should append the SHA-256 hash alone, as the delta snapshots did before the hash algorithm was configurable", func() {
+		hash := sha256.Sum256(events)
+		Expect(hashEvents(DeltaSnapshotHashSHA256)).Should(Equal(append(append([]byte{}, events...), hash[:]...)))
+		Expect(hashEvents("")).Should(Equal(hashEvents(DeltaSnapshotHashSHA256)))
+	})
+
+	It("should reject a delta snapshot missing its hash", func() {
+		_, _, err := VerifyDeltaSnapshotHash(events[:sha256.Size])
+		Expect(err).Should(MatchError(ContainSubstring("delta snapshot is missing hash")))
+	})
+
+	It("should reject an unsupported hash algorithm", func() {
+		Expect(ValidateDeltaSnapshotHashAlgorithm("md5")).Should(MatchError(ContainSubstring("unsupported delta snapshot hash algorithm md5")))
+		_, err := NewDeltaSnapshotHash("md5")
+		Expect(err).Should(HaveOccurred())
+	})
+})
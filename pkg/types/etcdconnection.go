@@ -30,19 +30,39 @@ const (
 type EtcdConnectionConfig struct {
 	// Endpoints are the endpoints from which the backup will be take or defragmentation will be called.
 	// This need not be necessary match the entire etcd cluster.
-	Endpoints          []string          `json:"endpoints"`
-	ServiceEndpoints   []string          `json:"serviceEndpoints,omitempty"`
-	Username           string            `json:"username,omitempty"`
-	Password           string            `json:"password,omitempty"`
-	ConnectionTimeout  wrappers.Duration `json:"connectionTimeout,omitempty"`
-	SnapshotTimeout    wrappers.Duration `json:"snapshotTimeout,omitempty"`
-	DefragTimeout      wrappers.Duration `json:"defragTimeout,omitempty"`
-	InsecureTransport  bool              `json:"insecureTransport,omitempty"`
-	InsecureSkipVerify bool              `json:"insecureSkipVerify,omitempty"`
-	CertFile           string            `json:"certFile,omitempty"`
-	KeyFile            string            `json:"keyFile,omitempty"`
-	CaFile             string            `json:"caFile,omitempty"`
-	MaxCallSendMsgSize int               `json:"maxCallSendMsgSize,omitempty"`
+	Endpoints            []string          `json:"endpoints"`
+	ServiceEndpoints     []string          `json:"serviceEndpoints,omitempty"`
+	Username             string            `json:"username,omitempty"`
+	Password             string            `json:"password,omitempty"`
+	UsernameFile         string            `json:"usernameFile,omitempty"`
+	PasswordFile         string            `json:"passwordFile,omitempty"`
+	ConnectionTimeout    wrappers.Duration `json:"connectionTimeout,omitempty"`
+	KVGetTimeout         wrappers.Duration `json:"kvGetTimeout,omitempty"`
+	WatchSetupTimeout    wrappers.Duration `json:"watchSetupTimeout,omitempty"`
+	SnapshotTimeout      wrappers.Duration `json:"snapshotTimeout,omitempty"`
+	SnapshotTimeoutPerGB wrappers.Duration `json:"snapshotTimeoutPerGB,omitempty"`
+	MinSnapshotTimeout   wrappers.Duration `json:"minSnapshotTimeout,omitempty"`
+	MaxSnapshotTimeout   wrappers.Duration `json:"maxSnapshotTimeout,omitempty"`
+	DefragTimeout        wrappers.Duration `json:"defragTimeout,omitempty"`
+	InsecureTransport    bool              `json:"insecureTransport,omitempty"`
+	InsecureSkipVerify   bool              `json:"insecureSkipVerify,omitempty"`
+	CertFile             string            `json:"certFile,omitempty"`
+	KeyFile              string            `json:"keyFile,omitempty"`
+	CaFile               string            `json:"caFile,omitempty"`
+	MaxCallSendMsgSize   int               `json:"maxCallSendMsgSize,omitempty"`
+	PeerTLSConfig
+}
+
+// PeerTLSConfig holds the TLS files with which an etcd member secures the communication with its peers.
+type PeerTLSConfig struct {
+	PeerCertFile string `json:"peerCertFile,omitempty"`
+	PeerKeyFile  string `json:"peerKeyFile,omitempty"`
+	PeerCaFile   string `json:"peerCaFile,omitempty"`
+}
+
+// PeerTLSEnabled returns whether the peer communication is secured with TLS.
+func (c PeerTLSConfig) PeerTLSEnabled() bool {
+	return c.PeerCertFile != "" && c.PeerKeyFile != ""
 }
 
 // NewEtcdConnectionConfig returns etcd connection config.
@@ -63,14 +83,24 @@ func (c *EtcdConnectionConfig) AddFlags(fs *flag.FlagSet) {
 	fs.StringSliceVar(&c.ServiceEndpoints, "service-endpoints", c.ServiceEndpoints, "comma separated list of etcd endpoints that are used for etcd-backup-restore to connect to etcd through a (Kubernetes) service")
 	fs.StringVar(&c.Username, "etcd-username", c.Username, "etcd server username, if one is required")
 	fs.StringVar(&c.Password, "etcd-password", c.Password, "etcd server password, if one is required")
+	fs.StringVar(&c.UsernameFile, "etcd-username-file", c.UsernameFile, "file containing the etcd server username, if one is required. The file is read again when it is rotated")
+	fs.StringVar(&c.PasswordFile, "etcd-password-file", c.PasswordFile, "file containing the etcd server password, if one is required. The file is read again when it is rotated")
 	fs.DurationVar(&c.ConnectionTimeout.Duration, "etcd-connection-timeout", c.ConnectionTimeout.Duration, "etcd client connection timeout")
+	fs.DurationVar(&c.KVGetTimeout.Duration, "etcd-kv-get-timeout", c.KVGetTimeout.Duration, "timeout duration for the etcd GET calls of the snapshotter, such as for the latest etcd revision before each snapshot. If this value is set to be lesser than 1, the etcd connection timeout is used")
+	fs.DurationVar(&c.WatchSetupTimeout.Duration, "etcd-watch-setup-timeout", c.WatchSetupTimeout.Duration, "timeout duration for establishing the etcd watch of the snapshotter, which is waited for before the events are collected. If this value is set to be lesser than 1, the watch is not waited for to be established")
 	fs.DurationVar(&c.SnapshotTimeout.Duration, "etcd-snapshot-timeout", c.SnapshotTimeout.Duration, "timeout duration for taking etcd snapshots")
+	fs.DurationVar(&c.SnapshotTimeoutPerGB.Duration, "etcd-snapshot-timeout-per-gb", c.SnapshotTimeoutPerGB.Duration, "additional timeout duration for taking full snapshots per GiB of the previous full snapshot. If this value is set to be lesser than 1, the snapshot timeout is not scaled")
+	fs.DurationVar(&c.MinSnapshotTimeout.Duration, "etcd-min-snapshot-timeout", c.MinSnapshotTimeout.Duration, "lower bound of the scaled timeout duration for taking full snapshots")
+	fs.DurationVar(&c.MaxSnapshotTimeout.Duration, "etcd-max-snapshot-timeout", c.MaxSnapshotTimeout.Duration, "upper bound of the scaled timeout duration for taking full snapshots, which should not be lesser than the snapshot timeout")
 	fs.DurationVar(&c.DefragTimeout.Duration, "etcd-defrag-timeout", c.DefragTimeout.Duration, "timeout duration for etcd defrag call")
 	fs.BoolVar(&c.InsecureTransport, "insecure-transport", c.InsecureTransport, "disable transport security for client connections")
 	fs.BoolVar(&c.InsecureSkipVerify, "insecure-skip-tls-verify", c.InsecureTransport, "skip server certificate verification")
 	fs.StringVar(&c.CertFile, "cert", c.CertFile, "identify secure client using this TLS certificate file")
 	fs.StringVar(&c.KeyFile, "key", c.KeyFile, "identify secure client using this TLS key file")
 	fs.StringVar(&c.CaFile, "cacert", c.CaFile, "verify certificates of TLS-enabled secure servers using this CA bundle")
+	fs.StringVar(&c.PeerCertFile, "peer-cert", c.PeerCertFile, "TLS certificate file with which a restored etcd member communicates with its peers")
+	fs.StringVar(&c.PeerKeyFile, "peer-key", c.PeerKeyFile, "TLS key file with which a restored etcd member communicates with its peers")
+	fs.StringVar(&c.PeerCaFile, "peer-cacert", c.PeerCaFile, "verify the certificates of the peers of a restored etcd member using this CA bundle")
 }
 
 // Validate validates the config.
@@ -84,8 +114,60 @@ func (c *EtcdConnectionConfig) Validate() error {
 	if c.SnapshotTimeout.Duration < c.ConnectionTimeout.Duration {
 		return fmt.Errorf("snapshot timeout should be greater than or equal to connection timeout")
 	}
+	if c.MaxSnapshotTimeout.Duration > 0 && c.MinSnapshotTimeout.Duration > c.MaxSnapshotTimeout.Duration {
+		return fmt.Errorf("min snapshot timeout should be lesser than or equal to max snapshot timeout")
+	}
+	if c.MaxSnapshotTimeout.Duration > 0 && c.SnapshotTimeout.Duration > c.MaxSnapshotTimeout.Duration {
+		return fmt.Errorf("max snapshot timeout should be greater than or equal to snapshot timeout")
+	}
 	if c.DefragTimeout.Duration <= 0 {
 		return fmt.Errorf("etcd defrag timeout should be greater than zero")
 	}
+	if c.Username != "" && c.UsernameFile != "" {
+		return fmt.Errorf("only one of etcd username and etcd username file should be set")
+	}
+	if c.Password != "" && c.PasswordFile != "" {
+		return fmt.Errorf("only one of etcd password and etcd password file should be set")
+	}
+	if (c.PeerCertFile == "") != (c.PeerKeyFile == "") {
+		return fmt.Errorf("both or none of peer cert and peer key should be set")
+	}
 	return nil
 }
+
+// GetFullSnapshotTimeout returns the timeout for taking a full snapshot, given the size of the previous full snapshot in bytes.
+// The snapshot timeout is increased by the configured duration per GiB of the previous full snapshot, and bounded by
+// the configured min and max snapshot timeouts.
+func (c *EtcdConnectionConfig) GetFullSnapshotTimeout(prevFullSnapshotSizeBytes int64) time.Duration {
+	if c.SnapshotTimeoutPerGB.Duration <= 0 {
+		return c.SnapshotTimeout.Duration
+	}
+	timeout := c.SnapshotTimeout.Duration + time.Duration(float64(c.SnapshotTimeoutPerGB.Duration)*float64(prevFullSnapshotSizeBytes)/(1<<30))
+	if c.MinSnapshotTimeout.Duration > 0 && timeout < c.MinSnapshotTimeout.Duration {
+		timeout = c.MinSnapshotTimeout.Duration
+	}
+	if c.MaxSnapshotTimeout.Duration > 0 && timeout > c.MaxSnapshotTimeout.Duration {
+		timeout = c.MaxSnapshotTimeout.Duration
+	}
+	return timeout
+}
+
+// GetKVGetTimeout returns the timeout for the GET calls to etcd, which is the connection timeout unless a distinct
+// timeout is configured.
+func (c *EtcdConnectionConfig) GetKVGetTimeout() time.Duration {
+	if c.KVGetTimeout.Duration > 0 {
+		return c.KVGetTimeout.Duration
+	}
+	return c.ConnectionTimeout.Duration
+}
+
+// CredentialFiles returns the configured files holding the credentials for the etcd client connection.
+func (c *EtcdConnectionConfig) CredentialFiles() []string {
+	var files []string
+	for _, file := range []string{c.CertFile, c.KeyFile, c.CaFile, c.UsernameFile, c.PasswordFile} {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
@@ -0,0 +1,36 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types_test
+
+import (
+	"time"
+
+	. "github.com/gardener/etcd-backup-restore/pkg/types"
+	"github.com/gardener/etcd-backup-restore/pkg/wrappers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validating the etcd connection config", func() {
+	var config *EtcdConnectionConfig
+
+	BeforeEach(func() {
+		config = NewEtcdConnectionConfig()
+	})
+
+	It("should accept the default config", func() {
+		Expect(config.Validate()).To(Succeed())
+	})
+
+	It("should accept a max snapshot timeout greater than the snapshot timeout", func() {
+		config.MaxSnapshotTimeout = wrappers.Duration{Duration: config.SnapshotTimeout.Duration + time.Minute}
+		Expect(config.Validate()).To(Succeed())
+	})
+
+	It("should reject a max snapshot timeout lesser than the snapshot timeout", func() {
+		config.MaxSnapshotTimeout = wrappers.Duration{Duration: config.SnapshotTimeout.Duration - time.Second}
+		Expect(config.Validate()).To(MatchError(ContainSubstring("max snapshot timeout should be greater than or equal to snapshot timeout")))
+	})
+})
@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gardener/etcd-backup-restore/pkg/wrappers"
+	flag "github.com/spf13/pflag"
+)
+
+const (
+	// defaultFollowerPollInterval is the default interval at which the follower polls the snapstore for new delta snapshots.
+	defaultFollowerPollInterval = 30 * time.Second
+)
+
+// FollowerConfig holds the configuration of the follower, which keeps a standby etcd up to date with the snapstore.
+type FollowerConfig struct {
+	// PollInterval is the interval at which the snapstore is polled for new delta snapshots.
+	PollInterval wrappers.Duration `json:"pollInterval,omitempty"`
+}
+
+// NewFollowerConfig returns the follower config.
+func NewFollowerConfig() *FollowerConfig {
+	return &FollowerConfig{
+		PollInterval: wrappers.Duration{Duration: defaultFollowerPollInterval},
+	}
+}
+
+// AddFlags adds the flags to flagset.
+func (c *FollowerConfig) AddFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&c.PollInterval.Duration, "follow-poll-interval", c.PollInterval.Duration, "interval at which the snapstore is polled for new delta snapshots, whose events are applied to the standby etcd")
+}
+
+// Validate validates the config.
+func (c *FollowerConfig) Validate() error {
+	if c.PollInterval.Duration <= 0 {
+		return fmt.Errorf("follow poll interval should be greater than zero")
+	}
+	return nil
+}
@@ -6,10 +6,12 @@ package types
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gardener/etcd-backup-restore/pkg/wrappers"
 	flag "github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 const (
@@ -21,6 +23,8 @@ const (
 	DefaultMemberLeaseRenewalEnabled = false
 	// DefaultEtcdMemberGCEnabled is a default value for enabling the etcd member garbage collection feature
 	DefaultEtcdMemberGCEnabled = false
+	// DefaultKubernetesEventsEnabled is a default value for enabling the Kubernetes events feature
+	DefaultKubernetesEventsEnabled = false
 	// DefaultFullSnapshotLeaseName is the name for the full snapshot lease.
 	DefaultFullSnapshotLeaseName = "full-snapshot-revisions"
 	// DefaultDeltaSnapshotLeaseName is the name for the delta snapshot lease.
@@ -39,10 +43,13 @@ type HealthConfig struct {
 	FullSnapshotLeaseUpdateInterval wrappers.Duration `json:"fullSnapshotLeaseUpdateInterval,omitempty"`
 	MemberLeaseRenewalEnabled       bool              `json:"memberLeaseRenewalEnabled,omitempty"`
 	EtcdMemberGCEnabled             bool              `json:"etcdMemberGCEnabled,omitempty"`
+	KubernetesEventsEnabled         bool              `json:"kubernetesEventsEnabled,omitempty"`
 	HeartbeatDuration               wrappers.Duration `json:"heartbeatDuration,omitempty"`
 	MemberGCDuration                wrappers.Duration `json:"memberGCDuration,omitempty"`
 	FullSnapshotLeaseName           string            `json:"fullSnapshotLeaseName,omitempty"`
 	DeltaSnapshotLeaseName          string            `json:"deltaSnapshotLeaseName,omitempty"`
+	// SnapshotLeaseNamespace is the namespace of the full and delta snapshot leases. It defaults to the namespace of the pod.
+	SnapshotLeaseNamespace string `json:"snapshotLeaseNamespace,omitempty"`
 }
 
 // NewHealthConfig returns the health config.
@@ -52,6 +59,7 @@ func NewHealthConfig() *HealthConfig {
 		FullSnapshotLeaseUpdateInterval: wrappers.Duration{Duration: DefaultFullSnapshotLeaseUpdateInterval},
 		MemberLeaseRenewalEnabled:       DefaultMemberLeaseRenewalEnabled,
 		EtcdMemberGCEnabled:             DefaultEtcdMemberGCEnabled,
+		KubernetesEventsEnabled:         DefaultKubernetesEventsEnabled,
 		HeartbeatDuration:               wrappers.Duration{Duration: DefaultHeartbeatDuration},
 		MemberGCDuration:                wrappers.Duration{Duration: DefaultMemberGarbageCollectionPeriod},
 		FullSnapshotLeaseName:           DefaultFullSnapshotLeaseName,
@@ -66,10 +74,12 @@ func (c *HealthConfig) AddFlags(fs *flag.FlagSet) {
 	fs.DurationVar(&c.FullSnapshotLeaseUpdateInterval.Duration, "full-snapshot-lease-update-interval", c.FullSnapshotLeaseUpdateInterval.Duration, "Interval for Periodic Full Snapshot lease updates")
 	fs.BoolVar(&c.MemberLeaseRenewalEnabled, "enable-member-lease-renewal", c.MemberLeaseRenewalEnabled, "Allows sidecar to periodically renew the member leases")
 	fs.BoolVar(&c.EtcdMemberGCEnabled, "enable-etcd-member-gc", c.EtcdMemberGCEnabled, "Allows leading sidecar to remove any superfluous etcd members from the cluster")
+	fs.BoolVar(&c.KubernetesEventsEnabled, "enable-k8s-events", c.KubernetesEventsEnabled, "Allows sidecar to emit Kubernetes events on its pod for the snapshot, restoration and learner promotion milestones")
 	fs.DurationVar(&c.HeartbeatDuration.Duration, "k8s-heartbeat-duration", c.HeartbeatDuration.Duration, "Heartbeat duration")
 	fs.DurationVar(&c.MemberGCDuration.Duration, "k8s-member-gc-duration", c.MemberGCDuration.Duration, "Etcd member garbage collection duration")
 	fs.StringVar(&c.FullSnapshotLeaseName, "full-snapshot-lease-name", c.FullSnapshotLeaseName, "full snapshot lease name")
 	fs.StringVar(&c.DeltaSnapshotLeaseName, "delta-snapshot-lease-name", c.DeltaSnapshotLeaseName, "delta snapshot lease name")
+	fs.StringVar(&c.SnapshotLeaseNamespace, "snapshot-lease-namespace", c.SnapshotLeaseNamespace, "namespace of the full and delta snapshot leases (defaults to the namespace of the pod)")
 }
 
 // Validate validates the health Config.
@@ -93,7 +103,25 @@ func (c *HealthConfig) Validate() error {
 		if len(c.DeltaSnapshotLeaseName) == 0 {
 			return fmt.Errorf("DeltaSnapshotLeaseName can not be an empty string when enable-snapshot-lease-renewal is true")
 		}
+		return validateSnapshotLeases(c.FullSnapshotLeaseName, c.DeltaSnapshotLeaseName, c.SnapshotLeaseNamespace)
 	}
 	return nil
 
 }
+
+// validateSnapshotLeases validates that the names and the namespace of the snapshot leases are RFC 1123 compliant,
+// as required for Kubernetes objects. An empty namespace stands for the namespace of the pod.
+func validateSnapshotLeases(fullSnapshotLeaseName, deltaSnapshotLeaseName, namespace string) error {
+	for _, name := range []string{fullSnapshotLeaseName, deltaSnapshotLeaseName} {
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return fmt.Errorf("invalid snapshot lease name %q: %s", name, strings.Join(errs, ", "))
+		}
+	}
+	if namespace == "" {
+		return nil
+	}
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		return fmt.Errorf("invalid snapshot lease namespace %q: %s", namespace, strings.Join(errs, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types_test
+
+import (
+	. "github.com/gardener/etcd-backup-restore/pkg/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validating the health config", func() {
+	var config *HealthConfig
+
+	BeforeEach(func() {
+		config = NewHealthConfig()
+		config.SnapshotLeaseRenewalEnabled = true
+	})
+
+	It("should accept the default config", func() {
+		Expect(config.Validate()).To(Succeed())
+	})
+
+	It("should accept configured snapshot lease names and namespace", func() {
+		config.FullSnapshotLeaseName = "etcd-main-full-snapshot-revisions"
+		config.DeltaSnapshotLeaseName = "etcd-main-delta-snapshot-revisions"
+		config.SnapshotLeaseNamespace = "etcd-tenant"
+		Expect(config.Validate()).To(Succeed())
+	})
+
+	It("should not validate the snapshot leases if their renewal is disabled", func() {
+		config.SnapshotLeaseRenewalEnabled = false
+		config.FullSnapshotLeaseName = "Full_Snapshot_Revisions"
+		Expect(config.Validate()).To(Succeed())
+	})
+
+	DescribeTable("should reject a snapshot lease which is not RFC 1123 compliant",
+		func(invalidate func(*HealthConfig), message string) {
+			invalidate(config)
+			err := config.Validate()
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(message))
+		},
+		Entry("full snapshot lease name", func(c *HealthConfig) { c.FullSnapshotLeaseName = "Full_Snapshot_Revisions" }, "invalid snapshot lease name"),
+		Entry("delta snapshot lease name", func(c *HealthConfig) { c.DeltaSnapshotLeaseName = "delta-snapshot-revisions-" }, "invalid snapshot lease name"),
+		Entry("snapshot lease namespace", func(c *HealthConfig) { c.SnapshotLeaseNamespace = "etcd.tenant" }, "invalid snapshot lease namespace"),
+	)
+})
@@ -43,6 +43,8 @@ type EtcdMemberStatusCallbackFunc func(context.Context, *EtcdConnectionConfig, t
 // PromoteLearnerCallback is callback which is triggered when backup-restore wants to promote etcd learner to a voting member.
 type PromoteLearnerCallback struct {
 	Promote func(context.Context, *logrus.Entry)
+	// RaftIndexLag returns the number of raft indices by which the learner lags behind the leader.
+	RaftIndexLag func(context.Context, *logrus.Entry) (uint64, error)
 }
 
 // Config holds the LeaderElection config.
@@ -51,6 +53,16 @@ type Config struct {
 	ReelectionPeriod wrappers.Duration `json:"reelectionPeriod,omitempty"`
 	// EtcdConnectionTimeout defines the timeout duration for etcd client connection during leader election.
 	EtcdConnectionTimeout wrappers.Duration `json:"etcdConnectionTimeout,omitempty"`
+	// MinStartupDelay defines the lower bound of the random delay before the first leadership status check.
+	MinStartupDelay wrappers.Duration `json:"minStartupDelay,omitempty"`
+	// MaxStartupDelay defines the upper bound of the random delay before the first leadership status check.
+	MaxStartupDelay wrappers.Duration `json:"maxStartupDelay,omitempty"`
+	// LearnerPromotionMaxRaftIndexLag defines the maximum number of raft indices by which a learner may lag behind the leader
+	// to be promoted. 0 disables the check, so that the learner is promoted as soon as etcd considers it to be in sync.
+	LearnerPromotionMaxRaftIndexLag uint64 `json:"learnerPromotionMaxRaftIndexLag,omitempty"`
+	// LearnerPromotionStabilityPeriod defines the period for which the raft index lag of a learner must stay within
+	// LearnerPromotionMaxRaftIndexLag before it is promoted. It can only be set along with LearnerPromotionMaxRaftIndexLag.
+	LearnerPromotionStabilityPeriod wrappers.Duration `json:"learnerPromotionStabilityPeriod,omitempty"`
 }
 
 // NewLeaderElectionConfig returns the Config.
@@ -65,6 +77,10 @@ func NewLeaderElectionConfig() *Config {
 func (c *Config) AddFlags(fs *flag.FlagSet) {
 	fs.DurationVar(&c.EtcdConnectionTimeout.Duration, "etcd-connection-timeout-leader-election", c.EtcdConnectionTimeout.Duration, "timeout duration of etcd client connection during leader election")
 	fs.DurationVar(&c.ReelectionPeriod.Duration, "reelection-period", c.ReelectionPeriod.Duration, "period after which election will be re-triggered to check the leadership status")
+	fs.DurationVar(&c.MinStartupDelay.Duration, "leader-election-min-startup-delay", c.MinStartupDelay.Duration, "minimum of the random delay before the first leadership status check, to spread out the elections of a restarting cluster")
+	fs.DurationVar(&c.MaxStartupDelay.Duration, "leader-election-max-startup-delay", c.MaxStartupDelay.Duration, "maximum of the random delay before the first leadership status check, to spread out the elections of a restarting cluster")
+	fs.Uint64Var(&c.LearnerPromotionMaxRaftIndexLag, "learner-promotion-max-raft-index-lag", c.LearnerPromotionMaxRaftIndexLag, "maximum number of raft indices by which the learner may lag behind the leader to be promoted to a voting member. 0 disables the check")
+	fs.DurationVar(&c.LearnerPromotionStabilityPeriod.Duration, "learner-promotion-stability-period", c.LearnerPromotionStabilityPeriod.Duration, "period for which the raft index lag of the learner must stay within the maximum lag before it is promoted to a voting member. Requires learner-promotion-max-raft-index-lag to be set")
 }
 
 // Validate validates the Config.
@@ -77,5 +93,21 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("etcd connection timeout during leader election should be greater than 1 second")
 	}
 
+	if c.MinStartupDelay.Duration < 0 {
+		return fmt.Errorf("minimum startup delay of leader election should not be negative")
+	}
+
+	if c.MaxStartupDelay.Duration < c.MinStartupDelay.Duration {
+		return fmt.Errorf("maximum startup delay of leader election should not be lesser than the minimum startup delay")
+	}
+
+	if c.LearnerPromotionStabilityPeriod.Duration < 0 {
+		return fmt.Errorf("learner promotion stability period should not be negative")
+	}
+
+	if c.LearnerPromotionStabilityPeriod.Duration > 0 && c.LearnerPromotionMaxRaftIndexLag == 0 {
+		return fmt.Errorf("learner promotion stability period requires the maximum raft index lag of the learner to be set, as it is the period for which the lag must stay within it")
+	}
+
 	return nil
 }
@@ -0,0 +1,37 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types_test
+
+import (
+	"time"
+
+	. "github.com/gardener/etcd-backup-restore/pkg/types"
+	"github.com/gardener/etcd-backup-restore/pkg/wrappers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validating the leader election config", func() {
+	var config *Config
+
+	BeforeEach(func() {
+		config = NewLeaderElectionConfig()
+	})
+
+	It("should accept the default config", func() {
+		Expect(config.Validate()).To(Succeed())
+	})
+
+	It("should accept a learner promotion stability period along with a maximum raft index lag", func() {
+		config.LearnerPromotionMaxRaftIndexLag = 1000
+		config.LearnerPromotionStabilityPeriod = wrappers.Duration{Duration: 30 * time.Second}
+		Expect(config.Validate()).To(Succeed())
+	})
+
+	It("should reject a learner promotion stability period without a maximum raft index lag", func() {
+		config.LearnerPromotionStabilityPeriod = wrappers.Duration{Duration: 30 * time.Second}
+		Expect(config.Validate()).To(MatchError(ContainSubstring("requires the maximum raft index lag")))
+	})
+})
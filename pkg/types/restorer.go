@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/gardener/etcd-backup-restore/pkg/etcdutil/client"
+	"github.com/gardener/etcd-backup-restore/pkg/wrappers"
 	flag "github.com/spf13/pflag"
 	"go.etcd.io/etcd/clientv3"
 	"go.etcd.io/etcd/pkg/types"
@@ -29,6 +30,13 @@ const (
 	defaultAutoCompactionRetention  = "30m"
 )
 
+const (
+	// BackendFreelistTypeArray is the bbolt freelist type which keeps the free pages in an array.
+	BackendFreelistTypeArray = "array"
+	// BackendFreelistTypeMap is the bbolt freelist type which keeps the free pages in a hashmap.
+	BackendFreelistTypeMap = "map"
+)
+
 // NewClientFactoryFunc allows to define how to create a client.Factory
 type NewClientFactoryFunc func(cfg EtcdConnectionConfig, opts ...client.Option) client.Factory
 
@@ -44,25 +52,130 @@ type RestoreOptions struct {
 	BaseSnapshot     *Snapshot
 	DeltaSnapList    SnapList
 	NewClientFactory NewClientFactoryFunc
+	// WALDir is the WAL directory of the embedded etcd, for data directories whose WAL directory has been moved out of
+	// the member directory. The WAL directory within the member directory is used if empty.
+	WALDir string
+	// PeerTLS holds the TLS files with which the embedded etcd secures its peer communication, if peer TLS is enabled.
+	PeerTLS PeerTLSConfig
+	// MaxRestoreDuration bounds the duration of the restoration, after which it is aborted. No bound if 0.
+	MaxRestoreDuration time.Duration
+	// RestoreLockTTL is the duration for which the restoration holds the restore lock of the snapstore, to reject other
+	// restorations of the same cluster in the meantime, after which the restore lock is considered stale, e.g. as the
+	// restoration crashed. No restore lock is acquired if 0.
+	RestoreLockTTL time.Duration
+	// RestoreToTime is the time up to which the events of the delta snapshots are restored, as per the timestamps
+	// recorded along with the events. All the events are restored if zero.
+	RestoreToTime time.Time
+	// SkipDeltaRevisions are the revisions whose delta snapshots are skipped by the restoration, along with all the
+	// other events they hold, to work around events which can't be applied. No delta snapshot is skipped if empty.
+	SkipDeltaRevisions []int64
+	// BaseOnly restores only the base snapshot and discards all the delta snapshots, e.g. if they are suspected to be
+	// corrupted. It can't be combined with RestoreToTime or SkipDeltaRevisions.
+	BaseOnly bool
+	// InitialClusterState is the initial cluster state of the restored member, either "new" if it bootstraps a new
+	// cluster, or "existing" if it joins an existing cluster. The member bootstraps a new cluster if empty.
+	InitialClusterState string
+	// PreservedKeysConnectionConfig holds the connection config, i.e. the TLS and auth settings, of the live etcd cluster
+	// from which the preserved key prefixes are captured, whose endpoints are replaced by the preserved keys endpoints
+	// of the restoration config. The live etcd cluster is connected to without TLS and auth if nil.
+	PreservedKeysConnectionConfig *EtcdConnectionConfig
+	// ChainManifestFallback is the reason why the snapshots to restore from were found by listing the snapstore instead
+	// of from the chain manifest, which is recorded as a warning in the restore report. Empty if there was no fallback.
+	ChainManifestFallback string
+	// BaseSnapshotIsKeyExport indicates that the base snapshot is an export of the keys of the whole keyspace instead of
+	// a database snapshot, as taken by a full snapshot at a historical revision. It is detected by the restoration.
+	BaseSnapshotIsKeyExport bool
+}
+
+// RestoreVerificationReport holds the outcome of a verification restoration into a throwaway directory.
+type RestoreVerificationReport struct {
+	// Passed indicates whether the snapshots were restored successfully and, if started, the restored etcd reached the expected revision.
+	Passed bool `json:"passed"`
+	// BaseSnapshot is the name of the base full snapshot which was restored.
+	BaseSnapshot string `json:"baseSnapshot,omitempty"`
+	// DeltaSnapshots is the number of delta snapshots which were applied over the base snapshot.
+	DeltaSnapshots int `json:"deltaSnapshots"`
+	// ExpectedRevision is the last revision of the restored snapshots.
+	ExpectedRevision int64 `json:"expectedRevision"`
+	// RestoredRevision is the revision reached by the restored etcd, only set if the restored etcd was started.
+	RestoredRevision int64 `json:"restoredRevision,omitempty"`
+	// CanaryVerified indicates whether the canary written before the base snapshot was found in the restored etcd.
+	CanaryVerified bool `json:"canaryVerified,omitempty"`
+	// Duration is the time taken by the verification.
+	Duration time.Duration `json:"duration"`
+	// Error describes why the verification failed.
+	Error string `json:"error,omitempty"`
+}
+
+// RestoreReport describes the outcome of a restoration, e.g. to keep it as audit evidence.
+type RestoreReport struct {
+	// Succeeded indicates whether the restoration completed successfully.
+	Succeeded bool `json:"succeeded"`
+	// BaseSnapshot is the path of the base full snapshot in the snapstore which was restored.
+	BaseSnapshot string `json:"baseSnapshot,omitempty"`
+	// DeltaSnapshots are the paths of the delta snapshots in the snapstore which were applied over the base snapshot.
+	DeltaSnapshots []string `json:"deltaSnapshots,omitempty"`
+	// SkippedDeltaSnapshots are the paths of the delta snapshots which were skipped along with their events.
+	SkippedDeltaSnapshots []string `json:"skippedDeltaSnapshots,omitempty"`
+	// DiscardedDeltaSnapshots is the number of delta snapshots discarded by restoring only the base snapshot.
+	DiscardedDeltaSnapshots int `json:"discardedDeltaSnapshots,omitempty"`
+	// ResumedFromCheckpoint indicates whether the restoration resumed from the checkpoint of a previous restoration.
+	ResumedFromCheckpoint bool `json:"resumedFromCheckpoint,omitempty"`
+	// FinalRevision is the revision of the restored etcd data.
+	FinalRevision int64 `json:"finalRevision"`
+	// StartedOn is the time the restoration started at.
+	StartedOn time.Time `json:"startedOn"`
+	// Duration is the time taken by the restoration.
+	Duration time.Duration `json:"duration"`
+	// Warnings are the warnings raised during the restoration, e.g. about skipped delta snapshots.
+	Warnings []string `json:"warnings,omitempty"`
+	// Error describes why the restoration failed.
+	Error string `json:"error,omitempty"`
 }
 
 // RestorationConfig holds the restoration configuration.
 // Note: Please ensure DeepCopy and DeepCopyInto are properly implemented.
 type RestorationConfig struct {
-	InitialCluster           string   `json:"initialCluster"`
-	InitialClusterToken      string   `json:"initialClusterToken,omitempty"`
-	DataDir                  string   `json:"dataDir,omitempty"`
-	TempSnapshotsDir         string   `json:"tempDir,omitempty"`
-	InitialAdvertisePeerURLs []string `json:"initialAdvertisePeerURLs"`
-	Name                     string   `json:"name"`
-	SkipHashCheck            bool     `json:"skipHashCheck,omitempty"`
-	MaxFetchers              uint     `json:"maxFetchers,omitempty"`
-	MaxRequestBytes          uint     `json:"MaxRequestBytes,omitempty"`
-	MaxTxnOps                uint     `json:"MaxTxnOps,omitempty"`
-	MaxCallSendMsgSize       int      `json:"maxCallSendMsgSize,omitempty"`
-	EmbeddedEtcdQuotaBytes   int64    `json:"embeddedEtcdQuotaBytes,omitempty"`
-	AutoCompactionMode       string   `json:"autoCompactionMode,omitempty"`
-	AutoCompactionRetention  string   `json:"autoCompactionRetention,omitempty"`
+	InitialCluster            string   `json:"initialCluster"`
+	InitialClusterToken       string   `json:"initialClusterToken,omitempty"`
+	DataDir                   string   `json:"dataDir,omitempty"`
+	MemberSubPath             string   `json:"memberSubPath,omitempty"`
+	WALSubPath                string   `json:"walSubPath,omitempty"`
+	TempSnapshotsDir          string   `json:"tempDir,omitempty"`
+	InitialAdvertisePeerURLs  []string `json:"initialAdvertisePeerURLs"`
+	Name                      string   `json:"name"`
+	SkipHashCheck             bool     `json:"skipHashCheck,omitempty"`
+	MaxFetchers               uint     `json:"maxFetchers,omitempty"`
+	MaxNodeFetchers           uint     `json:"maxNodeFetchers,omitempty"`
+	MaxRequestBytes           uint     `json:"MaxRequestBytes,omitempty"`
+	MaxTxnOps                 uint     `json:"MaxTxnOps,omitempty"`
+	MaxCallSendMsgSize        int      `json:"maxCallSendMsgSize,omitempty"`
+	EmbeddedEtcdQuotaBytes    int64    `json:"embeddedEtcdQuotaBytes,omitempty"`
+	AutoCompactionMode        string   `json:"autoCompactionMode,omitempty"`
+	AutoCompactionRetention   string   `json:"autoCompactionRetention,omitempty"`
+	StreamBaseSnapshot        bool     `json:"streamBaseSnapshot,omitempty"`
+	ScaleUpClusterSize        int      `json:"scaleUpClusterSize,omitempty"`
+	PreservedKeyPrefixes      []string `json:"preservedKeyPrefixes,omitempty"`
+	PreservedKeysEndpoints    []string `json:"preservedKeysEndpoints,omitempty"`
+	CanaryKeyPrefix           string   `json:"canaryKeyPrefix,omitempty"`
+	RestoreCheckpointInterval uint     `json:"restoreCheckpointInterval,omitempty"`
+	RestoreKeyPrefix          string   `json:"restoreKeyPrefix,omitempty"`
+	UseChainManifest          bool     `json:"useChainManifest,omitempty"`
+	MaxRestoreRevisionGap     int64    `json:"maxRestoreRevisionGap,omitempty"`
+	ForceRestore              bool     `json:"forceRestore,omitempty"`
+	UseClusterMetadata        bool     `json:"useClusterMetadata,omitempty"`
+	AllowClusterIDMismatch    bool     `json:"allowClusterIDMismatch,omitempty"`
+	RestoreReportPath         string   `json:"restoreReportPath,omitempty"`
+	BackendFreelistType       string   `json:"backendFreelistType,omitempty"`
+	BackendInitialMmapSize    uint64   `json:"backendInitialMmapSize,omitempty"`
+	// RestoreLockTTL is the duration for which a restoration holds the restore lock of the snapstore. No restore lock
+	// is acquired if 0.
+	RestoreLockTTL wrappers.Duration `json:"restoreLockTTL,omitempty"`
+	// MaxRestoreDuration bounds the duration of a restoration, after which it is aborted. No bound if 0.
+	MaxRestoreDuration wrappers.Duration `json:"maxRestoreDuration,omitempty"`
+	// RestoreToTime is the RFC3339 time up to which the events of the delta snapshots are restored. All the events are
+	// restored if empty.
+	RestoreToTime string `json:"restoreToTime,omitempty"`
 }
 
 // NewRestorationConfig returns the restoration config.
@@ -90,17 +203,38 @@ func (c *RestorationConfig) AddFlags(fs *flag.FlagSet) {
 	fs.StringVar(&c.InitialCluster, "initial-cluster", c.InitialCluster, "initial cluster configuration for restore bootstrap")
 	fs.StringVar(&c.InitialClusterToken, "initial-cluster-token", c.InitialClusterToken, "initial cluster token for the etcd cluster during restore bootstrap")
 	fs.StringVarP(&c.DataDir, "data-dir", "d", c.DataDir, "path to the data directory")
+	fs.StringVar(&c.MemberSubPath, "member-sub-path", c.MemberSubPath, "path of the member directory relative to the data directory, if not member")
+	fs.StringVar(&c.WALSubPath, "wal-sub-path", c.WALSubPath, "path of the WAL directory relative to the data directory, or absolute, if the WAL directory has been moved out of the member directory")
 	fs.StringVar(&c.TempSnapshotsDir, "restoration-temp-snapshots-dir", c.TempSnapshotsDir, "path to the temporary directory to store snapshot files during restoration")
 	fs.StringArrayVar(&c.InitialAdvertisePeerURLs, "initial-advertise-peer-urls", c.InitialAdvertisePeerURLs, "list of this member's peer URLs to advertise to the rest of the cluster")
 	fs.StringVar(&c.Name, "name", c.Name, "human-readable name for this member")
 	fs.BoolVar(&c.SkipHashCheck, "skip-hash-check", c.SkipHashCheck, "ignore snapshot integrity hash value (required if copied from data directory)")
 	fs.UintVar(&c.MaxFetchers, "max-fetchers", c.MaxFetchers, "maximum number of threads that will fetch delta snapshots in parallel")
+	fs.UintVar(&c.MaxNodeFetchers, "max-node-fetchers", c.MaxNodeFetchers, "maximum number of delta snapshots fetched in parallel by all the restorations running in the process, e.g. of the members restored at once on a shared node. Each restoration still uses at most --max-fetchers of them. 0 disables the limit.")
 	fs.IntVar(&c.MaxCallSendMsgSize, "max-call-send-message-size", c.MaxCallSendMsgSize, "maximum size of message that the client sends")
 	fs.UintVar(&c.MaxRequestBytes, "max-request-bytes", c.MaxRequestBytes, "Maximum client request size in bytes the server will accept")
 	fs.UintVar(&c.MaxTxnOps, "max-txn-ops", c.MaxTxnOps, "Maximum number of operations permitted in a transaction")
 	fs.Int64Var(&c.EmbeddedEtcdQuotaBytes, "embedded-etcd-quota-bytes", c.EmbeddedEtcdQuotaBytes, "maximum backend quota for the embedded etcd used for applying delta snapshots")
 	fs.StringVar(&c.AutoCompactionMode, "auto-compaction-mode", c.AutoCompactionMode, "mode for auto-compaction: 'periodic' for duration based retention. 'revision' for revision number based retention.")
 	fs.StringVar(&c.AutoCompactionRetention, "auto-compaction-retention", c.AutoCompactionRetention, "Auto-compaction retention length.")
+	fs.BoolVar(&c.StreamBaseSnapshot, "stream-base-snapshot", c.StreamBaseSnapshot, "stream the base snapshot from the snapstore into the data directory while verifying its integrity hash on the fly, instead of re-reading the restored db from disk. The base snapshot is fetched anew and verified from disk if the stream fails")
+	fs.IntVar(&c.ScaleUpClusterSize, "scale-up-cluster-size", c.ScaleUpClusterSize, "size of the cluster to scale up to after a single member restoration, by adding the remaining members as learners and promoting them once they are in sync. Only supported by the server. 0 disables the scale-up")
+	fs.StringSliceVar(&c.PreservedKeyPrefixes, "preserve-key-prefixes", c.PreservedKeyPrefixes, "comma separated list of key prefixes whose values are captured from the live etcd cluster before the restoration and re-applied over the restored data (merge restore)")
+	fs.StringSliceVar(&c.PreservedKeysEndpoints, "preserve-keys-endpoints", c.PreservedKeysEndpoints, "comma separated list of endpoints of the live etcd cluster from which the preserved key prefixes are captured")
+	fs.UintVar(&c.RestoreCheckpointInterval, "restore-checkpoint-interval", c.RestoreCheckpointInterval, "number of delta snapshots applied between the checkpoints recorded in the data directory during restoration. A failed restoration resumes from its last checkpoint instead of starting over from the base snapshot, as long as the checkpoint is consistent with the partially restored data directory. 0 disables the checkpointing.")
+	fs.StringVar(&c.RestoreKeyPrefix, "restore-key-prefix", c.RestoreKeyPrefix, "key prefix to which the restored snapshots were scoped with --snapshot-key-prefix. Only the keys under the prefix are restored, and the revisions of the restored etcd do not match the revisions of the snapshots. If empty, the snapshots are expected to hold the whole keyspace.")
+	fs.BoolVar(&c.UseChainManifest, "use-chain-manifest", c.UseChainManifest, "find the latest full snapshot and its delta snapshots to restore from the chain manifest written by the snapshotter with --write-chain-manifest, instead of listing the snapstore. The snapstore is still listed if there is no chain manifest, or if the snapshots of the chain manifest are not present in the snapstore with their recorded sizes.")
+	fs.Int64Var(&c.MaxRestoreRevisionGap, "max-restore-revision-gap", c.MaxRestoreRevisionGap, "maximum number of revisions the data directory may be ahead of the latest snapshot for it to be replaced by a restoration from the snapshots. A restoration over a data directory further ahead fails unless --force-restore is set. 0 disables the check.")
+	fs.BoolVar(&c.ForceRestore, "force-restore", c.ForceRestore, "restore over a data directory which is ahead of the latest snapshot by more than --max-restore-revision-gap revisions")
+	fs.BoolVar(&c.UseClusterMetadata, "use-cluster-metadata", c.UseClusterMetadata, "bootstrap the restored member with the peer URLs recorded for it along with the base snapshot by the snapshotter with --record-cluster-metadata, instead of --initial-advertise-peer-urls. The restored member keeps its recorded ID if the original --initial-cluster-token is used, and the restoration fails otherwise unless --allow-cluster-id-mismatch is set. The configured initial cluster is used if no cluster metadata was recorded along with the base snapshot.")
+	fs.BoolVar(&c.AllowClusterIDMismatch, "allow-cluster-id-mismatch", c.AllowClusterIDMismatch, "allow restoring a full snapshot with recorded cluster metadata with --use-cluster-metadata into another cluster than the one it was taken from, i.e. with another --initial-cluster-token, e.g. to clone a cluster. The ID of the restored member is regenerated, so that it can't rejoin the members of the original cluster")
+	fs.StringVar(&c.RestoreReportPath, "restore-report-path", c.RestoreReportPath, "path of the file to which a JSON report of each restoration is written, describing the restored base snapshot, the applied and skipped delta snapshots, the final revision, the duration and the warnings raised. If empty, no report is written.")
+	fs.StringVar(&c.BackendFreelistType, "restore-backend-freelist-type", c.BackendFreelistType, "type of the freelist of the bbolt database of the restored data directory and the embedded etcd applying the delta snapshots, either 'array' or 'map'. The map freelist speeds up the restoration of very large databases with many free pages. The default freelist type of etcd is used if empty.")
+	fs.Uint64Var(&c.BackendInitialMmapSize, "restore-backend-initial-mmap-size", c.BackendInitialMmapSize, "initial size in bytes of the memory map of the bbolt database restored from the base snapshot, which should exceed the size of the database so that it isn't remapped while it grows. The default initial mmap size of etcd of 10 GiB is used if zero.")
+	fs.DurationVar(&c.RestoreLockTTL.Duration, "restore-lock-ttl", c.RestoreLockTTL.Duration, "duration for which the restoration holds the restore lock in the snapstore, which rejects the other restorations of the same cluster while it is held, and after which a restore lock left behind is considered stale (0 means no restore lock)")
+	fs.DurationVar(&c.MaxRestoreDuration.Duration, "max-restore-duration", c.MaxRestoreDuration.Duration, "maximum duration of the restoration, after which it is aborted and the partially restored data directory is removed (0 means no limit)")
+	fs.StringVar(&c.RestoreToTime, "restore-to-time", c.RestoreToTime, "RFC3339 time up to which the events of the delta snapshots are restored, matched by the timestamps recorded along with the events (empty means all events)")
+	fs.StringVar(&c.CanaryKeyPrefix, "verify-canary-key-prefix", c.CanaryKeyPrefix, "key prefix of the canary keys written by the snapshotter before each full snapshot, whose canary is verified in the etcd started over the restored data by the restore verification. If empty, no canary is verified.")
 }
 
 // Validate validates the config.
@@ -120,14 +254,59 @@ func (c *RestorationConfig) Validate() error {
 	if c.EmbeddedEtcdQuotaBytes <= 0 {
 		return fmt.Errorf("etcd quota size for etcd must be greater than 0")
 	}
+	if c.MaxRestoreRevisionGap < 0 {
+		return fmt.Errorf("max restore revision gap should not be negative")
+	}
+	if c.ScaleUpClusterSize < 0 {
+		return fmt.Errorf("scale up cluster size should not be negative")
+	}
+	if len(c.PreservedKeyPrefixes) > 0 && len(c.PreservedKeysEndpoints) == 0 {
+		return fmt.Errorf("endpoints of the live etcd cluster are required to preserve key prefixes")
+	}
+	if err := ValidateCanaryKeyPrefix(c.CanaryKeyPrefix); err != nil {
+		return err
+	}
+	if c.RestoreKeyPrefix != "" && c.CanaryKeyPrefix != "" {
+		return fmt.Errorf("canary cannot be verified for a restoration scoped to the key prefix %s", c.RestoreKeyPrefix)
+	}
+	if c.RestoreKeyPrefix != "" && c.RestoreCheckpointInterval > 0 {
+		return fmt.Errorf("restoration scoped to the key prefix %s cannot be checkpointed", c.RestoreKeyPrefix)
+	}
 	if c.AutoCompactionMode != "periodic" && c.AutoCompactionMode != "revision" {
 		return fmt.Errorf("UnSupported auto-compaction-mode")
 	}
+	if c.RestoreLockTTL.Duration < 0 {
+		return fmt.Errorf("restore lock TTL should not be negative")
+	}
+	if c.MaxRestoreDuration.Duration < 0 {
+		return fmt.Errorf("max restore duration should not be negative")
+	}
+	if c.RestoreLockTTL.Duration > 0 && c.MaxRestoreDuration.Duration > 0 && c.RestoreLockTTL.Duration < c.MaxRestoreDuration.Duration {
+		return fmt.Errorf("restore lock TTL should not be less than the max restore duration, so that the restore lock isn't considered stale while the restoration runs")
+	}
+	if _, err := c.GetRestoreToTime(); err != nil {
+		return err
+	}
+	if c.BackendFreelistType != "" && c.BackendFreelistType != BackendFreelistTypeArray && c.BackendFreelistType != BackendFreelistTypeMap {
+		return fmt.Errorf("unsupported backend freelist type %q, must be either %q or %q", c.BackendFreelistType, BackendFreelistTypeArray, BackendFreelistTypeMap)
+	}
 	c.DataDir = path.Clean(c.DataDir)
 	c.TempSnapshotsDir = path.Clean(c.TempSnapshotsDir)
 	return nil
 }
 
+// GetRestoreToTime returns the parsed time to restore to, or the zero time if none is configured.
+func (c *RestorationConfig) GetRestoreToTime() (time.Time, error) {
+	if c.RestoreToTime == "" {
+		return time.Time{}, nil
+	}
+	restoreToTime, err := time.Parse(time.RFC3339, c.RestoreToTime)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("restore to time must be an RFC3339 time: %v", err)
+	}
+	return restoreToTime, nil
+}
+
 // DeepCopyInto copies the structure deeply from in to out.
 func (c *RestorationConfig) DeepCopyInto(out *RestorationConfig) {
 	*out = *c
@@ -138,6 +317,16 @@ func (c *RestorationConfig) DeepCopyInto(out *RestorationConfig) {
 			(*out)[i] = v
 		}
 	}
+	if c.PreservedKeyPrefixes != nil {
+		c, out := &c.PreservedKeyPrefixes, &out.PreservedKeyPrefixes
+		*out = make([]string, len(*c))
+		copy(*out, *c)
+	}
+	if c.PreservedKeysEndpoints != nil {
+		c, out := &c.PreservedKeysEndpoints, &out.PreservedKeysEndpoints
+		*out = make([]string, len(*c))
+		copy(*out, *c)
+	}
 }
 
 // DeepCopy returns a deeply copied structure.
@@ -211,6 +400,12 @@ func (in *RestoreOptions) DeepCopyInto(out *RestoreOptions) {
 	if in.NewClientFactory != nil {
 		out.NewClientFactory = DeepCopyNewClientFactory(in.NewClientFactory)
 	}
+	if in.PreservedKeysConnectionConfig != nil {
+		in, out := &in.PreservedKeysConnectionConfig, &out.PreservedKeysConnectionConfig
+		*out = new(EtcdConnectionConfig)
+		**out = **in
+		(*out).Endpoints = append([]string(nil), (*in).Endpoints...)
+	}
 }
 
 // DeepCopyURLs returns a deeply copy
@@ -5,7 +5,10 @@
 package types
 
 import (
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gardener/etcd-backup-restore/pkg/wrappers"
@@ -19,9 +22,19 @@ const (
 	GarbageCollectionPolicyExponential = "Exponential"
 	// GarbageCollectionPolicyLimitBased defines the limit based policy for garbage collecting old backups
 	GarbageCollectionPolicyLimitBased = "LimitBased"
+	// GarbageCollectionPolicyStorageBudget defines the storage budget based policy for garbage collecting old backups
+	GarbageCollectionPolicyStorageBudget = "StorageBudget"
 	// DefaultMaxBackups is default number of maximum backups for limit based garbage collection policy.
 	DefaultMaxBackups = 7
 
+	// EtcdAlarmPolicyIgnore defines the policy of not querying the active etcd alarms during full snapshots
+	EtcdAlarmPolicyIgnore = "Ignore"
+	// EtcdAlarmPolicyReport defines the policy of reporting the active etcd alarms during full snapshots
+	EtcdAlarmPolicyReport = "Report"
+	// EtcdAlarmPolicyFailOnNoSpace defines the policy of reporting the active etcd alarms during full snapshots, and
+	// failing the full snapshots while a NOSPACE alarm is active
+	EtcdAlarmPolicyFailOnNoSpace = "FailOnNoSpace"
+
 	// SnapshotterInactive is set when the snapshotter has not started taking snapshots.
 	SnapshotterInactive SnapshotterState = 0
 	// SnapshotterActive is set when the snapshotter has started taking snapshots.
@@ -36,9 +49,42 @@ const (
 	DefaultFullSnapshotSchedule = "0 */1 * * *"
 	// DefaultGarbageCollectionPeriod is the default interval for garbage collection
 	DefaultGarbageCollectionPeriod = time.Minute
+	// DefaultBaseSnapshotCheckPeriod is the default interval for verifying the presence of the previous full snapshot
+	DefaultBaseSnapshotCheckPeriod = 5 * time.Minute
+	// DefaultDeltaSnapshotReconciliationPeriod is the default interval for reconciling the previous delta snapshots with the snapstore
+	DefaultDeltaSnapshotReconciliationPeriod = 10 * time.Minute
+	// DefaultDeltaEventsCollectionTimeout is the default timeout for collecting the events since the previous snapshot at startup
+	DefaultDeltaEventsCollectionTimeout = 5 * time.Minute
+	// DefaultMaxParallelDeltaSnapshotUploads is the default number of delta snapshots uploaded concurrently while the delta snapshots pile up
+	DefaultMaxParallelDeltaSnapshotUploads = 1
+	// DefaultMaxConsecutiveFullSnapshotFailures is the default number of consecutive failed full snapshots after which the snapshotter fails
+	DefaultMaxConsecutiveFullSnapshotFailures = 5
+	// DefaultDegradedModeRetryPeriod is the default interval for retrying the snapshots while the snapshotter is degraded
+	DefaultDegradedModeRetryPeriod = time.Minute
+	// PrimaryFullSnapshotScheduleName is the name of the full snapshot schedule given with --schedule, among the named
+	// additional full snapshot schedules.
+	PrimaryFullSnapshotScheduleName = "primary"
+
+	// DefaultFullSnapshotScheduleSkewTolerance is the default tolerance for the clock skew between the scheduled time of a full snapshot and its creation time
+	DefaultFullSnapshotScheduleSkewTolerance = time.Minute
+	// DefaultDegradedModeMemoryLimit is the default memory limit for the events buffered while the snapshotter is degraded
+	DefaultDegradedModeMemoryLimit = 10 * DefaultDeltaSnapMemoryLimit
+	// DefaultRestoreDrillMaxFetchers is the default number of delta snapshots fetched in parallel by a restore drill
+	DefaultRestoreDrillMaxFetchers = 1
+	// DefaultRestoreDrillTimeout is the default maximum duration of a restore drill
+	DefaultRestoreDrillTimeout = 30 * time.Minute
+	// DefaultKVExportPageSize is the default number of keys fetched at once by the ranged export of the keys under the
+	// snapshot key prefix
+	DefaultKVExportPageSize = 1000
+	// DefaultTriggerCoalescingWindow is the default time for which an out of schedule snapshot trigger waits for
+	// further triggers of the same kind
+	DefaultTriggerCoalescingWindow = 250 * time.Millisecond
 
 	// DeltaSnapshotIntervalThreshold is interval between delta snapshot
 	DeltaSnapshotIntervalThreshold = time.Second
+	// MinDeltaSnapshotMemoryLimit is the size of the framing of the events of a delta snapshot, that is the brackets
+	// enclosing the list of events and the hash appended to it, with the default hash algorithm.
+	MinDeltaSnapshotMemoryLimit = 2 + sha256.Size
 )
 
 // SnapshotterState denotes the state the snapshotter would be in.
@@ -46,45 +92,219 @@ type SnapshotterState int
 
 // SnapshotterConfig holds the snapshotter config.
 type SnapshotterConfig struct {
-	FullSnapshotSchedule         string            `json:"schedule,omitempty"`
-	DeltaSnapshotPeriod          wrappers.Duration `json:"deltaSnapshotPeriod,omitempty"`
-	DeltaSnapshotMemoryLimit     uint              `json:"deltaSnapshotMemoryLimit,omitempty"`
-	GarbageCollectionPeriod      wrappers.Duration `json:"garbageCollectionPeriod,omitempty"`
-	GarbageCollectionPolicy      string            `json:"garbageCollectionPolicy,omitempty"`
-	MaxBackups                   uint              `json:"maxBackups,omitempty"`
-	DeltaSnapshotRetentionPeriod wrappers.Duration `json:"deltaSnapshotRetentionPeriod,omitempty"`
+	FullSnapshotSchedule               string            `json:"schedule,omitempty"`
+	AdditionalFullSnapshotSchedules    []string          `json:"additionalSchedules,omitempty"`
+	DeltaSnapshotPeriod                wrappers.Duration `json:"deltaSnapshotPeriod,omitempty"`
+	DeltaSnapshotMemoryLimit           uint              `json:"deltaSnapshotMemoryLimit,omitempty"`
+	GarbageCollectionPeriod            wrappers.Duration `json:"garbageCollectionPeriod,omitempty"`
+	GarbageCollectionPolicy            string            `json:"garbageCollectionPolicy,omitempty"`
+	MaxBackups                         uint              `json:"maxBackups,omitempty"`
+	MaxTotalStorageBytes               int64             `json:"maxTotalStorageBytes,omitempty"`
+	DeltaSnapshotRetentionPeriod       wrappers.Duration `json:"deltaSnapshotRetentionPeriod,omitempty"`
+	MinDeltaSnapshotsToKeep            uint              `json:"minDeltaSnapshotsToKeep,omitempty"`
+	BaseSnapshotCheckPeriod            wrappers.Duration `json:"baseSnapshotCheckPeriod,omitempty"`
+	DeltaSnapshotReconciliationPeriod  wrappers.Duration `json:"deltaSnapshotReconciliationPeriod,omitempty"`
+	MaxFullSnapshotAge                 wrappers.Duration `json:"maxFullSnapshotAge,omitempty"`
+	FullSnapshotScheduleSkewTolerance  wrappers.Duration `json:"fullSnapshotScheduleSkewTolerance,omitempty"`
+	IncrementalDeltaCompression        bool              `json:"incrementalDeltaCompression,omitempty"`
+	DeltaEventsCollectionTimeout       wrappers.Duration `json:"deltaEventsCollectionTimeout,omitempty"`
+	MaxConsecutiveFullSnapshotFailures uint              `json:"maxConsecutiveFullSnapshotFailures,omitempty"`
+	GarbageCollectInvalidSnapshots     bool              `json:"garbageCollectInvalidSnapshots,omitempty"`
+	MaxParallelDeltaSnapshotUploads    uint              `json:"maxParallelDeltaSnapshotUploads,omitempty"`
+	CanaryKeyPrefix                    string            `json:"canaryKeyPrefix,omitempty"`
+	SnapshotKeyPrefix                  string            `json:"snapshotKeyPrefix,omitempty"`
+	RequireDeltaSnapshots              bool              `json:"requireDeltaSnapshots,omitempty"`
+	EtcdAlarmPolicy                    string            `json:"etcdAlarmPolicy,omitempty"`
+	DegradedModeRetryPeriod            wrappers.Duration `json:"degradedModeRetryPeriod,omitempty"`
+	DegradedModeMemoryLimit            uint              `json:"degradedModeMemoryLimit,omitempty"`
+	WriteChainManifest                 bool              `json:"writeChainManifest,omitempty"`
+	RecordClusterMetadata              bool              `json:"recordClusterMetadata,omitempty"`
+	SplitDeltaSnapshotsAtMemoryLimit   bool              `json:"splitDeltaSnapshotsAtMemoryLimit,omitempty"`
+	DeduplicateDeltaSnapshots          bool              `json:"deduplicateDeltaSnapshots,omitempty"`
+	RestoreDrillSchedule               string            `json:"restoreDrillSchedule,omitempty"`
+	RestoreDrillMaxFetchers            uint              `json:"restoreDrillMaxFetchers,omitempty"`
+	RestoreDrillTimeout                wrappers.Duration `json:"restoreDrillTimeout,omitempty"`
+	DeltaSnapshotHashAlgorithm         string            `json:"deltaSnapshotHashAlgorithm,omitempty"`
+	EtcdDBSizeMetricsPeriod            wrappers.Duration `json:"etcdDBSizeMetricsPeriod,omitempty"`
+	KVExportPageSize                   int64             `json:"kvExportPageSize,omitempty"`
+	TriggerCoalescingWindow            wrappers.Duration `json:"triggerCoalescingWindow,omitempty"`
 }
 
 // AddFlags adds the flags to flagset.
 func (c *SnapshotterConfig) AddFlags(fs *flag.FlagSet) {
 	fs.StringVarP(&c.FullSnapshotSchedule, "schedule", "s", c.FullSnapshotSchedule, "schedule for snapshots")
+	fs.StringArrayVar(&c.AdditionalFullSnapshotSchedules, "additional-schedule", c.AdditionalFullSnapshotSchedules, "named schedule for full snapshots in addition to --schedule, given as <name>=<cron spec>, such as 'weekend=0 */6 * * 0,6'. It can be given multiple times. The full snapshots are taken at the earliest upcoming time across all the schedules.")
 	fs.DurationVar(&c.DeltaSnapshotPeriod.Duration, "delta-snapshot-period", c.DeltaSnapshotPeriod.Duration, "Period after which delta snapshot will be persisted. If this value is set to be lesser than 1, delta snapshotting will be disabled.")
 	fs.UintVar(&c.DeltaSnapshotMemoryLimit, "delta-snapshot-memory-limit", c.DeltaSnapshotMemoryLimit, "memory limit after which delta snapshots will be taken")
 	fs.DurationVar(&c.GarbageCollectionPeriod.Duration, "garbage-collection-period", c.GarbageCollectionPeriod.Duration, "Period for garbage collecting old backups")
 	fs.StringVar(&c.GarbageCollectionPolicy, "garbage-collection-policy", c.GarbageCollectionPolicy, "Policy for garbage collecting old backups")
 	fs.UintVarP(&c.MaxBackups, "max-backups", "m", c.MaxBackups, "maximum number of previous backups to keep")
+	fs.Int64Var(&c.MaxTotalStorageBytes, "max-total-storage-bytes", c.MaxTotalStorageBytes, "maximum total size in bytes of the snapshot objects in the snapstore, for garbage collection policy set to storage budget. The oldest snapshots are deleted until the snapshots fit within it, except for the latest full snapshot and its delta snapshots, which are always retained.")
 	fs.DurationVar(&c.DeltaSnapshotRetentionPeriod.Duration, "delta-snapshot-retention-period", c.DeltaSnapshotRetentionPeriod.Duration, "Defines the retention period for older delta snapshots, excluding the latest snapshot set which is always retained for data safety.")
+	fs.UintVar(&c.MinDeltaSnapshotsToKeep, "min-delta-snapshots-to-keep", c.MinDeltaSnapshotsToKeep, "minimum number of most recent delta snapshots to retain during garbage collection, irrespective of their age")
+	fs.DurationVar(&c.BaseSnapshotCheckPeriod.Duration, "base-snapshot-check-period", c.BaseSnapshotCheckPeriod.Duration, "Period after which the presence of the previous full snapshot in the snapstore is verified, and a new full snapshot is taken if it is missing. If this value is set to be lesser than 1, the verification will be disabled.")
+	fs.DurationVar(&c.DeltaSnapshotReconciliationPeriod.Duration, "delta-snapshot-reconciliation-period", c.DeltaSnapshotReconciliationPeriod.Duration, "Period after which the snapstore is listed again to reconcile the delta snapshots of the previous full snapshot known to the snapshotter with the ones actually present in the snapstore, such as after they were deleted or added by another process. If this value is set to be lesser than 1, the reconciliation will be disabled.")
+	fs.DurationVar(&c.MaxFullSnapshotAge.Duration, "max-full-snapshot-age", c.MaxFullSnapshotAge.Duration, "Maximum age of the latest full snapshot, beyond which a full snapshot is taken at startup. If set, it takes precedence over the time window derived from the full snapshot schedule. If this value is set to be lesser than 1, the time window derived from the full snapshot schedule is used.")
+	fs.DurationVar(&c.FullSnapshotScheduleSkewTolerance.Duration, "full-snapshot-schedule-skew-tolerance", c.FullSnapshotScheduleSkewTolerance.Duration, "Tolerance for the clock skew between the previous scheduled full snapshot time and the creation time of the previous full snapshot, within which the previous full snapshot is considered to be taken at the scheduled time at startup, instead of a missed scheduled full snapshot. If this value is set to be lesser than 1, the times have to match exactly.")
+	fs.BoolVar(&c.IncrementalDeltaCompression, "incremental-delta-snapshot-compression", c.IncrementalDeltaCompression, "compress the events of delta snapshots into a temporary file as they arrive, instead of holding them uncompressed in memory until the delta snapshot is taken. Only applies if compression is enabled, and with the auto compression policy once a compression policy is locked in.")
+	fs.DurationVar(&c.DeltaEventsCollectionTimeout.Duration, "delta-events-collection-timeout", c.DeltaEventsCollectionTimeout.Duration, "Timeout for collecting the events since the previous snapshot at startup, after which a full snapshot is taken instead. This guards against the watch never reaching the latest etcd revision, for example if the events have been compacted. If this value is set to be lesser than 1, the collection of events will not time out.")
+	fs.UintVar(&c.MaxConsecutiveFullSnapshotFailures, "max-consecutive-full-snapshot-failures", c.MaxConsecutiveFullSnapshotFailures, "Number of consecutive failed full snapshots after which the snapshotter fails. A failed full snapshot is retried with an exponential backoff until then. If this value is set to be lesser than 2, the snapshotter fails on the first failed full snapshot.")
+	fs.BoolVar(&c.GarbageCollectInvalidSnapshots, "garbage-collect-invalid-snapshots", c.GarbageCollectInvalidSnapshots, "delete the structurally invalid delta snapshots, such as the partial objects left behind by failed uploads, during garbage collection. A delta snapshot is only deleted if its contents are definitely not a list of events followed by their hash.")
+	fs.UintVar(&c.MaxParallelDeltaSnapshotUploads, "max-parallel-delta-snapshot-uploads", c.MaxParallelDeltaSnapshotUploads, "maximum number of delta snapshots uploaded concurrently while the events keep crossing the delta snapshot memory limit. The delta snapshots are still recorded in the order of their revisions. If this value is set to be lesser than 2, the delta snapshots are uploaded one after the other.")
+	fs.StringVar(&c.CanaryKeyPrefix, "canary-key-prefix", c.CanaryKeyPrefix, "key prefix under which a canary key with a unique value is written before each full snapshot, replacing the previous canary keys, to verify the snapshots end-to-end on restore. It must end with a '/' and must not overlap with the keys of the etcd clients. If empty, no canary keys are written.")
+	fs.StringVar(&c.SnapshotKeyPrefix, "snapshot-key-prefix", c.SnapshotKeyPrefix, "key prefix to which the snapshots are scoped. If set, the full snapshots are taken as a ranged export of the keys under the prefix instead of with the snapshot API of etcd, and the delta snapshots only hold the events of the keys under the prefix. Such snapshots can only be restored with the same --restore-key-prefix. If empty, the snapshots hold the whole keyspace.")
+	fs.Int64Var(&c.KVExportPageSize, "kv-export-page-size", c.KVExportPageSize, "number of keys fetched at once by the ranged export of the keys under the snapshot key prefix, to bound the size of each response of etcd and the memory it takes. All the pages are read at the same revision, so that the full snapshot is consistent. If this value is set to be lesser than 1, the default page size is used.")
+	fs.BoolVar(&c.RequireDeltaSnapshots, "require-delta-snapshots", c.RequireDeltaSnapshots, "reject a delta snapshot period which disables delta snapshotting, instead of only warning about it. Without delta snapshots, the data can only be restored up to the latest full snapshot.")
+	fs.StringVar(&c.EtcdAlarmPolicy, "etcd-alarm-policy", c.EtcdAlarmPolicy, "Policy for handling the active etcd alarms, which are queried before and after each full snapshot. With the Ignore policy they are not queried. With the Report policy they are logged and exposed as a metric. The FailOnNoSpace policy additionally fails the full snapshots while a NOSPACE alarm is active, as etcd rejects all writes until it is disarmed.")
+	fs.DurationVar(&c.DegradedModeRetryPeriod.Duration, "degraded-mode-retry-period", c.DegradedModeRetryPeriod.Duration, "Period after which the snapshots are retried while the snapshotter is degraded because the snapstore is out of quota or capacity. While degraded, the watch on etcd is kept and its events are buffered instead of uploaded. If this value is set to be lesser than 1, the degraded mode is disabled and such failed snapshots fail the snapshotter like any other.")
+	fs.UintVar(&c.DegradedModeMemoryLimit, "degraded-mode-memory-limit", c.DegradedModeMemoryLimit, "memory limit of the events buffered while the snapshotter is degraded, beyond which the snapshotter fails.")
+	fs.BoolVar(&c.WriteChainManifest, "write-chain-manifest", c.WriteChainManifest, "maintain a chain manifest object under the prefix of the snapstore, listing the latest full snapshot and its delta snapshots with their revisions and object sizes, which is updated after each snapshot and garbage collection. It allows finding the snapshots to restore from without listing the snapstore. Only supported by the Local and S3 compatible storage providers.")
+	fs.BoolVar(&c.RecordClusterMetadata, "record-cluster-metadata", c.RecordClusterMetadata, "save the members of the etcd cluster, with their IDs, names and peer URLs, along with each full snapshot, so that the cluster can be rebuilt with the same members from it with --use-cluster-metadata. Only supported by the Local and S3 compatible storage providers.")
+	fs.BoolVar(&c.SplitDeltaSnapshotsAtMemoryLimit, "split-delta-snapshots-at-memory-limit", c.SplitDeltaSnapshotsAtMemoryLimit, "take a delta snapshot as soon as the events of a watch response cross the delta snapshot memory limit, and carry the remaining events of the response over to the next delta snapshot, instead of taking a single delta snapshot of all the events of the response. The events of a revision are never split across delta snapshots.")
+	fs.BoolVar(&c.DeduplicateDeltaSnapshots, "deduplicate-delta-snapshots", c.DeduplicateDeltaSnapshots, "skip saving a delta snapshot whose events repeat the operations of the previous delta snapshot, e.g. due to retried writes, and carry its events over to the next delta snapshot instead, so that no revision is dropped.")
+	fs.StringVar(&c.RestoreDrillSchedule, "restore-drill-schedule", c.RestoreDrillSchedule, "schedule of the restore drills, which restore the latest snapshots into a throwaway directory next to the etcd data directory and boot an embedded etcd on it, to verify that it reaches the revision of the latest snapshot. The outcome of the drills is exposed as a metric. If empty, no restore drills are run.")
+	fs.UintVar(&c.RestoreDrillMaxFetchers, "restore-drill-max-fetchers", c.RestoreDrillMaxFetchers, "maximum number of delta snapshots fetched in parallel by a restore drill.")
+	fs.DurationVar(&c.RestoreDrillTimeout.Duration, "restore-drill-timeout", c.RestoreDrillTimeout.Duration, "maximum duration of a restore drill, after which it is aborted and fails. If this value is set to be lesser than 1, the restore drills are not limited in time.")
+	fs.StringVar(&c.DeltaSnapshotHashAlgorithm, "delta-snapshot-hash-algorithm", c.DeltaSnapshotHashAlgorithm, "hash algorithm of the hash appended to the events of the delta snapshots to verify their integrity, one of sha256, sha512 or blake2b. The hash algorithm is recorded in the delta snapshots other than with sha256, so that the restoration verifies each delta snapshot with the hash algorithm it was taken with. The delta snapshots taken with sha512 or blake2b can't be restored by older versions.")
+	fs.DurationVar(&c.EtcdDBSizeMetricsPeriod.Duration, "etcd-db-size-metrics-period", c.EtcdDBSizeMetricsPeriod.Duration, "Period after which the size of the backend database of etcd and its size in use are read from the status of the etcd member and exposed as metrics, to show its fragmentation. If this value is set to be lesser than 1, the size of the database is not read.")
+	fs.DurationVar(&c.TriggerCoalescingWindow.Duration, "trigger-coalescing-window", c.TriggerCoalescingWindow.Duration, "Time for which an out of schedule snapshot trigger waits for further triggers of the same kind, which then share the snapshot taken for it instead of each queueing up a snapshot of their own. If this value is set to be lesser than 1, the triggers don't wait, and only share the snapshot of a trigger if they arrive before it is started.")
+}
+
+// NamedSchedule is a full snapshot schedule along with its name.
+type NamedSchedule struct {
+	Name string
+	cron.Schedule
+}
+
+// ParseAdditionalFullSnapshotSchedules parses the additional full snapshot schedules, each given as <name>=<cron spec>,
+// in the order they are given. The names must be unique and must not be the name of the primary schedule.
+func (c *SnapshotterConfig) ParseAdditionalFullSnapshotSchedules() ([]NamedSchedule, error) {
+	var (
+		schedules []NamedSchedule
+		errs      []error
+		names     = map[string]bool{PrimaryFullSnapshotScheduleName: true}
+	)
+	for _, namedSchedule := range c.AdditionalFullSnapshotSchedules {
+		name, spec, found := strings.Cut(namedSchedule, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			errs = append(errs, fmt.Errorf("invalid additional full snapshot schedule %s: should be given as <name>=<cron spec>", namedSchedule))
+			continue
+		}
+		if names[name] {
+			errs = append(errs, fmt.Errorf("invalid additional full snapshot schedule %s: name %s is already used", namedSchedule, name))
+			continue
+		}
+		names[name] = true
+		sdl, err := cron.ParseStandard(spec)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid additional full snapshot schedule %s: %v", namedSchedule, err))
+			continue
+		}
+		schedules = append(schedules, NamedSchedule{Name: name, Schedule: sdl})
+	}
+	return schedules, errors.Join(errs...)
 }
 
-// Validate validates the config.
+// Validate validates the config, returning the combined errors of all the invalid fields.
 func (c *SnapshotterConfig) Validate() error {
+	var errs []error
 	if _, err := cron.ParseStandard(c.FullSnapshotSchedule); err != nil {
-		return err
+		errs = append(errs, fmt.Errorf("invalid full snapshot schedule %s: %v", c.FullSnapshotSchedule, err))
 	}
-	if c.GarbageCollectionPolicy != GarbageCollectionPolicyLimitBased && c.GarbageCollectionPolicy != GarbageCollectionPolicyExponential {
-		return fmt.Errorf("invalid garbage collection policy: %s", c.GarbageCollectionPolicy)
+	if _, err := c.ParseAdditionalFullSnapshotSchedules(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.RestoreDrillSchedule != "" {
+		if _, err := cron.ParseStandard(c.RestoreDrillSchedule); err != nil {
+			errs = append(errs, fmt.Errorf("invalid restore drill schedule %s: %v", c.RestoreDrillSchedule, err))
+		}
+		if c.RestoreDrillMaxFetchers <= 0 {
+			errs = append(errs, fmt.Errorf("restore drill max fetchers should be greater than zero"))
+		}
+	}
+	if c.GarbageCollectionPolicy != GarbageCollectionPolicyLimitBased && c.GarbageCollectionPolicy != GarbageCollectionPolicyExponential && c.GarbageCollectionPolicy != GarbageCollectionPolicyStorageBudget {
+		errs = append(errs, fmt.Errorf("invalid garbage collection policy: %s", c.GarbageCollectionPolicy))
 	}
 	if c.GarbageCollectionPolicy == GarbageCollectionPolicyLimitBased && c.MaxBackups <= 0 {
-		return fmt.Errorf("max backups should be greather than zero for garbage collection policy set to limit based")
+		errs = append(errs, fmt.Errorf("max backups should be greather than zero for garbage collection policy set to limit based"))
+	}
+	if c.GarbageCollectionPolicy == GarbageCollectionPolicyStorageBudget && c.MaxTotalStorageBytes <= 0 {
+		errs = append(errs, fmt.Errorf("max total storage bytes should be greater than zero for garbage collection policy set to storage budget"))
+	}
+	if c.EtcdAlarmPolicy != EtcdAlarmPolicyIgnore && c.EtcdAlarmPolicy != EtcdAlarmPolicyReport && c.EtcdAlarmPolicy != EtcdAlarmPolicyFailOnNoSpace {
+		errs = append(errs, fmt.Errorf("invalid etcd alarm policy: %s", c.EtcdAlarmPolicy))
 	}
 
-	if c.DeltaSnapshotPeriod.Duration < DeltaSnapshotIntervalThreshold {
-		logrus.Infof("Found delta snapshot interval %s less than 1 second. Disabling delta snapshotting. ", c.DeltaSnapshotPeriod)
+	for _, d := range []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"delta snapshot period", c.DeltaSnapshotPeriod.Duration},
+		{"garbage collection period", c.GarbageCollectionPeriod.Duration},
+		{"delta snapshot retention period", c.DeltaSnapshotRetentionPeriod.Duration},
+		{"base snapshot check period", c.BaseSnapshotCheckPeriod.Duration},
+		{"delta snapshot reconciliation period", c.DeltaSnapshotReconciliationPeriod.Duration},
+		{"max full snapshot age", c.MaxFullSnapshotAge.Duration},
+		{"full snapshot schedule skew tolerance", c.FullSnapshotScheduleSkewTolerance.Duration},
+		{"delta events collection timeout", c.DeltaEventsCollectionTimeout.Duration},
+		{"degraded mode retry period", c.DegradedModeRetryPeriod.Duration},
+		{"restore drill timeout", c.RestoreDrillTimeout.Duration},
+		{"etcd db size metrics period", c.EtcdDBSizeMetricsPeriod.Duration},
+		{"trigger coalescing window", c.TriggerCoalescingWindow.Duration},
+	} {
+		if d.duration < 0 {
+			errs = append(errs, fmt.Errorf("%s should not be negative: %s", d.name, d.duration))
+		}
+	}
+
+	if err := ValidateDeltaSnapshotHashAlgorithm(c.DeltaSnapshotHashAlgorithm); err != nil {
+		errs = append(errs, err)
+	} else if minLimit := uint(2 + DeltaSnapshotHashTrailerSize(c.DeltaSnapshotHashAlgorithm)); c.DeltaSnapshotMemoryLimit > 0 && c.DeltaSnapshotMemoryLimit < minLimit {
+		errs = append(errs, fmt.Errorf("delta snapshot memory limit %d bytes should not be less than the %d bytes framing the events of a delta snapshot", c.DeltaSnapshotMemoryLimit, minLimit))
+	}
+
+	if c.DegradedModeMemoryLimit > 0 && c.DeltaSnapshotMemoryLimit > 0 && c.DegradedModeMemoryLimit < c.DeltaSnapshotMemoryLimit {
+		errs = append(errs, fmt.Errorf("degraded mode memory limit %d bytes should not be less than the delta snapshot memory limit %d bytes", c.DegradedModeMemoryLimit, c.DeltaSnapshotMemoryLimit))
+	}
+
+	// The garbage collector and the delta snapshots are disabled below their thresholds.
+	if c.GarbageCollectionPeriod.Duration > time.Second && c.DeltaSnapshotPeriod.Duration >= DeltaSnapshotIntervalThreshold &&
+		c.GarbageCollectionPeriod.Duration < c.DeltaSnapshotPeriod.Duration {
+		errs = append(errs, fmt.Errorf("garbage collection period %s should not be shorter than delta snapshot period %s", c.GarbageCollectionPeriod.Duration, c.DeltaSnapshotPeriod.Duration))
+	}
+	if err := ValidateCanaryKeyPrefix(c.CanaryKeyPrefix); err != nil {
+		errs = append(errs, err)
+	}
+	if c.SnapshotKeyPrefix != "" && c.CanaryKeyPrefix != "" {
+		errs = append(errs, fmt.Errorf("canary keys cannot be written for snapshots scoped to the key prefix %s", c.SnapshotKeyPrefix))
+	}
+	if c.RequireDeltaSnapshots && c.DeltaSnapshotPeriod.Duration < DeltaSnapshotIntervalThreshold {
+		errs = append(errs, fmt.Errorf("delta snapshot period %s should not be less than %s, as delta snapshots are required", c.DeltaSnapshotPeriod.Duration, DeltaSnapshotIntervalThreshold))
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
 	}
 
 	if c.DeltaSnapshotMemoryLimit < 1 {
 		logrus.Infof("Found delta snapshot memory limit %d bytes less than 1 byte. Setting it to default: %d ", c.DeltaSnapshotMemoryLimit, DefaultDeltaSnapMemoryLimit)
 		c.DeltaSnapshotMemoryLimit = DefaultDeltaSnapMemoryLimit
 	}
+	if c.DegradedModeMemoryLimit < 1 {
+		logrus.Infof("Found degraded mode memory limit %d bytes less than 1 byte. Setting it to default: %d ", c.DegradedModeMemoryLimit, DefaultDegradedModeMemoryLimit)
+		c.DegradedModeMemoryLimit = DefaultDegradedModeMemoryLimit
+	}
+	return nil
+}
+
+// ValidateCanaryKeyPrefix validates the key prefix of the canary keys, if set. The prefix has to name a dedicated
+// directory-like key range, as all the keys under it are deleted when a new canary key is written.
+func ValidateCanaryKeyPrefix(prefix string) error {
+	if prefix == "" {
+		return nil
+	}
+	if !strings.HasSuffix(prefix, "/") || strings.Trim(prefix, "/") == "" {
+		return fmt.Errorf("canary key prefix %s should end with a '/' and name a dedicated key range", prefix)
+	}
 	return nil
 }
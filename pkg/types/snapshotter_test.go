@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types_test
+
+import (
+	"time"
+
+	. "github.com/gardener/etcd-backup-restore/pkg/types"
+	"github.com/gardener/etcd-backup-restore/pkg/wrappers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validating the snapshotter config", func() {
+	var config *SnapshotterConfig
+
+	BeforeEach(func() {
+		config = &SnapshotterConfig{
+			FullSnapshotSchedule:         DefaultFullSnapshotSchedule,
+			DeltaSnapshotPeriod:          wrappers.Duration{Duration: DefaultDeltaSnapshotInterval},
+			DeltaSnapshotMemoryLimit:     DefaultDeltaSnapMemoryLimit,
+			GarbageCollectionPeriod:      wrappers.Duration{Duration: DefaultGarbageCollectionPeriod},
+			GarbageCollectionPolicy:      GarbageCollectionPolicyExponential,
+			EtcdAlarmPolicy:              EtcdAlarmPolicyIgnore,
+			MaxBackups:                   DefaultMaxBackups,
+			BaseSnapshotCheckPeriod:      wrappers.Duration{Duration: DefaultBaseSnapshotCheckPeriod},
+			DeltaEventsCollectionTimeout: wrappers.Duration{Duration: DefaultDeltaEventsCollectionTimeout},
+		}
+	})
+
+	It("should accept the default config", func() {
+		Expect(config.Validate()).To(Succeed())
+	})
+
+	It("should accept disabled delta snapshots and garbage collection", func() {
+		config.DeltaSnapshotPeriod.Duration = 0
+		config.GarbageCollectionPeriod.Duration = 0
+		config.DeltaSnapshotMemoryLimit = 0
+		Expect(config.Validate()).To(Succeed())
+		Expect(config.DeltaSnapshotMemoryLimit).To(Equal(uint(DefaultDeltaSnapMemoryLimit)))
+		Expect(config.DegradedModeMemoryLimit).To(Equal(uint(DefaultDegradedModeMemoryLimit)))
+	})
+
+	DescribeTable("should reject an invalid field",
+		func(invalidate func(*SnapshotterConfig), message string) {
+			invalidate(config)
+			err := config.Validate()
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(message))
+		},
+		Entry("full snapshot schedule", func(c *SnapshotterConfig) { c.FullSnapshotSchedule = "* * *" }, "invalid full snapshot schedule"),
+		Entry("additional full snapshot schedule", func(c *SnapshotterConfig) {
+			c.AdditionalFullSnapshotSchedules = []string{"weekend=* * *"}
+		}, "invalid additional full snapshot schedule weekend=* * *"),
+		Entry("additional full snapshot schedule without a name", func(c *SnapshotterConfig) {
+			c.AdditionalFullSnapshotSchedules = []string{"0 */6 * * 0,6"}
+		}, "should be given as <name>=<cron spec>"),
+		Entry("additional full snapshot schedule with a duplicate name", func(c *SnapshotterConfig) {
+			c.AdditionalFullSnapshotSchedules = []string{"weekend=0 */6 * * 6", "weekend=0 */6 * * 0"}
+		}, "name weekend is already used"),
+		Entry("additional full snapshot schedule with the name of the primary schedule", func(c *SnapshotterConfig) {
+			c.AdditionalFullSnapshotSchedules = []string{PrimaryFullSnapshotScheduleName + "=0 */6 * * 0,6"}
+		}, "name primary is already used"),
+		Entry("garbage collection policy", func(c *SnapshotterConfig) { c.GarbageCollectionPolicy = "Random" }, "invalid garbage collection policy"),
+		Entry("etcd alarm policy", func(c *SnapshotterConfig) { c.EtcdAlarmPolicy = "Random" }, "invalid etcd alarm policy"),
+		Entry("max backups", func(c *SnapshotterConfig) {
+			c.GarbageCollectionPolicy = GarbageCollectionPolicyLimitBased
+			c.MaxBackups = 0
+		}, "max backups should be greather than zero"),
+		Entry("max total storage bytes", func(c *SnapshotterConfig) {
+			c.GarbageCollectionPolicy = GarbageCollectionPolicyStorageBudget
+			c.MaxTotalStorageBytes = 0
+		}, "max total storage bytes should be greater than zero"),
+		Entry("delta snapshot period", func(c *SnapshotterConfig) { c.DeltaSnapshotPeriod.Duration = -time.Second }, "delta snapshot period should not be negative"),
+		Entry("garbage collection period", func(c *SnapshotterConfig) { c.GarbageCollectionPeriod.Duration = -time.Second }, "garbage collection period should not be negative"),
+		Entry("delta snapshot retention period", func(c *SnapshotterConfig) { c.DeltaSnapshotRetentionPeriod.Duration = -time.Hour }, "delta snapshot retention period should not be negative"),
+		Entry("base snapshot check period", func(c *SnapshotterConfig) { c.BaseSnapshotCheckPeriod.Duration = -time.Minute }, "base snapshot check period should not be negative"),
+		Entry("delta snapshot reconciliation period", func(c *SnapshotterConfig) { c.DeltaSnapshotReconciliationPeriod.Duration = -time.Minute }, "delta snapshot reconciliation period should not be negative"),
+		Entry("max full snapshot age", func(c *SnapshotterConfig) { c.MaxFullSnapshotAge.Duration = -time.Hour }, "max full snapshot age should not be negative"),
+		Entry("full snapshot schedule skew tolerance", func(c *SnapshotterConfig) { c.FullSnapshotScheduleSkewTolerance.Duration = -time.Second }, "full snapshot schedule skew tolerance should not be negative"),
+		Entry("delta events collection timeout", func(c *SnapshotterConfig) { c.DeltaEventsCollectionTimeout.Duration = -time.Minute }, "delta events collection timeout should not be negative"),
+		Entry("delta snapshot memory limit", func(c *SnapshotterConfig) { c.DeltaSnapshotMemoryLimit = MinDeltaSnapshotMemoryLimit - 1 }, "delta snapshot memory limit"),
+		Entry("delta snapshot memory limit with another hash algorithm", func(c *SnapshotterConfig) {
+			c.DeltaSnapshotHashAlgorithm = DeltaSnapshotHashSHA512
+			c.DeltaSnapshotMemoryLimit = MinDeltaSnapshotMemoryLimit
+		}, "delta snapshot memory limit"),
+		Entry("delta snapshot hash algorithm", func(c *SnapshotterConfig) { c.DeltaSnapshotHashAlgorithm = "md5" }, "unsupported delta snapshot hash algorithm md5"),
+		Entry("degraded mode retry period", func(c *SnapshotterConfig) { c.DegradedModeRetryPeriod.Duration = -time.Minute }, "degraded mode retry period should not be negative"),
+		Entry("degraded mode memory limit", func(c *SnapshotterConfig) { c.DegradedModeMemoryLimit = DefaultDeltaSnapMemoryLimit - 1 }, "degraded mode memory limit"),
+		Entry("garbage collection period shorter than delta snapshot period", func(c *SnapshotterConfig) {
+			c.GarbageCollectionPeriod.Duration = 10 * time.Second
+			c.DeltaSnapshotPeriod.Duration = time.Minute
+		}, "garbage collection period 10s should not be shorter than delta snapshot period 1m0s"),
+		Entry("canary key prefix", func(c *SnapshotterConfig) { c.CanaryKeyPrefix = "/" }, "canary key prefix / should end with a '/' and name a dedicated key range"),
+		Entry("canary key prefix with a snapshot key prefix", func(c *SnapshotterConfig) {
+			c.SnapshotKeyPrefix = "/registry/"
+			c.CanaryKeyPrefix = "/etcd-backup-restore/canary/"
+		}, "canary keys cannot be written for snapshots scoped to the key prefix /registry/"),
+		Entry("delta snapshot period with delta snapshots required", func(c *SnapshotterConfig) {
+			c.RequireDeltaSnapshots = true
+			c.DeltaSnapshotPeriod.Duration = 0
+		}, "delta snapshot period 0s should not be less than 1s, as delta snapshots are required"),
+		Entry("restore drill schedule", func(c *SnapshotterConfig) { c.RestoreDrillSchedule = "* * *" }, "invalid restore drill schedule"),
+		Entry("restore drill max fetchers", func(c *SnapshotterConfig) {
+			c.RestoreDrillSchedule = "0 3 * * *"
+			c.RestoreDrillMaxFetchers = 0
+		}, "restore drill max fetchers should be greater than zero"),
+		Entry("restore drill timeout", func(c *SnapshotterConfig) { c.RestoreDrillTimeout.Duration = -time.Minute }, "restore drill timeout should not be negative"),
+		Entry("etcd db size metrics period", func(c *SnapshotterConfig) { c.EtcdDBSizeMetricsPeriod.Duration = -time.Minute }, "etcd db size metrics period should not be negative"),
+		Entry("trigger coalescing window", func(c *SnapshotterConfig) { c.TriggerCoalescingWindow.Duration = -time.Second }, "trigger coalescing window should not be negative"),
+	)
+
+	It("should accept the named additional full snapshot schedules", func() {
+		config.AdditionalFullSnapshotSchedules = []string{"weekend=0 */6 * * 0,6", "month-start=30 0 1 * *"}
+		Expect(config.Validate()).To(Succeed())
+		schedules, err := config.ParseAdditionalFullSnapshotSchedules()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(schedules).To(HaveLen(2))
+		Expect(schedules[0].Name).To(Equal("weekend"))
+		Expect(schedules[1].Name).To(Equal("month-start"))
+	})
+
+	It("should report all the invalid fields at once", func() {
+		config.FullSnapshotSchedule = "* * *"
+		config.DeltaSnapshotPeriod.Duration = -time.Second
+		config.DeltaSnapshotMemoryLimit = 1
+		err := config.Validate()
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("invalid full snapshot schedule"))
+		Expect(err.Error()).To(ContainSubstring("delta snapshot period should not be negative"))
+		Expect(err.Error()).To(ContainSubstring("delta snapshot memory limit"))
+	})
+})
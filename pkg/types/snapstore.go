@@ -12,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gardener/etcd-backup-restore/pkg/wrappers"
 	flag "github.com/spf13/pflag"
 )
 
@@ -56,6 +57,38 @@ const (
 
 	// MinChunkSize is set to 5Mib since it is lower chunk size limit for AWS.
 	MinChunkSize int64 = 5 * (1 << 20) //5 MiB
+	// MaxS3SinglePutObjectSize is set to 5GiB since it is the upper size limit of an object uploaded with a single put to AWS.
+	MaxS3SinglePutObjectSize int64 = 5 * (1 << 30) //5 GiB
+
+	// DefaultMaxIdleConns is the default maximum number of idle connections across all hosts kept by the snapstore HTTP clients.
+	DefaultMaxIdleConns = 100
+	// DefaultMaxIdleConnsPerHost is the default maximum number of idle connections per host kept by the snapstore HTTP clients.
+	DefaultMaxIdleConnsPerHost = 20
+	// DefaultIdleConnTimeout is the default duration for which an idle connection is kept by the snapstore HTTP clients.
+	DefaultIdleConnTimeout = 90 * time.Second
+	// DefaultFetchCacheTTL is the default duration for which a snapshot is served from the fetch cache of the snapstore.
+	DefaultFetchCacheTTL = time.Hour
+	// DefaultListThrottlingRetries is the default number of times a listing of the snapstore is retried while it is throttled.
+	DefaultListThrottlingRetries = 5
+	// DefaultListThrottlingBackoff is the default backoff before the first retry of a throttled listing of the snapstore.
+	DefaultListThrottlingBackoff = time.Second
+
+	// DatePartitionLayout is the time layout of the date based partitions of a date partitioned snapstore.
+	DatePartitionLayout = "2006/01"
+
+	// SnapshotExcludeTag is the tag set on the snapshots which are to be excluded from garbage collection.
+	SnapshotExcludeTag = "x-etcd-snapshot-exclude"
+
+	// ChainManifestName is the name of the chain manifest object under the prefix of the snapstore.
+	ChainManifestName = "chain-manifest.json"
+	// CompressionDictionaryPrefix is the prefix of the names of the compression dictionary objects under the prefix
+	// of the snapstore, which are followed by the hexadecimal identifier of the dictionary.
+	CompressionDictionaryPrefix = "compression-dictionary-"
+	// ClusterMetadataSuffix is the suffix appended to the name of a full snapshot to name the object of the metadata of
+	// the etcd cluster it was taken from, which is saved next to it.
+	ClusterMetadataSuffix = ".cluster-metadata.json"
+	// RestoreLockName is the name of the restore lock object under the prefix of the snapstore.
+	RestoreLockName = "restore-lock.json"
 )
 
 // SnapStore is the interface to be implemented for different
@@ -73,6 +106,243 @@ type SnapStore interface {
 	Delete(Snapshot) error
 }
 
+// SnapshotNamer generates the object names of the snapshots and parses them back, which determines the layout
+// of the snapshots under the prefix of the snapstore.
+type SnapshotNamer interface {
+	// GenerateName returns the name of the given snapshot.
+	GenerateName(snap *Snapshot) string
+	// Parse returns the snapshot with the kind, revisions, creation time, compression suffix and finality
+	// parsed from the given snapshot name.
+	Parse(snapName string) (*Snapshot, error)
+}
+
+// PagedSnapStore is a SnapStore which is able to list the snapshots page by page,
+// using the native pagination of the storage provider.
+type PagedSnapStore interface {
+	SnapStore
+	// ListPaged will return sorted list with at most limit snapshot files on store, which are stored after the given marker.
+	// The returned marker is passed to the next call to list the following page, and is empty after the last page.
+	ListPaged(marker string, limit int) (SnapList, string, error)
+}
+
+// PartitionedSnapStore is a SnapStore which saves the snapshots into date based partitions,
+// and is able to list the snapshots one partition at a time.
+type PartitionedSnapStore interface {
+	SnapStore
+	// ListPartitions will return the partitions present on store, sorted from oldest to newest.
+	ListPartitions() ([]string, error)
+	// ListPartition will return sorted list with all snapshot files in the given partition.
+	ListPartition(string) (SnapList, error)
+}
+
+// RetainableSnapStore is a SnapStore which is able to tag snapshots with the SnapshotExcludeTag,
+// to retain them irrespective of the garbage collection policy.
+type RetainableSnapStore interface {
+	SnapStore
+	// SetRetained sets the SnapshotExcludeTag on the snapshot if retained is true, and clears it otherwise.
+	SetRetained(snap Snapshot, retained bool) error
+	// IsRetained returns whether the SnapshotExcludeTag is set on the snapshot.
+	IsRetained(snap Snapshot) (bool, error)
+}
+
+// PrefixCopyingSnapStore is a SnapStore which is able to copy the snapshots to another prefix of the same container,
+// preserving the metadata and the tags of the snapshot objects. It is implemented by the Local, the S3 compatible and
+// the GCS snapstores, but not by the ABS, Swift and OSS snapstores.
+type PrefixCopyingSnapStore interface {
+	SnapStore
+	// ListPrefix will return sorted list with all snapshot files stored under the given prefix.
+	ListPrefix(prefix string) (SnapList, error)
+	// CopyToPrefix copies the snapshot to the given prefix, which replaces the prefix of the snapshot.
+	CopyToPrefix(snap Snapshot, prefix string) error
+	// ListPrefixObjects should return the paths of the objects stored alongside the snapshots under the given prefix
+	// which aren't snapshots, i.e. the chain manifest, the compression dictionaries, the cluster metadata of the full
+	// snapshots and the restore lock.
+	ListPrefixObjects(prefix string) ([]string, error)
+	// CopyObject should copy the object at the given path to the other given path, preserving its metadata.
+	CopyObject(srcPath, dstPath string) error
+	// DeleteObject should delete the object at the given path, if any.
+	DeleteObject(objectPath string) error
+}
+
+// RangeFetchingSnapStore is a SnapStore which is able to fetch a range of the bytes of a snapshot object, so that
+// the parts of a snapshot can be read without downloading the whole object.
+type RangeFetchingSnapStore interface {
+	SnapStore
+	// FetchRange should open reader for the given number of bytes of the snapshot object, starting at the given offset.
+	// The reader returns fewer bytes if the object ends before the end of the range.
+	FetchRange(snap Snapshot, offset, length int64) (io.ReadCloser, error)
+}
+
+// SizingSnapStore is a SnapStore which is able to return the size of a snapshot object, as stored in the snapstore.
+type SizingSnapStore interface {
+	SnapStore
+	// Size should return the size of the snapshot object in bytes.
+	Size(snap Snapshot) (int64, error)
+}
+
+// ListThrottlingSnapStore is a SnapStore which is configured with the retries of its listings throttled by the storage
+// provider.
+type ListThrottlingSnapStore interface {
+	SnapStore
+	// ListThrottlingRetries should return the number of times a throttled listing is retried, and the backoff before
+	// its first retry, which is doubled for every further retry.
+	ListThrottlingRetries() (int, time.Duration)
+}
+
+// ExistenceCheckingSnapStore is a SnapStore which is able to check whether a snapshot object is present in the
+// snapstore without listing it.
+type ExistenceCheckingSnapStore interface {
+	SnapStore
+	// Exists should return whether the snapshot object is present in the store.
+	Exists(snap Snapshot) (bool, error)
+}
+
+// ChainManifestSnapStore is a SnapStore which is able to save the chain manifest, a single object describing the
+// latest full snapshot and its delta snapshots, so that the chain can be discovered without listing the snapstore.
+type ChainManifestSnapStore interface {
+	SnapStore
+	// SaveChainManifest should atomically replace the chain manifest on store. The snapshots of the manifest without
+	// a prefix or an object size are completed with the prefix of the snapstore and the size of their object, if known.
+	SaveChainManifest(manifest *ChainManifest) error
+	// FetchChainManifest should return the chain manifest from store, or nil if no chain manifest was saved.
+	FetchChainManifest() (*ChainManifest, error)
+	// DeleteChainManifest should delete the chain manifest from store, if any.
+	DeleteChainManifest() error
+}
+
+// CompressionDictionarySnapStore is a SnapStore which is able to save the dictionaries the delta snapshots are
+// compressed with by the zlib-dict compression policy, alongside the snapshots.
+type CompressionDictionarySnapStore interface {
+	SnapStore
+	// SaveCompressionDictionary should save the compression dictionary with the given identifier on store, replacing
+	// the one saved before with the same identifier, so that the time it was saved on is refreshed.
+	SaveCompressionDictionary(id uint32, dictionary []byte) error
+	// FetchCompressionDictionary should return the compression dictionary with the given identifier from store.
+	FetchCompressionDictionary(id uint32) ([]byte, error)
+	// ListCompressionDictionaries should return the compression dictionaries on store, without their contents.
+	ListCompressionDictionaries() ([]CompressionDictionary, error)
+	// DeleteCompressionDictionary should delete the compression dictionary with the given identifier from store.
+	DeleteCompressionDictionary(id uint32) error
+}
+
+// ClusterMetadataSnapStore is a SnapStore which is able to save the metadata of the etcd cluster along with each full
+// snapshot, so that the membership of the cluster the snapshot was taken from is known when restoring from it.
+type ClusterMetadataSnapStore interface {
+	SnapStore
+	// SaveClusterMetadata should save the cluster metadata of the given full snapshot on store, next to the snapshot.
+	SaveClusterMetadata(snap Snapshot, metadata *ClusterMetadata) error
+	// FetchClusterMetadata should return the cluster metadata of the given full snapshot from store, or nil if no
+	// cluster metadata was saved along with it.
+	FetchClusterMetadata(snap Snapshot) (*ClusterMetadata, error)
+}
+
+// RestoreLockSnapStore is a SnapStore which is able to hold the restore lock, a single object under the prefix of the
+// snapstore which keeps the restorations of the same cluster from the snapstore from running concurrently.
+type RestoreLockSnapStore interface {
+	SnapStore
+	// CreateRestoreLock should atomically save the given restore lock on store, unless a restore lock is already saved,
+	// in which case it should return an error wrapping snapstore.ErrRestoreLockExists.
+	CreateRestoreLock(lock *RestoreLock) error
+	// FetchRestoreLock should return the restore lock from store along with its version, or nil if no restore lock
+	// is saved.
+	FetchRestoreLock() (*RestoreLock, error)
+	// ReplaceRestoreLock should atomically replace the given restore lock, as fetched from store, with the given lock,
+	// and return an error wrapping snapstore.ErrRestoreLockExists if the restore lock was replaced or deleted since.
+	ReplaceRestoreLock(current, lock *RestoreLock) error
+	// DeleteRestoreLock should atomically delete the restore lock from store if it is held by the given holder, and
+	// leave it untouched if it was replaced since it was found to be held by the given holder.
+	DeleteRestoreLock(holder string) error
+}
+
+// VerifiableSnapStore is a SnapStore which is able to verify that its bucket or container exists and is accessible
+// with the configured credentials, so that a misconfigured snapstore is reported at startup instead of by the first
+// snapshot saved to it.
+type VerifiableSnapStore interface {
+	SnapStore
+	// Verify should return an error if the bucket or container of the snapstore doesn't exist or can't be read, or if
+	// write is true and objects can't be written to it.
+	Verify(write bool) error
+}
+
+// RestoreLock is held by a restoration from the snapstore while it replaces the data directory.
+type RestoreLock struct {
+	// Holder uniquely identifies the restoration holding the lock.
+	Holder string `json:"holder"`
+	// Host is the name of the host the restoration holding the lock runs on.
+	Host       string    `json:"host,omitempty"`
+	AcquiredOn time.Time `json:"acquiredOn"`
+	// ExpiresOn is the time after which the lock is considered stale, e.g. as it was left behind by a crashed
+	// restoration, and may be taken over by another restoration.
+	ExpiresOn time.Time `json:"expiresOn"`
+	// Version identifies the stored restore lock the lock was fetched as, such as the ETag of the restore lock
+	// object, on which replacing or deleting the restore lock is conditioned.
+	Version string `json:"-"`
+}
+
+// CompressionDictionary is a compression dictionary saved in the snapstore.
+type CompressionDictionary struct {
+	ID      uint32
+	SavedOn time.Time
+}
+
+// ChainManifest describes the latest full snapshot in the snapstore and the delta snapshots taken after it.
+type ChainManifest struct {
+	UpdatedOn time.Time `json:"updatedOn"`
+	// LatestRevision is the last revision of the latest snapshot taken into the chain, which the snapshots of the
+	// chain manifest have to cover without a gap.
+	LatestRevision int64                    `json:"latestRevision"`
+	FullSnapshot   *ChainManifestSnapshot   `json:"fullSnapshot"`
+	DeltaSnapshots []*ChainManifestSnapshot `json:"deltaSnapshots"`
+}
+
+// Snapshots returns the full snapshot of the chain manifest, if any, followed by its delta snapshots.
+func (m *ChainManifest) Snapshots() []*ChainManifestSnapshot {
+	var snaps []*ChainManifestSnapshot
+	if m.FullSnapshot != nil {
+		snaps = append(snaps, m.FullSnapshot)
+	}
+	return append(snaps, m.DeltaSnapshots...)
+}
+
+// ChainManifestSnapshot is a snapshot listed in the chain manifest, along with the size of its object in the snapstore.
+type ChainManifestSnapshot struct {
+	Snapshot
+	ObjectSizeBytes int64 `json:"objectSizeBytes"`
+}
+
+// ClusterMetadata describes the membership of the etcd cluster at the time a full snapshot was taken from it.
+type ClusterMetadata struct {
+	ClusterID uint64          `json:"clusterID"`
+	Members   []ClusterMember `json:"members"`
+}
+
+// ClusterMember describes a member of the etcd cluster, as listed by etcd.
+type ClusterMember struct {
+	ID         uint64   `json:"id"`
+	Name       string   `json:"name"`
+	PeerURLs   []string `json:"peerURLs"`
+	ClientURLs []string `json:"clientURLs,omitempty"`
+	IsLearner  bool     `json:"isLearner,omitempty"`
+}
+
+// Member returns the member of the cluster metadata with the given name, or nil if there is none.
+func (m *ClusterMetadata) Member(name string) *ClusterMember {
+	for i := range m.Members {
+		if m.Members[i].Name == name {
+			return &m.Members[i]
+		}
+	}
+	return nil
+}
+
+// SnapshotStatus holds the status of the database of a full snapshot, as reported by `etcdctl snapshot status`.
+type SnapshotStatus struct {
+	Hash      uint32 `json:"hash"`
+	Revision  int64  `json:"revision"`
+	TotalKey  int    `json:"totalKey"`
+	TotalSize int64  `json:"totalSize"`
+}
+
 // Snapshot structure represents the metadata of snapshot.s
 type Snapshot struct {
 	Kind              string    `json:"kind"` //incr:incremental,full:full
@@ -85,6 +355,7 @@ type Snapshot struct {
 	Prefix            string    `json:"prefix"`            // Points to correct prefix of a snapshot in snapstore (Required for Backward Compatibility)
 	CompressionSuffix string    `json:"compressionSuffix"` // CompressionSuffix depends on compessionPolicy
 	IsFinal           bool      `json:"isFinal"`
+	SizeBytes         int64     `json:"sizeBytes,omitempty"` // Uncompressed size of a full snapshot taken by this process, or the stored size of a listed snapshot if the snapstore lists the sizes
 }
 
 // GenerateSnapshotName prepares the snapshot name from metadata
@@ -146,6 +417,11 @@ func (s SnapList) Less(i, j int) bool {
 			return false
 		}
 		if !s[i].IsChunk && !s[j].IsChunk {
+			// the delta snapshot ending at the revision of a full snapshot taken within the same second belongs to
+			// the chain before the full snapshot
+			if s[i].CreatedOn.Unix() == s[j].CreatedOn.Unix() && s[i].Kind != s[j].Kind {
+				return s[i].Kind == SnapshotKindDelta
+			}
 			return (s[i].CreatedOn.Unix() < s[j].CreatedOn.Unix())
 		}
 		// If both are chunks, ordering doesn't matter.
@@ -155,6 +431,18 @@ func (s SnapList) Less(i, j int) bool {
 	return false
 }
 
+// RevisionCoverage describes the revisions which can be restored from a snapshot chain.
+type RevisionCoverage struct {
+	// MinRevision is the oldest revision which can be restored, i.e. the last revision of the full snapshot of the chain.
+	MinRevision int64 `json:"minRevision"`
+	// MaxRevision is the newest revision which can be restored, i.e. the last revision of the final delta snapshot of
+	// the chain before its first gap, or of the full snapshot if there is no such delta snapshot.
+	MaxRevision int64 `json:"maxRevision"`
+	// Contiguous indicates whether each delta snapshot of the chain starts right after the last revision of the
+	// previous snapshot. The revisions after a gap in the chain cannot be restored, hence they are beyond MaxRevision.
+	Contiguous bool `json:"contiguous"`
+}
+
 // SnapstoreConfig defines the configuration to create snapshot store.
 type SnapstoreConfig struct {
 	// Provider indicated the cloud provider.
@@ -163,14 +451,82 @@ type SnapstoreConfig struct {
 	Container string `json:"container"`
 	// Prefix holds the prefix or directory under StorageContainer under which snapshot will be stored.
 	Prefix string `json:"prefix,omitempty"`
+	// FullSnapshotPrefix holds the prefix or directory under StorageContainer under which the full snapshots are stored,
+	// instead of the Prefix, e.g. to apply other lifecycle rules to them. The full snapshots are stored under the Prefix if empty.
+	FullSnapshotPrefix string `json:"fullSnapshotPrefix,omitempty"`
+	// DeltaSnapshotPrefix holds the prefix or directory under StorageContainer under which the delta snapshots are
+	// stored, instead of the Prefix. The delta snapshots are stored under the Prefix if empty.
+	DeltaSnapshotPrefix string `json:"deltaSnapshotPrefix,omitempty"`
 	// MaxParallelChunkUploads holds the maximum number of parallel chunk uploads allowed.
 	MaxParallelChunkUploads uint `json:"maxParallelChunkUploads,omitempty"`
 	// MinChunkSize holds the minimum size for a multi-part chunk upload.
 	MinChunkSize int64 `json:"minChunkSize,omitempty"`
+	// MultipartThreshold holds the size from which the snapshots are uploaded to the S3 compatible storage providers in
+	// multiple parts, while the smaller snapshots are uploaded with a single put. All the snapshots are uploaded in
+	// multiple parts if it is zero.
+	MultipartThreshold int64 `json:"multipartThreshold,omitempty"`
 	// Temporary Directory
 	TempDir string `json:"tempDir,omitempty"`
-	// IsSource determines if this SnapStore is the source for a copy operation
+	// IsSource determines if this SnapStore is the source for a copy operation or a restoration, whose credentials are
+	// read from the environment variables with the `SOURCE_` prefix.
 	IsSource bool `json:"isSource,omitempty"`
+	// MaxIdleConns holds the maximum number of idle connections across all hosts kept by the HTTP client of the snapstore.
+	MaxIdleConns int `json:"maxIdleConns,omitempty"`
+	// MaxIdleConnsPerHost holds the maximum number of idle connections per host kept by the HTTP client of the snapstore.
+	MaxIdleConnsPerHost int `json:"maxIdleConnsPerHost,omitempty"`
+	// IdleConnTimeout holds the duration for which an idle connection is kept by the HTTP client of the snapstore.
+	IdleConnTimeout wrappers.Duration `json:"idleConnTimeout,omitempty"`
+	// DatePartitionedPrefix determines if the snapshots are saved into date based partitions under the prefix.
+	DatePartitionedPrefix bool `json:"datePartitionedPrefix,omitempty"`
+	// FetchCacheSize holds the maximum size in bytes of the local cache of the snapshots fetched from the snapstore.
+	// The cache is kept under the temporary directory and is disabled if the size is zero.
+	FetchCacheSize int64 `json:"fetchCacheSize,omitempty"`
+	// FetchCacheTTL holds the duration for which a snapshot is served from the fetch cache, before being fetched again.
+	FetchCacheTTL wrappers.Duration `json:"fetchCacheTTL,omitempty"`
+	// ListThrottlingRetries holds the number of times a listing of the snapstore is retried while it is throttled by the storage provider.
+	ListThrottlingRetries int `json:"listThrottlingRetries,omitempty"`
+	// ListThrottlingBackoff holds the backoff before the first retry of a throttled listing, which is doubled for every further retry.
+	ListThrottlingBackoff wrappers.Duration `json:"listThrottlingBackoff,omitempty"`
+	// SkipVerification determines if the verification of the bucket or container at startup is skipped, e.g. if the
+	// credentials aren't permitted to check whether it exists.
+	SkipVerification bool `json:"skipVerification,omitempty"`
+	// ObjectACL holds the canned ACL of the S3 compatible storage providers or the predefined ACL of GCS, which is set
+	// on the objects written to the snapstore instead of relying on the default ACL of the bucket.
+	ObjectACL string `json:"objectACL,omitempty"`
+	// AllowPublicObjectACL determines if the ObjectACL may grant public access to the objects written to the snapstore.
+	AllowPublicObjectACL bool `json:"allowPublicObjectACL,omitempty"`
+	// ObjectLockProtection determines if the S3 snapstore respects the object lock retention of the snapshots in a bucket
+	// with object lock enabled, by neither overwriting nor deleting the snapshots which are still under retention.
+	ObjectLockProtection bool `json:"objectLockProtection,omitempty"`
+	// S3Region holds the region of the S3 compatible storage providers, which takes precedence over the region of the
+	// credentials and the region resolved by the AWS SDK, e.g. for a store whose region doesn't follow the AWS conventions.
+	S3Region string `json:"s3Region,omitempty"`
+	// SnapshotNamer holds the namer to generate and parse the snapshot names with. The default naming scheme is used if it is nil.
+	SnapshotNamer SnapshotNamer `json:"-"`
+}
+
+// maxSinglePutObjectSizes are the upper size limits of an object uploaded with a single put to the storage providers
+// which support a multipart threshold.
+var maxSinglePutObjectSizes = map[string]int64{
+	SnapstoreProviderS3:  MaxS3SinglePutObjectSize,
+	SnapstoreProviderECS: MaxS3SinglePutObjectSize,
+	SnapstoreProviderOCS: MaxS3SinglePutObjectSize,
+}
+
+// publicObjectACLs are the canned ACLs of the S3 compatible storage providers and the predefined ACLs of GCS which grant
+// access to the objects beyond the accounts of the bucket owner.
+var publicObjectACLs = map[string]bool{
+	"public-read":        true,
+	"public-read-write":  true,
+	"authenticated-read": true,
+	"publicRead":         true,
+	"publicReadWrite":    true,
+	"authenticatedRead":  true,
+}
+
+// IsPublicObjectACL checks whether the object ACL grants public access to the objects.
+func IsPublicObjectACL(acl string) bool {
+	return publicObjectACLs[acl]
 }
 
 // AddFlags adds the flags to flagset.
@@ -183,13 +539,34 @@ func (c *SnapstoreConfig) AddSourceFlags(fs *flag.FlagSet) {
 	c.addFlags(fs, "source-")
 }
 
+// AddRestoreFlags adds the flags to flagset using `restore-` prefix for all parameters.
+func (c *SnapstoreConfig) AddRestoreFlags(fs *flag.FlagSet) {
+	c.addFlags(fs, "restore-")
+}
+
 func (c *SnapstoreConfig) addFlags(fs *flag.FlagSet, parameterPrefix string) {
 	fs.StringVar(&c.Provider, parameterPrefix+"storage-provider", c.Provider, "snapshot storage provider")
 	fs.StringVar(&c.Container, parameterPrefix+"store-container", c.Container, "container which will be used as snapstore")
 	fs.StringVar(&c.Prefix, parameterPrefix+"store-prefix", c.Prefix, "prefix or directory inside container under which snapstore is created")
+	fs.StringVar(&c.FullSnapshotPrefix, parameterPrefix+"full-snapshot-store-prefix", c.FullSnapshotPrefix, "prefix or directory inside container under which the full snapshots are stored instead of the store prefix, e.g. to apply other lifecycle rules to them. The other objects of the snapstore, such as the chain manifest, remain under the store prefix")
+	fs.StringVar(&c.DeltaSnapshotPrefix, parameterPrefix+"delta-snapshot-store-prefix", c.DeltaSnapshotPrefix, "prefix or directory inside container under which the delta snapshots are stored instead of the store prefix, e.g. to apply other lifecycle rules to them")
 	fs.UintVar(&c.MaxParallelChunkUploads, parameterPrefix+"max-parallel-chunk-uploads", c.MaxParallelChunkUploads, "maximum number of parallel chunk uploads allowed")
 	fs.Int64Var(&c.MinChunkSize, parameterPrefix+"min-chunk-size", c.MinChunkSize, "Minimum size for multipart chunk upload")
+	fs.Int64Var(&c.MultipartThreshold, parameterPrefix+"multipart-threshold", c.MultipartThreshold, "size in bytes from which the snapshots are uploaded to S3 compatible storage providers in multiple parts, while smaller snapshots are uploaded with a single put. All the snapshots are uploaded in multiple parts if zero")
 	fs.StringVar(&c.TempDir, parameterPrefix+"snapstore-temp-directory", c.TempDir, "temporary directory for processing")
+	fs.IntVar(&c.MaxIdleConns, parameterPrefix+"max-idle-conns", c.MaxIdleConns, "maximum number of idle connections across all hosts kept by the HTTP clients of the storage providers")
+	fs.IntVar(&c.MaxIdleConnsPerHost, parameterPrefix+"max-idle-conns-per-host", c.MaxIdleConnsPerHost, "maximum number of idle connections per host kept by the HTTP clients of the storage providers")
+	fs.DurationVar(&c.IdleConnTimeout.Duration, parameterPrefix+"idle-conn-timeout", c.IdleConnTimeout.Duration, "duration for which an idle connection is kept by the HTTP clients of the storage providers")
+	fs.BoolVar(&c.DatePartitionedPrefix, parameterPrefix+"date-partitioned-prefix", c.DatePartitionedPrefix, "save snapshots into monthly partitions of the form YYYY/MM under the prefix, so that the latest snapshots can be listed without listing the whole snapstore")
+	fs.Int64Var(&c.FetchCacheSize, parameterPrefix+"fetch-cache-size", c.FetchCacheSize, "maximum size in bytes of the cache of the fetched snapshots in the temporary directory, which lets the restorations on the same node share the fetched snapshots (disabled if zero)")
+	fs.DurationVar(&c.FetchCacheTTL.Duration, parameterPrefix+"fetch-cache-ttl", c.FetchCacheTTL.Duration, "duration for which a snapshot is served from the fetch cache before being fetched again")
+	fs.IntVar(&c.ListThrottlingRetries, parameterPrefix+"list-throttling-retries", c.ListThrottlingRetries, "number of times the listing of the snapshots by the garbage collection and the lookup of the latest snapshots is retried while the storage provider throttles it, e.g. with a SlowDown or 429 response")
+	fs.DurationVar(&c.ListThrottlingBackoff.Duration, parameterPrefix+"list-throttling-backoff", c.ListThrottlingBackoff.Duration, "backoff before the first retry of a throttled listing of the snapshots, doubled for every further retry")
+	fs.BoolVar(&c.SkipVerification, parameterPrefix+"skip-snapstore-verification", c.SkipVerification, "skip the verification at startup that the bucket or container exists and is writable, e.g. if the credentials aren't permitted to check it")
+	fs.StringVar(&c.ObjectACL, parameterPrefix+"snapstore-object-acl", c.ObjectACL, "canned ACL of S3 compatible storage providers, e.g. bucket-owner-full-control, or predefined ACL of GCS, e.g. bucketOwnerFullControl, set on the objects written to the snapstore. The default ACL of the bucket applies if empty")
+	fs.BoolVar(&c.AllowPublicObjectACL, parameterPrefix+"allow-public-snapstore-object-acl", c.AllowPublicObjectACL, "allow an ACL granting public access, e.g. public-read, to be set on the objects written to the snapstore")
+	fs.BoolVar(&c.ObjectLockProtection, parameterPrefix+"snapstore-object-lock-protection", c.ObjectLockProtection, "detect whether object lock is enabled on the bucket of S3 compatible storage providers, and if so, never overwrite or delete the snapshots which are still under object lock retention or legal hold. Such snapshots are skipped by the garbage collection until their retention expires")
+	fs.StringVar(&c.S3Region, parameterPrefix+"snapstore-s3-region", c.S3Region, "region of S3 compatible storage providers with which the requests are signed, overriding the region of the credentials and the one resolved from the environment. The region of the credentials applies if empty")
 }
 
 // Validate validates the config.
@@ -200,12 +577,41 @@ func (c *SnapstoreConfig) Validate() error {
 	if c.MinChunkSize < MinChunkSize {
 		return fmt.Errorf("min chunk size for multi-part chunk upload should be greater than or equal to 5 MiB")
 	}
+	if c.MultipartThreshold != 0 {
+		if c.MultipartThreshold < MinChunkSize {
+			return fmt.Errorf("multipart threshold should be zero or greater than or equal to 5 MiB")
+		}
+		if maxSize, ok := maxSinglePutObjectSizes[c.Provider]; ok && c.MultipartThreshold > maxSize {
+			return fmt.Errorf("multipart threshold should not exceed %d bytes, the maximum size of an object uploaded with a single put to storage provider %s", maxSize, c.Provider)
+		}
+	}
+	if c.MaxIdleConns < 0 || c.MaxIdleConnsPerHost < 0 {
+		return fmt.Errorf("max idle connections should not be negative")
+	}
+	if c.IdleConnTimeout.Duration < 0 {
+		return fmt.Errorf("idle connection timeout should not be negative")
+	}
+	if c.FetchCacheSize < 0 || c.FetchCacheTTL.Duration < 0 {
+		return fmt.Errorf("fetch cache size and ttl should not be negative")
+	}
+	if c.ListThrottlingRetries < 0 || c.ListThrottlingBackoff.Duration < 0 {
+		return fmt.Errorf("list throttling retries and backoff should not be negative")
+	}
+	if IsPublicObjectACL(c.ObjectACL) && !c.AllowPublicObjectACL {
+		return fmt.Errorf("object ACL %s grants public access to the snapshots, which has to be allowed explicitly", c.ObjectACL)
+	}
 	return nil
 }
 
 // Complete completes the config.
 func (c *SnapstoreConfig) Complete() {
 	c.Prefix = path.Join(c.Prefix, backupFormatVersion)
+	if c.FullSnapshotPrefix != "" {
+		c.FullSnapshotPrefix = path.Join(c.FullSnapshotPrefix, backupFormatVersion)
+	}
+	if c.DeltaSnapshotPrefix != "" {
+		c.DeltaSnapshotPrefix = path.Join(c.DeltaSnapshotPrefix, backupFormatVersion)
+	}
 }
 
 // MergeWith completes the config based on other config
@@ -213,12 +619,26 @@ func (c *SnapstoreConfig) MergeWith(other *SnapstoreConfig) {
 	if c.Provider == "" {
 		c.Provider = other.Provider
 	}
+	if c.FullSnapshotPrefix != "" {
+		c.FullSnapshotPrefix = path.Join(c.FullSnapshotPrefix, backupFormatVersion)
+	}
+	if c.DeltaSnapshotPrefix != "" {
+		c.DeltaSnapshotPrefix = path.Join(c.DeltaSnapshotPrefix, backupFormatVersion)
+	}
 	if c.Prefix == "" {
 		c.Prefix = other.Prefix
+		// the snapshots are laid out across the prefixes of the other config, unless they are given
+		if c.FullSnapshotPrefix == "" && c.DeltaSnapshotPrefix == "" {
+			c.FullSnapshotPrefix = other.FullSnapshotPrefix
+			c.DeltaSnapshotPrefix = other.DeltaSnapshotPrefix
+		}
 	} else {
 		c.Prefix = path.Join(c.Prefix, backupFormatVersion)
 	}
 	if c.TempDir == "" {
 		c.TempDir = other.TempDir
 	}
+	if c.SnapshotNamer == nil {
+		c.SnapshotNamer = other.SnapshotNamer
+	}
 }
@@ -0,0 +1,125 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types_test
+
+import (
+	. "github.com/gardener/etcd-backup-restore/pkg/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validating the object ACL of the snapstore config", func() {
+	var config *SnapstoreConfig
+
+	BeforeEach(func() {
+		config = &SnapstoreConfig{
+			Provider:                SnapstoreProviderS3,
+			MaxParallelChunkUploads: 5,
+			MinChunkSize:            MinChunkSize,
+		}
+	})
+
+	DescribeTable("should accept an object ACL which doesn't grant public access",
+		func(acl string) {
+			config.ObjectACL = acl
+			Expect(config.Validate()).To(Succeed())
+		},
+		Entry("no object ACL", ""),
+		Entry("the S3 canned ACL granting the bucket owner full control", "bucket-owner-full-control"),
+		Entry("the S3 private canned ACL", "private"),
+		Entry("the GCS predefined ACL granting the bucket owner full control", "bucketOwnerFullControl"),
+	)
+
+	DescribeTable("should reject an object ACL which grants public access unless it is allowed",
+		func(acl string) {
+			config.ObjectACL = acl
+			err := config.Validate()
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring(acl))
+
+			config.AllowPublicObjectACL = true
+			Expect(config.Validate()).To(Succeed())
+		},
+		Entry("the S3 public-read canned ACL", "public-read"),
+		Entry("the S3 public-read-write canned ACL", "public-read-write"),
+		Entry("the S3 authenticated-read canned ACL", "authenticated-read"),
+		Entry("the GCS publicRead predefined ACL", "publicRead"),
+	)
+})
+
+var _ = Describe("Validating the multipart threshold of the snapstore config", func() {
+	var config *SnapstoreConfig
+
+	BeforeEach(func() {
+		config = &SnapstoreConfig{
+			Provider:                SnapstoreProviderS3,
+			MaxParallelChunkUploads: 5,
+			MinChunkSize:            MinChunkSize,
+		}
+	})
+
+	DescribeTable("should accept a multipart threshold which is disabled or within the limits of the provider",
+		func(provider string, threshold int64) {
+			config.Provider = provider
+			config.MultipartThreshold = threshold
+			Expect(config.Validate()).To(Succeed())
+		},
+		Entry("no multipart threshold", SnapstoreProviderS3, int64(0)),
+		Entry("the minimum chunk size", SnapstoreProviderS3, MinChunkSize),
+		Entry("the maximum size of a single put to S3", SnapstoreProviderS3, MaxS3SinglePutObjectSize),
+		Entry("a threshold above the maximum size of a single put to S3 for another provider", SnapstoreProviderGCS, MaxS3SinglePutObjectSize+1),
+	)
+
+	DescribeTable("should reject a multipart threshold beyond the limits of the provider",
+		func(provider string, threshold int64, message string) {
+			config.Provider = provider
+			config.MultipartThreshold = threshold
+			Expect(config.Validate()).Should(MatchError(ContainSubstring(message)))
+		},
+		Entry("a negative threshold", SnapstoreProviderS3, int64(-1), "greater than or equal to 5 MiB"),
+		Entry("a threshold below the minimum chunk size", SnapstoreProviderS3, MinChunkSize-1, "greater than or equal to 5 MiB"),
+		Entry("a threshold above the maximum size of a single put to S3", SnapstoreProviderS3, MaxS3SinglePutObjectSize+1, "storage provider S3"),
+		Entry("a threshold above the maximum size of a single put to ECS", SnapstoreProviderECS, MaxS3SinglePutObjectSize+1, "storage provider ECS"),
+	)
+})
+
+var _ = Describe("Completing the prefixes of the kinds of snapshots of the snapstore config", func() {
+	It("should append the backup format version to the configured prefixes only", func() {
+		config := &SnapstoreConfig{Prefix: "etcd", FullSnapshotPrefix: "fulls"}
+		config.Complete()
+		Expect(config.Prefix).To(Equal("etcd/v2"))
+		Expect(config.FullSnapshotPrefix).To(Equal("fulls/v2"))
+		Expect(config.DeltaSnapshotPrefix).To(BeEmpty())
+	})
+
+	It("should inherit the prefixes of the kinds along with the prefix of the other config", func() {
+		other := &SnapstoreConfig{Prefix: "etcd", FullSnapshotPrefix: "fulls", DeltaSnapshotPrefix: "deltas"}
+		other.Complete()
+		config := &SnapstoreConfig{}
+		config.MergeWith(other)
+		Expect(config.Prefix).To(Equal("etcd/v2"))
+		Expect(config.FullSnapshotPrefix).To(Equal("fulls/v2"))
+		Expect(config.DeltaSnapshotPrefix).To(Equal("deltas/v2"))
+	})
+
+	It("should not inherit the prefixes of the kinds if any of them is given", func() {
+		other := &SnapstoreConfig{Prefix: "etcd", FullSnapshotPrefix: "fulls", DeltaSnapshotPrefix: "deltas"}
+		other.Complete()
+		config := &SnapstoreConfig{DeltaSnapshotPrefix: "source-deltas"}
+		config.MergeWith(other)
+		Expect(config.Prefix).To(Equal("etcd/v2"))
+		Expect(config.FullSnapshotPrefix).To(BeEmpty())
+		Expect(config.DeltaSnapshotPrefix).To(Equal("source-deltas/v2"))
+	})
+
+	It("should not inherit the prefixes of the kinds if the prefix is given", func() {
+		other := &SnapstoreConfig{Prefix: "etcd", FullSnapshotPrefix: "fulls"}
+		other.Complete()
+		config := &SnapstoreConfig{Prefix: "source"}
+		config.MergeWith(other)
+		Expect(config.Prefix).To(Equal("source/v2"))
+		Expect(config.FullSnapshotPrefix).To(BeEmpty())
+	})
+})